@@ -0,0 +1,144 @@
+// Command lint checks workflow definitions against the workflow lint rules
+// and reports their issues. Arguments are workflow files (YAML, or JSON as
+// accepted by the workflows API) or directories of them. It exits 1 when a
+// workflow is invalid or has lint errors, or with -strict any warnings.
+//
+//	go run ./cmd/lint workflows
+//	go run ./cmd/lint -strict -json workflows/book-chapter-processing.yaml
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/memmieai/memmie-studio/internal/simulation"
+	"github.com/memmieai/memmie-studio/internal/workflows"
+	_ "github.com/memmieai/memmie-studio/schemas"
+)
+
+// result is one file's lint outcome
+type result struct {
+	File       string                `json:"file"`
+	WorkflowID string                `json:"workflow_id,omitempty"`
+	Error      string                `json:"error,omitempty"`
+	Issues     []workflows.LintIssue `json:"issues"`
+}
+
+func main() {
+	strict := flag.Bool("strict", false, "fail on warnings as well as errors")
+	asJSON := flag.Bool("json", false, "print the results as JSON")
+	listRules := flag.Bool("rules", false, "list the lint rules and exit")
+	flag.Parse()
+
+	if *listRules {
+		for _, rule := range workflows.LintRules {
+			fmt.Printf("%-26s %-8s %s\n", rule.ID, rule.Severity, rule.Description)
+		}
+		return
+	}
+	if flag.NArg() == 0 {
+		log.Fatal("usage: lint [-strict] [-json] workflow-file-or-dir...")
+	}
+	files, err := workflowFiles(flag.Args())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(files) == 0 {
+		log.Fatal("no workflow files found")
+	}
+
+	passed := true
+	results := make([]result, 0, len(files))
+	for _, file := range files {
+		res := result{File: file, Issues: []workflows.LintIssue{}}
+		workflow, err := simulation.LoadWorkflow(file)
+		if err == nil {
+			res.WorkflowID = workflow.ID
+			if workflow.Type == "" {
+				workflow.Type = workflows.WorkflowTypeProcessBlob
+			}
+			err = workflow.Validate()
+		}
+		if err != nil {
+			res.Error = err.Error()
+			passed = false
+		} else {
+			res.Issues = workflows.Lint(workflow)
+		}
+		for _, issue := range res.Issues {
+			if issue.Severity == workflows.SeverityError || (*strict && issue.Severity == workflows.SeverityWarning) {
+				passed = false
+			}
+		}
+		results = append(results, res)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(results); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		for _, res := range results {
+			printResult(res)
+		}
+	}
+	if !passed {
+		os.Exit(1)
+	}
+}
+
+// workflowFiles expands directories in paths to the YAML and JSON files
+// directly inside them
+func workflowFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		var found []string
+		for _, entry := range entries {
+			switch strings.ToLower(filepath.Ext(entry.Name())) {
+			case ".yaml", ".yml", ".json":
+				if !entry.IsDir() {
+					found = append(found, filepath.Join(path, entry.Name()))
+				}
+			}
+		}
+		sort.Strings(found)
+		files = append(files, found...)
+	}
+	return files, nil
+}
+
+// printResult writes a file's issues, one per line
+func printResult(res result) {
+	switch {
+	case res.Error != "":
+		fmt.Printf("FAIL %s: %s\n", res.File, res.Error)
+		return
+	case len(res.Issues) == 0:
+		fmt.Printf("ok   %s (%s)\n", res.File, res.WorkflowID)
+		return
+	}
+	fmt.Printf("%s (%s)\n", res.File, res.WorkflowID)
+	for _, issue := range res.Issues {
+		fmt.Printf("  %s\n", issue)
+	}
+}
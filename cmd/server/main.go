@@ -2,15 +2,50 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
+	_ "github.com/lib/pq"
 	"go.uber.org/zap"
+
+	"github.com/memmieai/memmie-studio/internal/api"
+	"github.com/memmieai/memmie-studio/internal/artifact"
+	"github.com/memmieai/memmie-studio/internal/awsauth"
+	_ "github.com/memmieai/memmie-studio/internal/backends/conductor"
+	_ "github.com/memmieai/memmie-studio/internal/backends/temporal"
+	"github.com/memmieai/memmie-studio/internal/blob"
+	"github.com/memmieai/memmie-studio/internal/branches"
+	"github.com/memmieai/memmie-studio/internal/chaos"
+	"github.com/memmieai/memmie-studio/internal/deltastore/postgres"
+	"github.com/memmieai/memmie-studio/internal/eventbus/kafka"
+	"github.com/memmieai/memmie-studio/internal/forward"
+	"github.com/memmieai/memmie-studio/internal/integrations/calendar"
+	"github.com/memmieai/memmie-studio/internal/integrations/gdocs"
+	"github.com/memmieai/memmie-studio/internal/integrations/github"
+	"github.com/memmieai/memmie-studio/internal/integrations/gitrepo"
+	"github.com/memmieai/memmie-studio/internal/integrations/s3"
+	"github.com/memmieai/memmie-studio/internal/integrations/slack"
+	"github.com/memmieai/memmie-studio/internal/langdetect"
+	"github.com/memmieai/memmie-studio/internal/moderation"
+	"github.com/memmieai/memmie-studio/internal/packs"
+	"github.com/memmieai/memmie-studio/internal/payloads"
+	"github.com/memmieai/memmie-studio/internal/projections"
+	"github.com/memmieai/memmie-studio/internal/quotas"
+	"github.com/memmieai/memmie-studio/internal/reprocess"
+	"github.com/memmieai/memmie-studio/internal/timers"
+	"github.com/memmieai/memmie-studio/internal/trash"
+	"github.com/memmieai/memmie-studio/internal/workflows"
+	_ "github.com/memmieai/memmie-studio/providers"
+	_ "github.com/memmieai/memmie-studio/schemas"
 )
 
 func main() {
@@ -29,15 +64,603 @@ func main() {
 		port = "8010"
 	}
 
+	backendConfig := workflows.BackendConfig{
+		Type:      getEnv("EXECUTION_BACKEND", workflows.BackendHTTP),
+		URL:       os.Getenv("EXECUTION_BACKEND_URL"),
+		Namespace: os.Getenv("EXECUTION_BACKEND_NAMESPACE"),
+		TaskQueue: os.Getenv("EXECUTION_BACKEND_TASK_QUEUE"),
+		Options: map[string]string{
+			"owner_email": os.Getenv("EXECUTION_BACKEND_OWNER_EMAIL"),
+		},
+	}
+	if backendConfig.URL == "" {
+		backendConfig.URL = getEnv("WORKFLOW_SERVICE_URL", "http://localhost:8005")
+	}
+
 	sugar.Infow("Starting Memmie Studio service",
 		"port", port,
 		"version", "1.0.0",
+		"execution_backend", backendConfig.Type,
 	)
 
+	// Create execution backend
+	workflowService, err := workflows.NewBackend(backendConfig)
+	if err != nil {
+		sugar.Fatalw("Failed to create execution backend", "error", err, "available", workflows.Backends())
+	}
+	if closer, ok := workflowService.(interface{ Close() }); ok {
+		defer closer.Close()
+	}
+	// Backends that can report a running execution's output stream the
+	// steps of interactive executions as they finish; the wrappers below
+	// do not need to pass the reports on
+	progress, _ := workflowService.(workflows.ProgressReporter)
+
+	// With FORWARD_DIR set, executions started while the execution
+	// backend fails its health checks are queued there and forwarded once
+	// it recovers, instead of failing
+	var forwarder *forward.Service
+	if dir := os.Getenv("FORWARD_DIR"); dir != "" {
+		checker, ok := workflowService.(workflows.HealthChecker)
+		if !ok {
+			sugar.Fatalw("FORWARD_DIR is set but the execution backend has no health check", "execution_backend", backendConfig.Type)
+		}
+		forwarder = forward.NewService(workflowService, checker, forward.NewFileStore(dir), forward.DefaultCheckInterval, func(err error) {
+			sugar.Warnw("Failed to forward queued executions", "error", err)
+		})
+		defer forwarder.Close()
+		workflowService = forwarder.Backend()
+	}
+
+	// Fault injection is off unless CHAOS sets faults for every workflow
+	// call or CHAOS_HEADER lets requests set their own
+	chaosHeader := os.Getenv("CHAOS_HEADER") == "true"
+	if spec := os.Getenv("CHAOS"); spec != "" || chaosHeader {
+		var injector *chaos.Injector
+		if spec != "" {
+			cfg, err := chaos.Parse(spec)
+			if err != nil {
+				sugar.Fatalw("Invalid CHAOS", "error", err)
+			}
+			injector = chaos.NewInjector(cfg)
+		}
+		workflowService = chaos.WrapService(workflowService, injector)
+		sugar.Warnw("Fault injection enabled", "chaos", spec, "chaos_header", chaosHeader)
+	}
+
+	// Executions are scheduled fairly between users within the limits of
+	// the QUOTAS file, if one is given
+	var scheduler *quotas.Scheduler
+	if path := os.Getenv("QUOTAS"); path != "" {
+		quotaConfig, err := quotas.LoadConfig(path)
+		if err != nil {
+			sugar.Fatalw("Failed to load quotas", "error", err)
+		}
+		scheduler = quotas.NewScheduler(quotaConfig)
+		workflowService = quotas.NewService(workflowService, scheduler, quotas.DefaultPollInterval, func(err error) {
+			sugar.Warnw("Failed to check execution status", "error", err)
+		})
+		if closer, ok := workflowService.(interface{ Close() }); ok {
+			defer closer.Close()
+		}
+	}
+
+	// Create API
+	blobs := langdetect.NewStore(blob.NewClient(getEnv("STATE_SERVICE_URL", "http://localhost:8006")))
+	artifacts := artifact.NewLocalStore(
+		getEnv("ARTIFACT_DIR", "./data/artifacts"),
+		getEnv("ARTIFACT_BASE_URL", "http://localhost:"+port+"/artifacts"),
+	)
+	// Processing events are published in process for blob event streams,
+	// queued per subscriber (EVENT_QUEUE_SIZE events, 1024 by default) and
+	// when a queue is full waited for, dropped, made room for by dropping
+	// the oldest or spilled to EVENT_SPILL_DIR (EVENT_QUEUE_POLICY, block,
+	// drop, drop-oldest or spill). Event streams drop their oldest events
+	// whatever the policy.
+	queueSize, err := strconv.Atoi(getEnv("EVENT_QUEUE_SIZE", "0"))
+	if err != nil {
+		sugar.Fatalw("Invalid EVENT_QUEUE_SIZE", "error", err)
+	}
+	events, err := workflows.NewMemoryBus(workflows.MemoryBusConfig{
+		QueueSize: queueSize,
+		Policy:    os.Getenv("EVENT_QUEUE_POLICY"),
+		SpillDir:  os.Getenv("EVENT_SPILL_DIR"),
+		OnError: func(err error) {
+			sugar.Warnw("Event handler failed", "error", err)
+		},
+	})
+	if err != nil {
+		sugar.Fatalw("Invalid event queue", "error", err)
+	}
+	// With KAFKA_REST_URL set they are mirrored to Kafka through its REST
+	// Proxy, to KAFKA_TOPIC or the topics KAFKA_TOPICS routes types to.
+	// EVENT_BATCH_WINDOW (a Go duration) batches each blob's events of a
+	// type over that window into one record, gzipped with
+	// EVENT_BATCH_COMPRESS=true.
+	var bus workflows.EventBus = events
+	if url := os.Getenv("KAFKA_REST_URL"); url != "" {
+		topics, err := kafka.ParseTopics(os.Getenv("KAFKA_TOPICS"))
+		if err != nil {
+			sugar.Fatalw("Invalid KAFKA_TOPICS", "error", err)
+		}
+		kafkaBus, err := kafka.NewBus(kafka.Config{
+			URL:    url,
+			Topic:  getEnv("KAFKA_TOPIC", "studio.events"),
+			Topics: topics,
+		}, func(err error) {
+			sugar.Warnw("Kafka event bus failed", "error", err)
+		})
+		if err != nil {
+			sugar.Fatalw("Failed to create Kafka event bus", "error", err)
+		}
+		var mirror workflows.EventBus = kafkaBus
+		if value := os.Getenv("EVENT_BATCH_WINDOW"); value != "" {
+			window, err := time.ParseDuration(value)
+			if err != nil {
+				sugar.Fatalw("Invalid EVENT_BATCH_WINDOW", "error", err)
+			}
+			batches := workflows.NewBatchingBus(kafkaBus, workflows.BatchConfig{
+				Window:   window,
+				Compress: os.Getenv("EVENT_BATCH_COMPRESS") == "true",
+				OnError: func(err error) {
+					sugar.Warnw("Failed to publish event batch", "error", err)
+				},
+			})
+			defer batches.Close()
+			mirror = batches
+		}
+		bus = workflows.Mirror(events, mirror)
+	}
+	// Events with a registered schema are checked against it before they
+	// are published; EVENT_VALIDATION=strict also rejects types without one
+	// and off skips the check
+	switch mode := getEnv("EVENT_VALIDATION", "on"); mode {
+	case "on", "strict":
+		bus = workflows.ValidateEvents(bus, mode == "strict")
+	case "off":
+	default:
+		sugar.Fatalw("Invalid EVENT_VALIDATION", "value", mode)
+	}
+	// Events the bus will not take, and events a handler still fails on
+	// after EVENT_DELIVERY_ATTEMPTS tries (3 by default), are kept as dead
+	// letters to list and replay, up to the latest DEAD_LETTER_LIMIT (1000)
+	attempts, err := strconv.Atoi(getEnv("EVENT_DELIVERY_ATTEMPTS", "0"))
+	if err != nil {
+		sugar.Fatalw("Invalid EVENT_DELIVERY_ATTEMPTS", "error", err)
+	}
+	letterLimit, err := strconv.Atoi(getEnv("DEAD_LETTER_LIMIT", "0"))
+	if err != nil {
+		sugar.Fatalw("Invalid DEAD_LETTER_LIMIT", "error", err)
+	}
+	letters := workflows.NewDeadLetterQueue(bus, workflows.DeadLetterConfig{Attempts: attempts, Limit: letterLimit})
+	bus = letters
+	// Workflow output is stored as deltas in the Postgres database at
+	// DATABASE_URL, with their events committed through its outbox; without
+	// it providers can be registered but their workflows' output is not
+	// applied
+	// Stored deltas are chained by hash. DELTA_SIGNING_KEY, given as
+	// algorithm:id:base64-key with algorithm hmac-sha256 or ed25519, signs
+	// them as well; DELTA_VERIFY_KEYS lists, comma-separated, further keys
+	// signatures are checked against, such as rotated out or public keys
+	var deltaKeys *workflows.DeltaKeyring
+	if os.Getenv("DELTA_SIGNING_KEY") != "" || os.Getenv("DELTA_VERIFY_KEYS") != "" {
+		var signing *workflows.DeltaKey
+		if spec := os.Getenv("DELTA_SIGNING_KEY"); spec != "" {
+			if signing, err = workflows.ParseDeltaKey(spec); err != nil {
+				sugar.Fatalw("Invalid DELTA_SIGNING_KEY", "error", err)
+			}
+		}
+		var others []*workflows.DeltaKey
+		for _, spec := range strings.Split(os.Getenv("DELTA_VERIFY_KEYS"), ",") {
+			if strings.TrimSpace(spec) == "" {
+				continue
+			}
+			key, err := workflows.ParseDeltaKey(spec)
+			if err != nil {
+				sugar.Fatalw("Invalid DELTA_VERIFY_KEYS", "error", err)
+			}
+			others = append(others, key)
+		}
+		if deltaKeys, err = workflows.NewDeltaKeyring(signing, others...); err != nil {
+			sugar.Fatalw("Invalid delta signing keys", "error", err)
+		}
+	}
+	var deltaStorage workflows.DeltaStorage
+	if url := os.Getenv("DATABASE_URL"); url != "" {
+		db, err := sql.Open("postgres", url)
+		if err != nil {
+			sugar.Fatalw("Failed to open database", "error", err)
+		}
+		defer db.Close()
+		storage := postgres.NewStorage(db)
+		storage.SetSigningKeys(deltaKeys)
+		if err := storage.Migrate(context.Background()); err != nil {
+			sugar.Fatalw("Failed to migrate delta storage", "error", err)
+		}
+		deltaStorage = storage
+		// With STATE_CHECK_INTERVAL set to a Go duration, STATE_CHECK_SAMPLE
+		// (10) random blobs are rebuilt from their delta logs at that
+		// interval and compared with their stored state, logging divergence
+		if value := os.Getenv("STATE_CHECK_INTERVAL"); value != "" {
+			interval, err := time.ParseDuration(value)
+			if err != nil {
+				sugar.Fatalw("Invalid STATE_CHECK_INTERVAL", "error", err)
+			}
+			sample, err := strconv.Atoi(getEnv("STATE_CHECK_SAMPLE", "0"))
+			if err != nil {
+				sugar.Fatalw("Invalid STATE_CHECK_SAMPLE", "error", err)
+			}
+			checker := workflows.NewConsistencyChecker(storage, workflows.ConsistencyConfig{Interval: interval, Sample: sample}, func(report *workflows.ConsistencyReport) {
+				sugar.Errorw("Blob state diverges from its delta log", "blob_id", report.BlobID, "sequence", report.Sequence, "divergences", report.Divergences)
+			}, func(err error) {
+				sugar.Warnw("Failed to check blob state", "error", err)
+			})
+			defer checker.Close()
+		}
+	}
+	orchestrator := workflows.NewOrchestratorWithService(workflowService, bus, deltaStorage)
+	orchestrator.SetBlobLoader(blob.Loader{Store: blobs})
+	if deltaStorage != nil {
+		outbox, err := orchestrator.EnableOutbox(workflows.OutboxConfig{}, func(err error) {
+			sugar.Warnw("Failed to dispatch delta events", "error", err)
+		})
+		if err != nil {
+			sugar.Fatalw("Failed to enable the event outbox", "error", err)
+		}
+		defer outbox.Close()
+	}
+	// Step durations reported by the backend are kept for workflow health
+	// and lint recommends timeouts of their p99 plus STEP_TIMEOUT_MARGIN
+	// (0.25), within STEP_TIMEOUT_MIN and STEP_TIMEOUT_MAX seconds
+	margin, err := strconv.ParseFloat(getEnv("STEP_TIMEOUT_MARGIN", "0"), 64)
+	if err != nil {
+		sugar.Fatalw("Invalid STEP_TIMEOUT_MARGIN", "error", err)
+	}
+	minTimeout, err := strconv.Atoi(getEnv("STEP_TIMEOUT_MIN", "0"))
+	if err != nil {
+		sugar.Fatalw("Invalid STEP_TIMEOUT_MIN", "error", err)
+	}
+	maxTimeout, err := strconv.Atoi(getEnv("STEP_TIMEOUT_MAX", "0"))
+	if err != nil {
+		sugar.Fatalw("Invalid STEP_TIMEOUT_MAX", "error", err)
+	}
+	latencies := workflows.NewStepLatencies(workflows.LatencyConfig{Margin: margin, MinTimeout: minTimeout, MaxTimeout: maxTimeout})
+	orchestrator.SetStepLatencies(latencies)
+	workflows.SetLintLatencies(latencies)
+	// Workflow estimates price models from MODEL_PRICING_FILE, a YAML map of
+	// model names to input_per_1k and output_per_1k dollars, over the
+	// default prices
+	var pricing workflows.PricingTable
+	if path := os.Getenv("MODEL_PRICING_FILE"); path != "" {
+		if pricing, err = workflows.LoadPricing(path); err != nil {
+			sugar.Fatalw("Failed to load model pricing", "error", err)
+		}
+	}
+	registry := workflows.NewWorkflowRegistry(workflowService)
+	// With STEP_TIMEOUT_AUTOTUNE set to a Go duration, workflows are given
+	// the recommended timeouts at that interval, as new versions
+	if value := os.Getenv("STEP_TIMEOUT_AUTOTUNE"); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err != nil {
+			sugar.Fatalw("Invalid STEP_TIMEOUT_AUTOTUNE", "error", err)
+		}
+		tuner := workflows.NewTimeoutTuner(registry, latencies, interval, func(change workflows.TimeoutChange) {
+			sugar.Infow("Tuned step timeout", "workflow_id", change.WorkflowID, "step_id", change.StepID, "from", change.From, "to", change.To)
+		}, func(err error) {
+			sugar.Warnw("Failed to tune step timeouts", "error", err)
+		})
+		defer tuner.Close()
+	}
+	// Failed executions of workflows with EnableRollback are rolled back
+	// in the background; failures are only logged
+	orchestrator.SetRollbackErrorHandler(func(err error) {
+		sugar.Warnw("Execution rollback failed", "error", err)
+	})
+	// Interactive executions, asked for with the X-Execution-Profile header
+	// or a trigger's interactive flag, run under INTERACTIVE_MODEL and
+	// INTERACTIVE_MAX_TOKENS below any interactive settings of namespace
+	// model policies, and complete with what they have after
+	// INTERACTIVE_DEADLINE (a Go duration, 10s by default)
+	interactive := workflows.InteractiveProfile{ModelPolicy: workflows.ModelPolicy{Model: os.Getenv("INTERACTIVE_MODEL")}}
+	if value := os.Getenv("INTERACTIVE_MAX_TOKENS"); value != "" {
+		if interactive.ModelPolicy.MaxTokens, err = strconv.Atoi(value); err != nil || interactive.ModelPolicy.MaxTokens < 0 {
+			sugar.Fatalw("Invalid INTERACTIVE_MAX_TOKENS", "value", value)
+		}
+	}
+	if value := os.Getenv("INTERACTIVE_DEADLINE"); value != "" {
+		if interactive.Deadline, err = time.ParseDuration(value); err != nil || interactive.Deadline <= 0 {
+			sugar.Fatalw("Invalid INTERACTIVE_DEADLINE", "value", value)
+		}
+	}
+	orchestrator.SetInteractiveProfile(interactive)
+	if progress != nil {
+		orchestrator.SetProgressReporter(progress)
+	}
+	// Executions in flight are journaled under EXECUTION_JOURNAL_DIR and
+	// reconciled on startup: those the backend lost are failed or, with
+	// LOST_EXECUTION_POLICY=requeue, started again, and those still running
+	// after EXECUTION_MAX_AGE (a Go duration) are cancelled first
+	if dir := os.Getenv("EXECUTION_JOURNAL_DIR"); dir != "" {
+		orchestrator.SetExecutionJournal(workflows.NewFileJournal(dir), func(err error) {
+			sugar.Warnw("Execution journal failed", "error", err)
+		})
+		var maxAge time.Duration
+		if value := os.Getenv("EXECUTION_MAX_AGE"); value != "" {
+			if maxAge, err = time.ParseDuration(value); err != nil {
+				sugar.Fatalw("Invalid EXECUTION_MAX_AGE", "error", err)
+			}
+		}
+		results, err := orchestrator.ReconcileExecutions(context.Background(), workflows.ReconcilePolicy{
+			Lost:   os.Getenv("LOST_EXECUTION_POLICY"),
+			MaxAge: maxAge,
+		})
+		if err != nil {
+			sugar.Fatalw("Failed to reconcile executions", "error", err)
+		}
+		for _, result := range results {
+			sugar.Infow("Reconciled execution", "execution_id", result.ExecutionID, "workflow_id", result.WorkflowID,
+				"action", result.Action, "status", result.Status, "new_execution_id", result.NewExecutionID, "error", result.Error)
+		}
+	}
+	// Repositories are cloned under REPO_WORK_DIR, or read from under
+	// REPO_LOCAL_ROOT, and their ingested files processed by the providers
+	repos := gitrepo.NewIngester(blobs, orchestrator, getEnv("REPO_WORK_DIR", "./data/repos"), os.Getenv("REPO_LOCAL_ROOT"))
+	// Blob processing can be scheduled for later when TIMER_DIR is set;
+	// scheduled runs are kept there and survive restarts
+	var scheduled *timers.Service
+	if dir := os.Getenv("TIMER_DIR"); dir != "" {
+		scheduled = timers.NewService(timers.NewFileStore(dir), orchestrator, timers.DefaultCheckInterval, func(err error) {
+			sugar.Warnw("Failed to check scheduled runs", "error", err)
+		})
+		defer scheduled.Close()
+	}
+	// Bulk reprocessing jobs checkpoint under REPROCESS_DIR, so jobs cut
+	// short by a restart pick up where they stopped
+	reprocessor := reprocess.NewService(reprocess.NewFileStore(getEnv("REPROCESS_DIR", "./data/reprocess")), orchestrator, blobs, func(err error) {
+		sugar.Warnw("Failed to save reprocess job", "error", err)
+	})
+	defer reprocessor.Close()
+	// Deleted blobs are kept under TRASH_DIR for TRASH_RETENTION (a Go
+	// duration, 30 days by default) before they are purged
+	var retention time.Duration
+	if value := os.Getenv("TRASH_RETENTION"); value != "" {
+		if retention, err = time.ParseDuration(value); err != nil {
+			sugar.Fatalw("Invalid TRASH_RETENTION", "error", err)
+		}
+	}
+	bin := trash.NewService(trash.NewFileStore(getEnv("TRASH_DIR", "./data/trash")), blobs, nil, retention, trash.DefaultCheckInterval, func(err error) {
+		sugar.Warnw("Failed to purge trash", "error", err)
+	})
+	defer bin.Close()
+	// Blobs are branched and merged back over their delta logs, so only
+	// with delta storage; branches are recorded under BRANCH_DIR
+	var branching *branches.Service
+	if deltaStorage != nil {
+		branching = branches.NewService(branches.NewFileStore(getEnv("BRANCH_DIR", "./data/branches")), blobs, deltaStorage, orchestrator)
+	}
+	// Workflow packs installed into namespaces are recorded under PACK_DIR
+	// and registered again at startup
+	packService := packs.NewService(packs.NewFileStore(getEnv("PACK_DIR", "./data/packs")), registry, orchestrator)
+	if err := packService.Restore(context.Background()); err != nil {
+		sugar.Warnw("Failed to restore workflow packs", "error", err)
+	}
+	// Book contents and topic indexes are read models projected from the
+	// blobs as deltas are applied; they are kept in memory and rebuilt on
+	// request after a restart
+	projector := projections.NewProjector(blobs, deltaStorage, projections.NewMemoryStore(), projections.Defaults(), func(err error) {
+		sugar.Warnw("Failed to project blob", "error", err)
+	})
+	projectCtx, stopProjecting := context.WithCancel(context.Background())
+	defer stopProjecting()
+	if err := projector.Subscribe(projectCtx, bus); err != nil {
+		sugar.Fatalw("Failed to subscribe read models", "error", err)
+	}
+	// Step requests and responses captured by workers sharing
+	// STEP_PAYLOAD_DIR are served with their executions, and deleted there
+	// once their retention passes
+	var payloadLog *payloads.Log
+	if dir := os.Getenv("STEP_PAYLOAD_DIR"); dir != "" {
+		payloadLog = payloads.NewLog(payloads.NewFileStore(dir), payloads.DefaultCheckInterval, func(err error) {
+			sugar.Warnw("Failed to delete expired step payloads", "error", err)
+		})
+		defer payloadLog.Close()
+	}
+	// With CALENDAR_RULES naming a JSON file of schedule rules, the Google
+	// calendar GOOGLE_CALENDAR_ID (primary by default) is polled with
+	// GOOGLE_ACCESS_TOKEN and the Outlook calendar OUTLOOK_CALENDAR_ID with
+	// OUTLOOK_ACCESS_TOKEN every CALENDAR_POLL_INTERVAL (a Go duration, a
+	// minute by default), and the events the rules match fire onSchedule
+	if path := os.Getenv("CALENDAR_RULES"); path != "" {
+		if deltaStorage == nil {
+			sugar.Warnw("Calendars are not polled without delta storage")
+		} else {
+			rules, err := calendar.LoadRules(path)
+			if err != nil {
+				sugar.Fatalw("Failed to load calendar rules", "error", err)
+			}
+			var sources []calendar.Source
+			if token := os.Getenv("GOOGLE_ACCESS_TOKEN"); token != "" {
+				sources = append(sources, calendar.NewGoogleCalendar(gdocs.StaticToken(token), os.Getenv("GOOGLE_CALENDAR_ID")))
+			}
+			if token := os.Getenv("OUTLOOK_ACCESS_TOKEN"); token != "" {
+				sources = append(sources, calendar.NewOutlookCalendar(gdocs.StaticToken(token), os.Getenv("OUTLOOK_CALENDAR_ID")))
+			}
+			if len(sources) == 0 {
+				sugar.Fatalw("CALENDAR_RULES is set but neither GOOGLE_ACCESS_TOKEN nor OUTLOOK_ACCESS_TOKEN is")
+			}
+			interval, err := time.ParseDuration(getEnv("CALENDAR_POLL_INTERVAL", "1m"))
+			if err != nil || interval <= 0 {
+				sugar.Fatalw("Invalid CALENDAR_POLL_INTERVAL", "value", os.Getenv("CALENDAR_POLL_INTERVAL"))
+			}
+			calendarCtx, stopCalendars := context.WithCancel(context.Background())
+			defer stopCalendars()
+			calendars := calendar.NewScheduler(sources, rules, blobs, orchestrator)
+			go calendars.Run(calendarCtx, interval, func(err error) {
+				sugar.Warnw("Failed to poll calendars", "error", err)
+			})
+		}
+	}
+	// With GDOCS_USER_ID set, the Google Docs GDOCS_DOCUMENT_IDS lists,
+	// comma-separated, are imported as that user's blobs with
+	// GOOGLE_ACCESS_TOKEN, and the documents imported are re-imported as
+	// the Drive changes feed, polled every GDOCS_SYNC_INTERVAL (a Go
+	// duration, a minute by default), reports them edited. The feed's
+	// position is kept in GDOCS_PAGE_TOKEN_FILE, if set, across restarts.
+	if docsUser := os.Getenv("GDOCS_USER_ID"); docsUser != "" {
+		token := os.Getenv("GOOGLE_ACCESS_TOKEN")
+		switch {
+		case token == "":
+			sugar.Fatalw("GDOCS_USER_ID is set but GOOGLE_ACCESS_TOKEN is not")
+		case deltaStorage == nil:
+			sugar.Warnw("Google Docs are not synced without delta storage")
+		default:
+			interval, err := time.ParseDuration(getEnv("GDOCS_SYNC_INTERVAL", "1m"))
+			if err != nil || interval <= 0 {
+				sugar.Fatalw("Invalid GDOCS_SYNC_INTERVAL", "value", os.Getenv("GDOCS_SYNC_INTERVAL"))
+			}
+			syncer := gdocs.NewSyncer(gdocs.NewClient(gdocs.StaticToken(token)), blobs, orchestrator, gdocs.SyncConfig{
+				UserID:     docsUser,
+				ProviderID: os.Getenv("GDOCS_PROVIDER_ID"),
+			})
+			tokenFile := os.Getenv("GDOCS_PAGE_TOKEN_FILE")
+			if tokenFile != "" {
+				if data, err := os.ReadFile(tokenFile); err == nil {
+					syncer.SetPageToken(strings.TrimSpace(string(data)))
+				} else if !os.IsNotExist(err) {
+					sugar.Fatalw("Failed to read GDOCS_PAGE_TOKEN_FILE", "error", err)
+				}
+			}
+			syncCtx, stopSyncing := context.WithCancel(context.Background())
+			defer func() {
+				stopSyncing()
+				if tokenFile == "" || syncer.PageToken() == "" {
+					return
+				}
+				if err := os.WriteFile(tokenFile, []byte(syncer.PageToken()), 0o644); err != nil {
+					sugar.Warnw("Failed to save the Google Docs page token", "error", err)
+				}
+			}()
+			go func() {
+				for _, documentID := range splitList(os.Getenv("GDOCS_DOCUMENT_IDS")) {
+					if _, _, err := syncer.ImportDocument(syncCtx, documentID); err != nil {
+						sugar.Warnw("Failed to import Google Doc", "document_id", documentID, "error", err)
+					}
+				}
+				syncer.Run(syncCtx, interval, func(err error) {
+					sugar.Warnw("Failed to sync Google Docs", "error", err)
+				})
+			}()
+		}
+	}
+	// Third-party webhooks store blobs for the providers to process, which
+	// takes delta storage, so they are only served with it
+	webhooks := make(map[string]http.Handler)
+	// With GITHUB_WEBHOOK_SECRET set, GitHub pushes re-import the changed
+	// files of repositories imported for GITHUB_USER_ID, on the branches
+	// GITHUB_WEBHOOK_BRANCHES lists, comma-separated, or on any without it
+	if secret := os.Getenv("GITHUB_WEBHOOK_SECRET"); secret != "" {
+		if deltaStorage == nil {
+			sugar.Warnw("GitHub webhooks are not served without delta storage")
+		} else {
+			importUser := os.Getenv("GITHUB_USER_ID")
+			if importUser == "" {
+				sugar.Fatalw("GITHUB_WEBHOOK_SECRET is set but GITHUB_USER_ID is not")
+			}
+			client := github.NewClient(os.Getenv("GITHUB_TOKEN"))
+			if baseURL := os.Getenv("GITHUB_API_URL"); baseURL != "" {
+				client.WithBaseURL(baseURL)
+			}
+			importer := github.NewImporter(client, blobs, orchestrator, github.ImportConfig{
+				UserID:     importUser,
+				ProviderID: os.Getenv("GITHUB_PROVIDER_ID"),
+			})
+			onError := func(err error) {
+				sugar.Warnw("Failed to import GitHub push", "error", err)
+			}
+			webhooks["github"] = github.WebhookHandler(secret, importer, onError, splitList(os.Getenv("GITHUB_WEBHOOK_BRANCHES"))...)
+		}
+	}
+	// With S3_WEBHOOK_TOKEN set, bucket notifications carrying it ingest
+	// new objects under S3_PREFIX with the S3_EXTENSIONS listed,
+	// comma-separated, as blobs of S3_USER_ID, read from S3_ENDPOINT (AWS
+	// without it) with the standard AWS credentials
+	if token := os.Getenv("S3_WEBHOOK_TOKEN"); token != "" {
+		if deltaStorage == nil {
+			sugar.Warnw("Bucket notifications are not served without delta storage")
+		} else {
+			ingestUser := os.Getenv("S3_USER_ID")
+			if ingestUser == "" {
+				sugar.Fatalw("S3_WEBHOOK_TOKEN is set but S3_USER_ID is not")
+			}
+			creds, err := awsauth.CredentialsFromEnv()
+			if err != nil {
+				sugar.Fatalw("Invalid bucket credentials", "error", err)
+			}
+			client := s3.NewClient(os.Getenv("S3_ENDPOINT"), os.Getenv("AWS_REGION"), creds)
+			ingester := s3.NewIngester(client, blobs, orchestrator, s3.IngestConfig{
+				UserID:     ingestUser,
+				ProviderID: os.Getenv("S3_PROVIDER_ID"),
+				Prefix:     os.Getenv("S3_PREFIX"),
+				Extensions: splitList(os.Getenv("S3_EXTENSIONS")),
+			})
+			webhooks["s3"] = s3.WebhookHandler(token, ingester, func(err error) {
+				sugar.Warnw("Failed to ingest bucket objects", "error", err)
+			})
+		}
+	}
+	// With SLACK_BOT_TOKEN set, execution summaries and failure alerts are
+	// posted to SLACK_DEFAULT_CHANNEL, threaded per blob, and with
+	// SLACK_SIGNING_SECRET set approval button presses are taken at the
+	// slack webhook and published as approval.responded events
+	if token := os.Getenv("SLACK_BOT_TOKEN"); token != "" {
+		notifier := slack.NewNotifier(slack.NewClient(token), os.Getenv("SLACK_DEFAULT_CHANNEL"))
+		notifyCtx, stopNotifying := context.WithCancel(context.Background())
+		defer stopNotifying()
+		if err := bus.Subscribe(notifyCtx, notifier.HandleEvent, workflows.EventFilter{
+			Types: []string{workflows.EventExecutionCompleted, workflows.EventExecutionFailed},
+		}); err != nil {
+			sugar.Fatalw("Failed to subscribe Slack notifications", "error", err)
+		}
+	}
+	if secret := os.Getenv("SLACK_SIGNING_SECRET"); secret != "" {
+		webhooks["slack"] = slack.InteractionHandler(secret, bus)
+	}
+	policies, err := moderation.LoadEngine(os.Getenv("MODERATION_POLICIES"))
+	if err != nil {
+		sugar.Fatalw("Failed to load moderation policies", "error", err)
+	}
+	apiServer := api.NewServer(api.Config{
+		Blobs:       blobs,
+		Artifacts:   artifacts,
+		Deltas:      deltaStorage,
+		Events:      bus,
+		Repos:       repos,
+		Moderation:  policies,
+		Workflows:   workflowService,
+		Providers:   orchestrator,
+		Quotas:      scheduler,
+		Timers:      scheduled,
+		Reprocess:   reprocessor,
+		Trash:       bin,
+		Branches:    branching,
+		Packs:       packService,
+		DeadLetters: letters,
+		Registry:    registry,
+		Latencies:   latencies,
+		Pricing:     pricing,
+		QueueStats:  events.Stats,
+		Projections: projector,
+		Payloads:    payloadLog,
+		DeltaKeys:   deltaKeys,
+		Webhooks:    webhooks,
+		AdminToken:  os.Getenv("ADMIN_TOKEN"),
+		ChaosHeader: chaosHeader,
+	})
+
 	// Create server
 	srv := &http.Server{
 		Addr:         ":" + port,
-		Handler:      setupRoutes(),
+		Handler:      setupRoutes(apiServer, artifacts.Dir(), events, forwarder),
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
@@ -69,22 +692,52 @@ func main() {
 	sugar.Info("Server shutdown complete")
 }
 
-func setupRoutes() http.Handler {
+func setupRoutes(apiServer http.Handler, artifactDir string, events *workflows.MemoryBus, forwarder *forward.Service) http.Handler {
 	mux := http.NewServeMux()
 	
-	// Health check
+	// Health check; the server stays up while the workflow service is
+	// down and executions are queued, but reports itself degraded
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
+		if forwarder != nil && (!forwarder.Healthy() || forwarder.Pending() > 0) {
+			fmt.Fprintf(w, `{"status":"degraded","service":"memmie-studio","version":"1.0.0","queued_executions":%d}`, forwarder.Pending())
+			return
+		}
 		fmt.Fprintf(w, `{"status":"healthy","service":"memmie-studio","version":"1.0.0"}`)
 	})
 
-	// Placeholder for API routes
-	mux.HandleFunc("/api/v1/", func(w http.ResponseWriter, r *http.Request) {
+	// Event queue counters
+	mux.HandleFunc("/metrics/events", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		fmt.Fprintf(w, `{"message":"Memmie Studio API - Coming Soon"}`)
+		json.NewEncoder(w).Encode(events.Stats())
 	})
 
+	// Generated artifacts (audio, images, exports)
+	mux.Handle("/artifacts/", http.StripPrefix("/artifacts/", http.FileServer(http.Dir(artifactDir))))
+
+	// API routes
+	mux.Handle("/api/v1/", apiServer)
+	mux.Handle("/api/v2/", apiServer)
+
 	return mux
-}
\ No newline at end of file
+}
+
+// splitList splits a comma-separated setting, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// getEnv returns an environment variable or a default value
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
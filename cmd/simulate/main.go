@@ -0,0 +1,108 @@
+// Command simulate runs a workflow against the stubbed step outputs of a
+// fixtures file and reports whether each case behaved as expected. It calls
+// no providers or backends. Pointed at executions recorded by a worker with
+// STEP_RECORD_DIR set, it verifies the workflow still maps and orders its
+// steps as it did when they were recorded.
+//
+//	go run ./cmd/simulate -fixtures workflows/simulations/chapter-review.yaml
+//	go run ./cmd/simulate -workflow workflows/my-workflow.yaml -fixtures recordings/my_workflow
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/memmieai/memmie-studio/internal/simulation"
+)
+
+func main() {
+	workflowPath := flag.String("workflow", "", "workflow definition (YAML, or JSON as accepted by the workflows API); defaults to the fixtures' workflow")
+	fixturesPath := flag.String("fixtures", "", "fixtures file of cases to simulate (YAML or JSON), or a directory of them such as recorded executions")
+	caseName := flag.String("case", "", "only run the case with this name")
+	asJSON := flag.Bool("json", false, "print the reports as JSON")
+	flag.Parse()
+
+	if *fixturesPath == "" {
+		log.Fatal("-fixtures is required")
+	}
+	fixtures, err := simulation.LoadFixtures(*fixturesPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *workflowPath == "" {
+		*workflowPath = fixtures.Workflow
+	}
+	if *workflowPath == "" {
+		log.Fatal("-workflow is required when the fixtures do not name one")
+	}
+	workflow, err := simulation.LoadWorkflow(*workflowPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var reports []*simulation.Report
+	passed := true
+	for _, c := range fixtures.Cases {
+		if *caseName != "" && c.Name != *caseName {
+			continue
+		}
+		report, err := simulation.Run(workflow, c)
+		if err != nil {
+			log.Fatalf("%s: %v", c.Name, err)
+		}
+		reports = append(reports, report)
+		passed = passed && report.Passed()
+	}
+	if len(reports) == 0 {
+		log.Fatal("no cases to run")
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(reports); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		for _, report := range reports {
+			printReport(report)
+		}
+	}
+	if !passed {
+		os.Exit(1)
+	}
+}
+
+// printReport writes a readable summary of one case
+func printReport(report *simulation.Report) {
+	result := "PASS"
+	if !report.Passed() {
+		result = "FAIL"
+	}
+	fmt.Printf("%s %s (%s: %s)\n", result, report.Case, report.Workflow, report.Status)
+	if report.Error != "" {
+		fmt.Printf("  error: %s\n", report.Error)
+	}
+	for _, step := range report.Steps {
+		detail := step.Reason
+		if step.Error != "" {
+			detail = step.Error
+		}
+		if step.Attempts > 1 {
+			detail = strings.TrimSpace(fmt.Sprintf("%s (%d attempts)", detail, step.Attempts))
+		}
+		fmt.Println(strings.TrimRight(fmt.Sprintf("  %-10s %s %s", step.Status, step.ID, detail), " "))
+	}
+	for _, warning := range report.Warnings {
+		fmt.Printf("  warning: %s\n", warning)
+	}
+	for _, check := range report.Checks {
+		if !check.Passed {
+			fmt.Printf("  expected %s to be %v, got %v\n", check.Name, check.Expected, check.Actual)
+		}
+	}
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"go.temporal.io/sdk/worker"
+	"go.uber.org/zap"
+
+	"github.com/memmieai/memmie-studio/internal/artifact"
+	"github.com/memmieai/memmie-studio/internal/awsauth"
+	"github.com/memmieai/memmie-studio/internal/backends/temporal"
+	"github.com/memmieai/memmie-studio/internal/blob"
+	"github.com/memmieai/memmie-studio/internal/books"
+	"github.com/memmieai/memmie-studio/internal/chaos"
+	"github.com/memmieai/memmie-studio/internal/dataprofile"
+	"github.com/memmieai/memmie-studio/internal/dedup"
+	"github.com/memmieai/memmie-studio/internal/embeddings"
+	"github.com/memmieai/memmie-studio/internal/integrations/citations"
+	"github.com/memmieai/memmie-studio/internal/integrations/email"
+	"github.com/memmieai/memmie-studio/internal/integrations/gdocs"
+	"github.com/memmieai/memmie-studio/internal/integrations/github"
+	"github.com/memmieai/memmie-studio/internal/integrations/imagegen"
+	"github.com/memmieai/memmie-studio/internal/integrations/notion"
+	"github.com/memmieai/memmie-studio/internal/integrations/papers"
+	"github.com/memmieai/memmie-studio/internal/integrations/slack"
+	"github.com/memmieai/memmie-studio/internal/integrations/tts"
+	"github.com/memmieai/memmie-studio/internal/integrations/whisper"
+	"github.com/memmieai/memmie-studio/internal/langdetect"
+	"github.com/memmieai/memmie-studio/internal/moderation"
+	"github.com/memmieai/memmie-studio/internal/outputlimit"
+	"github.com/memmieai/memmie-studio/internal/payloads"
+	"github.com/memmieai/memmie-studio/internal/preview"
+	"github.com/memmieai/memmie-studio/internal/proposals"
+	"github.com/memmieai/memmie-studio/internal/reviews"
+	"github.com/memmieai/memmie-studio/internal/screenplay"
+	"github.com/memmieai/memmie-studio/internal/similarity"
+	"github.com/memmieai/memmie-studio/internal/simulation"
+	"github.com/memmieai/memmie-studio/internal/style"
+	"github.com/memmieai/memmie-studio/internal/tagging"
+	"github.com/memmieai/memmie-studio/internal/voicenotes"
+	"github.com/memmieai/memmie-studio/internal/workflows"
+	_ "github.com/memmieai/memmie-studio/schemas"
+)
+
+func main() {
+	// Initialize logger
+	logger, err := zap.NewProduction()
+	if err != nil {
+		log.Fatal("Failed to initialize logger:", err)
+	}
+	defer logger.Sync()
+
+	sugar := logger.Sugar()
+
+	// Configuration
+	cfg := temporal.Config{
+		HostPort:  getEnv("TEMPORAL_HOST_PORT", "localhost:7233"),
+		Namespace: getEnv("TEMPORAL_NAMESPACE", "default"),
+		TaskQueue: getEnv("TEMPORAL_TASK_QUEUE", temporal.DefaultTaskQueue),
+	}
+
+	backend, err := temporal.Dial(cfg)
+	if err != nil {
+		sugar.Fatalw("Failed to connect to Temporal", "error", err)
+	}
+	defer backend.Close()
+
+	blobs := langdetect.NewStore(blob.NewClient(getEnv("STATE_SERVICE_URL", "http://localhost:8006")))
+	artifacts := artifact.NewLocalStore(
+		getEnv("ARTIFACT_DIR", "./data/artifacts"),
+		getEnv("ARTIFACT_BASE_URL", "http://localhost:8010/artifacts"),
+	)
+
+	policies, err := moderation.LoadEngine(os.Getenv("MODERATION_POLICIES"))
+	if err != nil {
+		sugar.Fatalw("Failed to load moderation policies", "error", err)
+	}
+	moderator := moderation.NewService(blobs, policies)
+
+	bookService := books.NewService(blobs)
+	registry := workflows.NewStepRegistry()
+	registry.Register(books.OutlineManagerID, books.NewStepExecutor(bookService))
+	registry.Register(style.StepType, style.NewStepExecutor())
+	embedder := newEmbedder()
+	detector := similarity.NewDetector(bookService, blobs, embedder, embeddings.NewMemoryIndex())
+	registry.Register(similarity.StepType, similarity.NewStepExecutor(detector))
+	registry.Register(dedup.StepType, dedup.NewStepExecutor(dedup.NewDetector(blobs, embedder, embeddings.NewMemoryIndex())))
+	registry.Register(dataprofile.StepType, dataprofile.NewStepExecutor(dataprofile.NewService(blobs)))
+	if token := os.Getenv("SLACK_BOT_TOKEN"); token != "" {
+		notifier := slack.NewNotifier(slack.NewClient(token), os.Getenv("SLACK_DEFAULT_CHANNEL"))
+		registry.Register(slack.StepType, slack.NewStepExecutor(notifier))
+	}
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		client := github.NewClient(token)
+		if baseURL := os.Getenv("GITHUB_API_URL"); baseURL != "" {
+			client.WithBaseURL(baseURL)
+		}
+		publisher := github.NewPublisher(client, os.Getenv("GITHUB_DOCS_DIR"))
+		registry.Register(github.StepType, moderation.NewGate(moderator, github.NewStepExecutor(publisher)))
+	}
+	if token := os.Getenv("GOOGLE_ACCESS_TOKEN"); token != "" {
+		registry.Register(gdocs.StepType, gdocs.NewStepExecutor(gdocs.NewClient(gdocs.StaticToken(token))))
+	}
+	if token := os.Getenv("NOTION_TOKEN"); token != "" {
+		exporter := notion.NewExporter(notion.NewClient(token), os.Getenv("NOTION_KEY_PROPERTY"))
+		registry.Register(notion.StepType, moderation.NewGate(moderator, notion.NewStepExecutor(exporter)))
+	}
+	registry.Register(papers.ProviderID, papers.NewStepExecutor(papers.NewFinder(
+		papers.NewArXiv(),
+		papers.NewPubMed(os.Getenv("PUBMED_API_KEY")),
+		papers.NewSemanticScholar(os.Getenv("SEMANTIC_SCHOLAR_API_KEY")),
+	)))
+	var zotero *citations.Zotero
+	if key := os.Getenv("ZOTERO_API_KEY"); key != "" {
+		zotero = citations.NewZotero(key, getEnv("ZOTERO_LIBRARY_TYPE", "user"), os.Getenv("ZOTERO_LIBRARY_ID"))
+	}
+	registry.Register(citations.StepType, citations.NewStepExecutor(zotero, blobs))
+	registry.Register(citations.RecordStepType, citations.NewRecordExecutor())
+	voiceNotes := voicenotes.NewService(blobs, embedder)
+	registry.Register(voicenotes.ClusterStepType, voicenotes.NewClusterExecutor(voiceNotes))
+	registry.Register(voicenotes.DraftStepType, voicenotes.NewDraftExecutor(voiceNotes))
+	proposalService := proposals.NewService(blobs)
+	registry.Register(proposals.ComplianceStepType, proposals.NewComplianceExecutor(proposalService))
+	registry.Register(proposals.BudgetStepType, proposals.NewBudgetExecutor(proposalService))
+	screenplays := screenplay.NewService(blobs)
+	registry.Register(screenplay.FormatStepType, screenplay.NewFormatExecutor(screenplays))
+	registry.Register(screenplay.BreakdownStepType, screenplay.NewBreakdownExecutor())
+	registry.Register(screenplay.CoverageStepType, screenplay.NewCoverageExecutor(screenplays))
+	registry.Register(reviews.StepType, reviews.NewStepExecutor(reviews.NewService(blobs, nil)))
+	registry.Register(moderation.StepType, moderation.NewStepExecutor(moderator))
+	tagger := tagging.NewService(blobs, embedder, embeddings.NewMemoryIndex())
+	registry.Register(tagging.SuggestStepType, tagging.NewSuggestExecutor(tagger))
+	registry.Register(tagging.ApplyStepType, tagging.NewApplyExecutor(tagger))
+	registry.Register(preview.StepType, preview.NewStepExecutor(preview.NewService(blobs, nil)))
+	if transcriber := newTranscriber(); transcriber != nil {
+		registry.Register(whisper.StepType, whisper.NewStepExecutor(transcriber, blobs, nil))
+	}
+	if engines := newTTSEngines(); len(engines) > 0 {
+		registry.Register(tts.StepType, tts.NewStepExecutor(engines, getEnv("TTS_DEFAULT_ENGINE", "openai"), artifacts, blobs))
+	}
+	if generators := newImageGenerators(); len(generators) > 0 {
+		registry.Register(imagegen.StepType, imagegen.NewStepExecutor(generators, getEnv("IMAGE_DEFAULT_ENGINE", "openai"), artifacts, blobs))
+	}
+	sender, err := newEmailSender(os.Getenv("EMAIL_BACKEND"))
+	if err != nil {
+		sugar.Fatalw("Failed to configure email", "error", err)
+	}
+	if sender != nil {
+		registry.Register(email.StepType, moderation.NewGate(moderator, email.NewStepExecutor(sender, email.NewRenderer(), os.Getenv("EMAIL_FROM"))))
+	}
+
+	if dir := os.Getenv("STEP_RECORD_DIR"); dir != "" {
+		recorder := simulation.NewRecorder(dir, func(err error) {
+			sugar.Warnw("Failed to record step", "error", err)
+		})
+		registry.Wrap(recorder.Wrap)
+		sugar.Infow("Recording steps", "dir", dir)
+	}
+	// With STEP_PAYLOAD_DIR set, each step's request and response are
+	// captured there, redacted and capped at STEP_PAYLOAD_MAX_BYTES, for the
+	// server to serve until STEP_PAYLOAD_RETENTION (a Go duration) passes
+	if dir := os.Getenv("STEP_PAYLOAD_DIR"); dir != "" {
+		maxBytes, err := strconv.Atoi(getEnv("STEP_PAYLOAD_MAX_BYTES", "0"))
+		if err != nil || maxBytes < 0 {
+			sugar.Fatalw("Invalid STEP_PAYLOAD_MAX_BYTES", "value", os.Getenv("STEP_PAYLOAD_MAX_BYTES"))
+		}
+		var retention time.Duration
+		if value := os.Getenv("STEP_PAYLOAD_RETENTION"); value != "" {
+			if retention, err = time.ParseDuration(value); err != nil {
+				sugar.Fatalw("Invalid STEP_PAYLOAD_RETENTION", "error", err)
+			}
+		}
+		capture := payloads.NewCapture(payloads.NewFileStore(dir), maxBytes, retention, func(err error) {
+			sugar.Warnw("Failed to capture step payload", "error", err)
+		})
+		registry.Wrap(capture.Wrap)
+		sugar.Infow("Capturing step payloads", "dir", dir)
+	}
+	// Faults wrap the recorder and payload capture so they hold only real
+	// step results
+	if spec, perRequest := os.Getenv("CHAOS"), os.Getenv("CHAOS_HEADER") == "true"; spec != "" || perRequest {
+		var injector *chaos.Injector
+		if spec != "" {
+			faults, err := chaos.Parse(spec)
+			if err != nil {
+				sugar.Fatalw("Invalid CHAOS", "error", err)
+			}
+			injector = chaos.NewInjector(faults)
+		}
+		registry.Wrap(chaos.NewSteps(injector, perRequest).Wrap)
+		sugar.Warnw("Fault injection enabled", "chaos", spec, "per_request", perRequest)
+	}
+	// Output limits wrap everything else so they see the outputs downstream
+	// steps would; steps without max_output_bytes get the worker's default
+	maxOutputBytes, err := strconv.Atoi(getEnv("STEP_MAX_OUTPUT_BYTES", "0"))
+	if err != nil || maxOutputBytes < 0 {
+		sugar.Fatalw("Invalid STEP_MAX_OUTPUT_BYTES", "value", os.Getenv("STEP_MAX_OUTPUT_BYTES"))
+	}
+	outputPolicy := getEnv("STEP_OUTPUT_POLICY", workflows.OutputPolicyTruncate)
+	if !workflows.IsOutputPolicy(outputPolicy) {
+		sugar.Fatalw("Invalid STEP_OUTPUT_POLICY", "value", outputPolicy)
+	}
+	// Outputs are checked against their output_schema_id before limits can
+	// truncate or spill them
+	registry.Wrap(workflows.CheckOutputSchemas)
+	limiter := outputlimit.NewLimiter(outputlimit.Limit{MaxBytes: maxOutputBytes, Policy: outputPolicy}, blobs)
+	registry.Wrap(limiter.Wrap)
+	// Mutex keys wrap everything, so a step holds its key until its output
+	// is final
+	registry.Wrap(workflows.NewKeyedMutex().Wrap)
+	// Steps that set cache_results share their results across workflows,
+	// up to STEP_MEMO_ENTRIES of them, unless STEP_MEMO is off. Hits skip
+	// the mutex, since they change nothing.
+	if os.Getenv("STEP_MEMO") != "off" {
+		entries, err := strconv.Atoi(getEnv("STEP_MEMO_ENTRIES", "0"))
+		if err != nil || entries < 0 {
+			sugar.Fatalw("Invalid STEP_MEMO_ENTRIES", "value", os.Getenv("STEP_MEMO_ENTRIES"))
+		}
+		memo := workflows.NewStepMemo(workflows.NewMemoryStepCache(entries), func(err error) {
+			sugar.Warnw("Step memo failed", "error", err)
+		})
+		registry.Wrap(memo.Wrap)
+	}
+
+	sugar.Infow("Starting Temporal worker",
+		"host_port", cfg.HostPort,
+		"namespace", cfg.Namespace,
+		"task_queue", backend.TaskQueue(),
+		"executors", registry.Keys(),
+	)
+
+	w := backend.NewWorker(registry, worker.Options{})
+	if err := w.Run(worker.InterruptCh()); err != nil {
+		sugar.Fatalw("Worker stopped with error", "error", err)
+	}
+
+	sugar.Info("Worker shutdown complete")
+}
+
+// newTranscriber returns a local Whisper server client when WHISPER_URL is
+// set, the OpenAI API client when OPENAI_API_KEY is set, or nil
+func newTranscriber() whisper.Transcriber {
+	if url := os.Getenv("WHISPER_URL"); url != "" {
+		return whisper.NewLocalClient(url, os.Getenv("WHISPER_MODEL"))
+	}
+	if key := os.Getenv("OPENAI_API_KEY"); key != "" {
+		return whisper.NewAPIClient(key)
+	}
+	return nil
+}
+
+// newEmbedder returns the OpenAI embedder when OPENAI_API_KEY is set and
+// the local hashing embedder otherwise
+func newEmbedder() embeddings.Embedder {
+	if key := os.Getenv("OPENAI_API_KEY"); key != "" {
+		return embeddings.NewOpenAIEmbedder(key, os.Getenv("OPENAI_EMBEDDING_MODEL"))
+	}
+	return embeddings.NewHashEmbedder(0)
+}
+
+// newTTSEngines returns the text-to-speech engines with credentials set
+func newTTSEngines() map[string]tts.Engine {
+	engines := make(map[string]tts.Engine)
+	if key := os.Getenv("OPENAI_API_KEY"); key != "" {
+		engines["openai"] = tts.NewOpenAIEngine(key, os.Getenv("OPENAI_TTS_MODEL"))
+	}
+	if key := os.Getenv("ELEVENLABS_API_KEY"); key != "" {
+		engines["elevenlabs"] = tts.NewElevenLabsEngine(key, os.Getenv("ELEVENLABS_VOICE_ID"))
+	}
+	return engines
+}
+
+// newImageGenerators returns the image generators with credentials set
+func newImageGenerators() map[string]imagegen.Generator {
+	generators := make(map[string]imagegen.Generator)
+	if key := os.Getenv("OPENAI_API_KEY"); key != "" {
+		generators["openai"] = imagegen.NewOpenAIGenerator(key, os.Getenv("OPENAI_IMAGE_MODEL"))
+	}
+	if key := os.Getenv("STABILITY_API_KEY"); key != "" {
+		generators["stability"] = imagegen.NewStabilityGenerator(key)
+	}
+	return generators
+}
+
+// newEmailSender builds the email sender for a backend name, or nil when
+// email is not configured
+func newEmailSender(backend string) (email.Sender, error) {
+	switch backend {
+	case "":
+		return nil, nil
+	case "smtp":
+		port, err := strconv.Atoi(getEnv("SMTP_PORT", "587"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid SMTP_PORT: %w", err)
+		}
+		return email.NewSMTPSender(email.SMTPConfig{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+		}), nil
+	case "ses":
+		creds, err := awsauth.CredentialsFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		return email.NewSESSender(getEnv("AWS_REGION", "us-east-1"), creds), nil
+	default:
+		return nil, fmt.Errorf("unknown email backend %q", backend)
+	}
+}
+
+// getEnv returns an environment variable or a default value
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
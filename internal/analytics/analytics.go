@@ -0,0 +1,392 @@
+// Package analytics derives writing progress from blob delta logs: word
+// counts, velocity, sessions, streaks, and edit heatmaps per chapter
+package analytics
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// DefaultSessionGap is the idle time that ends a writing session
+const DefaultSessionGap = 30 * time.Minute
+
+// Edit is one change to a chapter's word count, derived from a delta that
+// set its content or metadata.word_count
+type Edit struct {
+	ChapterID  string    `json:"chapter_id"`
+	ProviderID string    `json:"provider_id"`
+	Time       time.Time `json:"time"`
+	Before     int       `json:"words_before"`
+	After      int       `json:"words_after"`
+}
+
+// Added returns the words the edit added, or 0
+func (e Edit) Added() int {
+	if e.After > e.Before {
+		return e.After - e.Before
+	}
+	return 0
+}
+
+// Removed returns the words the edit removed, or 0
+func (e Edit) Removed() int {
+	if e.Before > e.After {
+		return e.Before - e.After
+	}
+	return 0
+}
+
+// EditsFromDeltas replays a chapter's delta log into word count edits.
+// Content deltas are counted directly; metadata.word_count deltas are used
+// for chapters whose content changes are not in the log.
+func EditsFromDeltas(chapterID string, deltas []workflows.Delta) []Edit {
+	ordered := append([]workflows.Delta(nil), deltas...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Sequence < ordered[j].Sequence
+	})
+
+	hasContent := false
+	for _, d := range ordered {
+		if isContentPath(d.Path) {
+			hasContent = true
+			break
+		}
+	}
+
+	var edits []Edit
+	words := 0
+	for _, d := range ordered {
+		var after int
+		switch {
+		case isContentPath(d.Path):
+			if d.Type != "delete" {
+				text, _ := d.NewValue.(string)
+				after = len(strings.Fields(text))
+			}
+		case !hasContent && strings.TrimPrefix(d.Path, "/") == "metadata.word_count":
+			if d.Type != "delete" {
+				after = toInt(d.NewValue)
+			}
+		default:
+			continue
+		}
+		if after == words {
+			continue
+		}
+		edits = append(edits, Edit{
+			ChapterID:  chapterID,
+			ProviderID: d.ProviderID,
+			Time:       d.Timestamp,
+			Before:     words,
+			After:      after,
+		})
+		words = after
+	}
+	return edits
+}
+
+// Options controls the dashboard window and grouping
+type Options struct {
+	Since      time.Time      // edits before this are excluded from activity
+	Location   *time.Location // days and hours are bucketed here; defaults to UTC
+	SessionGap time.Duration  // defaults to DefaultSessionGap
+	Now        time.Time      // defaults to time.Now; streaks end here
+}
+
+// Chapter identifies a chapter and its current word count
+type Chapter struct {
+	ID        string `json:"id"`
+	BookID    string `json:"book_id"`
+	Number    int    `json:"chapter_number"`
+	Title     string `json:"chapter_title"`
+	WordCount int    `json:"word_count"`
+}
+
+// ChapterStats is a chapter's activity in the window
+type ChapterStats struct {
+	Chapter
+	Edits        int            `json:"edits"`
+	WordsAdded   int            `json:"words_added"`
+	WordsRemoved int            `json:"words_removed"`
+	LastEdited   *time.Time     `json:"last_edited,omitempty"`
+	Heatmap      map[string]int `json:"heatmap"` // date -> edits
+}
+
+// DayStats is one day's activity
+type DayStats struct {
+	Date         string `json:"date"`
+	Edits        int    `json:"edits"`
+	WordsAdded   int    `json:"words_added"`
+	WordsRemoved int    `json:"words_removed"`
+	NetWords     int    `json:"net_words"`
+}
+
+// Session is a run of edits without an idle gap
+type Session struct {
+	Start        time.Time `json:"start"`
+	End          time.Time `json:"end"`
+	Minutes      float64   `json:"minutes"`
+	Edits        int       `json:"edits"`
+	WordsAdded   int       `json:"words_added"`
+	WordsRemoved int       `json:"words_removed"`
+	WordsPerHour float64   `json:"words_per_hour"`
+	ChapterIDs   []string  `json:"chapter_ids"`
+}
+
+// Velocity summarizes writing speed in the window
+type Velocity struct {
+	WordsPerDay       float64 `json:"words_per_day"`  // net words per active day
+	WordsPerHour      float64 `json:"words_per_hour"` // words added per session hour
+	ActiveDays        int     `json:"active_days"`
+	SessionMinutes    float64 `json:"session_minutes"`
+	AverageSessionMin float64 `json:"average_session_minutes"`
+}
+
+// Streak counts consecutive days with words added
+type Streak struct {
+	Current    int    `json:"current"`
+	Longest    int    `json:"longest"`
+	LastActive string `json:"last_active,omitempty"`
+}
+
+// Dashboard is the writing progress summary for a set of chapters
+type Dashboard struct {
+	Since        time.Time      `json:"since"`
+	Until        time.Time      `json:"until"`
+	TotalWords   int            `json:"total_words"`
+	WordsAdded   int            `json:"words_added"`
+	WordsRemoved int            `json:"words_removed"`
+	ByProvider   map[string]int `json:"words_added_by_provider"`
+	Chapters     []ChapterStats `json:"chapters"`
+	Daily        []DayStats     `json:"daily"`
+	Sessions     []Session      `json:"sessions"`
+	Velocity     Velocity       `json:"velocity"`
+	Streak       Streak         `json:"streak"`
+	HourOfWeek   [7][24]int     `json:"hour_of_week"` // [weekday][hour] -> edits, Sunday first
+}
+
+// Build aggregates chapter edits into a dashboard. edits maps chapter IDs
+// to their edits in time order.
+func Build(chapters []Chapter, edits map[string][]Edit, opts Options) *Dashboard {
+	loc := opts.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	gap := opts.SessionGap
+	if gap <= 0 {
+		gap = DefaultSessionGap
+	}
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	dash := &Dashboard{
+		Since:      opts.Since,
+		Until:      now,
+		ByProvider: make(map[string]int),
+		Chapters:   make([]ChapterStats, 0, len(chapters)),
+		Daily:      []DayStats{},
+		Sessions:   []Session{},
+	}
+
+	// Streaks look at every edit, not just the window
+	activeDays := make(map[string]bool)
+	var windowEdits []Edit
+	for _, chapter := range chapters {
+		// The delta log is more current than metadata.word_count, which
+		// content deltas do not update
+		if chapterEdits := edits[chapter.ID]; len(chapterEdits) > 0 {
+			chapter.WordCount = chapterEdits[len(chapterEdits)-1].After
+		}
+		stats := ChapterStats{Chapter: chapter, Heatmap: make(map[string]int)}
+		dash.TotalWords += chapter.WordCount
+
+		for _, e := range edits[chapter.ID] {
+			day := e.Time.In(loc).Format("2006-01-02")
+			if e.Added() > 0 {
+				activeDays[day] = true
+			}
+			if e.Time.Before(opts.Since) || e.Time.After(now) {
+				continue
+			}
+			windowEdits = append(windowEdits, e)
+
+			stats.Edits++
+			stats.WordsAdded += e.Added()
+			stats.WordsRemoved += e.Removed()
+			stats.Heatmap[day]++
+			edited := e.Time
+			if stats.LastEdited == nil || edited.After(*stats.LastEdited) {
+				stats.LastEdited = &edited
+			}
+		}
+		dash.WordsAdded += stats.WordsAdded
+		dash.WordsRemoved += stats.WordsRemoved
+		dash.Chapters = append(dash.Chapters, stats)
+	}
+
+	sort.SliceStable(windowEdits, func(i, j int) bool {
+		return windowEdits[i].Time.Before(windowEdits[j].Time)
+	})
+
+	days := make(map[string]*DayStats)
+	for _, e := range windowEdits {
+		local := e.Time.In(loc)
+		dash.HourOfWeek[local.Weekday()][local.Hour()]++
+		if added := e.Added(); added > 0 {
+			provider := e.ProviderID
+			if provider == "" {
+				provider = "unknown"
+			}
+			dash.ByProvider[provider] += added
+		}
+
+		date := local.Format("2006-01-02")
+		day, ok := days[date]
+		if !ok {
+			day = &DayStats{Date: date}
+			days[date] = day
+		}
+		day.Edits++
+		day.WordsAdded += e.Added()
+		day.WordsRemoved += e.Removed()
+		day.NetWords = day.WordsAdded - day.WordsRemoved
+	}
+	for _, day := range days {
+		dash.Daily = append(dash.Daily, *day)
+	}
+	sort.Slice(dash.Daily, func(i, j int) bool {
+		return dash.Daily[i].Date < dash.Daily[j].Date
+	})
+
+	dash.Sessions = sessions(windowEdits, gap)
+	dash.Velocity = velocity(dash.Daily, dash.Sessions)
+	dash.Streak = streak(activeDays, now.In(loc))
+	return dash
+}
+
+// sessions splits time-ordered edits wherever the idle time exceeds gap
+func sessions(edits []Edit, gap time.Duration) []Session {
+	result := []Session{}
+	var current *Session
+	chapters := make(map[string]bool)
+
+	flush := func() {
+		if current == nil {
+			return
+		}
+		current.Minutes = current.End.Sub(current.Start).Minutes()
+		if current.Minutes > 0 {
+			current.WordsPerHour = float64(current.WordsAdded) / (current.Minutes / 60)
+		}
+		for id := range chapters {
+			current.ChapterIDs = append(current.ChapterIDs, id)
+		}
+		sort.Strings(current.ChapterIDs)
+		result = append(result, *current)
+	}
+
+	for _, e := range edits {
+		if current == nil || e.Time.Sub(current.End) > gap {
+			flush()
+			current = &Session{Start: e.Time, End: e.Time}
+			chapters = make(map[string]bool)
+		}
+		current.End = e.Time
+		current.Edits++
+		current.WordsAdded += e.Added()
+		current.WordsRemoved += e.Removed()
+		chapters[e.ChapterID] = true
+	}
+	flush()
+	return result
+}
+
+// velocity averages net words over active days and added words over
+// session time
+func velocity(daily []DayStats, sessions []Session) Velocity {
+	var v Velocity
+	net := 0
+	for _, day := range daily {
+		if day.WordsAdded > 0 {
+			v.ActiveDays++
+			net += day.NetWords
+		}
+	}
+	if v.ActiveDays > 0 {
+		v.WordsPerDay = float64(net) / float64(v.ActiveDays)
+	}
+
+	added := 0
+	for _, s := range sessions {
+		v.SessionMinutes += s.Minutes
+		added += s.WordsAdded
+	}
+	if v.SessionMinutes > 0 {
+		v.WordsPerHour = float64(added) / (v.SessionMinutes / 60)
+	}
+	if len(sessions) > 0 {
+		v.AverageSessionMin = v.SessionMinutes / float64(len(sessions))
+	}
+	return v
+}
+
+// streak counts consecutive active days. The current streak survives until
+// the end of the day after the last active day, so it is not broken before
+// the writer has had a chance to write today.
+func streak(active map[string]bool, now time.Time) Streak {
+	var s Streak
+	if len(active) == 0 {
+		return s
+	}
+
+	dates := make([]string, 0, len(active))
+	for date := range active {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+	s.LastActive = dates[len(dates)-1]
+
+	run := 0
+	var previous time.Time
+	for _, date := range dates {
+		day, _ := time.Parse("2006-01-02", date)
+		if run > 0 && day.Sub(previous) == 24*time.Hour {
+			run++
+		} else {
+			run = 1
+		}
+		if run > s.Longest {
+			s.Longest = run
+		}
+		previous = day
+	}
+
+	today, _ := time.Parse("2006-01-02", now.Format("2006-01-02"))
+	if today.Sub(previous) <= 24*time.Hour {
+		s.Current = run
+	}
+	return s
+}
+
+// isContentPath reports whether a delta path targets blob content
+func isContentPath(path string) bool {
+	return strings.TrimPrefix(path, "/") == "content"
+}
+
+// toInt converts a JSON number to an int
+func toInt(value interface{}) int {
+	switch v := value.(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	}
+	return 0
+}
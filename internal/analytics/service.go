@@ -0,0 +1,70 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/memmieai/memmie-studio/internal/books"
+	"github.com/memmieai/memmie-studio/internal/revisions"
+)
+
+// Service builds dashboards for books from their chapters' delta logs
+type Service struct {
+	books   *books.Service
+	history revisions.History
+}
+
+// NewService creates an analytics service
+func NewService(books *books.Service, history revisions.History) *Service {
+	return &Service{books: books, history: history}
+}
+
+// BookDashboard summarizes writing progress on one book
+func (s *Service) BookDashboard(ctx context.Context, userID, bookID string, opts Options) (*Dashboard, error) {
+	chapters, err := s.books.ListChapters(ctx, userID, bookID)
+	if err != nil {
+		return nil, err
+	}
+	return s.build(ctx, chapters, opts)
+}
+
+// UserDashboard summarizes writing progress across all of a user's books.
+// Streaks count a day when any book gained words.
+func (s *Service) UserDashboard(ctx context.Context, userID string, opts Options) (*Dashboard, error) {
+	list, err := s.books.ListBooks(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var chapters []*books.Chapter
+	for _, book := range list {
+		bookChapters, err := s.books.ListChapters(ctx, userID, book.ID)
+		if err != nil {
+			return nil, err
+		}
+		chapters = append(chapters, bookChapters...)
+	}
+	return s.build(ctx, chapters, opts)
+}
+
+// build loads each chapter's delta log and aggregates the edits
+func (s *Service) build(ctx context.Context, chapters []*books.Chapter, opts Options) (*Dashboard, error) {
+	summaries := make([]Chapter, len(chapters))
+	edits := make(map[string][]Edit, len(chapters))
+	for i, chapter := range chapters {
+		summaries[i] = Chapter{
+			ID:        chapter.ID,
+			BookID:    chapter.BookID,
+			Number:    chapter.Number,
+			Title:     chapter.Title,
+			WordCount: chapter.WordCount,
+		}
+
+		deltas, err := s.history.GetByBlobID(ctx, chapter.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load deltas for chapter %s: %w", chapter.ID, err)
+		}
+		edits[chapter.ID] = EditsFromDeltas(chapter.ID, deltas)
+	}
+	return Build(summaries, edits, opts), nil
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// requireAdmin rejects requests without the admin token as a bearer
+// token. Admin endpoints are not served when no token is configured.
+func (s *Server) requireAdmin(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.adminToken == "" {
+			writeError(w, http.StatusNotImplemented, "admin API is not configured")
+			return
+		}
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) != 1 {
+			writeError(w, http.StatusUnauthorized, "admin token is required")
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// exportState handles GET /admin/state, capturing the providers,
+// workflows, trigger mappings, namespace defaults, experiments and event
+// queue counters as one snapshot
+func (s *Server) exportState(w http.ResponseWriter, r *http.Request) {
+	if s.providers == nil {
+		writeError(w, http.StatusNotImplemented, "provider registration is not configured")
+		return
+	}
+	snapshot, err := s.providers.Snapshot(r.Context(), s.registry)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	if s.queueStats != nil {
+		stats := s.queueStats()
+		snapshot.Queues = &stats
+	}
+	writeJSON(w, http.StatusOK, snapshot)
+}
+
+// importState handles POST /admin/state, restoring a snapshot taken by
+// GET /admin/state
+func (s *Server) importState(w http.ResponseWriter, r *http.Request) {
+	if s.providers == nil || s.registry == nil {
+		writeError(w, http.StatusNotImplemented, "provider registration is not configured")
+		return
+	}
+	var snapshot workflows.StateSnapshot
+	if err := decodeJSON(r, &snapshot); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	result, err := s.providers.Restore(r.Context(), s.registry, &snapshot)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
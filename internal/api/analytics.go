@@ -0,0 +1,66 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/memmieai/memmie-studio/internal/analytics"
+)
+
+// bookAnalytics handles GET /books/{bookID}/analytics
+func (s *Server) bookAnalytics(w http.ResponseWriter, r *http.Request) {
+	s.writeDashboard(w, r, func(opts analytics.Options) (*analytics.Dashboard, error) {
+		return s.analytics.BookDashboard(r.Context(), userID(r), mux.Vars(r)["bookID"], opts)
+	})
+}
+
+// writingAnalytics handles GET /analytics/writing, covering all the user's
+// books
+func (s *Server) writingAnalytics(w http.ResponseWriter, r *http.Request) {
+	s.writeDashboard(w, r, func(opts analytics.Options) (*analytics.Dashboard, error) {
+		return s.analytics.UserDashboard(r.Context(), userID(r), opts)
+	})
+}
+
+// writeDashboard parses the dashboard query parameters: days (the window,
+// default 30), tz (an IANA zone for day boundaries, default UTC), and
+// session_gap (idle minutes that end a session)
+func (s *Server) writeDashboard(w http.ResponseWriter, r *http.Request, build func(analytics.Options) (*analytics.Dashboard, error)) {
+	if s.analytics == nil {
+		writeError(w, http.StatusNotImplemented, "delta history is not configured")
+		return
+	}
+
+	query := r.URL.Query()
+	days, err := queryInt(query.Get("days"), 30)
+	if err != nil || days <= 0 {
+		writeError(w, http.StatusBadRequest, "invalid days")
+		return
+	}
+	opts := analytics.Options{Now: time.Now()}
+	if tz := query.Get("tz"); tz != "" {
+		if opts.Location, err = time.LoadLocation(tz); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid tz")
+			return
+		}
+	}
+	if gap := query.Get("session_gap"); gap != "" {
+		minutes, err := strconv.Atoi(gap)
+		if err != nil || minutes <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid session_gap")
+			return
+		}
+		opts.SessionGap = time.Duration(minutes) * time.Minute
+	}
+	opts.Since = opts.Now.AddDate(0, 0, -int(days))
+
+	dashboard, err := build(opts)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, dashboard)
+}
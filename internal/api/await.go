@@ -0,0 +1,232 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/memmieai/memmie-studio/internal/blob"
+	"github.com/memmieai/memmie-studio/internal/forward"
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// defaultBlobAwait is how long an await is held when the request gives no
+// wait
+const defaultBlobAwait = 30 * time.Second
+
+// awaitBlobRequest waits for the executions an event triggered for a blob.
+// Process first processes the blob for the event, waiting for what that
+// starts; otherwise the executions waited for are those still running and
+// those started at or after since. Wait is a Go duration of up to a minute.
+type awaitBlobRequest struct {
+	EventType string     `json:"event_type"`
+	Process   bool       `json:"process"`
+	Since     *time.Time `json:"since"`
+	Wait      string     `json:"wait"`
+}
+
+// awaitBlobResponse is the outcome of the executions waited for: running
+// until each is final, then failed if any did not complete. Queued lists
+// executions started by process that wait for the workflow service to
+// recover, which are not waited for. Blob is the blob as it was last seen.
+type awaitBlobResponse struct {
+	BlobID        string           `json:"blob_id"`
+	EventType     string           `json:"event_type"`
+	Status        string           `json:"status"`
+	Executions    []*executionView `json:"executions"`
+	Queued        []string         `json:"queued,omitempty"`
+	DeltasApplied int              `json:"deltas_applied"`
+	Blob          *blob.Blob       `json:"blob"`
+}
+
+// awaitBlob handles POST /blobs/{blobID}/await, holding the request until
+// every execution the event triggered for the blob is final, including
+// those started while waiting, or the wait runs out. It answers 200 once
+// they are final and 202 when some are still running.
+func (s *Server) awaitBlob(w http.ResponseWriter, r *http.Request) {
+	if s.providers == nil || s.executions == nil {
+		writeError(w, http.StatusNotImplemented, "execution tracking is not configured")
+		return
+	}
+	var req awaitBlobRequest
+	if err := decodeJSON(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.EventType == "" {
+		req.EventType = defaultProcessEvent
+	}
+	if !workflows.IsTriggerEvent(req.EventType) {
+		writeError(w, http.StatusBadRequest, "unknown event_type "+req.EventType)
+		return
+	}
+	if req.Process && req.Since != nil {
+		writeError(w, http.StatusBadRequest, "give process or since, not both")
+		return
+	}
+	wait := defaultBlobAwait
+	if req.Wait != "" {
+		var err error
+		if wait, err = time.ParseDuration(req.Wait); err != nil || wait < 0 || wait > maxBlobWait {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid wait: use a duration of at most %s", maxBlobWait))
+			return
+		}
+	}
+
+	blobID := mux.Vars(r)["blobID"]
+	if _, err := s.blobs.GetBlob(r.Context(), userID(r), blobID); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	// Without since, the executions started from now on are waited for,
+	// which covers what processing starts and anything that triggers in turn
+	awaited := blobAwait{
+		filter:  workflows.ExecutionFilter{UserID: userID(r), BlobID: blobID, EventType: req.EventType},
+		since:   time.Now(),
+		running: make(map[string]bool),
+	}
+	if req.Since != nil {
+		awaited.since = *req.Since
+	}
+	resp := awaitBlobResponse{BlobID: blobID, EventType: req.EventType}
+	if req.Process {
+		executions, err := s.providers.ProcessBlobExecutions(r.Context(), blobID, userID(r), req.EventType)
+		if err != nil && len(executions) == 0 {
+			writeServiceError(w, err)
+			return
+		}
+		for _, ids := range executions {
+			for _, id := range ids {
+				if forward.IsQueuedID(id) {
+					resp.Queued = append(resp.Queued, id)
+				}
+			}
+		}
+		sort.Strings(resp.Queued)
+	} else {
+		running, _ := s.providers.Executions(awaited.filter)
+		for _, record := range running {
+			if !workflows.IsTerminalStatus(record.Status) {
+				awaited.running[record.ExecutionID] = true
+			}
+		}
+	}
+
+	s.awaitExecutions(w, r, awaited, wait)
+	records := awaited.records(s.providers)
+	resp.Status = "completed"
+	resp.Executions = make([]*executionView, 0, len(records))
+	for _, record := range records {
+		view, err := s.execution(r, record.ExecutionID)
+		if err != nil {
+			view = &executionView{
+				ExecutionResponse: &workflows.ExecutionResponse{ExecutionID: record.ExecutionID, Status: record.Status},
+				WorkflowID:        record.WorkflowID,
+				ProviderID:        record.ProviderID,
+				BlobID:            record.BlobID,
+				EventType:         record.EventType,
+				WorkflowVersion:   record.WorkflowVersion,
+				WorkflowDigest:    record.WorkflowDigest,
+				Tracked:           true,
+			}
+		}
+		if record, ok := s.providers.Execution(record.ExecutionID); ok {
+			view.DeltasApplied = record.DeltasApplied
+		}
+		resp.DeltasApplied += view.DeltasApplied
+		resp.Executions = append(resp.Executions, view)
+		switch {
+		case !workflows.IsTerminalStatus(view.Status):
+			resp.Status = "running"
+		case view.Status != "completed" && resp.Status == "completed":
+			resp.Status = "failed"
+		}
+	}
+
+	b, err := s.blobs.GetBlob(r.Context(), userID(r), blobID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	resp.Blob = b
+	status := http.StatusOK
+	if resp.Status == "running" {
+		status = http.StatusAccepted
+	}
+	writeJSON(w, status, resp)
+}
+
+// blobAwait selects the executions an await waits for: those the filter
+// matches that were running when it began or started from since on
+type blobAwait struct {
+	filter  workflows.ExecutionFilter
+	since   time.Time
+	running map[string]bool
+}
+
+// records returns the executions waited for, oldest first
+func (a blobAwait) records(providers *workflows.Orchestrator) []workflows.ExecutionRecord {
+	all, _ := providers.Executions(a.filter)
+	var records []workflows.ExecutionRecord
+	for i := len(all) - 1; i >= 0; i-- {
+		if a.running[all[i].ExecutionID] || !all[i].StartedAt.Before(a.since) {
+			records = append(records, all[i])
+		}
+	}
+	return records
+}
+
+// awaitExecutions holds a request until the executions waited for are all
+// final or the wait runs out, checking again on each execution event for
+// the blob and every blobPollInterval
+func (s *Server) awaitExecutions(w http.ResponseWriter, r *http.Request, awaited blobAwait, wait time.Duration) {
+	ctx, cancel := context.WithTimeout(r.Context(), wait)
+	defer cancel()
+	// Held requests outlive the server's write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Now().Add(wait + 10*time.Second))
+
+	changed := make(chan struct{}, 1)
+	if s.events != nil {
+		s.events.Subscribe(ctx, func(_ context.Context, event workflows.Event) error {
+			if event.BlobID == awaited.filter.BlobID {
+				select {
+				case changed <- struct{}{}:
+				default:
+				}
+			}
+			return nil
+		}, workflows.EventFilter{
+			Types:          []string{workflows.EventExecutionStarted, workflows.EventExecutionCompleted, workflows.EventExecutionFailed},
+			BlobIDPrefixes: []string{awaited.filter.BlobID},
+		})
+	}
+	poll := time.NewTicker(blobPollInterval)
+	defer poll.Stop()
+	for {
+		final := true
+		for _, record := range awaited.records(s.providers) {
+			if workflows.IsTerminalStatus(record.Status) {
+				continue
+			}
+			// Fetching the status records it when it is final
+			if view, err := s.execution(r, record.ExecutionID); err != nil || !workflows.IsTerminalStatus(view.Status) {
+				final = false
+			}
+		}
+		if final {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-changed:
+		case <-poll.C:
+		}
+	}
+}
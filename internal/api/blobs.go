@@ -0,0 +1,336 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/memmieai/memmie-studio/internal/blob"
+	"github.com/memmieai/memmie-studio/internal/revisions"
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// maxBlobWait bounds how long a blob read is held waiting for a change
+const maxBlobWait = time.Minute
+
+// blobPollInterval is how often a held blob read fetches the blob again,
+// for changes no event announces
+const blobPollInterval = time.Second
+
+// getBlob handles GET /blobs/{blobID}. The ETag changes whenever the
+// blob's delta sequence or stored form does, and a request whose
+// If-None-Match still matches gets a 304. With wait, a Go duration of up to
+// a minute, such a request is held until the blob changes, returning it,
+// or the wait runs out.
+func (s *Server) getBlob(w http.ResponseWriter, r *http.Request) {
+	var wait time.Duration
+	if value := r.URL.Query().Get("wait"); value != "" {
+		var err error
+		if wait, err = time.ParseDuration(value); err != nil || wait < 0 || wait > maxBlobWait {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid wait: use a duration of at most %s", maxBlobWait))
+			return
+		}
+	}
+
+	blobID := mux.Vars(r)["blobID"]
+	b, tag, err := s.blobVersion(r.Context(), userID(r), blobID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	match := r.Header.Get("If-None-Match")
+	if wait > 0 && etagMatches(match, tag) {
+		if b, tag, err = s.awaitBlobChange(w, r, blobID, b, tag, wait); err != nil {
+			writeServiceError(w, err)
+			return
+		}
+	}
+
+	w.Header().Set("ETag", tag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if etagMatches(match, tag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	writeJSON(w, http.StatusOK, b)
+}
+
+// blobVersion fetches a blob with its ETag, a quoted delta sequence and
+// hash of the blob. Blobs in the trash are not found.
+func (s *Server) blobVersion(ctx context.Context, user, blobID string) (*blob.Blob, string, error) {
+	b, err := s.blobs.GetBlob(ctx, user, blobID)
+	if err != nil {
+		return nil, "", err
+	}
+	if b.Trashed() {
+		return nil, "", fmt.Errorf("%w: %s is in the trash", blob.ErrNotFound, blobID)
+	}
+	var sequence int64
+	if s.deltas != nil {
+		deltas, err := s.deltas.GetByBlobID(ctx, blobID)
+		if err != nil {
+			return nil, "", err
+		}
+		sequence = revisions.Latest(deltas)
+	}
+	data, err := json.Marshal(b)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to marshal blob: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return b, fmt.Sprintf(`"%d-%s"`, sequence, hex.EncodeToString(sum[:8])), nil
+}
+
+// awaitBlobChange holds a blob read until the blob's ETag differs from tag
+// or the wait runs out, checking again on each event for the blob and
+// every blobPollInterval. It returns the blob as last seen.
+func (s *Server) awaitBlobChange(w http.ResponseWriter, r *http.Request, blobID string, b *blob.Blob, tag string, wait time.Duration) (*blob.Blob, string, error) {
+	ctx, cancel := context.WithTimeout(r.Context(), wait)
+	defer cancel()
+	// Held reads outlive the server's write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Now().Add(wait + 10*time.Second))
+
+	changed := make(chan struct{}, 1)
+	if s.events != nil {
+		// Without a subscription, polling still sees the change
+		s.events.Subscribe(ctx, func(_ context.Context, event workflows.Event) error {
+			if event.BlobID == blobID {
+				select {
+				case changed <- struct{}{}:
+				default:
+				}
+			}
+			return nil
+		}, workflows.EventFilter{BlobIDPrefixes: []string{blobID}})
+	}
+	poll := time.NewTicker(blobPollInterval)
+	defer poll.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return b, tag, nil
+		case <-changed:
+		case <-poll.C:
+		}
+		latest, latestTag, err := s.blobVersion(ctx, userID(r), blobID)
+		if ctx.Err() != nil {
+			return b, tag, nil
+		}
+		if err != nil || latestTag != tag {
+			return latest, latestTag, err
+		}
+	}
+}
+
+// etagMatches reports whether an If-None-Match header names an ETag,
+// comparing weakly as RFC 9110 asks for
+func etagMatches(header, tag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == strings.TrimPrefix(tag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// diffBlob handles GET /blobs/{blobID}/diff. from and to are delta sequence
+// numbers, defaulting to the version before to and the latest version;
+// context sets the unchanged lines around each change. With format=unified
+// the unified diff is returned as text instead of the structured diff.
+func (s *Server) diffBlob(w http.ResponseWriter, r *http.Request) {
+	if s.deltas == nil {
+		writeError(w, http.StatusNotImplemented, "delta history is not configured")
+		return
+	}
+
+	blobID := mux.Vars(r)["blobID"]
+	if _, err := s.blobs.GetBlob(r.Context(), userID(r), blobID); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	deltas, err := s.deltas.GetByBlobID(r.Context(), blobID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	query := r.URL.Query()
+	to, err := queryInt(query.Get("to"), revisions.Latest(deltas))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid to: "+err.Error())
+		return
+	}
+	from, err := queryInt(query.Get("from"), to-1)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid from: "+err.Error())
+		return
+	}
+	if from < 0 {
+		from = 0
+	}
+	context, err := queryInt(query.Get("context"), revisions.DefaultContext)
+	if err != nil || context < 0 {
+		writeError(w, http.StatusBadRequest, "invalid context")
+		return
+	}
+
+	diff, err := revisions.Compare(blobID, deltas, from, to, int(context))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	if query.Get("format") == "unified" {
+		w.Header().Set("Content-Type", "text/x-diff; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(diff.Unified))
+		return
+	}
+	writeJSON(w, http.StatusOK, diff)
+}
+
+// blobState is a blob's state rebuilt from its delta log
+type blobState struct {
+	BlobID         string                 `json:"blob_id"`
+	Sequence       int64                  `json:"sequence"`
+	LatestSequence int64                  `json:"latest_sequence"`
+	State          map[string]interface{} `json:"state"`
+}
+
+// getBlobState handles GET /blobs/{blobID}/state, the blob's state after
+// the deltas up to at_sequence, which defaults to the latest. Sequence 0 is
+// the empty state before the first delta.
+func (s *Server) getBlobState(w http.ResponseWriter, r *http.Request) {
+	if s.deltas == nil {
+		writeError(w, http.StatusNotImplemented, "delta history is not configured")
+		return
+	}
+
+	blobID := mux.Vars(r)["blobID"]
+	if _, err := s.blobs.GetBlob(r.Context(), userID(r), blobID); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	deltas, err := s.deltas.GetByBlobID(r.Context(), blobID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	latest := revisions.Latest(deltas)
+	at, err := queryInt(r.URL.Query().Get("at_sequence"), latest)
+	if err != nil || at < 0 {
+		writeError(w, http.StatusBadRequest, "invalid at_sequence")
+		return
+	}
+	if at > latest {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("sequence %d is past the latest version %d", at, latest))
+		return
+	}
+
+	var processor workflows.DeltaProcessor
+	writeJSON(w, http.StatusOK, blobState{
+		BlobID:         blobID,
+		Sequence:       at,
+		LatestSequence: latest,
+		State:          processor.Replay(deltas, at),
+	})
+}
+
+// deltaList pages through a blob's delta log
+var deltaList = listSpec{
+	key:          "deltas",
+	idField:      "sequence",
+	sortable:     []string{"sequence", "timestamp", "provider_id", "type", "path"},
+	defaultSort:  "sequence",
+	defaultLimit: 100,
+	maxLimit:     500,
+}
+
+// listDeltas handles GET /blobs/{blobID}/deltas, the blob's delta log, by
+// default in sequence order. provider_id, type and path filter it, type
+// taking comma-separated delta types and path, repeated, the paths whose
+// changes to list; since_sequence resumes after a sequence number, such as
+// the next_since_sequence of the previous page.
+func (s *Server) listDeltas(w http.ResponseWriter, r *http.Request) {
+	if s.deltas == nil {
+		writeError(w, http.StatusNotImplemented, "delta history is not configured")
+		return
+	}
+
+	q, err := parseListQuery(r, deltaList)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	query := r.URL.Query()
+	since, err := queryInt(query.Get("since_sequence"), 0)
+	if err != nil || since < 0 {
+		writeError(w, http.StatusBadRequest, "invalid since_sequence")
+		return
+	}
+	var types []string
+	for _, value := range query["type"] {
+		for _, t := range strings.Split(value, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				types = append(types, t)
+			}
+		}
+	}
+	paths, err := queryPaths(query)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	blobID := mux.Vars(r)["blobID"]
+	if _, err := s.blobs.GetBlob(r.Context(), userID(r), blobID); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	deltas, err := s.deltas.GetByBlobID(r.Context(), blobID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	log := revisions.Log(deltas, revisions.LogQuery{
+		ProviderID:    query.Get("provider_id"),
+		Types:         types,
+		Paths:         paths,
+		SinceSequence: since,
+	})
+	page, next, total, err := q.page(log.Deltas)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp := q.response(page, next, total)
+	resp["latest_sequence"] = log.LatestSequence
+	// Pages in sequence order can also be resumed with since_sequence
+	if next != "" && q.sortText == deltaList.defaultSort && len(page) > 0 {
+		if sequence, ok := page[len(page)-1]["sequence"].(float64); ok {
+			resp["next_since_sequence"] = int64(sequence)
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// queryInt parses an integer query parameter, returning fallback when it is
+// absent
+func queryInt(value string, fallback int64) (int64, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/memmieai/memmie-studio/internal/books"
+)
+
+// createBook handles POST /books
+func (s *Server) createBook(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Title       string `json:"title"`
+		Author      string `json:"author"`
+		Genre       string `json:"genre"`
+		Description string `json:"description"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if strings.TrimSpace(req.Title) == "" {
+		writeError(w, http.StatusBadRequest, "title is required")
+		return
+	}
+
+	book, err := s.books.CreateBook(r.Context(), &books.Book{
+		UserID:      userID(r),
+		Title:       req.Title,
+		Author:      req.Author,
+		Genre:       req.Genre,
+		Description: req.Description,
+	})
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, book)
+}
+
+// listBooks handles GET /books
+func (s *Server) listBooks(w http.ResponseWriter, r *http.Request) {
+	list, err := s.books.ListBooks(r.Context(), userID(r))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"books": list})
+}
+
+// getBook handles GET /books/{bookID}
+func (s *Server) getBook(w http.ResponseWriter, r *http.Request) {
+	book, err := s.books.GetBook(r.Context(), userID(r), mux.Vars(r)["bookID"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, book)
+}
+
+// addChapter handles POST /books/{bookID}/chapters
+func (s *Server) addChapter(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Title   string `json:"chapter_title"`
+		Content string `json:"content"`
+		Status  string `json:"status"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	chapter, err := s.books.AddChapter(r.Context(), userID(r), mux.Vars(r)["bookID"], &books.Chapter{
+		Title:   req.Title,
+		Content: req.Content,
+		Status:  req.Status,
+	})
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	s.refreshProjections(r, chapter.ID)
+	writeJSON(w, http.StatusCreated, chapter)
+}
+
+// listChapters handles GET /books/{bookID}/chapters. Content is omitted
+// unless include_content=true.
+func (s *Server) listChapters(w http.ResponseWriter, r *http.Request) {
+	chapters, err := s.books.ListChapters(r.Context(), userID(r), mux.Vars(r)["bookID"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	if r.URL.Query().Get("include_content") != "true" {
+		for _, chapter := range chapters {
+			chapter.Content = ""
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"chapters": chapters})
+}
+
+// reorderChapters handles PUT /books/{bookID}/chapters/order
+func (s *Server) reorderChapters(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ChapterIDs []string `json:"chapter_ids"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	chapters, err := s.books.ReorderChapters(r.Context(), userID(r), mux.Vars(r)["bookID"], req.ChapterIDs)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	for _, chapter := range chapters {
+		chapter.Content = ""
+		s.refreshProjections(r, chapter.ID)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"chapters": chapters})
+}
+
+// getOutline handles GET /books/{bookID}/outline
+func (s *Server) getOutline(w http.ResponseWriter, r *http.Request) {
+	outline, err := s.books.GetOutline(r.Context(), userID(r), mux.Vars(r)["bookID"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, outline)
+}
+
+// updateOutline handles PATCH /books/{bookID}/outline, which the book
+// chapter processing workflow calls with chapter_number and chapter_data
+func (s *Server) updateOutline(w http.ResponseWriter, r *http.Request) {
+	var req map[string]interface{}
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	outline, err := s.books.UpdateOutline(r.Context(), userID(r), mux.Vars(r)["bookID"], books.EntryFromMap(req))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, outline)
+}
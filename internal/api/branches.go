@@ -0,0 +1,117 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/memmieai/memmie-studio/internal/branches"
+)
+
+// createBranchRequest names a branch and the sequence of the blob's delta
+// log it starts from, by default the latest
+type createBranchRequest struct {
+	Name       string `json:"name"`
+	AtSequence int64  `json:"at_sequence"`
+}
+
+// mergeBranchRequest settles conflicts for the branch or the parent, or
+// only works out the merge
+type mergeBranchRequest struct {
+	Resolve string `json:"resolve"`
+	DryRun  bool   `json:"dry_run"`
+}
+
+// createBranch handles POST /blobs/{blobID}/branches
+func (s *Server) createBranch(w http.ResponseWriter, r *http.Request) {
+	if s.branches == nil {
+		writeError(w, http.StatusNotImplemented, "blob branching is not configured")
+		return
+	}
+	var req createBranchRequest
+	if err := decodeJSON(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	branch, err := s.branches.Create(r.Context(), userID(r), mux.Vars(r)["blobID"], req.Name, req.AtSequence)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, branch)
+}
+
+// listBranches handles GET /blobs/{blobID}/branches, newest first
+func (s *Server) listBranches(w http.ResponseWriter, r *http.Request) {
+	if s.branches == nil {
+		writeError(w, http.StatusNotImplemented, "blob branching is not configured")
+		return
+	}
+	blobID := mux.Vars(r)["blobID"]
+	if _, err := s.blobs.GetBlob(r.Context(), userID(r), blobID); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	list, err := s.branches.List(r.Context(), userID(r), blobID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"branches": list})
+}
+
+// getBranch handles GET /branches/{branchID}
+func (s *Server) getBranch(w http.ResponseWriter, r *http.Request) {
+	if s.branches == nil {
+		writeError(w, http.StatusNotImplemented, "blob branching is not configured")
+		return
+	}
+	branch, err := s.branches.Get(r.Context(), userID(r), mux.Vars(r)["branchID"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, branch)
+}
+
+// mergeBranch handles POST /branches/{branchID}/merge. A merge stopped by
+// conflicts answers 409 with the conflicts, having applied nothing.
+func (s *Server) mergeBranch(w http.ResponseWriter, r *http.Request) {
+	if s.branches == nil {
+		writeError(w, http.StatusNotImplemented, "blob branching is not configured")
+		return
+	}
+	var req mergeBranchRequest
+	if err := decodeJSON(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	result, err := s.branches.Merge(r.Context(), userID(r), mux.Vars(r)["branchID"], branches.MergeOptions{
+		Resolve: req.Resolve,
+		DryRun:  req.DryRun,
+	})
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	status := http.StatusOK
+	if !result.Merged && !req.DryRun {
+		status = http.StatusConflict
+	}
+	writeJSON(w, status, result)
+}
+
+// deleteBranch handles DELETE /branches/{branchID}, abandoning the branch
+func (s *Server) deleteBranch(w http.ResponseWriter, r *http.Request) {
+	if s.branches == nil {
+		writeError(w, http.StatusNotImplemented, "blob branching is not configured")
+		return
+	}
+	if err := s.branches.Delete(r.Context(), userID(r), mux.Vars(r)["branchID"]); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/memmieai/memmie-studio/internal/preview"
+)
+
+// maxCards bounds how many cards one request returns
+const maxCards = 100
+
+// getCard handles GET /blobs/{blobID}/card. refresh=true rebuilds the card
+// even when the content has not changed.
+func (s *Server) getCard(w http.ResponseWriter, r *http.Request) {
+	refresh := r.URL.Query().Get("refresh") == "true"
+	card, err := s.cards.Card(r.Context(), userID(r), mux.Vars(r)["blobID"], refresh)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, card)
+}
+
+// listCards handles GET /cards, the cards of the blobs in ids, which takes
+// comma-separated IDs and may be repeated, or else of a provider's blobs
+func (s *Server) listCards(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit, err := queryInt(query.Get("limit"), maxCards)
+	if err != nil || limit <= 0 || limit > maxCards {
+		writeError(w, http.StatusBadRequest, "invalid limit")
+		return
+	}
+
+	var ids []string
+	for _, value := range query["ids"] {
+		for _, id := range strings.Split(value, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				ids = append(ids, id)
+			}
+		}
+	}
+	providerID := query.Get("provider_id")
+	if len(ids) == 0 && providerID == "" {
+		writeError(w, http.StatusBadRequest, "ids or provider_id is required")
+		return
+	}
+	if len(ids) > int(limit) {
+		ids = ids[:limit]
+	}
+
+	var cards []*preview.Card
+	if len(ids) > 0 {
+		cards, err = s.cards.Cards(r.Context(), userID(r), ids)
+	} else {
+		cards, err = s.cards.ProviderCards(r.Context(), userID(r), providerID, int(limit))
+	}
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"cards": cards})
+}
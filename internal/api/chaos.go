@@ -0,0 +1,28 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/memmieai/memmie-studio/internal/chaos"
+)
+
+// chaosFromHeader attaches the fault spec in the X-Chaos header, if any, to
+// the request context
+func chaosFromHeader(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		spec := r.Header.Get(chaos.Header)
+		if spec == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cfg, err := chaos.Parse(spec)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if cfg.Enabled() {
+			r = r.WithContext(chaos.WithInjector(r.Context(), chaos.NewInjector(cfg)))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/memmieai/memmie-studio/internal/integrations/citations"
+)
+
+// citationGraph handles GET /topics/{topicID}/citations/graph. With
+// format=graphml the graph is downloaded as GraphML instead of JSON.
+func (s *Server) citationGraph(w http.ResponseWriter, r *http.Request) {
+	graph, ok := s.buildCitationGraph(w, r)
+	if !ok {
+		return
+	}
+	if r.URL.Query().Get("format") != "graphml" {
+		writeJSON(w, http.StatusOK, graph)
+		return
+	}
+	w.Header().Set("Content-Type", "application/graphml+xml")
+	w.Header().Set("Content-Disposition", `attachment; filename="citations-`+graph.TopicID+`.graphml"`)
+	w.WriteHeader(http.StatusOK)
+	graph.WriteGraphML(w)
+}
+
+// listCitationNodes handles GET /topics/{topicID}/citations/graph/nodes,
+// optionally filtered by kind
+func (s *Server) listCitationNodes(w http.ResponseWriter, r *http.Request) {
+	graph, ok := s.buildCitationGraph(w, r)
+	if !ok {
+		return
+	}
+	kind := r.URL.Query().Get("kind")
+	nodes := []citations.Node{}
+	for _, node := range graph.Nodes {
+		if kind == "" || node.Kind == kind {
+			nodes = append(nodes, node)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"nodes": nodes})
+}
+
+// getCitationNode handles GET /topics/{topicID}/citations/graph/nodes/{nodeID}
+// with the node's edges and neighbors
+func (s *Server) getCitationNode(w http.ResponseWriter, r *http.Request) {
+	graph, ok := s.buildCitationGraph(w, r)
+	if !ok {
+		return
+	}
+	node, edges, neighbors, err := graph.Neighborhood(mux.Vars(r)["nodeID"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"node":      node,
+		"edges":     edges,
+		"neighbors": neighbors,
+	})
+}
+
+// listCitationEdges handles GET /topics/{topicID}/citations/graph/edges,
+// optionally filtered by kind and by a node at either end
+func (s *Server) listCitationEdges(w http.ResponseWriter, r *http.Request) {
+	graph, ok := s.buildCitationGraph(w, r)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	kind, node := query.Get("kind"), query.Get("node")
+	edges := []citations.Edge{}
+	for _, edge := range graph.Edges {
+		if kind != "" && edge.Kind != kind {
+			continue
+		}
+		if node != "" && edge.Source != node && edge.Target != node {
+			continue
+		}
+		edges = append(edges, edge)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"edges": edges})
+}
+
+// buildCitationGraph builds the requested topic's graph. authors=false
+// leaves out author nodes; min_cocitations sets how many documents must
+// cite two sources together to link them, with 0 leaving out co-citation
+// edges. It writes the error response and returns false on failure.
+func (s *Server) buildCitationGraph(w http.ResponseWriter, r *http.Request) (*citations.Graph, bool) {
+	query := r.URL.Query()
+	opts := citations.GraphOptions{Authors: true, MinCoCitations: citations.DefaultMinCoCitations}
+	if value := query.Get("authors"); value != "" {
+		authors, err := strconv.ParseBool(value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid authors")
+			return nil, false
+		}
+		opts.Authors = authors
+	}
+	if value := query.Get("min_cocitations"); value != "" {
+		count, err := strconv.Atoi(value)
+		if err != nil || count < 0 {
+			writeError(w, http.StatusBadRequest, "invalid min_cocitations")
+			return nil, false
+		}
+		opts.MinCoCitations = count
+	}
+
+	graph, err := s.citations.Build(r.Context(), userID(r), mux.Vars(r)["topicID"], opts)
+	if err != nil {
+		writeServiceError(w, err)
+		return nil, false
+	}
+	return graph, true
+}
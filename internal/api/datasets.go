@@ -0,0 +1,41 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// listDatasetRuns handles GET /datasets/{datasetID}/reports, newest first
+func (s *Server) listDatasetRuns(w http.ResponseWriter, r *http.Request) {
+	runs, err := s.reports.Runs(r.Context(), userID(r), mux.Vars(r)["datasetID"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"runs": runs})
+}
+
+// getDatasetReport handles GET /datasets/{datasetID}/reports/{reportID}
+func (s *Server) getDatasetReport(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	report, err := s.reports.Get(r.Context(), userID(r), vars["datasetID"], vars["reportID"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// compareDatasetRuns handles GET /datasets/{datasetID}/reports/compare.
+// base and target are report IDs, defaulting to the latest run and the run
+// before it.
+func (s *Server) compareDatasetRuns(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	comparison, err := s.reports.Compare(r.Context(), userID(r), mux.Vars(r)["datasetID"], query.Get("base"), query.Get("target"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, comparison)
+}
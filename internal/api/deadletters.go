@@ -0,0 +1,106 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// deadLetterList pages through dead letters
+var deadLetterList = listSpec{
+	key:          "dead_letters",
+	idField:      "id",
+	sortable:     []string{"failed_at", "event_type", "stage", "attempts"},
+	defaultSort:  "-failed_at",
+	defaultLimit: 50,
+	maxLimit:     200,
+}
+
+// listDeadLetters handles GET /dead-letters, the user's events that failed,
+// filtered by event_type and stage
+func (s *Server) listDeadLetters(w http.ResponseWriter, r *http.Request) {
+	if s.letters == nil {
+		writeError(w, http.StatusNotImplemented, "dead letters are not configured")
+		return
+	}
+	q, err := parseListQuery(r, deadLetterList)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	query := r.URL.Query()
+	q.writeList(w, s.letters.List(workflows.DeadLetterFilter{
+		UserID:    userID(r),
+		EventType: query.Get("event_type"),
+		Stage:     query.Get("stage"),
+	}), nil)
+}
+
+// getDeadLetter handles GET /dead-letters/{letterID}
+func (s *Server) getDeadLetter(w http.ResponseWriter, r *http.Request) {
+	if s.letters == nil {
+		writeError(w, http.StatusNotImplemented, "dead letters are not configured")
+		return
+	}
+	letter, err := s.deadLetter(r)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, letter)
+}
+
+// replayDeadLetter handles POST /dead-letters/{letterID}/replay. A letter
+// that replays is gone; one that fails again is kept and the failure
+// returned.
+func (s *Server) replayDeadLetter(w http.ResponseWriter, r *http.Request) {
+	if s.letters == nil {
+		writeError(w, http.StatusNotImplemented, "dead letters are not configured")
+		return
+	}
+	letter, err := s.deadLetter(r)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	if err := s.letters.Replay(r.Context(), letter.ID); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"id": letter.ID, "replayed": true})
+}
+
+// deleteDeadLetter handles DELETE /dead-letters/{letterID}
+func (s *Server) deleteDeadLetter(w http.ResponseWriter, r *http.Request) {
+	if s.letters == nil {
+		writeError(w, http.StatusNotImplemented, "dead letters are not configured")
+		return
+	}
+	letter, err := s.deadLetter(r)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	if err := s.letters.Delete(letter.ID); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deadLetter returns the letter a request names; other users' letters are
+// not found
+func (s *Server) deadLetter(r *http.Request) (workflows.DeadLetter, error) {
+	id := mux.Vars(r)["letterID"]
+	letter, err := s.letters.Get(id)
+	if err != nil {
+		return workflows.DeadLetter{}, err
+	}
+	if letter.Event.UserID != userID(r) {
+		return workflows.DeadLetter{}, fmt.Errorf("%w: %s", workflows.ErrDeadLetterNotFound, id)
+	}
+	return letter, nil
+}
@@ -0,0 +1,323 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/memmieai/memmie-studio/internal/blob"
+	"github.com/memmieai/memmie-studio/internal/books"
+	"github.com/memmieai/memmie-studio/internal/branches"
+	"github.com/memmieai/memmie-studio/internal/dataprofile"
+	"github.com/memmieai/memmie-studio/internal/errorcodes"
+	"github.com/memmieai/memmie-studio/internal/export"
+	"github.com/memmieai/memmie-studio/internal/integrations/citations"
+	"github.com/memmieai/memmie-studio/internal/integrations/gitrepo"
+	"github.com/memmieai/memmie-studio/internal/moderation"
+	"github.com/memmieai/memmie-studio/internal/packs"
+	"github.com/memmieai/memmie-studio/internal/projections"
+	"github.com/memmieai/memmie-studio/internal/quotas"
+	"github.com/memmieai/memmie-studio/internal/reprocess"
+	"github.com/memmieai/memmie-studio/internal/reviews"
+	"github.com/memmieai/memmie-studio/internal/revisions"
+	"github.com/memmieai/memmie-studio/internal/timers"
+	"github.com/memmieai/memmie-studio/internal/trash"
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// RequestIDHeader carries a request's ID. The gateway's ID is kept;
+// requests without one are given one. Error responses repeat it.
+const RequestIDHeader = "X-Request-ID"
+
+// TraceResponseHeader carries the traceparent of the trace a request that
+// came without one was given
+const TraceResponseHeader = "traceresponse"
+
+// Error codes for failures clients are expected to handle, from the
+// errorcodes catalog. Other errors carry the snake_case HTTP status text,
+// such as not_found or bad_request.
+const (
+	CodeBlobNotFound      = errorcodes.BlobNotFound
+	CodeWorkflowNotFound  = errorcodes.WorkflowNotFound
+	CodeWorkflowExists    = errorcodes.WorkflowExists
+	CodeWorkflowCycle     = errorcodes.WorkflowCycle
+	CodeInvalidWorkflow   = errorcodes.InvalidWorkflow
+	CodeLintFailed        = errorcodes.LintFailed
+	CodeProviderNotFound  = errorcodes.ProviderNotFound
+	CodeProviderInactive  = errorcodes.ProviderInactive
+	CodeInvalidProvider   = errorcodes.InvalidProvider
+	CodeExecutionNotFound = errorcodes.ExecutionNotFound
+	CodeDeltaConflict     = errorcodes.DeltaConflict
+	CodeQuotaExceeded     = errorcodes.QuotaExceeded
+	CodeBackendError      = errorcodes.BackendError
+	CodeBackendTimeout    = errorcodes.BackendTimeout
+)
+
+// ErrorBody describes a failed request. Every error response is a JSON
+// object with it under "error". Message is for developers; UserMessage is
+// the code's message from the catalog, in Locale, for users.
+type ErrorBody struct {
+	Code        string                 `json:"code"`
+	Message     string                 `json:"message"`
+	UserMessage string                 `json:"user_message,omitempty"`
+	Locale      string                 `json:"locale,omitempty"`
+	Details     map[string]interface{} `json:"details,omitempty"`
+	RequestID   string                 `json:"request_id,omitempty"`
+}
+
+// serviceError maps errors matching err to a status and code
+type serviceError struct {
+	err    error
+	status int
+	code   string
+}
+
+// serviceErrors maps service errors to responses; the first match wins, so
+// specific errors come before those they wrap. An empty code means the
+// status's generic code.
+var serviceErrors = []serviceError{
+	{workflows.ErrWorkflowCycle, http.StatusBadRequest, CodeWorkflowCycle},
+	{workflows.ErrInvalidWorkflow, http.StatusBadRequest, CodeInvalidWorkflow},
+	{workflows.ErrWorkflowNotFound, http.StatusNotFound, CodeWorkflowNotFound},
+	{workflows.ErrWorkflowExists, http.StatusConflict, CodeWorkflowExists},
+	{workflows.ErrProviderNotFound, http.StatusNotFound, CodeProviderNotFound},
+	{workflows.ErrProviderInactive, http.StatusConflict, CodeProviderInactive},
+	{workflows.ErrInvalidProvider, http.StatusBadRequest, CodeInvalidProvider},
+	{workflows.ErrExecutionNotFound, http.StatusNotFound, CodeExecutionNotFound},
+	{workflows.ErrDeltaConflict, http.StatusConflict, CodeDeltaConflict},
+	{blob.ErrNotFound, http.StatusNotFound, CodeBlobNotFound},
+	{quotas.ErrQuotaExceeded, http.StatusTooManyRequests, CodeQuotaExceeded},
+
+	{books.ErrNotFound, http.StatusNotFound, ""},
+	{export.ErrJobNotFound, http.StatusNotFound, ""},
+	{citations.ErrNodeNotFound, http.StatusNotFound, ""},
+	{dataprofile.ErrReportNotFound, http.StatusNotFound, ""},
+	{gitrepo.ErrJobNotFound, http.StatusNotFound, ""},
+	{reviews.ErrThreadNotFound, http.StatusNotFound, ""},
+	{timers.ErrTimerNotFound, http.StatusNotFound, ""},
+	{reprocess.ErrJobNotFound, http.StatusNotFound, ""},
+	{trash.ErrNotInTrash, http.StatusNotFound, ""},
+	{branches.ErrBranchNotFound, http.StatusNotFound, ""},
+	{packs.ErrPackNotFound, http.StatusNotFound, ""},
+	{workflows.ErrNamespaceDefaultsNotFound, http.StatusNotFound, ""},
+	{workflows.ErrExperimentNotFound, http.StatusNotFound, ""},
+	{workflows.ErrFeedbackNotFound, http.StatusNotFound, ""},
+	{workflows.ErrDeadLetterNotFound, http.StatusNotFound, ""},
+	{workflows.ErrTemplateNotFound, http.StatusNotFound, ""},
+	{workflows.ErrRollbackNotFound, http.StatusNotFound, ""},
+	{projections.ErrUnknownProjection, http.StatusNotFound, ""},
+
+	{gitrepo.ErrJobRunning, http.StatusConflict, ""},
+	{timers.ErrTimerFinished, http.StatusConflict, ""},
+	{reprocess.ErrJobRunning, http.StatusConflict, ""},
+	{reprocess.ErrJobFinished, http.StatusConflict, ""},
+	{trash.ErrAlreadyTrashed, http.StatusConflict, ""},
+	{workflows.ErrSubscriptionEnded, http.StatusConflict, ""},
+	{workflows.ErrAlreadyRolledBack, http.StatusConflict, ""},
+	{workflows.ErrRollbackTooDeep, http.StatusConflict, ""},
+	{packs.ErrUnresolvedDependency, http.StatusConflict, ""},
+	{packs.ErrPackInUse, http.StatusConflict, ""},
+
+	{packs.ErrNamespaceNotOwned, http.StatusForbidden, ""},
+
+	{workflows.ErrReplayFailed, http.StatusBadGateway, ""},
+
+	{books.ErrInvalidOrder, http.StatusBadRequest, ""},
+	{books.ErrInvalidEntry, http.StatusBadRequest, ""},
+	{revisions.ErrInvalidRange, http.StatusBadRequest, ""},
+	{export.ErrInvalidRequest, http.StatusBadRequest, ""},
+	{gitrepo.ErrInvalidSource, http.StatusBadRequest, ""},
+	{reviews.ErrInvalidAnchor, http.StatusBadRequest, ""},
+	{reviews.ErrInvalidComment, http.StatusBadRequest, ""},
+	{moderation.ErrInvalidOverride, http.StatusBadRequest, ""},
+	{timers.ErrInvalidTimer, http.StatusBadRequest, ""},
+	{reprocess.ErrInvalidRequest, http.StatusBadRequest, ""},
+	{trash.ErrInvalidSequence, http.StatusBadRequest, ""},
+	{branches.ErrInvalidSequence, http.StatusBadRequest, ""},
+	{branches.ErrInvalidResolution, http.StatusBadRequest, ""},
+	{packs.ErrInvalidManifest, http.StatusBadRequest, ""},
+	{workflows.ErrInvalidNamespaceDefaults, http.StatusBadRequest, ""},
+	{workflows.ErrInvalidExperiment, http.StatusBadRequest, ""},
+	{workflows.ErrInvalidFeedback, http.StatusBadRequest, ""},
+	{workflows.ErrInvalidSnapshot, http.StatusBadRequest, ""},
+	{workflows.ErrInvalidTemplateRequest, http.StatusBadRequest, ""},
+	{workflows.ErrInvalidDeltaBatch, http.StatusBadRequest, ""},
+}
+
+// writeError writes an error response with the status's generic code
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeErrorCode(w, status, "", message, nil)
+}
+
+// writeErrorCode writes an error response. An empty code means the
+// status's generic code.
+func writeErrorCode(w http.ResponseWriter, status int, code, message string, details map[string]interface{}) {
+	if code == "" {
+		code = statusCode(status)
+	}
+	body := ErrorBody{
+		Code:      code,
+		Message:   message,
+		Details:   details,
+		RequestID: w.Header().Get(RequestIDHeader),
+	}
+	localize(&body, responseLocale(w))
+	writeJSON(w, status, map[string]interface{}{"error": body})
+}
+
+// writeServiceError maps a service error to a response
+func writeServiceError(w http.ResponseWriter, err error) {
+	status, body := describeServiceError(err)
+	body.RequestID = w.Header().Get(RequestIDHeader)
+	localize(&body, responseLocale(w))
+	writeJSON(w, status, map[string]interface{}{"error": body})
+}
+
+// localize sets an error's user message from the catalog, filled in from
+// its details. Codes outside the catalog get none.
+func localize(body *ErrorBody, locale string) {
+	body.UserMessage, body.Locale = errorcodes.Message(body.Code, locale, body.Details)
+}
+
+// executionFailure describes an execution's error as an API error, so
+// clients render both the same way
+func executionFailure(execErr *workflows.ExecutionError, locale string) *ErrorBody {
+	if execErr == nil {
+		return nil
+	}
+	body := &ErrorBody{Code: execErr.Code, Message: execErr.Message}
+	if execErr.StepID != "" || len(execErr.Violations) > 0 {
+		body.Details = map[string]interface{}{}
+		if execErr.StepID != "" {
+			body.Details["step_id"] = execErr.StepID
+		}
+		if len(execErr.Violations) > 0 {
+			body.Details["violations"] = execErr.Violations
+		}
+	}
+	if body.Code == "" {
+		body.Code = errorcodes.WorkflowFailed
+	}
+	localize(body, locale)
+	return body
+}
+
+// localeWriter carries the locale negotiated for a request to the error
+// writers, which see only the response
+type localeWriter struct {
+	http.ResponseWriter
+	locale string
+}
+
+// Flush flushes the underlying response, for event streams
+func (w *localeWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack takes over the underlying connection, for WebSocket sessions
+func (w *localeWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response does not support hijacking")
+	}
+	return hijacker.Hijack()
+}
+
+// Unwrap returns the underlying response, for http.ResponseController
+func (w *localeWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// responseLocale returns the locale negotiated for a response
+func responseLocale(w http.ResponseWriter) string {
+	if lw, ok := w.(*localeWriter); ok {
+		return lw.locale
+	}
+	return errorcodes.DefaultLocale
+}
+
+// requestLocale returns the locale of the messages a request prefers
+func requestLocale(r *http.Request) string {
+	return errorcodes.Negotiate(r.Header.Get("Accept-Language"))
+}
+
+// listErrorCodes handles GET /errors, the error code catalog with each
+// code's message template in the request's locale, or in locale when
+// given. Templates fill {param} placeholders from the error's details.
+func (s *Server) listErrorCodes(w http.ResponseWriter, r *http.Request) {
+	locale := requestLocale(r)
+	if requested := r.URL.Query().Get("locale"); requested != "" {
+		locale = errorcodes.Negotiate(requested)
+	}
+	type codeView struct {
+		errorcodes.Entry
+		Message string `json:"message"`
+		Locale  string `json:"locale"`
+	}
+	codes := []codeView{}
+	for _, entry := range errorcodes.Entries() {
+		if scope := r.URL.Query().Get("scope"); scope != "" && entry.Scope != scope {
+			continue
+		}
+		template, in := errorcodes.Template(entry.Code, locale)
+		codes = append(codes, codeView{Entry: entry, Message: template, Locale: in})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"locale":  locale,
+		"locales": errorcodes.Locales(),
+		"codes":   codes,
+	})
+}
+
+// describeServiceError maps a service error to a status and error body.
+// Lint failures list their issues in the details, and invalid workflow
+// definitions their problems. Errors from the workflow backend or State
+// Service that carry an HTTP status are reported as a bad gateway with that
+// status in the details, and timeouts as a gateway timeout; anything else
+// unknown is an internal error.
+func describeServiceError(err error) (int, ErrorBody) {
+	var lintErr *workflows.LintError
+	if errors.As(err, &lintErr) {
+		return http.StatusBadRequest, ErrorBody{Code: CodeLintFailed, Message: err.Error(), Details: map[string]interface{}{"issues": lintErr.Issues}}
+	}
+	var details map[string]interface{}
+	var validationErr *workflows.ValidationError
+	if errors.As(err, &validationErr) {
+		details = map[string]interface{}{"problems": validationErr.Problems}
+	}
+	for _, mapping := range serviceErrors {
+		if errors.Is(err, mapping.err) {
+			code := mapping.code
+			if code == "" {
+				code = statusCode(mapping.status)
+			}
+			return mapping.status, ErrorBody{Code: code, Message: err.Error(), Details: details}
+		}
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusGatewayTimeout, ErrorBody{Code: CodeBackendTimeout, Message: err.Error()}
+	}
+	if status := workflows.ErrorStatus(err); status != 0 {
+		return http.StatusBadGateway, ErrorBody{Code: CodeBackendError, Message: err.Error(), Details: map[string]interface{}{
+			"backend_status": status,
+			"retryable":      workflows.IsRetryable(err),
+		}}
+	}
+	return http.StatusInternalServerError, ErrorBody{Code: statusCode(http.StatusInternalServerError), Message: err.Error()}
+}
+
+// statusCode returns the generic code for a status, its text in snake
+// case
+func statusCode(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return "error"
+	}
+	return strings.ReplaceAll(strings.ToLower(text), " ", "_")
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// maxEstimateBlobs bounds how many blobs one estimate reads
+const maxEstimateBlobs = 1000
+
+// estimateRequest names the blobs to estimate a workflow for: blob_ids,
+// or count blobs of size_bytes bytes or tokens tokens. max_cost_usd, if
+// set, is the cost above which the estimate warns.
+type estimateRequest struct {
+	BlobIDs    []string `json:"blob_ids"`
+	SizeBytes  int      `json:"size_bytes"`
+	Tokens     int      `json:"tokens"`
+	Count      int      `json:"count"`
+	MaxCostUSD float64  `json:"max_cost_usd"`
+}
+
+// estimateResponse is an estimate with whether it is over the caller's
+// budget
+type estimateResponse struct {
+	*workflows.Estimate
+	MaxCostUSD    float64 `json:"max_cost_usd,omitempty"`
+	ExceedsBudget bool    `json:"exceeds_budget"`
+}
+
+// estimateWorkflow handles POST /workflows/{workflowID}/estimate,
+// predicting what running the workflow on the blobs the body describes
+// would cost and how long it would take, before anything is run
+func (s *Server) estimateWorkflow(w http.ResponseWriter, r *http.Request) {
+	if s.registry == nil {
+		writeError(w, http.StatusNotImplemented, "workflow backend is not configured")
+		return
+	}
+	var req estimateRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	switch {
+	case len(req.BlobIDs) == 0 && req.SizeBytes <= 0 && req.Tokens <= 0:
+		writeError(w, http.StatusBadRequest, "blob_ids, size_bytes or tokens is required")
+		return
+	case len(req.BlobIDs) > 0 && (req.SizeBytes != 0 || req.Tokens != 0 || req.Count != 0):
+		writeError(w, http.StatusBadRequest, "blob_ids cannot be combined with size_bytes, tokens or count")
+		return
+	case len(req.BlobIDs) > maxEstimateBlobs:
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("at most %d blob_ids can be estimated at once", maxEstimateBlobs))
+		return
+	case req.SizeBytes < 0 || req.Tokens < 0 || req.Count < 0 || req.MaxCostUSD < 0:
+		writeError(w, http.StatusBadRequest, "size_bytes, tokens, count and max_cost_usd cannot be negative")
+		return
+	}
+
+	workflow, err := s.registry.Get(r.Context(), mux.Vars(r)["workflowID"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	var inputs []workflows.EstimateInput
+	if len(req.BlobIDs) == 0 {
+		inputs = append(inputs, workflows.EstimateInput{SizeBytes: req.SizeBytes, Tokens: req.Tokens, Count: req.Count})
+	}
+	for _, blobID := range req.BlobIDs {
+		b, err := s.blobs.GetBlob(r.Context(), userID(r), blobID)
+		if err != nil {
+			writeServiceError(w, err)
+			return
+		}
+		input := workflows.EstimateInput{BlobID: b.ID, SizeBytes: len(b.Content)}
+		if s.providers != nil && b.NamespaceID != "" {
+			input.Policy = s.providers.EffectiveModelPolicy(r.Context(), userID(r), b.NamespaceID)
+		}
+		inputs = append(inputs, input)
+	}
+
+	estimate, err := s.estimator.Estimate(workflow, inputs)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	resp := estimateResponse{Estimate: estimate, MaxCostUSD: req.MaxCostUSD}
+	if req.MaxCostUSD > 0 && estimate.CostUSD > req.MaxCostUSD {
+		resp.ExceedsBudget = true
+		estimate.Warnings = append(estimate.Warnings, fmt.Sprintf("estimated cost of $%.2f exceeds the budget of $%.2f", estimate.CostUSD, req.MaxCostUSD))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// eventQueueSize bounds the events the bus queues for a stream that is
+// slow to read; the oldest are dropped, so a slow client never holds up
+// processing or other clients and still sees the latest events
+const eventQueueSize = 64
+
+// streamBuffer is the bus buffer of event streams
+var streamBuffer = workflows.SubscriberBuffer{Size: eventQueueSize, Policy: workflows.QueueDropOldest}
+
+// maxSubscribedPaths bounds the paths a request names, or a session
+// follows on one blob
+const maxSubscribedPaths = 50
+
+// eventKeepAlive is how often an idle stream sends a comment, so proxies do
+// not close it
+const eventKeepAlive = 30 * time.Second
+
+// streamBlobEvents handles GET /blobs/{blobID}/events, streaming the blob's
+// processing events as Server-Sent Events until the client disconnects.
+// Each message's event is the event type and its data the event as JSON.
+// type, repeated or comma-separated, limits the stream to those event
+// types. path, repeated, limits it to the delta.applied and
+// delta.batch_applied events changing those paths of the blob, each naming
+// the paths it changed in its data's matched_paths.
+func (s *Server) streamBlobEvents(w http.ResponseWriter, r *http.Request) {
+	if s.events == nil {
+		writeError(w, http.StatusNotImplemented, "event streaming is not configured")
+		return
+	}
+	if _, ok := w.(http.Flusher); !ok {
+		writeError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+
+	filter := workflows.EventFilter{}
+	for _, value := range r.URL.Query()["type"] {
+		for _, t := range strings.Split(value, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				filter.Types = append(filter.Types, t)
+			}
+		}
+	}
+
+	paths, err := queryPaths(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter.Paths = paths
+
+	blobID := mux.Vars(r)["blobID"]
+	if _, err := s.blobs.GetBlob(r.Context(), userID(r), blobID); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	filter.BlobIDPrefixes = []string{blobID}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	queue := make(chan workflows.Event)
+	err = s.events.Subscribe(workflows.WithSubscriberBuffer(ctx, streamBuffer), func(_ context.Context, event workflows.Event) error {
+		// The filter matches blob IDs by prefix
+		if event.BlobID != blobID {
+			return nil
+		}
+		if len(paths) > 0 {
+			var ok bool
+			if event, ok = workflows.PathNotification(paths, event); !ok {
+				return nil
+			}
+		}
+		select {
+		case queue <- event:
+		case <-ctx.Done():
+		}
+		return nil
+	}, filter)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	// Streams outlive the server's write timeout
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-queue:
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// queryPaths reads the delta paths a request names with the repeated path
+// parameter
+func queryPaths(query url.Values) ([]string, error) {
+	var paths []string
+	for _, path := range query["path"] {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) > maxSubscribedPaths {
+		return nil, fmt.Errorf("at most %d paths can be given", maxSubscribedPaths)
+	}
+	return paths, nil
+}
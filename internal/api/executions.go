@@ -0,0 +1,187 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// executionList pages through executions
+var executionList = listSpec{
+	key:          "executions",
+	idField:      "execution_id",
+	sortable:     []string{"started_at", "updated_at", "status", "workflow_id", "provider_id", "blob_id", "execution_id"},
+	defaultSort:  "-started_at",
+	defaultLimit: 50,
+	maxLimit:     200,
+}
+
+// executionView is a backend execution status with what the orchestrator
+// recorded when it started the execution. Only tracked executions are
+// served, so Tracked is always true; it is kept for clients that read it.
+type executionView struct {
+	*workflows.ExecutionResponse
+	WorkflowID      string `json:"workflow_id,omitempty"`
+	ProviderID      string `json:"provider_id,omitempty"`
+	BlobID          string `json:"blob_id,omitempty"`
+	EventType       string `json:"event_type,omitempty"`
+	WorkflowVersion int    `json:"workflow_version,omitempty"`
+	WorkflowDigest  string `json:"workflow_digest,omitempty"`
+	Profile         string `json:"profile,omitempty"`
+	Partial         bool   `json:"partial,omitempty"`
+	DeltasProduced  int    `json:"deltas_produced"`
+	DeltasApplied   int    `json:"deltas_applied"`
+	Tracked         bool   `json:"tracked"`
+	// Failure is the execution's error as an API error, with its message
+	// for users in the request's locale
+	Failure *ErrorBody `json:"failure,omitempty"`
+}
+
+// listExecutions handles GET /executions, the user's tracked executions,
+// by default newest first. blob_id, provider_id, experiment_id and status
+// filter them; since and until bound their start times as RFC 3339
+// timestamps. Statuses are as last seen by the server, so an execution
+// nobody has looked up since it started may show as running.
+func (s *Server) listExecutions(w http.ResponseWriter, r *http.Request) {
+	if s.providers == nil {
+		writeError(w, http.StatusNotImplemented, "execution tracking is not configured")
+		return
+	}
+	q, err := parseListQuery(r, executionList)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	query := r.URL.Query()
+	filter := workflows.ExecutionFilter{
+		UserID:       userID(r),
+		BlobID:       query.Get("blob_id"),
+		ProviderID:   query.Get("provider_id"),
+		ExperimentID: query.Get("experiment_id"),
+		Status:       query.Get("status"),
+	}
+	for name, bound := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		if *bound, err = time.Parse(time.RFC3339, value); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid "+name+": "+err.Error())
+			return
+		}
+	}
+
+	executions, _ := s.providers.Executions(filter)
+	q.writeList(w, executions, nil)
+}
+
+// getExecution handles GET /executions/{executionID}, for polling progress
+func (s *Server) getExecution(w http.ResponseWriter, r *http.Request) {
+	if s.executions == nil {
+		writeError(w, http.StatusNotImplemented, "workflow backend is not configured")
+		return
+	}
+	view, err := s.execution(r, mux.Vars(r)["executionID"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, view)
+}
+
+// getExecutionDefinition handles GET /executions/{executionID}/definition,
+// the workflow definition a tracked execution ran, as it was when the
+// execution started
+func (s *Server) getExecutionDefinition(w http.ResponseWriter, r *http.Request) {
+	if s.providers == nil {
+		writeError(w, http.StatusNotImplemented, "execution tracking is not configured")
+		return
+	}
+	executionID := mux.Vars(r)["executionID"]
+	record, ok := s.providers.Execution(executionID)
+	var definition *workflows.BlobProcessingWorkflow
+	if ok && record.UserID == userID(r) {
+		definition, ok = s.providers.ExecutionDefinition(executionID)
+	}
+	if !ok || definition == nil {
+		writeServiceError(w, fmt.Errorf("%w: %s", workflows.ErrExecutionNotFound, executionID))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"execution_id":     executionID,
+		"workflow_version": record.WorkflowVersion,
+		"workflow_digest":  record.WorkflowDigest,
+		"workflow":         definition,
+	})
+}
+
+// cancelExecution handles POST /executions/{executionID}/cancel. Backends
+// cancel asynchronously, so the execution may still be running when this
+// returns.
+func (s *Server) cancelExecution(w http.ResponseWriter, r *http.Request) {
+	if s.executions == nil {
+		writeError(w, http.StatusNotImplemented, "workflow backend is not configured")
+		return
+	}
+	id := mux.Vars(r)["executionID"]
+	view, err := s.execution(r, id)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	if workflows.IsTerminalStatus(view.Status) {
+		writeError(w, http.StatusConflict, fmt.Sprintf("execution is already %s", view.Status))
+		return
+	}
+	if err := s.executions.CancelExecution(r.Context(), id); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	if updated, err := s.execution(r, id); err == nil {
+		view = updated
+	}
+	writeJSON(w, http.StatusAccepted, view)
+}
+
+// execution fetches an execution's status and adds the orchestrator's
+// record of it. Executions the orchestrator started for another user are
+// not found, and so are those it has no record of, such as executions
+// started before a restart, as their owner is unknown.
+func (s *Server) execution(r *http.Request, executionID string) (*executionView, error) {
+	var record workflows.ExecutionRecord
+	tracked := false
+	if s.providers != nil {
+		record, tracked = s.providers.Execution(executionID)
+	}
+	if !tracked || record.UserID != userID(r) {
+		return nil, fmt.Errorf("%w: %s", workflows.ErrExecutionNotFound, executionID)
+	}
+
+	resp, err := s.executions.GetExecutionStatus(r.Context(), executionID)
+	if err != nil {
+		return nil, err
+	}
+	s.providers.ObserveExecutionStatus(r.Context(), executionID, resp)
+	view := &executionView{
+		ExecutionResponse: resp,
+		WorkflowID:        record.WorkflowID,
+		ProviderID:        record.ProviderID,
+		BlobID:            record.BlobID,
+		EventType:         record.EventType,
+		DeltasApplied:     record.DeltasApplied,
+		Tracked:           tracked,
+		WorkflowVersion:   record.WorkflowVersion,
+		WorkflowDigest:    record.WorkflowDigest,
+		Profile:           record.Profile,
+		Partial:           workflows.IsPartialOutput(resp.Output),
+		Failure:           executionFailure(resp.Error, requestLocale(r)),
+	}
+	if deltas, ok := resp.Output["deltas"].([]interface{}); ok {
+		view.DeltasProduced = len(deltas)
+	}
+	return view, nil
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// experimentRequest sets an experiment's variants
+type experimentRequest struct {
+	Variants []workflows.ExperimentVariant `json:"variants"`
+}
+
+// experimentList pages through experiments
+var experimentList = listSpec{
+	key:          "experiments",
+	idField:      "id",
+	sortable:     []string{"id", "created_at", "updated_at"},
+	defaultSort:  "id",
+	defaultLimit: 100,
+	maxLimit:     500,
+}
+
+// listExperiments handles GET /experiments
+func (s *Server) listExperiments(w http.ResponseWriter, r *http.Request) {
+	if s.providers == nil {
+		writeError(w, http.StatusNotImplemented, "provider registration is not configured")
+		return
+	}
+	q, err := parseListQuery(r, experimentList)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	q.writeList(w, s.providers.ListExperiments(), nil)
+}
+
+// getExperiment handles GET /experiments/{experimentID}
+func (s *Server) getExperiment(w http.ResponseWriter, r *http.Request) {
+	if s.providers == nil {
+		writeError(w, http.StatusNotImplemented, "provider registration is not configured")
+		return
+	}
+	experiment, err := s.providers.Experiment(mux.Vars(r)["experimentID"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, experiment)
+}
+
+// setExperiment handles PUT /experiments/{experimentID}, creating the
+// experiment or replacing its variants and split
+func (s *Server) setExperiment(w http.ResponseWriter, r *http.Request) {
+	if s.providers == nil {
+		writeError(w, http.StatusNotImplemented, "provider registration is not configured")
+		return
+	}
+	var req experimentRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	experiment := &workflows.Experiment{ID: mux.Vars(r)["experimentID"], Variants: req.Variants}
+
+	_, err := s.providers.Experiment(experiment.ID)
+	replaced := err == nil
+	if err := s.providers.SetExperiment(r.Context(), experiment); err != nil {
+		// A missing variant workflow is a problem with the request
+		if errors.Is(err, workflows.ErrWorkflowNotFound) {
+			writeErrorCode(w, http.StatusBadRequest, CodeWorkflowNotFound, err.Error(), nil)
+			return
+		}
+		writeServiceError(w, err)
+		return
+	}
+	status := http.StatusCreated
+	if replaced {
+		status = http.StatusOK
+	}
+	writeJSON(w, status, experiment)
+}
+
+// deleteExperiment handles DELETE /experiments/{experimentID}
+func (s *Server) deleteExperiment(w http.ResponseWriter, r *http.Request) {
+	if s.providers == nil {
+		writeError(w, http.StatusNotImplemented, "provider registration is not configured")
+		return
+	}
+	if err := s.providers.DeleteExperiment(mux.Vars(r)["experimentID"]); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// experimentMetrics handles GET /experiments/{experimentID}/metrics,
+// comparing the variants' executions
+func (s *Server) experimentMetrics(w http.ResponseWriter, r *http.Request) {
+	if s.providers == nil {
+		writeError(w, http.StatusNotImplemented, "provider registration is not configured")
+		return
+	}
+	experimentID := mux.Vars(r)["experimentID"]
+	metrics, err := s.providers.ExperimentMetrics(experimentID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"experiment_id": experimentID,
+		"variants":      metrics,
+	})
+}
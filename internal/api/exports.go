@@ -0,0 +1,67 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/memmieai/memmie-studio/internal/export"
+)
+
+// startExport handles POST /books/{bookID}/exports. The export runs in the
+// background; the response is the queued job.
+func (s *Server) startExport(w http.ResponseWriter, r *http.Request) {
+	if s.exports == nil {
+		writeError(w, http.StatusNotImplemented, "artifact storage is not configured")
+		return
+	}
+
+	var req export.Request
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	job, err := s.exports.Start(r.Context(), userID(r), mux.Vars(r)["bookID"], req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// listExports handles GET /books/{bookID}/exports
+func (s *Server) listExports(w http.ResponseWriter, r *http.Request) {
+	if s.exports == nil {
+		writeError(w, http.StatusNotImplemented, "artifact storage is not configured")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"exports": s.exports.List(userID(r), mux.Vars(r)["bookID"]),
+	})
+}
+
+// getExport handles GET /exports/{jobID}
+func (s *Server) getExport(w http.ResponseWriter, r *http.Request) {
+	if s.exports == nil {
+		writeError(w, http.StatusNotImplemented, "artifact storage is not configured")
+		return
+	}
+	job, err := s.exports.Get(userID(r), mux.Vars(r)["jobID"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// listExportTemplates handles GET /exports/templates
+func (s *Server) listExportTemplates(w http.ResponseWriter, r *http.Request) {
+	templates := make([]export.Template, 0, len(export.Templates))
+	for _, name := range export.TemplateNames() {
+		templates = append(templates, export.Templates[name])
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"formats":   []string{"epub", "pdf", "docx"},
+		"templates": templates,
+	})
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// feedbackRequest rates or comments on an execution's output, optionally
+// about some of the deltas it produced
+type feedbackRequest struct {
+	Rating   string   `json:"rating"`
+	Comment  string   `json:"comment"`
+	DeltaIDs []string `json:"delta_ids"`
+}
+
+// listFeedback handles GET /executions/{executionID}/feedback
+func (s *Server) listFeedback(w http.ResponseWriter, r *http.Request) {
+	if s.providers == nil {
+		writeError(w, http.StatusNotImplemented, "execution tracking is not configured")
+		return
+	}
+	executionID := mux.Vars(r)["executionID"]
+	if err := s.checkExecutionOwner(r, executionID); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	feedback, err := s.providers.ExecutionFeedback(executionID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"feedback": feedback})
+}
+
+// addFeedback handles POST /executions/{executionID}/feedback, a thumbs up
+// or down, a comment, or both
+func (s *Server) addFeedback(w http.ResponseWriter, r *http.Request) {
+	if s.providers == nil {
+		writeError(w, http.StatusNotImplemented, "execution tracking is not configured")
+		return
+	}
+	var req feedbackRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	executionID := mux.Vars(r)["executionID"]
+	if err := s.checkExecutionOwner(r, executionID); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	feedback := &workflows.ExecutionFeedback{
+		ExecutionID: executionID,
+		Rating:      req.Rating,
+		Comment:     req.Comment,
+		DeltaIDs:    req.DeltaIDs,
+	}
+	if err := s.providers.AddFeedback(r.Context(), feedback); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, feedback)
+}
+
+// deleteFeedback handles DELETE /executions/{executionID}/feedback/{feedbackID}
+func (s *Server) deleteFeedback(w http.ResponseWriter, r *http.Request) {
+	if s.providers == nil {
+		writeError(w, http.StatusNotImplemented, "execution tracking is not configured")
+		return
+	}
+	vars := mux.Vars(r)
+	if err := s.checkExecutionOwner(r, vars["executionID"]); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	if err := s.providers.DeleteFeedback(vars["executionID"], vars["feedbackID"]); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// providerFeedback handles GET /providers/{providerID}/feedback, the
+// ratings and comment counts on the provider's executions per workflow
+// version, across users
+func (s *Server) providerFeedback(w http.ResponseWriter, r *http.Request) {
+	if s.providers == nil {
+		writeError(w, http.StatusNotImplemented, "provider registration is not configured")
+		return
+	}
+	providerID := mux.Vars(r)["providerID"]
+	if _, err := s.providers.GetProvider(providerID); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"provider_id": providerID,
+		"workflows":   s.providers.ProviderFeedback(providerID),
+	})
+}
+
+// checkExecutionOwner reports tracked executions the user did not start as
+// not found
+func (s *Server) checkExecutionOwner(r *http.Request, executionID string) error {
+	record, ok := s.providers.Execution(executionID)
+	if !ok || record.UserID != userID(r) {
+		return fmt.Errorf("%w: %s", workflows.ErrExecutionNotFound, executionID)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/memmieai/memmie-studio/internal/integrations/gitrepo"
+)
+
+// startIngestion handles POST /projects/{projectID}/ingestions. The
+// repository is ingested in the background; the response is the queued job.
+func (s *Server) startIngestion(w http.ResponseWriter, r *http.Request) {
+	if s.ingestions == nil {
+		writeError(w, http.StatusNotImplemented, "repository ingestion is not configured")
+		return
+	}
+
+	var req gitrepo.Request
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	job, err := s.ingestions.Start(r.Context(), userID(r), mux.Vars(r)["projectID"], req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// listIngestions handles GET /projects/{projectID}/ingestions
+func (s *Server) listIngestions(w http.ResponseWriter, r *http.Request) {
+	if s.ingestions == nil {
+		writeError(w, http.StatusNotImplemented, "repository ingestion is not configured")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"ingestions": s.ingestions.List(userID(r), mux.Vars(r)["projectID"]),
+	})
+}
+
+// getIngestion handles GET /ingestions/{jobID}
+func (s *Server) getIngestion(w http.ResponseWriter, r *http.Request) {
+	if s.ingestions == nil {
+		writeError(w, http.StatusNotImplemented, "repository ingestion is not configured")
+		return
+	}
+	job, err := s.ingestions.Get(userID(r), mux.Vars(r)["jobID"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
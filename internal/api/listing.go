@@ -0,0 +1,306 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// listSpec describes a list endpoint's items for paging, sorting and
+// projection: the response key, the field that identifies an item and
+// breaks ties between equal sort values, the fields it can be sorted by
+// and its default sort and page sizes
+type listSpec struct {
+	key          string
+	idField      string
+	sortable     []string
+	defaultSort  string
+	defaultLimit int
+	maxLimit     int
+}
+
+// listQuery is the page of a list a request asks for: limit with either
+// cursor or offset, sort as comma-separated fields, each prefixed with - to
+// sort descending, and fields naming the fields to return
+type listQuery struct {
+	spec     listSpec
+	limit    int
+	offset   int
+	cursor   *listCursor
+	sortText string
+	sort     []sortKey
+	fields   []string
+}
+
+// sortKey is a field to sort by
+type sortKey struct {
+	field string
+	desc  bool
+}
+
+// listCursor marks the last item of a page by its sort values, so the next
+// page starts after it even if items were added or removed meanwhile
+type listCursor struct {
+	Sort   string        `json:"s"`
+	Values []interface{} `json:"v"`
+}
+
+// listItem is an item as JSON
+type listItem map[string]interface{}
+
+// parseListQuery reads a list request's paging, sorting and projection
+func parseListQuery(r *http.Request, spec listSpec) (listQuery, error) {
+	query := r.URL.Query()
+	q := listQuery{spec: spec}
+
+	limit, err := queryInt(query.Get("limit"), int64(spec.defaultLimit))
+	if err != nil || limit <= 0 || limit > int64(spec.maxLimit) {
+		return q, fmt.Errorf("limit must be between 1 and %d", spec.maxLimit)
+	}
+	q.limit = int(limit)
+	offset, err := queryInt(query.Get("offset"), 0)
+	if err != nil || offset < 0 {
+		return q, errors.New("invalid offset")
+	}
+	q.offset = int(offset)
+
+	q.sortText = query.Get("sort")
+	if q.sortText == "" {
+		q.sortText = spec.defaultSort
+	}
+	if q.sort, err = parseSort(q.sortText, spec); err != nil {
+		return q, err
+	}
+
+	if value := query.Get("cursor"); value != "" {
+		if q.offset > 0 {
+			return q, errors.New("give cursor or offset, not both")
+		}
+		if q.cursor, err = decodeCursor(value); err != nil {
+			return q, errors.New("invalid cursor")
+		}
+		if q.cursor.Sort != q.sortText {
+			return q, errors.New("cursor is for a different sort")
+		}
+		if len(q.cursor.Values) != len(q.sort) {
+			return q, errors.New("invalid cursor")
+		}
+	}
+
+	for _, value := range query["fields"] {
+		for _, field := range strings.Split(value, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				q.fields = append(q.fields, field)
+			}
+		}
+	}
+	return q, nil
+}
+
+// parseSort reads sort fields, adding the ID field to break ties
+func parseSort(value string, spec listSpec) ([]sortKey, error) {
+	var keys []sortKey
+	hasID := false
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		key := sortKey{field: strings.TrimPrefix(field, "-"), desc: strings.HasPrefix(field, "-")}
+		if !contains(spec.sortable, key.field) {
+			return nil, fmt.Errorf("cannot sort by %q; sortable fields are %s", key.field, strings.Join(spec.sortable, ", "))
+		}
+		hasID = hasID || key.field == spec.idField
+		keys = append(keys, key)
+	}
+	if !hasID {
+		keys = append(keys, sortKey{field: spec.idField})
+	}
+	return keys, nil
+}
+
+// page sorts items, which must encode to JSON objects, and returns the
+// requested page with only the requested fields, the cursor for the next
+// page or "" on the last, and how many items there are in all
+func (q listQuery) page(items interface{}) ([]listItem, string, int, error) {
+	data, err := json.Marshal(items)
+	if err != nil {
+		return nil, "", 0, fmt.Errorf("failed to encode list: %w", err)
+	}
+	var all []listItem
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, "", 0, fmt.Errorf("failed to decode list: %w", err)
+	}
+
+	sort.SliceStable(all, func(i, j int) bool { return q.compare(q.values(all[i]), q.values(all[j])) < 0 })
+	start := q.offset
+	if q.cursor != nil {
+		start = sort.Search(len(all), func(i int) bool { return q.compare(q.values(all[i]), q.cursor.Values) > 0 })
+	}
+	if start > len(all) {
+		start = len(all)
+	}
+	end := start + q.limit
+	if end > len(all) {
+		end = len(all)
+	}
+
+	page := make([]listItem, 0, end-start)
+	for _, item := range all[start:end] {
+		page = append(page, q.project(item))
+	}
+	next := ""
+	if end < len(all) && end > 0 {
+		next = encodeCursor(listCursor{Sort: q.sortText, Values: q.values(all[end-1])})
+	}
+	return page, next, len(all), nil
+}
+
+// writeList writes a page of items under the spec's key with the paging
+// details and any extra fields
+func (q listQuery) writeList(w http.ResponseWriter, items interface{}, extra map[string]interface{}) {
+	page, next, total, err := q.page(items)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp := q.response(page, next, total)
+	for key, value := range extra {
+		resp[key] = value
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// response builds a list response from a page
+func (q listQuery) response(page []listItem, next string, total int) map[string]interface{} {
+	resp := map[string]interface{}{
+		q.spec.key: page,
+		"total":    total,
+		"limit":    q.limit,
+	}
+	if q.cursor == nil {
+		resp["offset"] = q.offset
+	}
+	if next != "" {
+		resp["next_cursor"] = next
+	}
+	return resp
+}
+
+// values returns an item's sort values
+func (q listQuery) values(item listItem) []interface{} {
+	values := make([]interface{}, len(q.sort))
+	for i, key := range q.sort {
+		values[i] = item[key.field]
+	}
+	return values
+}
+
+// compare orders two items' sort values
+func (q listQuery) compare(a, b []interface{}) int {
+	for i, key := range q.sort {
+		c := compareValues(a[i], b[i])
+		if key.desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// project keeps only the requested fields of an item
+func (q listQuery) project(item listItem) listItem {
+	if len(q.fields) == 0 {
+		return item
+	}
+	projected := make(listItem, len(q.fields))
+	for _, field := range q.fields {
+		if value, ok := item[field]; ok {
+			projected[field] = value
+		}
+	}
+	return projected
+}
+
+// compareValues orders JSON values: missing and null first, then booleans,
+// numbers and strings, with RFC 3339 timestamps compared as times
+func compareValues(a, b interface{}) int {
+	rank := func(v interface{}) int {
+		switch v.(type) {
+		case nil:
+			return 0
+		case bool:
+			return 1
+		case float64:
+			return 2
+		case string:
+			return 3
+		}
+		return 4
+	}
+	if ra, rb := rank(a), rank(b); ra != rb {
+		return ra - rb
+	}
+	switch x := a.(type) {
+	case bool:
+		y := b.(bool)
+		switch {
+		case x == y:
+			return 0
+		case !x:
+			return -1
+		}
+		return 1
+	case float64:
+		y := b.(float64)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	case string:
+		y := b.(string)
+		if tx, err := time.Parse(time.RFC3339Nano, x); err == nil {
+			if ty, err := time.Parse(time.RFC3339Nano, y); err == nil {
+				return tx.Compare(ty)
+			}
+		}
+		return strings.Compare(x, y)
+	}
+	return 0
+}
+
+// encodeCursor encodes a cursor as an opaque token
+func encodeCursor(cursor listCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor reads a token made by encodeCursor
+func decodeCursor(token string) (*listCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, err
+	}
+	var cursor listCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
+
+// contains reports whether values includes value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// overrideRequest gives the reason blocked content may be published anyway
+type overrideRequest struct {
+	Reason string `json:"reason"`
+	Target string `json:"target,omitempty"` // what it is being published to
+}
+
+// checkBlob handles GET /blobs/{blobID}/moderation
+func (s *Server) checkBlob(w http.ResponseWriter, r *http.Request) {
+	result, err := s.moderation.CheckBlob(r.Context(), userID(r), mux.Vars(r)["blobID"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// overrideBlob handles POST /blobs/{blobID}/moderation/overrides. The blob
+// is checked again and the override covers what blocks it now.
+func (s *Server) overrideBlob(w http.ResponseWriter, r *http.Request) {
+	var req overrideRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	blobID := mux.Vars(r)["blobID"]
+	result, err := s.moderation.CheckBlob(r.Context(), userID(r), blobID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	target := req.Target
+	if target == "" {
+		target = "publish"
+	}
+	override, err := s.moderation.Override(r.Context(), userID(r), blobID, target, result, req.Reason)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, override)
+}
+
+// listOverrides handles GET /blobs/{blobID}/moderation/overrides, the audit
+// trail of a blob's overrides. A book's export overrides are listed under
+// the book's ID.
+func (s *Server) listOverrides(w http.ResponseWriter, r *http.Request) {
+	overrides, err := s.moderation.Overrides(r.Context(), userID(r), mux.Vars(r)["blobID"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"overrides": overrides})
+}
+
+// overrideExport handles POST /exports/{jobID}/override. Start the export
+// again once the override is recorded.
+func (s *Server) overrideExport(w http.ResponseWriter, r *http.Request) {
+	if s.exports == nil {
+		writeError(w, http.StatusNotImplemented, "artifact storage is not configured")
+		return
+	}
+	var req overrideRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	override, err := s.exports.Override(r.Context(), userID(r), mux.Vars(r)["jobID"], req.Reason)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, override)
+}
+
+// listModerationPolicies handles GET /moderation/policies
+func (s *Server) listModerationPolicies(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"policies": s.moderation.Engine().Policies(),
+	})
+}
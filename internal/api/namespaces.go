@@ -0,0 +1,105 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// namespaceDefaultsRequest sets a namespace's default providers
+type namespaceDefaultsRequest struct {
+	AllowSubNamespaces bool                           `json:"allow_sub_namespaces"`
+	NoInherit          bool                           `json:"no_inherit"`
+	Providers          []workflows.ProviderAttachment `json:"providers"`
+	OptOut             []string                       `json:"opt_out"`
+	ModelPolicy        *workflows.ModelPolicy         `json:"model_policy"`
+}
+
+// getNamespaceDefaults handles GET /namespaces/{namespaceID}/defaults
+func (s *Server) getNamespaceDefaults(w http.ResponseWriter, r *http.Request) {
+	if s.providers == nil {
+		writeError(w, http.StatusNotImplemented, "provider registration is not configured")
+		return
+	}
+	defaults, err := s.providers.NamespaceDefaults(userID(r), mux.Vars(r)["namespaceID"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, defaults)
+}
+
+// setNamespaceDefaults handles PUT /namespaces/{namespaceID}/defaults,
+// replacing the namespace's default providers and model policy
+func (s *Server) setNamespaceDefaults(w http.ResponseWriter, r *http.Request) {
+	if s.providers == nil {
+		writeError(w, http.StatusNotImplemented, "provider registration is not configured")
+		return
+	}
+	var req namespaceDefaultsRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	defaults := &workflows.NamespaceDefaults{
+		NamespaceID:        mux.Vars(r)["namespaceID"],
+		UserID:             userID(r),
+		AllowSubNamespaces: req.AllowSubNamespaces,
+		NoInherit:          req.NoInherit,
+		Providers:          req.Providers,
+		OptOut:             req.OptOut,
+		ModelPolicy:        req.ModelPolicy,
+	}
+	if defaults.Providers == nil {
+		defaults.Providers = []workflows.ProviderAttachment{}
+	}
+	if err := s.providers.SetNamespaceDefaults(defaults); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, defaults)
+}
+
+// deleteNamespaceDefaults handles DELETE /namespaces/{namespaceID}/defaults
+func (s *Server) deleteNamespaceDefaults(w http.ResponseWriter, r *http.Request) {
+	if s.providers == nil {
+		writeError(w, http.StatusNotImplemented, "provider registration is not configured")
+		return
+	}
+	if err := s.providers.DeleteNamespaceDefaults(userID(r), mux.Vars(r)["namespaceID"]); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// namespaceProviders handles GET /namespaces/{namespaceID}/providers, the
+// providers attached to the namespace's blobs, its own and inherited
+func (s *Server) namespaceProviders(w http.ResponseWriter, r *http.Request) {
+	if s.providers == nil {
+		writeError(w, http.StatusNotImplemented, "provider registration is not configured")
+		return
+	}
+	namespaceID := mux.Vars(r)["namespaceID"]
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"namespace_id": namespaceID,
+		"providers":    s.providers.EffectiveAttachments(r.Context(), userID(r), namespaceID),
+	})
+}
+
+// namespaceModelPolicy handles GET /namespaces/{namespaceID}/model-policy,
+// the model policy the namespace's AI steps run under, its own merged with
+// those inherited. The policy is null when none applies.
+func (s *Server) namespaceModelPolicy(w http.ResponseWriter, r *http.Request) {
+	if s.providers == nil {
+		writeError(w, http.StatusNotImplemented, "provider registration is not configured")
+		return
+	}
+	namespaceID := mux.Vars(r)["namespaceID"]
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"namespace_id": namespaceID,
+		"model_policy": s.providers.EffectiveModelPolicy(r.Context(), userID(r), namespaceID),
+	})
+}
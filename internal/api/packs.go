@@ -0,0 +1,86 @@
+package api
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/memmieai/memmie-studio/internal/packs"
+)
+
+// installPack handles POST /namespaces/{namespaceID}/packs, installing the
+// pack whose manifest is the body, or moving the installed pack to its
+// version
+func (s *Server) installPack(w http.ResponseWriter, r *http.Request) {
+	if s.packs == nil {
+		writeError(w, http.StatusNotImplemented, "workflow packs are not configured")
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	manifest, err := packs.ParseManifest(data)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	installation, err := s.packs.Install(r.Context(), userID(r), mux.Vars(r)["namespaceID"], manifest)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	status := http.StatusCreated
+	if !installation.UpdatedAt.Equal(installation.InstalledAt) {
+		status = http.StatusOK
+	}
+	writeJSON(w, status, installation)
+}
+
+// listPacks handles GET /namespaces/{namespaceID}/packs
+func (s *Server) listPacks(w http.ResponseWriter, r *http.Request) {
+	if s.packs == nil {
+		writeError(w, http.StatusNotImplemented, "workflow packs are not configured")
+		return
+	}
+	namespaceID := mux.Vars(r)["namespaceID"]
+	list, err := s.packs.List(r.Context(), userID(r), namespaceID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"namespace_id": namespaceID, "packs": list})
+}
+
+// getPack handles GET /namespaces/{namespaceID}/packs/{name}
+func (s *Server) getPack(w http.ResponseWriter, r *http.Request) {
+	if s.packs == nil {
+		writeError(w, http.StatusNotImplemented, "workflow packs are not configured")
+		return
+	}
+	vars := mux.Vars(r)
+	installation, err := s.packs.Get(r.Context(), userID(r), vars["namespaceID"], vars["name"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, installation)
+}
+
+// uninstallPack handles DELETE /namespaces/{namespaceID}/packs/{name},
+// removing what the pack registered. Packs other installed packs require
+// answer 409.
+func (s *Server) uninstallPack(w http.ResponseWriter, r *http.Request) {
+	if s.packs == nil {
+		writeError(w, http.StatusNotImplemented, "workflow packs are not configured")
+		return
+	}
+	vars := mux.Vars(r)
+	if err := s.packs.Uninstall(r.Context(), userID(r), vars["namespaceID"], vars["name"]); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/memmieai/memmie-studio/internal/payloads"
+)
+
+// listPayloads handles GET /executions/{executionID}/payloads, the
+// redacted requests and responses of the execution's steps still within
+// their retention. A step_id query parameter narrows them to one step.
+func (s *Server) listPayloads(w http.ResponseWriter, r *http.Request) {
+	if s.payloads == nil {
+		writeError(w, http.StatusNotImplemented, "step payload capture is not configured")
+		return
+	}
+	executionID := mux.Vars(r)["executionID"]
+	// Payloads are only listed for their own user in any case; the
+	// execution is looked up so unknown ones are not found
+	if s.executions != nil {
+		if _, err := s.execution(r, executionID); err != nil {
+			writeServiceError(w, err)
+			return
+		}
+	}
+	captured, err := s.payloads.List(r.Context(), userID(r), executionID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	if stepID := r.URL.Query().Get("step_id"); stepID != "" {
+		kept := make([]*payloads.Payload, 0, len(captured))
+		for _, payload := range captured {
+			if payload.StepID == stepID {
+				kept = append(kept, payload)
+			}
+		}
+		captured = kept
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"execution_id": executionID,
+		"payloads":     captured,
+	})
+}
@@ -0,0 +1,180 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/memmieai/memmie-studio/internal/forward"
+	"github.com/memmieai/memmie-studio/internal/timers"
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// defaultProcessEvent is the event a blob is processed for when the request
+// names none
+const defaultProcessEvent = "onUpdate"
+
+// processBlobRequest names the event to process a blob for and, to run it
+// later, when: not_before is a time and delay a duration such as "8h"
+type processBlobRequest struct {
+	EventType string     `json:"event_type"`
+	NotBefore *time.Time `json:"not_before"`
+	Delay     string     `json:"delay"`
+}
+
+// processBlobResponse lists the workflow executions started for each
+// provider, or the run scheduled for later. Queued lists the executions
+// waiting for the workflow service to recover.
+type processBlobResponse struct {
+	BlobID     string              `json:"blob_id"`
+	EventType  string              `json:"event_type"`
+	Executions map[string][]string `json:"executions"`
+	Queued     []string            `json:"queued,omitempty"`
+	Scheduled  *timers.Timer       `json:"scheduled,omitempty"`
+	Error      string              `json:"error,omitempty"`
+}
+
+// processBlob handles POST /blobs/{blobID}/process, running the blob through
+// the providers triggered by the event. Executions run asynchronously, so
+// this only reports which were started.
+func (s *Server) processBlob(w http.ResponseWriter, r *http.Request) {
+	if s.providers == nil {
+		writeError(w, http.StatusNotImplemented, "blob processing is not configured")
+		return
+	}
+	var req processBlobRequest
+	if err := decodeJSON(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.EventType == "" {
+		req.EventType = defaultProcessEvent
+	}
+	if !workflows.IsTriggerEvent(req.EventType) {
+		writeError(w, http.StatusBadRequest, "unknown event_type "+req.EventType)
+		return
+	}
+
+	blobID := mux.Vars(r)["blobID"]
+	if _, err := s.blobs.GetBlob(r.Context(), userID(r), blobID); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	now := time.Now()
+	notBefore, err := req.runAt(now)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if notBefore.After(now) {
+		if s.timers == nil {
+			writeError(w, http.StatusNotImplemented, "scheduled processing is not configured")
+			return
+		}
+		timer, err := s.timers.ProcessBlobAt(r.Context(), blobID, userID(r), req.EventType, notBefore)
+		if err != nil {
+			writeServiceError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, processBlobResponse{
+			BlobID:     blobID,
+			EventType:  req.EventType,
+			Executions: map[string][]string{},
+			Scheduled:  timer,
+		})
+		return
+	}
+
+	executions, err := s.providers.ProcessBlobExecutions(r.Context(), blobID, userID(r), req.EventType)
+	resp := processBlobResponse{BlobID: blobID, EventType: req.EventType, Executions: executions}
+	if resp.Executions == nil {
+		resp.Executions = map[string][]string{}
+	}
+	for _, ids := range resp.Executions {
+		for _, id := range ids {
+			if forward.IsQueuedID(id) {
+				resp.Queued = append(resp.Queued, id)
+			}
+		}
+	}
+	sort.Strings(resp.Queued)
+	if err != nil {
+		if len(executions) == 0 {
+			writeServiceError(w, err)
+			return
+		}
+		// Some providers started, so say which alongside the failure
+		resp.Error = err.Error()
+		writeJSON(w, http.StatusBadGateway, resp)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, resp)
+}
+
+// runAt returns when a request asks to be run; times not after now, and
+// the zero time, mean run now
+func (req processBlobRequest) runAt(now time.Time) (time.Time, error) {
+	switch {
+	case req.NotBefore != nil && req.Delay != "":
+		return time.Time{}, errors.New("give not_before or delay, not both")
+	case req.NotBefore != nil:
+		return *req.NotBefore, nil
+	case req.Delay != "":
+		delay, err := time.ParseDuration(req.Delay)
+		if err != nil || delay < 0 {
+			return time.Time{}, fmt.Errorf("invalid delay %q", req.Delay)
+		}
+		return now.Add(delay), nil
+	}
+	return time.Time{}, nil
+}
+
+// listScheduled handles GET /scheduled, the user's scheduled processing
+// runs soonest first, optionally for one blob_id
+func (s *Server) listScheduled(w http.ResponseWriter, r *http.Request) {
+	if s.timers == nil {
+		writeError(w, http.StatusNotImplemented, "scheduled processing is not configured")
+		return
+	}
+	scheduled, err := s.timers.List(r.Context(), userID(r), r.URL.Query().Get("blob_id"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"scheduled": scheduled})
+}
+
+// getScheduled handles GET /scheduled/{timerID}
+func (s *Server) getScheduled(w http.ResponseWriter, r *http.Request) {
+	if s.timers == nil {
+		writeError(w, http.StatusNotImplemented, "scheduled processing is not configured")
+		return
+	}
+	timer, err := s.timers.Get(r.Context(), userID(r), mux.Vars(r)["timerID"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, timer)
+}
+
+// cancelScheduled handles DELETE /scheduled/{timerID}, cancelling a run
+// that has not fired yet
+func (s *Server) cancelScheduled(w http.ResponseWriter, r *http.Request) {
+	if s.timers == nil {
+		writeError(w, http.StatusNotImplemented, "scheduled processing is not configured")
+		return
+	}
+	timer, err := s.timers.Cancel(r.Context(), userID(r), mux.Vars(r)["timerID"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, timer)
+}
@@ -0,0 +1,28 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// ProfileHeader selects the execution profile of the executions a request
+// starts, interactive or standard, whatever the triggers ask for
+const ProfileHeader = "X-Execution-Profile"
+
+// profileFromHeader attaches the execution profile in the
+// X-Execution-Profile header, if any, to the request context
+func profileFromHeader(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		profile := r.Header.Get(ProfileHeader)
+		if profile == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !workflows.IsProfile(profile) {
+			writeError(w, http.StatusBadRequest, "unknown "+ProfileHeader+" "+profile)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(workflows.WithProfile(r.Context(), profile)))
+	})
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/memmieai/memmie-studio/internal/projections"
+)
+
+// bookContentsList pages through a book's table of contents
+var bookContentsList = listSpec{
+	key:          "chapters",
+	idField:      "key",
+	sortable:     []string{"position", "title", "updated_at"},
+	defaultSort:  "position",
+	defaultLimit: 200,
+	maxLimit:     1000,
+}
+
+// topicIndexList pages through a research topic's index
+var topicIndexList = listSpec{
+	key:          "documents",
+	idField:      "key",
+	sortable:     []string{"title", "updated_at"},
+	defaultSort:  "title",
+	defaultLimit: 100,
+	maxLimit:     500,
+}
+
+// bookContents handles GET /books/{bookID}/contents, the book's chapters
+// from the table of contents read model rather than the chapter blobs
+func (s *Server) bookContents(w http.ResponseWriter, r *http.Request) {
+	s.listReadModel(w, r, projections.BookContentsName, mux.Vars(r)["bookID"], bookContentsList)
+}
+
+// topicIndex handles GET /topics/{topicID}/index, the topic's research
+// documents from the topic index read model
+func (s *Server) topicIndex(w http.ResponseWriter, r *http.Request) {
+	s.listReadModel(w, r, projections.TopicIndexName, mux.Vars(r)["topicID"], topicIndexList)
+}
+
+// listReadModel writes a page of a group of the user's rows of a read
+// model
+func (s *Server) listReadModel(w http.ResponseWriter, r *http.Request, name, group string, spec listSpec) {
+	if s.projector == nil {
+		writeError(w, http.StatusNotImplemented, "read models are not configured")
+		return
+	}
+	q, err := parseListQuery(r, spec)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	rows, err := s.projector.List(r.Context(), name, userID(r), group)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	q.writeList(w, rows, nil)
+}
+
+// rebuildProjections handles POST /projections/rebuild, projecting the
+// user's read models afresh from their blobs and delta history
+func (s *Server) rebuildProjections(w http.ResponseWriter, r *http.Request) {
+	if s.projector == nil {
+		writeError(w, http.StatusNotImplemented, "read models are not configured")
+		return
+	}
+	count, err := s.projector.Rebuild(r.Context(), userID(r))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"projections": s.projector.Names(),
+		"blobs":       count,
+	})
+}
+
+// refreshProjections projects blobs the API changed without applying
+// deltas, such as chapters it renumbered. Failures leave the read models
+// to the blobs' next deltas or a rebuild.
+func (s *Server) refreshProjections(r *http.Request, blobIDs ...string) {
+	if s.projector == nil {
+		return
+	}
+	for _, blobID := range blobIDs {
+		s.projector.Refresh(r.Context(), userID(r), blobID)
+	}
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// registerProviderRequest registers a provider. Active defaults to true
+// and type to processor.
+type registerProviderRequest struct {
+	ID          string                    `json:"id"`
+	Name        string                    `json:"name"`
+	Type        string                    `json:"type"`
+	NamespaceID string                    `json:"namespace_id"`
+	WorkflowIDs []string                  `json:"workflow_ids"`
+	Triggers    []workflows.TriggerConfig `json:"triggers"`
+	Config      workflows.ProviderConfig  `json:"config"`
+	Active      *bool                     `json:"active"`
+}
+
+// providerList pages through providers
+var providerList = listSpec{
+	key:          "providers",
+	idField:      "id",
+	sortable:     []string{"id", "name", "type", "namespace_id", "active"},
+	defaultSort:  "id",
+	defaultLimit: 100,
+	maxLimit:     500,
+}
+
+// listProviders handles GET /providers
+func (s *Server) listProviders(w http.ResponseWriter, r *http.Request) {
+	if s.providers == nil {
+		writeError(w, http.StatusNotImplemented, "provider registration is not configured")
+		return
+	}
+	q, err := parseListQuery(r, providerList)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	q.writeList(w, s.providers.ListProviders(), nil)
+}
+
+// providerGraph handles GET /providers/dag, the providers and the
+// providers their workflows call. With format=dot the graph is returned as
+// Graphviz DOT instead of JSON.
+func (s *Server) providerGraph(w http.ResponseWriter, r *http.Request) {
+	if s.providers == nil {
+		writeError(w, http.StatusNotImplemented, "provider registration is not configured")
+		return
+	}
+	graph := s.providers.ProviderGraph()
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+		writeJSON(w, http.StatusOK, graph)
+	case "dot":
+		w.Header().Set("Content-Type", "text/vnd.graphviz; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		graph.WriteDOT(w)
+	default:
+		writeError(w, http.StatusBadRequest, "format must be json or dot")
+	}
+}
+
+// registerProvider handles POST /providers. Registering an existing ID
+// replaces that provider.
+func (s *Server) registerProvider(w http.ResponseWriter, r *http.Request) {
+	if s.providers == nil {
+		writeError(w, http.StatusNotImplemented, "provider registration is not configured")
+		return
+	}
+	var req registerProviderRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	provider := &workflows.Provider{
+		ID:          req.ID,
+		Name:        req.Name,
+		Type:        req.Type,
+		NamespaceID: req.NamespaceID,
+		WorkflowIDs: req.WorkflowIDs,
+		Triggers:    req.Triggers,
+		Config:      req.Config,
+		Active:      req.Active == nil || *req.Active,
+	}
+	if provider.Type == "" {
+		provider.Type = "processor"
+	}
+	if provider.Name == "" {
+		provider.Name = provider.ID
+	}
+
+	_, err := s.providers.GetProvider(provider.ID)
+	replaced := err == nil
+	if err := s.providers.RegisterProvider(r.Context(), provider); err != nil {
+		// A missing workflow is a problem with the request, not a missing provider
+		if errors.Is(err, workflows.ErrWorkflowNotFound) {
+			writeErrorCode(w, http.StatusBadRequest, CodeWorkflowNotFound, err.Error(), nil)
+			return
+		}
+		writeServiceError(w, err)
+		return
+	}
+	status := http.StatusCreated
+	if replaced {
+		status = http.StatusOK
+	}
+	writeJSON(w, status, provider)
+}
+
+// getProvider handles GET /providers/{providerID}
+func (s *Server) getProvider(w http.ResponseWriter, r *http.Request) {
+	if s.providers == nil {
+		writeError(w, http.StatusNotImplemented, "provider registration is not configured")
+		return
+	}
+	provider, err := s.providers.GetProvider(mux.Vars(r)["providerID"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, provider)
+}
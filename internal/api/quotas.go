@@ -0,0 +1,23 @@
+package api
+
+import "net/http"
+
+// getQuota handles GET /quota, the user's execution limits and how much of
+// them is in use
+func (s *Server) getQuota(w http.ResponseWriter, r *http.Request) {
+	if s.quotas == nil {
+		writeError(w, http.StatusNotImplemented, "execution quotas are not configured")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.quotas.Usage(userID(r)))
+}
+
+// getQuotaLanes handles GET /quota/lanes, how long each execution lane's
+// requests wait for slots
+func (s *Server) getQuotaLanes(w http.ResponseWriter, r *http.Request) {
+	if s.quotas == nil {
+		writeError(w, http.StatusNotImplemented, "execution quotas are not configured")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"lanes": s.quotas.Lanes()})
+}
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/memmieai/memmie-studio/internal/reprocess"
+)
+
+// The /reprocess endpoints were superseded by /jobs; v1 keeps them for six
+// months
+var (
+	reprocessDeprecated = time.Date(2026, time.October, 16, 0, 0, 0, 0, time.UTC)
+	reprocessSunset     = reprocessDeprecated.AddDate(0, 6, 0)
+)
+
+// reprocessDeprecation deprecates a /reprocess endpoint for its /jobs
+// successor
+func reprocessDeprecation(successor string) Deprecation {
+	return Deprecation{Since: reprocessDeprecated, Sunset: reprocessSunset, Successor: successor}
+}
+
+// startReprocess handles POST /providers/{providerID}/reprocess, starting
+// a job that re-runs the provider's workflows on the listed blob_ids or the
+// blobs a query matches
+func (s *Server) startReprocess(w http.ResponseWriter, r *http.Request) {
+	if s.reprocess == nil {
+		writeError(w, http.StatusNotImplemented, "reprocessing is not configured")
+		return
+	}
+	var req reprocess.Request
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	job, err := s.reprocess.Start(r.Context(), userID(r), mux.Vars(r)["providerID"], req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// reprocessJobRequest is a reprocess request naming its provider in the
+// body, for POST /jobs/reprocess
+type reprocessJobRequest struct {
+	ProviderID string `json:"provider_id"`
+	reprocess.Request
+}
+
+// startReprocessJob handles POST /jobs/reprocess, starting a reprocess job
+// for the provider_id the body names
+func (s *Server) startReprocessJob(w http.ResponseWriter, r *http.Request) {
+	if s.reprocess == nil {
+		writeError(w, http.StatusNotImplemented, "reprocessing is not configured")
+		return
+	}
+	var req reprocessJobRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.ProviderID == "" {
+		writeError(w, http.StatusBadRequest, "provider_id is required")
+		return
+	}
+	job, err := s.reprocess.Start(r.Context(), userID(r), req.ProviderID, req.Request)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// listReprocess handles GET /reprocess, the user's reprocess jobs newest
+// first, optionally for one provider_id
+func (s *Server) listReprocess(w http.ResponseWriter, r *http.Request) {
+	if s.reprocess == nil {
+		writeError(w, http.StatusNotImplemented, "reprocessing is not configured")
+		return
+	}
+	jobs := s.reprocess.List(userID(r), r.URL.Query().Get("provider_id"))
+	writeJSON(w, http.StatusOK, map[string]interface{}{"jobs": jobs})
+}
+
+// getReprocess handles GET /reprocess/{jobID}
+func (s *Server) getReprocess(w http.ResponseWriter, r *http.Request) {
+	if s.reprocess == nil {
+		writeError(w, http.StatusNotImplemented, "reprocessing is not configured")
+		return
+	}
+	job, err := s.reprocess.Get(userID(r), mux.Vars(r)["jobID"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// cancelReprocess handles POST /reprocess/{jobID}/cancel
+func (s *Server) cancelReprocess(w http.ResponseWriter, r *http.Request) {
+	if s.reprocess == nil {
+		writeError(w, http.StatusNotImplemented, "reprocessing is not configured")
+		return
+	}
+	job, err := s.reprocess.Cancel(r.Context(), userID(r), mux.Vars(r)["jobID"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// pauseReprocess handles POST /reprocess/{jobID}/pause
+func (s *Server) pauseReprocess(w http.ResponseWriter, r *http.Request) {
+	if s.reprocess == nil {
+		writeError(w, http.StatusNotImplemented, "reprocessing is not configured")
+		return
+	}
+	job, err := s.reprocess.Pause(r.Context(), userID(r), mux.Vars(r)["jobID"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// resumeReprocess handles POST /reprocess/{jobID}/resume, re-running a
+// stopped job on its unprocessed and failed blobs
+func (s *Server) resumeReprocess(w http.ResponseWriter, r *http.Request) {
+	if s.reprocess == nil {
+		writeError(w, http.StatusNotImplemented, "reprocessing is not configured")
+		return
+	}
+	job, err := s.reprocess.Resume(r.Context(), userID(r), mux.Vars(r)["jobID"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, job)
+}
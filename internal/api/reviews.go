@@ -0,0 +1,107 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/memmieai/memmie-studio/internal/reviews"
+)
+
+// createThreadRequest starts a review thread. The anchor's path defaults to
+// the blob's content; give start and end, or a quote to find, to anchor a
+// character range.
+type createThreadRequest struct {
+	Anchor reviews.Anchor `json:"anchor"`
+	Body   string         `json:"body"`
+}
+
+// commentRequest adds a comment to a thread
+type commentRequest struct {
+	Body string `json:"body"`
+}
+
+// listThreads handles GET /blobs/{blobID}/threads, optionally filtered by
+// status
+func (s *Server) listThreads(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status != "" && status != reviews.StatusOpen && status != reviews.StatusResolved {
+		writeError(w, http.StatusBadRequest, "invalid status")
+		return
+	}
+	threads, err := s.reviews.List(r.Context(), userID(r), mux.Vars(r)["blobID"], status)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"threads": threads})
+}
+
+// createThread handles POST /blobs/{blobID}/threads
+func (s *Server) createThread(w http.ResponseWriter, r *http.Request) {
+	var req createThreadRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	thread, err := s.reviews.Create(r.Context(), userID(r), mux.Vars(r)["blobID"], req.Anchor, req.Body, userAuthor(r))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, thread)
+}
+
+// getThread handles GET /blobs/{blobID}/threads/{threadID}
+func (s *Server) getThread(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	thread, err := s.reviews.Get(r.Context(), userID(r), vars["blobID"], vars["threadID"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, thread)
+}
+
+// addComment handles POST /blobs/{blobID}/threads/{threadID}/comments
+func (s *Server) addComment(w http.ResponseWriter, r *http.Request) {
+	var req commentRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	vars := mux.Vars(r)
+	thread, err := s.reviews.Reply(r.Context(), userID(r), vars["blobID"], vars["threadID"], req.Body, userAuthor(r))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, thread)
+}
+
+// resolveThread handles POST /blobs/{blobID}/threads/{threadID}/resolve
+func (s *Server) resolveThread(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	thread, err := s.reviews.Resolve(r.Context(), userID(r), vars["blobID"], vars["threadID"], userAuthor(r))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, thread)
+}
+
+// reopenThread handles POST /blobs/{blobID}/threads/{threadID}/reopen
+func (s *Server) reopenThread(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	thread, err := s.reviews.Reopen(r.Context(), userID(r), vars["blobID"], vars["threadID"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, thread)
+}
+
+// userAuthor is the requesting user as a comment author
+func userAuthor(r *http.Request) reviews.Author {
+	return reviews.Author{ID: userID(r), Type: reviews.AuthorUser}
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// rollbackRequest rolls back an execution, saying why
+type rollbackRequest struct {
+	Reason string `json:"reason"`
+}
+
+// rollbackExecution handles POST /executions/{executionID}/rollback,
+// undoing the deltas an execution applied and starting the workflows that
+// compensate its steps
+func (s *Server) rollbackExecution(w http.ResponseWriter, r *http.Request) {
+	if s.providers == nil {
+		writeError(w, http.StatusNotImplemented, "execution tracking is not configured")
+		return
+	}
+	var req rollbackRequest
+	if err := decodeJSON(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	executionID := mux.Vars(r)["executionID"]
+	if err := s.checkExecutionOwner(r, executionID); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	result, err := s.providers.RollbackExecution(r.Context(), executionID, req.Reason)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// getRollback handles GET /executions/{executionID}/rollback
+func (s *Server) getRollback(w http.ResponseWriter, r *http.Request) {
+	if s.providers == nil {
+		writeError(w, http.StatusNotImplemented, "execution tracking is not configured")
+		return
+	}
+	executionID := mux.Vars(r)["executionID"]
+	if err := s.checkExecutionOwner(r, executionID); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	result, err := s.providers.ExecutionRollback(executionID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
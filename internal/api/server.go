@@ -0,0 +1,421 @@
+// Package api serves the studio's REST API under /api/v1 and /api/v2
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"github.com/memmieai/memmie-studio/internal/analytics"
+	"github.com/memmieai/memmie-studio/internal/artifact"
+	"github.com/memmieai/memmie-studio/internal/blob"
+	"github.com/memmieai/memmie-studio/internal/books"
+	"github.com/memmieai/memmie-studio/internal/branches"
+	"github.com/memmieai/memmie-studio/internal/dataprofile"
+	"github.com/memmieai/memmie-studio/internal/export"
+	"github.com/memmieai/memmie-studio/internal/integrations/citations"
+	"github.com/memmieai/memmie-studio/internal/integrations/gitrepo"
+	"github.com/memmieai/memmie-studio/internal/moderation"
+	"github.com/memmieai/memmie-studio/internal/packs"
+	"github.com/memmieai/memmie-studio/internal/payloads"
+	"github.com/memmieai/memmie-studio/internal/preview"
+	"github.com/memmieai/memmie-studio/internal/projections"
+	"github.com/memmieai/memmie-studio/internal/quotas"
+	"github.com/memmieai/memmie-studio/internal/reprocess"
+	"github.com/memmieai/memmie-studio/internal/reviews"
+	"github.com/memmieai/memmie-studio/internal/revisions"
+	"github.com/memmieai/memmie-studio/internal/tagging"
+	"github.com/memmieai/memmie-studio/internal/timers"
+	"github.com/memmieai/memmie-studio/internal/trash"
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// UserHeader carries the authenticated user's ID, set by the API gateway
+const UserHeader = "X-User-ID"
+
+// maxBodySize bounds request bodies
+const maxBodySize = 10 << 20
+
+// maxRequestIDLength bounds the request IDs kept from the gateway
+const maxRequestIDLength = 128
+
+// Config holds the services the API is built on
+type Config struct {
+	Blobs      blob.Store
+	Deltas     revisions.History         // optional; diffs and analytics need it, and review anchors follow it
+	Artifacts  artifact.Store            // optional; exports need it
+	Events     workflows.EventBus        // optional; export progress is published on it, and blob event streams read from it
+	Repos      *gitrepo.Ingester         // optional; repository ingestion needs it
+	Moderation *moderation.Engine        // optional; the default content policies apply without it
+	Workflows  workflows.WorkflowService // optional; workflow management and execution status need it, and cards are summarized through it
+	Providers  *workflows.Orchestrator   // optional; providers are registered with it
+	Quotas     *quotas.Scheduler         // optional; users can see their execution quotas
+	Timers     *timers.Service           // optional; blob processing can be scheduled for later with it
+	Reprocess  *reprocess.Service        // optional; providers can reprocess blobs in bulk with it
+	Trash      *trash.Service            // optional; blobs can be deleted into it and restored
+	Branches   *branches.Service         // optional; blobs can be branched and merged back with it
+	Packs      *packs.Service            // optional; workflow packs can be installed into namespaces with it
+	// DeadLetters, optional, keeps the events that failed on Events, for
+	// users to list and replay
+	DeadLetters *workflows.DeadLetterQueue
+	// Registry, optional, manages workflows over Workflows; one is created
+	// without it
+	Registry *workflows.WorkflowRegistry
+	// Latencies, optional, are the step durations workflow health is
+	// reported from
+	Latencies *workflows.StepLatencies
+	// Pricing, optional, prices the models workflow estimates are made
+	// for; the default prices are used without it
+	Pricing workflows.PricingTable
+	// QueueStats, optional, reports the event queue counters state
+	// snapshots include
+	QueueStats func() workflows.BusStats
+	// Projections, optional, keeps the read models book contents and topic
+	// indexes are listed from
+	Projections *projections.Projector
+	// Payloads, optional, holds the step requests and responses workers
+	// captured, served with their executions
+	Payloads *payloads.Log
+	// DeltaKeys, optional, are the keys delta signatures are checked
+	// against when blobs' delta chains are verified; signatures go
+	// unchecked without them
+	DeltaKeys *workflows.DeltaKeyring
+	// Webhooks, optional, are third-party webhook handlers by name, served
+	// at /api/v1/webhooks/{name} without a user: they authenticate their
+	// callers themselves
+	Webhooks map[string]http.Handler
+	// AdminToken is the bearer token admin endpoints require; they are not
+	// served without one
+	AdminToken string
+	// ChaosHeader lets requests inject faults into workflow calls with the
+	// X-Chaos header; the workflow service must be wrapped by chaos.WrapService
+	ChaosHeader bool
+}
+
+// Server routes API requests
+type Server struct {
+	router     *mux.Router
+	blobs      blob.Store
+	deltas     revisions.History
+	books      *books.Service
+	analytics  *analytics.Service
+	exports    *export.Service
+	events     workflows.EventBus
+	citations  *citations.GraphBuilder
+	reports    *dataprofile.Service
+	ingestions *gitrepo.Service
+	reviews    *reviews.Service
+	moderation *moderation.Service
+	tags       *tagging.Service
+	cards      *preview.Service
+	registry   *workflows.WorkflowRegistry
+	providers  *workflows.Orchestrator
+	executions workflows.WorkflowService
+	quotas     *quotas.Scheduler
+	timers     *timers.Service
+	reprocess  *reprocess.Service
+	trash      *trash.Service
+	branches   *branches.Service
+	packs      *packs.Service
+	letters    *workflows.DeadLetterQueue
+	latencies  *workflows.StepLatencies
+	estimator  *workflows.Estimator
+	queueStats func() workflows.BusStats
+	projector  *projections.Projector
+	payloads   *payloads.Log
+	deltaKeys  *workflows.DeltaKeyring
+	webhooks   map[string]http.Handler
+	adminToken string
+	chaos      bool
+}
+
+// NewServer creates the API server
+func NewServer(cfg Config) *Server {
+	s := &Server{
+		router:     mux.NewRouter(),
+		blobs:      cfg.Blobs,
+		deltas:     cfg.Deltas,
+		events:     cfg.Events,
+		books:      books.NewService(cfg.Blobs),
+		citations:  citations.NewGraphBuilder(cfg.Blobs),
+		reports:    dataprofile.NewService(cfg.Blobs),
+		reviews:    reviews.NewService(cfg.Blobs, cfg.Deltas),
+		tags:       tagging.NewService(cfg.Blobs, nil, nil),
+		cards:      preview.NewService(cfg.Blobs, cfg.Workflows),
+		providers:  cfg.Providers,
+		quotas:     cfg.Quotas,
+		timers:     cfg.Timers,
+		reprocess:  cfg.Reprocess,
+		trash:      cfg.Trash,
+		branches:   cfg.Branches,
+		packs:      cfg.Packs,
+		letters:    cfg.DeadLetters,
+		latencies:  cfg.Latencies,
+		estimator:  workflows.NewEstimator(cfg.Latencies, cfg.Pricing),
+		queueStats: cfg.QueueStats,
+		projector:  cfg.Projections,
+		payloads:   cfg.Payloads,
+		deltaKeys:  cfg.DeltaKeys,
+		webhooks:   cfg.Webhooks,
+		adminToken: cfg.AdminToken,
+		chaos:      cfg.ChaosHeader,
+	}
+	engine := cfg.Moderation
+	if engine == nil {
+		engine = moderation.DefaultEngine()
+	}
+	s.moderation = moderation.NewService(cfg.Blobs, engine)
+	if cfg.Deltas != nil {
+		s.analytics = analytics.NewService(s.books, cfg.Deltas)
+	}
+	if cfg.Artifacts != nil {
+		s.exports = export.NewService(s.books, cfg.Deltas, cfg.Artifacts, cfg.Events)
+		s.exports.SetModeration(s.moderation)
+	}
+	if cfg.Repos != nil {
+		s.ingestions = gitrepo.NewService(cfg.Repos)
+	}
+	if cfg.Workflows != nil {
+		s.registry = cfg.Registry
+		if s.registry == nil {
+			s.registry = workflows.NewWorkflowRegistry(cfg.Workflows)
+		}
+		s.executions = cfg.Workflows
+		if cfg.Providers != nil {
+			s.registry.OnChange(cfg.Providers.RefreshWorkflow)
+		}
+	}
+	s.routes()
+	return s
+}
+
+// ServeHTTP dispatches a request to its route in the request's trace,
+// tagging the response with the request's ID and localizing its errors to
+// the request's Accept-Language
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id := r.Header.Get(RequestIDHeader)
+	if id == "" || len(id) > maxRequestIDLength {
+		id = uuid.New().String()
+	}
+	w.Header().Set(RequestIDHeader, id)
+	w.Header().Add("Vary", "Accept-Language")
+
+	// Requests join the caller's trace, or start one that traceresponse
+	// tells the caller of, and everything they publish or run carries it
+	trace := workflows.TraceFromHeaders(r.Header)
+	if trace.TraceParent == "" {
+		trace = workflows.TraceContext{TraceParent: workflows.NewTraceParent()}
+		w.Header().Set(TraceResponseHeader, trace.TraceParent)
+	}
+	r = r.WithContext(workflows.ContextWithTrace(r.Context(), trace))
+	s.router.ServeHTTP(&localeWriter{ResponseWriter: w, locale: requestLocale(r)}, r)
+}
+
+// routes registers every endpoint
+func (s *Server) routes() {
+	// Webhooks come before the versions' routers, which require a user
+	for name, handler := range s.webhooks {
+		s.router.Handle("/api/v1/webhooks/"+name, handler).Methods("POST")
+	}
+
+	v1 := s.newAPIVersion("v1")
+	v2 := s.newAPIVersion("v2")
+	// Endpoints are served by every version unless deprecated: deprecated
+	// endpoints stay in the versions that had them until their sunset and
+	// are left out of newer ones
+	api := routeSet{v1, v2}
+	legacy := routeSet{v1}
+
+	api.HandleFunc("/admin/state", s.requireAdmin(s.exportState)).Methods("GET")
+	api.HandleFunc("/admin/state", s.requireAdmin(s.importState)).Methods("POST")
+
+	api.HandleFunc("/blobs/{blobID}", s.getBlob).Methods("GET")
+	api.HandleFunc("/blobs/{blobID}", s.deleteBlob).Methods("DELETE")
+	api.HandleFunc("/blobs/{blobID}/await", s.awaitBlob).Methods("POST")
+	api.HandleFunc("/blobs/{blobID}/branches", s.listBranches).Methods("GET")
+	api.HandleFunc("/blobs/{blobID}/branches", s.createBranch).Methods("POST")
+	api.HandleFunc("/blobs/{blobID}/card", s.getCard).Methods("GET")
+	api.HandleFunc("/blobs/{blobID}/deltas", s.listDeltas).Methods("GET")
+	api.HandleFunc("/blobs/{blobID}/deltas/verify", s.verifyDeltas).Methods("GET")
+	api.HandleFunc("/blobs/{blobID}/diff", s.diffBlob).Methods("GET")
+	api.HandleFunc("/blobs/{blobID}/events", s.streamBlobEvents).Methods("GET")
+	api.HandleFunc("/blobs/{blobID}/moderation", s.checkBlob).Methods("GET")
+	api.HandleFunc("/blobs/{blobID}/moderation/overrides", s.listOverrides).Methods("GET")
+	api.HandleFunc("/blobs/{blobID}/moderation/overrides", s.overrideBlob).Methods("POST")
+	api.HandleFunc("/blobs/{blobID}/process", s.processBlob).Methods("POST")
+	api.HandleFunc("/blobs/{blobID}/state", s.getBlobState).Methods("GET")
+	api.HandleFunc("/blobs/{blobID}/tags", s.getBlobTags).Methods("GET")
+	api.HandleFunc("/blobs/{blobID}/tags", s.editBlobTags).Methods("PATCH")
+	api.HandleFunc("/blobs/{blobID}/threads", s.listThreads).Methods("GET")
+	api.HandleFunc("/blobs/{blobID}/threads", s.createThread).Methods("POST")
+	api.HandleFunc("/blobs/{blobID}/threads/{threadID}", s.getThread).Methods("GET")
+	api.HandleFunc("/blobs/{blobID}/threads/{threadID}/comments", s.addComment).Methods("POST")
+	api.HandleFunc("/blobs/{blobID}/threads/{threadID}/resolve", s.resolveThread).Methods("POST")
+	api.HandleFunc("/blobs/{blobID}/threads/{threadID}/reopen", s.reopenThread).Methods("POST")
+
+	api.HandleFunc("/books", s.createBook).Methods("POST")
+	api.HandleFunc("/books", s.listBooks).Methods("GET")
+	api.HandleFunc("/books/{bookID}", s.getBook).Methods("GET")
+	api.HandleFunc("/books/{bookID}/chapters", s.addChapter).Methods("POST")
+	api.HandleFunc("/books/{bookID}/chapters", s.listChapters).Methods("GET")
+	api.HandleFunc("/books/{bookID}/chapters/order", s.reorderChapters).Methods("PUT")
+	api.HandleFunc("/books/{bookID}/contents", s.bookContents).Methods("GET")
+	api.HandleFunc("/books/{bookID}/outline", s.getOutline).Methods("GET")
+	api.HandleFunc("/books/{bookID}/outline", s.updateOutline).Methods("PATCH")
+	api.HandleFunc("/books/{bookID}/analytics", s.bookAnalytics).Methods("GET")
+	api.HandleFunc("/books/{bookID}/exports", s.startExport).Methods("POST")
+	api.HandleFunc("/books/{bookID}/exports", s.listExports).Methods("GET")
+
+	api.HandleFunc("/analytics/writing", s.writingAnalytics).Methods("GET")
+
+	api.HandleFunc("/branches/{branchID}", s.getBranch).Methods("GET")
+	api.HandleFunc("/branches/{branchID}", s.deleteBranch).Methods("DELETE")
+	api.HandleFunc("/branches/{branchID}/merge", s.mergeBranch).Methods("POST")
+
+	api.HandleFunc("/cards", s.listCards).Methods("GET")
+
+	api.HandleFunc("/datasets/{datasetID}/reports", s.listDatasetRuns).Methods("GET")
+	api.HandleFunc("/datasets/{datasetID}/reports/compare", s.compareDatasetRuns).Methods("GET")
+	api.HandleFunc("/datasets/{datasetID}/reports/{reportID}", s.getDatasetReport).Methods("GET")
+
+	api.HandleFunc("/dead-letters", s.listDeadLetters).Methods("GET")
+	api.HandleFunc("/dead-letters/{letterID}", s.getDeadLetter).Methods("GET")
+	api.HandleFunc("/dead-letters/{letterID}", s.deleteDeadLetter).Methods("DELETE")
+	api.HandleFunc("/dead-letters/{letterID}/replay", s.replayDeadLetter).Methods("POST")
+
+	api.HandleFunc("/errors", s.listErrorCodes).Methods("GET")
+
+	api.HandleFunc("/executions", s.listExecutions).Methods("GET")
+	api.HandleFunc("/executions/{executionID:.+}/cancel", s.cancelExecution).Methods("POST")
+	api.HandleFunc("/executions/{executionID:.+}/definition", s.getExecutionDefinition).Methods("GET")
+	api.HandleFunc("/executions/{executionID:.+}/feedback", s.listFeedback).Methods("GET")
+	api.HandleFunc("/executions/{executionID:.+}/feedback", s.addFeedback).Methods("POST")
+	api.HandleFunc("/executions/{executionID:.+}/feedback/{feedbackID}", s.deleteFeedback).Methods("DELETE")
+	api.HandleFunc("/executions/{executionID:.+}/payloads", s.listPayloads).Methods("GET")
+	api.HandleFunc("/executions/{executionID:.+}/rollback", s.getRollback).Methods("GET")
+	api.HandleFunc("/executions/{executionID:.+}/rollback", s.rollbackExecution).Methods("POST")
+	api.HandleFunc("/executions/{executionID:.+}", s.getExecution).Methods("GET")
+
+	api.HandleFunc("/experiments", s.listExperiments).Methods("GET")
+	api.HandleFunc("/experiments/{experimentID}", s.getExperiment).Methods("GET")
+	api.HandleFunc("/experiments/{experimentID}", s.setExperiment).Methods("PUT")
+	api.HandleFunc("/experiments/{experimentID}", s.deleteExperiment).Methods("DELETE")
+	api.HandleFunc("/experiments/{experimentID}/metrics", s.experimentMetrics).Methods("GET")
+
+	api.HandleFunc("/exports/templates", s.listExportTemplates).Methods("GET")
+	api.HandleFunc("/exports/{jobID}", s.getExport).Methods("GET")
+	api.HandleFunc("/exports/{jobID}/override", s.overrideExport).Methods("POST")
+
+	api.HandleFunc("/jobs", s.listReprocess).Methods("GET")
+	api.HandleFunc("/jobs/reprocess", s.startReprocessJob).Methods("POST")
+	api.HandleFunc("/jobs/{jobID}", s.getReprocess).Methods("GET")
+	api.HandleFunc("/jobs/{jobID}/pause", s.pauseReprocess).Methods("POST")
+	api.HandleFunc("/jobs/{jobID}/cancel", s.cancelReprocess).Methods("POST")
+	api.HandleFunc("/jobs/{jobID}/resume", s.resumeReprocess).Methods("POST")
+
+	api.HandleFunc("/moderation/policies", s.listModerationPolicies).Methods("GET")
+
+	api.HandleFunc("/namespaces/{namespaceID}/defaults", s.getNamespaceDefaults).Methods("GET")
+	api.HandleFunc("/namespaces/{namespaceID}/defaults", s.setNamespaceDefaults).Methods("PUT")
+	api.HandleFunc("/namespaces/{namespaceID}/defaults", s.deleteNamespaceDefaults).Methods("DELETE")
+	api.HandleFunc("/namespaces/{namespaceID}/model-policy", s.namespaceModelPolicy).Methods("GET")
+	api.HandleFunc("/namespaces/{namespaceID}/packs", s.listPacks).Methods("GET")
+	api.HandleFunc("/namespaces/{namespaceID}/packs", s.installPack).Methods("POST")
+	api.HandleFunc("/namespaces/{namespaceID}/packs/{name}", s.getPack).Methods("GET")
+	api.HandleFunc("/namespaces/{namespaceID}/packs/{name}", s.uninstallPack).Methods("DELETE")
+	api.HandleFunc("/namespaces/{namespaceID}/providers", s.namespaceProviders).Methods("GET")
+
+	api.HandleFunc("/projections/rebuild", s.rebuildProjections).Methods("POST")
+
+	api.HandleFunc("/providers", s.listProviders).Methods("GET")
+	api.HandleFunc("/providers", s.registerProvider).Methods("POST")
+	api.HandleFunc("/providers/dag", s.providerGraph).Methods("GET")
+	api.HandleFunc("/providers/{providerID}", s.getProvider).Methods("GET")
+	api.HandleFunc("/providers/{providerID}/feedback", s.providerFeedback).Methods("GET")
+	api.HandleFunc("/providers/{providerID}/reprocess", s.startReprocess).Methods("POST")
+
+	api.HandleFunc("/templates/{templateID}/instantiate", s.instantiateTemplate).Methods("POST")
+
+	api.HandleFunc("/quota", s.getQuota).Methods("GET")
+	api.HandleFunc("/quota/lanes", s.getQuotaLanes).Methods("GET")
+
+	api.HandleFunc("/projects/{projectID}/ingestions", s.startIngestion).Methods("POST")
+	api.HandleFunc("/projects/{projectID}/ingestions", s.listIngestions).Methods("GET")
+	api.HandleFunc("/ingestions/{jobID}", s.getIngestion).Methods("GET")
+
+	// Superseded by /jobs
+	legacy.HandleFunc("/reprocess", s.listReprocess).Methods("GET").Deprecated(reprocessDeprecation("/jobs"))
+	legacy.HandleFunc("/reprocess/{jobID}", s.getReprocess).Methods("GET").Deprecated(reprocessDeprecation("/jobs/{jobID}"))
+	legacy.HandleFunc("/reprocess/{jobID}/pause", s.pauseReprocess).Methods("POST").Deprecated(reprocessDeprecation("/jobs/{jobID}/pause"))
+	legacy.HandleFunc("/reprocess/{jobID}/cancel", s.cancelReprocess).Methods("POST").Deprecated(reprocessDeprecation("/jobs/{jobID}/cancel"))
+	legacy.HandleFunc("/reprocess/{jobID}/resume", s.resumeReprocess).Methods("POST").Deprecated(reprocessDeprecation("/jobs/{jobID}/resume"))
+
+	api.HandleFunc("/scheduled", s.listScheduled).Methods("GET")
+	api.HandleFunc("/scheduled/{timerID}", s.getScheduled).Methods("GET")
+	api.HandleFunc("/scheduled/{timerID}", s.cancelScheduled).Methods("DELETE")
+
+	api.HandleFunc("/tags", s.listTags).Methods("GET")
+	api.HandleFunc("/tags/query", s.queryTags).Methods("GET")
+
+	api.HandleFunc("/trash", s.listTrash).Methods("GET")
+	api.HandleFunc("/trash/{blobID}", s.purgeBlob).Methods("DELETE")
+	api.HandleFunc("/trash/{blobID}/restore", s.restoreBlob).Methods("POST")
+
+	api.HandleFunc("/topics/{topicID}/citations/graph", s.citationGraph).Methods("GET")
+	api.HandleFunc("/topics/{topicID}/citations/graph/nodes", s.listCitationNodes).Methods("GET")
+	api.HandleFunc("/topics/{topicID}/citations/graph/nodes/{nodeID}", s.getCitationNode).Methods("GET")
+	api.HandleFunc("/topics/{topicID}/citations/graph/edges", s.listCitationEdges).Methods("GET")
+	api.HandleFunc("/topics/{topicID}/index", s.topicIndex).Methods("GET")
+
+	api.HandleFunc("/workflows", s.listWorkflows).Methods("GET")
+	api.HandleFunc("/workflows", s.createWorkflow).Methods("POST")
+	api.HandleFunc("/workflows/lint", s.lintWorkflowDraft).Methods("POST")
+	api.HandleFunc("/workflows/lint/rules", s.listLintRules).Methods("GET")
+	api.HandleFunc("/workflows/{workflowID}", s.getWorkflow).Methods("GET")
+	api.HandleFunc("/workflows/{workflowID}", s.updateWorkflow).Methods("PUT")
+	api.HandleFunc("/workflows/{workflowID}", s.deleteWorkflow).Methods("DELETE")
+	api.HandleFunc("/workflows/{workflowID}/health", s.getWorkflowHealth).Methods("GET")
+	api.HandleFunc("/workflows/{workflowID}/estimate", s.estimateWorkflow).Methods("POST")
+	api.HandleFunc("/workflows/{workflowID}/lint", s.lintWorkflow).Methods("GET")
+
+	api.HandleFunc("/ws", s.openSession).Methods("GET")
+
+	s.router.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "not found")
+	})
+}
+
+// requireUser rejects requests without a user ID
+func requireUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(UserHeader) == "" {
+			writeError(w, http.StatusUnauthorized, "missing "+UserHeader+" header")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// userID returns the requesting user's ID
+func userID(r *http.Request) string {
+	return r.Header.Get(UserHeader)
+}
+
+// decodeJSON reads a JSON request body into v
+func decodeJSON(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxBodySize)).Decode(v); err != nil {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	return nil
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
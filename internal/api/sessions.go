@@ -0,0 +1,318 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/memmieai/memmie-studio/internal/websocket"
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// maxSessionBlobs bounds how many blobs one session can subscribe to
+const maxSessionBlobs = 100
+
+// sessionCommand is a message a client sends over a studio session. ID is
+// echoed in the reply so clients can match replies to commands. Paths
+// narrow a subscribe or unsubscribe to those paths of its blobs.
+type sessionCommand struct {
+	ID          string   `json:"id,omitempty"`
+	Type        string   `json:"type"` // subscribe, unsubscribe, process or cancel
+	BlobIDs     []string `json:"blob_ids,omitempty"`
+	Paths       []string `json:"paths,omitempty"`
+	BlobID      string   `json:"blob_id,omitempty"`
+	EventType   string   `json:"event_type,omitempty"`
+	ExecutionID string   `json:"execution_id,omitempty"`
+}
+
+// sessionMessage is a message the server sends over a studio session: an
+// event on a subscribed blob, or a command's result or error
+type sessionMessage struct {
+	Type   string           `json:"type"` // event, result or error
+	ID     string           `json:"id,omitempty"`
+	Event  *workflows.Event `json:"event,omitempty"`
+	Result interface{}      `json:"result,omitempty"`
+	Error  *ErrorBody       `json:"error,omitempty"`
+}
+
+// commandError is a command the client got wrong, or that the server
+// cannot run, with the status a request would have been answered with
+type commandError struct {
+	status  int
+	message string
+}
+
+func (e *commandError) Error() string { return e.message }
+
+// session is one client's studio session: the blobs it follows, each
+// whole, with nil paths, or at the paths listed
+type session struct {
+	mu    sync.RWMutex
+	blobs map[string][]string
+}
+
+// notification returns the event to send the session for an event, if it
+// follows the event's blob and, for a blob followed at paths, the event
+// changed one of them
+func (s *session) notification(event workflows.Event) (workflows.Event, bool) {
+	s.mu.RLock()
+	paths, ok := s.blobs[event.BlobID]
+	s.mu.RUnlock()
+
+	if !ok {
+		return event, false
+	}
+	if paths == nil {
+		return event, true
+	}
+	return workflows.PathNotification(paths, event)
+}
+
+// subscribed lists the blobs the session follows, sorted, and the paths
+// it follows of those it does not follow whole
+func (s *session) subscribed() map[string]interface{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	blobIDs := make([]string, 0, len(s.blobs))
+	paths := make(map[string][]string)
+	for blobID, followed := range s.blobs {
+		blobIDs = append(blobIDs, blobID)
+		if followed != nil {
+			paths[blobID] = followed
+		}
+	}
+	sort.Strings(blobIDs)
+	result := map[string]interface{}{"blob_ids": blobIDs}
+	if len(paths) > 0 {
+		result["paths"] = paths
+	}
+	return result
+}
+
+// openSession handles GET /ws, upgrading to a WebSocket over which the
+// client subscribes to blobs' processing events and sends commands
+func (s *Server) openSession(w http.ResponseWriter, r *http.Request) {
+	if s.events == nil {
+		writeError(w, http.StatusNotImplemented, "event streaming is not configured")
+		return
+	}
+	conn, err := websocket.Upgrade(w, r)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	sess := &session{blobs: make(map[string][]string)}
+	queue := make(chan workflows.Event)
+	err = s.events.Subscribe(workflows.WithSubscriberBuffer(ctx, streamBuffer), func(_ context.Context, event workflows.Event) error {
+		event, ok := sess.notification(event)
+		if !ok {
+			return nil
+		}
+		select {
+		case queue <- event:
+		case <-ctx.Done():
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	// Events are written here and replies by the read loop; a failed write
+	// closes the connection, which ends the read loop too
+	go func() {
+		keepAlive := time.NewTicker(eventKeepAlive)
+		defer keepAlive.Stop()
+		for {
+			var err error
+			select {
+			case <-ctx.Done():
+				return
+			case event := <-queue:
+				err = sendSession(conn, sessionMessage{Type: "event", Event: &event})
+			case <-keepAlive.C:
+				err = conn.Ping()
+			}
+			if err != nil {
+				conn.Close()
+				return
+			}
+		}
+	}()
+
+	for {
+		data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var cmd sessionCommand
+		if err := json.Unmarshal(data, &cmd); err != nil {
+			err = &commandError{http.StatusBadRequest, "invalid command: " + err.Error()}
+			sendSession(conn, sessionReply(cmd, nil, err))
+			continue
+		}
+		result, err := s.runCommand(r, sess, cmd)
+		if err := sendSession(conn, sessionReply(cmd, result, err)); err != nil {
+			return
+		}
+	}
+}
+
+// runCommand runs one session command for the session's user
+func (s *Server) runCommand(r *http.Request, sess *session, cmd sessionCommand) (interface{}, error) {
+	switch cmd.Type {
+	case "subscribe":
+		if len(cmd.BlobIDs) == 0 {
+			return nil, &commandError{http.StatusBadRequest, "blob_ids is required"}
+		}
+		// Every blob must be the user's before any is followed
+		for _, blobID := range cmd.BlobIDs {
+			if _, err := s.blobs.GetBlob(r.Context(), userID(r), blobID); err != nil {
+				return nil, err
+			}
+		}
+		sess.mu.Lock()
+		added := make(map[string]bool)
+		for _, blobID := range cmd.BlobIDs {
+			if _, ok := sess.blobs[blobID]; !ok {
+				added[blobID] = true
+			}
+		}
+		if len(sess.blobs)+len(added) > maxSessionBlobs {
+			sess.mu.Unlock()
+			return nil, &commandError{http.StatusBadRequest, fmt.Sprintf("a session can follow at most %d blobs", maxSessionBlobs)}
+		}
+		// Paths add to those a blob is followed at; without paths, or
+		// once followed whole, a blob is followed whole
+		followed := make(map[string][]string, len(cmd.BlobIDs))
+		for _, blobID := range cmd.BlobIDs {
+			paths, ok := sess.blobs[blobID]
+			if len(cmd.Paths) == 0 || (ok && paths == nil) {
+				followed[blobID] = nil
+				continue
+			}
+			// Copied, since events are matched against the old paths unlocked
+			paths = append([]string(nil), paths...)
+			for _, path := range cmd.Paths {
+				if !contains(paths, path) {
+					paths = append(paths, path)
+				}
+			}
+			if len(paths) > maxSubscribedPaths {
+				sess.mu.Unlock()
+				return nil, &commandError{http.StatusBadRequest, fmt.Sprintf("a session can follow at most %d paths of a blob", maxSubscribedPaths)}
+			}
+			followed[blobID] = paths
+		}
+		for blobID, paths := range followed {
+			sess.blobs[blobID] = paths
+		}
+		sess.mu.Unlock()
+		return sess.subscribed(), nil
+
+	case "unsubscribe":
+		// Paths stop following those paths, and a blob followed at no
+		// others; a blob followed whole stays followed
+		sess.mu.Lock()
+		for _, blobID := range cmd.BlobIDs {
+			paths, ok := sess.blobs[blobID]
+			if !ok || (len(cmd.Paths) > 0 && paths == nil) {
+				continue
+			}
+			var kept []string
+			for _, path := range paths {
+				if !contains(cmd.Paths, path) {
+					kept = append(kept, path)
+				}
+			}
+			if len(cmd.Paths) == 0 || len(kept) == 0 {
+				delete(sess.blobs, blobID)
+				continue
+			}
+			sess.blobs[blobID] = kept
+		}
+		sess.mu.Unlock()
+		return sess.subscribed(), nil
+
+	case "process":
+		if s.providers == nil {
+			return nil, &commandError{http.StatusNotImplemented, "blob processing is not configured"}
+		}
+		if cmd.EventType == "" {
+			cmd.EventType = defaultProcessEvent
+		}
+		if !workflows.IsTriggerEvent(cmd.EventType) {
+			return nil, &commandError{http.StatusBadRequest, "unknown event_type " + cmd.EventType}
+		}
+		if _, err := s.blobs.GetBlob(r.Context(), userID(r), cmd.BlobID); err != nil {
+			return nil, err
+		}
+		executions, err := s.providers.ProcessBlobExecutions(r.Context(), cmd.BlobID, userID(r), cmd.EventType)
+		if err != nil && len(executions) == 0 {
+			return nil, err
+		}
+		resp := processBlobResponse{BlobID: cmd.BlobID, EventType: cmd.EventType, Executions: executions}
+		if resp.Executions == nil {
+			resp.Executions = map[string][]string{}
+		}
+		if err != nil {
+			// Some providers started, so say which alongside the failure
+			resp.Error = err.Error()
+		}
+		return resp, nil
+
+	case "cancel":
+		if s.executions == nil {
+			return nil, &commandError{http.StatusNotImplemented, "workflow backend is not configured"}
+		}
+		view, err := s.execution(r, cmd.ExecutionID)
+		if err != nil {
+			return nil, err
+		}
+		if workflows.IsTerminalStatus(view.Status) {
+			return nil, &commandError{http.StatusConflict, fmt.Sprintf("execution is already %s", view.Status)}
+		}
+		if err := s.executions.CancelExecution(r.Context(), cmd.ExecutionID); err != nil {
+			return nil, err
+		}
+		if updated, err := s.execution(r, cmd.ExecutionID); err == nil {
+			view = updated
+		}
+		return view, nil
+	}
+	return nil, &commandError{http.StatusBadRequest, fmt.Sprintf("unknown command type %q", cmd.Type)}
+}
+
+// sessionReply builds the reply to a command: its result, or its error in
+// the body a request would have been answered with
+func sessionReply(cmd sessionCommand, result interface{}, err error) sessionMessage {
+	if err == nil {
+		return sessionMessage{Type: "result", ID: cmd.ID, Result: result}
+	}
+	var body ErrorBody
+	var cmdErr *commandError
+	if errors.As(err, &cmdErr) {
+		body = ErrorBody{Code: statusCode(cmdErr.status), Message: cmdErr.message}
+	} else {
+		_, body = describeServiceError(err)
+	}
+	return sessionMessage{Type: "error", ID: cmd.ID, Error: &body}
+}
+
+// sendSession writes a message to a session as JSON
+func sendSession(conn *websocket.Conn, msg sessionMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+	return conn.WriteMessage(data)
+}
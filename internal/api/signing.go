@@ -0,0 +1,30 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// verifyDeltas handles GET /blobs/{blobID}/deltas/verify, checking that the
+// blob's delta log is an unbroken chain whose deltas were not altered
+// since they were stored and whose signatures hold
+func (s *Server) verifyDeltas(w http.ResponseWriter, r *http.Request) {
+	if s.deltas == nil {
+		writeError(w, http.StatusNotImplemented, "delta history is not configured")
+		return
+	}
+	blobID := mux.Vars(r)["blobID"]
+	if _, err := s.blobs.GetBlob(r.Context(), userID(r), blobID); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	deltas, err := s.deltas.GetByBlobID(r.Context(), blobID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, workflows.VerifyDeltaChain(blobID, deltas, s.deltaKeys))
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/memmieai/memmie-studio/internal/tagging"
+)
+
+// editTagsRequest adds and removes a blob's tags
+type editTagsRequest struct {
+	Add    []string `json:"add"`
+	Remove []string `json:"remove"`
+}
+
+// listTags handles GET /tags, the user's tags with how many blobs carry
+// each, optionally for one provider
+func (s *Server) listTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := s.tags.Tags(r.Context(), userID(r), r.URL.Query().Get("provider_id"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"tags": tags})
+}
+
+// queryTags handles GET /tags/query. all, any and none take
+// comma-separated tags and may be repeated.
+func (s *Server) queryTags(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit, err := queryInt(query.Get("limit"), 0)
+	if err != nil || limit < 0 {
+		writeError(w, http.StatusBadRequest, "invalid limit")
+		return
+	}
+	q := tagging.Query{
+		All:        tagList(query["all"]),
+		Any:        tagList(query["any"]),
+		None:       tagList(query["none"]),
+		ProviderID: query.Get("provider_id"),
+		Limit:      int(limit),
+	}
+	if len(q.All) == 0 && len(q.Any) == 0 {
+		writeError(w, http.StatusBadRequest, "all or any is required")
+		return
+	}
+	blobs, err := s.tags.Find(r.Context(), userID(r), q)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"blobs": blobs})
+}
+
+// getBlobTags handles GET /blobs/{blobID}/tags
+func (s *Server) getBlobTags(w http.ResponseWriter, r *http.Request) {
+	b, err := s.blobs.GetBlob(r.Context(), userID(r), mux.Vars(r)["blobID"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, blobTags(b.Metadata))
+}
+
+// editBlobTags handles PATCH /blobs/{blobID}/tags
+func (s *Server) editBlobTags(w http.ResponseWriter, r *http.Request) {
+	var req editTagsRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	b, err := s.tags.Edit(r.Context(), userID(r), mux.Vars(r)["blobID"], req.Add, req.Remove)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, blobTags(b.Metadata))
+}
+
+// blobTags picks a blob's tag fields out of its metadata
+func blobTags(metadata map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{})
+	for _, key := range []string{tagging.TagsKey, tagging.AutoTagsKey, tagging.ManualTagsKey, tagging.SuppressedKey, tagging.TopicsKey} {
+		if value, ok := metadata[key]; ok {
+			out[key] = value
+		}
+	}
+	if _, ok := out[tagging.TagsKey]; !ok {
+		out[tagging.TagsKey] = []string{}
+	}
+	return out
+}
+
+// tagList splits repeated, comma-separated query values into tags
+func tagList(values []string) []string {
+	var tags []string
+	for _, value := range values {
+		tags = append(tags, strings.Split(value, ",")...)
+	}
+	return tagging.NormalizeTags(tags)
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/memmieai/memmie-studio/internal/blob"
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// namespaceBlobType is the metadata type of namespaces created for
+// template instances
+const namespaceBlobType = "namespace"
+
+// instantiateTemplateRequest instantiates a template. Without a namespace
+// ID a new namespace is created, named Name or else for the template.
+type instantiateTemplateRequest struct {
+	NamespaceID string                 `json:"namespace_id"`
+	Name        string                 `json:"name"`
+	Variables   map[string]interface{} `json:"variables"`
+}
+
+// instantiateTemplate handles POST /templates/{templateID}/instantiate,
+// registering the template's workflow for a namespace of the user's along
+// with the providers it requires
+func (s *Server) instantiateTemplate(w http.ResponseWriter, r *http.Request) {
+	if s.providers == nil || s.registry == nil {
+		writeError(w, http.StatusNotImplemented, "provider registration is not configured")
+		return
+	}
+	var req instantiateTemplateRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	template, err := workflows.LookupTemplate(mux.Vars(r)["templateID"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	var created *blob.Blob
+	if req.NamespaceID != "" {
+		if _, err := s.blobs.GetBlob(r.Context(), userID(r), req.NamespaceID); err != nil {
+			writeServiceError(w, err)
+			return
+		}
+	} else {
+		name := req.Name
+		if name == "" {
+			name = template.Name
+		}
+		created, err = s.blobs.CreateBlob(r.Context(), &blob.Blob{
+			UserID: userID(r),
+			Metadata: map[string]interface{}{
+				"type":        namespaceBlobType,
+				"name":        name,
+				"template_id": template.ID,
+			},
+		})
+		if err != nil {
+			writeServiceError(w, err)
+			return
+		}
+		req.NamespaceID = created.ID
+	}
+
+	instance, err := s.providers.InstantiateTemplate(r.Context(), s.registry, workflows.TemplateRequest{
+		TemplateID:  template.ID,
+		NamespaceID: req.NamespaceID,
+		Variables:   req.Variables,
+	})
+	if err != nil {
+		// A namespace created for an instance that was never set up is
+		// removed where the store allows it
+		if deleter, ok := s.blobs.(blob.Deleter); ok && created != nil && instance == nil {
+			deleter.DeleteBlob(r.Context(), userID(r), created.ID)
+		}
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, instance)
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// deleteBlob handles DELETE /blobs/{blobID}, moving the blob to the trash
+func (s *Server) deleteBlob(w http.ResponseWriter, r *http.Request) {
+	if s.trash == nil {
+		writeError(w, http.StatusNotImplemented, "blob deletion is not configured")
+		return
+	}
+	entry, err := s.trash.Delete(r.Context(), userID(r), mux.Vars(r)["blobID"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, entry)
+}
+
+// listTrash handles GET /trash, the user's deleted blobs, most recent
+// first
+func (s *Server) listTrash(w http.ResponseWriter, r *http.Request) {
+	if s.trash == nil {
+		writeError(w, http.StatusNotImplemented, "blob deletion is not configured")
+		return
+	}
+	entries, err := s.trash.List(r.Context(), userID(r))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"trash": entries})
+}
+
+// restoreBlob handles POST /trash/{blobID}/restore. at_sequence replays
+// the delta log to another sequence than the one the blob was deleted at.
+func (s *Server) restoreBlob(w http.ResponseWriter, r *http.Request) {
+	if s.trash == nil {
+		writeError(w, http.StatusNotImplemented, "blob deletion is not configured")
+		return
+	}
+	at, err := queryInt(r.URL.Query().Get("at_sequence"), 0)
+	if err != nil || at < 0 {
+		writeError(w, http.StatusBadRequest, "invalid at_sequence")
+		return
+	}
+	restored, err := s.trash.Restore(r.Context(), userID(r), mux.Vars(r)["blobID"], at)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, restored)
+}
+
+// purgeBlob handles DELETE /trash/{blobID}, deleting the blob for good
+// without waiting for its retention to pass
+func (s *Server) purgeBlob(w http.ResponseWriter, r *http.Request) {
+	if s.trash == nil {
+		writeError(w, http.StatusNotImplemented, "blob deletion is not configured")
+		return
+	}
+	if err := s.trash.Purge(r.Context(), userID(r), mux.Vars(r)["blobID"]); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
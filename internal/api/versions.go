@@ -0,0 +1,112 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/memmieai/memmie-studio/internal/errorcodes"
+)
+
+// APIVersionHeader names the API version that served a response
+const APIVersionHeader = "X-API-Version"
+
+// CodeEndpointSunset is the error code of deprecated endpoints past their
+// sunset
+const CodeEndpointSunset = errorcodes.EndpointSunset
+
+// Deprecation marks an endpoint as deprecated. Responses carry the
+// Deprecation header (RFC 9745) from Since, the Sunset header (RFC 8594)
+// and a Link to the successor endpoint, and once Sunset has passed the
+// endpoint answers 410 Gone.
+type Deprecation struct {
+	Since     time.Time
+	Sunset    time.Time
+	Successor string // path of the replacing endpoint within the version, with the route's {variables}
+}
+
+// routeSet registers routes in several API versions at once
+type routeSet []*apiVersion
+
+// apiVersion is the router of one API version
+type apiVersion struct {
+	name   string
+	router *mux.Router
+}
+
+// routeGroup is a route as registered in each version of a routeSet
+type routeGroup struct {
+	versions []*apiVersion
+	routes   []*mux.Route
+}
+
+// newAPIVersion creates the router of a version under /api/<name>
+func (s *Server) newAPIVersion(name string) *apiVersion {
+	router := s.router.PathPrefix("/api/" + name).Subrouter()
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(APIVersionHeader, name)
+			next.ServeHTTP(w, r)
+		})
+	})
+	router.Use(requireUser)
+	router.Use(profileFromHeader)
+	if s.chaos {
+		router.Use(chaosFromHeader)
+	}
+	return &apiVersion{name: name, router: router}
+}
+
+// HandleFunc registers a handler for a path in every version of the set
+func (rs routeSet) HandleFunc(path string, handler func(http.ResponseWriter, *http.Request)) routeGroup {
+	group := routeGroup{versions: rs}
+	for _, version := range rs {
+		group.routes = append(group.routes, version.router.HandleFunc(path, handler))
+	}
+	return group
+}
+
+// Methods restricts the route to HTTP methods
+func (g routeGroup) Methods(methods ...string) routeGroup {
+	for _, route := range g.routes {
+		route.Methods(methods...)
+	}
+	return g
+}
+
+// Deprecated marks the route as deprecated in every version it is
+// registered in
+func (g routeGroup) Deprecated(d Deprecation) routeGroup {
+	for i, route := range g.routes {
+		route.Handler(deprecated(d, "/api/"+g.versions[i].name, route.GetHandler()))
+	}
+	return g
+}
+
+// deprecated wraps a deprecated endpoint's handler
+func deprecated(d Deprecation, prefix string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "@"+strconv.FormatInt(d.Since.Unix(), 10))
+		w.Header().Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+		successor := ""
+		if d.Successor != "" {
+			successor = prefix + d.Successor
+			for name, value := range mux.Vars(r) {
+				successor = strings.ReplaceAll(successor, "{"+name+"}", value)
+			}
+			w.Header().Set("Link", "<"+successor+`>; rel="successor-version"`)
+		}
+		if !time.Now().Before(d.Sunset) {
+			message := "this endpoint was removed on " + d.Sunset.UTC().Format(time.DateOnly)
+			if successor != "" {
+				message += "; use " + successor
+			}
+			writeErrorCode(w, http.StatusGone, CodeEndpointSunset, message, nil)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
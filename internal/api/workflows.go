@@ -0,0 +1,193 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// workflowList pages through workflows
+var workflowList = listSpec{
+	key:          "workflows",
+	idField:      "id",
+	sortable:     []string{"id", "name", "provider_id", "type", "created_at", "updated_at"},
+	defaultSort:  "id",
+	defaultLimit: 100,
+	maxLimit:     500,
+}
+
+// listWorkflows handles GET /workflows, optionally for one provider
+func (s *Server) listWorkflows(w http.ResponseWriter, r *http.Request) {
+	if s.registry == nil {
+		writeError(w, http.StatusNotImplemented, "workflow backend is not configured")
+		return
+	}
+	q, err := parseListQuery(r, workflowList)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	list, err := s.registry.List(r.Context(), r.URL.Query().Get("provider_id"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	q.writeList(w, list, nil)
+}
+
+// createWorkflow handles POST /workflows
+func (s *Server) createWorkflow(w http.ResponseWriter, r *http.Request) {
+	if s.registry == nil {
+		writeError(w, http.StatusNotImplemented, "workflow backend is not configured")
+		return
+	}
+	workflow, err := decodeWorkflow(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := s.registry.Create(r.Context(), workflow); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, workflow)
+}
+
+// getWorkflow handles GET /workflows/{workflowID}
+func (s *Server) getWorkflow(w http.ResponseWriter, r *http.Request) {
+	if s.registry == nil {
+		writeError(w, http.StatusNotImplemented, "workflow backend is not configured")
+		return
+	}
+	workflow, err := s.registry.Get(r.Context(), mux.Vars(r)["workflowID"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, workflow)
+}
+
+// updateWorkflow handles PUT /workflows/{workflowID}, replacing the whole
+// definition. The body's id may be left out but must match when given.
+func (s *Server) updateWorkflow(w http.ResponseWriter, r *http.Request) {
+	if s.registry == nil {
+		writeError(w, http.StatusNotImplemented, "workflow backend is not configured")
+		return
+	}
+	workflow, err := decodeWorkflow(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	id := mux.Vars(r)["workflowID"]
+	if workflow.ID != "" && workflow.ID != id {
+		writeError(w, http.StatusBadRequest, "workflow id does not match the path")
+		return
+	}
+	workflow.ID = id
+	if err := s.registry.Update(r.Context(), workflow); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, workflow)
+}
+
+// deleteWorkflow handles DELETE /workflows/{workflowID}
+func (s *Server) deleteWorkflow(w http.ResponseWriter, r *http.Request) {
+	if s.registry == nil {
+		writeError(w, http.StatusNotImplemented, "workflow backend is not configured")
+		return
+	}
+	if err := s.registry.Delete(r.Context(), mux.Vars(r)["workflowID"]); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// lintWorkflowDraft handles POST /workflows/lint, linting a definition
+// without registering it. Definitions that fail validation are a 400.
+func (s *Server) lintWorkflowDraft(w http.ResponseWriter, r *http.Request) {
+	workflow, err := decodeWorkflow(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if workflow.Type == "" {
+		workflow.Type = workflows.WorkflowTypeProcessBlob
+	}
+	if err := workflow.Validate(); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeLintReport(w, workflow)
+}
+
+// lintWorkflow handles GET /workflows/{workflowID}/lint
+func (s *Server) lintWorkflow(w http.ResponseWriter, r *http.Request) {
+	if s.registry == nil {
+		writeError(w, http.StatusNotImplemented, "workflow backend is not configured")
+		return
+	}
+	workflow, err := s.registry.Get(r.Context(), mux.Vars(r)["workflowID"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeLintReport(w, workflow)
+}
+
+// getWorkflowHealth handles GET /workflows/{workflowID}/health, reporting
+// each step's durations, health score and recommended timeout
+func (s *Server) getWorkflowHealth(w http.ResponseWriter, r *http.Request) {
+	if s.registry == nil || s.latencies == nil {
+		writeError(w, http.StatusNotImplemented, "step latencies are not recorded")
+		return
+	}
+	workflow, err := s.registry.Get(r.Context(), mux.Vars(r)["workflowID"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"workflow_id": workflow.ID,
+		"version":     workflow.Version,
+		"steps":       s.latencies.Health(workflow),
+	})
+}
+
+// listLintRules handles GET /workflows/lint/rules
+func (s *Server) listLintRules(w http.ResponseWriter, r *http.Request) {
+	rules := make([]map[string]string, len(workflows.LintRules))
+	for i, rule := range workflows.LintRules {
+		rules[i] = map[string]string{"id": rule.ID, "severity": rule.Severity, "description": rule.Description}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"rules": rules})
+}
+
+// writeLintReport writes a workflow's lint issues; it passes when none are
+// errors
+func writeLintReport(w http.ResponseWriter, workflow *workflows.BlobProcessingWorkflow) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"workflow_id": workflow.ID,
+		"passed":      workflows.CheckLint(workflow) == nil,
+		"issues":      workflows.Lint(workflow),
+	})
+}
+
+// decodeWorkflow reads a workflow definition, rejecting unknown fields so
+// misspelled settings are not silently dropped
+func decodeWorkflow(r *http.Request) (*workflows.BlobProcessingWorkflow, error) {
+	decoder := json.NewDecoder(io.LimitReader(r.Body, maxBodySize))
+	decoder.DisallowUnknownFields()
+	var workflow workflows.BlobProcessingWorkflow
+	if err := decoder.Decode(&workflow); err != nil {
+		return nil, fmt.Errorf("invalid request body: %w", err)
+	}
+	return &workflow, nil
+}
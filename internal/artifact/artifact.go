@@ -0,0 +1,105 @@
+// Package artifact stores binary outputs such as audio, images, and exported
+// documents that are too large to keep in blob content. Blobs reference
+// artifacts by URL in their metadata.
+package artifact
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotFound is returned when an artifact does not exist
+var ErrNotFound = errors.New("artifact not found")
+
+// Store persists artifacts and returns a URL they can be fetched from
+type Store interface {
+	Put(ctx context.Context, key, contentType string, data []byte) (string, error)
+	Get(ctx context.Context, key string) ([]byte, string, error)
+}
+
+// LocalStore keeps artifacts on the local filesystem and serves them under
+// a base URL
+type LocalStore struct {
+	dir     string
+	baseURL string
+}
+
+// NewLocalStore creates a filesystem store rooted at dir
+func NewLocalStore(dir, baseURL string) *LocalStore {
+	return &LocalStore{dir: dir, baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// Put writes an artifact and returns its URL
+func (s *LocalStore) Put(ctx context.Context, key, contentType string, data []byte) (string, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create artifact directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write artifact %s: %w", key, err)
+	}
+	return s.baseURL + "/" + key, nil
+}
+
+// Get reads an artifact and its content type
+func (s *LocalStore) Get(ctx context.Context, key string) ([]byte, string, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, "", err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, "", ErrNotFound
+		}
+		return nil, "", fmt.Errorf("failed to read artifact %s: %w", key, err)
+	}
+	return data, mime.TypeByExtension(filepath.Ext(path)), nil
+}
+
+// Dir returns the directory artifacts are written to, for serving them
+// with http.FileServer
+func (s *LocalStore) Dir() string {
+	return s.dir
+}
+
+// path maps a key to a file path, rejecting keys that escape the root
+func (s *LocalStore) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if clean == "/" || strings.Contains(key, "..") {
+		return "", fmt.Errorf("invalid artifact key %q", key)
+	}
+	return filepath.Join(s.dir, clean), nil
+}
+
+// Key builds an artifact key from path segments and a file extension
+func Key(ext string, segments ...string) string {
+	if ext != "" && !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return strings.Join(segments, "/") + ext
+}
+
+// ExtensionFor returns a file extension for a content type
+func ExtensionFor(contentType string) string {
+	switch contentType {
+	case "audio/mpeg":
+		return ".mp3"
+	case "image/png":
+		return ".png"
+	case "image/jpeg":
+		return ".jpg"
+	}
+	if exts, err := mime.ExtensionsByType(contentType); err == nil && len(exts) > 0 {
+		return exts[0]
+	}
+	return ".bin"
+}
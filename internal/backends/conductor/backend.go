@@ -0,0 +1,403 @@
+// Package conductor executes blob processing workflows on Netflix Conductor
+// through its REST API
+package conductor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// errNotFound is returned for Conductor API 404 responses
+var errNotFound = errors.New("not found")
+
+func init() {
+	workflows.RegisterBackend("conductor", func(cfg workflows.BackendConfig) (workflows.WorkflowService, error) {
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("conductor backend requires the Conductor API URL")
+		}
+		return NewBackend(cfg.URL, cfg.Options["owner_email"]), nil
+	})
+}
+
+// Backend implements workflows.WorkflowService on top of the Conductor API
+type Backend struct {
+	baseURL      string
+	ownerEmail   string
+	httpClient   *http.Client
+	pollInterval time.Duration
+	definitions  map[string]*workflows.BlobProcessingWorkflow
+	mu           sync.RWMutex
+}
+
+// NewBackend creates a Conductor backend. baseURL points at the Conductor
+// API root, e.g. http://conductor:8080/api.
+func NewBackend(baseURL, ownerEmail string) *Backend {
+	return &Backend{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		ownerEmail: ownerEmail,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		pollInterval: time.Second,
+		definitions:  make(map[string]*workflows.BlobProcessingWorkflow),
+	}
+}
+
+// startRequest is the Conductor start workflow payload
+type startRequest struct {
+	Name          string                 `json:"name"`
+	Version       int                    `json:"version"`
+	CorrelationID string                 `json:"correlationId,omitempty"`
+	Priority      int                    `json:"priority"`
+	Input         map[string]interface{} `json:"input"`
+}
+
+// workflowStatus is the subset of a Conductor workflow execution we read
+type workflowStatus struct {
+	WorkflowID            string                 `json:"workflowId"`
+	WorkflowName          string                 `json:"workflowName"`
+	Status                string                 `json:"status"`
+	Output                map[string]interface{} `json:"output"`
+	ReasonForIncompletion string                 `json:"reasonForIncompletion"`
+	StartTime             int64                  `json:"startTime"`
+	EndTime               int64                  `json:"endTime"`
+}
+
+// ExecuteWorkflow starts a Conductor workflow. Synchronous requests poll
+// until the execution reaches a terminal state.
+func (b *Backend) ExecuteWorkflow(ctx context.Context, req workflows.ExecutionRequest) (*workflows.ExecutionResponse, error) {
+	input := make(map[string]interface{}, len(req.Input)+1)
+	for k, v := range req.Input {
+		input[k] = v
+	}
+	input["context"] = req.Context
+
+	priority := req.Priority
+	if priority > 99 {
+		priority = 99
+	}
+
+	body, err := json.Marshal(startRequest{
+		Name:          req.WorkflowID,
+		Version:       1,
+		CorrelationID: req.Context.RequestID,
+		Priority:      priority,
+		Input:         input,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	var raw []byte
+	if err := b.do(ctx, http.MethodPost, "/workflow", body, &raw); err != nil {
+		return nil, fmt.Errorf("failed to start conductor workflow: %w", err)
+	}
+
+	executionID := strings.Trim(strings.TrimSpace(string(raw)), `"`)
+	if req.Async {
+		return &workflows.ExecutionResponse{
+			ExecutionID: executionID,
+			Status:      "running",
+			StartedAt:   time.Now(),
+		}, nil
+	}
+
+	ticker := time.NewTicker(b.pollInterval)
+	defer ticker.Stop()
+	for {
+		resp, err := b.GetExecutionStatus(ctx, executionID)
+		if err != nil {
+			return nil, err
+		}
+		if resp.Status != "running" && resp.Status != "paused" {
+			return resp, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// GetExecutionStatus fetches a Conductor execution and maps it onto the
+// studio execution response
+func (b *Backend) GetExecutionStatus(ctx context.Context, executionID string) (*workflows.ExecutionResponse, error) {
+	var status workflowStatus
+	path := fmt.Sprintf("/workflow/%s?includeTasks=false", url.PathEscape(executionID))
+	if err := b.do(ctx, http.MethodGet, path, nil, &status); err != nil {
+		if errors.Is(err, errNotFound) {
+			return nil, fmt.Errorf("%w: %s", workflows.ErrExecutionNotFound, executionID)
+		}
+		return nil, fmt.Errorf("failed to get execution status: %w", err)
+	}
+
+	resp := &workflows.ExecutionResponse{
+		ExecutionID: executionID,
+		Status:      statusName(status.Status),
+		StartedAt:   time.UnixMilli(status.StartTime),
+	}
+	if status.EndTime > 0 {
+		completedAt := time.UnixMilli(status.EndTime)
+		resp.CompletedAt = &completedAt
+	}
+
+	switch resp.Status {
+	case "completed":
+		resp.Output = b.aggregateOutput(ctx, status)
+	case "failed", "timed_out", "terminated":
+		resp.Error = &workflows.ExecutionError{
+			Code:    "workflow_" + resp.Status,
+			Message: status.ReasonForIncompletion,
+		}
+	}
+
+	return resp, nil
+}
+
+// aggregateOutput shapes Conductor's per-step output into the same form as
+// the other backends, concatenating step deltas in DAG order
+func (b *Backend) aggregateOutput(ctx context.Context, status workflowStatus) map[string]interface{} {
+	steps, _ := status.Output["steps"].(map[string]interface{})
+
+	wf, err := b.GetWorkflow(ctx, status.WorkflowName)
+	if err != nil || steps == nil {
+		return status.Output
+	}
+	levels, err := wf.GetDAGOrder()
+	if err != nil {
+		return status.Output
+	}
+
+	scope := workflows.ExecutionScope{}
+	var order []string
+	for _, level := range levels {
+		for _, step := range level {
+			order = append(order, step.ID)
+			if output, ok := steps[step.ID].(map[string]interface{}); ok {
+				scope.SetStepOutput(step.ID, output)
+			} else {
+				scope.SetStepSkipped(step.ID, "no output")
+			}
+		}
+	}
+	return scope.Output(order)
+}
+
+// CancelExecution terminates a Conductor workflow
+func (b *Backend) CancelExecution(ctx context.Context, executionID string) error {
+	path := fmt.Sprintf("/workflow/%s?reason=%s", url.PathEscape(executionID), url.QueryEscape("cancelled by studio"))
+	if err := b.do(ctx, http.MethodDelete, path, nil, nil); err != nil {
+		if errors.Is(err, errNotFound) {
+			return fmt.Errorf("%w: %s", workflows.ErrExecutionNotFound, executionID)
+		}
+		return fmt.Errorf("failed to cancel execution: %w", err)
+	}
+	return nil
+}
+
+// RegisterWorkflow registers task definitions for the workflow's executors
+// and then the workflow definition itself
+func (b *Backend) RegisterWorkflow(ctx context.Context, workflow *workflows.BlobProcessingWorkflow) error {
+	def, err := toWorkflowDef(workflow, b.ownerEmail)
+	if err != nil {
+		return fmt.Errorf("invalid workflow %s: %w", workflow.ID, err)
+	}
+
+	if err := b.registerTaskDefs(ctx, workflow); err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(def)
+	if err != nil {
+		return fmt.Errorf("failed to marshal workflow: %w", err)
+	}
+	if err := b.do(ctx, http.MethodPost, "/metadata/workflow", body, nil); err != nil {
+		return fmt.Errorf("failed to register workflow: %w", err)
+	}
+
+	b.cache(workflow)
+	return nil
+}
+
+// UpdateWorkflow overwrites an existing Conductor workflow definition
+func (b *Backend) UpdateWorkflow(ctx context.Context, workflow *workflows.BlobProcessingWorkflow) error {
+	def, err := toWorkflowDef(workflow, b.ownerEmail)
+	if err != nil {
+		return fmt.Errorf("invalid workflow %s: %w", workflow.ID, err)
+	}
+
+	if err := b.registerTaskDefs(ctx, workflow); err != nil {
+		return err
+	}
+
+	body, err := json.Marshal([]*WorkflowDef{def})
+	if err != nil {
+		return fmt.Errorf("failed to marshal workflow: %w", err)
+	}
+	if err := b.do(ctx, http.MethodPut, "/metadata/workflow", body, nil); err != nil {
+		return fmt.Errorf("failed to update workflow: %w", err)
+	}
+
+	b.cache(workflow)
+	return nil
+}
+
+// GetWorkflow returns a workflow definition, reading it back from Conductor
+// when it was registered by another studio instance
+func (b *Backend) GetWorkflow(ctx context.Context, workflowID string) (*workflows.BlobProcessingWorkflow, error) {
+	b.mu.RLock()
+	workflow, ok := b.definitions[workflowID]
+	b.mu.RUnlock()
+	if ok {
+		return workflow, nil
+	}
+
+	var def WorkflowDef
+	if err := b.do(ctx, http.MethodGet, "/metadata/workflow/"+url.PathEscape(workflowID), nil, &def); err != nil {
+		return nil, fmt.Errorf("failed to get workflow %s: %w", workflowID, err)
+	}
+
+	workflow, err := fromWorkflowDef(&def)
+	if err != nil {
+		return nil, err
+	}
+	b.cache(workflow)
+	return workflow, nil
+}
+
+// ListWorkflows lists Conductor workflow definitions created by the studio
+// for a provider, or all of them when providerID is empty
+func (b *Backend) ListWorkflows(ctx context.Context, providerID string) ([]*workflows.BlobProcessingWorkflow, error) {
+	var defs []WorkflowDef
+	if err := b.do(ctx, http.MethodGet, "/metadata/workflow", nil, &defs); err != nil {
+		return nil, fmt.Errorf("failed to list workflows: %w", err)
+	}
+
+	var result []*workflows.BlobProcessingWorkflow
+	for i := range defs {
+		workflow, err := fromWorkflowDef(&defs[i])
+		if err != nil {
+			// Not a studio workflow
+			continue
+		}
+		if providerID == "" || workflow.ProviderID == providerID {
+			result = append(result, workflow)
+		}
+	}
+	return result, nil
+}
+
+// DeleteWorkflow removes a studio workflow definition from Conductor.
+// Task definitions are left for other workflows that share them.
+func (b *Backend) DeleteWorkflow(ctx context.Context, workflowID string) error {
+	path := fmt.Sprintf("/metadata/workflow/%s/1", url.PathEscape(workflowID))
+	if err := b.do(ctx, http.MethodDelete, path, nil, nil); err != nil {
+		return fmt.Errorf("failed to delete workflow: %w", err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.definitions, workflowID)
+	return nil
+}
+
+// registerTaskDefs registers the task definitions a workflow depends on
+func (b *Backend) registerTaskDefs(ctx context.Context, workflow *workflows.BlobProcessingWorkflow) error {
+	body, err := json.Marshal(taskDefs(workflow, b.ownerEmail))
+	if err != nil {
+		return fmt.Errorf("failed to marshal task definitions: %w", err)
+	}
+	if err := b.do(ctx, http.MethodPost, "/metadata/taskdefs", body, nil); err != nil {
+		return fmt.Errorf("failed to register task definitions: %w", err)
+	}
+	return nil
+}
+
+// cache stores a workflow definition locally
+func (b *Backend) cache(workflow *workflows.BlobProcessingWorkflow) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.definitions[workflow.ID] = workflow
+}
+
+// do performs a Conductor API request. out may be a *[]byte to receive the
+// raw body or any other pointer to decode JSON into.
+func (b *Backend) do(ctx context.Context, method, path string, body []byte, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, method, b.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := b.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		if resp.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("%w: %s", errNotFound, strings.TrimSpace(string(msg)))
+		}
+		return fmt.Errorf("unexpected status code: %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	switch target := out.(type) {
+	case nil:
+		return nil
+	case *[]byte:
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response: %w", err)
+		}
+		*target = data
+		return nil
+	default:
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+		return nil
+	}
+}
+
+// statusName maps Conductor workflow statuses onto workflow service statuses
+func statusName(status string) string {
+	switch status {
+	case "RUNNING":
+		return "running"
+	case "PAUSED":
+		return "paused"
+	case "COMPLETED":
+		return "completed"
+	case "FAILED":
+		return "failed"
+	case "TIMED_OUT":
+		return "timed_out"
+	case "TERMINATED":
+		return "terminated"
+	}
+	return strings.ToLower(status)
+}
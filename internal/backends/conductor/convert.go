@@ -0,0 +1,349 @@
+package conductor
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// WorkflowDef is the subset of the Conductor workflow definition we use
+type WorkflowDef struct {
+	Name             string                 `json:"name"`
+	Description      string                 `json:"description,omitempty"`
+	Version          int                    `json:"version"`
+	Tasks            []WorkflowTask         `json:"tasks"`
+	OutputParameters map[string]interface{} `json:"outputParameters,omitempty"`
+	Variables        map[string]interface{} `json:"variables,omitempty"`
+	SchemaVersion    int                    `json:"schemaVersion"`
+	OwnerEmail       string                 `json:"ownerEmail,omitempty"`
+	TimeoutPolicy    string                 `json:"timeoutPolicy,omitempty"`
+	TimeoutSeconds   int                    `json:"timeoutSeconds"`
+}
+
+// WorkflowTask is a task entry in a Conductor workflow definition
+type WorkflowTask struct {
+	Name              string                    `json:"name"`
+	TaskReferenceName string                    `json:"taskReferenceName"`
+	Description       string                    `json:"description,omitempty"`
+	Type              string                    `json:"type"`
+	InputParameters   map[string]interface{}    `json:"inputParameters,omitempty"`
+	Optional          bool                      `json:"optional,omitempty"`
+	RetryCount        *int                      `json:"retryCount,omitempty"`
+	ForkTasks         [][]WorkflowTask          `json:"forkTasks,omitempty"`
+	JoinOn            []string                  `json:"joinOn,omitempty"`
+	EvaluatorType     string                    `json:"evaluatorType,omitempty"`
+	Expression        string                    `json:"expression,omitempty"`
+	DecisionCases     map[string][]WorkflowTask `json:"decisionCases,omitempty"`
+}
+
+// TaskDef is a Conductor task definition, registered once per executor name
+type TaskDef struct {
+	Name                   string `json:"name"`
+	RetryCount             int    `json:"retryCount"`
+	RetryLogic             string `json:"retryLogic"`
+	RetryDelaySeconds      int    `json:"retryDelaySeconds"`
+	TimeoutSeconds         int    `json:"timeoutSeconds"`
+	ResponseTimeoutSeconds int    `json:"responseTimeoutSeconds"`
+	TimeoutPolicy          string `json:"timeoutPolicy"`
+	OwnerEmail             string `json:"ownerEmail,omitempty"`
+}
+
+// pathPattern matches $.-prefixed scope paths inside condition expressions
+var pathPattern = regexp.MustCompile(`\$\.[A-Za-z0-9_.]+`)
+
+// toWorkflowDef converts a blob processing workflow into a Conductor
+// definition. Each DAG level becomes a single task or a FORK_JOIN of its
+// steps; conditional steps are wrapped in a javascript SWITCH. The original
+// step is stored as JSON in the task description so the definition can be
+// read back without loss.
+func toWorkflowDef(wf *workflows.BlobProcessingWorkflow, ownerEmail string) (*WorkflowDef, error) {
+	levels, err := wf.GetDAGOrder()
+	if err != nil {
+		return nil, err
+	}
+
+	def := &WorkflowDef{
+		Name:          wf.ID,
+		Description:   wf.Description,
+		Version:       1,
+		SchemaVersion: 2,
+		OwnerEmail:    ownerEmail,
+		TimeoutPolicy: "TIME_OUT_WF",
+		OutputParameters: map[string]interface{}{
+			"steps": stepOutputs(wf),
+		},
+		Variables: map[string]interface{}{
+			"provider_id": wf.ProviderID,
+			"name":        wf.Name,
+			"type":        string(wf.Type),
+			"config":      wf.Config,
+		},
+		TimeoutSeconds: wf.Config.MaxExecutionTime,
+	}
+
+	for i, level := range levels {
+		var branches [][]WorkflowTask
+		var joinOn []string
+		for _, step := range level {
+			task, err := toWorkflowTask(step)
+			if err != nil {
+				return nil, err
+			}
+			branches = append(branches, []WorkflowTask{task})
+			joinOn = append(joinOn, task.TaskReferenceName)
+		}
+
+		if len(branches) == 1 {
+			def.Tasks = append(def.Tasks, branches[0][0])
+			continue
+		}
+
+		fork := fmt.Sprintf("level_%d", i)
+		def.Tasks = append(def.Tasks,
+			WorkflowTask{
+				Name:              fork + "_fork",
+				TaskReferenceName: fork + "_fork",
+				Type:              "FORK_JOIN",
+				ForkTasks:         branches,
+			},
+			WorkflowTask{
+				Name:              fork + "_join",
+				TaskReferenceName: fork + "_join",
+				Type:              "JOIN",
+				JoinOn:            joinOn,
+			},
+		)
+	}
+
+	return def, nil
+}
+
+// toWorkflowTask converts a step into a SIMPLE task, wrapped in a SWITCH when
+// the step has a condition
+func toWorkflowTask(step workflows.BlobProcessingStep) (WorkflowTask, error) {
+	original, err := json.Marshal(step)
+	if err != nil {
+		return WorkflowTask{}, fmt.Errorf("failed to encode step %s: %w", step.ID, err)
+	}
+
+	task := WorkflowTask{
+		Name:              taskName(step),
+		TaskReferenceName: step.ID,
+		Description:       string(original),
+		Type:              "SIMPLE",
+		InputParameters:   toInputParameters(step.InputMap),
+		Optional:          step.OnFailure == "skip" || step.OnFailure == "continue",
+	}
+	if step.RetryPolicy != nil {
+		retries := step.RetryPolicy.MaxAttempts - 1
+		task.RetryCount = &retries
+	} else if step.Config.MaxRetries > 0 {
+		retries := step.Config.MaxRetries
+		task.RetryCount = &retries
+	}
+	if task.InputParameters == nil {
+		task.InputParameters = map[string]interface{}{}
+	}
+	task.InputParameters["_step"] = map[string]interface{}{
+		"id":         step.ID,
+		"type":       step.Type,
+		"parameters": toInputParameters(step.Config.Parameters),
+		// Workers apply it with workflows.ModelPolicy.Apply
+		"model_policy": "${workflow.input.model_policy}",
+	}
+
+	if step.Condition == "" {
+		return task, nil
+	}
+
+	params := map[string]interface{}{}
+	n := 0
+	expression := pathPattern.ReplaceAllStringFunc(step.Condition, func(path string) string {
+		name := fmt.Sprintf("p%d", n)
+		n++
+		params[name] = toExpression(path)
+		return "$." + name
+	})
+	expression = strings.NewReplacer("&&", " && ", "||", " || ").Replace(expression)
+
+	return WorkflowTask{
+		Name:              step.ID + "_condition",
+		TaskReferenceName: step.ID + "_condition",
+		Type:              "SWITCH",
+		EvaluatorType:     "javascript",
+		Expression:        fmt.Sprintf("(%s) ? 'run' : 'skip'", expression),
+		InputParameters:   params,
+		DecisionCases: map[string][]WorkflowTask{
+			"run": {task},
+		},
+	}, nil
+}
+
+// taskName picks the Conductor task definition a step runs as. Workers poll
+// by this name, so it matches the step executor registry keys.
+func taskName(step workflows.BlobProcessingStep) string {
+	if step.ProviderID != "" {
+		return step.ProviderID
+	}
+	return step.Type
+}
+
+// toInputParameters rewrites scope paths in an input map into Conductor
+// ${...} expressions
+func toInputParameters(inputMap map[string]interface{}) map[string]interface{} {
+	if inputMap == nil {
+		return nil
+	}
+	params := make(map[string]interface{}, len(inputMap))
+	for key, value := range inputMap {
+		params[key] = toParameterValue(value)
+	}
+	return params
+}
+
+// toParameterValue rewrites a single mapping value
+func toParameterValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		if strings.HasPrefix(v, "$.") {
+			return toExpression(v)
+		}
+		return v
+	case map[string]interface{}:
+		return toInputParameters(v)
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = toParameterValue(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// toExpression maps a scope path onto the equivalent Conductor expression.
+// Step outputs are addressed by task reference name; everything else lives
+// under the workflow input.
+func toExpression(path string) string {
+	rest := strings.TrimPrefix(path, "$.")
+	switch {
+	case strings.HasPrefix(rest, "steps."):
+		return "${" + strings.TrimPrefix(rest, "steps.") + "}"
+	case strings.HasPrefix(rest, "input."):
+		return "${workflow.input." + strings.TrimPrefix(rest, "input.") + "}"
+	case strings.HasPrefix(rest, "provider.config"):
+		return "${workflow.input.parameters" + strings.TrimPrefix(rest, "provider.config") + "}"
+	case strings.HasPrefix(rest, "execution.id"):
+		return "${workflow.workflowId}"
+	default:
+		return "${workflow.input." + rest + "}"
+	}
+}
+
+// stepOutputs builds the workflow output parameters exposing each step output
+func stepOutputs(wf *workflows.BlobProcessingWorkflow) map[string]interface{} {
+	outputs := make(map[string]interface{}, len(wf.Steps))
+	for _, step := range wf.Steps {
+		outputs[step.ID] = "${" + step.ID + ".output}"
+	}
+	return outputs
+}
+
+// taskDefs builds one task definition per executor name used by the workflow,
+// taking the most generous timeout and retry settings among its steps
+func taskDefs(wf *workflows.BlobProcessingWorkflow, ownerEmail string) []TaskDef {
+	byName := make(map[string]*TaskDef)
+	var order []string
+
+	for _, step := range wf.Steps {
+		name := taskName(step)
+		def, ok := byName[name]
+		if !ok {
+			def = &TaskDef{
+				Name:              name,
+				RetryLogic:        "EXPONENTIAL_BACKOFF",
+				RetryDelaySeconds: wf.Config.RetryDelay,
+				TimeoutSeconds:    60,
+				TimeoutPolicy:     "RETRY",
+				OwnerEmail:        ownerEmail,
+			}
+			byName[name] = def
+			order = append(order, name)
+		}
+		if step.Config.Timeout > def.TimeoutSeconds {
+			def.TimeoutSeconds = step.Config.Timeout
+		}
+		if step.Config.MaxRetries > def.RetryCount {
+			def.RetryCount = step.Config.MaxRetries
+		}
+	}
+
+	defs := make([]TaskDef, 0, len(order))
+	for _, name := range order {
+		def := byName[name]
+		def.ResponseTimeoutSeconds = def.TimeoutSeconds
+		defs = append(defs, *def)
+	}
+	return defs
+}
+
+// fromWorkflowDef rebuilds a blob processing workflow from a Conductor
+// definition created by toWorkflowDef
+func fromWorkflowDef(def *WorkflowDef) (*workflows.BlobProcessingWorkflow, error) {
+	wf := &workflows.BlobProcessingWorkflow{
+		ID:          def.Name,
+		Name:        def.Name,
+		Description: def.Description,
+		Type:        workflows.WorkflowTypeProcessBlob,
+		Config: workflows.ProcessingConfig{
+			MaxExecutionTime: def.TimeoutSeconds,
+		},
+	}
+	if providerID, ok := def.Variables["provider_id"].(string); ok {
+		wf.ProviderID = providerID
+	}
+	if name, ok := def.Variables["name"].(string); ok && name != "" {
+		wf.Name = name
+	}
+	if t, ok := def.Variables["type"].(string); ok && t != "" {
+		wf.Type = workflows.WorkflowType(t)
+	}
+	if config, ok := def.Variables["config"]; ok {
+		data, _ := json.Marshal(config)
+		json.Unmarshal(data, &wf.Config)
+	}
+
+	var collect func(tasks []WorkflowTask) error
+	collect = func(tasks []WorkflowTask) error {
+		for _, task := range tasks {
+			switch task.Type {
+			case "SIMPLE":
+				var step workflows.BlobProcessingStep
+				if err := json.Unmarshal([]byte(task.Description), &step); err != nil {
+					return fmt.Errorf("task %s was not created by the studio: %w", task.TaskReferenceName, err)
+				}
+				wf.Steps = append(wf.Steps, step)
+			case "FORK_JOIN":
+				for _, branch := range task.ForkTasks {
+					if err := collect(branch); err != nil {
+						return err
+					}
+				}
+			case "SWITCH":
+				if err := collect(task.DecisionCases["run"]); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	if err := collect(def.Tasks); err != nil {
+		return nil, err
+	}
+	return wf, nil
+}
@@ -0,0 +1,323 @@
+// Package temporal executes blob processing workflows on Temporal, mapping
+// each workflow DAG onto a Temporal workflow and each step onto an activity
+package temporal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+	sdktemporal "go.temporal.io/sdk/temporal"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// DefaultTaskQueue is the task queue used when none is configured
+const DefaultTaskQueue = "memmie-studio-blob-processing"
+
+// Config holds connection settings for the Temporal backend
+type Config struct {
+	HostPort  string `json:"host_port"`
+	Namespace string `json:"namespace"`
+	TaskQueue string `json:"task_queue"`
+}
+
+// Backend implements workflows.WorkflowService on top of a Temporal cluster.
+// Workflow definitions are kept in memory and passed with each start request,
+// so every execution history carries the exact definition it ran.
+type Backend struct {
+	client    client.Client
+	taskQueue string
+	workflows map[string]*workflows.BlobProcessingWorkflow
+	mu        sync.RWMutex
+}
+
+// NewBackend creates a backend using an existing Temporal client
+func NewBackend(c client.Client, taskQueue string) *Backend {
+	if taskQueue == "" {
+		taskQueue = DefaultTaskQueue
+	}
+	return &Backend{
+		client:    c,
+		taskQueue: taskQueue,
+		workflows: make(map[string]*workflows.BlobProcessingWorkflow),
+	}
+}
+
+func init() {
+	workflows.RegisterBackend("temporal", func(cfg workflows.BackendConfig) (workflows.WorkflowService, error) {
+		return Dial(Config{
+			HostPort:  cfg.URL,
+			Namespace: cfg.Namespace,
+			TaskQueue: cfg.TaskQueue,
+		})
+	})
+}
+
+// Dial connects to Temporal and creates a backend
+func Dial(cfg Config) (*Backend, error) {
+	c, err := client.Dial(client.Options{
+		HostPort:  cfg.HostPort,
+		Namespace: cfg.Namespace,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to temporal: %w", err)
+	}
+	return NewBackend(c, cfg.TaskQueue), nil
+}
+
+// Client returns the underlying Temporal client
+func (b *Backend) Client() client.Client {
+	return b.client
+}
+
+// TaskQueue returns the task queue workflows are started on
+func (b *Backend) TaskQueue() string {
+	return b.taskQueue
+}
+
+// Close closes the Temporal client
+func (b *Backend) Close() {
+	b.client.Close()
+}
+
+// ExecuteWorkflow starts a Temporal workflow for the request. The Temporal
+// workflow ID is derived from the request ID, so retried submissions of the
+// same request attach to the existing execution instead of starting another.
+func (b *Backend) ExecuteWorkflow(ctx context.Context, req workflows.ExecutionRequest) (*workflows.ExecutionResponse, error) {
+	definition, err := b.GetWorkflow(ctx, req.WorkflowID)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := client.StartWorkflowOptions{
+		ID:        executionID(req),
+		TaskQueue: b.taskQueue,
+	}
+	if definition.Config.MaxExecutionTime > 0 {
+		opts.WorkflowExecutionTimeout = time.Duration(definition.Config.MaxExecutionTime) * time.Second
+	}
+
+	startedAt := time.Now()
+	run, err := b.client.ExecuteWorkflow(ctx, opts, WorkflowName, WorkflowInput{
+		Definition: definition,
+		Request:    req,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start temporal workflow: %w", err)
+	}
+
+	resp := &workflows.ExecutionResponse{
+		ExecutionID: run.GetID(),
+		Status:      "running",
+		StartedAt:   startedAt,
+	}
+	if req.Async {
+		return resp, nil
+	}
+
+	var result WorkflowResult
+	if err := run.Get(ctx, &result); err != nil {
+		completedAt := time.Now()
+		resp.Status = "failed"
+		resp.CompletedAt = &completedAt
+		resp.Error = executionError(resp.Status, err)
+		return resp, nil
+	}
+
+	completedAt := time.Now()
+	resp.Status = "completed"
+	resp.Output = result.Output
+	resp.CompletedAt = &completedAt
+	return resp, nil
+}
+
+// GetExecutionStatus describes a Temporal workflow execution, fetching its
+// result when it has completed
+func (b *Backend) GetExecutionStatus(ctx context.Context, executionID string) (*workflows.ExecutionResponse, error) {
+	desc, err := b.client.DescribeWorkflowExecution(ctx, executionID, "")
+	if err != nil {
+		var notFound *serviceerror.NotFound
+		if errors.As(err, &notFound) {
+			return nil, fmt.Errorf("%w: %s", workflows.ErrExecutionNotFound, executionID)
+		}
+		return nil, fmt.Errorf("failed to describe execution: %w", err)
+	}
+
+	info := desc.GetWorkflowExecutionInfo()
+	resp := &workflows.ExecutionResponse{
+		ExecutionID: executionID,
+		Status:      statusName(info.GetStatus()),
+		StartedAt:   info.GetStartTime().AsTime(),
+	}
+	if info.GetCloseTime() != nil {
+		closedAt := info.GetCloseTime().AsTime()
+		resp.CompletedAt = &closedAt
+	}
+
+	switch info.GetStatus() {
+	case enums.WORKFLOW_EXECUTION_STATUS_COMPLETED:
+		var result WorkflowResult
+		if err := b.client.GetWorkflow(ctx, executionID, "").Get(ctx, &result); err != nil {
+			return nil, fmt.Errorf("failed to fetch execution result: %w", err)
+		}
+		resp.Output = result.Output
+	case enums.WORKFLOW_EXECUTION_STATUS_FAILED,
+		enums.WORKFLOW_EXECUTION_STATUS_TIMED_OUT,
+		enums.WORKFLOW_EXECUTION_STATUS_TERMINATED:
+		err := b.client.GetWorkflow(ctx, executionID, "").Get(ctx, nil)
+		resp.Error = executionError(resp.Status, err)
+	}
+
+	return resp, nil
+}
+
+// ExecutionProgress queries a running workflow for its output so far,
+// holding the results of the steps that have finished
+func (b *Backend) ExecutionProgress(ctx context.Context, executionID string) (map[string]interface{}, error) {
+	value, err := b.client.QueryWorkflow(ctx, executionID, "", ProgressQueryName)
+	if err != nil {
+		var notFound *serviceerror.NotFound
+		if errors.As(err, &notFound) {
+			return nil, fmt.Errorf("%w: %s", workflows.ErrExecutionNotFound, executionID)
+		}
+		return nil, fmt.Errorf("failed to query execution progress: %w", err)
+	}
+	var output map[string]interface{}
+	if err := value.Get(&output); err != nil {
+		return nil, fmt.Errorf("failed to decode execution progress: %w", err)
+	}
+	return output, nil
+}
+
+// executionError describes why an execution ended with a status. Outputs
+// that did not match their schema are reported as schema_violation with
+// the violations.
+func executionError(status string, err error) *workflows.ExecutionError {
+	for cause := err; cause != nil; cause = errors.Unwrap(cause) {
+		var appErr *sdktemporal.ApplicationError
+		if !errors.As(cause, &appErr) {
+			break
+		}
+		var violation workflows.SchemaViolationError
+		if appErr.Type() == SchemaViolationType && appErr.Details(&violation) == nil {
+			return &workflows.ExecutionError{
+				Code:       "schema_violation",
+				Message:    fmt.Sprint(err),
+				StepID:     violation.StepID,
+				Violations: violation.Violations,
+			}
+		}
+		cause = appErr
+	}
+	return &workflows.ExecutionError{
+		Code:    "workflow_" + status,
+		Message: fmt.Sprint(err),
+	}
+}
+
+// CancelExecution requests cancellation of a running Temporal workflow
+func (b *Backend) CancelExecution(ctx context.Context, executionID string) error {
+	if err := b.client.CancelWorkflow(ctx, executionID, ""); err != nil {
+		var notFound *serviceerror.NotFound
+		if errors.As(err, &notFound) {
+			return fmt.Errorf("%w: %s", workflows.ErrExecutionNotFound, executionID)
+		}
+		return fmt.Errorf("failed to cancel execution: %w", err)
+	}
+	return nil
+}
+
+// RegisterWorkflow stores a workflow definition after checking its DAG
+func (b *Backend) RegisterWorkflow(ctx context.Context, workflow *workflows.BlobProcessingWorkflow) error {
+	if _, err := workflow.GetDAGOrder(); err != nil {
+		return fmt.Errorf("invalid workflow %s: %w", workflow.ID, err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.workflows[workflow.ID] = workflow
+	return nil
+}
+
+// UpdateWorkflow replaces a stored workflow definition. Running executions
+// keep the definition they were started with.
+func (b *Backend) UpdateWorkflow(ctx context.Context, workflow *workflows.BlobProcessingWorkflow) error {
+	b.mu.RLock()
+	_, exists := b.workflows[workflow.ID]
+	b.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("workflow %s not found", workflow.ID)
+	}
+	return b.RegisterWorkflow(ctx, workflow)
+}
+
+// DeleteWorkflow removes a stored workflow definition. Running executions
+// are not affected.
+func (b *Backend) DeleteWorkflow(ctx context.Context, workflowID string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.workflows, workflowID)
+	return nil
+}
+
+// GetWorkflow returns a stored workflow definition
+func (b *Backend) GetWorkflow(ctx context.Context, workflowID string) (*workflows.BlobProcessingWorkflow, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	workflow, exists := b.workflows[workflowID]
+	if !exists {
+		return nil, fmt.Errorf("workflow %s not found", workflowID)
+	}
+	return workflow, nil
+}
+
+// ListWorkflows lists stored workflows for a provider, or all workflows when
+// providerID is empty
+func (b *Backend) ListWorkflows(ctx context.Context, providerID string) ([]*workflows.BlobProcessingWorkflow, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	var result []*workflows.BlobProcessingWorkflow
+	for _, workflow := range b.workflows {
+		if providerID == "" || workflow.ProviderID == providerID {
+			result = append(result, workflow)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result, nil
+}
+
+// executionID derives a stable Temporal workflow ID for a request
+func executionID(req workflows.ExecutionRequest) string {
+	return fmt.Sprintf("%s/%s/%s", req.Context.ProviderID, req.WorkflowID, req.Context.RequestID)
+}
+
+// statusName maps Temporal execution statuses onto workflow service statuses
+func statusName(status enums.WorkflowExecutionStatus) string {
+	switch status {
+	case enums.WORKFLOW_EXECUTION_STATUS_RUNNING, enums.WORKFLOW_EXECUTION_STATUS_CONTINUED_AS_NEW:
+		return "running"
+	case enums.WORKFLOW_EXECUTION_STATUS_COMPLETED:
+		return "completed"
+	case enums.WORKFLOW_EXECUTION_STATUS_FAILED:
+		return "failed"
+	case enums.WORKFLOW_EXECUTION_STATUS_CANCELED:
+		return "cancelled"
+	case enums.WORKFLOW_EXECUTION_STATUS_TERMINATED:
+		return "terminated"
+	case enums.WORKFLOW_EXECUTION_STATUS_TIMED_OUT:
+		return "timed_out"
+	}
+	return "unknown"
+}
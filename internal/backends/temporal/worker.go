@@ -0,0 +1,28 @@
+package temporal
+
+import (
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// NewWorker creates a Temporal worker on the backend's task queue that runs
+// blob processing workflows and executes steps through registry
+func (b *Backend) NewWorker(registry *workflows.StepRegistry, opts worker.Options) worker.Worker {
+	w := worker.New(b.client, b.taskQueue, opts)
+
+	w.RegisterWorkflowWithOptions(BlobProcessingWorkflow, workflow.RegisterOptions{
+		Name: WorkflowName,
+	})
+	activities := NewActivities(registry)
+	w.RegisterActivityWithOptions(activities.RunStep, activity.RegisterOptions{
+		Name: StepActivityName,
+	})
+	w.RegisterActivityWithOptions(activities.ValidateOutput, activity.RegisterOptions{
+		Name: OutputActivityName,
+	})
+
+	return w
+}
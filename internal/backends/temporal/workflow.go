@@ -0,0 +1,330 @@
+package temporal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+	sdktemporal "go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+const (
+	// WorkflowName is the Temporal workflow type that runs blob processing DAGs
+	WorkflowName = "BlobProcessingWorkflow"
+
+	// StepActivityName is the Temporal activity type that runs a single step
+	StepActivityName = "RunBlobProcessingStep"
+
+	// OutputActivityName is the Temporal activity type that checks a
+	// workflow's output against its output schema
+	OutputActivityName = "ValidateBlobProcessingOutput"
+
+	// ProgressQueryName is the Temporal query that returns a running
+	// workflow's output so far
+	ProgressQueryName = "progress"
+
+	// SchemaViolationType is the application error type of outputs that do
+	// not match their schema; the error's details hold the
+	// workflows.SchemaViolationError
+	SchemaViolationType = "SchemaViolation"
+
+	// StepTypeDelay is a step type handled inside the workflow as a durable
+	// timer instead of an activity. The duration is read from the
+	// "seconds" step parameter.
+	StepTypeDelay = "delay"
+
+	defaultStepTimeout = 60 * time.Second
+)
+
+// WorkflowInput is the start payload for a blob processing workflow
+type WorkflowInput struct {
+	Definition *workflows.BlobProcessingWorkflow `json:"definition"`
+	Request    workflows.ExecutionRequest        `json:"request"`
+}
+
+// WorkflowResult is the result of a completed blob processing workflow
+type WorkflowResult struct {
+	Output map[string]interface{} `json:"output"`
+}
+
+// BlobProcessingWorkflow runs the definition's DAG level by level. Steps in a
+// level run as parallel activities; conditions, input mappings and step
+// parameters are evaluated in the workflow so they are recorded in history,
+// as are the mutex keys steps hold while they run. A request with a
+// deadline gives up on the steps still to finish when it passes and
+// completes with a partial output of the rest.
+func BlobProcessingWorkflow(ctx workflow.Context, in WorkflowInput) (*WorkflowResult, error) {
+	logger := workflow.GetLogger(ctx)
+	executionID := workflow.GetInfo(ctx).WorkflowExecution.ID
+
+	levels, err := in.Definition.GetDAGOrder()
+	if err != nil {
+		return nil, sdktemporal.NewNonRetryableApplicationError(err.Error(), "InvalidWorkflow", err)
+	}
+
+	scope := workflows.NewExecutionScope(executionID, in.Request)
+	var order []string
+	if err := workflow.SetQueryHandler(ctx, ProgressQueryName, func() (map[string]interface{}, error) {
+		return scope.Output(order), nil
+	}); err != nil {
+		return nil, err
+	}
+	var deadline time.Time
+	if in.Request.DeadlineMs > 0 {
+		deadline = workflow.Now(ctx).Add(time.Duration(in.Request.DeadlineMs) * time.Millisecond)
+	}
+	// pastDeadline reports whether the deadline, if any, has passed
+	pastDeadline := func() bool {
+		return !deadline.IsZero() && !workflow.Now(ctx).Before(deadline)
+	}
+
+	for _, level := range levels {
+		if pastDeadline() {
+			for _, step := range level {
+				order = append(order, step.ID)
+				scope.SetStepSkipped(step.ID, workflows.DeadlineExceededReason)
+			}
+			scope.SetPartial()
+			continue
+		}
+
+		type pending struct {
+			step   workflows.BlobProcessingStep
+			future workflow.Future
+			timer  bool
+		}
+		var running []pending
+
+		for _, step := range level {
+			order = append(order, step.ID)
+
+			ok, err := scope.Evaluate(step.Condition)
+			if err != nil {
+				return nil, sdktemporal.NewNonRetryableApplicationError(
+					fmt.Sprintf("step %s: invalid condition: %v", step.ID, err), "InvalidCondition", err)
+			}
+			if !ok {
+				scope.SetStepSkipped(step.ID, "condition not met")
+				continue
+			}
+
+			if step.Type == StepTypeDelay {
+				if !deadline.IsZero() && workflow.Now(ctx).Add(delayDuration(step)).After(deadline) {
+					scope.SetStepSkipped(step.ID, workflows.DeadlineExceededReason)
+					scope.SetPartial()
+					continue
+				}
+				timer := workflow.NewTimer(ctx, delayDuration(step))
+				running = append(running, pending{step: step, future: timer, timer: true})
+				continue
+			}
+
+			mutexKey, err := scope.MutexKey(in.Definition.StepMutexKey(step))
+			if err != nil {
+				return nil, sdktemporal.NewNonRetryableApplicationError(
+					fmt.Sprintf("step %s: %v", step.ID, err), "InvalidMutexKey", err)
+			}
+
+			parameters, input := scope.SelectStep(step)
+			step.Config.Parameters = parameters
+			req := workflows.StepRequest{
+				ExecutionID: executionID,
+				WorkflowID:  in.Definition.ID,
+				Step:        step,
+				Input:       input,
+				Context:     in.Request.Context,
+				MutexKey:    mutexKey,
+			}
+			options := activityOptions(step, in.Definition.Config)
+			if !deadline.IsZero() {
+				options = withDeadline(options, deadline.Sub(workflow.Now(ctx)))
+			}
+			actx := workflow.WithActivityOptions(ctx, options)
+			running = append(running, pending{step: step, future: workflow.ExecuteActivity(actx, StepActivityName, req)})
+		}
+
+		// Note when each step finishes, so its duration does not include
+		// waiting on the steps before it in the level
+		scheduled := workflow.Now(ctx)
+		finished := make([]time.Time, len(running))
+		selector := workflow.NewSelector(ctx)
+		for i, p := range running {
+			i := i
+			selector.AddFuture(p.future, func(workflow.Future) { finished[i] = workflow.Now(ctx) })
+		}
+		for range running {
+			selector.Select(ctx)
+		}
+
+		for i, p := range running {
+			if p.timer {
+				if err := p.future.Get(ctx, nil); err != nil {
+					return nil, err
+				}
+				scope.SetStepOutput(p.step.ID, map[string]interface{}{})
+				continue
+			}
+
+			var output map[string]interface{}
+			if err := p.future.Get(ctx, &output); err != nil {
+				if !deadline.IsZero() && !finished[i].Before(deadline) {
+					// Timed out at the deadline rather than failed
+					scope.SetStepSkipped(p.step.ID, workflows.DeadlineExceededReason)
+					scope.SetPartial()
+					continue
+				}
+				if p.step.OnFailure == "skip" || p.step.OnFailure == "continue" {
+					logger.Warn("Step failed, continuing", "step_id", p.step.ID, "error", err)
+					scope.SetStepFailed(p.step.ID, err)
+					scope.SetStepDuration(p.step.ID, finished[i].Sub(scheduled))
+					continue
+				}
+				return nil, fmt.Errorf("step %s failed: %w", p.step.ID, err)
+			}
+			scope.SetStepOutput(p.step.ID, output)
+			scope.SetStepDuration(p.step.ID, finished[i].Sub(scheduled))
+		}
+	}
+
+	output := scope.Output(order)
+	// A partial output is short of steps its schema may require, and is
+	// better than none
+	if in.Definition.OutputSchemaID != "" && !workflows.IsPartialOutput(output) {
+		actx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+			StartToCloseTimeout: defaultStepTimeout,
+			RetryPolicy:         &sdktemporal.RetryPolicy{MaximumAttempts: 1},
+		})
+		if err := workflow.ExecuteActivity(actx, OutputActivityName, in.Definition.OutputSchemaID, output).Get(ctx, nil); err != nil {
+			return nil, err
+		}
+	}
+	return &WorkflowResult{Output: output}, nil
+}
+
+// activityOptions maps step timeouts and retry policies onto activity options
+func activityOptions(step workflows.BlobProcessingStep, config workflows.ProcessingConfig) workflow.ActivityOptions {
+	timeout := defaultStepTimeout
+	if step.Config.Timeout > 0 {
+		timeout = time.Duration(step.Config.Timeout) * time.Second
+	}
+
+	retry := &sdktemporal.RetryPolicy{MaximumAttempts: 1}
+	switch {
+	case step.RetryPolicy != nil:
+		retry = &sdktemporal.RetryPolicy{
+			InitialInterval:    time.Duration(step.RetryPolicy.InitialDelay) * time.Millisecond,
+			BackoffCoefficient: step.RetryPolicy.BackoffMultiplier,
+			MaximumInterval:    time.Duration(step.RetryPolicy.MaxDelay) * time.Millisecond,
+			MaximumAttempts:    int32(step.RetryPolicy.MaxAttempts),
+		}
+	case step.Config.MaxRetries > 0:
+		retry.MaximumAttempts = int32(step.Config.MaxRetries + 1)
+		if config.RetryDelay > 0 {
+			retry.InitialInterval = time.Duration(config.RetryDelay) * time.Second
+		}
+	case config.AutoRetry:
+		retry.MaximumAttempts = 3
+		if config.RetryDelay > 0 {
+			retry.InitialInterval = time.Duration(config.RetryDelay) * time.Second
+		}
+	}
+	if retry.BackoffCoefficient != 0 && retry.BackoffCoefficient < 1 {
+		retry.BackoffCoefficient = 1
+	}
+
+	return workflow.ActivityOptions{
+		StartToCloseTimeout: timeout,
+		RetryPolicy:         retry,
+	}
+}
+
+// withDeadline bounds activity options by the time left before an
+// execution's deadline, retries included
+func withDeadline(options workflow.ActivityOptions, left time.Duration) workflow.ActivityOptions {
+	if left < time.Millisecond {
+		left = time.Millisecond
+	}
+	if options.StartToCloseTimeout > left {
+		options.StartToCloseTimeout = left
+	}
+	options.ScheduleToCloseTimeout = left
+	return options
+}
+
+// delayDuration reads the duration of a delay step
+func delayDuration(step workflows.BlobProcessingStep) time.Duration {
+	switch seconds := step.Config.Parameters["seconds"].(type) {
+	case float64:
+		return time.Duration(seconds * float64(time.Second))
+	case int:
+		return time.Duration(seconds) * time.Second
+	}
+	return 0
+}
+
+// Activities runs workflow steps through the registered step executors
+type Activities struct {
+	registry *workflows.StepRegistry
+}
+
+// NewActivities creates activities backed by a step registry
+func NewActivities(registry *workflows.StepRegistry) *Activities {
+	return &Activities{registry: registry}
+}
+
+// RunStep executes a single step. Missing executors and step errors that
+// workflows.ClassifyError finds permanent are reported as non-retryable, so
+// Temporal neither spins on a misconfigured worker nor pays again for a
+// request that cannot succeed. The step runs in the trace of the request
+// that started the execution, so what it calls and publishes joins it.
+func (a *Activities) RunStep(ctx context.Context, req workflows.StepRequest) (map[string]interface{}, error) {
+	executor, err := a.registry.Lookup(req.Step)
+	if err != nil {
+		return nil, sdktemporal.NewNonRetryableApplicationError(err.Error(), "NoStepExecutor", err)
+	}
+
+	activity.GetLogger(ctx).Info("Running step",
+		"execution_id", req.ExecutionID,
+		"step_id", req.Step.ID,
+		"attempt", activity.GetInfo(ctx).Attempt,
+	)
+
+	ctx = workflows.ContextWithTrace(ctx, req.Context.Trace())
+	output, err := executor.Execute(ctx, req)
+	if violation := schemaViolation(err); violation != nil {
+		return nil, violation
+	}
+	if err != nil && !workflows.IsRetryable(err) {
+		return nil, sdktemporal.NewNonRetryableApplicationError(err.Error(), "PermanentStepError", err)
+	}
+	return output, err
+}
+
+// ValidateOutput checks a workflow's output against a registered schema.
+// Mismatches and unknown schemas fail the workflow without retrying.
+func (a *Activities) ValidateOutput(ctx context.Context, schemaID string, output map[string]interface{}) error {
+	err := workflows.CheckOutput(schemaID, "", output)
+	if violation := schemaViolation(err); violation != nil {
+		return violation
+	}
+	if err != nil {
+		return sdktemporal.NewNonRetryableApplicationError(err.Error(), "InvalidOutputSchema", err)
+	}
+	return nil
+}
+
+// schemaViolation converts a schema violation into a non-retryable
+// application error carrying the violations, or returns nil for other
+// errors
+func schemaViolation(err error) error {
+	var violation *workflows.SchemaViolationError
+	if !errors.As(err, &violation) {
+		return nil
+	}
+	return sdktemporal.NewNonRetryableApplicationError(err.Error(), SchemaViolationType, err, violation)
+}
@@ -0,0 +1,147 @@
+package bench
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+var profileDir = flag.String("bench.profile", "", "write pprof captures for each benchmark into this directory")
+
+// run executes fn b.N times, optionally wrapping the loop in a pprof capture
+func run(b *testing.B, fn func()) {
+	b.Helper()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	if *profileDir == "" {
+		for i := 0; i < b.N; i++ {
+			fn()
+		}
+		return
+	}
+
+	name := strings.ReplaceAll(b.Name(), "/", "_")
+	if err := Capture(*profileDir, name, b.N, fn); err != nil {
+		b.Fatalf("profile capture failed: %v", err)
+	}
+}
+
+func BenchmarkGetDAGOrder(b *testing.B) {
+	cases := []struct {
+		name     string
+		workflow *workflows.BlobProcessingWorkflow
+	}{
+		{"linear_10", LinearWorkflow(10)},
+		{"linear_100", LinearWorkflow(100)},
+		{"linear_1000", LinearWorkflow(1000)},
+		{"fanout_10x10", FanOutWorkflow(10, 10)},
+		{"fanout_50x4", FanOutWorkflow(50, 4)},
+	}
+
+	for _, tc := range cases {
+		b.Run(tc.name, func(b *testing.B) {
+			run(b, func() {
+				if _, err := tc.workflow.GetDAGOrder(); err != nil {
+					b.Fatal(err)
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkExtractDeltas(b *testing.B) {
+	for _, n := range []int{1, 10, 100, 1000} {
+		output := DeltaOutput(n)
+		b.Run(fmt.Sprintf("deltas_%d", n), func(b *testing.B) {
+			run(b, func() {
+				workflows.ExtractDeltas(output, "bench-provider", "bench-blob")
+			})
+		})
+	}
+}
+
+func BenchmarkApplyDeltas(b *testing.B) {
+	ctx := context.Background()
+	for _, n := range []int{1, 10, 100, 1000} {
+		deltas := workflows.ExtractDeltas(DeltaOutput(n), "bench-provider", "bench-blob")
+		b.Run(fmt.Sprintf("deltas_%d", n), func(b *testing.B) {
+			storage := NewMemoryStorage()
+			run(b, func() {
+				for _, delta := range deltas {
+					if err := storage.Store(ctx, delta); err != nil {
+						b.Fatal(err)
+					}
+				}
+				if err := storage.ApplyDeltas(ctx, "bench-blob", deltas); err != nil {
+					b.Fatal(err)
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkTriggerMatching measures provider selection and condition
+// evaluation. Providers are registered inactive so ProcessBlob stops after
+// matching and never reaches the workflow service.
+func BenchmarkTriggerMatching(b *testing.B) {
+	ctx := context.Background()
+	conditions := []workflows.TriggerCondition{
+		{Field: "metadata.type", Operator: "eq", Value: "chapter"},
+		{Field: "metadata.status", Operator: "in", Value: []interface{}{"draft", "outline"}},
+	}
+
+	for _, n := range []int{10, 100, 1000} {
+		b.Run(fmt.Sprintf("providers_%d", n), func(b *testing.B) {
+			server := StubWorkflowService(LinearWorkflow(1), nil)
+			defer server.Close()
+
+			orch := workflows.NewOrchestrator(server.URL, &CountingBus{}, NewMemoryStorage())
+			for _, p := range Providers(n, "onUpdate", false, conditions) {
+				if err := orch.RegisterProvider(ctx, p); err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			run(b, func() {
+				if err := orch.ProcessBlob(ctx, "bench-blob", "bench-user", "onUpdate"); err != nil {
+					b.Fatal(err)
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkProcessBlobPublish measures a full synchronous execution against a
+// local stub workflow service, including delta storage and event publishing
+func BenchmarkProcessBlobPublish(b *testing.B) {
+	ctx := context.Background()
+
+	for _, n := range []int{1, 10, 100} {
+		b.Run(fmt.Sprintf("deltas_%d", n), func(b *testing.B) {
+			server := StubWorkflowService(LinearWorkflow(1), DeltaOutput(n))
+			defer server.Close()
+
+			bus := &CountingBus{}
+			storage := NewMemoryStorage()
+			orch := workflows.NewOrchestrator(server.URL, bus, storage)
+			for _, p := range Providers(1, "onUpdate", true, nil) {
+				if err := orch.RegisterProvider(ctx, p); err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			run(b, func() {
+				if err := orch.ProcessBlob(ctx, "bench-blob", "bench-user", "onUpdate"); err != nil {
+					b.Fatal(err)
+				}
+			})
+
+			b.ReportMetric(float64(bus.Published())/float64(b.N), "events/op")
+		})
+	}
+}
@@ -0,0 +1,328 @@
+// Package bench provides reproducible fixtures and profiling helpers for
+// benchmarking the workflow orchestration hot paths
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// LinearWorkflow builds a workflow of n steps where each step depends on the previous one
+func LinearWorkflow(n int) *workflows.BlobProcessingWorkflow {
+	wf := &workflows.BlobProcessingWorkflow{
+		ID:         fmt.Sprintf("bench_linear_%d", n),
+		ProviderID: "bench",
+		Name:       "Linear Benchmark Workflow",
+		Type:       workflows.WorkflowTypeProcessBlob,
+	}
+
+	for i := 0; i < n; i++ {
+		step := workflows.BlobProcessingStep{
+			ID:         fmt.Sprintf("step_%d", i),
+			Name:       fmt.Sprintf("Step %d", i),
+			ProviderID: "bench",
+			Type:       "transform",
+		}
+		if i > 0 {
+			step.Dependencies = []string{fmt.Sprintf("step_%d", i-1)}
+		}
+		wf.Steps = append(wf.Steps, step)
+	}
+
+	return wf
+}
+
+// FanOutWorkflow builds a layered workflow of the given depth where every step
+// in a layer depends on every step of the previous layer
+func FanOutWorkflow(width, depth int) *workflows.BlobProcessingWorkflow {
+	wf := &workflows.BlobProcessingWorkflow{
+		ID:         fmt.Sprintf("bench_fanout_%dx%d", width, depth),
+		ProviderID: "bench",
+		Name:       "Fan-out Benchmark Workflow",
+		Type:       workflows.WorkflowTypeProcessBlob,
+	}
+
+	var previous []string
+	for d := 0; d < depth; d++ {
+		var layer []string
+		for w := 0; w < width; w++ {
+			id := fmt.Sprintf("step_%d_%d", d, w)
+			wf.Steps = append(wf.Steps, workflows.BlobProcessingStep{
+				ID:           id,
+				Name:         id,
+				ProviderID:   "bench",
+				Type:         "transform",
+				Dependencies: previous,
+			})
+			layer = append(layer, id)
+		}
+		previous = layer
+	}
+
+	return wf
+}
+
+// DeltaOutput builds a workflow output map carrying n explicit deltas
+func DeltaOutput(n int) map[string]interface{} {
+	deltas := make([]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		deltas = append(deltas, map[string]interface{}{
+			"type":      "update",
+			"path":      fmt.Sprintf("/sections/%d/content", i),
+			"old_value": "draft",
+			"new_value": strings.Repeat("expanded text ", 16),
+			"metadata": map[string]interface{}{
+				"step_id": fmt.Sprintf("step_%d", i),
+			},
+		})
+	}
+	return map[string]interface{}{"deltas": deltas}
+}
+
+// Providers builds n providers subscribed to eventType, each carrying the
+// given trigger conditions
+func Providers(n int, eventType string, active bool, conditions []workflows.TriggerCondition) []*workflows.Provider {
+	providers := make([]*workflows.Provider, 0, n)
+	for i := 0; i < n; i++ {
+		providers = append(providers, &workflows.Provider{
+			ID:          fmt.Sprintf("bench-provider-%d", i),
+			Name:        fmt.Sprintf("Bench Provider %d", i),
+			Type:        "processor",
+			WorkflowIDs: []string{"bench_workflow"},
+			Triggers: []workflows.TriggerConfig{
+				{Event: eventType, Conditions: conditions},
+			},
+			Active: active,
+		})
+	}
+	return providers
+}
+
+// MemoryStorage is a minimal in-memory DeltaStorage used as a benchmark sink.
+// It is also an OutboxStorage, committing deltas and their events under one
+// lock, a BatchStorage and a StateStorage.
+type MemoryStorage struct {
+	mu     sync.Mutex
+	deltas map[string][]workflows.Delta
+	state  map[string]map[string]interface{}
+	outbox []workflows.Event
+}
+
+// NewMemoryStorage creates an empty in-memory delta storage
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{
+		deltas: make(map[string][]workflows.Delta),
+		state:  make(map[string]map[string]interface{}),
+	}
+}
+
+// Store appends a delta to the blob's log
+func (s *MemoryStorage) Store(ctx context.Context, delta workflows.Delta) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.store(delta)
+	return nil
+}
+
+// store appends a delta; the caller holds the lock
+func (s *MemoryStorage) store(delta workflows.Delta) {
+	delta.Sequence = int64(len(s.deltas[delta.BlobID]) + 1)
+	s.deltas[delta.BlobID] = append(s.deltas[delta.BlobID], delta)
+}
+
+// GetByBlobID returns the delta log for a blob
+func (s *MemoryStorage) GetByBlobID(ctx context.Context, blobID string) ([]workflows.Delta, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]workflows.Delta(nil), s.deltas[blobID]...), nil
+}
+
+// ApplyDeltas writes each delta's new value into the blob state keyed by path
+func (s *MemoryStorage) ApplyDeltas(ctx context.Context, blobID string, deltas []workflows.Delta) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.apply(blobID, deltas)
+	return nil
+}
+
+// apply writes deltas into the blob state; the caller holds the lock
+func (s *MemoryStorage) apply(blobID string, deltas []workflows.Delta) {
+	state, ok := s.state[blobID]
+	if !ok {
+		state = make(map[string]interface{})
+		s.state[blobID] = state
+	}
+	for _, delta := range deltas {
+		if delta.Type == "delete" {
+			delete(state, delta.Path)
+			continue
+		}
+		state[delta.Path] = delta.NewValue
+	}
+}
+
+// State returns a copy of the blob state keyed by path
+func (s *MemoryStorage) State(ctx context.Context, blobID string) (map[string]interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := make(map[string]interface{}, len(s.state[blobID]))
+	for path, value := range s.state[blobID] {
+		state[path] = value
+	}
+	return state, nil
+}
+
+// SampleBlobs picks up to n blobs with deltas at random
+func (s *MemoryStorage) SampleBlobs(ctx context.Context, n int) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	blobIDs := make([]string, 0, len(s.deltas))
+	for blobID := range s.deltas {
+		blobIDs = append(blobIDs, blobID)
+	}
+	rand.Shuffle(len(blobIDs), func(i, j int) { blobIDs[i], blobIDs[j] = blobIDs[j], blobIDs[i] })
+	if n < len(blobIDs) {
+		blobIDs = blobIDs[:n]
+	}
+	return blobIDs, nil
+}
+
+// CommitBatch stores and applies a batch of deltas in one step
+func (s *MemoryStorage) CommitBatch(ctx context.Context, batch *workflows.DeltaBatch) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, delta := range batch.Deltas {
+		s.store(delta)
+	}
+	s.apply(batch.BlobID, batch.Deltas)
+	return nil
+}
+
+// CommitDeltas stores and applies deltas and queues their events in one step
+func (s *MemoryStorage) CommitDeltas(ctx context.Context, blobID string, deltas []workflows.Delta, events []workflows.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, delta := range deltas {
+		s.store(delta)
+	}
+	s.apply(blobID, deltas)
+	s.outbox = append(s.outbox, events...)
+	return nil
+}
+
+// PendingEvents returns the oldest queued events, up to limit
+func (s *MemoryStorage) PendingEvents(ctx context.Context, limit int) ([]workflows.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if limit <= 0 || limit > len(s.outbox) {
+		limit = len(s.outbox)
+	}
+	return append([]workflows.Event(nil), s.outbox[:limit]...), nil
+}
+
+// MarkDispatched drops queued events by ID
+func (s *MemoryStorage) MarkDispatched(ctx context.Context, ids []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dispatched := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		dispatched[id] = true
+	}
+	pending := s.outbox[:0]
+	for _, event := range s.outbox {
+		if !dispatched[event.ID] {
+			pending = append(pending, event)
+		}
+	}
+	s.outbox = pending
+	return nil
+}
+
+// Reset discards all stored deltas, state and queued events
+func (s *MemoryStorage) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deltas = make(map[string][]workflows.Delta)
+	s.state = make(map[string]map[string]interface{})
+	s.outbox = nil
+}
+
+// CountingBus is an EventBus that counts published events and fans them out
+// synchronously to subscribed handlers
+type CountingBus struct {
+	published int64
+	mu        sync.RWMutex
+	handlers  []workflows.EventHandler
+}
+
+// Publish records the event and delivers it to all handlers
+func (b *CountingBus) Publish(ctx context.Context, event workflows.Event) error {
+	atomic.AddInt64(&b.published, 1)
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, handler := range b.handlers {
+		if err := handler(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Subscribe registers a handler for the events matching filters
+func (b *CountingBus) Subscribe(ctx context.Context, handler workflows.EventHandler, filters ...workflows.EventFilter) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.handlers = append(b.handlers, workflows.FilterHandler(handler, filters...))
+	return nil
+}
+
+// Published returns the number of events published so far
+func (b *CountingBus) Published() int64 {
+	return atomic.LoadInt64(&b.published)
+}
+
+// StubWorkflowService starts an HTTP server that mimics the workflow service,
+// serving workflow definitions and completing every execution with output
+func StubWorkflowService(workflow *workflows.BlobProcessingWorkflow, output map[string]interface{}) *httptest.Server {
+	workflowJSON, _ := json.Marshal(workflow)
+	executionJSON, _ := json.Marshal(workflows.ExecutionResponse{
+		ExecutionID: "bench-execution",
+		Status:      "completed",
+		Output:      output,
+		StartedAt:   time.Unix(0, 0),
+	})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/workflows/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/execute") {
+			w.Write(executionJSON)
+			return
+		}
+		w.Write(workflowJSON)
+	})
+
+	return httptest.NewServer(mux)
+}
@@ -0,0 +1,82 @@
+package bench
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+)
+
+// Profile captures CPU, heap, and allocation profiles for a section of code
+type Profile struct {
+	dir     string
+	name    string
+	cpuFile *os.File
+}
+
+// StartProfile begins a CPU profile written to <dir>/<name>.cpu.pprof. Call
+// Stop to finish the CPU profile and write heap and allocation snapshots.
+func StartProfile(dir, name string) (*Profile, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create profile dir: %w", err)
+	}
+
+	f, err := os.Create(filepath.Join(dir, name+".cpu.pprof"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cpu profile: %w", err)
+	}
+
+	if err := pprof.StartCPUProfile(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to start cpu profile: %w", err)
+	}
+
+	return &Profile{dir: dir, name: name, cpuFile: f}, nil
+}
+
+// Stop ends the CPU profile and writes heap and allocs profiles next to it
+func (p *Profile) Stop() error {
+	pprof.StopCPUProfile()
+	if err := p.cpuFile.Close(); err != nil {
+		return fmt.Errorf("failed to close cpu profile: %w", err)
+	}
+
+	runtime.GC()
+	for _, kind := range []string{"heap", "allocs"} {
+		if err := p.writeLookup(kind); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeLookup writes a named runtime profile to disk
+func (p *Profile) writeLookup(kind string) error {
+	f, err := os.Create(filepath.Join(p.dir, fmt.Sprintf("%s.%s.pprof", p.name, kind)))
+	if err != nil {
+		return fmt.Errorf("failed to create %s profile: %w", kind, err)
+	}
+	defer f.Close()
+
+	if err := pprof.Lookup(kind).WriteTo(f, 0); err != nil {
+		return fmt.Errorf("failed to write %s profile: %w", kind, err)
+	}
+	return nil
+}
+
+// Capture runs fn iterations times under a profile, leaving the results in
+// dir for inspection with `go tool pprof`
+func Capture(dir, name string, iterations int, fn func()) error {
+	profile, err := StartProfile(dir, name)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < iterations; i++ {
+		fn()
+	}
+
+	return profile.Stop()
+}
@@ -0,0 +1,98 @@
+// Package blob provides access to user blobs stored by the State Service
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNotFound is returned when a blob does not exist
+var ErrNotFound = errors.New("blob not found")
+
+// ErrDeleteUnsupported is returned by stores that cannot delete blobs
+var ErrDeleteUnsupported = errors.New("blob store cannot delete blobs")
+
+// TrashedKey is the metadata key holding when a soft-deleted blob was
+// moved to the trash, as an RFC 3339 timestamp
+const TrashedKey = "trashed_at"
+
+// Blob is a unit of user content
+type Blob struct {
+	ID          string                 `json:"id"`
+	UserID      string                 `json:"user_id"`
+	ProviderID  string                 `json:"provider_id"`
+	NamespaceID string                 `json:"namespace_id,omitempty"`
+	Content     string                 `json:"content"`
+	ParentID    *string                `json:"parent_id,omitempty"`
+	Metadata    map[string]interface{} `json:"metadata"`
+	CreatedAt   time.Time              `json:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at"`
+}
+
+// Filter narrows blob listings
+type Filter struct {
+	ProviderID  string
+	NamespaceID string
+	ParentID    string
+	Limit       int
+	Offset      int
+}
+
+// Store is the blob persistence contract
+type Store interface {
+	CreateBlob(ctx context.Context, blob *Blob) (*Blob, error)
+	GetBlob(ctx context.Context, userID, blobID string) (*Blob, error)
+	UpdateBlob(ctx context.Context, blob *Blob) (*Blob, error)
+	ListBlobs(ctx context.Context, userID string, filter Filter) ([]*Blob, error)
+}
+
+// Deleter is implemented by stores that can delete blobs permanently
+type Deleter interface {
+	DeleteBlob(ctx context.Context, userID, blobID string) error
+}
+
+// Trashed reports whether a blob has been soft-deleted
+func (b *Blob) Trashed() bool {
+	_, ok := b.Metadata[TrashedKey]
+	return ok
+}
+
+// ToMap converts a blob into the map form used in workflow inputs
+func (b *Blob) ToMap() map[string]interface{} {
+	m := map[string]interface{}{
+		"id":          b.ID,
+		"user_id":     b.UserID,
+		"provider_id": b.ProviderID,
+		"content":     b.Content,
+		"metadata":    b.Metadata,
+		"created_at":  b.CreatedAt,
+		"updated_at":  b.UpdatedAt,
+	}
+	if b.NamespaceID != "" {
+		m["namespace_id"] = b.NamespaceID
+	}
+	if b.ParentID != nil {
+		m["parent_id"] = *b.ParentID
+	}
+	return m
+}
+
+// Loader adapts a Store to the orchestrator's blob loading hook
+type Loader struct {
+	Store Store
+}
+
+// LoadBlob fetches a blob and returns it in workflow input form. Blobs in
+// the trash are not found, so they are not processed.
+func (l Loader) LoadBlob(ctx context.Context, userID, blobID string) (map[string]interface{}, error) {
+	b, err := l.Store.GetBlob(ctx, userID, blobID)
+	if err != nil {
+		return nil, err
+	}
+	if b.Trashed() {
+		return nil, fmt.Errorf("%w: %s is in the trash", ErrNotFound, blobID)
+	}
+	return b.ToMap(), nil
+}
@@ -0,0 +1,161 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Client talks to the State Service blob API
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewClient creates a new State Service client
+func NewClient(baseURL string) *Client {
+	return &Client{
+		baseURL: baseURL,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// CreateBlob creates a blob for its user
+func (c *Client) CreateBlob(ctx context.Context, blob *Blob) (*Blob, error) {
+	url := fmt.Sprintf("%s/api/v1/users/%s/blobs", c.baseURL, url.PathEscape(blob.UserID))
+	return c.send(ctx, "POST", url, blob, http.StatusCreated, http.StatusOK)
+}
+
+// GetBlob fetches a single blob
+func (c *Client) GetBlob(ctx context.Context, userID, blobID string) (*Blob, error) {
+	url := fmt.Sprintf("%s/api/v1/users/%s/blobs/%s", c.baseURL, url.PathEscape(userID), url.PathEscape(blobID))
+	return c.send(ctx, "GET", url, nil, http.StatusOK)
+}
+
+// UpdateBlob replaces a blob's content and metadata
+func (c *Client) UpdateBlob(ctx context.Context, blob *Blob) (*Blob, error) {
+	url := fmt.Sprintf("%s/api/v1/users/%s/blobs/%s", c.baseURL, url.PathEscape(blob.UserID), url.PathEscape(blob.ID))
+	return c.send(ctx, "PUT", url, blob, http.StatusOK)
+}
+
+// DeleteBlob permanently deletes a blob
+func (c *Client) DeleteBlob(ctx context.Context, userID, blobID string) error {
+	url := fmt.Sprintf("%s/api/v1/users/%s/blobs/%s", c.baseURL, url.PathEscape(userID), url.PathEscape(blobID))
+	httpReq, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+		return nil
+	case http.StatusNotFound:
+		return ErrNotFound
+	}
+	return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+}
+
+// ListBlobs lists a user's blobs
+func (c *Client) ListBlobs(ctx context.Context, userID string, filter Filter) ([]*Blob, error) {
+	query := url.Values{}
+	if filter.ProviderID != "" {
+		query.Set("provider_id", filter.ProviderID)
+	}
+	if filter.NamespaceID != "" {
+		query.Set("namespace_id", filter.NamespaceID)
+	}
+	if filter.ParentID != "" {
+		query.Set("parent_id", filter.ParentID)
+	}
+	if filter.Limit > 0 {
+		query.Set("limit", strconv.Itoa(filter.Limit))
+	}
+	if filter.Offset > 0 {
+		query.Set("offset", strconv.Itoa(filter.Offset))
+	}
+
+	url := fmt.Sprintf("%s/api/v1/users/%s/blobs?%s", c.baseURL, url.PathEscape(userID), query.Encode())
+
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var blobs []*Blob
+	if err := json.NewDecoder(resp.Body).Decode(&blobs); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return blobs, nil
+}
+
+// send performs a request with an optional JSON body and decodes a blob
+func (c *Client) send(ctx context.Context, method, url string, payload interface{}, expected ...int) (*Blob, error) {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal blob: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if payload != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	ok := false
+	for _, code := range expected {
+		if resp.StatusCode == code {
+			ok = true
+			break
+		}
+	}
+	if !ok {
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var result Blob
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &result, nil
+}
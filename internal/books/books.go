@@ -0,0 +1,391 @@
+// Package books layers book projects on blobs. A book is a blob whose ID is
+// the namespace of its chapters and its outline, so everything belonging to
+// a book can be listed with one namespace query.
+package books
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/memmieai/memmie-studio/internal/blob"
+)
+
+const (
+	// ProviderID owns book blobs; chapters and outlines belong to the book's
+	// own provider (see BookProviderID)
+	ProviderID = "book-writer"
+
+	// Blob types, stored in metadata.type as the book-writer provider expects
+	TypeBook    = "book"
+	TypeChapter = "chapter"
+	TypeOutline = "outline"
+
+	// StatusDraft is the status given to new chapters
+	StatusDraft = "draft"
+)
+
+var (
+	// ErrNotFound is returned when a book or chapter does not exist
+	ErrNotFound = errors.New("not found")
+
+	// ErrInvalidOrder is returned when a chapter order is not a permutation
+	// of the book's chapters
+	ErrInvalidOrder = errors.New("chapter order must list every chapter exactly once")
+
+	// ErrInvalidEntry is returned when an outline entry names no chapter
+	ErrInvalidEntry = errors.New("outline entry needs a chapter_id or chapter_number")
+)
+
+// Book is a book project
+type Book struct {
+	ID           string    `json:"id"`
+	UserID       string    `json:"user_id"`
+	Title        string    `json:"title"`
+	Author       string    `json:"author,omitempty"`
+	Genre        string    `json:"genre,omitempty"`
+	Description  string    `json:"description,omitempty"`
+	ChapterOrder []string  `json:"chapter_order"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Chapter is a chapter blob in a book's namespace
+type Chapter struct {
+	ID        string    `json:"id"`
+	BookID    string    `json:"book_id"`
+	Number    int       `json:"chapter_number"`
+	Title     string    `json:"chapter_title"`
+	Status    string    `json:"status"`
+	WordCount int       `json:"word_count"`
+	Content   string    `json:"content,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// BookProviderID is the provider ID chapter blobs carry, matching the
+// provider created by workflows.CreateBookWritingWorkflow
+func BookProviderID(bookID string) string {
+	return "book:" + bookID
+}
+
+// Service manages books, chapters and outlines in the blob store
+type Service struct {
+	blobs blob.Store
+}
+
+// NewService creates a book service
+func NewService(blobs blob.Store) *Service {
+	return &Service{blobs: blobs}
+}
+
+// CreateBook creates a book for its user
+func (s *Service) CreateBook(ctx context.Context, book *Book) (*Book, error) {
+	if strings.TrimSpace(book.Title) == "" {
+		return nil, fmt.Errorf("book title is required")
+	}
+
+	created, err := s.blobs.CreateBlob(ctx, &blob.Blob{
+		UserID:     book.UserID,
+		ProviderID: ProviderID,
+		Content:    book.Description,
+		Metadata: map[string]interface{}{
+			"type":          TypeBook,
+			"title":         book.Title,
+			"author":        book.Author,
+			"genre":         book.Genre,
+			"chapter_order": []interface{}{},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create book: %w", err)
+	}
+	return bookFromBlob(created), nil
+}
+
+// GetBook fetches a book
+func (s *Service) GetBook(ctx context.Context, userID, bookID string) (*Book, error) {
+	b, err := s.getBookBlob(ctx, userID, bookID)
+	if err != nil {
+		return nil, err
+	}
+	return bookFromBlob(b), nil
+}
+
+// ListBooks lists a user's books
+func (s *Service) ListBooks(ctx context.Context, userID string) ([]*Book, error) {
+	blobs, err := s.blobs.ListBlobs(ctx, userID, blob.Filter{ProviderID: ProviderID})
+	if err != nil && !errors.Is(err, blob.ErrNotFound) {
+		return nil, fmt.Errorf("failed to list books: %w", err)
+	}
+
+	books := make([]*Book, 0, len(blobs))
+	for _, b := range blobs {
+		if b.Metadata["type"] == TypeBook {
+			books = append(books, bookFromBlob(b))
+		}
+	}
+	return books, nil
+}
+
+// AddChapter creates a chapter at the end of a book. The chapter number is
+// assigned from its position.
+func (s *Service) AddChapter(ctx context.Context, userID, bookID string, chapter *Chapter) (*Chapter, error) {
+	bookBlob, err := s.getBookBlob(ctx, userID, bookID)
+	if err != nil {
+		return nil, err
+	}
+	book := bookFromBlob(bookBlob)
+
+	status := chapter.Status
+	if status == "" {
+		status = StatusDraft
+	}
+	created, err := s.blobs.CreateBlob(ctx, &blob.Blob{
+		UserID:      userID,
+		ProviderID:  BookProviderID(bookID),
+		NamespaceID: bookID,
+		Content:     chapter.Content,
+		Metadata: map[string]interface{}{
+			"type":           TypeChapter,
+			"book_id":        bookID,
+			"chapter_number": len(book.ChapterOrder) + 1,
+			"chapter_title":  chapter.Title,
+			"status":         status,
+			"word_count":     len(strings.Fields(chapter.Content)),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create chapter: %w", err)
+	}
+
+	// The chapter exists even if recording its position fails; ListChapters
+	// places chapters missing from the order after the ordered ones
+	bookBlob.Metadata["chapter_order"] = toInterfaces(append(book.ChapterOrder, created.ID))
+	if _, err := s.blobs.UpdateBlob(ctx, bookBlob); err != nil {
+		return nil, fmt.Errorf("failed to update chapter order: %w", err)
+	}
+	return chapterFromBlob(created), nil
+}
+
+// ListChapters lists a book's chapters in reading order
+func (s *Service) ListChapters(ctx context.Context, userID, bookID string) ([]*Chapter, error) {
+	book, err := s.GetBook(ctx, userID, bookID)
+	if err != nil {
+		return nil, err
+	}
+	chapters, err := s.chapters(ctx, userID, bookID)
+	if err != nil {
+		return nil, err
+	}
+	return inOrder(chapters, book.ChapterOrder), nil
+}
+
+// ReorderChapters sets a book's chapter order and renumbers the chapters to
+// match. The order must list every chapter of the book exactly once.
+func (s *Service) ReorderChapters(ctx context.Context, userID, bookID string, order []string) ([]*Chapter, error) {
+	bookBlob, err := s.getBookBlob(ctx, userID, bookID)
+	if err != nil {
+		return nil, err
+	}
+	chapterBlobs, err := s.chapterBlobs(ctx, userID, bookID)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]*blob.Blob, len(chapterBlobs))
+	for _, b := range chapterBlobs {
+		byID[b.ID] = b
+	}
+	if len(order) != len(byID) {
+		return nil, ErrInvalidOrder
+	}
+	seen := make(map[string]bool, len(order))
+	for _, id := range order {
+		if byID[id] == nil || seen[id] {
+			return nil, ErrInvalidOrder
+		}
+		seen[id] = true
+	}
+
+	previous := make(map[string]int, len(order))
+	chapters := make([]*Chapter, len(order))
+	for i, id := range order {
+		b := byID[id]
+		previous[id] = metaInt(b.Metadata, "chapter_number")
+		if previous[id] != i+1 {
+			b.Metadata["chapter_number"] = i + 1
+			if b, err = s.blobs.UpdateBlob(ctx, b); err != nil {
+				return nil, fmt.Errorf("failed to renumber chapter %s: %w", id, err)
+			}
+		}
+		chapters[i] = chapterFromBlob(b)
+	}
+
+	bookBlob.Metadata["chapter_order"] = toInterfaces(order)
+	if _, err := s.blobs.UpdateBlob(ctx, bookBlob); err != nil {
+		return nil, fmt.Errorf("failed to update chapter order: %w", err)
+	}
+
+	if err := s.renumberOutline(ctx, userID, bookID, chapters, previous); err != nil {
+		return nil, err
+	}
+	return chapters, nil
+}
+
+// getBookBlob fetches a book's blob, rejecting blobs that are not books
+func (s *Service) getBookBlob(ctx context.Context, userID, bookID string) (*blob.Blob, error) {
+	b, err := s.blobs.GetBlob(ctx, userID, bookID)
+	if errors.Is(err, blob.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get book: %w", err)
+	}
+	if b.Metadata["type"] != TypeBook {
+		return nil, ErrNotFound
+	}
+	return b, nil
+}
+
+// chapters lists a book's chapters in no particular order
+func (s *Service) chapters(ctx context.Context, userID, bookID string) ([]*Chapter, error) {
+	blobs, err := s.chapterBlobs(ctx, userID, bookID)
+	if err != nil {
+		return nil, err
+	}
+	chapters := make([]*Chapter, len(blobs))
+	for i, b := range blobs {
+		chapters[i] = chapterFromBlob(b)
+	}
+	return chapters, nil
+}
+
+// chapterBlobs lists the chapter blobs in a book's namespace
+func (s *Service) chapterBlobs(ctx context.Context, userID, bookID string) ([]*blob.Blob, error) {
+	return s.namespaceBlobs(ctx, userID, bookID, TypeChapter)
+}
+
+// namespaceBlobs lists the blobs of one type in a book's namespace
+func (s *Service) namespaceBlobs(ctx context.Context, userID, bookID, blobType string) ([]*blob.Blob, error) {
+	blobs, err := s.blobs.ListBlobs(ctx, userID, blob.Filter{NamespaceID: bookID})
+	if err != nil && !errors.Is(err, blob.ErrNotFound) {
+		return nil, fmt.Errorf("failed to list book blobs: %w", err)
+	}
+
+	var matched []*blob.Blob
+	for _, b := range blobs {
+		if b.Metadata["type"] == blobType {
+			matched = append(matched, b)
+		}
+	}
+	return matched, nil
+}
+
+// inOrder sorts chapters by the book's chapter order. Chapters missing from
+// the order follow the ordered ones by chapter number, then creation time.
+func inOrder(chapters []*Chapter, order []string) []*Chapter {
+	position := make(map[string]int, len(order))
+	for i, id := range order {
+		position[id] = i
+	}
+
+	sorted := append([]*Chapter(nil), chapters...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		pi, iOrdered := position[sorted[i].ID]
+		pj, jOrdered := position[sorted[j].ID]
+		switch {
+		case iOrdered && jOrdered:
+			return pi < pj
+		case iOrdered != jOrdered:
+			return iOrdered
+		case sorted[i].Number != sorted[j].Number:
+			return sorted[i].Number < sorted[j].Number
+		}
+		return sorted[i].CreatedAt.Before(sorted[j].CreatedAt)
+	})
+	return sorted
+}
+
+// bookFromBlob reads a book from its blob
+func bookFromBlob(b *blob.Blob) *Book {
+	return &Book{
+		ID:           b.ID,
+		UserID:       b.UserID,
+		Title:        metaString(b.Metadata, "title"),
+		Author:       metaString(b.Metadata, "author"),
+		Genre:        metaString(b.Metadata, "genre"),
+		Description:  b.Content,
+		ChapterOrder: metaStrings(b.Metadata, "chapter_order"),
+		CreatedAt:    b.CreatedAt,
+		UpdatedAt:    b.UpdatedAt,
+	}
+}
+
+// chapterFromBlob reads a chapter from its blob
+func chapterFromBlob(b *blob.Blob) *Chapter {
+	return &Chapter{
+		ID:        b.ID,
+		BookID:    b.NamespaceID,
+		Number:    metaInt(b.Metadata, "chapter_number"),
+		Title:     metaString(b.Metadata, "chapter_title"),
+		Status:    metaString(b.Metadata, "status"),
+		WordCount: metaInt(b.Metadata, "word_count"),
+		Content:   b.Content,
+		CreatedAt: b.CreatedAt,
+		UpdatedAt: b.UpdatedAt,
+	}
+}
+
+// metaString reads a string metadata field
+func metaString(metadata map[string]interface{}, key string) string {
+	s, _ := metadata[key].(string)
+	return s
+}
+
+// metaInt reads a numeric metadata field, which arrives as float64 after a
+// JSON round trip
+func metaInt(metadata map[string]interface{}, key string) int {
+	return toInt(metadata[key])
+}
+
+// metaStrings reads a string list metadata field
+func metaStrings(metadata map[string]interface{}, key string) []string {
+	strs := []string{}
+	switch v := metadata[key].(type) {
+	case []string:
+		strs = append(strs, v...)
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				strs = append(strs, s)
+			}
+		}
+	}
+	return strs
+}
+
+// toInt converts a JSON number to an int
+func toInt(value interface{}) int {
+	switch v := value.(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	}
+	return 0
+}
+
+// toInterfaces converts a string slice for storage in metadata
+func toInterfaces(strs []string) []interface{} {
+	values := make([]interface{}, len(strs))
+	for i, s := range strs {
+		values[i] = s
+	}
+	return values
+}
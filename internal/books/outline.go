@@ -0,0 +1,379 @@
+package books
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/memmieai/memmie-studio/internal/blob"
+)
+
+// OutlineEntry is one chapter's line in a book outline
+type OutlineEntry struct {
+	ChapterID string                 `json:"chapter_id,omitempty"`
+	Number    int                    `json:"chapter_number"`
+	Title     string                 `json:"chapter_title,omitempty"`
+	Summary   string                 `json:"summary,omitempty"`
+	KeyPoints []string               `json:"key_points,omitempty"`
+	WordCount int                    `json:"word_count"`
+	Status    string                 `json:"status,omitempty"`
+	Extra     map[string]interface{} `json:"extra,omitempty"` // e.g. characters, consistency_score
+	UpdatedAt *time.Time             `json:"updated_at,omitempty"`
+}
+
+// Outline is a book's chapter-by-chapter outline
+type Outline struct {
+	BookID     string         `json:"book_id"`
+	Title      string         `json:"title"`
+	Chapters   []OutlineEntry `json:"chapters"`
+	TotalWords int            `json:"total_words"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+}
+
+// GetOutline returns a book's outline in the current chapter order. Every
+// chapter appears, with the summary the outline manager last recorded for
+// it; titles and word counts come from the chapters themselves.
+func (s *Service) GetOutline(ctx context.Context, userID, bookID string) (*Outline, error) {
+	book, err := s.GetBook(ctx, userID, bookID)
+	if err != nil {
+		return nil, err
+	}
+	chapters, err := s.chapters(ctx, userID, bookID)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := s.outlineBlob(ctx, userID, bookID)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []OutlineEntry
+	outline := &Outline{BookID: bookID, Title: book.Title}
+	if stored != nil {
+		entries = outlineEntries(stored)
+		outline.UpdatedAt = stored.UpdatedAt
+	}
+
+	for _, chapter := range inOrder(chapters, book.ChapterOrder) {
+		var entry OutlineEntry
+		if i := findEntry(entries, chapter.ID, chapter.Number); i >= 0 {
+			entry = entries[i]
+		}
+		entry = withChapter(entry, chapter)
+		outline.Chapters = append(outline.Chapters, entry)
+		outline.TotalWords += entry.WordCount
+	}
+	if outline.Chapters == nil {
+		outline.Chapters = []OutlineEntry{}
+	}
+	return outline, nil
+}
+
+// UpdateOutline records an entry in a book's outline, replacing the entry
+// for the same chapter ID, or chapter number when the ID is not known.
+// Empty fields keep their previous values.
+func (s *Service) UpdateOutline(ctx context.Context, userID, bookID string, entry OutlineEntry) (*Outline, error) {
+	if entry.ChapterID == "" && entry.Number == 0 {
+		return nil, ErrInvalidEntry
+	}
+	if _, err := s.getBookBlob(ctx, userID, bookID); err != nil {
+		return nil, err
+	}
+	chapters, err := s.chapters(ctx, userID, bookID)
+	if err != nil {
+		return nil, err
+	}
+	for _, chapter := range chapters {
+		if chapter.ID == entry.ChapterID || (entry.ChapterID == "" && chapter.Number == entry.Number) {
+			entry = withChapter(entry, chapter)
+			break
+		}
+	}
+	stored, err := s.outlineBlob(ctx, userID, bookID)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []OutlineEntry
+	if stored != nil {
+		entries = outlineEntries(stored)
+	}
+	now := time.Now().UTC()
+	entry.UpdatedAt = &now
+	if i := findEntry(entries, entry.ChapterID, entry.Number); i >= 0 {
+		entries[i] = mergeEntry(entries[i], entry)
+	} else {
+		entries = append(entries, entry)
+	}
+
+	if err := s.saveOutline(ctx, userID, bookID, stored, entries); err != nil {
+		return nil, err
+	}
+	return s.GetOutline(ctx, userID, bookID)
+}
+
+// renumberOutline updates stored entry numbers after chapters are
+// reordered. previous maps chapter IDs to their numbers before the reorder,
+// so entries recorded by number alone stay with their chapter.
+func (s *Service) renumberOutline(ctx context.Context, userID, bookID string, chapters []*Chapter, previous map[string]int) error {
+	stored, err := s.outlineBlob(ctx, userID, bookID)
+	if err != nil || stored == nil {
+		return err
+	}
+
+	entries := outlineEntries(stored)
+	matched := make([]bool, len(entries))
+	renumbered := make([]OutlineEntry, 0, len(entries))
+	for _, chapter := range chapters {
+		i := findEntry(entries, chapter.ID, previous[chapter.ID])
+		if i < 0 || matched[i] {
+			continue
+		}
+		matched[i] = true
+		entry := entries[i]
+		entry.ChapterID = chapter.ID
+		entry.Number = chapter.Number
+		renumbered = append(renumbered, entry)
+	}
+	for i, entry := range entries {
+		if !matched[i] {
+			renumbered = append(renumbered, entry)
+		}
+	}
+	return s.saveOutline(ctx, userID, bookID, stored, renumbered)
+}
+
+// withChapter fills an entry's ID, number, title and word count from its
+// chapter, which are more current than what was recorded
+func withChapter(entry OutlineEntry, chapter *Chapter) OutlineEntry {
+	entry.ChapterID = chapter.ID
+	entry.Number = chapter.Number
+	if chapter.Title != "" {
+		entry.Title = chapter.Title
+	}
+	if chapter.WordCount > 0 {
+		entry.WordCount = chapter.WordCount
+	}
+	if entry.Status == "" {
+		entry.Status = chapter.Status
+	}
+	return entry
+}
+
+// outlineBlob returns a book's outline blob, or nil before the first update
+func (s *Service) outlineBlob(ctx context.Context, userID, bookID string) (*blob.Blob, error) {
+	blobs, err := s.namespaceBlobs(ctx, userID, bookID, TypeOutline)
+	if err != nil || len(blobs) == 0 {
+		return nil, err
+	}
+	return blobs[0], nil
+}
+
+// saveOutline writes the outline entries, creating the outline blob on
+// first use. The blob content is a readable rendering of the outline.
+func (s *Service) saveOutline(ctx context.Context, userID, bookID string, existing *blob.Blob, entries []OutlineEntry) error {
+	stored := make([]interface{}, len(entries))
+	for i, entry := range entries {
+		stored[i] = entry.Map()
+	}
+
+	if existing == nil {
+		_, err := s.blobs.CreateBlob(ctx, &blob.Blob{
+			UserID:      userID,
+			ProviderID:  BookProviderID(bookID),
+			NamespaceID: bookID,
+			Content:     renderOutline(entries),
+			Metadata: map[string]interface{}{
+				"type":    TypeOutline,
+				"book_id": bookID,
+				"entries": stored,
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create outline: %w", err)
+		}
+		return nil
+	}
+
+	existing.Content = renderOutline(entries)
+	existing.Metadata["entries"] = stored
+	if _, err := s.blobs.UpdateBlob(ctx, existing); err != nil {
+		return fmt.Errorf("failed to update outline: %w", err)
+	}
+	return nil
+}
+
+// Map converts an entry into the map form stored in blob metadata
+func (e OutlineEntry) Map() map[string]interface{} {
+	m := map[string]interface{}{
+		"chapter_number": e.Number,
+		"word_count":     e.WordCount,
+	}
+	if e.ChapterID != "" {
+		m["chapter_id"] = e.ChapterID
+	}
+	if e.Title != "" {
+		m["chapter_title"] = e.Title
+	}
+	if e.Summary != "" {
+		m["summary"] = e.Summary
+	}
+	if len(e.KeyPoints) > 0 {
+		m["key_points"] = toInterfaces(e.KeyPoints)
+	}
+	if e.Status != "" {
+		m["status"] = e.Status
+	}
+	if len(e.Extra) > 0 {
+		m["extra"] = e.Extra
+	}
+	if e.UpdatedAt != nil {
+		m["updated_at"] = e.UpdatedAt.Format(time.RFC3339)
+	}
+	return m
+}
+
+// EntryFromMap reads an outline entry from step inputs or a request body.
+// Chapter fields may be given flat or under chapter_data, the shape the
+// book chapter processing workflow sends; chapter_summary may be a string or
+// a summarizer output with summary and key_points. Unrecognized chapter_data
+// fields are kept in Extra.
+func EntryFromMap(m map[string]interface{}) OutlineEntry {
+	fields := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		fields[k] = v
+	}
+	var extra map[string]interface{}
+	if data, ok := m["chapter_data"].(map[string]interface{}); ok {
+		for k, v := range data {
+			fields[k] = v
+		}
+		extra = make(map[string]interface{})
+		for k, v := range data {
+			switch k {
+			case "summary", "key_points", "word_count", "status", "chapter_title", "title":
+			default:
+				extra[k] = v
+			}
+		}
+	}
+
+	entry := OutlineEntry{
+		ChapterID: metaString(fields, "chapter_id"),
+		Number:    toInt(fields["chapter_number"]),
+		Title:     metaString(fields, "chapter_title"),
+		Summary:   metaString(fields, "summary"),
+		KeyPoints: metaStrings(fields, "key_points"),
+		WordCount: toInt(fields["word_count"]),
+		Status:    metaString(fields, "status"),
+		Extra:     extra,
+	}
+	if entry.Title == "" {
+		entry.Title = metaString(fields, "title")
+	}
+	switch summary := fields["chapter_summary"].(type) {
+	case string:
+		entry.Summary = summary
+	case map[string]interface{}:
+		if s := metaString(summary, "summary"); s != "" {
+			entry.Summary = s
+		}
+		if points := metaStrings(summary, "key_points"); len(points) > 0 {
+			entry.KeyPoints = points
+		}
+	}
+	if updated, err := time.Parse(time.RFC3339, metaString(fields, "updated_at")); err == nil {
+		entry.UpdatedAt = &updated
+	}
+	if entry.Extra == nil {
+		entry.Extra, _ = fields["extra"].(map[string]interface{})
+	}
+	return entry
+}
+
+// outlineEntries reads the entries stored on an outline blob
+func outlineEntries(b *blob.Blob) []OutlineEntry {
+	items, _ := b.Metadata["entries"].([]interface{})
+	entries := make([]OutlineEntry, 0, len(items))
+	for _, item := range items {
+		if m, ok := item.(map[string]interface{}); ok {
+			entries = append(entries, EntryFromMap(m))
+		}
+	}
+	return entries
+}
+
+// findEntry returns the index of the entry for a chapter ID, falling back to
+// an entry with the chapter number and no ID, or -1
+func findEntry(entries []OutlineEntry, chapterID string, number int) int {
+	if chapterID != "" {
+		for i, e := range entries {
+			if e.ChapterID == chapterID {
+				return i
+			}
+		}
+	}
+	if number > 0 {
+		for i, e := range entries {
+			if e.ChapterID == "" && e.Number == number {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// mergeEntry overlays the non-empty fields of update onto entry
+func mergeEntry(entry, update OutlineEntry) OutlineEntry {
+	if update.ChapterID != "" {
+		entry.ChapterID = update.ChapterID
+	}
+	if update.Number > 0 {
+		entry.Number = update.Number
+	}
+	if update.Title != "" {
+		entry.Title = update.Title
+	}
+	if update.Summary != "" {
+		entry.Summary = update.Summary
+	}
+	if len(update.KeyPoints) > 0 {
+		entry.KeyPoints = update.KeyPoints
+	}
+	if update.WordCount > 0 {
+		entry.WordCount = update.WordCount
+	}
+	if update.Status != "" {
+		entry.Status = update.Status
+	}
+	if len(update.Extra) > 0 {
+		if entry.Extra == nil {
+			entry.Extra = make(map[string]interface{})
+		}
+		for k, v := range update.Extra {
+			entry.Extra[k] = v
+		}
+	}
+	entry.UpdatedAt = update.UpdatedAt
+	return entry
+}
+
+// renderOutline renders entries as a plain text outline
+func renderOutline(entries []OutlineEntry) string {
+	var b strings.Builder
+	for _, e := range entries {
+		title := e.Title
+		if title == "" {
+			title = fmt.Sprintf("Chapter %d", e.Number)
+		}
+		fmt.Fprintf(&b, "%d. %s (%d words)\n", e.Number, title, e.WordCount)
+		if e.Summary != "" {
+			fmt.Fprintf(&b, "   %s\n", e.Summary)
+		}
+		for _, point := range e.KeyPoints {
+			fmt.Fprintf(&b, "   - %s\n", point)
+		}
+	}
+	return b.String()
+}
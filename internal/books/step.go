@@ -0,0 +1,40 @@
+package books
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// OutlineManagerID is the provider ID of the book template's update_outline
+// step
+const OutlineManagerID = "outline-manager"
+
+// NewStepExecutor creates the outline manager executor. Step inputs: book_id,
+// chapter_id, chapter_number, chapter_title, chapter_summary (text or the
+// summarizer's output), word_count and status. The output is the updated
+// outline.
+func NewStepExecutor(service *Service) workflows.StepExecutor {
+	return workflows.StepExecutorFunc(func(ctx context.Context, req workflows.StepRequest) (map[string]interface{}, error) {
+		bookID, _ := req.Input["book_id"].(string)
+		if bookID == "" {
+			return nil, fmt.Errorf("book_id is required")
+		}
+
+		outline, err := service.UpdateOutline(ctx, req.Context.UserID, bookID, EntryFromMap(req.Input))
+		if err != nil {
+			return nil, err
+		}
+
+		chapters := make([]interface{}, len(outline.Chapters))
+		for i, entry := range outline.Chapters {
+			chapters[i] = entry.Map()
+		}
+		return map[string]interface{}{
+			"book_id":     bookID,
+			"chapters":    chapters,
+			"total_words": outline.TotalWords,
+		}, nil
+	})
+}
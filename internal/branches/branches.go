@@ -0,0 +1,471 @@
+// Package branches lets authors try out changes to a blob without touching
+// it. A branch is a blob of its own, started from the blob's state at a
+// sequence of its delta log, that providers process independently. Merging
+// brings the branch's changes back into the blob, three ways: paths only
+// the branch changed are applied, and paths both changed differently are
+// conflicts.
+package branches
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/memmieai/memmie-studio/internal/blob"
+	"github.com/memmieai/memmie-studio/internal/revisions"
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// Metadata keys marking a branch's blob
+const (
+	BranchOfKey   = "branch_of"
+	BranchNameKey = "branch_name"
+)
+
+// Conflict resolutions
+const (
+	ResolveBranch = "branch" // the branch's value wins
+	ResolveParent = "parent" // the blob keeps its value
+)
+
+// Branch errors
+var (
+	ErrBranchNotFound    = errors.New("branch not found")
+	ErrInvalidSequence   = errors.New("invalid branch sequence")
+	ErrInvalidResolution = errors.New("invalid conflict resolution")
+)
+
+// Committer stores deltas, applies them to their blob and announces them.
+// The workflows orchestrator is a Committer.
+type Committer interface {
+	CommitDeltas(ctx context.Context, blobID, userID string, deltas []workflows.Delta) error
+}
+
+// Branch is a blob started from another at a sequence of its delta log.
+// MergedSequence is the branch's own sequence when it was last merged,
+// from which a later merge takes the branch's changes.
+type Branch struct {
+	ID             string     `json:"id"`
+	BlobID         string     `json:"blob_id"`
+	UserID         string     `json:"user_id"`
+	Name           string     `json:"name,omitempty"`
+	BaseSequence   int64      `json:"base_sequence"`
+	MergedSequence int64      `json:"merged_sequence,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	MergedAt       *time.Time `json:"merged_at,omitempty"`
+}
+
+// Conflict is a path the blob and the branch both changed since the
+// branch's base, to different values. Absent values were deleted.
+// Resolution says which side was taken, if either.
+type Conflict struct {
+	Path       string      `json:"path"`
+	Base       interface{} `json:"base,omitempty"`
+	Parent     interface{} `json:"parent,omitempty"`
+	Branch     interface{} `json:"branch,omitempty"`
+	Resolution string      `json:"resolution,omitempty"`
+}
+
+// MergeOptions controls a merge. Resolve settles conflicts for one side;
+// without it a merge with conflicts applies nothing. DryRun works out the
+// merge without applying it.
+type MergeOptions struct {
+	Resolve string
+	DryRun  bool
+}
+
+// MergeResult is a merge of a branch into its blob: the deltas applied to
+// the blob, or that would be, and the conflicts found. Merged is false for
+// dry runs and merges stopped by conflicts.
+type MergeResult struct {
+	BranchID  string            `json:"branch_id"`
+	BlobID    string            `json:"blob_id"`
+	Merged    bool              `json:"merged"`
+	Deltas    []workflows.Delta `json:"deltas"`
+	Conflicts []Conflict        `json:"conflicts"`
+}
+
+// Service creates branches of blobs and merges them back
+type Service struct {
+	store     Store
+	blobs     blob.Store
+	history   revisions.History
+	committer Committer
+	now       func() time.Time
+
+	mu sync.Mutex
+}
+
+// NewService creates a service over a blob store, reading delta logs from
+// history and writing deltas through committer
+func NewService(store Store, blobs blob.Store, history revisions.History, committer Committer) *Service {
+	return &Service{store: store, blobs: blobs, history: history, committer: committer, now: time.Now}
+}
+
+// Create branches one of a user's blobs at a sequence of its delta log, by
+// default the latest. The branch's blob copies the blob, with its content
+// and metadata as of the sequence, but sits under no parent blob. Its own
+// log starts with a delta setting each field of the state at the sequence.
+func (s *Service) Create(ctx context.Context, userID, blobID, name string, sequence int64) (*Branch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, err := s.blobs.GetBlob(ctx, userID, blobID)
+	if err != nil {
+		return nil, err
+	}
+	if b.Trashed() {
+		return nil, fmt.Errorf("%w: %s is in the trash", blob.ErrNotFound, blobID)
+	}
+	deltas, err := s.deltas(ctx, blobID)
+	if err != nil {
+		return nil, err
+	}
+	latest := revisions.Latest(deltas)
+	if sequence < 0 || sequence > latest {
+		return nil, fmt.Errorf("%w: %d is not between 0 and the latest sequence %d", ErrInvalidSequence, sequence, latest)
+	}
+	if sequence == 0 {
+		sequence = latest
+	}
+
+	var processor workflows.DeltaProcessor
+	state := processor.Replay(deltas, sequence)
+	copied := &blob.Blob{
+		UserID:      userID,
+		ProviderID:  b.ProviderID,
+		NamespaceID: b.NamespaceID,
+		Content:     b.Content,
+		Metadata:    make(map[string]interface{}, len(b.Metadata)+2),
+	}
+	for key, value := range b.Metadata {
+		copied.Metadata[key] = value
+	}
+	if content, ok := state["content"].(string); ok {
+		copied.Content = content
+	}
+	if metadata, ok := state["metadata"].(map[string]interface{}); ok {
+		for key, value := range metadata {
+			copied.Metadata[key] = value
+		}
+	}
+	copied.Metadata[BranchOfKey] = blobID
+	if name != "" {
+		copied.Metadata[BranchNameKey] = name
+	}
+	created, err := s.blobs.CreateBlob(ctx, copied)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create branch of %s: %w", blobID, err)
+	}
+
+	branch := &Branch{
+		ID:           created.ID,
+		BlobID:       blobID,
+		UserID:       userID,
+		Name:         name,
+		BaseSequence: sequence,
+		CreatedAt:    s.now().UTC(),
+	}
+	spelled := spellings(deltas)
+	keys := make([]string, 0, len(state))
+	for key := range state {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	seeds := make([]workflows.Delta, 0, len(keys))
+	for _, key := range keys {
+		seeds = append(seeds, workflows.Delta{
+			ID:         uuid.New().String(),
+			BlobID:     created.ID,
+			ProviderID: b.ProviderID,
+			Type:       "update",
+			Path:       spelled.path("/" + key),
+			NewValue:   state[key],
+			Metadata: map[string]interface{}{
+				"source":        "branch",
+				BranchOfKey:     blobID,
+				"base_sequence": sequence,
+			},
+			Timestamp: branch.CreatedAt,
+		})
+	}
+	if len(seeds) > 0 {
+		err = s.committer.CommitDeltas(ctx, created.ID, userID, seeds)
+	}
+	if err == nil {
+		err = s.store.Save(ctx, branch)
+	}
+	if err != nil {
+		// A branch that was never set up is removed where the store allows
+		if deleter, ok := s.blobs.(blob.Deleter); ok {
+			deleter.DeleteBlob(ctx, userID, created.ID)
+		}
+		return nil, fmt.Errorf("failed to create branch of %s: %w", blobID, err)
+	}
+	return branch, nil
+}
+
+// Get returns one of a user's branches
+func (s *Service) Get(ctx context.Context, userID, branchID string) (*Branch, error) {
+	branch, err := s.store.Get(ctx, branchID)
+	if err != nil {
+		return nil, err
+	}
+	if branch.UserID != userID {
+		return nil, fmt.Errorf("%w: %s", ErrBranchNotFound, branchID)
+	}
+	return branch, nil
+}
+
+// List returns the branches of one of a user's blobs, newest first
+func (s *Service) List(ctx context.Context, userID, blobID string) ([]*Branch, error) {
+	all, err := s.store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	branches := []*Branch{}
+	for _, branch := range all {
+		if branch.UserID == userID && branch.BlobID == blobID {
+			branches = append(branches, branch)
+		}
+	}
+	sort.Slice(branches, func(i, j int) bool {
+		if !branches[i].CreatedAt.Equal(branches[j].CreatedAt) {
+			return branches[i].CreatedAt.After(branches[j].CreatedAt)
+		}
+		return branches[i].ID < branches[j].ID
+	})
+	return branches, nil
+}
+
+// Delete abandons one of a user's branches, deleting its blob where the
+// store can
+func (s *Service) Delete(ctx context.Context, userID, branchID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.Get(ctx, userID, branchID); err != nil {
+		return err
+	}
+	if deleter, ok := s.blobs.(blob.Deleter); ok {
+		err := deleter.DeleteBlob(ctx, userID, branchID)
+		if err != nil && !errors.Is(err, blob.ErrNotFound) && !errors.Is(err, blob.ErrDeleteUnsupported) {
+			return fmt.Errorf("failed to delete branch %s: %w", branchID, err)
+		}
+	}
+	return s.store.Delete(ctx, branchID)
+}
+
+// Merge applies a branch's changes to its blob. Each path the branch
+// changed since its base is set to the branch's value, unless the blob
+// changed it too, to something else: that is a conflict, settled by the
+// resolution if one is given. The base is the blob's state at the branch
+// sequence, or the branch's state when it was last merged.
+func (s *Service) Merge(ctx context.Context, userID, branchID string, opts MergeOptions) (*MergeResult, error) {
+	if opts.Resolve != "" && opts.Resolve != ResolveBranch && opts.Resolve != ResolveParent {
+		return nil, fmt.Errorf("%w: %q, use %q or %q", ErrInvalidResolution, opts.Resolve, ResolveBranch, ResolveParent)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	branch, err := s.Get(ctx, userID, branchID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.blobs.GetBlob(ctx, userID, branch.BlobID); err != nil {
+		return nil, err
+	}
+	parentLog, err := s.deltas(ctx, branch.BlobID)
+	if err != nil {
+		return nil, err
+	}
+	branchLog, err := s.deltas(ctx, branch.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var processor workflows.DeltaProcessor
+	base := processor.Replay(parentLog, branch.BaseSequence)
+	if branch.MergedSequence > 0 {
+		base = processor.Replay(branchLog, branch.MergedSequence)
+	}
+	baseLeaves := leaves(base)
+	parentLeaves := leaves(processor.Replay(parentLog, math.MaxInt64))
+	branchLeaves := leaves(processor.Replay(branchLog, math.MaxInt64))
+	branchSequence := revisions.Latest(branchLog)
+
+	result := &MergeResult{BranchID: branch.ID, BlobID: branch.BlobID, Deltas: []workflows.Delta{}, Conflicts: []Conflict{}}
+	spelled := spellings(parentLog)
+	now := s.now()
+	for _, path := range unionPaths(baseLeaves, parentLeaves, branchLeaves) {
+		was, wasSet := baseLeaves[path]
+		theirs, theirsSet := branchLeaves[path]
+		ours, oursSet := parentLeaves[path]
+		if same(was, wasSet, theirs, theirsSet) || same(ours, oursSet, theirs, theirsSet) {
+			continue
+		}
+		resolution := ""
+		if !same(was, wasSet, ours, oursSet) {
+			result.Conflicts = append(result.Conflicts, Conflict{Path: path, Base: was, Parent: ours, Branch: theirs, Resolution: opts.Resolve})
+			if opts.Resolve != ResolveBranch {
+				continue
+			}
+			resolution = opts.Resolve
+		}
+		delta := workflows.Delta{
+			ID:        uuid.New().String(),
+			BlobID:    branch.BlobID,
+			Type:      "update",
+			Path:      spelled.path(path),
+			OldValue:  ours,
+			NewValue:  theirs,
+			Timestamp: now,
+			Metadata: map[string]interface{}{
+				"source":          "merge",
+				"branch_id":       branch.ID,
+				"branch_sequence": branchSequence,
+			},
+		}
+		if !theirsSet {
+			delta.Type, delta.NewValue = "delete", nil
+		}
+		if resolution != "" {
+			delta.Metadata["resolution"] = resolution
+		}
+		result.Deltas = append(result.Deltas, delta)
+	}
+	// Deletes go first, so a path that changes from an object to a value
+	// is cleared before it is set
+	sort.SliceStable(result.Deltas, func(i, j int) bool {
+		return result.Deltas[i].Type == "delete" && result.Deltas[j].Type != "delete"
+	})
+
+	if opts.DryRun || (len(result.Conflicts) > 0 && opts.Resolve == "") {
+		return result, nil
+	}
+	if len(result.Deltas) > 0 {
+		if err := s.committer.CommitDeltas(ctx, branch.BlobID, userID, result.Deltas); err != nil {
+			return nil, fmt.Errorf("failed to merge branch %s: %w", branch.ID, err)
+		}
+	}
+	mergedAt := now.UTC()
+	branch.MergedSequence, branch.MergedAt = branchSequence, &mergedAt
+	if err := s.store.Save(ctx, branch); err != nil {
+		return nil, err
+	}
+	result.Merged = true
+	return result, nil
+}
+
+// deltas loads a blob's delta log
+func (s *Service) deltas(ctx context.Context, blobID string) ([]workflows.Delta, error) {
+	deltas, err := s.history.GetByBlobID(ctx, blobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load deltas: %w", err)
+	}
+	return deltas, nil
+}
+
+// spelling maps a JSON pointer to the way a delta log first wrote it, for
+// stores that key state by the exact path
+type spelling map[string]string
+
+// spellings collects the path spellings of a delta log
+func spellings(deltas []workflows.Delta) spelling {
+	spelled := make(spelling)
+	for _, delta := range deltas {
+		pointer := toPointer(delta.Path)
+		if _, ok := spelled[pointer]; !ok {
+			spelled[pointer] = delta.Path
+		}
+	}
+	return spelled
+}
+
+// path returns the log's spelling of a JSON pointer, or the pointer
+func (s spelling) path(pointer string) string {
+	if path, ok := s[pointer]; ok {
+		return path
+	}
+	return pointer
+}
+
+// toPointer writes a delta path, a JSON pointer or dotted, as a JSON
+// pointer
+func toPointer(path string) string {
+	var segments []string
+	if strings.HasPrefix(path, "/") {
+		segments = strings.Split(path, "/")
+	} else {
+		segments = strings.Split(path, ".")
+	}
+	kept := segments[:0]
+	for _, segment := range segments {
+		if segment != "" {
+			kept = append(kept, segment)
+		}
+	}
+	return "/" + strings.Join(kept, "/")
+}
+
+// leaves flattens a state into its values by JSON pointer. Objects are
+// walked into; arrays, empty objects and other values are leaves.
+func leaves(state map[string]interface{}) map[string]interface{} {
+	flat := make(map[string]interface{})
+	var walk func(prefix string, value interface{})
+	walk = func(prefix string, value interface{}) {
+		object, ok := value.(map[string]interface{})
+		if !ok || len(object) == 0 {
+			flat[prefix] = value
+			return
+		}
+		for key, item := range object {
+			walk(prefix+"/"+key, item)
+		}
+	}
+	for key, value := range state {
+		walk("/"+key, value)
+	}
+	return flat
+}
+
+// unionPaths returns the paths of the states, sorted
+func unionPaths(states ...map[string]interface{}) []string {
+	seen := make(map[string]bool)
+	var paths []string
+	for _, state := range states {
+		for path := range state {
+			if !seen[path] {
+				seen[path] = true
+				paths = append(paths, path)
+			}
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// same reports whether two possibly absent values are equal as JSON
+func same(a interface{}, aSet bool, b interface{}, bSet bool) bool {
+	if !aSet || !bSet {
+		return aSet == bSet
+	}
+	left, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	right, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(left, right)
+}
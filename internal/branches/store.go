@@ -0,0 +1,134 @@
+package branches
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Store persists branches so they can be merged after a restart
+type Store interface {
+	Save(ctx context.Context, branch *Branch) error
+	Get(ctx context.Context, branchID string) (*Branch, error)
+	List(ctx context.Context) ([]*Branch, error)
+	Delete(ctx context.Context, branchID string) error
+}
+
+// FileStore keeps each branch in a JSON file under a directory, named by
+// its ID
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates a store writing under dir
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir}
+}
+
+// Save writes a branch, replacing the file atomically so a crash never
+// leaves a partial one
+func (s *FileStore) Save(ctx context.Context, branch *Branch) error {
+	path, err := s.path(branch.ID)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(branch, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal branch: %w", err)
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create branch directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(s.dir, ".branch-*")
+	if err != nil {
+		return fmt.Errorf("failed to write branch %s: %w", branch.ID, err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write branch %s: %w", branch.ID, err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write branch %s: %w", branch.ID, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write branch %s: %w", branch.ID, err)
+	}
+	return nil
+}
+
+// Get reads a branch
+func (s *FileStore) Get(ctx context.Context, branchID string) (*Branch, error) {
+	path, err := s.path(branchID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrBranchNotFound, branchID)
+	}
+	return s.read(path)
+}
+
+// List reads every branch
+func (s *FileStore) List(ctx context.Context) ([]*Branch, error) {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list branches: %w", err)
+	}
+	branches := make([]*Branch, 0, len(paths))
+	for _, path := range paths {
+		branch, err := s.read(path)
+		if errors.Is(err, ErrBranchNotFound) {
+			// Deleted since the listing
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		branches = append(branches, branch)
+	}
+	return branches, nil
+}
+
+// Delete removes a branch
+func (s *FileStore) Delete(ctx context.Context, branchID string) error {
+	path, err := s.path(branchID)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrBranchNotFound, branchID)
+	}
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%w: %s", ErrBranchNotFound, branchID)
+		}
+		return fmt.Errorf("failed to delete branch %s: %w", branchID, err)
+	}
+	return nil
+}
+
+// read decodes a branch file
+func (s *FileStore) read(path string) (*Branch, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %s", ErrBranchNotFound, strings.TrimSuffix(filepath.Base(path), ".json"))
+		}
+		return nil, fmt.Errorf("failed to read branch: %w", err)
+	}
+	var branch Branch
+	if err := json.Unmarshal(data, &branch); err != nil {
+		return nil, fmt.Errorf("failed to parse branch %s: %w", path, err)
+	}
+	return &branch, nil
+}
+
+// path maps a branch ID to its file, rejecting IDs that are not plain
+// names
+func (s *FileStore) path(branchID string) (string, error) {
+	if branchID == "" || strings.ContainsAny(branchID, `/\`) || strings.HasPrefix(branchID, ".") {
+		return "", fmt.Errorf("invalid branch id %q", branchID)
+	}
+	return filepath.Join(s.dir, branchID+".json"), nil
+}
@@ -0,0 +1,275 @@
+// Package chaos injects latency, errors and malformed outputs into workflow
+// execution so retries, failure handling and backoff can be exercised on
+// purpose. Faults are configured for a whole process or, where allowed, per
+// request, and are never enabled by default.
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Header carries a per-request fault spec where the server allows it
+const Header = "X-Chaos"
+
+// MetadataKey is the execution context metadata key a per-request spec
+// travels under from the server to step executors
+const MetadataKey = "chaos"
+
+// ErrInjected is the error injected faults fail with
+var ErrInjected = errors.New("chaos: injected failure")
+
+// Config describes the faults to inject. Rates are probabilities between 0
+// and 1; latency is drawn uniformly between LatencyMin and LatencyMax. An
+// empty Targets applies the faults everywhere, otherwise only to calls for
+// the listed workflow IDs, step IDs, step types and provider IDs.
+type Config struct {
+	LatencyMin    time.Duration `json:"latency_min"`
+	LatencyMax    time.Duration `json:"latency_max"`
+	LatencyRate   float64       `json:"latency_rate"`
+	ErrorRate     float64       `json:"error_rate"`
+	MalformedRate float64       `json:"malformed_rate"`
+	Targets       []string      `json:"targets,omitempty"`
+	Seed          int64         `json:"seed,omitempty"`
+}
+
+// Parse reads a fault spec of comma-separated settings:
+//
+//	latency=200ms-2s,latency_rate=0.5,error_rate=0.1,malformed_rate=0.05,targets=summarizer|translate,seed=7
+//
+// latency may be a single duration; latency_rate defaults to 1 when a
+// latency is given.
+func Parse(spec string) (Config, error) {
+	var cfg Config
+	latencyRate := -1.0
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return Config{}, fmt.Errorf("invalid chaos setting %q", field)
+		}
+		var err error
+		switch strings.TrimSpace(key) {
+		case "latency":
+			cfg.LatencyMin, cfg.LatencyMax, err = parseLatency(value)
+		case "latency_rate":
+			latencyRate, err = parseRate(value)
+		case "error_rate":
+			cfg.ErrorRate, err = parseRate(value)
+		case "malformed_rate":
+			cfg.MalformedRate, err = parseRate(value)
+		case "targets":
+			for _, target := range strings.Split(value, "|") {
+				if target = strings.TrimSpace(target); target != "" {
+					cfg.Targets = append(cfg.Targets, target)
+				}
+			}
+		case "seed":
+			cfg.Seed, err = strconv.ParseInt(value, 10, 64)
+		default:
+			return Config{}, fmt.Errorf("unknown chaos setting %q", key)
+		}
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid chaos setting %q: %w", field, err)
+		}
+	}
+	switch {
+	case latencyRate >= 0:
+		cfg.LatencyRate = latencyRate
+	case cfg.LatencyMax > 0:
+		cfg.LatencyRate = 1
+	}
+	return cfg, nil
+}
+
+// String formats the config as a spec Parse accepts
+func (c Config) String() string {
+	var fields []string
+	if c.LatencyMax > 0 {
+		latency := c.LatencyMin.String()
+		if c.LatencyMax != c.LatencyMin {
+			latency += "-" + c.LatencyMax.String()
+		}
+		fields = append(fields, "latency="+latency, "latency_rate="+formatRate(c.LatencyRate))
+	}
+	if c.ErrorRate > 0 {
+		fields = append(fields, "error_rate="+formatRate(c.ErrorRate))
+	}
+	if c.MalformedRate > 0 {
+		fields = append(fields, "malformed_rate="+formatRate(c.MalformedRate))
+	}
+	if len(c.Targets) > 0 {
+		fields = append(fields, "targets="+strings.Join(c.Targets, "|"))
+	}
+	if c.Seed != 0 {
+		fields = append(fields, "seed="+strconv.FormatInt(c.Seed, 10))
+	}
+	return strings.Join(fields, ",")
+}
+
+// Enabled reports whether the config injects anything
+func (c Config) Enabled() bool {
+	return (c.LatencyRate > 0 && c.LatencyMax > 0) || c.ErrorRate > 0 || c.MalformedRate > 0
+}
+
+// Injector draws fault decisions for a config. A seeded injector makes the
+// same decisions in the same order every run.
+type Injector struct {
+	cfg Config
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// NewInjector creates an injector for a config
+func NewInjector(cfg Config) *Injector {
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &Injector{cfg: cfg, rng: rand.New(rand.NewSource(seed))}
+}
+
+// Config returns the injector's config
+func (i *Injector) Config() Config {
+	return i.cfg
+}
+
+// Applies reports whether faults apply to a call concerning the given
+// workflow, step or provider IDs
+func (i *Injector) Applies(ids ...string) bool {
+	if len(i.cfg.Targets) == 0 {
+		return true
+	}
+	for _, target := range i.cfg.Targets {
+		for _, id := range ids {
+			if id != "" && id == target {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Delay sleeps for an injected latency, if one is drawn, returning early
+// with the context's error when it is cancelled
+func (i *Injector) Delay(ctx context.Context) error {
+	if i.cfg.LatencyMax <= 0 || !i.roll(i.cfg.LatencyRate) {
+		return nil
+	}
+	latency := i.cfg.LatencyMin
+	if spread := i.cfg.LatencyMax - i.cfg.LatencyMin; spread > 0 {
+		i.mu.Lock()
+		latency += time.Duration(i.rng.Int63n(int64(spread) + 1))
+		i.mu.Unlock()
+	}
+
+	timer := time.NewTimer(latency)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Fail returns an injected error, if one is drawn, naming the operation
+func (i *Injector) Fail(operation string) error {
+	if !i.roll(i.cfg.ErrorRate) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrInjected, operation)
+}
+
+// Malform corrupts an output, if a malformed output is drawn: it is dropped,
+// emptied, or has every value replaced by a string. The second result
+// reports whether the output was corrupted.
+func (i *Injector) Malform(output map[string]interface{}) (map[string]interface{}, bool) {
+	if !i.roll(i.cfg.MalformedRate) {
+		return output, false
+	}
+	i.mu.Lock()
+	kind := i.rng.Intn(3)
+	i.mu.Unlock()
+
+	switch kind {
+	case 0:
+		return nil, true
+	case 1:
+		return map[string]interface{}{}, true
+	}
+	malformed := make(map[string]interface{}, len(output)+1)
+	for key := range output {
+		malformed[key] = "chaos: malformed"
+	}
+	malformed["deltas"] = "chaos: malformed"
+	return malformed, true
+}
+
+// roll draws whether an event with the given probability happens
+func (i *Injector) roll(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.rng.Float64() < rate
+}
+
+type contextKey struct{}
+
+// WithInjector returns a context carrying a per-request injector
+func WithInjector(ctx context.Context, injector *Injector) context.Context {
+	return context.WithValue(ctx, contextKey{}, injector)
+}
+
+// FromContext returns the injector carried by a context, if any
+func FromContext(ctx context.Context) *Injector {
+	injector, _ := ctx.Value(contextKey{}).(*Injector)
+	return injector
+}
+
+// parseLatency reads "200ms" or "200ms-2s"
+func parseLatency(value string) (time.Duration, time.Duration, error) {
+	lowText, highText, ranged := strings.Cut(value, "-")
+	low, err := time.ParseDuration(strings.TrimSpace(lowText))
+	if err != nil {
+		return 0, 0, err
+	}
+	high := low
+	if ranged {
+		if high, err = time.ParseDuration(strings.TrimSpace(highText)); err != nil {
+			return 0, 0, err
+		}
+	}
+	if low < 0 || high < low {
+		return 0, 0, fmt.Errorf("latency range must be non-negative and ascending")
+	}
+	return low, high, nil
+}
+
+// parseRate reads a probability
+func parseRate(value string) (float64, error) {
+	rate, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return 0, err
+	}
+	if rate < 0 || rate > 1 {
+		return 0, fmt.Errorf("rate must be between 0 and 1")
+	}
+	return rate, nil
+}
+
+// formatRate writes a probability compactly
+func formatRate(rate float64) string {
+	return strconv.FormatFloat(rate, 'g', -1, 64)
+}
@@ -0,0 +1,146 @@
+package chaos
+
+import (
+	"context"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// Service injects faults into calls to a workflow service. Calls use the
+// injector carried by their context, set from the request header where the
+// server allows it, and otherwise the process-wide one, if any.
+type Service struct {
+	next     workflows.WorkflowService
+	injector *Injector
+}
+
+// deletingService is a Service over a backend that can delete workflows
+type deletingService struct {
+	*Service
+	deleter workflows.WorkflowDeleter
+}
+
+// WrapService wraps a workflow service with fault injection. injector may be
+// nil to inject only per-request faults. The result deletes workflows
+// exactly when the wrapped service can.
+func WrapService(next workflows.WorkflowService, injector *Injector) workflows.WorkflowService {
+	s := &Service{next: next, injector: injector}
+	if deleter, ok := next.(workflows.WorkflowDeleter); ok {
+		return &deletingService{Service: s, deleter: deleter}
+	}
+	return s
+}
+
+// ExecuteWorkflow starts an execution. A per-request spec is passed on in
+// the execution metadata so step executors can inject the same faults.
+func (s *Service) ExecuteWorkflow(ctx context.Context, req workflows.ExecutionRequest) (*workflows.ExecutionResponse, error) {
+	injector := s.active(ctx, req.WorkflowID, req.Context.ProviderID)
+	if perRequest := FromContext(ctx); perRequest != nil {
+		metadata := make(map[string]interface{}, len(req.Context.Metadata)+1)
+		for key, value := range req.Context.Metadata {
+			metadata[key] = value
+		}
+		metadata[MetadataKey] = perRequest.Config().String()
+		req.Context.Metadata = metadata
+	}
+	if err := s.before(ctx, injector, "execute workflow "+req.WorkflowID); err != nil {
+		return nil, err
+	}
+	resp, err := s.next.ExecuteWorkflow(ctx, req)
+	return s.malform(injector, resp), err
+}
+
+// GetExecutionStatus reports an execution's status
+func (s *Service) GetExecutionStatus(ctx context.Context, executionID string) (*workflows.ExecutionResponse, error) {
+	injector := s.active(ctx)
+	if err := s.before(ctx, injector, "get execution "+executionID); err != nil {
+		return nil, err
+	}
+	resp, err := s.next.GetExecutionStatus(ctx, executionID)
+	return s.malform(injector, resp), err
+}
+
+// CancelExecution cancels an execution
+func (s *Service) CancelExecution(ctx context.Context, executionID string) error {
+	if err := s.before(ctx, s.active(ctx), "cancel execution "+executionID); err != nil {
+		return err
+	}
+	return s.next.CancelExecution(ctx, executionID)
+}
+
+// RegisterWorkflow registers a workflow definition
+func (s *Service) RegisterWorkflow(ctx context.Context, workflow *workflows.BlobProcessingWorkflow) error {
+	if err := s.before(ctx, s.active(ctx, workflow.ID, workflow.ProviderID), "register workflow "+workflow.ID); err != nil {
+		return err
+	}
+	return s.next.RegisterWorkflow(ctx, workflow)
+}
+
+// UpdateWorkflow replaces a workflow definition
+func (s *Service) UpdateWorkflow(ctx context.Context, workflow *workflows.BlobProcessingWorkflow) error {
+	if err := s.before(ctx, s.active(ctx, workflow.ID, workflow.ProviderID), "update workflow "+workflow.ID); err != nil {
+		return err
+	}
+	return s.next.UpdateWorkflow(ctx, workflow)
+}
+
+// GetWorkflow returns a workflow definition
+func (s *Service) GetWorkflow(ctx context.Context, workflowID string) (*workflows.BlobProcessingWorkflow, error) {
+	if err := s.before(ctx, s.active(ctx, workflowID), "get workflow "+workflowID); err != nil {
+		return nil, err
+	}
+	return s.next.GetWorkflow(ctx, workflowID)
+}
+
+// ListWorkflows lists workflow definitions
+func (s *Service) ListWorkflows(ctx context.Context, providerID string) ([]*workflows.BlobProcessingWorkflow, error) {
+	if err := s.before(ctx, s.active(ctx, providerID), "list workflows"); err != nil {
+		return nil, err
+	}
+	return s.next.ListWorkflows(ctx, providerID)
+}
+
+// DeleteWorkflow removes a workflow definition
+func (s *deletingService) DeleteWorkflow(ctx context.Context, workflowID string) error {
+	if err := s.before(ctx, s.active(ctx, workflowID), "delete workflow "+workflowID); err != nil {
+		return err
+	}
+	return s.deleter.DeleteWorkflow(ctx, workflowID)
+}
+
+// active returns the injector for a call, or nil when no faults apply
+func (s *Service) active(ctx context.Context, ids ...string) *Injector {
+	injector := FromContext(ctx)
+	if injector == nil {
+		injector = s.injector
+	}
+	if injector == nil || (len(ids) > 0 && !injector.Applies(ids...)) {
+		return nil
+	}
+	return injector
+}
+
+// before injects latency and errors ahead of a call
+func (s *Service) before(ctx context.Context, injector *Injector, operation string) error {
+	if injector == nil {
+		return nil
+	}
+	if err := injector.Delay(ctx); err != nil {
+		return err
+	}
+	return injector.Fail(operation)
+}
+
+// malform corrupts a response's output
+func (s *Service) malform(injector *Injector, resp *workflows.ExecutionResponse) *workflows.ExecutionResponse {
+	if injector == nil || resp == nil {
+		return resp
+	}
+	output, malformed := injector.Malform(resp.Output)
+	if !malformed {
+		return resp
+	}
+	corrupted := *resp
+	corrupted.Output = output
+	return &corrupted
+}
@@ -0,0 +1,94 @@
+package chaos
+
+import (
+	"context"
+	"sync"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// maxRequestInjectors bounds how many per-request specs a step wrapper keeps
+// injectors for
+const maxRequestInjectors = 64
+
+// Steps injects faults into step executors. A step uses the spec carried in
+// its execution metadata when per-request faults are allowed, and otherwise
+// the process-wide injector, if any.
+type Steps struct {
+	injector     *Injector
+	allowRequest bool
+
+	mu         sync.Mutex
+	perRequest map[string]*Injector
+}
+
+// NewSteps creates a step fault injector. injector may be nil; allowRequest
+// honours specs passed on by the server in the execution metadata.
+func NewSteps(injector *Injector, allowRequest bool) *Steps {
+	return &Steps{
+		injector:     injector,
+		allowRequest: allowRequest,
+		perRequest:   make(map[string]*Injector),
+	}
+}
+
+// Wrap returns an executor that runs executor with faults injected. Errors
+// fail the attempt before the executor runs, so retries see them as
+// ordinary step failures; malformed outputs replace what the executor
+// returned.
+func (s *Steps) Wrap(executor workflows.StepExecutor) workflows.StepExecutor {
+	return workflows.StepExecutorFunc(func(ctx context.Context, req workflows.StepRequest) (map[string]interface{}, error) {
+		injector := s.active(req)
+		if injector == nil {
+			return executor.Execute(ctx, req)
+		}
+		if err := injector.Delay(ctx); err != nil {
+			return nil, err
+		}
+		if err := injector.Fail("step " + req.Step.ID); err != nil {
+			return nil, err
+		}
+		output, err := executor.Execute(ctx, req)
+		if err != nil {
+			return output, err
+		}
+		output, _ = injector.Malform(output)
+		return output, nil
+	})
+}
+
+// active returns the injector for a step, or nil when no faults apply
+func (s *Steps) active(req workflows.StepRequest) *Injector {
+	injector := s.injector
+	if s.allowRequest {
+		if spec, ok := req.Context.Metadata[MetadataKey].(string); ok && spec != "" {
+			injector = s.forSpec(spec)
+		}
+	}
+	if injector == nil || !injector.Applies(req.WorkflowID, req.Step.ID, req.Step.Type, req.Step.ProviderID, req.Context.ProviderID) {
+		return nil
+	}
+	return injector
+}
+
+// forSpec returns the injector for a per-request spec, creating it on first
+// use. An invalid spec injects nothing, since the server has already
+// rejected it.
+func (s *Steps) forSpec(spec string) *Injector {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if injector, ok := s.perRequest[spec]; ok {
+		return injector
+	}
+	cfg, err := Parse(spec)
+	if err != nil || !cfg.Enabled() {
+		return nil
+	}
+	if len(s.perRequest) >= maxRequestInjectors {
+		s.perRequest = make(map[string]*Injector)
+	}
+	injector := NewInjector(cfg)
+	s.perRequest[spec] = injector
+	return injector
+}
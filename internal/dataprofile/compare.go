@@ -0,0 +1,147 @@
+package dataprofile
+
+import (
+	"fmt"
+	"math"
+)
+
+// Drift thresholds between runs
+const (
+	nullRateDrift = 0.05 // absolute change in a column's null rate
+	meanDrift     = 1.0  // mean shift in base standard deviations
+)
+
+// Comparison describes how a dataset changed between two runs
+type Comparison struct {
+	Base               Run            `json:"base"`
+	Target             Run            `json:"target"`
+	RowsDelta          int            `json:"rows_delta"`
+	CompletenessDelta  float64        `json:"completeness_delta"`
+	DuplicateRowsDelta int            `json:"duplicate_rows_delta"`
+	AddedColumns       []string       `json:"added_columns"`
+	RemovedColumns     []string       `json:"removed_columns"`
+	Columns            []ColumnChange `json:"columns"`
+	Drifted            int            `json:"drifted"` // columns with a type change or drift
+	NewIssues          []Issue        `json:"new_issues"`
+	ResolvedIssues     []Issue        `json:"resolved_issues"`
+}
+
+// ColumnChange compares a column present in both runs
+type ColumnChange struct {
+	Name          string   `json:"name"`
+	TypeBefore    string   `json:"type_before"`
+	TypeAfter     string   `json:"type_after"`
+	NullRateDelta float64  `json:"null_rate_delta"`
+	DistinctDelta int      `json:"distinct_delta"`
+	MeanDelta     *float64 `json:"mean_delta,omitempty"`
+	MeanShift     *float64 `json:"mean_shift,omitempty"` // in base standard deviations
+	Drift         bool     `json:"drift"`
+	Notes         []string `json:"notes,omitempty"`
+}
+
+// Compare compares the profiles of two reports
+func Compare(base, target *Report) *Comparison {
+	before, after := base.Profile(), target.Profile()
+	c := &Comparison{
+		Base:               summarize(base),
+		Target:             summarize(target),
+		RowsDelta:          after.Rows - before.Rows,
+		CompletenessDelta:  round(after.Completeness - before.Completeness),
+		DuplicateRowsDelta: after.DuplicateRows - before.DuplicateRows,
+		AddedColumns:       []string{},
+		RemovedColumns:     []string{},
+		Columns:            []ColumnChange{},
+		NewIssues:          []Issue{},
+		ResolvedIssues:     []Issue{},
+	}
+
+	columns := make(map[string]ColumnProfile, len(before.Columns))
+	for _, column := range before.Columns {
+		columns[column.Name] = column
+	}
+	present := make(map[string]bool, len(after.Columns))
+	for _, column := range after.Columns {
+		present[column.Name] = true
+		old, ok := columns[column.Name]
+		if !ok {
+			c.AddedColumns = append(c.AddedColumns, column.Name)
+			continue
+		}
+		change := compareColumn(old, column)
+		if change.Drift {
+			c.Drifted++
+		}
+		c.Columns = append(c.Columns, change)
+	}
+	for _, column := range before.Columns {
+		if !present[column.Name] {
+			c.RemovedColumns = append(c.RemovedColumns, column.Name)
+		}
+	}
+
+	c.NewIssues = issueDifference(after.Issues, before.Issues)
+	c.ResolvedIssues = issueDifference(before.Issues, after.Issues)
+	return c
+}
+
+// compareColumn compares one column across runs
+func compareColumn(before, after ColumnProfile) ColumnChange {
+	change := ColumnChange{
+		Name:          after.Name,
+		TypeBefore:    before.Type,
+		TypeAfter:     after.Type,
+		NullRateDelta: round(after.NullRate - before.NullRate),
+		DistinctDelta: after.Distinct - before.Distinct,
+	}
+	if before.Type != after.Type {
+		change.Drift = true
+		change.Notes = append(change.Notes, fmt.Sprintf("type changed from %s to %s", before.Type, after.Type))
+	}
+	if math.Abs(change.NullRateDelta) >= nullRateDrift {
+		change.Drift = true
+		change.Notes = append(change.Notes, fmt.Sprintf("null rate moved from %.0f%% to %.0f%%", before.NullRate*100, after.NullRate*100))
+	}
+	if before.Mean != nil && after.Mean != nil {
+		change.MeanDelta = ptr(round(*after.Mean - *before.Mean))
+		if before.StdDev != nil && *before.StdDev > 0 {
+			shift := round((*after.Mean - *before.Mean) / *before.StdDev)
+			change.MeanShift = &shift
+			if math.Abs(shift) >= meanDrift {
+				change.Drift = true
+				change.Notes = append(change.Notes, fmt.Sprintf("mean moved %.1f standard deviations", shift))
+			}
+		}
+	}
+	return change
+}
+
+// issueDifference returns the issues in a with no issue of the same kind
+// and column in b
+func issueDifference(a, b []Issue) []Issue {
+	seen := make(map[[2]string]bool, len(b))
+	for _, issue := range b {
+		seen[[2]string{issue.Kind, issue.Column}] = true
+	}
+	diff := []Issue{}
+	for _, issue := range a {
+		if !seen[[2]string{issue.Kind, issue.Column}] {
+			diff = append(diff, issue)
+		}
+	}
+	return diff
+}
+
+// summarize lists a report as a run
+func summarize(r *Report) Run {
+	profile := r.Profile()
+	return Run{
+		ReportID:     r.ID,
+		SourceBlobID: r.SourceBlobID,
+		ExecutionID:  r.ExecutionID,
+		CreatedAt:    r.CreatedAt,
+		Rows:         profile.Rows,
+		Columns:      len(profile.Columns),
+		Completeness: profile.Completeness,
+		Issues:       len(profile.Issues),
+	}
+}
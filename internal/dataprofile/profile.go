@@ -0,0 +1,346 @@
+// Package dataprofile profiles tabular blobs for the data processing
+// template: column types and statistics, null rates, distributions and
+// data quality issues. Reports are stored as derived blobs so runs of a
+// pipeline can be compared.
+package dataprofile
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Column types
+const (
+	TypeNumber   = "number"
+	TypeBoolean  = "boolean"
+	TypeDatetime = "datetime"
+	TypeString   = "string"
+	TypeMixed    = "mixed"
+	TypeEmpty    = "empty" // every value is null
+)
+
+// Issue kinds
+const (
+	IssueHighNulls     = "high_null_rate"
+	IssueEmptyColumn   = "empty_column"
+	IssueConstant      = "constant_column"
+	IssueMixedTypes    = "mixed_types"
+	IssueDuplicateRows = "duplicate_rows"
+	IssueOutliers      = "outliers"
+)
+
+// Defaults for Options
+const (
+	DefaultBins          = 10
+	DefaultTopValues     = 5
+	DefaultNullThreshold = 0.2
+)
+
+// Options tunes profiling
+type Options struct {
+	Bins          int     // histogram bins for numeric columns
+	TopValues     int     // most frequent values listed per column
+	NullThreshold float64 // null rate at which a column is flagged
+}
+
+// Profile summarizes a table
+type Profile struct {
+	Format        string          `json:"format"`
+	Rows          int             `json:"rows"`
+	Columns       []ColumnProfile `json:"columns"`
+	DuplicateRows int             `json:"duplicate_rows"`
+	Completeness  float64         `json:"completeness"` // share of non-null cells
+	Issues        []Issue         `json:"issues"`
+}
+
+// ColumnProfile summarizes a column. Numeric fields are set for number
+// columns, and for the numeric values of mixed columns.
+type ColumnProfile struct {
+	Name       string         `json:"name"`
+	Type       string         `json:"type"`
+	Count      int            `json:"count"` // non-null values
+	Nulls      int            `json:"nulls"`
+	NullRate   float64        `json:"null_rate"`
+	Distinct   int            `json:"distinct"`
+	Types      map[string]int `json:"types,omitempty"` // value count per type, for mixed columns
+	Min        *float64       `json:"min,omitempty"`
+	Max        *float64       `json:"max,omitempty"`
+	Mean       *float64       `json:"mean,omitempty"`
+	StdDev     *float64       `json:"std_dev,omitempty"`
+	P25        *float64       `json:"p25,omitempty"`
+	Median     *float64       `json:"median,omitempty"`
+	P75        *float64       `json:"p75,omitempty"`
+	Outliers   int            `json:"outliers,omitempty"` // beyond 1.5 interquartile ranges
+	Histogram  []Bin          `json:"histogram,omitempty"`
+	Earliest   *time.Time     `json:"earliest,omitempty"`
+	Latest     *time.Time     `json:"latest,omitempty"`
+	MinLength  *int           `json:"min_length,omitempty"`
+	MaxLength  *int           `json:"max_length,omitempty"`
+	TopValues  []ValueCount   `json:"top_values,omitempty"`
+	TrueCount  *int           `json:"true_count,omitempty"`
+	FalseCount *int           `json:"false_count,omitempty"`
+}
+
+// Bin is a histogram bucket covering [Low, High)
+type Bin struct {
+	Low   float64 `json:"low"`
+	High  float64 `json:"high"`
+	Count int     `json:"count"`
+}
+
+// ValueCount is a value and how often it occurs
+type ValueCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// Issue is a data quality problem
+type Issue struct {
+	Kind    string `json:"kind"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// ProfileTable computes a table's profile
+func ProfileTable(t *Table, opts Options) *Profile {
+	if opts.Bins <= 0 {
+		opts.Bins = DefaultBins
+	}
+	if opts.TopValues <= 0 {
+		opts.TopValues = DefaultTopValues
+	}
+	if opts.NullThreshold <= 0 {
+		opts.NullThreshold = DefaultNullThreshold
+	}
+
+	p := &Profile{Format: t.Format, Rows: len(t.Rows), Columns: []ColumnProfile{}, Issues: []Issue{}}
+	nonNull := 0
+	for i, name := range t.Columns {
+		column := profileColumn(name, t.Rows, i, opts)
+		nonNull += column.Count
+		p.Columns = append(p.Columns, column)
+		p.Issues = append(p.Issues, columnIssues(column, opts)...)
+	}
+	if cells := len(t.Rows) * len(t.Columns); cells > 0 {
+		p.Completeness = round(float64(nonNull) / float64(cells))
+	}
+
+	seen := make(map[string]bool, len(t.Rows))
+	for _, row := range t.Rows {
+		data, _ := json.Marshal(row)
+		key := string(data)
+		if seen[key] {
+			p.DuplicateRows++
+		}
+		seen[key] = true
+	}
+	if p.DuplicateRows > 0 {
+		p.Issues = append(p.Issues, Issue{
+			Kind:    IssueDuplicateRows,
+			Message: fmt.Sprintf("%d of %d rows are duplicates", p.DuplicateRows, p.Rows),
+		})
+	}
+	return p
+}
+
+// profileColumn summarizes column i
+func profileColumn(name string, rows [][]interface{}, i int, opts Options) ColumnProfile {
+	c := ColumnProfile{Name: name, Types: make(map[string]int)}
+	var numbers []float64
+	var trues, falses int
+	var earliest, latest time.Time
+	minLength, maxLength := -1, 0
+	counts := make(map[string]int)
+
+	for _, row := range rows {
+		switch v := row[i].(type) {
+		case nil:
+			c.Nulls++
+			continue
+		case float64:
+			c.Types[TypeNumber]++
+			numbers = append(numbers, v)
+		case bool:
+			c.Types[TypeBoolean]++
+			if v {
+				trues++
+			} else {
+				falses++
+			}
+		case time.Time:
+			c.Types[TypeDatetime]++
+			if earliest.IsZero() || v.Before(earliest) {
+				earliest = v
+			}
+			if v.After(latest) {
+				latest = v
+			}
+		case string:
+			c.Types[TypeString]++
+			length := len([]rune(v))
+			if minLength < 0 || length < minLength {
+				minLength = length
+			}
+			if length > maxLength {
+				maxLength = length
+			}
+		}
+		c.Count++
+		counts[cellString(row[i])]++
+	}
+
+	if len(rows) > 0 {
+		c.NullRate = round(float64(c.Nulls) / float64(len(rows)))
+	}
+	c.Distinct = len(counts)
+	switch len(c.Types) {
+	case 0:
+		c.Type = TypeEmpty
+	case 1:
+		for kind := range c.Types {
+			c.Type = kind
+		}
+		c.Types = nil
+	default:
+		c.Type = TypeMixed
+	}
+
+	if len(numbers) > 0 {
+		numericStats(&c, numbers, opts.Bins)
+	}
+	if c.Type == TypeBoolean {
+		c.TrueCount, c.FalseCount = &trues, &falses
+	}
+	if !earliest.IsZero() {
+		c.Earliest, c.Latest = &earliest, &latest
+	}
+	if minLength >= 0 {
+		c.MinLength, c.MaxLength = &minLength, &maxLength
+	}
+	// Listing the most common numbers says little about a continuous column
+	if c.Type != TypeNumber || c.Distinct <= opts.Bins {
+		c.TopValues = topValues(counts, opts.TopValues)
+	}
+	return c
+}
+
+// numericStats fills in a column's numeric statistics and histogram
+func numericStats(c *ColumnProfile, numbers []float64, bins int) {
+	sort.Float64s(numbers)
+	sum := 0.0
+	for _, n := range numbers {
+		sum += n
+	}
+	mean := sum / float64(len(numbers))
+	variance := 0.0
+	for _, n := range numbers {
+		variance += (n - mean) * (n - mean)
+	}
+	stddev := math.Sqrt(variance / float64(len(numbers)))
+
+	lo, hi := numbers[0], numbers[len(numbers)-1]
+	p25, median, p75 := percentile(numbers, 0.25), percentile(numbers, 0.5), percentile(numbers, 0.75)
+	c.Min, c.Max = ptr(lo), ptr(hi)
+	c.Mean, c.StdDev = ptr(round(mean)), ptr(round(stddev))
+	c.P25, c.Median, c.P75 = ptr(round(p25)), ptr(round(median)), ptr(round(p75))
+
+	iqr := p75 - p25
+	for _, n := range numbers {
+		if n < p25-1.5*iqr || n > p75+1.5*iqr {
+			c.Outliers++
+		}
+	}
+
+	if lo == hi {
+		c.Histogram = []Bin{{Low: lo, High: hi, Count: len(numbers)}}
+		return
+	}
+	width := (hi - lo) / float64(bins)
+	c.Histogram = make([]Bin, bins)
+	for b := range c.Histogram {
+		c.Histogram[b].Low = round(lo + float64(b)*width)
+		c.Histogram[b].High = round(lo + float64(b+1)*width)
+	}
+	for _, n := range numbers {
+		b := int((n - lo) / width)
+		if b >= bins {
+			b = bins - 1 // the maximum closes the last bin
+		}
+		c.Histogram[b].Count++
+	}
+}
+
+// columnIssues flags a column's quality problems
+func columnIssues(c ColumnProfile, opts Options) []Issue {
+	var issues []Issue
+	switch {
+	case c.Type == TypeEmpty:
+		issues = append(issues, Issue{Kind: IssueEmptyColumn, Column: c.Name, Message: fmt.Sprintf("%s has no values", c.Name)})
+	case c.NullRate >= opts.NullThreshold:
+		issues = append(issues, Issue{Kind: IssueHighNulls, Column: c.Name, Message: fmt.Sprintf("%s is %.0f%% null", c.Name, c.NullRate*100)})
+	}
+	if c.Distinct == 1 && c.Count > 1 {
+		issues = append(issues, Issue{Kind: IssueConstant, Column: c.Name, Message: fmt.Sprintf("%s has a single value", c.Name)})
+	}
+	if c.Type == TypeMixed {
+		kinds := make([]string, 0, len(c.Types))
+		for kind, count := range c.Types {
+			kinds = append(kinds, fmt.Sprintf("%d %s", count, kind))
+		}
+		sort.Strings(kinds)
+		issues = append(issues, Issue{Kind: IssueMixedTypes, Column: c.Name, Message: fmt.Sprintf("%s mixes types: %s", c.Name, strings.Join(kinds, ", "))})
+	}
+	if c.Outliers > 0 && float64(c.Outliers) > 0.01*float64(c.Count) {
+		issues = append(issues, Issue{Kind: IssueOutliers, Column: c.Name, Message: fmt.Sprintf("%s has %d outlying values", c.Name, c.Outliers)})
+	}
+	return issues
+}
+
+// topValues returns the n most frequent values, ties broken by value
+func topValues(counts map[string]int, n int) []ValueCount {
+	values := make([]ValueCount, 0, len(counts))
+	for value, count := range counts {
+		values = append(values, ValueCount{Value: value, Count: count})
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if values[i].Count != values[j].Count {
+			return values[i].Count > values[j].Count
+		}
+		return values[i].Value < values[j].Value
+	})
+	if len(values) > n {
+		values = values[:n]
+	}
+	return values
+}
+
+// percentile interpolates the q quantile of sorted values
+func percentile(sorted []float64, q float64) float64 {
+	pos := q * float64(len(sorted)-1)
+	lower := int(pos)
+	if lower+1 >= len(sorted) {
+		return sorted[lower]
+	}
+	return sorted[lower] + (pos-float64(lower))*(sorted[lower+1]-sorted[lower])
+}
+
+// cellString formats a cell for distinct counts and top values
+func cellString(cell interface{}) string {
+	if t, ok := cell.(time.Time); ok {
+		return t.Format(time.RFC3339)
+	}
+	return fmt.Sprint(cell)
+}
+
+func ptr(f float64) *float64 {
+	return &f
+}
+
+// round keeps four decimal places
+func round(f float64) float64 {
+	return math.Round(f*10000) / 10000
+}
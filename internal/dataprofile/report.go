@@ -0,0 +1,211 @@
+package dataprofile
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/memmieai/memmie-studio/internal/blob"
+)
+
+// KindReport marks data quality report blobs
+const KindReport = "data_quality_report"
+
+// ErrReportNotFound is returned when a report does not exist
+var ErrReportNotFound = errors.New("data quality report not found")
+
+// Report is a pipeline run's data quality report. Input profiles the blob
+// as submitted and Output the processed data, when the pipeline produced
+// any.
+type Report struct {
+	ID           string      `json:"id"`
+	DatasetID    string      `json:"dataset_id"`
+	SourceBlobID string      `json:"source_blob_id"`
+	ExecutionID  string      `json:"execution_id,omitempty"`
+	CreatedAt    time.Time   `json:"created_at"`
+	Input        *Profile    `json:"input"`
+	Output       *Profile    `json:"output,omitempty"`
+	Validation   interface{} `json:"validation,omitempty"`
+}
+
+// Profile returns the profile runs are compared on: the output when there
+// is one, otherwise the input
+func (r *Report) Profile() *Profile {
+	if r.Output != nil {
+		return r.Output
+	}
+	return r.Input
+}
+
+// Run is a report's summary in run listings
+type Run struct {
+	ReportID     string    `json:"report_id"`
+	SourceBlobID string    `json:"source_blob_id"`
+	ExecutionID  string    `json:"execution_id,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	Rows         int       `json:"rows"`
+	Columns      int       `json:"columns"`
+	Completeness float64   `json:"completeness"`
+	Issues       int       `json:"issues"`
+}
+
+// Service stores reports as derived blobs of the profiled data and reads
+// them back per dataset
+type Service struct {
+	blobs blob.Store
+}
+
+// NewService creates a report service
+func NewService(blobs blob.Store) *Service {
+	return &Service{blobs: blobs}
+}
+
+// ProviderID returns the data processing provider of a dataset, which
+// owns its report blobs
+func ProviderID(datasetID string) string {
+	return "dataset:" + datasetID
+}
+
+// Save stores a report as a child blob of its source blob. The report's ID
+// and creation time are set from the stored blob.
+func (s *Service) Save(ctx context.Context, userID string, r *Report) (*Report, error) {
+	content, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode report: %w", err)
+	}
+	profile := r.Profile()
+	parentID := r.SourceBlobID
+	b, err := s.blobs.CreateBlob(ctx, &blob.Blob{
+		UserID:     userID,
+		ProviderID: ProviderID(r.DatasetID),
+		Content:    string(content),
+		ParentID:   &parentID,
+		Metadata: map[string]interface{}{
+			"kind":         KindReport,
+			"dataset_id":   r.DatasetID,
+			"derived_from": r.SourceBlobID,
+			"execution_id": r.ExecutionID,
+			"rows":         profile.Rows,
+			"columns":      len(profile.Columns),
+			"completeness": profile.Completeness,
+			"issues":       len(profile.Issues),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store report: %w", err)
+	}
+	saved := *r
+	saved.ID = b.ID
+	saved.CreatedAt = b.CreatedAt
+	return &saved, nil
+}
+
+// Get loads one of a dataset's reports
+func (s *Service) Get(ctx context.Context, userID, datasetID, reportID string) (*Report, error) {
+	b, err := s.blobs.GetBlob(ctx, userID, reportID)
+	if errors.Is(err, blob.ErrNotFound) {
+		return nil, ErrReportNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load report: %w", err)
+	}
+	if b.Metadata["kind"] != KindReport || b.ProviderID != ProviderID(datasetID) {
+		return nil, ErrReportNotFound
+	}
+	return decodeReport(b)
+}
+
+// Runs lists a dataset's reports, newest first
+func (s *Service) Runs(ctx context.Context, userID, datasetID string) ([]Run, error) {
+	reports, err := s.reports(ctx, userID, datasetID)
+	if err != nil {
+		return nil, err
+	}
+	runs := make([]Run, 0, len(reports))
+	for _, r := range reports {
+		runs = append(runs, summarize(r))
+	}
+	return runs, nil
+}
+
+// Compare compares two of a dataset's reports. An empty target is the latest
+// report and an empty base the report before the target.
+func (s *Service) Compare(ctx context.Context, userID, datasetID, baseID, targetID string) (*Comparison, error) {
+	var reports []*Report
+	if baseID == "" || targetID == "" {
+		var err error
+		if reports, err = s.reports(ctx, userID, datasetID); err != nil {
+			return nil, err
+		}
+	}
+
+	var target *Report
+	var err error
+	if targetID != "" {
+		if target, err = s.Get(ctx, userID, datasetID, targetID); err != nil {
+			return nil, err
+		}
+	} else if len(reports) > 0 {
+		target = reports[0]
+	} else {
+		return nil, fmt.Errorf("%w: dataset %s has no runs", ErrReportNotFound, datasetID)
+	}
+
+	var base *Report
+	if baseID != "" {
+		if base, err = s.Get(ctx, userID, datasetID, baseID); err != nil {
+			return nil, err
+		}
+	} else {
+		for _, r := range reports {
+			if r.ID != target.ID && !r.CreatedAt.After(target.CreatedAt) {
+				base = r
+				break
+			}
+		}
+		if base == nil {
+			return nil, fmt.Errorf("%w: no run before %s", ErrReportNotFound, target.ID)
+		}
+	}
+	return Compare(base, target), nil
+}
+
+// reports loads a dataset's reports, newest first
+func (s *Service) reports(ctx context.Context, userID, datasetID string) ([]*Report, error) {
+	blobs, err := s.blobs.ListBlobs(ctx, userID, blob.Filter{ProviderID: ProviderID(datasetID)})
+	if err != nil && !errors.Is(err, blob.ErrNotFound) {
+		return nil, fmt.Errorf("failed to list reports: %w", err)
+	}
+	var reports []*Report
+	for _, b := range blobs {
+		if b.Metadata["kind"] != KindReport {
+			continue
+		}
+		r, err := decodeReport(b)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, r)
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].CreatedAt.After(reports[j].CreatedAt)
+	})
+	return reports, nil
+}
+
+// decodeReport reads a report blob
+func decodeReport(b *blob.Blob) (*Report, error) {
+	var r Report
+	if err := json.Unmarshal([]byte(b.Content), &r); err != nil {
+		return nil, fmt.Errorf("failed to decode report %s: %w", b.ID, err)
+	}
+	r.ID = b.ID
+	r.CreatedAt = b.CreatedAt
+	if r.Input == nil {
+		return nil, fmt.Errorf("failed to decode report %s: no profile", b.ID)
+	}
+	return &r, nil
+}
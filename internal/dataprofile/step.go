@@ -0,0 +1,131 @@
+package dataprofile
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// StepType is the step type the profiling executor is registered under
+const StepType = "data_profile"
+
+// SummaryPath is the source blob metadata path of its latest report summary
+const SummaryPath = "metadata.data_quality"
+
+// NewStepExecutor creates the data profiling executor. Step inputs:
+// original_data (the blob content), format (its format; detected when
+// empty), processed_data (the pipeline's output, as text or decoded
+// records), output_format, and validation_results, which are copied into
+// the report. histogram_bins, top_values and null_threshold fall back to
+// the step's parameters. The report is stored as a child blob of the
+// source blob, and a summary is written to the source blob's metadata.
+func NewStepExecutor(service *Service) workflows.StepExecutor {
+	return workflows.StepExecutorFunc(func(ctx context.Context, req workflows.StepRequest) (map[string]interface{}, error) {
+		original, _ := req.Input["original_data"].(string)
+		format, _ := req.Input["format"].(string)
+		opts := Options{
+			Bins:          toInt(req.Setting("histogram_bins")),
+			TopValues:     toInt(req.Setting("top_values")),
+			NullThreshold: toFloat(req.Setting("null_threshold")),
+		}
+
+		table, err := Parse(original, format)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read original data: %w", err)
+		}
+		report := &Report{
+			DatasetID:    strings.TrimPrefix(req.Context.ProviderID, "dataset:"),
+			SourceBlobID: req.Context.BlobID,
+			ExecutionID:  req.ExecutionID,
+			Input:        ProfileTable(table, opts),
+			Validation:   req.Input["validation_results"],
+		}
+
+		output := map[string]interface{}{}
+		if processed := req.Input["processed_data"]; processed != nil {
+			outputFormat, _ := req.Input["output_format"].(string)
+			if table, err := processedTable(processed, outputFormat); err != nil {
+				// The input profile is still worth keeping
+				output["output_error"] = err.Error()
+			} else {
+				report.Output = ProfileTable(table, opts)
+			}
+		}
+
+		saved, err := service.Save(ctx, req.Context.UserID, report)
+		if err != nil {
+			return nil, err
+		}
+
+		profile := saved.Profile()
+		summary := map[string]interface{}{
+			"report_id":    saved.ID,
+			"rows":         profile.Rows,
+			"columns":      len(profile.Columns),
+			"completeness": profile.Completeness,
+			"issues":       len(profile.Issues),
+		}
+		output["report_id"] = saved.ID
+		output["report"] = saved
+		output["summary"] = summary
+		output["deltas"] = []interface{}{
+			map[string]interface{}{
+				"type":      "update",
+				"path":      SummaryPath,
+				"new_value": summary,
+				"metadata": map[string]interface{}{
+					"step_id":      req.Step.ID,
+					"execution_id": req.ExecutionID,
+				},
+			},
+		}
+		return output, nil
+	})
+}
+
+// processedTable reads a transform step's output: text in the output
+// format, a list of records, or a map holding either under data, records,
+// rows or content
+func processedTable(value interface{}, format string) (*Table, error) {
+	switch v := value.(type) {
+	case string:
+		return Parse(v, format)
+	case []interface{}:
+		records := make([]map[string]interface{}, 0, len(v))
+		for i, item := range v {
+			record, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%w: record %d is not an object", ErrInvalidData, i+1)
+			}
+			records = append(records, record)
+		}
+		return FromRecords(records, FormatJSON), nil
+	case map[string]interface{}:
+		for _, field := range []string{"data", "records", "rows", "content"} {
+			if inner, ok := v[field]; ok {
+				return processedTable(inner, format)
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: unrecognized processed data", ErrInvalidData)
+}
+
+// toInt converts a JSON number to an int
+func toInt(value interface{}) int {
+	return int(toFloat(value))
+}
+
+// toFloat converts a JSON number to a float64
+func toFloat(value interface{}) float64 {
+	switch v := value.(type) {
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	}
+	return 0
+}
@@ -0,0 +1,212 @@
+package dataprofile
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Supported tabular formats
+const (
+	FormatCSV   = "csv"
+	FormatTSV   = "tsv"
+	FormatJSON  = "json"  // an array of objects
+	FormatJSONL = "jsonl" // one object per line
+)
+
+// ErrInvalidData is returned for content that cannot be read as a table
+var ErrInvalidData = errors.New("invalid tabular data")
+
+// nullStrings are text cells treated as missing values
+var nullStrings = map[string]bool{
+	"": true, "null": true, "nil": true, "none": true, "na": true, "n/a": true, "nan": true, "-": true,
+}
+
+// dateLayouts are the layouts tried when detecting date and time cells
+var dateLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02", "01/02/2006"}
+
+// Table is parsed tabular data. Cells are nil, float64, bool, time.Time or
+// string.
+type Table struct {
+	Format  string
+	Columns []string
+	Rows    [][]interface{}
+}
+
+// Parse reads CSV, TSV, a JSON array of objects, or JSON lines. An empty
+// format is detected from the content.
+func Parse(content, format string) (*Table, error) {
+	content = strings.TrimPrefix(content, "\ufeff")
+	if strings.TrimSpace(content) == "" {
+		return nil, fmt.Errorf("%w: no data", ErrInvalidData)
+	}
+	if format == "" {
+		format = detectFormat(content)
+	}
+
+	switch strings.ToLower(format) {
+	case FormatCSV:
+		return parseDelimited(content, FormatCSV, sniffDelimiter(content))
+	case FormatTSV:
+		return parseDelimited(content, FormatTSV, '\t')
+	case FormatJSON:
+		var records []map[string]interface{}
+		if err := json.Unmarshal([]byte(content), &records); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidData, err)
+		}
+		return FromRecords(records, FormatJSON), nil
+	case FormatJSONL, "ndjson":
+		var records []map[string]interface{}
+		dec := json.NewDecoder(strings.NewReader(content))
+		for dec.More() {
+			var record map[string]interface{}
+			if err := dec.Decode(&record); err != nil {
+				return nil, fmt.Errorf("%w: record %d: %v", ErrInvalidData, len(records)+1, err)
+			}
+			records = append(records, record)
+		}
+		return FromRecords(records, FormatJSONL), nil
+	}
+	return nil, fmt.Errorf("%w: unsupported format %q", ErrInvalidData, format)
+}
+
+// FromRecords builds a table from decoded JSON objects. Columns are the
+// union of the records' keys, each record adding its new keys in sorted
+// order.
+func FromRecords(records []map[string]interface{}, format string) *Table {
+	t := &Table{Format: format}
+	index := make(map[string]int)
+	for _, record := range records {
+		var fresh []string
+		for key := range record {
+			if _, ok := index[key]; !ok {
+				fresh = append(fresh, key)
+			}
+		}
+		sort.Strings(fresh)
+		for _, key := range fresh {
+			index[key] = len(t.Columns)
+			t.Columns = append(t.Columns, key)
+		}
+	}
+	for _, record := range records {
+		row := make([]interface{}, len(t.Columns))
+		for key, value := range record {
+			row[index[key]] = jsonCell(value)
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	return t
+}
+
+// parseDelimited reads delimited text with a header row
+func parseDelimited(content, format string, delimiter rune) (*Table, error) {
+	r := csv.NewReader(strings.NewReader(content))
+	r.Comma = delimiter
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidData, err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%w: no header row", ErrInvalidData)
+	}
+
+	t := &Table{Format: format}
+	for i, name := range records[0] {
+		if name = strings.TrimSpace(name); name == "" {
+			name = fmt.Sprintf("column_%d", i+1)
+		}
+		t.Columns = append(t.Columns, name)
+	}
+	for _, record := range records[1:] {
+		row := make([]interface{}, len(t.Columns))
+		for i := range row {
+			if i < len(record) {
+				row[i] = textCell(record[i])
+			}
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	return t, nil
+}
+
+// textCell types a text cell
+func textCell(text string) interface{} {
+	text = strings.TrimSpace(text)
+	if nullStrings[strings.ToLower(text)] {
+		return nil
+	}
+	// ParseFloat also reads hex and infinities, which are not numbers here
+	if n, err := strconv.ParseFloat(text, 64); err == nil && !math.IsInf(n, 0) && !strings.ContainsAny(text, "xX") {
+		return n
+	}
+	switch strings.ToLower(text) {
+	case "true", "yes":
+		return true
+	case "false", "no":
+		return false
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, text); err == nil {
+			return t
+		}
+	}
+	return text
+}
+
+// jsonCell types a decoded JSON value. Nested values are kept as their JSON
+// text.
+func jsonCell(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil, float64, bool:
+		return v
+	case string:
+		if strings.TrimSpace(v) == "" {
+			return nil
+		}
+		for _, layout := range dateLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t
+			}
+		}
+		return v
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
+
+// detectFormat guesses a format from the first non-space character
+func detectFormat(content string) string {
+	trimmed := strings.TrimSpace(content)
+	switch trimmed[0] {
+	case '[':
+		return FormatJSON
+	case '{':
+		return FormatJSONL
+	}
+	if sniffDelimiter(content) == '\t' {
+		return FormatTSV
+	}
+	return FormatCSV
+}
+
+// sniffDelimiter picks the most common candidate delimiter in the header
+func sniffDelimiter(content string) rune {
+	header, _, _ := strings.Cut(content, "\n")
+	best, bestCount := ',', 0
+	for _, delimiter := range []rune{',', '\t', ';', '|'} {
+		if count := strings.Count(header, string(delimiter)); count > bestCount {
+			best, bestCount = delimiter, count
+		}
+	}
+	return best
+}
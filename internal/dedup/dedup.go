@@ -0,0 +1,221 @@
+// Package dedup finds blobs of a namespace that duplicate one another,
+// exactly by content hash or nearly by embedding similarity
+package dedup
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/memmieai/memmie-studio/internal/blob"
+	"github.com/memmieai/memmie-studio/internal/embeddings"
+)
+
+// Defaults for a check
+const (
+	DefaultThreshold = 0.95
+	DefaultMaxWords  = 2000
+	DefaultLimit     = 5
+)
+
+// How a duplicate matched
+const (
+	MatchExact = "exact" // same content, ignoring case and whitespace
+	MatchNear  = "near"  // embeddings at or above the threshold
+)
+
+// Request describes a blob to check against its namespace
+type Request struct {
+	UserID      string
+	NamespaceID string
+	BlobID      string  // excluded from the comparison; optional
+	Content     string  // the text to check
+	Threshold   float64 // minimum cosine similarity of a near duplicate
+}
+
+// Duplicate is a blob of the namespace that duplicates the checked one
+type Duplicate struct {
+	BlobID    string    `json:"blob_id"`
+	Title     string    `json:"title,omitempty"`
+	Kind      string    `json:"kind"`
+	Score     float64   `json:"score"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Report is the result of a check. DuplicateOf is the earliest duplicate
+// created before the checked blob, which it copies; it is empty when the
+// checked blob came first.
+type Report struct {
+	ContentHash string      `json:"content_hash"`
+	Duplicates  []Duplicate `json:"duplicates"`
+	DuplicateOf string      `json:"duplicate_of,omitempty"`
+}
+
+// Detector compares blobs with the others in their namespace. Each blob is
+// embedded as a whole, on its first words, and the index doubles as a
+// cache: a blob is embedded again only when its content changes.
+type Detector struct {
+	blobs    blob.Store
+	embedder embeddings.Embedder
+	index    embeddings.Index
+	maxWords int
+	limit    int
+}
+
+// NewDetector creates a detector
+func NewDetector(blobs blob.Store, embedder embeddings.Embedder, index embeddings.Index) *Detector {
+	return &Detector{
+		blobs:    blobs,
+		embedder: embedder,
+		index:    index,
+		maxWords: DefaultMaxWords,
+		limit:    DefaultLimit,
+	}
+}
+
+// Check finds the blobs of the request's namespace with the same content,
+// or content whose embedding scores at or above the threshold, best first
+func (d *Detector) Check(ctx context.Context, req Request) (*Report, error) {
+	if req.NamespaceID == "" {
+		return nil, fmt.Errorf("namespace is required")
+	}
+	threshold := req.Threshold
+	if threshold <= 0 {
+		threshold = DefaultThreshold
+	}
+	report := &Report{ContentHash: ContentHash(req.Content), Duplicates: []Duplicate{}}
+
+	listed, err := d.blobs.ListBlobs(ctx, req.UserID, blob.Filter{NamespaceID: req.NamespaceID})
+	if err != nil && !errors.Is(err, blob.ErrNotFound) {
+		return nil, fmt.Errorf("failed to list namespace %s: %w", req.NamespaceID, err)
+	}
+	var createdAt time.Time
+	others := make(map[string]*blob.Blob, len(listed))
+	for _, b := range listed {
+		switch {
+		case b.ID == req.BlobID:
+			createdAt = b.CreatedAt
+		case !b.Trashed():
+			others[b.ID] = b
+		}
+	}
+
+	found := make(map[string]bool)
+	for _, b := range others {
+		if ContentHash(b.Content) == report.ContentHash {
+			report.Duplicates = append(report.Duplicates, duplicateOf(b, MatchExact, 1))
+			found[b.ID] = true
+		}
+	}
+
+	collection := fmt.Sprintf("user:%s/namespace:%s", req.UserID, req.NamespaceID)
+	for _, b := range others {
+		if err := d.indexBlob(ctx, collection, b); err != nil {
+			return nil, err
+		}
+	}
+	if text := leadingWords(req.Content, d.maxWords); text != "" {
+		vectors, err := d.embedder.Embed(ctx, []string{text})
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed content: %w", err)
+		}
+		// Extra results leave room for the checked blob itself and blobs
+		// that have left the namespace since they were indexed
+		matches, err := d.index.Search(ctx, collection, vectors[0], d.limit+1+len(found))
+		if err != nil {
+			return nil, fmt.Errorf("failed to search %s: %w", collection, err)
+		}
+		for _, m := range matches {
+			b, ok := others[m.Source]
+			if !ok || found[m.Source] || m.Score < threshold {
+				continue
+			}
+			report.Duplicates = append(report.Duplicates, duplicateOf(b, MatchNear, round(m.Score)))
+			found[m.Source] = true
+		}
+	}
+
+	sort.SliceStable(report.Duplicates, func(i, j int) bool {
+		if report.Duplicates[i].Score != report.Duplicates[j].Score {
+			return report.Duplicates[i].Score > report.Duplicates[j].Score
+		}
+		return report.Duplicates[i].BlobID < report.Duplicates[j].BlobID
+	})
+	if len(report.Duplicates) > d.limit {
+		report.Duplicates = report.Duplicates[:d.limit]
+	}
+
+	var original *Duplicate
+	for i, dup := range report.Duplicates {
+		if !createdAt.IsZero() && !dup.CreatedAt.Before(createdAt) {
+			continue
+		}
+		if original == nil || dup.CreatedAt.Before(original.CreatedAt) {
+			original = &report.Duplicates[i]
+		}
+	}
+	if original != nil {
+		report.DuplicateOf = original.BlobID
+	}
+	return report, nil
+}
+
+// indexBlob embeds a blob unless the index already holds it for the same
+// content
+func (d *Detector) indexBlob(ctx context.Context, collection string, b *blob.Blob) error {
+	version := ContentHash(b.Content)
+	indexed, err := d.index.Version(ctx, collection, b.ID)
+	if err != nil {
+		return fmt.Errorf("failed to read index version: %w", err)
+	}
+	if indexed == version {
+		return nil
+	}
+
+	var docs []embeddings.Document
+	if text := leadingWords(b.Content, d.maxWords); text != "" {
+		vectors, err := d.embedder.Embed(ctx, []string{text})
+		if err != nil {
+			return fmt.Errorf("failed to embed %s: %w", b.ID, err)
+		}
+		docs = append(docs, embeddings.Document{ID: b.ID, Vector: vectors[0]})
+	}
+	if err := d.index.Replace(ctx, collection, b.ID, version, docs); err != nil {
+		return fmt.Errorf("failed to index %s: %w", b.ID, err)
+	}
+	return nil
+}
+
+// ContentHash identifies content regardless of case and whitespace, so
+// the same text imported twice hashes the same
+func ContentHash(content string) string {
+	normalized := strings.Join(strings.Fields(strings.ToLower(content)), " ")
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// duplicateOf describes a duplicate blob
+func duplicateOf(b *blob.Blob, kind string, score float64) Duplicate {
+	title, _ := b.Metadata["title"].(string)
+	return Duplicate{BlobID: b.ID, Title: title, Kind: kind, Score: score, CreatedAt: b.CreatedAt}
+}
+
+// leadingWords returns up to the first max words of a text
+func leadingWords(text string, max int) string {
+	words := strings.Fields(text)
+	if len(words) > max {
+		words = words[:max]
+	}
+	return strings.Join(words, " ")
+}
+
+// round rounds to three decimal places
+func round(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
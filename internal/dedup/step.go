@@ -0,0 +1,86 @@
+package dedup
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// StepType is the step type the duplicate check executor is registered under
+const StepType = "dedup_check"
+
+// SummaryPath is the delta path the duplicate check writes its report to
+const SummaryPath = "metadata.dedup"
+
+// NewStepExecutor creates the duplicate check executor. Step inputs:
+// content, namespace_id and threshold; namespace_id and threshold fall back
+// to the step's parameters. The output's duplicate is true when the blob
+// copies an earlier one, for later steps' conditions to skip on, and the
+// report is written to the blob's metadata, linking it to its duplicates.
+func NewStepExecutor(detector *Detector) workflows.StepExecutor {
+	return workflows.StepExecutorFunc(func(ctx context.Context, req workflows.StepRequest) (map[string]interface{}, error) {
+		content, _ := req.Input["content"].(string)
+		if content == "" {
+			return nil, fmt.Errorf("content is required")
+		}
+		namespaceID, _ := req.Setting("namespace_id").(string)
+		if namespaceID == "" {
+			return nil, fmt.Errorf("namespace_id is required")
+		}
+		threshold, _ := req.Setting("threshold").(float64)
+
+		report, err := detector.Check(ctx, Request{
+			UserID:      req.Context.UserID,
+			NamespaceID: namespaceID,
+			BlobID:      req.Context.BlobID,
+			Content:     content,
+			Threshold:   threshold,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		duplicates := make([]interface{}, len(report.Duplicates))
+		for i, dup := range report.Duplicates {
+			duplicates[i] = dup.Map()
+		}
+		summary := map[string]interface{}{
+			"content_hash": report.ContentHash,
+			"duplicate_of": report.DuplicateOf,
+			"duplicates":   duplicates,
+		}
+
+		return map[string]interface{}{
+			"duplicate":    report.DuplicateOf != "",
+			"duplicate_of": report.DuplicateOf,
+			"duplicates":   duplicates,
+			"content_hash": report.ContentHash,
+			"deltas": []interface{}{
+				map[string]interface{}{
+					"type":      "update",
+					"path":      SummaryPath,
+					"new_value": summary,
+					"metadata": map[string]interface{}{
+						"step_id":      req.Step.ID,
+						"execution_id": req.ExecutionID,
+					},
+				},
+			},
+		}, nil
+	})
+}
+
+// Map converts a duplicate to the map form used in step outputs
+func (d Duplicate) Map() map[string]interface{} {
+	m := map[string]interface{}{
+		"blob_id":    d.BlobID,
+		"kind":       d.Kind,
+		"score":      d.Score,
+		"created_at": d.CreatedAt,
+	}
+	if d.Title != "" {
+		m["title"] = d.Title
+	}
+	return m
+}
@@ -0,0 +1,428 @@
+// Package postgres stores blob deltas in PostgreSQL. Each blob's deltas are
+// numbered from 1 without gaps, in the order their transactions commit,
+// however many providers write to the blob at once.
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// schema creates the storage's tables. delta_sequences holds the last
+// sequence given out for each blob and the hash of the delta stored under
+// it; its row is locked by the transaction taking the next, so writers to
+// a blob take turns, each delta chains to the one before and a rolled back
+// transaction gives its sequences back. Deltas stored before the hash
+// columns were added are left unchained.
+const schema = `
+CREATE TABLE IF NOT EXISTS delta_sequences (
+	blob_id       TEXT PRIMARY KEY,
+	last_sequence BIGINT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS deltas (
+	id          TEXT PRIMARY KEY,
+	blob_id     TEXT NOT NULL,
+	sequence    BIGINT NOT NULL,
+	provider_id TEXT NOT NULL,
+	type        TEXT NOT NULL,
+	path        TEXT NOT NULL,
+	old_value   JSONB,
+	new_value   JSONB,
+	metadata    JSONB,
+	created_at  TIMESTAMPTZ NOT NULL,
+	UNIQUE (blob_id, sequence)
+);
+ALTER TABLE delta_sequences ADD COLUMN IF NOT EXISTS last_hash TEXT;
+ALTER TABLE deltas ADD COLUMN IF NOT EXISTS hash TEXT;
+ALTER TABLE deltas ADD COLUMN IF NOT EXISTS prev_hash TEXT;
+ALTER TABLE deltas ADD COLUMN IF NOT EXISTS signature TEXT;
+ALTER TABLE deltas ADD COLUMN IF NOT EXISTS key_id TEXT;
+CREATE INDEX IF NOT EXISTS deltas_blob_path ON deltas (blob_id, path);
+CREATE TABLE IF NOT EXISTS blob_state (
+	blob_id TEXT NOT NULL,
+	path    TEXT NOT NULL,
+	value   JSONB,
+	PRIMARY KEY (blob_id, path)
+);
+CREATE TABLE IF NOT EXISTS delta_outbox (
+	position BIGSERIAL PRIMARY KEY,
+	id       TEXT NOT NULL UNIQUE,
+	event    JSONB NOT NULL
+);
+`
+
+// deltaColumns are the columns queryDeltas reads, in order
+const deltaColumns = `id, blob_id, sequence, provider_id, type, path, old_value, new_value, metadata, created_at,
+	hash, prev_hash, signature, key_id`
+
+// Storage implements workflows.OutboxStorage, workflows.BatchStorage and
+// workflows.StateStorage on PostgreSQL. The database handle is opened by
+// the caller with a Postgres driver registered, such as github.com/lib/pq.
+type Storage struct {
+	db   *sql.DB
+	keys *workflows.DeltaKeyring
+}
+
+// NewStorage creates a storage over a database. Migrate creates its tables.
+func NewStorage(db *sql.DB) *Storage {
+	return &Storage{db: db}
+}
+
+// SetSigningKeys sets the keys deltas are signed with as they are
+// chained; without a signing key they are chained unsigned
+func (s *Storage) SetSigningKeys(keys *workflows.DeltaKeyring) {
+	s.keys = keys
+}
+
+// Migrate creates the storage's tables if they do not exist
+func (s *Storage) Migrate(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, schema); err != nil {
+		return fmt.Errorf("failed to migrate delta storage: %w", err)
+	}
+	return nil
+}
+
+// Store appends a delta to its blob's log under the blob's next sequence.
+// A delta that already has a sequence must get that one, or
+// workflows.ErrDeltaConflict is returned and nothing is stored, so a writer
+// can make sure nothing was written since it read the log.
+func (s *Storage) Store(ctx context.Context, delta workflows.Delta) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		return insertDeltas(ctx, tx, delta.BlobID, []workflows.Delta{delta}, s.keys)
+	})
+}
+
+// GetByBlobID returns a blob's delta log in sequence order
+func (s *Storage) GetByBlobID(ctx context.Context, blobID string) ([]workflows.Delta, error) {
+	return queryDeltas(ctx, s.db, blobID, `
+		SELECT `+deltaColumns+`
+		FROM deltas WHERE blob_id = $1 ORDER BY sequence`, blobID)
+}
+
+// querier runs queries, in a transaction or not
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// queryDeltas reads the deltas of a blob a query selects
+func queryDeltas(ctx context.Context, q querier, blobID, query string, args ...interface{}) ([]workflows.Delta, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query deltas of %s: %w", blobID, err)
+	}
+	defer rows.Close()
+
+	deltas := []workflows.Delta{}
+	for rows.Next() {
+		var delta workflows.Delta
+		var oldValue, newValue, metadata []byte
+		var hash, prevHash, signature, keyID sql.NullString
+		if err := rows.Scan(&delta.ID, &delta.BlobID, &delta.Sequence, &delta.ProviderID, &delta.Type, &delta.Path,
+			&oldValue, &newValue, &metadata, &delta.Timestamp, &hash, &prevHash, &signature, &keyID); err != nil {
+			return nil, fmt.Errorf("failed to read delta of %s: %w", blobID, err)
+		}
+		delta.Hash, delta.PrevHash, delta.Signature, delta.KeyID = hash.String, prevHash.String, signature.String, keyID.String
+		if err := decode(oldValue, &delta.OldValue); err != nil {
+			return nil, fmt.Errorf("failed to decode delta %s: %w", delta.ID, err)
+		}
+		if err := decode(newValue, &delta.NewValue); err != nil {
+			return nil, fmt.Errorf("failed to decode delta %s: %w", delta.ID, err)
+		}
+		if err := decode(metadata, &delta.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to decode delta %s: %w", delta.ID, err)
+		}
+		deltas = append(deltas, delta)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read deltas of %s: %w", blobID, err)
+	}
+	return deltas, nil
+}
+
+// ApplyDeltas writes each delta's new value into the blob's state by path,
+// removing the paths of delete deltas, in one transaction. The text of a
+// text_crdt delta's path is merged again from the blob's log, and a
+// json_patch or merge_patch delta patches the value its path has in the
+// log, so those deltas must be stored first. A patch that does not apply
+// fails the whole transaction.
+func (s *Storage) ApplyDeltas(ctx context.Context, blobID string, deltas []workflows.Delta) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		return applyDeltas(ctx, tx, blobID, deltas)
+	})
+}
+
+// State returns a blob's state as the deltas applied to it left it, by path
+func (s *Storage) State(ctx context.Context, blobID string) (map[string]interface{}, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT path, value FROM blob_state WHERE blob_id = $1`, blobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query state of %s: %w", blobID, err)
+	}
+	defer rows.Close()
+
+	state := make(map[string]interface{})
+	for rows.Next() {
+		var path string
+		var data []byte
+		if err := rows.Scan(&path, &data); err != nil {
+			return nil, fmt.Errorf("failed to read state of %s: %w", blobID, err)
+		}
+		var value interface{}
+		if err := decode(data, &value); err != nil {
+			return nil, fmt.Errorf("failed to decode %s of %s: %w", path, blobID, err)
+		}
+		state[path] = value
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read state of %s: %w", blobID, err)
+	}
+	return state, nil
+}
+
+// SampleBlobs picks up to n blobs with deltas at random
+func (s *Storage) SampleBlobs(ctx context.Context, n int) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT blob_id FROM delta_sequences ORDER BY random() LIMIT $1`, n)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sample blobs: %w", err)
+	}
+	defer rows.Close()
+
+	var blobIDs []string
+	for rows.Next() {
+		var blobID string
+		if err := rows.Scan(&blobID); err != nil {
+			return nil, fmt.Errorf("failed to read sampled blob: %w", err)
+		}
+		blobIDs = append(blobIDs, blobID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read sampled blobs: %w", err)
+	}
+	return blobIDs, nil
+}
+
+// CommitBatch stores a batch's deltas under consecutive sequences and
+// applies them in one transaction, so a delta that cannot be stored or
+// applied leaves the blob as it was
+func (s *Storage) CommitBatch(ctx context.Context, batch *workflows.DeltaBatch) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := insertDeltas(ctx, tx, batch.BlobID, batch.Deltas, s.keys); err != nil {
+			return err
+		}
+		return applyDeltas(ctx, tx, batch.BlobID, batch.Deltas)
+	})
+}
+
+// CommitDeltas stores and applies a blob's deltas, under consecutive
+// sequences, and adds their events to the outbox in one transaction
+func (s *Storage) CommitDeltas(ctx context.Context, blobID string, deltas []workflows.Delta, events []workflows.Event) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := insertDeltas(ctx, tx, blobID, deltas, s.keys); err != nil {
+			return err
+		}
+		if err := applyDeltas(ctx, tx, blobID, deltas); err != nil {
+			return err
+		}
+		for _, event := range events {
+			data, err := json.Marshal(event)
+			if err != nil {
+				return fmt.Errorf("failed to marshal %s event: %w", event.Type, err)
+			}
+			if _, err := tx.ExecContext(ctx, `INSERT INTO delta_outbox (id, event) VALUES ($1, $2)`, event.ID, string(data)); err != nil {
+				return fmt.Errorf("failed to add event %s to the outbox: %w", event.ID, err)
+			}
+		}
+		return nil
+	})
+}
+
+// PendingEvents returns up to limit events not yet dispatched, oldest
+// first
+func (s *Storage) PendingEvents(ctx context.Context, limit int) ([]workflows.Event, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT event FROM delta_outbox ORDER BY position LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query the outbox: %w", err)
+	}
+	defer rows.Close()
+
+	var events []workflows.Event
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to read the outbox: %w", err)
+		}
+		var event workflows.Event
+		if err := json.Unmarshal(data, &event); err != nil {
+			return nil, fmt.Errorf("failed to decode outbox event: %w", err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read the outbox: %w", err)
+	}
+	return events, nil
+}
+
+// MarkDispatched removes events from the outbox by ID
+func (s *Storage) MarkDispatched(ctx context.Context, ids []string) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		for _, id := range ids {
+			if _, err := tx.ExecContext(ctx, `DELETE FROM delta_outbox WHERE id = $1`, id); err != nil {
+				return fmt.Errorf("failed to remove event %s from the outbox: %w", id, err)
+			}
+		}
+		return nil
+	})
+}
+
+// inTx runs fn in a transaction, committing it if fn succeeds
+func (s *Storage) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// insertDeltas takes the next len(deltas) sequences of a blob and stores
+// the deltas under them, in order, chaining each to the one before and
+// signing it with keys
+func insertDeltas(ctx context.Context, tx *sql.Tx, blobID string, deltas []workflows.Delta, keys *workflows.DeltaKeyring) error {
+	if len(deltas) == 0 {
+		return nil
+	}
+	var last int64
+	var lastHash sql.NullString
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO delta_sequences (blob_id, last_sequence) VALUES ($1, $2)
+		ON CONFLICT (blob_id) DO UPDATE SET last_sequence = delta_sequences.last_sequence + EXCLUDED.last_sequence
+		RETURNING last_sequence, last_hash`, blobID, len(deltas)).Scan(&last, &lastHash)
+	if err != nil {
+		return fmt.Errorf("failed to take sequences of %s: %w", blobID, err)
+	}
+
+	first := last - int64(len(deltas)) + 1
+	for i, delta := range deltas {
+		sequence := first + int64(i)
+		if delta.BlobID != "" && delta.BlobID != blobID {
+			return fmt.Errorf("delta %s is of blob %s, not %s", delta.ID, delta.BlobID, blobID)
+		}
+		if delta.Sequence != 0 && delta.Sequence != sequence {
+			return fmt.Errorf("%w: delta %s expects sequence %d of blob %s, which is at %d", workflows.ErrDeltaConflict, delta.ID, delta.Sequence, blobID, sequence)
+		}
+		if delta.ID == "" {
+			delta.ID = uuid.New().String()
+		}
+		if delta.Timestamp.IsZero() {
+			delta.Timestamp = time.Now()
+		}
+		// Postgres keeps microseconds, and the hash must match what it keeps
+		delta.Timestamp = delta.Timestamp.Truncate(time.Microsecond)
+		delta.BlobID, delta.Sequence = blobID, sequence
+		if err := workflows.ChainDelta(&delta, lastHash.String, keys); err != nil {
+			return err
+		}
+		lastHash.String = delta.Hash
+		oldValue, err := encode(delta.OldValue)
+		if err != nil {
+			return fmt.Errorf("failed to encode delta %s: %w", delta.ID, err)
+		}
+		newValue, err := encode(delta.NewValue)
+		if err != nil {
+			return fmt.Errorf("failed to encode delta %s: %w", delta.ID, err)
+		}
+		metadata, err := encode(delta.Metadata)
+		if err != nil {
+			return fmt.Errorf("failed to encode delta %s: %w", delta.ID, err)
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO deltas (id, blob_id, sequence, provider_id, type, path, old_value, new_value, metadata, created_at,
+				hash, prev_hash, signature, key_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NULLIF($12, ''), NULLIF($13, ''), NULLIF($14, ''))`,
+			delta.ID, blobID, sequence, delta.ProviderID, delta.Type, delta.Path, oldValue, newValue, metadata, delta.Timestamp,
+			delta.Hash, delta.PrevHash, delta.Signature, delta.KeyID); err != nil {
+			return fmt.Errorf("failed to store delta %s: %w", delta.ID, err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE delta_sequences SET last_hash = $2 WHERE blob_id = $1`, blobID, lastHash.String); err != nil {
+		return fmt.Errorf("failed to chain deltas of %s: %w", blobID, err)
+	}
+	return nil
+}
+
+// applyDeltas writes deltas into a blob's state
+func applyDeltas(ctx context.Context, tx *sql.Tx, blobID string, deltas []workflows.Delta) error {
+	for _, delta := range deltas {
+		if delta.Type == "delete" {
+			if _, err := tx.ExecContext(ctx, `DELETE FROM blob_state WHERE blob_id = $1 AND path = $2`, blobID, delta.Path); err != nil {
+				return fmt.Errorf("failed to apply delta %s: %w", delta.ID, err)
+			}
+			continue
+		}
+		newValue := delta.NewValue
+		if delta.Type == workflows.DeltaTextCRDT {
+			// Concurrent edits merge from every delta of the path
+			history, err := queryDeltas(ctx, tx, blobID, `
+				SELECT `+deltaColumns+`
+				FROM deltas WHERE blob_id = $1 AND path = $2 ORDER BY sequence`, blobID, delta.Path)
+			if err != nil {
+				return err
+			}
+			newValue = workflows.ReplayText(history, delta.Path)
+		}
+		if delta.Type == workflows.DeltaJSONPatch || delta.Type == workflows.DeltaMergePatch {
+			history, err := queryDeltas(ctx, tx, blobID, `
+				SELECT `+deltaColumns+`
+				FROM deltas WHERE blob_id = $1 ORDER BY sequence`, blobID)
+			if err != nil {
+				return err
+			}
+			if newValue, err = workflows.ApplyPatchDelta(history, delta); err != nil {
+				return fmt.Errorf("failed to apply delta %s: %w", delta.ID, err)
+			}
+		}
+		value, err := encode(newValue)
+		if err != nil {
+			return fmt.Errorf("failed to encode delta %s: %w", delta.ID, err)
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO blob_state (blob_id, path, value) VALUES ($1, $2, $3)
+			ON CONFLICT (blob_id, path) DO UPDATE SET value = EXCLUDED.value`, blobID, delta.Path, value); err != nil {
+			return fmt.Errorf("failed to apply delta %s: %w", delta.ID, err)
+		}
+	}
+	return nil
+}
+
+// encode returns a value as a JSONB parameter, NULL when it is nil
+func encode(value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// decode reads a JSONB column into v, leaving v alone when it is NULL
+func decode(data []byte, v interface{}) error {
+	if data == nil {
+		return nil
+	}
+	return json.Unmarshal(data, v)
+}
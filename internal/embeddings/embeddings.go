@@ -0,0 +1,180 @@
+// Package embeddings turns text into vectors and indexes them for nearest
+// neighbour search
+package embeddings
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Embedder converts texts into vectors. Vectors are L2-normalized, so their
+// dot product is their cosine similarity.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// OpenAIEmbedder uses the OpenAI embeddings API
+type OpenAIEmbedder struct {
+	apiKey     string
+	baseURL    string
+	model      string
+	batchSize  int
+	httpClient *http.Client
+}
+
+// NewOpenAIEmbedder creates an OpenAI embedder
+func NewOpenAIEmbedder(apiKey, model string) *OpenAIEmbedder {
+	if model == "" {
+		model = "text-embedding-3-small"
+	}
+	return &OpenAIEmbedder{
+		apiKey:    apiKey,
+		baseURL:   "https://api.openai.com/v1",
+		model:     model,
+		batchSize: 256,
+		httpClient: &http.Client{
+			Timeout: 60 * time.Second,
+		},
+	}
+}
+
+// Embed embeds texts in batches
+func (e *OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += e.batchSize {
+		end := start + e.batchSize
+		if end > len(texts) {
+			end = len(texts)
+		}
+		batch, err := e.embedBatch(ctx, texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
+
+// embedBatch embeds texts in a single request
+func (e *OpenAIEmbedder) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"model": e.model,
+		"input": texts,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", e.baseURL+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+e.apiKey)
+
+	resp, err := e.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("embedding failed (status %d): %s", resp.StatusCode, detail)
+	}
+
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(result.Data) != len(texts) {
+		return nil, fmt.Errorf("embedding returned %d vectors for %d texts", len(result.Data), len(texts))
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, item := range result.Data {
+		if item.Index < 0 || item.Index >= len(texts) {
+			return nil, fmt.Errorf("embedding returned out of range index %d", item.Index)
+		}
+		vectors[item.Index] = normalize(item.Embedding)
+	}
+	return vectors, nil
+}
+
+// HashEmbedder embeds texts locally by hashing words and word pairs into a
+// fixed number of dimensions. It captures shared wording rather than
+// meaning, which is enough to find copied or lightly edited passages
+// without an API.
+type HashEmbedder struct {
+	Dimensions int
+}
+
+// NewHashEmbedder creates a hashing embedder; 0 dimensions means 1024
+func NewHashEmbedder(dimensions int) *HashEmbedder {
+	if dimensions <= 0 {
+		dimensions = 1024
+	}
+	return &HashEmbedder{Dimensions: dimensions}
+}
+
+// Embed embeds each text
+func (e *HashEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vector := make([]float32, e.Dimensions)
+		words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+		})
+		for j, word := range words {
+			e.add(vector, word)
+			if j > 0 {
+				e.add(vector, words[j-1]+" "+word)
+			}
+		}
+		vectors[i] = normalize(vector)
+	}
+	return vectors, nil
+}
+
+// add hashes a feature into the vector. A second hash bit picks the sign so
+// that collisions tend to cancel out.
+func (e *HashEmbedder) add(vector []float32, feature string) {
+	h := fnv.New64a()
+	h.Write([]byte(feature))
+	sum := h.Sum64()
+	if sum>>63 == 1 {
+		vector[sum%uint64(len(vector))]--
+	} else {
+		vector[sum%uint64(len(vector))]++
+	}
+}
+
+// normalize scales a vector to unit length
+func normalize(vector []float32) []float32 {
+	var sum float64
+	for _, v := range vector {
+		sum += float64(v) * float64(v)
+	}
+	if sum == 0 {
+		return vector
+	}
+	norm := float32(math.Sqrt(sum))
+	for i := range vector {
+		vector[i] /= norm
+	}
+	return vector
+}
@@ -0,0 +1,121 @@
+package embeddings
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// Document is an embedded text. Documents belong to a source (e.g. a blob)
+// within a collection, and a source's documents are replaced together.
+type Document struct {
+	ID       string                 `json:"id"`
+	Source   string                 `json:"source"`
+	Text     string                 `json:"text"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	Vector   []float32              `json:"-"`
+}
+
+// Match is a document found by a search, with its cosine similarity
+type Match struct {
+	Document
+	Score float64 `json:"score"`
+}
+
+// Index stores document vectors by collection
+type Index interface {
+	// Version returns the version recorded for a source's documents, or ""
+	// if the source is not indexed
+	Version(ctx context.Context, collection, source string) (string, error)
+	// Replace swaps a source's documents for new ones at a version
+	Replace(ctx context.Context, collection, source, version string, docs []Document) error
+	// Remove drops a source's documents
+	Remove(ctx context.Context, collection, source string) error
+	// Search returns up to limit documents most similar to vector
+	Search(ctx context.Context, collection string, vector []float32, limit int) ([]Match, error)
+}
+
+// MemoryIndex is an in-process Index with exhaustive search
+type MemoryIndex struct {
+	mu          sync.RWMutex
+	collections map[string]map[string]*source
+}
+
+type source struct {
+	version string
+	docs    []Document
+}
+
+// NewMemoryIndex creates an empty in-memory index
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{collections: make(map[string]map[string]*source)}
+}
+
+// Version returns the version recorded for a source
+func (m *MemoryIndex) Version(ctx context.Context, collection, sourceID string) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if s, ok := m.collections[collection][sourceID]; ok {
+		return s.version, nil
+	}
+	return "", nil
+}
+
+// Replace swaps a source's documents
+func (m *MemoryIndex) Replace(ctx context.Context, collection, sourceID, version string, docs []Document) error {
+	stored := make([]Document, len(docs))
+	for i, doc := range docs {
+		doc.Source = sourceID
+		stored[i] = doc
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sources, ok := m.collections[collection]
+	if !ok {
+		sources = make(map[string]*source)
+		m.collections[collection] = sources
+	}
+	sources[sourceID] = &source{version: version, docs: stored}
+	return nil
+}
+
+// Remove drops a source's documents
+func (m *MemoryIndex) Remove(ctx context.Context, collection, sourceID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.collections[collection], sourceID)
+	return nil
+}
+
+// Search scores every document in the collection against vector
+func (m *MemoryIndex) Search(ctx context.Context, collection string, vector []float32, limit int) ([]Match, error) {
+	m.mu.RLock()
+	var matches []Match
+	for _, s := range m.collections[collection] {
+		for _, doc := range s.docs {
+			matches = append(matches, Match{Document: doc, Score: Cosine(vector, doc.Vector)})
+		}
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
+
+// Cosine returns the cosine similarity of two normalized vectors
+func Cosine(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+	}
+	return dot
+}
@@ -0,0 +1,280 @@
+// Package errorcodes is the catalog of the stable codes API and execution
+// errors carry, with a message template for each code in every supported
+// locale, for clients to show users in place of the technical message
+package errorcodes
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultLocale is the locale messages fall back to
+const DefaultLocale = "en"
+
+// Where a code is reported
+const (
+	ScopeAPI       = "api"       // in API error responses
+	ScopeExecution = "execution" // in the errors of failed executions
+)
+
+// API codes for failures clients are expected to handle
+const (
+	BlobNotFound      = "blob_not_found"
+	WorkflowNotFound  = "workflow_not_found"
+	WorkflowExists    = "workflow_exists"
+	WorkflowCycle     = "workflow_cycle"
+	InvalidWorkflow   = "invalid_workflow"
+	LintFailed        = "lint_failed"
+	ProviderNotFound  = "provider_not_found"
+	ProviderInactive  = "provider_inactive"
+	InvalidProvider   = "invalid_provider"
+	ExecutionNotFound = "execution_not_found"
+	DeltaConflict     = "delta_conflict"
+	QuotaExceeded     = "quota_exceeded"
+	BackendError      = "backend_error"
+	BackendTimeout    = "backend_timeout"
+	EndpointSunset    = "endpoint_sunset"
+)
+
+// Generic API codes, the snake_case text of the statuses the API returns
+const (
+	BadRequest          = "bad_request"
+	Unauthorized        = "unauthorized"
+	NotFound            = "not_found"
+	Conflict            = "conflict"
+	Gone                = "gone"
+	TooManyRequests     = "too_many_requests"
+	InternalServerError = "internal_server_error"
+	NotImplemented      = "not_implemented"
+	BadGateway          = "bad_gateway"
+	GatewayTimeout      = "gateway_timeout"
+)
+
+// Execution codes, as the workflow backends report them
+const (
+	WorkflowFailed     = "workflow_failed"
+	WorkflowTimedOut   = "workflow_timed_out"
+	WorkflowCancelled  = "workflow_cancelled"
+	WorkflowTerminated = "workflow_terminated"
+	SchemaViolation    = "schema_violation"
+)
+
+// Entry describes a code. Status is the HTTP status API errors with the
+// code have, and Params the details its messages may fill in.
+type Entry struct {
+	Code   string   `json:"code"`
+	Scope  string   `json:"scope"`
+	Status int      `json:"status,omitempty"`
+	Params []string `json:"params,omitempty"`
+}
+
+// entries is the catalog. Codes are never renamed or reused; retired codes
+// stay so clients that still see them can render them.
+var entries = []Entry{
+	{Code: BlobNotFound, Scope: ScopeAPI, Status: 404},
+	{Code: WorkflowNotFound, Scope: ScopeAPI, Status: 404},
+	{Code: WorkflowExists, Scope: ScopeAPI, Status: 409},
+	{Code: WorkflowCycle, Scope: ScopeAPI, Status: 400},
+	{Code: InvalidWorkflow, Scope: ScopeAPI, Status: 400},
+	{Code: LintFailed, Scope: ScopeAPI, Status: 400},
+	{Code: ProviderNotFound, Scope: ScopeAPI, Status: 404},
+	{Code: ProviderInactive, Scope: ScopeAPI, Status: 409},
+	{Code: InvalidProvider, Scope: ScopeAPI, Status: 400},
+	{Code: ExecutionNotFound, Scope: ScopeAPI, Status: 404},
+	{Code: DeltaConflict, Scope: ScopeAPI, Status: 409},
+	{Code: QuotaExceeded, Scope: ScopeAPI, Status: 429},
+	{Code: BackendError, Scope: ScopeAPI, Status: 502, Params: []string{"backend_status"}},
+	{Code: BackendTimeout, Scope: ScopeAPI, Status: 504},
+	{Code: EndpointSunset, Scope: ScopeAPI, Status: 410},
+
+	{Code: BadRequest, Scope: ScopeAPI, Status: 400},
+	{Code: Unauthorized, Scope: ScopeAPI, Status: 401},
+	{Code: NotFound, Scope: ScopeAPI, Status: 404},
+	{Code: Conflict, Scope: ScopeAPI, Status: 409},
+	{Code: Gone, Scope: ScopeAPI, Status: 410},
+	{Code: TooManyRequests, Scope: ScopeAPI, Status: 429},
+	{Code: InternalServerError, Scope: ScopeAPI, Status: 500},
+	{Code: NotImplemented, Scope: ScopeAPI, Status: 501},
+	{Code: BadGateway, Scope: ScopeAPI, Status: 502},
+	{Code: GatewayTimeout, Scope: ScopeAPI, Status: 504},
+
+	{Code: WorkflowFailed, Scope: ScopeExecution},
+	{Code: WorkflowTimedOut, Scope: ScopeExecution},
+	{Code: WorkflowCancelled, Scope: ScopeExecution},
+	{Code: WorkflowTerminated, Scope: ScopeExecution},
+	{Code: SchemaViolation, Scope: ScopeExecution},
+}
+
+//go:embed locales/*.yaml
+var files embed.FS
+
+// messages holds each locale's templates by code
+var messages map[string]map[string]string
+
+// placeholder matches the {param} placeholders of templates
+var placeholder = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+func init() {
+	loaded, err := loadMessages()
+	if err != nil {
+		panic(err)
+	}
+	messages = loaded
+}
+
+// loadMessages reads the embedded locales, checking that the default locale
+// covers every code and that no template names an unknown code or param
+func loadMessages() (map[string]map[string]string, error) {
+	names, err := fs.Glob(files, "locales/*.yaml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list locales: %w", err)
+	}
+	loaded := make(map[string]map[string]string, len(names))
+	for _, name := range names {
+		data, err := files.ReadFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read locale %s: %w", name, err)
+		}
+		var templates map[string]string
+		if err := yaml.Unmarshal(data, &templates); err != nil {
+			return nil, fmt.Errorf("failed to parse locale %s: %w", name, err)
+		}
+		locale := strings.TrimSuffix(path.Base(name), ".yaml")
+		for code, template := range templates {
+			entry, ok := Lookup(code)
+			if !ok {
+				return nil, fmt.Errorf("locale %s has a message for unknown code %s", locale, code)
+			}
+			for _, match := range placeholder.FindAllStringSubmatch(template, -1) {
+				if !hasParam(entry, match[1]) {
+					return nil, fmt.Errorf("locale %s message for %s uses unknown param %s", locale, code, match[1])
+				}
+			}
+		}
+		loaded[locale] = templates
+	}
+	for _, entry := range entries {
+		if loaded[DefaultLocale][entry.Code] == "" {
+			return nil, fmt.Errorf("locale %s has no message for %s", DefaultLocale, entry.Code)
+		}
+	}
+	return loaded, nil
+}
+
+// Entries returns the catalog
+func Entries() []Entry {
+	return append([]Entry(nil), entries...)
+}
+
+// Lookup returns a code's entry
+func Lookup(code string) (Entry, bool) {
+	for _, entry := range entries {
+		if entry.Code == code {
+			return entry, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Locales returns the supported locales, sorted
+func Locales() []string {
+	locales := make([]string, 0, len(messages))
+	for locale := range messages {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Template returns a code's message template in a locale, or in the
+// default locale when the locale has none, and the locale it is in. Both
+// are empty for codes not in the catalog.
+func Template(code, locale string) (string, string) {
+	if template := messages[locale][code]; template != "" {
+		return template, locale
+	}
+	if template := messages[DefaultLocale][code]; template != "" {
+		return template, DefaultLocale
+	}
+	return "", ""
+}
+
+// Message renders a code's message in a locale, filling its placeholders
+// from params, and returns it with the locale it is in. A placeholder
+// without a param renders as "?".
+func Message(code, locale string, params map[string]interface{}) (string, string) {
+	template, locale := Template(code, locale)
+	message := placeholder.ReplaceAllStringFunc(template, func(match string) string {
+		value, ok := params[match[1:len(match)-1]]
+		if !ok || value == nil {
+			return "?"
+		}
+		return fmt.Sprint(value)
+	})
+	return message, locale
+}
+
+// Negotiate returns the supported locale that best matches an
+// Accept-Language header, by quality and then order, matching a tag
+// exactly or by its primary language. It returns the default locale when
+// none match.
+func Negotiate(acceptLanguage string) string {
+	type preference struct {
+		tag     string
+		quality float64
+	}
+	var prefs []preference
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(part, ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" {
+			continue
+		}
+		quality := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		if quality > 0 {
+			prefs = append(prefs, preference{tag, quality})
+		}
+	}
+	sort.SliceStable(prefs, func(i, j int) bool { return prefs[i].quality > prefs[j].quality })
+
+	for _, pref := range prefs {
+		if pref.tag == "*" {
+			return DefaultLocale
+		}
+		if _, ok := messages[pref.tag]; ok {
+			return pref.tag
+		}
+		if base, _, found := strings.Cut(pref.tag, "-"); found {
+			if _, ok := messages[base]; ok {
+				return base
+			}
+		}
+	}
+	return DefaultLocale
+}
+
+// hasParam reports whether a code's messages may use a param
+func hasParam(entry Entry, name string) bool {
+	for _, param := range entry.Params {
+		if param == name {
+			return true
+		}
+	}
+	return false
+}
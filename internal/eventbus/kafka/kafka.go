@@ -0,0 +1,489 @@
+// Package kafka publishes and consumes studio events on Kafka through the
+// Confluent REST Proxy (API v2)
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// REST Proxy content types: embedded JSON records, and everything else
+const (
+	contentTypeJSON = "application/vnd.kafka.json.v2+json"
+	contentTypeV2   = "application/vnd.kafka.v2+json"
+)
+
+// Defaults for unset Config fields
+const (
+	DefaultGroup        = "memmie-studio"
+	DefaultMaxInFlight  = 16
+	DefaultPollTimeout  = time.Second
+	DefaultMaxPollBytes = 1 << 20
+)
+
+const (
+	// maxAttempts bounds the attempts to publish an event, and to handle
+	// one before it is skipped
+	maxAttempts = 3
+	// retryDelay is the first wait between attempts, doubled after each
+	retryDelay = 200 * time.Millisecond
+	// maxPollDelay caps the wait between failed polls
+	maxPollDelay = 30 * time.Second
+	// retriableRecordError is the REST Proxy's error code for a record
+	// Kafka may accept on another attempt
+	retriableRecordError = 2
+)
+
+// errInstanceGone is returned when the REST Proxy no longer knows a
+// consumer instance, which it drops after it has been idle too long
+var errInstanceGone = errors.New("consumer instance not found")
+
+// Config configures a Kafka event bus
+type Config struct {
+	URL string // REST Proxy root, e.g. http://kafka-rest:8082
+	// Topic receives every event whose type Topics does not route elsewhere
+	Topic  string
+	Topics map[string]string // event type to topic
+	// Group is the consumer group Subscribe joins: subscribers in the same
+	// group share the topics' partitions, and each group sees every event
+	Group string
+	// OffsetReset is where a new group starts reading: latest (the
+	// default) or earliest
+	OffsetReset string
+	// MaxInFlight bounds concurrent publishes; Publish waits for a slot
+	MaxInFlight  int
+	PollTimeout  time.Duration // how long a poll waits for records
+	MaxPollBytes int           // how much one poll may return
+}
+
+// Bus implements workflows.EventBus on Kafka. Events are keyed by blob ID,
+// so the default partitioner keeps each blob's events on one partition
+// and consumers see them in the order they were published.
+type Bus struct {
+	cfg        Config
+	baseURL    string
+	httpClient *http.Client
+	inFlight   chan struct{}
+	onError    func(error)
+}
+
+// NewBus creates a Kafka event bus. onError, if set, is told when a
+// subscription cannot poll or a handler keeps failing on an event.
+func NewBus(cfg Config, onError func(error)) (*Bus, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("kafka event bus requires the REST Proxy URL")
+	}
+	if cfg.Topic == "" {
+		return nil, fmt.Errorf("kafka event bus requires a topic")
+	}
+	switch cfg.OffsetReset {
+	case "":
+		cfg.OffsetReset = "latest"
+	case "latest", "earliest":
+	default:
+		return nil, fmt.Errorf("invalid offset reset %q: use latest or earliest", cfg.OffsetReset)
+	}
+	if cfg.Group == "" {
+		cfg.Group = DefaultGroup
+	}
+	if cfg.MaxInFlight <= 0 {
+		cfg.MaxInFlight = DefaultMaxInFlight
+	}
+	if cfg.PollTimeout <= 0 {
+		cfg.PollTimeout = DefaultPollTimeout
+	}
+	if cfg.MaxPollBytes <= 0 {
+		cfg.MaxPollBytes = DefaultMaxPollBytes
+	}
+	return &Bus{
+		cfg:     cfg,
+		baseURL: strings.TrimRight(cfg.URL, "/"),
+		httpClient: &http.Client{
+			Timeout: cfg.PollTimeout + 30*time.Second,
+		},
+		inFlight: make(chan struct{}, cfg.MaxInFlight),
+		onError:  onError,
+	}, nil
+}
+
+// ParseTopics parses event type to topic routes written as
+// "type=topic,type=topic", such as "delta.applied=studio.deltas"
+func ParseTopics(spec string) (map[string]string, error) {
+	topics := make(map[string]string)
+	for _, route := range strings.Split(spec, ",") {
+		route = strings.TrimSpace(route)
+		if route == "" {
+			continue
+		}
+		eventType, topic, ok := strings.Cut(route, "=")
+		eventType, topic = strings.TrimSpace(eventType), strings.TrimSpace(topic)
+		if !ok || eventType == "" || topic == "" {
+			return nil, fmt.Errorf("invalid topic route %q: use type=topic", route)
+		}
+		topics[eventType] = topic
+	}
+	return topics, nil
+}
+
+// topic returns the topic an event type is published to
+func (b *Bus) topic(eventType string) string {
+	if topic, ok := b.cfg.Topics[eventType]; ok && topic != "" {
+		return topic
+	}
+	return b.cfg.Topic
+}
+
+// topics lists every topic events are published to
+func (b *Bus) topics() []string {
+	topics := []string{b.cfg.Topic}
+	seen := map[string]bool{b.cfg.Topic: true}
+	for _, topic := range b.cfg.Topics {
+		if topic != "" && !seen[topic] {
+			seen[topic] = true
+			topics = append(topics, topic)
+		}
+	}
+	return topics
+}
+
+// filterTopics lists the topics events matching filters are published to:
+// those their types route to, or every topic when they allow any type
+func (b *Bus) filterTopics(filters []workflows.EventFilter) []string {
+	types := workflows.FilterTypes(filters)
+	if types == nil {
+		return b.topics()
+	}
+	var topics []string
+	seen := make(map[string]bool)
+	for _, eventType := range types {
+		if topic := b.topic(eventType); !seen[topic] {
+			seen[topic] = true
+			topics = append(topics, topic)
+		}
+	}
+	return topics
+}
+
+// produceRequest is a REST Proxy produce payload
+type produceRequest struct {
+	Records []produceRecord `json:"records"`
+}
+
+// produceRecord is one record to produce
+type produceRecord struct {
+	Key   *string         `json:"key"`
+	Value workflows.Event `json:"value"`
+}
+
+// produceResponse reports where each record was written, or why not
+type produceResponse struct {
+	Offsets []struct {
+		Partition int    `json:"partition"`
+		Offset    int64  `json:"offset"`
+		ErrorCode *int   `json:"error_code"`
+		Error     string `json:"error"`
+	} `json:"offsets"`
+}
+
+// Publish writes an event to its topic, keyed by its blob ID. The REST
+// Proxy's JSON records have no headers, so the trace ctx carries goes in
+// the event's envelope. It waits
+// while MaxInFlight publishes are under way, so a slow cluster slows
+// publishers instead of queueing events without bound, and retries
+// failures the proxy reports as temporary.
+func (b *Bus) Publish(ctx context.Context, event workflows.Event) error {
+	select {
+	case b.inFlight <- struct{}{}:
+	case <-ctx.Done():
+		return fmt.Errorf("failed to publish %s event: %w", event.Type, ctx.Err())
+	}
+	defer func() { <-b.inFlight }()
+
+	workflows.InjectTrace(ctx, &event)
+	record := produceRecord{Value: event}
+	if event.BlobID != "" {
+		record.Key = &event.BlobID
+	}
+	body, err := json.Marshal(produceRequest{Records: []produceRecord{record}})
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	// Batches go where the events they hold would
+	topic := b.topic(workflows.BatchedType(event))
+	delay := retryDelay
+	for attempt := 1; ; attempt++ {
+		err = b.produce(ctx, topic, body)
+		if err == nil || !workflows.IsRetryable(err) || attempt == maxAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("failed to publish %s event: %w", event.Type, ctx.Err())
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+	if err != nil {
+		return fmt.Errorf("failed to publish %s event to %s: %w", event.Type, topic, err)
+	}
+	return nil
+}
+
+// produce writes one batch of records to a topic
+func (b *Bus) produce(ctx context.Context, topic string, body []byte) error {
+	var resp produceResponse
+	if err := b.do(ctx, http.MethodPost, b.baseURL+"/topics/"+topic, contentTypeJSON, body, &resp); err != nil {
+		return err
+	}
+	for _, offset := range resp.Offsets {
+		if offset.ErrorCode == nil {
+			continue
+		}
+		err := fmt.Errorf("record rejected: %s", offset.Error)
+		if *offset.ErrorCode == retriableRecordError {
+			return workflows.Retryable(err)
+		}
+		return workflows.Permanent(err)
+	}
+	return nil
+}
+
+// consumer is a REST Proxy consumer instance
+type consumer struct {
+	InstanceID string `json:"instance_id"`
+	BaseURI    string `json:"base_uri"`
+}
+
+// consumedRecord is a record a poll returned
+type consumedRecord struct {
+	Topic     string          `json:"topic"`
+	Key       *string         `json:"key"`
+	Value     json.RawMessage `json:"value"`
+	Partition int             `json:"partition"`
+	Offset    int64           `json:"offset"`
+}
+
+// Subscribe joins the configured consumer group and passes it events
+// until ctx is done. Each poll's events are handled in order before the
+// next poll, and their offsets committed once handled, so a slow handler
+// slows consumption rather than buffering events, and a restart resumes
+// after the last committed event. A handler that keeps failing on an event
+// has it skipped, reported to onError.
+//
+// Filters that name event types subscribe the consumer to only the topics
+// those types are routed to, so Kafka never sends it the rest; events of
+// those topics that do not match are committed without reaching handler.
+// Subscribers with different filters belong in different groups, since a
+// group shares its partitions among its members.
+func (b *Bus) Subscribe(ctx context.Context, handler workflows.EventHandler, filters ...workflows.EventFilter) error {
+	topics := b.filterTopics(filters)
+	c, err := b.join(ctx, topics)
+	if err != nil {
+		return err
+	}
+	go b.consume(ctx, c, topics, workflows.FilterHandler(workflows.TraceHandler(handler), filters...))
+	return nil
+}
+
+// join creates a consumer instance in the group and subscribes it to
+// topics
+func (b *Bus) join(ctx context.Context, topics []string) (*consumer, error) {
+	body, err := json.Marshal(map[string]string{
+		"format":             "json",
+		"auto.offset.reset":  b.cfg.OffsetReset,
+		"auto.commit.enable": "false",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal consumer: %w", err)
+	}
+	var c consumer
+	if err := b.do(ctx, http.MethodPost, b.baseURL+"/consumers/"+b.cfg.Group, contentTypeV2, body, &c); err != nil {
+		return nil, fmt.Errorf("failed to create consumer in group %s: %w", b.cfg.Group, err)
+	}
+	c.BaseURI = strings.TrimRight(c.BaseURI, "/")
+
+	body, err = json.Marshal(map[string][]string{"topics": topics})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal subscription: %w", err)
+	}
+	if err := b.do(ctx, http.MethodPost, c.BaseURI+"/subscription", contentTypeV2, body, nil); err != nil {
+		b.leave(&c)
+		return nil, fmt.Errorf("failed to subscribe consumer %s: %w", c.InstanceID, err)
+	}
+	return &c, nil
+}
+
+// leave deletes a consumer instance, handing its partitions to the rest of
+// the group
+func (b *Bus) leave(c *consumer) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := b.do(ctx, http.MethodDelete, c.BaseURI, contentTypeV2, nil, nil); err != nil && !errors.Is(err, errInstanceGone) {
+		b.report(fmt.Errorf("failed to delete consumer %s: %w", c.InstanceID, err))
+	}
+}
+
+// consume polls a consumer until ctx is done, rejoining the group if the
+// proxy dropped the instance
+func (b *Bus) consume(ctx context.Context, c *consumer, topics []string, handler workflows.EventHandler) {
+	delay := retryDelay
+	for {
+		var err error
+		if c == nil {
+			c, err = b.join(ctx, topics)
+		} else if err = b.poll(ctx, c, handler); errors.Is(err, errInstanceGone) {
+			c = nil
+		}
+		if ctx.Err() != nil {
+			if c != nil {
+				b.leave(c)
+			}
+			return
+		}
+		if err == nil {
+			delay = retryDelay
+			continue
+		}
+		b.report(err)
+		select {
+		case <-ctx.Done():
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > maxPollDelay {
+			delay = maxPollDelay
+		}
+	}
+}
+
+// poll fetches a batch of records, handles them and commits their offsets
+func (b *Bus) poll(ctx context.Context, c *consumer, handler workflows.EventHandler) error {
+	url := fmt.Sprintf("%s/records?timeout=%d&max_bytes=%d", c.BaseURI, b.cfg.PollTimeout.Milliseconds(), b.cfg.MaxPollBytes)
+	started := time.Now()
+	var records []consumedRecord
+	if err := b.do(ctx, http.MethodGet, url, "", nil, &records); err != nil {
+		return fmt.Errorf("failed to poll consumer %s: %w", c.InstanceID, err)
+	}
+	if len(records) == 0 {
+		// The proxy may answer before the timeout when there is nothing to
+		// read; waiting it out keeps an idle subscription from spinning
+		select {
+		case <-ctx.Done():
+		case <-time.After(b.cfg.PollTimeout - time.Since(started)):
+		}
+		return nil
+	}
+
+	for _, record := range records {
+		if ctx.Err() != nil {
+			// Nothing is committed, so the group gets the batch again
+			return nil
+		}
+		b.handle(ctx, record, handler)
+	}
+
+	// The proxy commits the position after each offset given
+	type partition struct {
+		topic     string
+		partition int
+	}
+	last := make(map[partition]int64)
+	var order []partition
+	for _, record := range records {
+		p := partition{record.Topic, record.Partition}
+		if _, ok := last[p]; !ok {
+			order = append(order, p)
+		}
+		last[p] = record.Offset
+	}
+	offsets := make([]map[string]interface{}, 0, len(order))
+	for _, p := range order {
+		offsets = append(offsets, map[string]interface{}{"topic": p.topic, "partition": p.partition, "offset": last[p]})
+	}
+	body, err := json.Marshal(map[string]interface{}{"offsets": offsets})
+	if err != nil {
+		return fmt.Errorf("failed to marshal offsets: %w", err)
+	}
+	if err := b.do(ctx, http.MethodPost, c.BaseURI+"/offsets", contentTypeV2, body, nil); err != nil {
+		return fmt.Errorf("failed to commit offsets for consumer %s: %w", c.InstanceID, err)
+	}
+	return nil
+}
+
+// handle passes a record's event to the handler, retrying failures
+func (b *Bus) handle(ctx context.Context, record consumedRecord, handler workflows.EventHandler) {
+	var event workflows.Event
+	if err := json.Unmarshal(record.Value, &event); err != nil {
+		b.report(fmt.Errorf("failed to decode event at %s/%d/%d: %w", record.Topic, record.Partition, record.Offset, err))
+		return
+	}
+	delay := retryDelay
+	for attempt := 1; ; attempt++ {
+		err := handler(ctx, event)
+		if err == nil {
+			return
+		}
+		if attempt == maxAttempts || ctx.Err() != nil {
+			b.report(fmt.Errorf("failed to handle %s event %s after %d attempts: %w", event.Type, event.ID, attempt, err))
+			return
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// report passes an error to onError, if set
+func (b *Bus) report(err error) {
+	if b.onError != nil && !errors.Is(err, context.Canceled) {
+		b.onError(err)
+	}
+}
+
+// do sends a REST Proxy request and decodes the JSON response into out
+func (b *Bus) do(ctx context.Context, method, url, contentType string, body []byte, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", contentTypeJSON+", "+contentTypeV2)
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		if resp.StatusCode == http.StatusNotFound && strings.Contains(url, "/consumers/") {
+			return fmt.Errorf("%w: %s", errInstanceGone, strings.TrimSpace(string(msg)))
+		}
+		return fmt.Errorf("unexpected status code: %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+// Package export assembles a book's chapters into EPUB, PDF, and DOCX files
+// laid out by a template, running each export as an asynchronous job
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Block kinds
+const (
+	BlockParagraph  = "paragraph"
+	BlockHeading    = "heading"
+	BlockSceneBreak = "scene_break"
+)
+
+// Document is a book ready to render
+type Document struct {
+	ID         string
+	Title      string
+	Author     string
+	Language   string
+	Dedication string
+	Copyright  string
+	Chapters   []Chapter
+	Modified   time.Time
+}
+
+// Chapter is one chapter of a document
+type Chapter struct {
+	Number int
+	Title  string
+	Blocks []Block
+}
+
+// Block is a paragraph, subheading, or scene break
+type Block struct {
+	Kind string
+	Text string
+}
+
+// Text returns the document's words as plain text, one block per line
+func (d *Document) Text() string {
+	var b strings.Builder
+	for _, part := range []string{d.Title, d.Dedication, d.Copyright} {
+		if part != "" {
+			b.WriteString(part + "\n")
+		}
+	}
+	for _, chapter := range d.Chapters {
+		b.WriteString("\n" + chapter.Title + "\n")
+		for _, block := range chapter.Blocks {
+			if block.Text != "" {
+				b.WriteString(block.Text + "\n")
+			}
+		}
+	}
+	return b.String()
+}
+
+// Heading returns the chapter's heading under a template's chapter label
+func (c Chapter) Heading(t Template) string {
+	label := t.chapterLabel(c.Number)
+	switch {
+	case label == "":
+		return c.Title
+	case c.Title == "":
+		return label
+	}
+	return label + ": " + c.Title
+}
+
+// Words counts the words in the chapter's paragraphs
+func (c Chapter) Words() int {
+	words := 0
+	for _, b := range c.Blocks {
+		words += len(strings.Fields(b.Text))
+	}
+	return words
+}
+
+// ParseBlocks splits chapter text into blocks. Each non-empty line is a
+// paragraph, so both blank-line and single-newline paragraph conventions
+// work. Lines starting with "# " are subheadings, and lines made only of
+// *, # or - characters (e.g. "* * *") are scene breaks.
+func ParseBlocks(content string) []Block {
+	var blocks []Block
+	for _, line := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+			continue
+		case isSceneBreak(line):
+			blocks = append(blocks, Block{Kind: BlockSceneBreak})
+		case strings.HasPrefix(line, "#"):
+			heading := strings.TrimSpace(strings.TrimLeft(line, "#"))
+			if heading != "" {
+				blocks = append(blocks, Block{Kind: BlockHeading, Text: heading})
+			}
+		default:
+			blocks = append(blocks, Block{Kind: BlockParagraph, Text: line})
+		}
+	}
+	return blocks
+}
+
+// isSceneBreak reports whether a line is a scene break marker
+func isSceneBreak(line string) bool {
+	marks := 0
+	for _, r := range line {
+		switch r {
+		case '*', '#', '-':
+			marks++
+		case ' ':
+		default:
+			return false
+		}
+	}
+	return marks > 0
+}
+
+// zipFile is an entry of a zip-based format
+type zipFile struct {
+	name  string
+	data  string
+	store bool // written uncompressed
+}
+
+// writeZip packages files in the given order
+func writeZip(files []zipFile, modified time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		header := &zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: modified}
+		if f.store {
+			header.Method = zip.Store
+		}
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add %s: %w", f.name, err)
+		}
+		if _, err := w.Write([]byte(f.data)); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", f.name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish archive: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,165 @@
+package export
+
+import (
+	"fmt"
+	"strings"
+)
+
+// docxFonts maps font families to fonts Word ships with
+var docxFonts = map[string]string{
+	FontSerif: "Times New Roman",
+	FontSans:  "Arial",
+	FontMono:  "Courier New",
+}
+
+// renderDOCX writes a Word document. Chapters use the Heading 1 style, so
+// Word's navigation pane and the contents field pick them up. Page breaks
+// come from the styles rather than break runs, which would leave blank pages
+// before headings that already start a page.
+func renderDOCX(doc *Document, t Template) ([]byte, error) {
+	var body strings.Builder
+	for _, section := range t.FrontMatter {
+		switch section {
+		case FrontTitle:
+			body.WriteString(docxParagraph("Title", doc.Title))
+			if doc.Author != "" {
+				body.WriteString(docxParagraph("Subtitle", doc.Author))
+			}
+		case FrontCopyright:
+			body.WriteString(docxParagraph("FrontMatter", copyrightText(doc)))
+		case FrontDedication:
+			if doc.Dedication != "" {
+				body.WriteString(docxParagraph("Dedication", doc.Dedication))
+			}
+		case FrontContents:
+			body.WriteString(docxParagraph("TOCHeading", "Contents"))
+			// Word fills the field when the document is opened or updated
+			body.WriteString(`<w:p><w:r><w:fldChar w:fldCharType="begin" w:dirty="true"/></w:r>` +
+				`<w:r><w:instrText xml:space="preserve"> TOC \o "1-1" \h \z </w:instrText></w:r>` +
+				`<w:r><w:fldChar w:fldCharType="separate"/></w:r>` +
+				`<w:r><w:t>Update this field to list the chapters.</w:t></w:r>` +
+				`<w:r><w:fldChar w:fldCharType="end"/></w:r></w:p>`)
+		}
+	}
+
+	for _, chapter := range doc.Chapters {
+		body.WriteString(docxParagraph("Heading1", chapter.Heading(t)))
+		for _, block := range chapter.Blocks {
+			switch block.Kind {
+			case BlockHeading:
+				body.WriteString(docxParagraph("Heading2", block.Text))
+			case BlockSceneBreak:
+				body.WriteString(docxParagraph("SceneBreak", "* * *"))
+			default:
+				body.WriteString(docxParagraph("BodyText", block.Text))
+			}
+		}
+	}
+
+	margin := int(t.Margin * 1440)
+	document := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<w:body>%s<w:sectPr><w:pgSz w:w="12240" w:h="15840"/><w:pgMar w:top="%d" w:right="%d" w:bottom="%d" w:left="%d" w:header="720" w:footer="720" w:gutter="0"/></w:sectPr></w:body>
+</w:document>
+`, body.String(), margin, margin, margin, margin)
+
+	return writeZip([]zipFile{
+		{name: "[Content_Types].xml", data: docxContentTypes},
+		{name: "_rels/.rels", data: docxRels},
+		{name: "word/_rels/document.xml.rels", data: docxDocumentRels},
+		{name: "word/document.xml", data: document},
+		{name: "word/styles.xml", data: docxStyles(t)},
+		{name: "word/settings.xml", data: docxSettings},
+		{name: "docProps/core.xml", data: docxCore(doc)},
+	}, doc.Modified)
+}
+
+// docxParagraph writes a paragraph in a style
+func docxParagraph(style, text string) string {
+	return fmt.Sprintf(`<w:p><w:pPr><w:pStyle w:val="%s"/></w:pPr><w:r><w:t xml:space="preserve">%s</w:t></w:r></w:p>`, style, esc(text))
+}
+
+// docxStyles writes the style sheet for a template. Sizes are in half
+// points, spacing in twentieths of a point, and line spacing in 240ths of a
+// line.
+func docxStyles(t Template) string {
+	font := docxFonts[t.FontFamily]
+	size := int(t.FontSize * 2)
+	line := int(t.LineHeight * 240)
+	body := fmt.Sprintf(`<w:spacing w:before="0" w:after="160" w:line="%d" w:lineRule="auto"/>`, line)
+	if t.Indent {
+		body = fmt.Sprintf(`<w:spacing w:before="0" w:after="0" w:line="%d" w:lineRule="auto"/><w:ind w:firstLine="432"/>`, line)
+	}
+
+	style := func(id, name, pPr, rPr string) string {
+		return fmt.Sprintf(`<w:style w:type="paragraph" w:styleId="%s"><w:name w:val="%s"/><w:basedOn w:val="Normal"/><w:qFormat/><w:pPr>%s</w:pPr><w:rPr>%s</w:rPr></w:style>`,
+			id, name, pPr, rPr)
+	}
+
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:styles xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+<w:docDefaults><w:rPrDefault><w:rPr><w:rFonts w:ascii="%[1]s" w:hAnsi="%[1]s" w:cs="%[1]s"/><w:sz w:val="%[2]d"/><w:szCs w:val="%[2]d"/></w:rPr></w:rPrDefault></w:docDefaults>
+<w:style w:type="paragraph" w:default="1" w:styleId="Normal"><w:name w:val="Normal"/><w:qFormat/></w:style>
+%[3]s
+%[4]s
+%[5]s
+%[6]s
+%[7]s
+%[8]s
+%[9]s
+%[10]s
+%[11]s
+</w:styles>
+`, font, size,
+		style("BodyText", "Body Text", body, ""),
+		style("Title", "Title", `<w:spacing w:before="2880" w:after="240"/><w:jc w:val="center"/>`, fmt.Sprintf(`<w:b/><w:sz w:val="%d"/>`, size*3)),
+		style("Subtitle", "Subtitle", `<w:jc w:val="center"/>`, fmt.Sprintf(`<w:sz w:val="%d"/>`, size*3/2)),
+		style("Heading1", "heading 1", `<w:keepNext/><w:pageBreakBefore/><w:spacing w:before="1440" w:after="480"/><w:jc w:val="center"/><w:outlineLvl w:val="0"/>`, fmt.Sprintf(`<w:b/><w:sz w:val="%d"/>`, size*8/5)),
+		style("Heading2", "heading 2", `<w:keepNext/><w:spacing w:before="360" w:after="160"/><w:outlineLvl w:val="1"/>`, fmt.Sprintf(`<w:b/><w:sz w:val="%d"/>`, size*6/5)),
+		style("SceneBreak", "Scene Break", `<w:spacing w:before="240" w:after="240"/><w:jc w:val="center"/>`, ""),
+		style("FrontMatter", "Front Matter", `<w:pageBreakBefore/><w:spacing w:before="5760"/><w:jc w:val="center"/>`, fmt.Sprintf(`<w:sz w:val="%d"/>`, size*4/5)),
+		style("Dedication", "Dedication", `<w:pageBreakBefore/><w:spacing w:before="4320"/><w:jc w:val="center"/>`, "<w:i/>"),
+		style("TOCHeading", "TOC Heading", `<w:pageBreakBefore/><w:spacing w:before="720" w:after="480"/><w:jc w:val="center"/>`, fmt.Sprintf(`<w:b/><w:sz w:val="%d"/>`, size*8/5)),
+	)
+}
+
+// docxCore writes the document properties
+func docxCore(doc *Document) string {
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:dcterms="http://purl.org/dc/terms/" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+<dc:title>%s</dc:title>
+<dc:creator>%s</dc:creator>
+<dcterms:modified xsi:type="dcterms:W3CDTF">%s</dcterms:modified>
+</cp:coreProperties>
+`, esc(doc.Title), esc(doc.Author), doc.Modified.UTC().Format("2006-01-02T15:04:05Z"))
+}
+
+const docxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>
+<Override PartName="/word/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.styles+xml"/>
+<Override PartName="/word/settings.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.settings+xml"/>
+<Override PartName="/docProps/core.xml" ContentType="application/vnd.openxmlformats-package.core-properties+xml"/>
+</Types>
+`
+
+const docxRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/package/2006/relationships/metadata/core-properties" Target="docProps/core.xml"/>
+</Relationships>
+`
+
+const docxDocumentRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/settings" Target="settings.xml"/>
+</Relationships>
+`
+
+// docxSettings asks Word to refresh fields such as the contents on open
+const docxSettings = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:settings xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:updateFields w:val="true"/></w:settings>
+`
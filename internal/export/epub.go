@@ -0,0 +1,209 @@
+package export
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// epubFonts maps font families to CSS font stacks
+var epubFonts = map[string]string{
+	FontSerif: `Georgia, "Times New Roman", serif`,
+	FontSans:  `"Helvetica Neue", Arial, sans-serif`,
+	FontMono:  `"Courier New", Courier, monospace`,
+}
+
+// epubItem is a content document in the package
+type epubItem struct {
+	id    string
+	file  string
+	title string // listed in the navigation when set
+	body  string
+}
+
+// renderEPUB writes an EPUB 3 package with an EPUB 2 NCX for older readers
+func renderEPUB(doc *Document, t Template) ([]byte, error) {
+	var items []epubItem
+	for _, section := range t.FrontMatter {
+		switch section {
+		case FrontTitle:
+			body := fmt.Sprintf(`<section class="title-page" epub:type="titlepage"><h1 class="book-title">%s</h1>`, esc(doc.Title))
+			if doc.Author != "" {
+				body += fmt.Sprintf(`<p class="author">%s</p>`, esc(doc.Author))
+			}
+			items = append(items, epubItem{id: "title", file: "title.xhtml", body: body + "</section>"})
+		case FrontCopyright:
+			items = append(items, epubItem{id: "copyright", file: "copyright.xhtml",
+				body: fmt.Sprintf(`<section class="copyright" epub:type="copyright-page"><p>%s</p></section>`, esc(copyrightText(doc)))})
+		case FrontDedication:
+			if doc.Dedication != "" {
+				items = append(items, epubItem{id: "dedication", file: "dedication.xhtml",
+					body: fmt.Sprintf(`<section class="dedication" epub:type="dedication"><p>%s</p></section>`, esc(doc.Dedication))})
+			}
+		}
+	}
+
+	for i, chapter := range doc.Chapters {
+		var body strings.Builder
+		heading := chapter.Heading(t)
+		fmt.Fprintf(&body, `<section class="chapter" epub:type="chapter"><h1>%s</h1>`, esc(heading))
+		for _, block := range chapter.Blocks {
+			switch block.Kind {
+			case BlockHeading:
+				fmt.Fprintf(&body, "<h2>%s</h2>", esc(block.Text))
+			case BlockSceneBreak:
+				body.WriteString(`<p class="scene-break">* * *</p>`)
+			default:
+				fmt.Fprintf(&body, "<p>%s</p>", esc(block.Text))
+			}
+		}
+		body.WriteString("</section>")
+		items = append(items, epubItem{
+			id:    fmt.Sprintf("chapter-%d", i+1),
+			file:  fmt.Sprintf("chapter-%03d.xhtml", i+1),
+			title: heading,
+			body:  body.String(),
+		})
+	}
+
+	identifier := "urn:uuid:" + uuid.NewSHA1(uuid.NameSpaceURL, []byte("memmie-studio:book:"+doc.ID)).String()
+	language := doc.Language
+	if language == "" {
+		language = "en"
+	}
+
+	// The mimetype must be the first entry and stored uncompressed
+	files := []zipFile{
+		{name: "mimetype", data: "application/epub+zip", store: true},
+		{name: "META-INF/container.xml", data: epubContainer},
+		{name: "OEBPS/style.css", data: epubCSS(t)},
+		{name: "OEBPS/content.opf", data: epubPackage(doc, t, items, identifier, language)},
+		{name: "OEBPS/nav.xhtml", data: epubNav(items, language)},
+		{name: "OEBPS/toc.ncx", data: epubNCX(doc, items, identifier)},
+	}
+	for _, item := range items {
+		title := item.title
+		if title == "" {
+			title = doc.Title
+		}
+		files = append(files, zipFile{name: "OEBPS/" + item.file, data: xhtmlPage(title, language, item.body)})
+	}
+	return writeZip(files, doc.Modified)
+}
+
+const epubContainer = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+
+// epubPackage writes the package document: metadata, manifest and spine
+func epubPackage(doc *Document, t Template, items []epubItem, identifier, language string) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+`)
+	fmt.Fprintf(&b, "    <dc:identifier id=\"book-id\">%s</dc:identifier>\n", esc(identifier))
+	fmt.Fprintf(&b, "    <dc:title>%s</dc:title>\n", esc(doc.Title))
+	if doc.Author != "" {
+		fmt.Fprintf(&b, "    <dc:creator>%s</dc:creator>\n", esc(doc.Author))
+	}
+	fmt.Fprintf(&b, "    <dc:language>%s</dc:language>\n", esc(language))
+	fmt.Fprintf(&b, "    <meta property=\"dcterms:modified\">%s</meta>\n", doc.Modified.UTC().Format("2006-01-02T15:04:05Z"))
+	b.WriteString(`  </metadata>
+  <manifest>
+    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+    <item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml"/>
+    <item id="css" href="style.css" media-type="text/css"/>
+`)
+	for _, item := range items {
+		fmt.Fprintf(&b, "    <item id=\"%s\" href=\"%s\" media-type=\"application/xhtml+xml\"/>\n", item.id, item.file)
+	}
+	b.WriteString("  </manifest>\n  <spine toc=\"ncx\">\n")
+	for _, item := range items {
+		if item.id == "chapter-1" && t.has(FrontContents) {
+			b.WriteString("    <itemref idref=\"nav\"/>\n")
+		}
+		fmt.Fprintf(&b, "    <itemref idref=\"%s\"/>\n", item.id)
+	}
+	b.WriteString("  </spine>\n</package>\n")
+	return b.String()
+}
+
+// epubNav writes the EPUB 3 navigation document, which doubles as the
+// contents page when the template has one
+func epubNav(items []epubItem, language string) string {
+	var b strings.Builder
+	b.WriteString(`<nav epub:type="toc" id="toc"><h1>Contents</h1><ol>`)
+	for _, item := range items {
+		if item.title != "" {
+			fmt.Fprintf(&b, `<li><a href="%s">%s</a></li>`, item.file, esc(item.title))
+		}
+	}
+	b.WriteString("</ol></nav>")
+	return xhtmlPage("Contents", language, b.String())
+}
+
+// epubNCX writes the EPUB 2 table of contents
+func epubNCX(doc *Document, items []epubItem, identifier string) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<ncx xmlns="http://www.daisy.org/z3986/2005/ncx/" version="2005-1">
+  <head>
+`)
+	fmt.Fprintf(&b, "    <meta name=\"dtb:uid\" content=\"%s\"/>\n", esc(identifier))
+	fmt.Fprintf(&b, "  </head>\n  <docTitle><text>%s</text></docTitle>\n  <navMap>\n", esc(doc.Title))
+	order := 0
+	for _, item := range items {
+		if item.title == "" {
+			continue
+		}
+		order++
+		fmt.Fprintf(&b, "    <navPoint id=\"nav-%d\" playOrder=\"%d\"><navLabel><text>%s</text></navLabel><content src=\"%s\"/></navPoint>\n",
+			order, order, esc(item.title), item.file)
+	}
+	b.WriteString("  </navMap>\n</ncx>\n")
+	return b.String()
+}
+
+// epubCSS writes the stylesheet for a template
+func epubCSS(t Template) string {
+	paragraph := "margin: 0 0 0.8em 0;"
+	if t.Indent {
+		paragraph = "margin: 0; text-indent: 1.5em;"
+	}
+	css := fmt.Sprintf(`body { font-family: %s; font-size: %gpt; line-height: %g; }
+p { %s }
+h1 { text-align: center; margin: 2em 0 1.5em 0; page-break-before: always; }
+h2 { margin: 1.5em 0 0.8em 0; }
+.scene-break { text-align: center; text-indent: 0; margin: 1em 0; }
+.title-page { text-align: center; margin-top: 30%%; }
+.book-title { font-size: 2em; page-break-before: auto; }
+.author { text-indent: 0; font-size: 1.2em; }
+.copyright, .dedication { text-align: center; margin-top: 40%%; }
+.copyright p, .dedication p { text-indent: 0; }
+.dedication p { font-style: italic; }
+`, epubFonts[t.FontFamily], t.FontSize, t.LineHeight, paragraph)
+	return css + t.CSS + "\n"
+}
+
+// xhtmlPage wraps a body in an XHTML content document
+func xhtmlPage(title, language, body string) string {
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" xml:lang="%s" lang="%s">
+<head><meta charset="utf-8"/><title>%s</title><link rel="stylesheet" type="text/css" href="style.css"/></head>
+<body>%s</body>
+</html>
+`, esc(language), esc(language), esc(title), body)
+}
+
+// esc escapes text for XML
+func esc(s string) string {
+	return html.EscapeString(s)
+}
@@ -0,0 +1,442 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/memmieai/memmie-studio/internal/artifact"
+	"github.com/memmieai/memmie-studio/internal/books"
+	"github.com/memmieai/memmie-studio/internal/moderation"
+	"github.com/memmieai/memmie-studio/internal/revisions"
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// Job statuses
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+	StatusBlocked   = "blocked" // stopped by a content policy; see the job's moderation
+)
+
+// Progress event types published on the event bus
+const (
+	EventProgress  = "export.progress"
+	EventCompleted = "export.completed"
+	EventFailed    = "export.failed"
+	EventBlocked   = "export.blocked"
+)
+
+// jobRetention is how long finished jobs stay queryable
+const jobRetention = 24 * time.Hour
+
+var (
+	// ErrJobNotFound is returned when an export job does not exist
+	ErrJobNotFound = errors.New("export job not found")
+
+	// ErrInvalidRequest is returned for unknown formats or templates and
+	// pinned versions that cannot be resolved
+	ErrInvalidRequest = errors.New("invalid export request")
+)
+
+// Format is an output format
+type Format struct {
+	Name        string
+	Extension   string
+	ContentType string
+	render      func(doc *Document, t Template) ([]byte, error)
+}
+
+// Formats are the supported output formats
+var Formats = map[string]Format{
+	"epub": {Name: "epub", Extension: ".epub", ContentType: "application/epub+zip", render: renderEPUB},
+	"pdf":  {Name: "pdf", Extension: ".pdf", ContentType: "application/pdf", render: renderPDF},
+	"docx": {Name: "docx", Extension: ".docx", ContentType: "application/vnd.openxmlformats-officedocument.wordprocessingml.document", render: renderDOCX},
+}
+
+// Request describes an export
+type Request struct {
+	Format     string           `json:"format"`
+	Template   string           `json:"template,omitempty"`
+	Versions   map[string]int64 `json:"versions,omitempty"` // chapter ID -> delta sequence to export instead of the current text
+	Chapters   []string         `json:"chapters,omitempty"` // chapter IDs to include; all when empty
+	Dedication string           `json:"dedication,omitempty"`
+	Copyright  string           `json:"copyright,omitempty"`
+	Language   string           `json:"language,omitempty"`
+}
+
+// Job is an export's progress and, once completed, its file
+type Job struct {
+	ID          string             `json:"id"`
+	UserID      string             `json:"user_id"`
+	BookID      string             `json:"book_id"`
+	Format      string             `json:"format"`
+	Template    string             `json:"template"`
+	Status      string             `json:"status"`
+	Stage       string             `json:"stage"`
+	Progress    float64            `json:"progress"` // 0 to 1
+	URL         string             `json:"url,omitempty"`
+	Size        int                `json:"size,omitempty"`
+	Error       string             `json:"error,omitempty"`
+	Moderation  *moderation.Result `json:"moderation,omitempty"`
+	CreatedAt   time.Time          `json:"created_at"`
+	CompletedAt *time.Time         `json:"completed_at,omitempty"`
+}
+
+// Service runs export jobs in the background. Jobs are tracked in memory;
+// the files outlive them in the artifact store.
+type Service struct {
+	books      *books.Service
+	history    revisions.History // optional; needed to export pinned versions
+	artifacts  artifact.Store
+	events     workflows.EventBus  // optional
+	moderation *moderation.Service // optional; exports are published unchecked without it
+	mu         sync.Mutex
+	jobs       map[string]*Job
+}
+
+// NewService creates an export service. history and events may be nil.
+func NewService(bookService *books.Service, history revisions.History, artifacts artifact.Store, events workflows.EventBus) *Service {
+	return &Service{
+		books:     bookService,
+		history:   history,
+		artifacts: artifacts,
+		events:    events,
+		jobs:      make(map[string]*Job),
+	}
+}
+
+// SetModeration checks the text of every export against the content
+// policies before it is rendered
+func (s *Service) SetModeration(service *moderation.Service) {
+	s.moderation = service
+}
+
+// Start validates a request and queues the export. The returned job is a
+// snapshot; poll Get for progress.
+func (s *Service) Start(ctx context.Context, userID, bookID string, req Request) (*Job, error) {
+	req.Format = strings.ToLower(req.Format)
+	if _, ok := Formats[req.Format]; !ok {
+		return nil, fmt.Errorf("%w: unsupported format %q (use epub, pdf or docx)", ErrInvalidRequest, req.Format)
+	}
+	if req.Template == "" {
+		req.Template = DefaultTemplate
+	}
+	if _, ok := Templates[req.Template]; !ok {
+		return nil, fmt.Errorf("%w: unknown template %q (use %s)", ErrInvalidRequest, req.Template, strings.Join(TemplateNames(), ", "))
+	}
+	if len(req.Versions) > 0 && s.history == nil {
+		return nil, fmt.Errorf("%w: exporting pinned versions needs the delta history", ErrInvalidRequest)
+	}
+	// Fail fast on a missing book rather than in the background
+	if _, err := s.books.GetBook(ctx, userID, bookID); err != nil {
+		return nil, err
+	}
+
+	job := &Job{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		BookID:    bookID,
+		Format:    req.Format,
+		Template:  req.Template,
+		Status:    StatusQueued,
+		Stage:     "queued",
+		CreatedAt: time.Now(),
+	}
+	s.mu.Lock()
+	s.prune()
+	s.jobs[job.ID] = job
+	snapshot := *job
+	s.mu.Unlock()
+
+	// The job outlives the request that started it
+	go s.run(context.Background(), job.ID, req)
+	return &snapshot, nil
+}
+
+// Get returns a snapshot of a user's job
+func (s *Service) Get(userID, jobID string) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[jobID]
+	if !ok || job.UserID != userID {
+		return nil, ErrJobNotFound
+	}
+	snapshot := *job
+	return &snapshot, nil
+}
+
+// List returns snapshots of a book's jobs, newest first
+func (s *Service) List(userID, bookID string) []*Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs := []*Job{}
+	for _, job := range s.jobs {
+		if job.UserID == userID && job.BookID == bookID {
+			snapshot := *job
+			jobs = append(jobs, &snapshot)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+	})
+	return jobs
+}
+
+// run performs an export, reporting progress as it goes
+func (s *Service) run(ctx context.Context, jobID string, req Request) {
+	job, _ := s.snapshot(jobID)
+	s.progress(ctx, jobID, StatusRunning, "loading", 0.05)
+
+	doc, err := s.assemble(ctx, job, req, func(done, total int) {
+		s.progress(ctx, jobID, StatusRunning, "loading", 0.05+0.55*float64(done)/float64(total))
+	})
+	if err != nil {
+		s.fail(ctx, jobID, err)
+		return
+	}
+
+	if s.moderation != nil {
+		s.progress(ctx, jobID, StatusRunning, "moderating", 0.6)
+		result, err := s.moderation.Gate(ctx, nil, job.UserID, job.BookID, doc.Text())
+		if errors.Is(err, moderation.ErrBlocked) {
+			s.block(ctx, jobID, result, err)
+			return
+		}
+		if err != nil {
+			s.fail(ctx, jobID, fmt.Errorf("failed to check content: %w", err))
+			return
+		}
+		if result.Decision != moderation.DecisionAllow {
+			s.update(jobID, func(j *Job) { j.Moderation = result })
+		}
+	}
+
+	s.progress(ctx, jobID, StatusRunning, "rendering", 0.65)
+	format := Formats[req.Format]
+	data, err := format.render(doc, Templates[req.Template])
+	if err != nil {
+		s.fail(ctx, jobID, fmt.Errorf("failed to render %s: %w", format.Name, err))
+		return
+	}
+
+	s.progress(ctx, jobID, StatusRunning, "uploading", 0.9)
+	key := artifact.Key(format.Extension, "exports", job.UserID, job.BookID, job.ID)
+	url, err := s.artifacts.Put(ctx, key, format.ContentType, data)
+	if err != nil {
+		s.fail(ctx, jobID, fmt.Errorf("failed to store export: %w", err))
+		return
+	}
+
+	now := time.Now()
+	s.update(jobID, func(j *Job) {
+		j.Status = StatusCompleted
+		j.Stage = "completed"
+		j.Progress = 1
+		j.URL = url
+		j.Size = len(data)
+		j.CompletedAt = &now
+	})
+	s.publish(ctx, jobID, EventCompleted)
+}
+
+// assemble loads the book and its chapters into a document. report is
+// called after each chapter is loaded.
+func (s *Service) assemble(ctx context.Context, job *Job, req Request, report func(done, total int)) (*Document, error) {
+	book, err := s.books.GetBook(ctx, job.UserID, job.BookID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load book: %w", err)
+	}
+	chapters, err := s.books.ListChapters(ctx, job.UserID, job.BookID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chapters: %w", err)
+	}
+
+	if len(req.Chapters) > 0 {
+		include := make(map[string]bool, len(req.Chapters))
+		for _, id := range req.Chapters {
+			include[id] = true
+		}
+		var selected []*books.Chapter
+		for _, chapter := range chapters {
+			if include[chapter.ID] {
+				selected = append(selected, chapter)
+			}
+		}
+		chapters = selected
+	}
+
+	doc := &Document{
+		ID:         book.ID,
+		Title:      book.Title,
+		Author:     book.Author,
+		Language:   req.Language,
+		Dedication: req.Dedication,
+		Copyright:  req.Copyright,
+		Modified:   time.Now(),
+	}
+	for i, chapter := range chapters {
+		content := chapter.Content
+		if sequence, ok := req.Versions[chapter.ID]; ok {
+			content, err = s.versionContent(ctx, chapter.ID, sequence)
+			if err != nil {
+				return nil, err
+			}
+		}
+		number := chapter.Number
+		if number <= 0 {
+			number = i + 1
+		}
+		doc.Chapters = append(doc.Chapters, Chapter{
+			Number: number,
+			Title:  chapter.Title,
+			Blocks: ParseBlocks(content),
+		})
+		report(i+1, len(chapters))
+	}
+	return doc, nil
+}
+
+// versionContent rebuilds a chapter's content at a delta sequence
+func (s *Service) versionContent(ctx context.Context, chapterID string, sequence int64) (string, error) {
+	deltas, err := s.history.GetByBlobID(ctx, chapterID)
+	if err != nil {
+		return "", fmt.Errorf("failed to load history of chapter %s: %w", chapterID, err)
+	}
+	if sequence < 0 || sequence > revisions.Latest(deltas) {
+		return "", fmt.Errorf("%w: chapter %s has no version %d", ErrInvalidRequest, chapterID, sequence)
+	}
+	content, _ := revisions.At(deltas, sequence).State["content"].(string)
+	return content, nil
+}
+
+// progress records a job's stage and publishes a progress event
+func (s *Service) progress(ctx context.Context, jobID, status, stage string, progress float64) {
+	s.update(jobID, func(j *Job) {
+		j.Status = status
+		j.Stage = stage
+		j.Progress = float64(int(progress*100)) / 100
+	})
+	s.publish(ctx, jobID, EventProgress)
+}
+
+// fail marks a job failed
+func (s *Service) fail(ctx context.Context, jobID string, err error) {
+	now := time.Now()
+	s.update(jobID, func(j *Job) {
+		j.Status = StatusFailed
+		j.Stage = "failed"
+		j.Error = err.Error()
+		j.CompletedAt = &now
+	})
+	s.publish(ctx, jobID, EventFailed)
+}
+
+// block marks a job stopped by a content policy
+func (s *Service) block(ctx context.Context, jobID string, result *moderation.Result, err error) {
+	now := time.Now()
+	s.update(jobID, func(j *Job) {
+		j.Status = StatusBlocked
+		j.Stage = "blocked"
+		j.Error = err.Error()
+		j.Moderation = result
+		j.CompletedAt = &now
+	})
+	s.publish(ctx, jobID, EventBlocked)
+}
+
+// Override records a user's reason for exporting a book a policy blocked
+// the export of, so that starting the export again goes through as long as
+// nothing else is blocked
+func (s *Service) Override(ctx context.Context, userID, jobID, reason string) (*moderation.Override, error) {
+	if s.moderation == nil {
+		return nil, fmt.Errorf("%w: content moderation is not enabled", ErrInvalidRequest)
+	}
+	job, err := s.Get(userID, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != StatusBlocked {
+		return nil, fmt.Errorf("%w: export %s was not blocked", moderation.ErrInvalidOverride, jobID)
+	}
+	return s.moderation.Override(ctx, userID, job.BookID, "export", job.Moderation, reason)
+}
+
+// publish sends a job's current state on the event bus. Events are best
+// effort: the job record is the source of truth.
+func (s *Service) publish(ctx context.Context, jobID, eventType string) {
+	if s.events == nil {
+		return
+	}
+	job, ok := s.snapshot(jobID)
+	if !ok {
+		return
+	}
+	data := map[string]interface{}{
+		"job_id":   job.ID,
+		"format":   job.Format,
+		"status":   job.Status,
+		"stage":    job.Stage,
+		"progress": job.Progress,
+	}
+	if job.URL != "" {
+		data["url"] = job.URL
+	}
+	if job.Error != "" {
+		data["error"] = job.Error
+	}
+	if job.Moderation != nil {
+		data["moderation"] = job.Moderation.Summary()
+	}
+	s.events.Publish(ctx, workflows.Event{
+		ID:            uuid.New().String(),
+		Type:          eventType,
+		BlobID:        job.BookID,
+		UserID:        job.UserID,
+		ProviderID:    books.ProviderID,
+		Timestamp:     time.Now(),
+		Data:          data,
+		Source:        "export",
+		CorrelationID: job.ID,
+	})
+}
+
+// update changes a job under the lock
+func (s *Service) update(jobID string, change func(*Job)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if job, ok := s.jobs[jobID]; ok {
+		change(job)
+	}
+}
+
+// snapshot copies a job under the lock
+func (s *Service) snapshot(jobID string) (*Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[jobID]
+	if !ok {
+		return nil, false
+	}
+	snapshot := *job
+	return &snapshot, true
+}
+
+// prune forgets jobs that finished more than jobRetention ago. Callers hold
+// the lock.
+func (s *Service) prune() {
+	cutoff := time.Now().Add(-jobRetention)
+	for id, job := range s.jobs {
+		if job.CompletedAt != nil && job.CompletedAt.Before(cutoff) {
+			delete(s.jobs, id)
+		}
+	}
+}
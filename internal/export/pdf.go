@@ -0,0 +1,369 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// US Letter, in points
+const (
+	pdfPageWidth  = 612.0
+	pdfPageHeight = 792.0
+)
+
+// pdfFont is a pair of standard PDF fonts, which every reader has, so no
+// font data is embedded
+type pdfFont struct {
+	regular string
+	bold    string
+	widths  func(c byte) float64 // glyph advance in 1/1000 em
+}
+
+// pdfFonts maps font families to standard fonts. Times is measured with
+// Helvetica's wider metrics, so lines may break slightly early but never
+// overflow the margin.
+var pdfFonts = map[string]pdfFont{
+	FontSerif: {regular: "Times-Roman", bold: "Times-Bold", widths: helveticaWidth},
+	FontSans:  {regular: "Helvetica", bold: "Helvetica-Bold", widths: helveticaWidth},
+	FontMono:  {regular: "Courier", bold: "Courier-Bold", widths: func(byte) float64 { return 600 }},
+}
+
+// helveticaWidths are Helvetica's advances for ASCII 32-126
+var helveticaWidths = [...]float64{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278, // space-/
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556, // 0-?
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778, // @-O
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556, // P-_
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556, // `-o
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584, // p-~
+}
+
+// helveticaWidth returns a Helvetica advance, using a typical letter width
+// outside ASCII
+func helveticaWidth(c byte) float64 {
+	if c >= 32 && c <= 126 {
+		return helveticaWidths[c-32]
+	}
+	return 556
+}
+
+// pdfPage is one page's content stream
+type pdfPage struct {
+	content bytes.Buffer
+	number  int // printed page number; 0 for unnumbered front matter
+}
+
+// pdfLayout places text on pages
+type pdfLayout struct {
+	t       Template
+	font    pdfFont
+	margin  float64
+	leading float64
+	pages   []*pdfPage
+	page    *pdfPage
+	y       float64 // baseline of the next line
+	numbers int     // last printed page number
+}
+
+// renderPDF lays out a document as a PDF. The contents page is reserved
+// before the chapters are laid out and filled in once their page numbers are
+// known.
+func renderPDF(doc *Document, t Template) ([]byte, error) {
+	l := &pdfLayout{
+		t:       t,
+		font:    pdfFonts[t.FontFamily],
+		margin:  t.Margin * 72,
+		leading: t.FontSize * t.LineHeight,
+	}
+
+	var contents []*pdfPage
+	for _, section := range t.FrontMatter {
+		switch section {
+		case FrontTitle:
+			l.newPage(false)
+			l.y = pdfPageHeight * 0.62
+			l.centered(doc.Title, t.FontSize*2.4, true)
+			if doc.Author != "" {
+				l.y -= t.FontSize
+				l.centered(doc.Author, t.FontSize*1.4, false)
+			}
+		case FrontCopyright:
+			l.newPage(false)
+			l.y = l.margin + 3*l.leading
+			l.centered(copyrightText(doc), t.FontSize*0.85, false)
+		case FrontDedication:
+			if doc.Dedication != "" {
+				l.newPage(false)
+				l.y = pdfPageHeight * 0.62
+				l.centered(doc.Dedication, t.FontSize, false)
+			}
+		case FrontContents:
+			for i := 0; i < l.contentsPages(len(doc.Chapters)); i++ {
+				l.newPage(false)
+				contents = append(contents, l.page)
+			}
+		}
+	}
+
+	starts := make([]int, len(doc.Chapters))
+	for i, chapter := range doc.Chapters {
+		l.newPage(true)
+		starts[i] = l.page.number
+		l.y = pdfPageHeight - l.margin - pdfPageHeight*0.12
+		l.centered(chapter.Heading(t), t.FontSize*1.6, true)
+		l.y -= l.leading
+
+		for _, block := range chapter.Blocks {
+			switch block.Kind {
+			case BlockHeading:
+				l.y -= l.leading / 2
+				l.ensure(3 * l.leading)
+				l.line(l.margin, block.Text, t.FontSize*1.15, true)
+				l.y -= l.leading / 2
+			case BlockSceneBreak:
+				l.ensure(2 * l.leading)
+				l.y -= l.leading / 2
+				l.centered("* * *", t.FontSize, false)
+				l.y -= l.leading / 2
+			default:
+				l.paragraph(block.Text)
+			}
+		}
+	}
+
+	if len(contents) > 0 {
+		l.fillContents(contents, doc, starts)
+	}
+	return l.serialize(doc), nil
+}
+
+// newPage starts a page, numbered when it belongs to the body
+func (l *pdfLayout) newPage(numbered bool) {
+	l.page = &pdfPage{}
+	if numbered {
+		l.numbers++
+		l.page.number = l.numbers
+	}
+	l.pages = append(l.pages, l.page)
+	l.y = pdfPageHeight - l.margin - l.t.FontSize
+}
+
+// ensure starts a new page unless height fits above the bottom margin
+func (l *pdfLayout) ensure(height float64) {
+	if l.y-height < l.margin {
+		l.newPage(l.page.number > 0)
+	}
+}
+
+// paragraph wraps body text to the margins, indenting or spacing it as the
+// template asks
+func (l *pdfLayout) paragraph(text string) {
+	width := pdfPageWidth - 2*l.margin
+	indent := 0.0
+	if l.t.Indent {
+		indent = l.t.FontSize * 1.5
+	}
+	for i, line := range l.wrap(text, l.t.FontSize, width, indent) {
+		l.ensure(l.leading)
+		x := l.margin
+		if i == 0 {
+			x += indent
+		}
+		l.line(x, line, l.t.FontSize, false)
+	}
+	if !l.t.Indent {
+		l.y -= l.leading * 0.6
+	}
+}
+
+// centered writes wrapped lines centered between the margins
+func (l *pdfLayout) centered(text string, size float64, bold bool) {
+	width := pdfPageWidth - 2*l.margin
+	for _, line := range l.wrap(text, size, width, 0) {
+		l.ensure(size * l.t.LineHeight)
+		x := (pdfPageWidth - l.measure(line, size)) / 2
+		l.text(x, l.y, line, size, bold)
+		l.y -= size * l.t.LineHeight
+	}
+}
+
+// line writes one line at x and moves down
+func (l *pdfLayout) line(x float64, text string, size float64, bold bool) {
+	l.text(x, l.y, text, size, bold)
+	l.y -= math.Max(l.leading, size*l.t.LineHeight)
+}
+
+// text draws text with its baseline at (x, y)
+func (l *pdfLayout) text(x, y float64, text string, size float64, bold bool) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(&l.page.content, "BT /%s %.2f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, pdfString(winAnsi(text)))
+}
+
+// measure returns the width of text in points
+func (l *pdfLayout) measure(text string, size float64) float64 {
+	width := 0.0
+	for _, c := range winAnsi(text) {
+		width += l.font.widths(c)
+	}
+	return width * size / 1000
+}
+
+// wrap breaks text into lines no wider than width; the first line is
+// shortened by indent. A word wider than a line gets a line to itself.
+func (l *pdfLayout) wrap(text string, size, width, indent float64) []string {
+	var lines []string
+	var current []string
+	available := width - indent
+	for _, word := range strings.Fields(text) {
+		candidate := strings.Join(append(current, word), " ")
+		if len(current) > 0 && l.measure(candidate, size) > available {
+			lines = append(lines, strings.Join(current, " "))
+			current = nil
+			available = width
+		}
+		current = append(current, word)
+	}
+	if len(current) > 0 {
+		lines = append(lines, strings.Join(current, " "))
+	}
+	return lines
+}
+
+// contentsPages returns the pages needed to list a number of chapters
+func (l *pdfLayout) contentsPages(chapters int) int {
+	usable := pdfPageHeight - 2*l.margin - 4*l.leading
+	perPage := int(usable / l.leading)
+	if perPage < 1 {
+		perPage = 1
+	}
+	pages := (chapters + perPage - 1) / perPage
+	if pages < 1 {
+		pages = 1
+	}
+	return pages
+}
+
+// fillContents writes the chapter list onto the reserved pages, with
+// headings left-aligned and page numbers right-aligned
+func (l *pdfLayout) fillContents(pages []*pdfPage, doc *Document, starts []int) {
+	size := l.t.FontSize
+	right := pdfPageWidth - l.margin
+	index := 0
+	l.page = pages[index]
+	l.y = pdfPageHeight - l.margin - size
+	l.centered("Contents", size*1.6, true)
+	l.y -= l.leading
+
+	for i, chapter := range doc.Chapters {
+		if l.y-l.leading < l.margin && index+1 < len(pages) {
+			index++
+			l.page = pages[index]
+			l.y = pdfPageHeight - l.margin - size
+		}
+		number := fmt.Sprintf("%d", starts[i])
+		heading := chapter.Heading(l.t)
+		maxWidth := right - l.margin - l.measure(number, size) - size*2
+		for heading != "" && l.measure(heading, size) > maxWidth {
+			runes := []rune(heading)
+			heading = string(runes[:len(runes)-1])
+		}
+		l.text(l.margin, l.y, heading, size, false)
+		l.text(right-l.measure(number, size), l.y, number, size, false)
+		l.y -= l.leading
+	}
+}
+
+// serialize writes the pages and fonts as a PDF file
+func (l *pdfLayout) serialize(doc *Document) []byte {
+	var buf bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// Objects 1-5 are fixed; each page then takes a page object and a
+	// content stream
+	const firstPage = 6
+	kids := make([]string, len(l.pages))
+	for i := range l.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(l.pages)))
+	object(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", l.font.regular))
+	object(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", l.font.bold))
+	object(fmt.Sprintf("<< /Title (%s) /Author (%s) /Producer (Memmie Studio) /CreationDate (D:%s) >>",
+		pdfString(winAnsi(doc.Title)), pdfString(winAnsi(doc.Author)), doc.Modified.UTC().Format("20060102150405Z")))
+
+	for i, page := range l.pages {
+		if page.number > 0 {
+			number := fmt.Sprintf("%d", page.number)
+			size := l.t.FontSize * 0.85
+			fmt.Fprintf(&page.content, "BT /F1 %.2f Tf %.2f %.2f Td (%s) Tj ET\n",
+				size, (pdfPageWidth-l.measure(number, size))/2, l.margin/2, number)
+		}
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %g %g] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, firstPage+2*i+1))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.content.Len(), page.content.String()))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return buf.Bytes()
+}
+
+// winAnsiExtras maps typographic characters to their WinAnsi codes
+var winAnsiExtras = map[rune]byte{
+	'€': 0x80, '…': 0x85, '‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94,
+	'•': 0x95, '–': 0x96, '—': 0x97, '™': 0x99, 'Œ': 0x8C, 'œ': 0x9C,
+}
+
+// winAnsi encodes text for the standard fonts, replacing characters they
+// lack with "?"
+func winAnsi(text string) []byte {
+	out := make([]byte, 0, len(text))
+	for _, r := range text {
+		switch {
+		case r < 0x80 && r >= 0x20:
+			out = append(out, byte(r))
+		case r >= 0xA0 && r <= 0xFF:
+			out = append(out, byte(r))
+		case r == '\t':
+			out = append(out, ' ')
+		default:
+			if c, ok := winAnsiExtras[r]; ok {
+				out = append(out, c)
+			} else if r >= 0x20 {
+				out = append(out, '?')
+			}
+		}
+	}
+	return out
+}
+
+// pdfString escapes encoded text for a PDF literal string
+func pdfString(text []byte) string {
+	var b strings.Builder
+	for _, c := range text {
+		switch c {
+		case '(', ')', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
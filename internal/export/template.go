@@ -0,0 +1,118 @@
+package export
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Front matter sections, rendered in the order a template lists them
+const (
+	FrontTitle      = "title"
+	FrontCopyright  = "copyright"
+	FrontDedication = "dedication"
+	FrontContents   = "contents"
+)
+
+// Font families. Each format maps them to fonts it can rely on.
+const (
+	FontSerif = "serif"
+	FontSans  = "sans"
+	FontMono  = "mono"
+)
+
+// DefaultTemplate is used when an export names no template
+const DefaultTemplate = "classic"
+
+// Template controls an export's front matter and styling
+type Template struct {
+	Name         string   `json:"name"`
+	Description  string   `json:"description"`
+	FrontMatter  []string `json:"front_matter"`
+	FontFamily   string   `json:"font_family"`
+	FontSize     float64  `json:"font_size"`     // points
+	LineHeight   float64  `json:"line_height"`   // multiple of the font size
+	Margin       float64  `json:"margin"`        // inches
+	Indent       bool     `json:"indent"`        // indent first lines rather than space paragraphs
+	ChapterLabel string   `json:"chapter_label"` // e.g. "Chapter %d"; empty for titles only
+	CSS          string   `json:"css,omitempty"` // extra EPUB styles
+}
+
+// Templates are the built-in export templates
+var Templates = map[string]Template{
+	"classic": {
+		Name:         "classic",
+		Description:  "Serif body with indented paragraphs, title, copyright, dedication and contents pages",
+		FrontMatter:  []string{FrontTitle, FrontCopyright, FrontDedication, FrontContents},
+		FontFamily:   FontSerif,
+		FontSize:     11,
+		LineHeight:   1.4,
+		Margin:       1,
+		Indent:       true,
+		ChapterLabel: "Chapter %d",
+	},
+	"modern": {
+		Name:        "modern",
+		Description: "Sans-serif body with spaced paragraphs and a contents page",
+		FrontMatter: []string{FrontTitle, FrontContents},
+		FontFamily:  FontSans,
+		FontSize:    10.5,
+		LineHeight:  1.5,
+		Margin:      0.9,
+		CSS:         "h1 { text-transform: uppercase; letter-spacing: 0.1em; }",
+	},
+	"manuscript": {
+		Name:         "manuscript",
+		Description:  "Standard manuscript format for submissions: monospaced, double-spaced, title page only",
+		FrontMatter:  []string{FrontTitle},
+		FontFamily:   FontMono,
+		FontSize:     12,
+		LineHeight:   2,
+		Margin:       1,
+		Indent:       true,
+		ChapterLabel: "Chapter %d",
+	},
+}
+
+// TemplateNames lists the built-in templates in order
+func TemplateNames() []string {
+	names := make([]string, 0, len(Templates))
+	for name := range Templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// has reports whether the template includes a front matter section
+func (t Template) has(section string) bool {
+	for _, s := range t.FrontMatter {
+		if s == section {
+			return true
+		}
+	}
+	return false
+}
+
+// chapterLabel formats the label for a chapter number
+func (t Template) chapterLabel(number int) string {
+	if t.ChapterLabel == "" || number <= 0 {
+		return ""
+	}
+	if strings.Contains(t.ChapterLabel, "%d") {
+		return fmt.Sprintf(t.ChapterLabel, number)
+	}
+	return t.ChapterLabel
+}
+
+// copyrightText returns the copyright notice for a document
+func copyrightText(doc *Document) string {
+	if doc.Copyright != "" {
+		return doc.Copyright
+	}
+	holder := doc.Author
+	if holder == "" {
+		holder = "the author"
+	}
+	return fmt.Sprintf("Copyright © %d %s. All rights reserved.", doc.Modified.Year(), holder)
+}
@@ -0,0 +1,403 @@
+// Package forward keeps workflow executions from failing while the workflow
+// service is down. Executions started while its health checks fail are
+// queued durably and forwarded, in the order they were queued, once it
+// recovers; callers are given the queued execution to follow meanwhile.
+package forward
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// Queued execution statuses
+const (
+	StatusQueued    = "queued"
+	StatusForwarded = "forwarded"
+	StatusCancelled = "cancelled"
+	StatusFailed    = "failed"
+)
+
+// IDPrefix starts the IDs given to queued executions
+const IDPrefix = "queued-"
+
+// DefaultCheckInterval is how often the workflow service's health is
+// checked while it is down, and the queue checked for executions to forward
+const DefaultCheckInterval = 10 * time.Second
+
+// MaxAttempts is how many times an execution is forwarded while the
+// service is healthy before it is failed
+const MaxAttempts = 5
+
+// retention is how long finished entries stay queryable
+const retention = 7 * 24 * time.Hour
+
+// ErrEntryNotFound is returned for queued executions that do not exist
+var ErrEntryNotFound = errors.New("queued execution not found")
+
+// Entry is an execution queued while the workflow service was down. Once
+// forwarded, ExecutionID is the execution the service started for it.
+type Entry struct {
+	ID          string                     `json:"id"`
+	Request     workflows.ExecutionRequest `json:"request"`
+	Status      string                     `json:"status"`
+	ExecutionID string                     `json:"execution_id,omitempty"`
+	Attempts    int                        `json:"attempts"`
+	Error       string                     `json:"error,omitempty"`
+	QueuedAt    time.Time                  `json:"queued_at"`
+	FinishedAt  *time.Time                 `json:"finished_at,omitempty"`
+}
+
+// response reports an entry that has not been forwarded as an execution
+func (e *Entry) response() *workflows.ExecutionResponse {
+	resp := &workflows.ExecutionResponse{
+		ExecutionID: e.ID,
+		Status:      e.Status,
+		StartedAt:   e.QueuedAt,
+		CompletedAt: e.FinishedAt,
+	}
+	if e.Status == StatusFailed {
+		resp.Error = &workflows.ExecutionError{Code: "forward_failed", Message: e.Error}
+	}
+	return resp
+}
+
+// IsQueuedID reports whether an execution ID was given to a queued
+// execution
+func IsQueuedID(executionID string) bool {
+	return strings.HasPrefix(executionID, IDPrefix)
+}
+
+// Service queues executions for a workflow service while it is down and
+// forwards them once it is back. Execution status and cancellation accept
+// the queued IDs; other calls go straight to the service. It assumes it is
+// the only service forwarding the store's entries.
+type Service struct {
+	workflows.WorkflowService
+	checker workflows.HealthChecker
+	store   Store
+	onError func(error)
+	now     func() time.Time
+
+	// mu serializes changes to queued entries
+	mu sync.Mutex
+
+	stateMu sync.Mutex
+	healthy bool
+	pending int
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wake   chan struct{}
+	done   chan struct{}
+}
+
+// deletingService is a Service over a backend that can delete workflows
+type deletingService struct {
+	*Service
+	workflows.WorkflowDeleter
+}
+
+// NewService queues next's executions in store while checker reports it
+// unhealthy, checking every interval. onError, if set, is told when the
+// store cannot be read or written. Close stops forwarding.
+func NewService(next workflows.WorkflowService, checker workflows.HealthChecker, store Store, interval time.Duration, onError func(error)) *Service {
+	if interval <= 0 {
+		interval = DefaultCheckInterval
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Service{
+		WorkflowService: next,
+		checker:         checker,
+		store:           store,
+		onError:         onError,
+		now:             time.Now,
+		healthy:         true,
+		ctx:             ctx,
+		cancel:          cancel,
+		wake:            make(chan struct{}, 1),
+		done:            make(chan struct{}),
+	}
+	// Executions queued before a restart go ahead of new ones
+	if entries, err := store.List(ctx); err != nil {
+		s.report(err)
+	} else {
+		for _, entry := range entries {
+			if entry.Status == StatusQueued {
+				s.pending++
+			}
+		}
+	}
+	go s.run(interval)
+	return s
+}
+
+// Backend returns the service to start executions through. It deletes
+// workflows exactly when the wrapped service can.
+func (s *Service) Backend() workflows.WorkflowService {
+	if deleter, ok := s.WorkflowService.(workflows.WorkflowDeleter); ok {
+		return &deletingService{Service: s, WorkflowDeleter: deleter}
+	}
+	return s
+}
+
+// Healthy reports whether the workflow service passed its last health check
+func (s *Service) Healthy() bool {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	return s.healthy
+}
+
+// Pending returns how many executions are waiting to be forwarded
+func (s *Service) Pending() int {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	return s.pending
+}
+
+// Close stops forwarding, interrupting any execution being forwarded
+func (s *Service) Close() {
+	s.cancel()
+	<-s.done
+}
+
+// ExecuteWorkflow starts an execution, or queues it while the service is
+// down or executions queued earlier are still waiting, so they start in
+// order. A call that fails the way an unreachable service does is queued
+// if the service then fails its health check too. Interactive executions
+// are never queued: they go straight to the service and fail with it.
+func (s *Service) ExecuteWorkflow(ctx context.Context, req workflows.ExecutionRequest) (*workflows.ExecutionResponse, error) {
+	if req.Profile == workflows.ProfileInteractive {
+		return s.WorkflowService.ExecuteWorkflow(ctx, req)
+	}
+	if s.direct() {
+		resp, err := s.WorkflowService.ExecuteWorkflow(ctx, req)
+		if err == nil || !workflows.IsRetryable(err) || ctx.Err() != nil {
+			return resp, err
+		}
+		if s.check(ctx) == nil {
+			return nil, err
+		}
+	}
+	entry := &Entry{
+		ID:       IDPrefix + uuid.New().String(),
+		Request:  req,
+		Status:   StatusQueued,
+		QueuedAt: s.now().UTC(),
+	}
+	if err := s.store.Save(ctx, entry); err != nil {
+		return nil, fmt.Errorf("failed to queue execution of %s: %w", req.WorkflowID, err)
+	}
+	s.stateMu.Lock()
+	s.pending++
+	s.stateMu.Unlock()
+	return entry.response(), nil
+}
+
+// GetExecutionStatus reports an execution's status. A forwarded execution
+// is reported as the service reports the execution it started, under its
+// queued ID.
+func (s *Service) GetExecutionStatus(ctx context.Context, executionID string) (*workflows.ExecutionResponse, error) {
+	if !IsQueuedID(executionID) {
+		return s.WorkflowService.GetExecutionStatus(ctx, executionID)
+	}
+	entry, err := s.entry(ctx, executionID)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Status != StatusForwarded {
+		return entry.response(), nil
+	}
+	resp, err := s.WorkflowService.GetExecutionStatus(ctx, entry.ExecutionID)
+	if err != nil {
+		return nil, err
+	}
+	forwarded := *resp
+	forwarded.ExecutionID = executionID
+	return &forwarded, nil
+}
+
+// CancelExecution cancels an execution. A queued execution is dropped
+// from the queue; a forwarded one is cancelled with the service.
+func (s *Service) CancelExecution(ctx context.Context, executionID string) error {
+	if !IsQueuedID(executionID) {
+		return s.WorkflowService.CancelExecution(ctx, executionID)
+	}
+	s.mu.Lock()
+	entry, err := s.entry(ctx, executionID)
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	if entry.Status != StatusQueued {
+		s.mu.Unlock()
+		if entry.Status == StatusForwarded {
+			return s.WorkflowService.CancelExecution(ctx, entry.ExecutionID)
+		}
+		return nil
+	}
+	entry.Status = StatusCancelled
+	err = s.finish(ctx, entry)
+	s.mu.Unlock()
+	return err
+}
+
+// entry reads a queued execution, not found as an execution
+func (s *Service) entry(ctx context.Context, id string) (*Entry, error) {
+	entry, err := s.store.Get(ctx, id)
+	if errors.Is(err, ErrEntryNotFound) {
+		return nil, fmt.Errorf("%w: %s", workflows.ErrExecutionNotFound, id)
+	}
+	return entry, err
+}
+
+// direct reports whether executions can go straight to the service
+func (s *Service) direct() bool {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	return s.healthy && s.pending == 0
+}
+
+// check checks the service's health and records the result, waking the
+// forwarder when the service recovers
+func (s *Service) check(ctx context.Context) error {
+	err := s.checker.CheckHealth(ctx)
+	s.stateMu.Lock()
+	recovered := err == nil && !s.healthy
+	s.healthy = err == nil
+	s.stateMu.Unlock()
+	if recovered {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+	return err
+}
+
+// run forwards queued executions until the service is closed
+func (s *Service) run(interval time.Duration) {
+	defer close(s.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.forwardQueued()
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// forwardQueued forwards queued executions oldest first while the service
+// is healthy, and forgets finished entries past their retention
+func (s *Service) forwardQueued() {
+	if !s.Healthy() && s.check(s.ctx) != nil {
+		return
+	}
+	entries, err := s.store.List(s.ctx)
+	if err != nil {
+		s.report(err)
+		return
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].QueuedAt.Equal(entries[j].QueuedAt) {
+			return entries[i].QueuedAt.Before(entries[j].QueuedAt)
+		}
+		return entries[i].ID < entries[j].ID
+	})
+	now := s.now()
+	for _, entry := range entries {
+		if s.ctx.Err() != nil {
+			return
+		}
+		switch {
+		case entry.Status == StatusQueued:
+			if !s.forward(entry.ID) {
+				// Later executions wait so they start in order
+				return
+			}
+		case entry.FinishedAt != nil && entry.FinishedAt.Before(now.Add(-retention)):
+			if err := s.store.Delete(s.ctx, entry.ID); err != nil && !errors.Is(err, ErrEntryNotFound) {
+				s.report(err)
+			}
+		}
+	}
+}
+
+// forward starts a queued execution with the service, recording the
+// outcome. It reports false when the execution is still waiting, because
+// the service is down again or failed it in a way worth another attempt.
+// The entry is read again first so an execution cancelled since the
+// listing is not started; a crash after starting it and before recording
+// that starts it again.
+func (s *Service) forward(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, err := s.store.Get(s.ctx, id)
+	if err != nil || entry.Status != StatusQueued {
+		if err != nil && !errors.Is(err, ErrEntryNotFound) {
+			s.report(err)
+		}
+		return true
+	}
+
+	resp, err := s.WorkflowService.ExecuteWorkflow(s.ctx, entry.Request)
+	if err != nil && s.ctx.Err() != nil {
+		return false
+	}
+	if err != nil && workflows.IsRetryable(err) {
+		if s.check(s.ctx) != nil {
+			return false
+		}
+		// The service is up, so this was the execution's own failure
+		entry.Attempts++
+		entry.Error = err.Error()
+		if entry.Attempts < MaxAttempts {
+			if err := s.store.Save(s.ctx, entry); err != nil {
+				s.report(err)
+			}
+			return false
+		}
+	}
+	if err != nil {
+		entry.Status = StatusFailed
+		entry.Error = err.Error()
+	} else {
+		entry.Status = StatusForwarded
+		entry.ExecutionID = resp.ExecutionID
+		entry.Error = ""
+	}
+	if err := s.finish(context.Background(), entry); err != nil {
+		s.report(err)
+	}
+	return true
+}
+
+// finish records that an entry left the queue
+func (s *Service) finish(ctx context.Context, entry *Entry) error {
+	finishedAt := s.now().UTC()
+	entry.FinishedAt = &finishedAt
+	s.stateMu.Lock()
+	s.pending--
+	s.stateMu.Unlock()
+	return s.store.Save(ctx, entry)
+}
+
+// report passes an error to onError, if set
+func (s *Service) report(err error) {
+	if s.onError != nil && !errors.Is(err, context.Canceled) {
+		s.onError(err)
+	}
+}
@@ -0,0 +1,132 @@
+package forward
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Store persists queued executions so they survive restarts
+type Store interface {
+	Save(ctx context.Context, entry *Entry) error
+	Get(ctx context.Context, id string) (*Entry, error)
+	List(ctx context.Context) ([]*Entry, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// FileStore keeps each queued execution in a JSON file under a directory
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates a store writing under dir
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir}
+}
+
+// Save writes an entry, replacing the file atomically so a crash never
+// leaves a partial one
+func (s *FileStore) Save(ctx context.Context, entry *Entry) error {
+	path, err := s.path(entry.ID)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal queued execution: %w", err)
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create queue directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(s.dir, ".entry-*")
+	if err != nil {
+		return fmt.Errorf("failed to write queued execution %s: %w", entry.ID, err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write queued execution %s: %w", entry.ID, err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write queued execution %s: %w", entry.ID, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write queued execution %s: %w", entry.ID, err)
+	}
+	return nil
+}
+
+// Get reads an entry
+func (s *FileStore) Get(ctx context.Context, id string) (*Entry, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrEntryNotFound, id)
+	}
+	return s.read(path)
+}
+
+// List reads every entry
+func (s *FileStore) List(ctx context.Context) ([]*Entry, error) {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list queued executions: %w", err)
+	}
+	entries := make([]*Entry, 0, len(paths))
+	for _, path := range paths {
+		entry, err := s.read(path)
+		if errors.Is(err, ErrEntryNotFound) {
+			// Deleted since the listing
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Delete removes an entry
+func (s *FileStore) Delete(ctx context.Context, id string) error {
+	path, err := s.path(id)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrEntryNotFound, id)
+	}
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%w: %s", ErrEntryNotFound, id)
+		}
+		return fmt.Errorf("failed to delete queued execution %s: %w", id, err)
+	}
+	return nil
+}
+
+// read decodes an entry file
+func (s *FileStore) read(path string) (*Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %s", ErrEntryNotFound, strings.TrimSuffix(filepath.Base(path), ".json"))
+		}
+		return nil, fmt.Errorf("failed to read queued execution: %w", err)
+	}
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("failed to parse queued execution %s: %w", path, err)
+	}
+	return &entry, nil
+}
+
+// path maps an entry ID to its file, rejecting IDs that are not plain names
+func (s *FileStore) path(id string) (string, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) || strings.HasPrefix(id, ".") {
+		return "", fmt.Errorf("invalid queued execution id %q", id)
+	}
+	return filepath.Join(s.dir, id+".json"), nil
+}
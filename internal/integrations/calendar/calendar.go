@@ -0,0 +1,274 @@
+// Package calendar schedules workflows around Google Calendar and Outlook
+// events, for example running meeting-notes processing when a meeting ends
+package calendar
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TokenSource supplies OAuth2 access tokens. gdocs.StaticToken satisfies it.
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// Event is a calendar event normalized across providers
+type Event struct {
+	ID          string
+	Calendar    string // source name, e.g. google or outlook
+	Title       string
+	Description string
+	Location    string
+	MeetingURL  string
+	Attendees   []string
+	Start       time.Time
+	End         time.Time
+	AllDay      bool
+	Cancelled   bool
+}
+
+// Source lists events from one calendar
+type Source interface {
+	Name() string
+	ListEvents(ctx context.Context, from, to time.Time) ([]Event, error)
+}
+
+// GoogleCalendar reads events from a Google calendar
+type GoogleCalendar struct {
+	tokens     TokenSource
+	calendarID string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewGoogleCalendar creates a source for a calendar ID; "primary" selects
+// the user's main calendar
+func NewGoogleCalendar(tokens TokenSource, calendarID string) *GoogleCalendar {
+	if calendarID == "" {
+		calendarID = "primary"
+	}
+	return &GoogleCalendar{
+		tokens:     tokens,
+		calendarID: calendarID,
+		baseURL:    "https://www.googleapis.com/calendar/v3",
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// Name returns the source name used in schedule rules
+func (g *GoogleCalendar) Name() string {
+	return "google"
+}
+
+// ListEvents returns events overlapping [from, to), expanding recurrences
+func (g *GoogleCalendar) ListEvents(ctx context.Context, from, to time.Time) ([]Event, error) {
+	type eventTime struct {
+		DateTime string `json:"dateTime"`
+		Date     string `json:"date"`
+	}
+
+	var events []Event
+	pageToken := ""
+	for {
+		params := url.Values{}
+		params.Set("timeMin", from.UTC().Format(time.RFC3339))
+		params.Set("timeMax", to.UTC().Format(time.RFC3339))
+		params.Set("singleEvents", "true")
+		params.Set("orderBy", "startTime")
+		params.Set("maxResults", "250")
+		if pageToken != "" {
+			params.Set("pageToken", pageToken)
+		}
+
+		var page struct {
+			Items []struct {
+				ID          string    `json:"id"`
+				Status      string    `json:"status"`
+				Summary     string    `json:"summary"`
+				Description string    `json:"description"`
+				Location    string    `json:"location"`
+				HangoutLink string    `json:"hangoutLink"`
+				Start       eventTime `json:"start"`
+				End         eventTime `json:"end"`
+				Attendees   []struct {
+					Email string `json:"email"`
+				} `json:"attendees"`
+			} `json:"items"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		endpoint := g.baseURL + "/calendars/" + url.PathEscape(g.calendarID) + "/events?" + params.Encode()
+		if err := getJSON(ctx, g.httpClient, g.tokens, endpoint, nil, &page); err != nil {
+			return nil, fmt.Errorf("failed to list google calendar events: %w", err)
+		}
+
+		for _, item := range page.Items {
+			event := Event{
+				ID:          item.ID,
+				Calendar:    g.Name(),
+				Title:       item.Summary,
+				Description: item.Description,
+				Location:    item.Location,
+				MeetingURL:  item.HangoutLink,
+				Cancelled:   item.Status == "cancelled",
+			}
+			for _, attendee := range item.Attendees {
+				event.Attendees = append(event.Attendees, attendee.Email)
+			}
+			if item.Start.DateTime == "" {
+				event.AllDay = true
+				event.Start, _ = time.Parse("2006-01-02", item.Start.Date)
+				event.End, _ = time.Parse("2006-01-02", item.End.Date)
+			} else {
+				event.Start, _ = time.Parse(time.RFC3339, item.Start.DateTime)
+				event.End, _ = time.Parse(time.RFC3339, item.End.DateTime)
+			}
+			events = append(events, event)
+		}
+
+		if page.NextPageToken == "" {
+			return events, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+// OutlookCalendar reads events from an Outlook calendar through Microsoft
+// Graph
+type OutlookCalendar struct {
+	tokens     TokenSource
+	calendarID string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewOutlookCalendar creates a source for a calendar ID, or the user's
+// default calendar when calendarID is empty
+func NewOutlookCalendar(tokens TokenSource, calendarID string) *OutlookCalendar {
+	return &OutlookCalendar{
+		tokens:     tokens,
+		calendarID: calendarID,
+		baseURL:    "https://graph.microsoft.com/v1.0",
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// Name returns the source name used in schedule rules
+func (o *OutlookCalendar) Name() string {
+	return "outlook"
+}
+
+// graphTimeLayout is the zone-less format Graph returns when asked for UTC
+const graphTimeLayout = "2006-01-02T15:04:05.9999999"
+
+// ListEvents returns events overlapping [from, to), expanding recurrences
+func (o *OutlookCalendar) ListEvents(ctx context.Context, from, to time.Time) ([]Event, error) {
+	type eventTime struct {
+		DateTime string `json:"dateTime"`
+	}
+
+	path := "/me/calendarView"
+	if o.calendarID != "" {
+		path = "/me/calendars/" + url.PathEscape(o.calendarID) + "/calendarView"
+	}
+	params := url.Values{}
+	params.Set("startDateTime", from.UTC().Format(time.RFC3339))
+	params.Set("endDateTime", to.UTC().Format(time.RFC3339))
+	params.Set("$top", "100")
+	endpoint := o.baseURL + path + "?" + params.Encode()
+	headers := map[string]string{"Prefer": `outlook.timezone="UTC"`}
+
+	var events []Event
+	for endpoint != "" {
+		var page struct {
+			Value []struct {
+				ID          string    `json:"id"`
+				Subject     string    `json:"subject"`
+				BodyPreview string    `json:"bodyPreview"`
+				IsAllDay    bool      `json:"isAllDay"`
+				IsCancelled bool      `json:"isCancelled"`
+				Start       eventTime `json:"start"`
+				End         eventTime `json:"end"`
+				Location    struct {
+					DisplayName string `json:"displayName"`
+				} `json:"location"`
+				OnlineMeeting *struct {
+					JoinURL string `json:"joinUrl"`
+				} `json:"onlineMeeting"`
+				Attendees []struct {
+					EmailAddress struct {
+						Address string `json:"address"`
+					} `json:"emailAddress"`
+				} `json:"attendees"`
+			} `json:"value"`
+			NextLink string `json:"@odata.nextLink"`
+		}
+		if err := getJSON(ctx, o.httpClient, o.tokens, endpoint, headers, &page); err != nil {
+			return nil, fmt.Errorf("failed to list outlook events: %w", err)
+		}
+
+		for _, item := range page.Value {
+			event := Event{
+				ID:          item.ID,
+				Calendar:    o.Name(),
+				Title:       item.Subject,
+				Description: item.BodyPreview,
+				Location:    item.Location.DisplayName,
+				AllDay:      item.IsAllDay,
+				Cancelled:   item.IsCancelled,
+			}
+			if item.OnlineMeeting != nil {
+				event.MeetingURL = item.OnlineMeeting.JoinURL
+			}
+			for _, attendee := range item.Attendees {
+				event.Attendees = append(event.Attendees, attendee.EmailAddress.Address)
+			}
+			event.Start, _ = time.Parse(graphTimeLayout, item.Start.DateTime)
+			event.End, _ = time.Parse(graphTimeLayout, item.End.DateTime)
+			events = append(events, event)
+		}
+		endpoint = page.NextLink
+	}
+	return events, nil
+}
+
+// getJSON sends an authorized GET request and decodes the response
+func getJSON(ctx context.Context, client *http.Client, tokens TokenSource, endpoint string, headers map[string]string, out interface{}) error {
+	token, err := tokens.Token(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get access token: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("calendar request failed (status %d): %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
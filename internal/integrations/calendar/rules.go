@@ -0,0 +1,83 @@
+package calendar
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ErrInvalidRules is returned for rules files that cannot be used
+var ErrInvalidRules = errors.New("invalid calendar rules")
+
+// ruleFile is a rule as written in a rules file
+type ruleFile struct {
+	Name          string `json:"name"`
+	UserID        string `json:"user_id"`
+	ProviderID    string `json:"provider_id"`
+	Calendar      string `json:"calendar"`
+	TitleContains string `json:"title_contains"`
+	MinAttendees  int    `json:"min_attendees"`
+	At            string `json:"at"`
+	Offset        string `json:"offset"` // a Go duration, such as -10m
+}
+
+// LoadRules reads schedule rules from a JSON file
+func LoadRules(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read calendar rules: %w", err)
+	}
+	return ParseRules(data)
+}
+
+// ParseRules reads schedule rules from a JSON list. Each rule needs a
+// name, user_id and provider_id; at is start, the default, or end.
+func ParseRules(data []byte) ([]Rule, error) {
+	var files []ruleFile
+	if err := json.Unmarshal(data, &files); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRules, err)
+	}
+	rules := make([]Rule, len(files))
+	names := make(map[string]bool, len(files))
+	for i, f := range files {
+		switch {
+		case f.Name == "":
+			return nil, fmt.Errorf("%w: rule %d has no name", ErrInvalidRules, i+1)
+		case names[f.Name]:
+			return nil, fmt.Errorf("%w: rule %s is given twice", ErrInvalidRules, f.Name)
+		case f.UserID == "" || f.ProviderID == "":
+			return nil, fmt.Errorf("%w: rule %s needs a user_id and provider_id", ErrInvalidRules, f.Name)
+		case f.MinAttendees < 0:
+			return nil, fmt.Errorf("%w: rule %s has negative min_attendees", ErrInvalidRules, f.Name)
+		}
+		names[f.Name] = true
+
+		at := f.At
+		if at == "" {
+			at = AtStart
+		}
+		if at != AtStart && at != AtEnd {
+			return nil, fmt.Errorf("%w: rule %s has at %q, not %s or %s", ErrInvalidRules, f.Name, f.At, AtStart, AtEnd)
+		}
+		var offset time.Duration
+		if f.Offset != "" {
+			var err error
+			if offset, err = time.ParseDuration(f.Offset); err != nil {
+				return nil, fmt.Errorf("%w: rule %s has invalid offset %q", ErrInvalidRules, f.Name, f.Offset)
+			}
+		}
+		rules[i] = Rule{
+			Name:          f.Name,
+			UserID:        f.UserID,
+			ProviderID:    f.ProviderID,
+			Calendar:      f.Calendar,
+			TitleContains: f.TitleContains,
+			MinAttendees:  f.MinAttendees,
+			At:            at,
+			Offset:        offset,
+		}
+	}
+	return rules, nil
+}
@@ -0,0 +1,214 @@
+package calendar
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/memmieai/memmie-studio/internal/blob"
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+const (
+	// SourceCalendar marks blobs created for calendar events
+	SourceCalendar = "calendar"
+
+	// TriggerEvent is the provider trigger event fired for scheduled events
+	TriggerEvent = "onSchedule"
+
+	// Rule anchors
+	AtStart = "start"
+	AtEnd   = "end"
+
+	// firedRetention is how long fired triggers are remembered to avoid
+	// firing twice when polls overlap
+	firedRetention = 48 * time.Hour
+)
+
+// Rule schedules a provider's workflows relative to matching events
+type Rule struct {
+	Name          string
+	UserID        string
+	ProviderID    string
+	Calendar      string        // source name; empty matches every source
+	TitleContains string        // case-insensitive; empty matches every event
+	MinAttendees  int           // e.g. 2 to skip focus-time blocks
+	At            string        // AtStart or AtEnd
+	Offset        time.Duration // shifts the trigger, e.g. -10m before start
+}
+
+// matches reports whether an event is covered by the rule
+func (r Rule) matches(event Event) bool {
+	if event.Cancelled || event.AllDay {
+		return false
+	}
+	if r.Calendar != "" && r.Calendar != event.Calendar {
+		return false
+	}
+	if r.TitleContains != "" && !strings.Contains(strings.ToLower(event.Title), strings.ToLower(r.TitleContains)) {
+		return false
+	}
+	return len(event.Attendees) >= r.MinAttendees
+}
+
+// triggerTime returns when the rule fires for an event
+func (r Rule) triggerTime(event Event) time.Time {
+	if r.At == AtEnd {
+		return event.End.Add(r.Offset)
+	}
+	return event.Start.Add(r.Offset)
+}
+
+// Scheduler polls calendars and, when a rule's trigger time passes, records
+// the event as a blob and fires the onSchedule trigger for it
+type Scheduler struct {
+	sources   []Source
+	rules     []Rule
+	blobs     blob.Store
+	processor workflows.BlobProcessor
+
+	lastPoll time.Time
+	fired    map[string]time.Time
+	mu       sync.Mutex
+}
+
+// NewScheduler creates a scheduler. Triggers whose time passed before the
+// scheduler was created are not fired.
+func NewScheduler(sources []Source, rules []Rule, blobs blob.Store, processor workflows.BlobProcessor) *Scheduler {
+	return &Scheduler{
+		sources:   sources,
+		rules:     rules,
+		blobs:     blobs,
+		processor: processor,
+		lastPoll:  time.Now(),
+		fired:     make(map[string]time.Time),
+	}
+}
+
+// Run polls every interval until ctx is cancelled. Poll errors are passed to
+// onError and do not stop the loop.
+func (s *Scheduler) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if _, err := s.Poll(ctx, now); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// Poll fires every rule whose trigger time falls between the previous poll
+// and now, and returns the number of triggers fired. A failed source is
+// retried on the next poll since the window only advances on success.
+func (s *Scheduler) Poll(ctx context.Context, now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	since := s.lastPoll
+	var span time.Duration
+	for _, rule := range s.rules {
+		if rule.Offset > span {
+			span = rule.Offset
+		}
+		if -rule.Offset > span {
+			span = -rule.Offset
+		}
+	}
+
+	fired := 0
+	for _, source := range s.sources {
+		events, err := source.ListEvents(ctx, since.Add(-span), now.Add(span))
+		if err != nil {
+			return fired, fmt.Errorf("failed to poll %s: %w", source.Name(), err)
+		}
+
+		for _, event := range events {
+			for _, rule := range s.rules {
+				if !rule.matches(event) {
+					continue
+				}
+				at := rule.triggerTime(event)
+				if !at.After(since) || at.After(now) {
+					continue
+				}
+				key := rule.Name + "/" + event.Calendar + "/" + event.ID + "/" + at.UTC().Format(time.RFC3339)
+				if _, ok := s.fired[key]; ok {
+					continue
+				}
+				if err := s.fire(ctx, rule, event); err != nil {
+					return fired, err
+				}
+				s.fired[key] = now
+				fired++
+			}
+		}
+	}
+
+	for key, at := range s.fired {
+		if now.Sub(at) > firedRetention {
+			delete(s.fired, key)
+		}
+	}
+	s.lastPoll = now
+	return fired, nil
+}
+
+// fire stores the event as a blob and triggers the rule's provider
+func (s *Scheduler) fire(ctx context.Context, rule Rule, event Event) error {
+	attendees := make([]interface{}, len(event.Attendees))
+	for i, attendee := range event.Attendees {
+		attendees[i] = attendee
+	}
+
+	created, err := s.blobs.CreateBlob(ctx, &blob.Blob{
+		UserID:     rule.UserID,
+		ProviderID: rule.ProviderID,
+		Content:    describe(event),
+		Metadata: map[string]interface{}{
+			"source":      SourceCalendar,
+			"calendar":    event.Calendar,
+			"event_id":    event.ID,
+			"title":       event.Title,
+			"start":       event.Start.UTC().Format(time.RFC3339),
+			"end":         event.End.UTC().Format(time.RFC3339),
+			"location":    event.Location,
+			"meeting_url": event.MeetingURL,
+			"attendees":   attendees,
+			"rule":        rule.Name,
+			"trigger":     rule.At,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store calendar event %s: %w", event.ID, err)
+	}
+
+	if err := s.processor.ProcessBlob(ctx, created.ID, rule.UserID, TriggerEvent); err != nil {
+		return fmt.Errorf("failed to trigger schedule for event %s: %w", event.ID, err)
+	}
+	return nil
+}
+
+// describe renders an event as the blob's text content
+func describe(event Event) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n", event.Title)
+	fmt.Fprintf(&b, "%s - %s\n", event.Start.UTC().Format(time.RFC1123), event.End.UTC().Format(time.RFC1123))
+	if event.Location != "" {
+		fmt.Fprintf(&b, "Location: %s\n", event.Location)
+	}
+	if len(event.Attendees) > 0 {
+		fmt.Fprintf(&b, "Attendees: %s\n", strings.Join(event.Attendees, ", "))
+	}
+	if event.Description != "" {
+		fmt.Fprintf(&b, "\n%s\n", event.Description)
+	}
+	return b.String()
+}
@@ -0,0 +1,298 @@
+// Package citations syncs citations extracted from research blobs to a
+// Zotero library or a BibTeX bibliography blob and renders them in the
+// citation style chosen for the research template
+package citations
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Author is a citation author. Literal holds organization names and any
+// name that could not be split.
+type Author struct {
+	Given   string
+	Family  string
+	Literal string
+}
+
+// Name returns the author's display name
+func (a Author) Name() string {
+	if a.Literal != "" {
+		return a.Literal
+	}
+	return strings.TrimSpace(a.Given + " " + a.Family)
+}
+
+// Citation is a bibliographic reference
+type Citation struct {
+	Type      string // article, book, inproceedings, thesis, webpage, misc
+	Title     string
+	Authors   []Author
+	Year      int
+	Container string // journal, proceedings, or website title
+	Publisher string
+	Volume    string
+	Issue     string
+	Pages     string
+	DOI       string
+	URL       string
+}
+
+// Identity returns the key used to recognize the same work across runs:
+// its DOI when known, otherwise its normalized title
+func (c Citation) Identity() string {
+	if c.DOI != "" {
+		return "doi:" + strings.ToLower(c.DOI)
+	}
+	return "title:" + normalize(c.Title)
+}
+
+// Key returns a BibTeX cite key such as smith2020deep
+func (c Citation) Key() string {
+	var b strings.Builder
+	if len(c.Authors) > 0 {
+		name := c.Authors[0].Family
+		if name == "" {
+			name = c.Authors[0].Literal
+		}
+		b.WriteString(keyPart(strings.Fields(name + " anon")[0]))
+	} else {
+		b.WriteString("anon")
+	}
+	if c.Year > 0 {
+		b.WriteString(strconv.Itoa(c.Year))
+	}
+	for _, word := range strings.Fields(c.Title) {
+		if word = keyPart(word); len(word) > 3 {
+			b.WriteString(word)
+			break
+		}
+	}
+	return b.String()
+}
+
+// Map converts the citation to the form stored in blob metadata and step
+// output; FromMap reads it back
+func (c Citation) Map() map[string]interface{} {
+	authors := make([]interface{}, len(c.Authors))
+	for i, author := range c.Authors {
+		if author.Literal != "" {
+			authors[i] = map[string]interface{}{"literal": author.Literal}
+		} else {
+			authors[i] = map[string]interface{}{"given": author.Given, "family": author.Family}
+		}
+	}
+	m := map[string]interface{}{
+		"type":    c.Type,
+		"title":   c.Title,
+		"authors": authors,
+		"key":     c.Key(),
+	}
+	for name, value := range map[string]string{
+		"container": c.Container,
+		"publisher": c.Publisher,
+		"volume":    c.Volume,
+		"issue":     c.Issue,
+		"pages":     c.Pages,
+		"doi":       c.DOI,
+		"url":       c.URL,
+	} {
+		if value != "" {
+			m[name] = value
+		}
+	}
+	if c.Year > 0 {
+		m["year"] = c.Year
+	}
+	return m
+}
+
+// merge fills fields missing from c with values from other
+func (c Citation) merge(other Citation) Citation {
+	fill := func(dst *string, src string) {
+		if *dst == "" {
+			*dst = src
+		}
+	}
+	fill(&c.Type, other.Type)
+	fill(&c.Container, other.Container)
+	fill(&c.Publisher, other.Publisher)
+	fill(&c.Volume, other.Volume)
+	fill(&c.Issue, other.Issue)
+	fill(&c.Pages, other.Pages)
+	fill(&c.DOI, other.DOI)
+	fill(&c.URL, other.URL)
+	if len(c.Authors) == 0 {
+		c.Authors = other.Authors
+	}
+	if c.Year == 0 {
+		c.Year = other.Year
+	}
+	return c
+}
+
+// Parse reads citations from a citation extractor's output. It accepts a
+// list of citation maps or a map holding one under "citations" or
+// "references". Entries without a title are skipped.
+func Parse(value interface{}) []Citation {
+	if m, ok := value.(map[string]interface{}); ok {
+		for _, field := range []string{"citations", "references", "entries"} {
+			if list, ok := m[field]; ok {
+				return Parse(list)
+			}
+		}
+		if c, ok := FromMap(m); ok {
+			return []Citation{c}
+		}
+		return nil
+	}
+
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil
+	}
+	var citations []Citation
+	for _, item := range list {
+		if m, ok := item.(map[string]interface{}); ok {
+			if c, ok := FromMap(m); ok {
+				citations = append(citations, c)
+			}
+		}
+	}
+	return citations
+}
+
+// FromMap reads one citation, accepting the common field names used by
+// extractors and CSL-JSON
+func FromMap(m map[string]interface{}) (Citation, bool) {
+	c := Citation{
+		Type:      strings.ToLower(str(m, "type", "entry_type")),
+		Title:     strings.TrimSpace(str(m, "title")),
+		Container: str(m, "container", "journal", "venue", "container-title", "booktitle", "publication"),
+		Publisher: str(m, "publisher"),
+		Volume:    str(m, "volume"),
+		Issue:     str(m, "issue", "number"),
+		Pages:     str(m, "pages", "page"),
+		DOI:       strings.TrimPrefix(strings.TrimPrefix(str(m, "doi", "DOI"), "https://doi.org/"), "doi:"),
+		URL:       str(m, "url", "URL"),
+	}
+	if c.Title == "" {
+		return c, false
+	}
+
+	switch year := firstOf(m, "year", "date").(type) {
+	case float64:
+		c.Year = int(year)
+	case int:
+		c.Year = year
+	case string:
+		if len(year) >= 4 {
+			c.Year, _ = strconv.Atoi(year[:4])
+		}
+	}
+
+	switch authors := firstOf(m, "authors", "author").(type) {
+	case string:
+		for _, name := range splitAuthors(authors) {
+			c.Authors = append(c.Authors, parseName(name))
+		}
+	case []interface{}:
+		for _, item := range authors {
+			switch a := item.(type) {
+			case string:
+				c.Authors = append(c.Authors, parseName(a))
+			case map[string]interface{}:
+				author := Author{
+					Given:   str(a, "given", "first", "firstName"),
+					Family:  str(a, "family", "last", "lastName"),
+					Literal: str(a, "literal", "name"),
+				}
+				if author.Literal != "" && author.Family == "" {
+					author = parseName(author.Literal)
+				}
+				c.Authors = append(c.Authors, author)
+			}
+		}
+	}
+
+	return c, true
+}
+
+// parseName splits "Family, Given" or "Given Family"
+func parseName(name string) Author {
+	name = strings.TrimSpace(name)
+	if family, given, ok := strings.Cut(name, ","); ok {
+		return Author{Given: strings.TrimSpace(given), Family: strings.TrimSpace(family)}
+	}
+	fields := strings.Fields(name)
+	if len(fields) < 2 {
+		return Author{Literal: name}
+	}
+	return Author{
+		Given:  strings.Join(fields[:len(fields)-1], " "),
+		Family: fields[len(fields)-1],
+	}
+}
+
+// splitAuthors splits an author string on " and " or semicolons
+func splitAuthors(authors string) []string {
+	var names []string
+	for _, part := range strings.Split(authors, ";") {
+		for _, name := range strings.Split(part, " and ") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+func firstOf(m map[string]interface{}, keys ...string) interface{} {
+	for _, key := range keys {
+		if value, ok := m[key]; ok && value != nil {
+			return value
+		}
+	}
+	return nil
+}
+
+func str(m map[string]interface{}, keys ...string) string {
+	switch v := firstOf(m, keys...).(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int:
+		return strconv.Itoa(v)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// normalize lowercases text and keeps only letters and digits
+func normalize(text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// keyPart keeps the ASCII letters and digits of a word, since BibTeX keys
+// cannot contain other characters
+func keyPart(word string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(word) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
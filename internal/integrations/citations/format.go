@@ -0,0 +1,411 @@
+package citations
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Formats accepted by Format, matching the research template's
+// citation_format variable
+const (
+	FormatAPA     = "apa"
+	FormatMLA     = "mla"
+	FormatChicago = "chicago"
+	FormatIEEE    = "ieee"
+	FormatBibTeX  = "bibtex"
+)
+
+// Format renders citations as a bibliography in a citation style. Author-
+// based styles are sorted by author and year; IEEE keeps the given order
+// because entries are numbered by first citation.
+func Format(citations []Citation, format string) (string, error) {
+	var render func(Citation) string
+	switch strings.ToLower(format) {
+	case "", FormatAPA:
+		render = formatAPA
+	case FormatMLA:
+		render = formatMLA
+	case FormatChicago:
+		render = formatChicago
+	case FormatIEEE:
+		entries := make([]string, len(citations))
+		for i, c := range citations {
+			entries[i] = fmt.Sprintf("[%d] %s", i+1, formatIEEE(c))
+		}
+		return strings.Join(entries, "\n"), nil
+	case FormatBibTeX:
+		return BibTeX(citations), nil
+	default:
+		return "", fmt.Errorf("unsupported citation format %q", format)
+	}
+
+	sorted := make([]Citation, len(citations))
+	copy(sorted, citations)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sortName(sorted[i]), sortName(sorted[j])
+		if a != b {
+			return a < b
+		}
+		return sorted[i].Year < sorted[j].Year
+	})
+
+	entries := make([]string, len(sorted))
+	for i, c := range sorted {
+		entries[i] = render(c)
+	}
+	return strings.Join(entries, "\n"), nil
+}
+
+// formatAPA renders an APA 7 reference
+func formatAPA(c Citation) string {
+	names := make([]string, len(c.Authors))
+	for i, a := range c.Authors {
+		names[i] = familyInitials(a)
+	}
+
+	var b strings.Builder
+	if len(names) > 0 {
+		b.WriteString(joinNames(names, ", ", ", & "))
+		b.WriteString(" ")
+	}
+	b.WriteString("(" + yearOrND(c) + "). ")
+	b.WriteString(sentence(c.Title))
+	if c.Container != "" {
+		b.WriteString(" " + c.Container)
+		if c.Volume != "" {
+			b.WriteString(", " + c.Volume)
+			if c.Issue != "" {
+				b.WriteString("(" + c.Issue + ")")
+			}
+		}
+		if c.Pages != "" {
+			b.WriteString(", " + c.Pages)
+		}
+		b.WriteString(".")
+	} else if c.Publisher != "" {
+		b.WriteString(" " + sentence(c.Publisher))
+	}
+	b.WriteString(link(c))
+	return b.String()
+}
+
+// formatMLA renders an MLA 9 works-cited entry
+func formatMLA(c Citation) string {
+	var b strings.Builder
+	switch len(c.Authors) {
+	case 0:
+	case 1:
+		b.WriteString(sentence(familyFirst(c.Authors[0])) + " ")
+	case 2:
+		b.WriteString(familyFirst(c.Authors[0]) + ", and " + sentence(c.Authors[1].Name()) + " ")
+	default:
+		b.WriteString(familyFirst(c.Authors[0]) + ", et al. ")
+	}
+
+	var parts []string
+	if c.Container != "" {
+		parts = append(parts, c.Container)
+	}
+	if c.Volume != "" {
+		parts = append(parts, "vol. "+c.Volume)
+	}
+	if c.Issue != "" {
+		parts = append(parts, "no. "+c.Issue)
+	}
+	if c.Container == "" && c.Publisher != "" {
+		parts = append(parts, c.Publisher)
+	}
+	if c.Year > 0 {
+		parts = append(parts, strconv.Itoa(c.Year))
+	}
+	if c.Pages != "" {
+		parts = append(parts, "pp. "+c.Pages)
+	}
+
+	if c.Container != "" {
+		b.WriteString(`"` + sentence(c.Title) + `"`)
+	} else {
+		b.WriteString(sentence(c.Title))
+	}
+	if len(parts) > 0 {
+		b.WriteString(" " + strings.Join(parts, ", ") + ".")
+	}
+	b.WriteString(link(c))
+	return b.String()
+}
+
+// formatChicago renders a Chicago author-date reference
+func formatChicago(c Citation) string {
+	names := make([]string, len(c.Authors))
+	for i, a := range c.Authors {
+		if i == 0 {
+			names[i] = familyFirst(a)
+		} else {
+			names[i] = a.Name()
+		}
+	}
+
+	var b strings.Builder
+	if len(names) > 0 {
+		b.WriteString(sentence(joinNames(names, ", ", ", and ")) + " ")
+	}
+	b.WriteString(yearOrND(c) + ". ")
+	if c.Container != "" {
+		b.WriteString(`"` + sentence(c.Title) + `" ` + c.Container)
+		if c.Volume != "" {
+			b.WriteString(" " + c.Volume)
+		}
+		if c.Issue != "" {
+			b.WriteString(" (" + c.Issue + ")")
+		}
+		if c.Pages != "" {
+			b.WriteString(": " + c.Pages)
+		}
+		b.WriteString(".")
+	} else {
+		b.WriteString(sentence(c.Title))
+		if c.Publisher != "" {
+			b.WriteString(" " + sentence(c.Publisher))
+		}
+	}
+	b.WriteString(link(c))
+	return b.String()
+}
+
+// formatIEEE renders an IEEE reference without its number
+func formatIEEE(c Citation) string {
+	names := make([]string, len(c.Authors))
+	for i, a := range c.Authors {
+		names[i] = initialsFamily(a)
+	}
+
+	var parts []string
+	if len(names) > 0 {
+		parts = append(parts, joinNames(names, ", ", ", and "))
+	}
+	parts = append(parts, `"`+strings.TrimSuffix(c.Title, ".")+`,"`)
+
+	var details []string
+	if c.Container != "" {
+		details = append(details, c.Container)
+	} else if c.Publisher != "" {
+		details = append(details, c.Publisher)
+	}
+	if c.Volume != "" {
+		details = append(details, "vol. "+c.Volume)
+	}
+	if c.Issue != "" {
+		details = append(details, "no. "+c.Issue)
+	}
+	if c.Pages != "" {
+		details = append(details, "pp. "+c.Pages)
+	}
+	if c.Year > 0 {
+		details = append(details, strconv.Itoa(c.Year))
+	}
+
+	entry := strings.Join(parts, ", ")
+	if len(details) > 0 {
+		entry += " " + strings.Join(details, ", ")
+	}
+	entry = strings.TrimSuffix(entry, ",") + "."
+	if c.DOI != "" {
+		entry += " doi: " + c.DOI + "."
+	} else if c.URL != "" {
+		entry += " [Online]. Available: " + c.URL
+	}
+	return entry
+}
+
+// BibTeX renders citations as BibTeX entries
+func BibTeX(citations []Citation) string {
+	entries := make([]string, len(citations))
+	keys := make(map[string]int)
+	for i, c := range citations {
+		key := c.Key()
+		if n := keys[key]; n > 0 {
+			// Later entries with a colliding key get a, b, ... suffixes
+			key += string(rune('a' + n - 1))
+		}
+		keys[c.Key()]++
+		entries[i] = bibtexEntry(c, key)
+	}
+	return strings.Join(entries, "\n\n") + "\n"
+}
+
+func bibtexEntry(c Citation, key string) string {
+	entryType := "misc"
+	container := "howpublished"
+	switch c.Type {
+	case "article", "journal", "journal-article", "journalarticle":
+		entryType, container = "article", "journal"
+	case "book":
+		entryType = "book"
+	case "inproceedings", "conference", "conferencepaper", "paper-conference":
+		entryType, container = "inproceedings", "booktitle"
+	case "thesis", "phdthesis":
+		entryType, container = "phdthesis", "school"
+	default:
+		if c.Container != "" {
+			entryType, container = "article", "journal"
+		}
+	}
+
+	names := make([]string, len(c.Authors))
+	for i, a := range c.Authors {
+		if a.Literal != "" {
+			names[i] = "{" + escapeBibTeX(a.Literal) + "}"
+		} else {
+			names[i] = escapeBibTeX(strings.TrimSuffix(a.Family+", "+a.Given, ", "))
+		}
+	}
+
+	fields := [][2]string{
+		{"title", c.Title},
+		{"author", strings.Join(names, " and ")},
+		{container, c.Container},
+		{"publisher", c.Publisher},
+		{"volume", c.Volume},
+		{"number", c.Issue},
+		{"pages", pageRange(c.Pages)},
+		{"doi", c.DOI},
+		{"url", c.URL},
+	}
+	if c.Year > 0 {
+		fields = append(fields, [2]string{"year", strconv.Itoa(c.Year)})
+	}
+
+	var b strings.Builder
+	b.WriteString("@" + entryType + "{" + key)
+	for _, field := range fields {
+		if field[1] == "" {
+			continue
+		}
+		value := field[1]
+		switch field[0] {
+		case "author", "doi", "url":
+			// Names are escaped individually; identifiers are verbatim
+		default:
+			value = escapeBibTeX(value)
+		}
+		b.WriteString(",\n  " + field[0] + " = {" + value + "}")
+	}
+	b.WriteString("\n}")
+	return b.String()
+}
+
+var bibtexEscaper = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	"&", `\&`,
+	"%", `\%`,
+	"$", `\$`,
+	"#", `\#`,
+	"_", `\_`,
+	"{", `\{`,
+	"}", `\}`,
+)
+
+// pageRange writes a page range with the en dash BibTeX expects
+func pageRange(pages string) string {
+	if strings.Contains(pages, "--") {
+		return pages
+	}
+	return strings.Replace(pages, "-", "--", 1)
+}
+
+// escapeBibTeX escapes LaTeX special characters
+func escapeBibTeX(value string) string {
+	return bibtexEscaper.Replace(value)
+}
+
+// familyInitials renders "Smith, J. A."
+func familyInitials(a Author) string {
+	if a.Literal != "" {
+		return a.Literal
+	}
+	if initials := initials(a.Given); initials != "" {
+		return a.Family + ", " + initials
+	}
+	return a.Family
+}
+
+// initialsFamily renders "J. A. Smith"
+func initialsFamily(a Author) string {
+	if a.Literal != "" {
+		return a.Literal
+	}
+	return strings.TrimSpace(initials(a.Given) + " " + a.Family)
+}
+
+// familyFirst renders "Smith, John"
+func familyFirst(a Author) string {
+	if a.Literal != "" || a.Given == "" {
+		return a.Name()
+	}
+	return a.Family + ", " + a.Given
+}
+
+func initials(given string) string {
+	var parts []string
+	for _, name := range strings.Fields(given) {
+		for _, piece := range strings.Split(name, "-") {
+			if r := []rune(strings.TrimSuffix(piece, ".")); len(r) > 0 {
+				parts = append(parts, string(r[0])+".")
+			}
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// joinNames joins names with sep, using last before the final name
+func joinNames(names []string, sep, last string) string {
+	switch len(names) {
+	case 0:
+		return ""
+	case 1:
+		return names[0]
+	case 2:
+		return names[0] + strings.TrimPrefix(last, ",")
+	}
+	return strings.Join(names[:len(names)-1], sep) + last + names[len(names)-1]
+}
+
+func sortName(c Citation) string {
+	if len(c.Authors) == 0 {
+		return strings.ToLower(c.Title)
+	}
+	a := c.Authors[0]
+	if a.Literal != "" {
+		return strings.ToLower(a.Literal)
+	}
+	return strings.ToLower(a.Family + " " + a.Given)
+}
+
+func yearOrND(c Citation) string {
+	if c.Year > 0 {
+		return strconv.Itoa(c.Year)
+	}
+	return "n.d."
+}
+
+// sentence ends text with a period unless it already has terminal
+// punctuation
+func sentence(text string) string {
+	text = strings.TrimSpace(text)
+	if text == "" || strings.ContainsAny(text[len(text)-1:], ".?!") {
+		return text
+	}
+	return text + "."
+}
+
+func link(c Citation) string {
+	if c.DOI != "" {
+		return " https://doi.org/" + c.DOI
+	}
+	if c.URL != "" {
+		return " " + c.URL
+	}
+	return ""
+}
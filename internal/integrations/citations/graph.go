@@ -0,0 +1,314 @@
+package citations
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/memmieai/memmie-studio/internal/blob"
+)
+
+// Graph node kinds
+const (
+	NodeDocument = "document" // a research blob in the topic
+	NodeWork     = "work"     // a cited work that is not itself in the topic
+	NodeAuthor   = "author"
+)
+
+// Graph edge kinds
+const (
+	EdgeCites    = "cites"    // document -> document or work
+	EdgeAuthored = "authored" // author -> document or work
+	EdgeCoCited  = "co_cited" // between two sources cited by the same documents
+)
+
+// DefaultMinCoCitations is how many documents must cite a pair of sources
+// together before the pair gets a co-citation edge
+const DefaultMinCoCitations = 2
+
+// ErrNodeNotFound is returned for graph nodes that do not exist
+var ErrNodeNotFound = errors.New("citation graph node not found")
+
+// Node is a citation graph vertex
+type Node struct {
+	ID        string `json:"id"`
+	Kind      string `json:"kind"`
+	Label     string `json:"label"`
+	BlobID    string `json:"blob_id,omitempty"`
+	DOI       string `json:"doi,omitempty"`
+	URL       string `json:"url,omitempty"`
+	Year      int    `json:"year,omitempty"`
+	Container string `json:"container,omitempty"`
+	CitedBy   int    `json:"cited_by,omitempty"` // documents in the topic citing this source
+	Degree    int    `json:"degree"`
+}
+
+// Edge is a citation graph edge. Co-citation edges are undirected; Weight
+// counts the documents citing both ends.
+type Edge struct {
+	ID     string `json:"id"`
+	Kind   string `json:"kind"`
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Weight int    `json:"weight"`
+}
+
+// Graph is the citation graph of a research topic
+type Graph struct {
+	TopicID string    `json:"topic_id"`
+	Nodes   []Node    `json:"nodes"`
+	Edges   []Edge    `json:"edges"`
+	BuiltAt time.Time `json:"built_at"`
+
+	index map[string]int
+}
+
+// GraphOptions tunes graph building
+type GraphOptions struct {
+	Authors        bool // add author nodes and authored edges
+	MinCoCitations int  // 0 leaves out co-citation edges
+}
+
+// Node returns a node by ID
+func (g *Graph) Node(id string) (Node, bool) {
+	i, ok := g.index[id]
+	if !ok {
+		return Node{}, false
+	}
+	return g.Nodes[i], true
+}
+
+// Neighborhood returns a node's edges and the nodes at their other ends
+func (g *Graph) Neighborhood(id string) (Node, []Edge, []Node, error) {
+	node, ok := g.Node(id)
+	if !ok {
+		return Node{}, nil, nil, fmt.Errorf("%w: %s", ErrNodeNotFound, id)
+	}
+	edges := []Edge{}
+	neighbors := []Node{}
+	seen := make(map[string]bool)
+	for _, e := range g.Edges {
+		other := ""
+		switch id {
+		case e.Source:
+			other = e.Target
+		case e.Target:
+			other = e.Source
+		default:
+			continue
+		}
+		edges = append(edges, e)
+		if !seen[other] {
+			seen[other] = true
+			neighbor, _ := g.Node(other)
+			neighbors = append(neighbors, neighbor)
+		}
+	}
+	return node, edges, neighbors, nil
+}
+
+// GraphBuilder aggregates the citations recorded on a topic's research
+// blobs into a graph
+type GraphBuilder struct {
+	blobs blob.Store
+}
+
+// NewGraphBuilder creates a graph builder
+func NewGraphBuilder(blobs blob.Store) *GraphBuilder {
+	return &GraphBuilder{blobs: blobs}
+}
+
+// Build reads every research blob in the topic's namespace and builds its
+// citation graph
+func (b *GraphBuilder) Build(ctx context.Context, userID, topicID string, opts GraphOptions) (*Graph, error) {
+	blobs, err := b.blobs.ListBlobs(ctx, userID, blob.Filter{NamespaceID: topicID})
+	if err != nil && !errors.Is(err, blob.ErrNotFound) {
+		return nil, fmt.Errorf("failed to list topic blobs: %w", err)
+	}
+	graph := BuildGraph(blobs, opts)
+	graph.TopicID = topicID
+	return graph, nil
+}
+
+// BuildGraph builds a citation graph from research blobs. A cited work that
+// matches one of the blobs by DOI or title points at the blob's document
+// node, so papers in the topic that cite each other are linked directly.
+// Bibliography blobs are skipped.
+func BuildGraph(blobs []*blob.Blob, opts GraphOptions) *Graph {
+	g := &Graph{Nodes: []Node{}, Edges: []Edge{}, BuiltAt: time.Now(), index: make(map[string]int)}
+
+	// Documents first, so citations can resolve to them
+	documents := make(map[string]string) // citation identity -> node ID
+	var sources []*blob.Blob
+	for _, b := range blobs {
+		if b.Metadata["kind"] == KindBibliography {
+			continue
+		}
+		sources = append(sources, b)
+		node := Node{ID: NodeDocument + ":" + b.ID, Kind: NodeDocument, Label: documentLabel(b), BlobID: b.ID}
+		if self, ok := FromMap(b.Metadata); ok {
+			node.DOI, node.URL, node.Year, node.Container = self.DOI, self.URL, self.Year, self.Container
+			documents[self.Identity()] = node.ID
+			if self.DOI != "" {
+				// Also match citations that only carry the title
+				documents["title:"+normalize(self.Title)] = node.ID
+			}
+		}
+		g.add(node)
+	}
+
+	works := make(map[string]Citation) // node ID -> merged citation
+	authored := make(map[[2]string]bool)
+	cited := make(map[[2]string]int)
+	for _, b := range sources {
+		docID := NodeDocument + ":" + b.ID
+		targets := make(map[string]bool)
+		for _, c := range Parse(b.Metadata["citations"]) {
+			target := resolve(documents, c)
+			if target == docID || targets[target] {
+				continue
+			}
+			if target == "" {
+				target = NodeWork + ":" + hashID(c.Identity())
+				if existing, ok := works[target]; ok {
+					works[target] = existing.merge(c)
+				} else {
+					works[target] = c
+				}
+			}
+			targets[target] = true
+			g.Edges = append(g.Edges, Edge{Kind: EdgeCites, Source: docID, Target: target, Weight: 1})
+
+			if opts.Authors {
+				for _, a := range c.Authors {
+					authorID := NodeAuthor + ":" + hashID(authorKey(a))
+					if _, ok := g.index[authorID]; !ok {
+						g.add(Node{ID: authorID, Kind: NodeAuthor, Label: a.Name()})
+					}
+					if key := [2]string{authorID, target}; !authored[key] {
+						authored[key] = true
+						g.Edges = append(g.Edges, Edge{Kind: EdgeAuthored, Source: authorID, Target: target, Weight: 1})
+					}
+				}
+			}
+		}
+
+		if opts.MinCoCitations > 0 {
+			list := make([]string, 0, len(targets))
+			for target := range targets {
+				list = append(list, target)
+			}
+			sort.Strings(list)
+			for i := range list {
+				for j := i + 1; j < len(list); j++ {
+					cited[[2]string{list[i], list[j]}]++
+				}
+			}
+		}
+	}
+
+	for id, c := range works {
+		g.add(Node{ID: id, Kind: NodeWork, Label: c.Title, DOI: c.DOI, URL: c.URL, Year: c.Year, Container: c.Container})
+	}
+	for pair, count := range cited {
+		if count >= opts.MinCoCitations {
+			g.Edges = append(g.Edges, Edge{Kind: EdgeCoCited, Source: pair[0], Target: pair[1], Weight: count})
+		}
+	}
+
+	g.finish()
+	return g
+}
+
+// add appends a node and indexes it
+func (g *Graph) add(node Node) {
+	g.index[node.ID] = len(g.Nodes)
+	g.Nodes = append(g.Nodes, node)
+}
+
+// finish sorts nodes and edges so builds are stable, numbers the edges, and
+// counts degrees and citations
+func (g *Graph) finish() {
+	kindOrder := map[string]int{NodeDocument: 0, NodeWork: 1, NodeAuthor: 2}
+	sort.Slice(g.Nodes, func(i, j int) bool {
+		a, b := g.Nodes[i], g.Nodes[j]
+		if a.Kind != b.Kind {
+			return kindOrder[a.Kind] < kindOrder[b.Kind]
+		}
+		if a.Label != b.Label {
+			return a.Label < b.Label
+		}
+		return a.ID < b.ID
+	})
+	for i, node := range g.Nodes {
+		g.index[node.ID] = i
+	}
+
+	sort.Slice(g.Edges, func(i, j int) bool {
+		a, b := g.Edges[i], g.Edges[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		return a.Target < b.Target
+	})
+	for i := range g.Edges {
+		e := &g.Edges[i]
+		e.ID = fmt.Sprintf("e%d", i+1)
+		g.Nodes[g.index[e.Source]].Degree++
+		g.Nodes[g.index[e.Target]].Degree++
+		if e.Kind == EdgeCites {
+			g.Nodes[g.index[e.Target]].CitedBy++
+		}
+	}
+}
+
+// resolve returns the document node a citation refers to, or ""
+func resolve(documents map[string]string, c Citation) string {
+	if id, ok := documents[c.Identity()]; ok {
+		return id
+	}
+	return documents["title:"+normalize(c.Title)]
+}
+
+// documentLabel names a research blob by its title metadata or first line
+func documentLabel(b *blob.Blob) string {
+	if title, _ := b.Metadata["title"].(string); strings.TrimSpace(title) != "" {
+		return strings.TrimSpace(title)
+	}
+	line, _, _ := strings.Cut(strings.TrimSpace(b.Content), "\n")
+	line = strings.TrimSpace(strings.TrimLeft(line, "# "))
+	if runes := []rune(line); len(runes) > 80 {
+		line = string(runes[:80]) + "…"
+	}
+	if line == "" {
+		return b.ID
+	}
+	return line
+}
+
+// authorKey identifies an author by family name and first initial, so
+// "Jane Smith" and "Smith, J." are the same node
+func authorKey(a Author) string {
+	if a.Family == "" {
+		return normalize(a.Literal)
+	}
+	key := normalize(a.Family)
+	if given := normalize(a.Given); given != "" {
+		key += "," + string([]rune(given)[:1])
+	}
+	return key
+}
+
+// hashID shortens an identity to a URL-safe node ID
+func hashID(identity string) string {
+	sum := sha1.Sum([]byte(identity))
+	return hex.EncodeToString(sum[:6])
+}
@@ -0,0 +1,42 @@
+package citations
+
+import (
+	"context"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// RecordStepType is the step type of the executor that stores a research
+// blob's extracted citations on the blob itself
+const RecordStepType = "citation_record"
+
+// CitationsPath is the blob metadata path extracted citations are stored at
+const CitationsPath = "metadata.citations"
+
+// NewRecordExecutor creates the citation record executor. Step input:
+// citations (the citation extractor's output). The output is a metadata
+// delta replacing the blob's citations, so reprocessing a paper drops
+// references that are no longer extracted.
+func NewRecordExecutor() workflows.StepExecutor {
+	return workflows.StepExecutorFunc(func(ctx context.Context, req workflows.StepRequest) (map[string]interface{}, error) {
+		citations := Parse(req.Input["citations"])
+		entries := make([]interface{}, len(citations))
+		for i, c := range citations {
+			entries[i] = c.Map()
+		}
+		return map[string]interface{}{
+			"count": len(citations),
+			"deltas": []interface{}{
+				map[string]interface{}{
+					"type":      "update",
+					"path":      CitationsPath,
+					"new_value": entries,
+					"metadata": map[string]interface{}{
+						"step_id":      req.Step.ID,
+						"execution_id": req.ExecutionID,
+					},
+				},
+			},
+		}, nil
+	})
+}
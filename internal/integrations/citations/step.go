@@ -0,0 +1,188 @@
+package citations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/memmieai/memmie-studio/internal/blob"
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+const (
+	// StepType is the workflow step type handled by the citation sync executor
+	StepType = "citation_sync"
+
+	// Sync targets
+	TargetZotero = "zotero"
+	TargetBibTeX = "bibtex"
+
+	// KindBibliography marks the blob that accumulates a provider's BibTeX
+	KindBibliography = "bibliography"
+)
+
+// stepExecutor syncs extracted citations to Zotero or a bibliography blob
+type stepExecutor struct {
+	zotero *Zotero
+	blobs  blob.Store
+}
+
+// NewStepExecutor creates a citation sync executor. zotero may be nil when
+// no library is configured; blobs may be nil when BibTeX sync is not used.
+// Step inputs: citations (the citation extractor's output), target (zotero
+// or bibtex, default bibtex), format (apa, mla, chicago, ieee, or bibtex)
+// for the rendered bibliography, and collection for a Zotero collection key.
+func NewStepExecutor(zotero *Zotero, blobs blob.Store) workflows.StepExecutor {
+	return &stepExecutor{zotero: zotero, blobs: blobs}
+}
+
+// Execute syncs the step's citations
+func (e *stepExecutor) Execute(ctx context.Context, req workflows.StepRequest) (map[string]interface{}, error) {
+	citations := Parse(req.Input["citations"])
+	format, _ := req.Input["format"].(string)
+	if format == "" {
+		format = FormatAPA
+	}
+	target, _ := req.Input["target"].(string)
+	if target == "" {
+		target = TargetBibTeX
+	}
+
+	output := map[string]interface{}{
+		"target": target,
+		"format": format,
+		"count":  len(citations),
+	}
+
+	switch target {
+	case TargetZotero:
+		if e.zotero == nil {
+			return nil, fmt.Errorf("zotero is not configured")
+		}
+		collection, _ := req.Input["collection"].(string)
+		result, err := e.zotero.Sync(ctx, citations, collection)
+		if err != nil {
+			return nil, err
+		}
+		keys := make([]interface{}, len(result.Keys))
+		for i, key := range result.Keys {
+			keys[i] = key
+		}
+		output["created"] = result.Created
+		output["updated"] = result.Updated
+		output["unchanged"] = result.Unchanged
+		output["item_keys"] = keys
+
+	case TargetBibTeX:
+		if e.blobs == nil {
+			return nil, fmt.Errorf("bibtex sync requires a blob store")
+		}
+		b, added, total, err := e.syncBibliography(ctx, req, citations)
+		if err != nil {
+			return nil, err
+		}
+		output["blob_id"] = b.ID
+		output["created"] = added
+		output["total"] = total
+
+	default:
+		return nil, fmt.Errorf("unknown citation target %q", target)
+	}
+
+	bibliography, err := Format(citations, format)
+	if err != nil {
+		return nil, err
+	}
+	output["bibliography"] = bibliography
+
+	entries := make([]interface{}, len(citations))
+	for i, c := range citations {
+		entries[i] = c.Map()
+	}
+	output["citations"] = entries
+
+	return output, nil
+}
+
+// syncBibliography merges citations into the provider's bibliography blob,
+// creating it on first use. Entries are keyed by DOI or title, so
+// reprocessing a paper fills in missing fields instead of duplicating it.
+// It returns the blob, how many entries were new, and the entry total.
+func (e *stepExecutor) syncBibliography(ctx context.Context, req workflows.StepRequest, citations []Citation) (*blob.Blob, int, int, error) {
+	existing, err := e.findBibliography(ctx, req.Context.UserID, req.Context.ProviderID)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	var entries []Citation
+	if existing != nil {
+		entries = Parse(existing.Metadata["entries"])
+	}
+	index := make(map[string]int, len(entries))
+	for i, c := range entries {
+		index[c.Identity()] = i
+	}
+
+	added := 0
+	for _, c := range citations {
+		if i, ok := index[c.Identity()]; ok {
+			entries[i] = entries[i].merge(c)
+			continue
+		}
+		index[c.Identity()] = len(entries)
+		entries = append(entries, c)
+		added++
+	}
+
+	stored := make([]interface{}, len(entries))
+	for i, c := range entries {
+		stored[i] = c.Map()
+	}
+	metadata := map[string]interface{}{
+		"kind":           KindBibliography,
+		"format":         FormatBibTeX,
+		"entries":        stored,
+		"last_source_id": req.Context.BlobID,
+		"execution_id":   req.ExecutionID,
+	}
+
+	if existing == nil {
+		created, err := e.blobs.CreateBlob(ctx, &blob.Blob{
+			UserID:     req.Context.UserID,
+			ProviderID: req.Context.ProviderID,
+			Content:    BibTeX(entries),
+			Metadata:   metadata,
+		})
+		if err != nil {
+			return nil, 0, 0, fmt.Errorf("failed to create bibliography blob: %w", err)
+		}
+		return created, added, len(entries), nil
+	}
+
+	existing.Content = BibTeX(entries)
+	if existing.Metadata == nil {
+		existing.Metadata = make(map[string]interface{})
+	}
+	for name, value := range metadata {
+		existing.Metadata[name] = value
+	}
+	updated, err := e.blobs.UpdateBlob(ctx, existing)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to update bibliography blob: %w", err)
+	}
+	return updated, added, len(entries), nil
+}
+
+// findBibliography returns the provider's bibliography blob, or nil
+func (e *stepExecutor) findBibliography(ctx context.Context, userID, providerID string) (*blob.Blob, error) {
+	blobs, err := e.blobs.ListBlobs(ctx, userID, blob.Filter{ProviderID: providerID})
+	if err != nil && !errors.Is(err, blob.ErrNotFound) {
+		return nil, fmt.Errorf("failed to list blobs: %w", err)
+	}
+	for _, b := range blobs {
+		if b.Metadata["kind"] == KindBibliography {
+			return b, nil
+		}
+	}
+	return nil, nil
+}
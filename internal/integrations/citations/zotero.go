@@ -0,0 +1,307 @@
+package citations
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// zoteroBatchSize is the most items the Zotero API accepts per write
+	zoteroBatchSize = 50
+
+	// zoteroTag marks items created by the sync
+	zoteroTag = "memmie"
+)
+
+// Zotero is a client for one Zotero user or group library
+type Zotero struct {
+	apiKey     string
+	baseURL    string
+	library    string
+	httpClient *http.Client
+}
+
+// NewZotero creates a client for a library. libraryType is "user" or
+// "group".
+func NewZotero(apiKey, libraryType, libraryID string) *Zotero {
+	return &Zotero{
+		apiKey:  apiKey,
+		baseURL: "https://api.zotero.org",
+		library: "/" + libraryType + "s/" + url.PathEscape(libraryID),
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// WithBaseURL points the client at a different API host
+func (z *Zotero) WithBaseURL(baseURL string) *Zotero {
+	z.baseURL = strings.TrimSuffix(baseURL, "/")
+	return z
+}
+
+// SyncResult counts the outcome of a Zotero sync
+type SyncResult struct {
+	Created   int
+	Updated   int
+	Unchanged int
+	Keys      []string // Zotero item keys in citation order
+}
+
+// Sync creates library items for new citations and updates items whose
+// fields changed. Existing items are matched by DOI or title within the
+// collection, or the whole library when collection is empty.
+func (z *Zotero) Sync(ctx context.Context, citations []Citation, collection string) (*SyncResult, error) {
+	existing, err := z.listItems(ctx, collection)
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[string]zoteroItem)
+	for _, item := range existing {
+		index[item.identity()] = item
+		if title, _ := item.Data["title"].(string); title != "" {
+			index["title:"+normalize(title)] = item
+		}
+	}
+
+	result := &SyncResult{Keys: make([]string, len(citations))}
+	var pending []map[string]interface{}
+	var pendingAt []int
+
+	for i, c := range citations {
+		data := itemData(c)
+		item, ok := index[c.Identity()]
+		if !ok {
+			item, ok = index["title:"+normalize(c.Title)]
+		}
+		if !ok {
+			if collection != "" {
+				data["collections"] = []string{collection}
+			}
+			data["tags"] = []map[string]string{{"tag": zoteroTag}}
+			pending = append(pending, data)
+			pendingAt = append(pendingAt, i)
+			continue
+		}
+
+		result.Keys[i] = item.Key
+		changes := item.changes(data)
+		if len(changes) == 0 {
+			result.Unchanged++
+			continue
+		}
+		if err := z.updateItem(ctx, item, changes); err != nil {
+			return nil, err
+		}
+		result.Updated++
+	}
+
+	for start := 0; start < len(pending); start += zoteroBatchSize {
+		end := start + zoteroBatchSize
+		if end > len(pending) {
+			end = len(pending)
+		}
+		keys, err := z.createItems(ctx, pending[start:end])
+		if err != nil {
+			return nil, err
+		}
+		for j, key := range keys {
+			result.Keys[pendingAt[start+j]] = key
+		}
+		result.Created += len(keys)
+	}
+
+	return result, nil
+}
+
+// zoteroItem is an item as returned by the API
+type zoteroItem struct {
+	Key     string                 `json:"key"`
+	Version int                    `json:"version"`
+	Data    map[string]interface{} `json:"data"`
+}
+
+func (item zoteroItem) identity() string {
+	if doi, _ := item.Data["DOI"].(string); doi != "" {
+		return "doi:" + strings.ToLower(doi)
+	}
+	title, _ := item.Data["title"].(string)
+	return "title:" + normalize(title)
+}
+
+// changes returns the fields of data that differ from the item. Fields the
+// item lacks entirely are skipped since Zotero rejects fields that do not
+// belong to an item's type.
+func (item zoteroItem) changes(data map[string]interface{}) map[string]interface{} {
+	changes := make(map[string]interface{})
+	for field, value := range data {
+		if field == "itemType" || field == "creators" {
+			continue
+		}
+		current, ok := item.Data[field]
+		if !ok {
+			continue
+		}
+		if s, _ := value.(string); s != "" && current != s {
+			changes[field] = s
+		}
+	}
+	return changes
+}
+
+// itemData maps a citation to Zotero item fields
+func itemData(c Citation) map[string]interface{} {
+	itemType, containerField := "journalArticle", "publicationTitle"
+	switch c.Type {
+	case "book":
+		itemType, containerField = "book", "series"
+	case "inproceedings", "conference", "conferencepaper", "paper-conference":
+		itemType, containerField = "conferencePaper", "proceedingsTitle"
+	case "webpage", "web", "website":
+		itemType, containerField = "webpage", "websiteTitle"
+	case "thesis", "phdthesis":
+		itemType, containerField = "thesis", "university"
+	}
+
+	creators := make([]map[string]string, len(c.Authors))
+	for i, a := range c.Authors {
+		if a.Literal != "" {
+			creators[i] = map[string]string{"creatorType": "author", "name": a.Literal}
+		} else {
+			creators[i] = map[string]string{"creatorType": "author", "firstName": a.Given, "lastName": a.Family}
+		}
+	}
+
+	data := map[string]interface{}{
+		"itemType": itemType,
+		"title":    c.Title,
+		"creators": creators,
+		"url":      c.URL,
+	}
+	if c.Year > 0 {
+		data["date"] = strconv.Itoa(c.Year)
+	}
+	if c.Container != "" {
+		data[containerField] = c.Container
+	}
+	switch itemType {
+	case "journalArticle":
+		data["DOI"] = c.DOI
+		data["volume"] = c.Volume
+		data["issue"] = c.Issue
+		data["pages"] = c.Pages
+	case "conferencePaper":
+		data["DOI"] = c.DOI
+		data["pages"] = c.Pages
+		data["publisher"] = c.Publisher
+	case "book":
+		data["publisher"] = c.Publisher
+		data["volume"] = c.Volume
+	}
+	return data
+}
+
+// listItems pages through top-level items in a collection or the library
+func (z *Zotero) listItems(ctx context.Context, collection string) ([]zoteroItem, error) {
+	path := z.library + "/items/top"
+	if collection != "" {
+		path = z.library + "/collections/" + url.PathEscape(collection) + "/items/top"
+	}
+
+	var items []zoteroItem
+	for start := 0; ; start += 100 {
+		var page []zoteroItem
+		query := url.Values{"format": {"json"}, "limit": {"100"}, "start": {strconv.Itoa(start)}}
+		if err := z.do(ctx, "GET", path+"?"+query.Encode(), nil, nil, &page); err != nil {
+			return nil, fmt.Errorf("failed to list zotero items: %w", err)
+		}
+		items = append(items, page...)
+		if len(page) < 100 {
+			return items, nil
+		}
+	}
+}
+
+// createItems writes a batch of new items and returns their keys in order
+func (z *Zotero) createItems(ctx context.Context, items []map[string]interface{}) ([]string, error) {
+	var result struct {
+		Successful map[string]zoteroItem `json:"successful"`
+		Failed     map[string]struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"failed"`
+	}
+	if err := z.do(ctx, "POST", z.library+"/items", items, nil, &result); err != nil {
+		return nil, fmt.Errorf("failed to create zotero items: %w", err)
+	}
+	for index, failure := range result.Failed {
+		return nil, fmt.Errorf("zotero rejected item %s: %s (%d)", index, failure.Message, failure.Code)
+	}
+
+	keys := make([]string, len(items))
+	for i := range items {
+		keys[i] = result.Successful[strconv.Itoa(i)].Key
+	}
+	return keys, nil
+}
+
+// updateItem patches changed fields, guarded by the item version so edits
+// made in Zotero since the listing are not overwritten
+func (z *Zotero) updateItem(ctx context.Context, item zoteroItem, changes map[string]interface{}) error {
+	headers := map[string]string{"If-Unmodified-Since-Version": strconv.Itoa(item.Version)}
+	if err := z.do(ctx, "PATCH", z.library+"/items/"+url.PathEscape(item.Key), changes, headers, nil); err != nil {
+		return fmt.Errorf("failed to update zotero item %s: %w", item.Key, err)
+	}
+	return nil
+}
+
+// do sends an API request and decodes the JSON response into out
+func (z *Zotero) do(ctx context.Context, method, path string, payload interface{}, headers map[string]string, out interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, z.baseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Zotero-API-Key", z.apiKey)
+	req.Header.Set("Zotero-API-Version", "3")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := z.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("zotero request failed (status %d): %s", resp.StatusCode, detail)
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+// Package email delivers workflow output such as reports and chapter
+// summaries by email through SMTP or Amazon SES
+package email
+
+import (
+	"context"
+	"fmt"
+)
+
+// Message is an email to send
+type Message struct {
+	From    string
+	To      []string
+	Cc      []string
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// Sender delivers messages
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// validate checks a message has the fields every backend needs
+func (m Message) validate() error {
+	if m.From == "" {
+		return fmt.Errorf("from address is required")
+	}
+	if len(m.To) == 0 {
+		return fmt.Errorf("at least one recipient is required")
+	}
+	if m.HTML == "" && m.Text == "" {
+		return fmt.Errorf("message body is empty")
+	}
+	return nil
+}
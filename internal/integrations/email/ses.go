@@ -0,0 +1,98 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/memmieai/memmie-studio/internal/awsauth"
+)
+
+// SESSender sends mail through the Amazon SES v2 API
+type SESSender struct {
+	region     string
+	creds      awsauth.Credentials
+	endpoint   string
+	httpClient *http.Client
+}
+
+// NewSESSender creates an SES sender for a region
+func NewSESSender(region string, creds awsauth.Credentials) *SESSender {
+	return &SESSender{
+		region:   region,
+		creds:    creds,
+		endpoint: fmt.Sprintf("https://email.%s.amazonaws.com", region),
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// WithEndpoint overrides the SES endpoint
+func (s *SESSender) WithEndpoint(endpoint string) *SESSender {
+	s.endpoint = endpoint
+	return s
+}
+
+// sesContent is an SES text content object
+type sesContent struct {
+	Data    string `json:"Data"`
+	Charset string `json:"Charset"`
+}
+
+// Send delivers a message
+func (s *SESSender) Send(ctx context.Context, msg Message) error {
+	if err := msg.validate(); err != nil {
+		return err
+	}
+
+	body := map[string]interface{}{}
+	if msg.HTML != "" {
+		body["Html"] = sesContent{Data: msg.HTML, Charset: "UTF-8"}
+	}
+	if msg.Text != "" {
+		body["Text"] = sesContent{Data: msg.Text, Charset: "UTF-8"}
+	}
+
+	destination := map[string]interface{}{"ToAddresses": msg.To}
+	if len(msg.Cc) > 0 {
+		destination["CcAddresses"] = msg.Cc
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"FromEmailAddress": msg.From,
+		"Destination":      destination,
+		"Content": map[string]interface{}{
+			"Simple": map[string]interface{}{
+				"Subject": sesContent{Data: msg.Subject, Charset: "UTF-8"},
+				"Body":    body,
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", s.endpoint+"/v2/email/outbound-emails", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	awsauth.Sign(httpReq, payload, s.creds, s.region, "ses", time.Now())
+
+	resp, err := s.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("ses error (status %d): %s", resp.StatusCode, detail)
+	}
+	return nil
+}
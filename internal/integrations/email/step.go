@@ -0,0 +1,101 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// StepType is the workflow step type handled by the email executor
+const StepType = "email"
+
+// NewStepExecutor creates a step executor that emails rendered output.
+// Step inputs: to and cc (address or list), subject (text template),
+// template (report, chapter_summary, data_quality, message, or a custom
+// name), html (inline body template overriding template), text (optional
+// plain text part), from (optional override) and any other fields, which
+// are available to the templates.
+func NewStepExecutor(sender Sender, renderer *Renderer, defaultFrom string) workflows.StepExecutor {
+	return workflows.StepExecutorFunc(func(ctx context.Context, req workflows.StepRequest) (map[string]interface{}, error) {
+		data := map[string]interface{}{
+			"execution_id": req.ExecutionID,
+			"workflow_id":  req.WorkflowID,
+			"step_id":      req.Step.ID,
+			"user_id":      req.Context.UserID,
+			"blob_id":      req.Context.BlobID,
+			"provider_id":  req.Context.ProviderID,
+		}
+		for k, v := range req.Input {
+			data[k] = v
+		}
+
+		to := addresses(req.Input["to"])
+		if len(to) == 0 {
+			return nil, fmt.Errorf("email step %s has no recipients", req.Step.ID)
+		}
+
+		name, _ := req.Input["template"].(string)
+		if name == "" {
+			name = TemplateMessage
+		}
+		inline, _ := req.Input["html"].(string)
+		subject, _ := req.Input["subject"].(string)
+		if subject == "" {
+			subject = `{{or .workflow_id "Memmie Studio"}} update`
+		}
+
+		renderedSubject, html, err := renderer.Render(name, inline, subject, data)
+		if err != nil {
+			return nil, err
+		}
+
+		from, _ := req.Input["from"].(string)
+		if from == "" {
+			from = defaultFrom
+		}
+		text, _ := req.Input["text"].(string)
+
+		msg := Message{
+			From:    from,
+			To:      to,
+			Cc:      addresses(req.Input["cc"]),
+			Subject: renderedSubject,
+			HTML:    html,
+			Text:    text,
+		}
+		if err := sender.Send(ctx, msg); err != nil {
+			return nil, fmt.Errorf("failed to send email: %w", err)
+		}
+
+		return map[string]interface{}{
+			"to":       to,
+			"subject":  renderedSubject,
+			"template": name,
+		}, nil
+	})
+}
+
+// addresses normalizes a string, comma-separated string, or list of
+// addresses
+func addresses(value interface{}) []string {
+	var out []string
+	switch v := value.(type) {
+	case string:
+		for _, addr := range strings.Split(v, ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				out = append(out, addr)
+			}
+		}
+	case []string:
+		out = append(out, v...)
+	case []interface{}:
+		for _, item := range v {
+			if addr, ok := item.(string); ok && addr != "" {
+				out = append(out, addr)
+			}
+		}
+	}
+	return out
+}
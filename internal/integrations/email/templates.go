@@ -0,0 +1,132 @@
+package email
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"sync"
+	texttemplate "text/template"
+)
+
+// Built-in template names
+const (
+	TemplateMessage        = "message"
+	TemplateReport         = "report"
+	TemplateChapterSummary = "chapter_summary"
+	TemplateDataQuality    = "data_quality"
+)
+
+// layout wraps every rendered body
+const layout = `<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>{{.subject}}</title></head>
+<body style="font-family:-apple-system,Segoe UI,Helvetica,Arial,sans-serif;color:#1f2933;max-width:640px;margin:0 auto;padding:24px">
+{{template "content" .}}
+<p style="color:#7b8794;font-size:12px;margin-top:32px">Sent by Memmie Studio{{with .workflow_id}} &middot; {{.}}{{end}}</p>
+</body></html>`
+
+// DefaultTemplates are the built-in HTML body templates. Templates receive
+// the step data map, so any step input can be referenced, e.g. {{.blob_id}}.
+var DefaultTemplates = map[string]string{
+	TemplateMessage: `<p>{{.text}}</p>`,
+	TemplateReport: `<h2>{{or .title "Report"}}</h2>
+{{with .summary}}<p>{{.}}</p>{{end}}
+{{with .sections}}{{range $name, $body := .}}<h3>{{$name}}</h3><p>{{$body}}</p>{{end}}{{end}}`,
+	TemplateChapterSummary: `<h2>Chapter {{.chapter_number}}{{with .chapter_title}}: {{.}}{{end}}</h2>
+<p>{{.summary}}</p>
+{{with .word_count}}<p style="color:#52606d">{{.}} words</p>{{end}}`,
+	TemplateDataQuality: `<h2>Data quality report{{with .dataset}} for {{.}}{{end}}</h2>
+{{with .score}}<p><strong>Score:</strong> {{.}}</p>{{end}}
+{{with .issues}}<table style="border-collapse:collapse;width:100%">
+<tr><th align="left">Field</th><th align="left">Issue</th><th align="right">Rows</th></tr>
+{{range .}}<tr><td>{{.field}}</td><td>{{.issue}}</td><td align="right">{{.count}}</td></tr>{{end}}
+</table>{{else}}<p>No issues found.</p>{{end}}`,
+}
+
+// Renderer renders subjects and HTML bodies from named templates
+type Renderer struct {
+	templates map[string]*template.Template
+	mu        sync.RWMutex
+}
+
+// NewRenderer creates a renderer loaded with the default templates
+func NewRenderer() *Renderer {
+	r := &Renderer{templates: make(map[string]*template.Template)}
+	for name, body := range DefaultTemplates {
+		if err := r.SetTemplate(name, body); err != nil {
+			panic(err)
+		}
+	}
+	return r
+}
+
+// SetTemplate adds or overrides a named body template
+func (r *Renderer) SetTemplate(name, body string) error {
+	tmpl, err := parseBody(name, body)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.templates[name] = tmpl
+	return nil
+}
+
+// Render renders the subject and HTML body. When inline is non-empty it is
+// used as the body template instead of the named one.
+func (r *Renderer) Render(name, inline, subject string, data map[string]interface{}) (string, string, error) {
+	renderedSubject, err := renderSubject(subject, data)
+	if err != nil {
+		return "", "", err
+	}
+	if _, ok := data["subject"]; !ok {
+		data["subject"] = renderedSubject
+	}
+
+	var tmpl *template.Template
+	if inline != "" {
+		tmpl, err = parseBody("inline", inline)
+		if err != nil {
+			return "", "", err
+		}
+	} else {
+		r.mu.RLock()
+		tmpl = r.templates[name]
+		r.mu.RUnlock()
+		if tmpl == nil {
+			return "", "", fmt.Errorf("unknown email template %q", name)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", "", fmt.Errorf("failed to render %s template: %w", name, err)
+	}
+	return renderedSubject, buf.String(), nil
+}
+
+// parseBody parses a body template inside the shared layout
+func parseBody(name, body string) (*template.Template, error) {
+	tmpl, err := template.New(name).Parse(layout)
+	if err != nil {
+		return nil, fmt.Errorf("invalid email layout: %w", err)
+	}
+	if _, err := tmpl.New("content").Parse(body); err != nil {
+		return nil, fmt.Errorf("invalid %s template: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// renderSubject renders a subject line template
+func renderSubject(subject string, data map[string]interface{}) (string, error) {
+	tmpl, err := texttemplate.New("subject").Parse(subject)
+	if err != nil {
+		return "", fmt.Errorf("invalid subject template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render subject: %w", err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,242 @@
+// Package gdocs keeps Google Docs and blobs in sync: documents are imported
+// as blobs, Drive change notifications re-import edited documents, and AI
+// expansions are written back to the document as anchored comments
+package gdocs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	defaultDocsURL  = "https://docs.googleapis.com/v1"
+	defaultDriveURL = "https://www.googleapis.com/drive/v3"
+
+	// MimeTypeDocument is the Drive MIME type of Google Docs
+	MimeTypeDocument = "application/vnd.google-apps.document"
+)
+
+// TokenSource supplies OAuth2 access tokens
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// StaticToken is a TokenSource that always returns the same token
+type StaticToken string
+
+// Token returns the static token
+func (t StaticToken) Token(ctx context.Context) (string, error) {
+	return string(t), nil
+}
+
+// Client is a minimal Google Docs and Drive API client
+type Client struct {
+	tokens     TokenSource
+	docsURL    string
+	driveURL   string
+	httpClient *http.Client
+}
+
+// NewClient creates a client authorized by the token source
+func NewClient(tokens TokenSource) *Client {
+	return &Client{
+		tokens:   tokens,
+		docsURL:  defaultDocsURL,
+		driveURL: defaultDriveURL,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// WithBaseURLs overrides the Docs and Drive API base URLs
+func (c *Client) WithBaseURLs(docsURL, driveURL string) *Client {
+	c.docsURL = strings.TrimSuffix(docsURL, "/")
+	c.driveURL = strings.TrimSuffix(driveURL, "/")
+	return c
+}
+
+// Document is the subset of a Docs API document we read
+type Document struct {
+	DocumentID string `json:"documentId"`
+	Title      string `json:"title"`
+	RevisionID string `json:"revisionId"`
+	Body       struct {
+		Content []StructuralElement `json:"content"`
+	} `json:"body"`
+}
+
+// StructuralElement is a paragraph, table, or other body element
+type StructuralElement struct {
+	Paragraph *struct {
+		Elements []struct {
+			TextRun *struct {
+				Content string `json:"content"`
+			} `json:"textRun"`
+		} `json:"elements"`
+	} `json:"paragraph"`
+	Table *struct {
+		TableRows []struct {
+			TableCells []struct {
+				Content []StructuralElement `json:"content"`
+			} `json:"tableCells"`
+		} `json:"tableRows"`
+	} `json:"table"`
+}
+
+// Text returns the document's plain text
+func (d *Document) Text() string {
+	var b strings.Builder
+	writeText(&b, d.Body.Content)
+	return b.String()
+}
+
+// writeText appends the text of structural elements, descending into tables
+func writeText(b *strings.Builder, elements []StructuralElement) {
+	for _, element := range elements {
+		if element.Paragraph != nil {
+			for _, e := range element.Paragraph.Elements {
+				if e.TextRun != nil {
+					b.WriteString(e.TextRun.Content)
+				}
+			}
+		}
+		if element.Table != nil {
+			for _, row := range element.Table.TableRows {
+				for _, cell := range row.TableCells {
+					writeText(b, cell.Content)
+				}
+			}
+		}
+	}
+}
+
+// Change is a Drive change entry
+type Change struct {
+	FileID  string `json:"fileId"`
+	Removed bool   `json:"removed"`
+	File    *struct {
+		Name     string `json:"name"`
+		MimeType string `json:"mimeType"`
+		Trashed  bool   `json:"trashed"`
+	} `json:"file"`
+}
+
+// ChangePage is one page of the Drive changes feed
+type ChangePage struct {
+	Changes           []Change `json:"changes"`
+	NextPageToken     string   `json:"nextPageToken"`
+	NewStartPageToken string   `json:"newStartPageToken"`
+}
+
+// Comment is an anchored Drive comment
+type Comment struct {
+	ID      string `json:"id"`
+	Content string `json:"content"`
+	Quoted  string `json:"-"`
+}
+
+// GetDocument fetches a document
+func (c *Client) GetDocument(ctx context.Context, documentID string) (*Document, error) {
+	var doc Document
+	endpoint := fmt.Sprintf("%s/documents/%s", c.docsURL, url.PathEscape(documentID))
+	if err := c.do(ctx, "GET", endpoint, nil, &doc); err != nil {
+		return nil, fmt.Errorf("failed to get document %s: %w", documentID, err)
+	}
+	return &doc, nil
+}
+
+// GetStartPageToken returns the token for changes made from now on
+func (c *Client) GetStartPageToken(ctx context.Context) (string, error) {
+	var result struct {
+		StartPageToken string `json:"startPageToken"`
+	}
+	if err := c.do(ctx, "GET", c.driveURL+"/changes/startPageToken", nil, &result); err != nil {
+		return "", fmt.Errorf("failed to get start page token: %w", err)
+	}
+	return result.StartPageToken, nil
+}
+
+// ListChanges returns one page of changes since pageToken
+func (c *Client) ListChanges(ctx context.Context, pageToken string) (*ChangePage, error) {
+	query := url.Values{}
+	query.Set("pageToken", pageToken)
+	query.Set("fields", "nextPageToken,newStartPageToken,changes(fileId,removed,file(name,mimeType,trashed))")
+
+	var page ChangePage
+	if err := c.do(ctx, "GET", c.driveURL+"/changes?"+query.Encode(), nil, &page); err != nil {
+		return nil, fmt.Errorf("failed to list changes: %w", err)
+	}
+	return &page, nil
+}
+
+// CreateComment adds a comment to a file, anchored to quoted text when set.
+// The Docs API cannot create suggestions, so anchored comments are how
+// proposed edits are surfaced to the author.
+func (c *Client) CreateComment(ctx context.Context, fileID string, comment Comment) (*Comment, error) {
+	payload := map[string]interface{}{
+		"content": comment.Content,
+	}
+	if comment.Quoted != "" {
+		payload["quotedFileContent"] = map[string]string{
+			"mimeType": "text/plain",
+			"value":    comment.Quoted,
+		}
+	}
+
+	var result Comment
+	endpoint := fmt.Sprintf("%s/files/%s/comments?fields=id,content", c.driveURL, url.PathEscape(fileID))
+	if err := c.do(ctx, "POST", endpoint, payload, &result); err != nil {
+		return nil, fmt.Errorf("failed to comment on %s: %w", fileID, err)
+	}
+	result.Quoted = comment.Quoted
+	return &result, nil
+}
+
+// do sends an authorized request and decodes the JSON response into out
+func (c *Client) do(ctx context.Context, method, endpoint string, payload, out interface{}) error {
+	token, err := c.tokens.Token(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get access token: %w", err)
+	}
+
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+token)
+	if payload != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
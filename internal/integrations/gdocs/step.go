@@ -0,0 +1,68 @@
+package gdocs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// StepType is the workflow step type that writes suggestions back to a doc
+const StepType = "gdocs_suggest"
+
+// NewStepExecutor creates a step executor that writes AI expansions back to
+// the source document as comments. Step inputs: document_id and suggestions,
+// either a string or a list whose items are strings or maps with text and an
+// optional quote to anchor the comment to.
+func NewStepExecutor(client *Client) workflows.StepExecutor {
+	return workflows.StepExecutorFunc(func(ctx context.Context, req workflows.StepRequest) (map[string]interface{}, error) {
+		documentID, _ := req.Input["document_id"].(string)
+		if documentID == "" {
+			return nil, fmt.Errorf("document_id is required")
+		}
+
+		comments := toComments(req.Input["suggestions"])
+		if len(comments) == 0 {
+			return map[string]interface{}{"comment_ids": []interface{}{}}, nil
+		}
+
+		ids := make([]interface{}, 0, len(comments))
+		for _, comment := range comments {
+			created, err := client.CreateComment(ctx, documentID, comment)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, created.ID)
+		}
+
+		return map[string]interface{}{
+			"document_id": documentID,
+			"comment_ids": ids,
+		}, nil
+	})
+}
+
+// toComments normalizes step suggestions into comments
+func toComments(value interface{}) []Comment {
+	switch v := value.(type) {
+	case string:
+		if v == "" {
+			return nil
+		}
+		return []Comment{{Content: v}}
+	case map[string]interface{}:
+		text, _ := v["text"].(string)
+		if text == "" {
+			return nil
+		}
+		quote, _ := v["quote"].(string)
+		return []Comment{{Content: text, Quoted: quote}}
+	case []interface{}:
+		var comments []Comment
+		for _, item := range v {
+			comments = append(comments, toComments(item)...)
+		}
+		return comments
+	}
+	return nil
+}
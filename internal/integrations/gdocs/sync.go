@@ -0,0 +1,250 @@
+package gdocs
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/memmieai/memmie-studio/internal/blob"
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// SourceGoogleDocs marks blobs imported from Google Docs in their metadata
+const SourceGoogleDocs = "gdocs"
+
+// SyncConfig sets who owns synced blobs and which provider they belong to
+type SyncConfig struct {
+	UserID     string
+	ProviderID string
+}
+
+// Syncer imports documents as blobs and follows the Drive changes feed to
+// re-import documents when they are edited
+type Syncer struct {
+	client    *Client
+	blobs     blob.Store
+	processor workflows.BlobProcessor
+	config    SyncConfig
+	index     map[string]string // document ID -> blob ID
+	pageToken string
+	loaded    bool
+	mu        sync.Mutex
+}
+
+// NewSyncer creates a syncer
+func NewSyncer(client *Client, blobs blob.Store, processor workflows.BlobProcessor, config SyncConfig) *Syncer {
+	if config.ProviderID == "" {
+		config.ProviderID = SourceGoogleDocs
+	}
+	return &Syncer{
+		client:    client,
+		blobs:     blobs,
+		processor: processor,
+		config:    config,
+		index:     make(map[string]string),
+	}
+}
+
+// PageToken returns the current changes feed position so callers can
+// persist it across restarts
+func (s *Syncer) PageToken() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pageToken
+}
+
+// SetPageToken resumes the changes feed from a persisted position
+func (s *Syncer) SetPageToken(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pageToken = token
+}
+
+// ImportDocument creates or refreshes the blob for a document and triggers
+// processing when its content changed. Documents whose revision matches the
+// blob are skipped, which also stops our own write-backs from looping.
+func (s *Syncer) ImportDocument(ctx context.Context, documentID string) (*blob.Blob, bool, error) {
+	if err := s.loadIndex(ctx); err != nil {
+		return nil, false, err
+	}
+
+	doc, err := s.client.GetDocument(ctx, documentID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	metadata := map[string]interface{}{
+		"source":      SourceGoogleDocs,
+		"document_id": doc.DocumentID,
+		"revision_id": doc.RevisionID,
+		"title":       doc.Title,
+	}
+
+	s.mu.Lock()
+	blobID, known := s.index[documentID]
+	s.mu.Unlock()
+
+	if known {
+		existing, err := s.blobs.GetBlob(ctx, s.config.UserID, blobID)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to get blob %s: %w", blobID, err)
+		}
+		if existing.Metadata["revision_id"] == doc.RevisionID {
+			return existing, false, nil
+		}
+
+		if existing.Metadata == nil {
+			existing.Metadata = map[string]interface{}{}
+		}
+		for k, v := range metadata {
+			existing.Metadata[k] = v
+		}
+		existing.Content = doc.Text()
+
+		updated, err := s.blobs.UpdateBlob(ctx, existing)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to update blob %s: %w", blobID, err)
+		}
+		if err := s.process(ctx, updated.ID, "onUpdate"); err != nil {
+			return updated, true, err
+		}
+		return updated, true, nil
+	}
+
+	created, err := s.blobs.CreateBlob(ctx, &blob.Blob{
+		UserID:     s.config.UserID,
+		ProviderID: s.config.ProviderID,
+		Content:    doc.Text(),
+		Metadata:   metadata,
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create blob for document %s: %w", documentID, err)
+	}
+
+	s.mu.Lock()
+	s.index[documentID] = created.ID
+	s.mu.Unlock()
+
+	if err := s.process(ctx, created.ID, "onCreate"); err != nil {
+		return created, true, err
+	}
+	return created, true, nil
+}
+
+// Poll drains the changes feed and re-imports every tracked document that
+// changed. It returns the number of blobs updated.
+func (s *Syncer) Poll(ctx context.Context) (int, error) {
+	if err := s.loadIndex(ctx); err != nil {
+		return 0, err
+	}
+
+	token := s.PageToken()
+	if token == "" {
+		start, err := s.client.GetStartPageToken(ctx)
+		if err != nil {
+			return 0, err
+		}
+		s.SetPageToken(start)
+		return 0, nil
+	}
+
+	updated := 0
+	for token != "" {
+		page, err := s.client.ListChanges(ctx, token)
+		if err != nil {
+			return updated, err
+		}
+
+		for _, change := range page.Changes {
+			if change.Removed || change.File == nil || change.File.Trashed || change.File.MimeType != MimeTypeDocument {
+				continue
+			}
+
+			s.mu.Lock()
+			_, tracked := s.index[change.FileID]
+			s.mu.Unlock()
+			if !tracked {
+				continue
+			}
+
+			_, changed, err := s.ImportDocument(ctx, change.FileID)
+			if err != nil {
+				return updated, err
+			}
+			if changed {
+				updated++
+			}
+		}
+
+		if page.NewStartPageToken != "" {
+			s.SetPageToken(page.NewStartPageToken)
+			break
+		}
+		token = page.NextPageToken
+		s.SetPageToken(token)
+	}
+
+	return updated, nil
+}
+
+// Run polls the changes feed every interval until ctx is cancelled. Poll
+// errors are passed to onError, if set, and do not stop the loop.
+func (s *Syncer) Run(ctx context.Context, interval time.Duration, onError func(error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.Poll(ctx); err != nil && onError != nil {
+			onError(fmt.Errorf("failed to sync google docs: %w", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// process triggers workflow processing when a processor is configured
+func (s *Syncer) process(ctx context.Context, blobID, eventType string) error {
+	if s.processor == nil {
+		return nil
+	}
+	if err := s.processor.ProcessBlob(ctx, blobID, s.config.UserID, eventType); err != nil {
+		return fmt.Errorf("failed to process blob %s: %w", blobID, err)
+	}
+	return nil
+}
+
+// loadIndex rebuilds the document to blob index from previously synced
+// blobs the first time it is needed
+func (s *Syncer) loadIndex(ctx context.Context) error {
+	s.mu.Lock()
+	loaded := s.loaded
+	s.mu.Unlock()
+	if loaded {
+		return nil
+	}
+
+	blobs, err := s.blobs.ListBlobs(ctx, s.config.UserID, blob.Filter{ProviderID: s.config.ProviderID})
+	if err != nil {
+		return fmt.Errorf("failed to list synced blobs: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, b := range blobs {
+		if b.Metadata["source"] != SourceGoogleDocs {
+			continue
+		}
+		if documentID, ok := b.Metadata["document_id"].(string); ok {
+			if _, exists := s.index[documentID]; !exists {
+				s.index[documentID] = b.ID
+			}
+		}
+	}
+	s.loaded = true
+	return nil
+}
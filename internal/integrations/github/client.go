@@ -0,0 +1,280 @@
+// Package github imports repository files as blobs, triggers the code
+// documentation workflow on push, and publishes generated documentation back
+// as pull requests or pull request comments
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const defaultBaseURL = "https://api.github.com"
+
+// Client is a minimal GitHub REST API client
+type Client struct {
+	token      string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewClient creates a GitHub client authenticated with a token
+func NewClient(token string) *Client {
+	return &Client{
+		token:   token,
+		baseURL: defaultBaseURL,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// WithBaseURL overrides the GitHub API base URL, e.g. for GitHub Enterprise
+func (c *Client) WithBaseURL(baseURL string) *Client {
+	c.baseURL = strings.TrimSuffix(baseURL, "/")
+	return c
+}
+
+// Repository identifies a GitHub repository
+type Repository struct {
+	Owner string `json:"owner"`
+	Name  string `json:"name"`
+}
+
+// ParseRepository parses an "owner/name" string
+func ParseRepository(fullName string) (Repository, error) {
+	parts := strings.Split(fullName, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return Repository{}, fmt.Errorf("invalid repository %q, expected owner/name", fullName)
+	}
+	return Repository{Owner: parts[0], Name: parts[1]}, nil
+}
+
+// FullName returns the "owner/name" form
+func (r Repository) FullName() string {
+	return r.Owner + "/" + r.Name
+}
+
+// TreeEntry is a file or directory in a git tree
+type TreeEntry struct {
+	Path string `json:"path"`
+	Type string `json:"type"` // blob, tree, commit
+	SHA  string `json:"sha"`
+	Size int    `json:"size"`
+}
+
+// File is a decoded file from the contents API
+type File struct {
+	Path    string
+	SHA     string
+	Content string
+}
+
+// FileChange creates or updates a file on a branch
+type FileChange struct {
+	Path    string
+	Content string
+	Message string
+	Branch  string
+	SHA     string // required when updating an existing file
+}
+
+// PullRequest is a pull request to open
+type PullRequest struct {
+	Title string `json:"title"`
+	Head  string `json:"head"`
+	Base  string `json:"base"`
+	Body  string `json:"body"`
+}
+
+// PullRequestResult identifies an opened pull request
+type PullRequestResult struct {
+	Number  int    `json:"number"`
+	HTMLURL string `json:"html_url"`
+}
+
+// Comment identifies a posted issue or pull request comment
+type Comment struct {
+	ID      int64  `json:"id"`
+	HTMLURL string `json:"html_url"`
+}
+
+// StatusError is returned for non-2xx GitHub responses
+type StatusError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("github error (status %d): %s", e.StatusCode, e.Message)
+}
+
+// GetBranchSHA returns the commit SHA a branch points to
+func (c *Client) GetBranchSHA(ctx context.Context, repo Repository, branch string) (string, error) {
+	var ref struct {
+		Object struct {
+			SHA string `json:"sha"`
+		} `json:"object"`
+	}
+	if err := c.do(ctx, "GET", c.repoPath(repo, "git/ref/heads/"+branch), nil, &ref); err != nil {
+		return "", fmt.Errorf("failed to get branch %s: %w", branch, err)
+	}
+	return ref.Object.SHA, nil
+}
+
+// GetTree lists every entry in the tree at ref
+func (c *Client) GetTree(ctx context.Context, repo Repository, ref string) ([]TreeEntry, error) {
+	var tree struct {
+		Tree      []TreeEntry `json:"tree"`
+		Truncated bool        `json:"truncated"`
+	}
+	if err := c.do(ctx, "GET", c.repoPath(repo, "git/trees/"+url.PathEscape(ref)+"?recursive=1"), nil, &tree); err != nil {
+		return nil, fmt.Errorf("failed to get tree %s: %w", ref, err)
+	}
+	if tree.Truncated {
+		return nil, fmt.Errorf("tree %s is too large to list in one request", ref)
+	}
+	return tree.Tree, nil
+}
+
+// GetFile fetches and decodes a file at ref
+func (c *Client) GetFile(ctx context.Context, repo Repository, path, ref string) (*File, error) {
+	var content struct {
+		Path     string `json:"path"`
+		SHA      string `json:"sha"`
+		Content  string `json:"content"`
+		Encoding string `json:"encoding"`
+	}
+	endpoint := c.repoPath(repo, "contents/"+escapePath(path))
+	if ref != "" {
+		endpoint += "?ref=" + url.QueryEscape(ref)
+	}
+	if err := c.do(ctx, "GET", endpoint, nil, &content); err != nil {
+		return nil, fmt.Errorf("failed to get file %s: %w", path, err)
+	}
+
+	if content.Encoding != "base64" {
+		return nil, fmt.Errorf("unsupported encoding %q for file %s", content.Encoding, path)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(content.Content, "\n", ""))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode file %s: %w", path, err)
+	}
+
+	return &File{Path: content.Path, SHA: content.SHA, Content: string(decoded)}, nil
+}
+
+// CreateBranch creates a branch pointing at sha
+func (c *Client) CreateBranch(ctx context.Context, repo Repository, branch, sha string) error {
+	payload := map[string]string{
+		"ref": "refs/heads/" + branch,
+		"sha": sha,
+	}
+	if err := c.do(ctx, "POST", c.repoPath(repo, "git/refs"), payload, nil); err != nil {
+		return fmt.Errorf("failed to create branch %s: %w", branch, err)
+	}
+	return nil
+}
+
+// PutFile creates or updates a file with a single commit
+func (c *Client) PutFile(ctx context.Context, repo Repository, change FileChange) error {
+	payload := map[string]string{
+		"message": change.Message,
+		"content": base64.StdEncoding.EncodeToString([]byte(change.Content)),
+		"branch":  change.Branch,
+	}
+	if change.SHA != "" {
+		payload["sha"] = change.SHA
+	}
+	if err := c.do(ctx, "PUT", c.repoPath(repo, "contents/"+escapePath(change.Path)), payload, nil); err != nil {
+		return fmt.Errorf("failed to write file %s: %w", change.Path, err)
+	}
+	return nil
+}
+
+// CreatePullRequest opens a pull request
+func (c *Client) CreatePullRequest(ctx context.Context, repo Repository, pr PullRequest) (*PullRequestResult, error) {
+	var result PullRequestResult
+	if err := c.do(ctx, "POST", c.repoPath(repo, "pulls"), pr, &result); err != nil {
+		return nil, fmt.Errorf("failed to create pull request: %w", err)
+	}
+	return &result, nil
+}
+
+// CreateComment comments on an issue or pull request
+func (c *Client) CreateComment(ctx context.Context, repo Repository, number int, body string) (*Comment, error) {
+	var result Comment
+	payload := map[string]string{"body": body}
+	if err := c.do(ctx, "POST", c.repoPath(repo, fmt.Sprintf("issues/%d/comments", number)), payload, &result); err != nil {
+		return nil, fmt.Errorf("failed to comment on #%d: %w", number, err)
+	}
+	return &result, nil
+}
+
+// repoPath builds a /repos/{owner}/{name}/... endpoint path
+func (c *Client) repoPath(repo Repository, suffix string) string {
+	return fmt.Sprintf("/repos/%s/%s/%s", url.PathEscape(repo.Owner), url.PathEscape(repo.Name), suffix)
+}
+
+// do sends a request and decodes the JSON response into out when non-nil
+func (c *Client) do(ctx context.Context, method, path string, payload, out interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Accept", "application/vnd.github+json")
+	httpReq.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if c.token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if payload != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		return &StatusError{StatusCode: resp.StatusCode, Message: apiErr.Message}
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// escapePath escapes each segment of a repository file path
+func escapePath(path string) string {
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
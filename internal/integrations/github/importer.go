@@ -0,0 +1,270 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/memmieai/memmie-studio/internal/blob"
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// SourceGitHub marks blobs imported from GitHub in their metadata
+const SourceGitHub = "github"
+
+// ImportConfig controls which files are imported and who owns the blobs
+type ImportConfig struct {
+	UserID      string
+	ProviderID  string
+	Extensions  []string // defaults to every extension with a known language
+	MaxFileSize int      // bytes, defaults to 512KB
+}
+
+// ImportResult summarizes an import run. Created and Updated hold blob IDs,
+// Skipped holds file paths.
+type ImportResult struct {
+	Created []string `json:"created"`
+	Updated []string `json:"updated"`
+	Skipped []string `json:"skipped"`
+}
+
+// Importer pulls repository files into blobs, one blob per file, and
+// triggers processing for every blob it creates or changes
+type Importer struct {
+	client    *Client
+	blobs     blob.Store
+	processor workflows.BlobProcessor
+	config    ImportConfig
+	index     map[string]map[string]string // repo -> file path -> blob ID
+	mu        sync.Mutex
+}
+
+// NewImporter creates an importer
+func NewImporter(client *Client, blobs blob.Store, processor workflows.BlobProcessor, config ImportConfig) *Importer {
+	if config.ProviderID == "" {
+		config.ProviderID = SourceGitHub
+	}
+	if config.MaxFileSize == 0 {
+		config.MaxFileSize = 512 * 1024
+	}
+	return &Importer{
+		client:    client,
+		blobs:     blobs,
+		processor: processor,
+		config:    config,
+		index:     make(map[string]map[string]string),
+	}
+}
+
+// ImportRepository imports every supported file on a branch
+func (i *Importer) ImportRepository(ctx context.Context, repo Repository, branch string) (*ImportResult, error) {
+	sha, err := i.client.GetBranchSHA(ctx, repo, branch)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := i.client.GetTree(ctx, repo, sha)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, entry := range tree {
+		if entry.Type != "blob" || entry.Size > i.config.MaxFileSize {
+			continue
+		}
+		paths = append(paths, entry.Path)
+	}
+
+	return i.ImportFiles(ctx, repo, branch, sha, paths)
+}
+
+// ImportFiles imports the given paths at a commit. Unsupported files are
+// skipped; unchanged files are skipped without reprocessing.
+func (i *Importer) ImportFiles(ctx context.Context, repo Repository, branch, sha string, paths []string) (*ImportResult, error) {
+	index, err := i.repoIndex(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{}
+	for _, filePath := range paths {
+		language := LanguageForPath(filePath)
+		if language == "" || !i.wants(filePath) {
+			result.Skipped = append(result.Skipped, filePath)
+			continue
+		}
+
+		file, err := i.client.GetFile(ctx, repo, filePath, sha)
+		if err != nil {
+			return result, err
+		}
+		if len(file.Content) > i.config.MaxFileSize {
+			result.Skipped = append(result.Skipped, filePath)
+			continue
+		}
+
+		metadata := map[string]interface{}{
+			"source":    SourceGitHub,
+			"repo":      repo.FullName(),
+			"branch":    branch,
+			"commit":    sha,
+			"file_path": filePath,
+			"file_sha":  file.SHA,
+			"language":  language,
+		}
+
+		i.mu.Lock()
+		blobID, known := index[filePath]
+		i.mu.Unlock()
+
+		if known {
+			updated, err := i.updateBlob(ctx, blobID, file, metadata)
+			if err != nil {
+				return result, err
+			}
+			if !updated {
+				result.Skipped = append(result.Skipped, filePath)
+				continue
+			}
+			result.Updated = append(result.Updated, blobID)
+			if err := i.process(ctx, blobID, "onUpdate"); err != nil {
+				return result, err
+			}
+			continue
+		}
+
+		created, err := i.blobs.CreateBlob(ctx, &blob.Blob{
+			UserID:     i.config.UserID,
+			ProviderID: i.config.ProviderID,
+			Content:    file.Content,
+			Metadata:   metadata,
+		})
+		if err != nil {
+			return result, fmt.Errorf("failed to create blob for %s: %w", filePath, err)
+		}
+
+		i.mu.Lock()
+		index[filePath] = created.ID
+		i.mu.Unlock()
+
+		result.Created = append(result.Created, created.ID)
+		if err := i.process(ctx, created.ID, "onCreate"); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// updateBlob refreshes an imported blob, reporting whether the file changed
+func (i *Importer) updateBlob(ctx context.Context, blobID string, file *File, metadata map[string]interface{}) (bool, error) {
+	existing, err := i.blobs.GetBlob(ctx, i.config.UserID, blobID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get blob %s: %w", blobID, err)
+	}
+	if existing.Metadata["file_sha"] == file.SHA {
+		return false, nil
+	}
+
+	if existing.Metadata == nil {
+		existing.Metadata = map[string]interface{}{}
+	}
+	for k, v := range metadata {
+		existing.Metadata[k] = v
+	}
+	existing.Content = file.Content
+
+	if _, err := i.blobs.UpdateBlob(ctx, existing); err != nil {
+		return false, fmt.Errorf("failed to update blob %s: %w", blobID, err)
+	}
+	return true, nil
+}
+
+// process triggers workflow processing when a processor is configured
+func (i *Importer) process(ctx context.Context, blobID, eventType string) error {
+	if i.processor == nil {
+		return nil
+	}
+	if err := i.processor.ProcessBlob(ctx, blobID, i.config.UserID, eventType); err != nil {
+		return fmt.Errorf("failed to process blob %s: %w", blobID, err)
+	}
+	return nil
+}
+
+// repoIndex returns the path to blob ID index for a repository, loading it
+// from previously imported blobs on first use
+func (i *Importer) repoIndex(ctx context.Context, repo Repository) (map[string]string, error) {
+	i.mu.Lock()
+	index, ok := i.index[repo.FullName()]
+	i.mu.Unlock()
+	if ok {
+		return index, nil
+	}
+
+	blobs, err := i.blobs.ListBlobs(ctx, i.config.UserID, blob.Filter{ProviderID: i.config.ProviderID})
+	if err != nil && !errors.Is(err, blob.ErrNotFound) {
+		return nil, fmt.Errorf("failed to list imported blobs: %w", err)
+	}
+
+	index = make(map[string]string)
+	for _, b := range blobs {
+		if b.Metadata["source"] != SourceGitHub || b.Metadata["repo"] != repo.FullName() {
+			continue
+		}
+		if filePath, ok := b.Metadata["file_path"].(string); ok {
+			index[filePath] = b.ID
+		}
+	}
+
+	i.mu.Lock()
+	i.index[repo.FullName()] = index
+	i.mu.Unlock()
+	return index, nil
+}
+
+// wants reports whether a path passes the configured extension filter
+func (i *Importer) wants(filePath string) bool {
+	if len(i.config.Extensions) == 0 {
+		return true
+	}
+	ext := strings.ToLower(path.Ext(filePath))
+	for _, allowed := range i.config.Extensions {
+		if strings.ToLower(allowed) == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// languages maps file extensions to the language names the code
+// documentation workflow expects
+var languages = map[string]string{
+	".go":    "go",
+	".py":    "python",
+	".js":    "javascript",
+	".jsx":   "javascript",
+	".ts":    "typescript",
+	".tsx":   "typescript",
+	".java":  "java",
+	".kt":    "kotlin",
+	".rb":    "ruby",
+	".rs":    "rust",
+	".c":     "c",
+	".h":     "c",
+	".cc":    "cpp",
+	".cpp":   "cpp",
+	".hpp":   "cpp",
+	".cs":    "csharp",
+	".swift": "swift",
+	".php":   "php",
+	".scala": "scala",
+}
+
+// LanguageForPath returns the language for a file path, or "" if unknown
+func LanguageForPath(filePath string) string {
+	return languages[strings.ToLower(path.Ext(filePath))]
+}
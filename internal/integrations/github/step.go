@@ -0,0 +1,215 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// StepType is the workflow step type handled by the GitHub publish executor
+const StepType = "github_publish"
+
+// Publish modes
+const (
+	ModePullRequest = "pull_request"
+	ModeComment     = "comment"
+)
+
+// Publisher writes generated documentation back to a repository
+type Publisher struct {
+	client  *Client
+	docsDir string
+}
+
+// NewPublisher creates a publisher that writes docs under docsDir
+func NewPublisher(client *Client, docsDir string) *Publisher {
+	if docsDir == "" {
+		docsDir = "docs"
+	}
+	return &Publisher{client: client, docsDir: docsDir}
+}
+
+// DocsPath returns where the documentation for a source file is written
+func (p *Publisher) DocsPath(filePath string) string {
+	return path.Join(p.docsDir, filePath+".md")
+}
+
+// OpenPullRequest commits files to a new branch cut from base and opens a
+// pull request. An existing branch with the same name is reused.
+func (p *Publisher) OpenPullRequest(ctx context.Context, repo Repository, base, branch string, files map[string]string, pr PullRequest) (*PullRequestResult, error) {
+	sha, err := p.client.GetBranchSHA(ctx, repo, base)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.client.CreateBranch(ctx, repo, branch, sha); err != nil {
+		var statusErr *StatusError
+		if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusUnprocessableEntity {
+			return nil, err
+		}
+	}
+
+	for filePath, content := range files {
+		change := FileChange{
+			Path:    filePath,
+			Content: content,
+			Message: fmt.Sprintf("docs: update %s", filePath),
+			Branch:  branch,
+		}
+
+		existing, err := p.client.GetFile(ctx, repo, filePath, branch)
+		if err == nil {
+			if existing.Content == content {
+				continue
+			}
+			change.SHA = existing.SHA
+		} else {
+			var statusErr *StatusError
+			if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+				return nil, err
+			}
+		}
+
+		if err := p.client.PutFile(ctx, repo, change); err != nil {
+			return nil, err
+		}
+	}
+
+	pr.Head = branch
+	pr.Base = base
+	return p.client.CreatePullRequest(ctx, repo, pr)
+}
+
+// NewStepExecutor creates a step executor that publishes documentation.
+// Step inputs: repo (owner/name), branch (base branch), file_path (source
+// file the docs describe), docs (string, or a map with markdown/content), mode
+// (pull_request or comment, default pull_request) and pull_number (required
+// for comment mode).
+func NewStepExecutor(publisher *Publisher) workflows.StepExecutor {
+	return workflows.StepExecutorFunc(func(ctx context.Context, req workflows.StepRequest) (map[string]interface{}, error) {
+		repoName, _ := req.Input["repo"].(string)
+		repo, err := ParseRepository(repoName)
+		if err != nil {
+			return nil, err
+		}
+
+		docs := renderDocs(req.Input["docs"])
+		if docs == "" {
+			return nil, fmt.Errorf("no documentation to publish for blob %s", req.Context.BlobID)
+		}
+
+		filePath, _ := req.Input["file_path"].(string)
+		mode, _ := req.Input["mode"].(string)
+		if mode == "" {
+			mode = ModePullRequest
+		}
+
+		switch mode {
+		case ModeComment:
+			number, ok := toInt(req.Input["pull_number"])
+			if !ok {
+				return nil, fmt.Errorf("pull_number is required in comment mode")
+			}
+			body := docs
+			if filePath != "" {
+				body = fmt.Sprintf("### Documentation for `%s`\n\n%s", filePath, docs)
+			}
+			comment, err := publisher.client.CreateComment(ctx, repo, number, body)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{
+				"mode":        ModeComment,
+				"pull_number": number,
+				"url":         comment.HTMLURL,
+			}, nil
+
+		case ModePullRequest:
+			base, _ := req.Input["branch"].(string)
+			if base == "" {
+				return nil, fmt.Errorf("branch is required in pull_request mode")
+			}
+			if filePath == "" {
+				return nil, fmt.Errorf("file_path is required in pull_request mode")
+			}
+
+			docsPath := publisher.DocsPath(filePath)
+			branch := fmt.Sprintf("memmie/docs-%s", branchSlug(filePath))
+			result, err := publisher.OpenPullRequest(ctx, repo, base, branch, map[string]string{docsPath: docs}, PullRequest{
+				Title: fmt.Sprintf("docs: generated documentation for %s", filePath),
+				Body:  fmt.Sprintf("Generated by Memmie Studio (execution `%s`).", req.ExecutionID),
+			})
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{
+				"mode":        ModePullRequest,
+				"pull_number": result.Number,
+				"url":         result.HTMLURL,
+				"docs_path":   docsPath,
+			}, nil
+
+		default:
+			return nil, fmt.Errorf("unknown publish mode %q", mode)
+		}
+	})
+}
+
+// renderDocs turns a documentation step output into markdown
+func renderDocs(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case map[string]interface{}:
+		for _, key := range []string{"markdown", "content", "docs"} {
+			if s, ok := v[key].(string); ok {
+				return s
+			}
+		}
+	}
+
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return "```json\n" + string(data) + "\n```\n"
+}
+
+// branchSlug makes a file path safe for use in a branch name
+func branchSlug(filePath string) string {
+	slug := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + ('a' - 'A')
+		default:
+			return '-'
+		}
+	}, filePath)
+	return strings.Trim(slug, "-")
+}
+
+// toInt converts a JSON number or numeric value to an int
+func toInt(value interface{}) (int, bool) {
+	switch v := value.(type) {
+	case int:
+		return v, true
+	case int64:
+		return int(v), true
+	case float64:
+		return int(v), true
+	case json.Number:
+		n, err := v.Int64()
+		return int(n), err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,149 @@
+package github
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// pushEvent is the subset of a GitHub push webhook payload we read
+type pushEvent struct {
+	Ref     string `json:"ref"`
+	After   string `json:"after"`
+	Deleted bool   `json:"deleted"`
+	Commits []struct {
+		Added    []string `json:"added"`
+		Modified []string `json:"modified"`
+	} `json:"commits"`
+	Repository struct {
+		FullName      string `json:"full_name"`
+		DefaultBranch string `json:"default_branch"`
+	} `json:"repository"`
+}
+
+// WebhookHandler returns an http.Handler for GitHub webhooks. It verifies the
+// X-Hub-Signature-256 header, refusing every request when secret is empty,
+// and, for pushes to a branch, imports the added and modified files in the
+// background so the response returns promptly; import errors are passed to
+// onError, if set. When branches is non-empty only pushes to those
+// branches are imported.
+func WebhookHandler(secret string, importer *Importer, onError func(error), branches ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, 5<<20))
+		if err != nil {
+			http.Error(w, "failed to read body", http.StatusBadRequest)
+			return
+		}
+
+		if err := verifySignature(secret, r.Header.Get("X-Hub-Signature-256"), body); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		switch r.Header.Get("X-GitHub-Event") {
+		case "ping":
+			w.WriteHeader(http.StatusOK)
+			return
+		case "push":
+		default:
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		var event pushEvent
+		if err := json.Unmarshal(body, &event); err != nil {
+			http.Error(w, "invalid payload", http.StatusBadRequest)
+			return
+		}
+
+		branch := strings.TrimPrefix(event.Ref, "refs/heads/")
+		if event.Deleted || branch == event.Ref || !branchAllowed(branch, branches) {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		repo, err := ParseRepository(event.Repository.FullName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		paths := changedPaths(event)
+		if len(paths) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+			defer cancel()
+
+			if _, err := importer.ImportFiles(ctx, repo, branch, event.After, paths); err != nil && onError != nil {
+				onError(fmt.Errorf("failed to import push to %s@%s: %w", repo.FullName(), branch, err))
+			}
+		}()
+
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+// changedPaths returns the added and modified files across all commits
+func changedPaths(event pushEvent) []string {
+	seen := make(map[string]bool)
+	for _, commit := range event.Commits {
+		for _, p := range commit.Added {
+			seen[p] = true
+		}
+		for _, p := range commit.Modified {
+			seen[p] = true
+		}
+	}
+
+	paths := make([]string, 0, len(seen))
+	for p := range seen {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// branchAllowed reports whether a branch passes the optional branch filter
+func branchAllowed(branch string, branches []string) bool {
+	if len(branches) == 0 {
+		return true
+	}
+	for _, b := range branches {
+		if b == branch {
+			return true
+		}
+	}
+	return false
+}
+
+// verifySignature checks GitHub's sha256 HMAC webhook signature. Without a
+// secret anyone could sign, so every request is refused.
+func verifySignature(secret, signature string, body []byte) error {
+	if secret == "" {
+		return fmt.Errorf("no github webhook secret configured")
+	}
+	if !strings.HasPrefix(signature, "sha256=") {
+		return fmt.Errorf("missing github signature")
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return fmt.Errorf("invalid github signature")
+	}
+	return nil
+}
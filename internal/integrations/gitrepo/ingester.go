@@ -0,0 +1,281 @@
+package gitrepo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/memmieai/memmie-studio/internal/blob"
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// SourceGit marks blobs ingested from repositories in their metadata
+const SourceGit = "git"
+
+// Defaults for Request
+const (
+	DefaultBatchSize   = 10
+	DefaultMaxFileSize = 512 * 1024
+)
+
+// Request describes one ingestion run
+type Request struct {
+	Source
+	Extensions  []string `json:"extensions,omitempty"` // e.g. .go, .py; empty accepts every known language
+	Exclude     []string `json:"exclude,omitempty"`    // glob patterns or directory prefixes
+	MaxFileSize int64    `json:"max_file_size,omitempty"`
+	BatchSize   int      `json:"batch_size,omitempty"` // blobs processed concurrently
+}
+
+// Result summarizes an ingestion run. Created, Updated and Removed hold
+// blob IDs; Skipped holds file paths and Failed maps file paths to
+// processing errors.
+type Result struct {
+	Repo      string            `json:"repo"`
+	Commit    string            `json:"commit,omitempty"`
+	Files     int               `json:"files"`
+	Created   []string          `json:"created"`
+	Updated   []string          `json:"updated"`
+	Unchanged int               `json:"unchanged"`
+	Removed   []string          `json:"removed"`
+	Skipped   []string          `json:"skipped"`
+	Processed int               `json:"processed"`
+	Failed    map[string]string `json:"failed,omitempty"`
+	Batches   int               `json:"batches"`
+}
+
+// Ingester walks repositories into blobs owned by a code documentation
+// provider and triggers processing for new and changed files
+type Ingester struct {
+	blobs     blob.Store
+	processor workflows.BlobProcessor // optional
+	workDir   string
+	localRoot string
+}
+
+// NewIngester creates an ingester. Clones are kept under workDir; local
+// paths are resolved under localRoot, and are refused when it is empty.
+// processor may be nil, in which case blobs are stored but not processed.
+func NewIngester(blobs blob.Store, processor workflows.BlobProcessor, workDir, localRoot string) *Ingester {
+	return &Ingester{blobs: blobs, processor: processor, workDir: workDir, localRoot: localRoot}
+}
+
+// pending is a blob waiting to be processed
+type pending struct {
+	path   string
+	blobID string
+	event  string
+	retry  bool // marked with an earlier run's processing error
+}
+
+// Ingest ingests a repository into the provider's blobs. Files are matched
+// to earlier runs by repository and path and compared by content hash, so
+// only new and changed files are stored and processed. Blobs of files that
+// disappeared are marked removed. report is called as the run progresses,
+// with done and total counting files to process.
+func (i *Ingester) Ingest(ctx context.Context, userID, providerID string, req Request, report func(stage string, done, total int)) (*Result, error) {
+	if err := req.validate(); err != nil {
+		return nil, err
+	}
+	if req.MaxFileSize <= 0 {
+		req.MaxFileSize = DefaultMaxFileSize
+	}
+	if req.BatchSize <= 0 {
+		req.BatchSize = DefaultBatchSize
+	}
+	if report == nil {
+		report = func(string, int, int) {}
+	}
+
+	report("checking out", 0, 0)
+	root, commit, err := checkout(ctx, req.Source, i.workDir, i.localRoot)
+	if err != nil {
+		return nil, err
+	}
+	report("walking", 0, 0)
+	files, skipped, err := walk(root, walkOptions{Extensions: req.Extensions, Exclude: req.Exclude, MaxFileSize: req.MaxFileSize})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk %s: %w", req.Name(), err)
+	}
+
+	existing, err := i.existing(ctx, userID, providerID, req.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		Repo:    req.Name(),
+		Commit:  commit,
+		Files:   len(files),
+		Created: []string{},
+		Updated: []string{},
+		Removed: []string{},
+		Skipped: append([]string{}, skipped...),
+	}
+	report("storing", 0, len(files))
+	var queue []pending
+	var storeErr error
+	for n, f := range files {
+		sum := sha256.Sum256([]byte(f.Content))
+		metadata := map[string]interface{}{
+			"source":      SourceGit,
+			"repo":        req.Name(),
+			"branch":      req.Branch,
+			"commit":      commit,
+			"file_path":   f.Path,
+			"language":    f.Language,
+			"size":        len(f.Content),
+			"content_sha": hex.EncodeToString(sum[:]),
+		}
+
+		b, known := existing[f.Path]
+		delete(existing, f.Path)
+		switch {
+		case !known:
+			created, err := i.blobs.CreateBlob(ctx, &blob.Blob{
+				UserID:     userID,
+				ProviderID: providerID,
+				Content:    f.Content,
+				Metadata:   metadata,
+			})
+			if err != nil {
+				storeErr = fmt.Errorf("failed to create blob for %s: %w", f.Path, err)
+				break
+			}
+			result.Created = append(result.Created, created.ID)
+			queue = append(queue, pending{path: f.Path, blobID: created.ID, event: "onCreate"})
+
+		case b.Metadata["content_sha"] == metadata["content_sha"] && b.Metadata["removed"] != true:
+			result.Unchanged++
+			if _, failed := b.Metadata["processing_error"]; failed {
+				// Processing failed last run; the content is fine, try again
+				queue = append(queue, pending{path: f.Path, blobID: b.ID, event: "onUpdate", retry: true})
+			}
+
+		default:
+			for k, v := range metadata {
+				b.Metadata[k] = v
+			}
+			delete(b.Metadata, "removed")
+			delete(b.Metadata, "processing_error")
+			b.Content = f.Content
+			if _, err := i.blobs.UpdateBlob(ctx, b); err != nil {
+				storeErr = fmt.Errorf("failed to update blob %s: %w", b.ID, err)
+				break
+			}
+			result.Updated = append(result.Updated, b.ID)
+			queue = append(queue, pending{path: f.Path, blobID: b.ID, event: "onUpdate"})
+		}
+		if storeErr != nil {
+			break
+		}
+		report("storing", n+1, len(files))
+	}
+
+	// Blob stores cannot delete, so files gone from the repository are
+	// flagged instead. A run that stopped early has not seen every file.
+	for _, b := range existing {
+		if storeErr != nil {
+			break
+		}
+		if b.Metadata["removed"] == true {
+			continue
+		}
+		b.Metadata["removed"] = true
+		b.Metadata["commit"] = commit
+		if _, err := i.blobs.UpdateBlob(ctx, b); err != nil {
+			storeErr = fmt.Errorf("failed to mark blob %s removed: %w", b.ID, err)
+			break
+		}
+		result.Removed = append(result.Removed, b.ID)
+	}
+
+	// Whatever was stored is processed even when storing stopped early,
+	// since later runs will see those files as unchanged
+	i.process(ctx, userID, queue, req.BatchSize, result, report)
+	return result, storeErr
+}
+
+// process sends queued blobs to the processor in batches, waiting for each
+// batch before starting the next so a large repository does not flood the
+// workflow backend. Failures are recorded per file and on the blob, so the
+// next run retries them.
+func (i *Ingester) process(ctx context.Context, userID string, queue []pending, batchSize int, result *Result, report func(string, int, int)) {
+	if i.processor == nil || len(queue) == 0 {
+		return
+	}
+	var mu sync.Mutex
+	report("processing", 0, len(queue))
+	for start := 0; start < len(queue); start += batchSize {
+		end := start + batchSize
+		if end > len(queue) {
+			end = len(queue)
+		}
+		var wg sync.WaitGroup
+		for _, p := range queue[start:end] {
+			wg.Add(1)
+			go func(p pending) {
+				defer wg.Done()
+				err := i.processor.ProcessBlob(ctx, p.blobID, userID, p.event)
+				if err != nil || p.retry {
+					i.recordProcessing(ctx, userID, p.blobID, err)
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					if result.Failed == nil {
+						result.Failed = make(map[string]string)
+					}
+					result.Failed[p.path] = err.Error()
+					return
+				}
+				result.Processed++
+			}(p)
+		}
+		wg.Wait()
+		result.Batches++
+		report("processing", end, len(queue))
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// existing indexes the blobs of earlier runs by file path
+func (i *Ingester) existing(ctx context.Context, userID, providerID, repo string) (map[string]*blob.Blob, error) {
+	blobs, err := i.blobs.ListBlobs(ctx, userID, blob.Filter{ProviderID: providerID})
+	if err != nil && !errors.Is(err, blob.ErrNotFound) {
+		return nil, fmt.Errorf("failed to list ingested blobs: %w", err)
+	}
+	index := make(map[string]*blob.Blob)
+	for _, b := range blobs {
+		if b.Metadata["source"] != SourceGit || b.Metadata["repo"] != repo {
+			continue
+		}
+		if filePath, ok := b.Metadata["file_path"].(string); ok {
+			index[filePath] = b
+		}
+	}
+	return index, nil
+}
+
+// recordProcessing sets or clears a blob's processing error. It is best
+// effort: a blob that cannot be marked is only retried when it changes.
+func (i *Ingester) recordProcessing(ctx context.Context, userID, blobID string, err error) {
+	b, getErr := i.blobs.GetBlob(ctx, userID, blobID)
+	if getErr != nil {
+		return
+	}
+	if err != nil {
+		if b.Metadata == nil {
+			b.Metadata = make(map[string]interface{})
+		}
+		b.Metadata["processing_error"] = err.Error()
+	} else {
+		delete(b.Metadata, "processing_error")
+	}
+	i.blobs.UpdateBlob(ctx, b)
+}
@@ -0,0 +1,174 @@
+package gitrepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Job statuses
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// jobRetention is how long finished jobs stay queryable
+const jobRetention = 24 * time.Hour
+
+var (
+	// ErrJobNotFound is returned when an ingestion job does not exist
+	ErrJobNotFound = errors.New("ingestion job not found")
+
+	// ErrJobRunning is returned when the repository is already being
+	// ingested for the project
+	ErrJobRunning = errors.New("repository ingestion already running")
+)
+
+// Job is an ingestion run's progress and, once finished, its result
+type Job struct {
+	ID          string     `json:"id"`
+	UserID      string     `json:"user_id"`
+	ProjectID   string     `json:"project_id"`
+	Repo        string     `json:"repo"`
+	Status      string     `json:"status"`
+	Stage       string     `json:"stage"`
+	Done        int        `json:"done"`
+	Total       int        `json:"total"`
+	Result      *Result    `json:"result,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// Service runs ingestion jobs in the background. Jobs are tracked in
+// memory; the blobs they create are the durable record of each run.
+type Service struct {
+	ingester *Ingester
+	mu       sync.Mutex
+	jobs     map[string]*Job
+}
+
+// NewService creates an ingestion job service
+func NewService(ingester *Ingester) *Service {
+	return &Service{ingester: ingester, jobs: make(map[string]*Job)}
+}
+
+// ProviderID returns the code documentation provider of a project, which
+// owns its file blobs
+func ProviderID(projectID string) string {
+	return "project:" + projectID
+}
+
+// Start validates a request and queues the ingestion. The returned job is a
+// snapshot; poll Get for progress.
+func (s *Service) Start(ctx context.Context, userID, projectID string, req Request) (*Job, error) {
+	if err := req.validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune()
+	for _, job := range s.jobs {
+		if job.UserID == userID && job.ProjectID == projectID && job.Repo == req.Name() && job.CompletedAt == nil {
+			return nil, fmt.Errorf("%w: job %s", ErrJobRunning, job.ID)
+		}
+	}
+	job := &Job{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		ProjectID: projectID,
+		Repo:      req.Name(),
+		Status:    StatusQueued,
+		Stage:     "queued",
+		CreatedAt: time.Now(),
+	}
+	s.jobs[job.ID] = job
+	snapshot := *job
+
+	// The job outlives the request that started it
+	go s.run(context.Background(), job.ID, userID, projectID, req)
+	return &snapshot, nil
+}
+
+// Get returns a snapshot of a user's job
+func (s *Service) Get(userID, jobID string) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[jobID]
+	if !ok || job.UserID != userID {
+		return nil, ErrJobNotFound
+	}
+	snapshot := *job
+	return &snapshot, nil
+}
+
+// List returns snapshots of a project's jobs, newest first
+func (s *Service) List(userID, projectID string) []*Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs := []*Job{}
+	for _, job := range s.jobs {
+		if job.UserID == userID && job.ProjectID == projectID {
+			snapshot := *job
+			jobs = append(jobs, &snapshot)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+	})
+	return jobs
+}
+
+// run performs an ingestion, recording progress as it goes
+func (s *Service) run(ctx context.Context, jobID, userID, projectID string, req Request) {
+	result, err := s.ingester.Ingest(ctx, userID, ProviderID(projectID), req, func(stage string, done, total int) {
+		s.update(jobID, func(j *Job) {
+			j.Status = StatusRunning
+			j.Stage = stage
+			j.Done = done
+			j.Total = total
+		})
+	})
+
+	now := time.Now()
+	s.update(jobID, func(j *Job) {
+		j.Result = result
+		j.CompletedAt = &now
+		if err != nil {
+			j.Status = StatusFailed
+			j.Stage = "failed"
+			j.Error = err.Error()
+			return
+		}
+		j.Status = StatusCompleted
+		j.Stage = "completed"
+	})
+}
+
+// update changes a job under the lock
+func (s *Service) update(jobID string, change func(*Job)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if job, ok := s.jobs[jobID]; ok {
+		change(job)
+	}
+}
+
+// prune forgets jobs that finished more than jobRetention ago. Callers hold
+// the lock.
+func (s *Service) prune() {
+	cutoff := time.Now().Add(-jobRetention)
+	for id, job := range s.jobs {
+		if job.CompletedAt != nil && job.CompletedAt.Before(cutoff) {
+			delete(s.jobs, id)
+		}
+	}
+}
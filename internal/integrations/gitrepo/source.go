@@ -0,0 +1,144 @@
+// Package gitrepo ingests a whole code repository, cloned from a git URL or
+// read from a local directory, into one blob per source file for the code
+// documentation workflow. Re-running an ingestion only creates and
+// processes blobs for files that changed.
+package gitrepo
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ErrInvalidSource is returned for repositories that may not be ingested
+var ErrInvalidSource = errors.New("invalid repository source")
+
+// Source is a repository to ingest: a git URL to clone, or a directory
+// under the ingester's local root
+type Source struct {
+	URL    string `json:"url,omitempty"`
+	Path   string `json:"path,omitempty"`
+	Branch string `json:"branch,omitempty"` // defaults to the remote's default branch
+	Subdir string `json:"subdir,omitempty"` // only files under this directory
+}
+
+// Name identifies the repository in blob metadata
+func (s Source) Name() string {
+	if s.URL != "" {
+		return strings.TrimSuffix(strings.TrimSuffix(s.URL, "/"), ".git")
+	}
+	return filepath.ToSlash(filepath.Clean(s.Path))
+}
+
+// validate checks a source before any work is done. Only network URLs are
+// cloned, so file:// URLs cannot reach around the local root.
+func (s Source) validate() error {
+	switch {
+	case (s.URL == "") == (s.Path == ""):
+		return fmt.Errorf("%w: set exactly one of url and path", ErrInvalidSource)
+	case s.URL != "" && !remoteURL(s.URL):
+		return fmt.Errorf("%w: unsupported url %q (use https, ssh or git@host:)", ErrInvalidSource, s.URL)
+	case strings.HasPrefix(s.Branch, "-"):
+		return fmt.Errorf("%w: invalid branch %q", ErrInvalidSource, s.Branch)
+	}
+	return nil
+}
+
+func remoteURL(url string) bool {
+	for _, prefix := range []string{"https://", "http://", "ssh://", "git://", "git@"} {
+		if strings.HasPrefix(url, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkout returns the directory holding the source's files and the commit
+// they are at. Clones are kept under workDir and refreshed with a shallow
+// fetch on later runs. The commit is empty for local directories that are
+// not git checkouts.
+func checkout(ctx context.Context, src Source, workDir, localRoot string) (string, string, error) {
+	var dir string
+	if src.Path != "" {
+		if localRoot == "" {
+			return "", "", fmt.Errorf("%w: local paths are disabled", ErrInvalidSource)
+		}
+		root, err := filepath.Abs(localRoot)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to resolve local root: %w", err)
+		}
+		dir = filepath.Join(root, filepath.Clean("/"+src.Path))
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			return "", "", fmt.Errorf("%w: %s is not a directory", ErrInvalidSource, src.Path)
+		}
+	} else {
+		sum := sha1.Sum([]byte(src.URL + "#" + src.Branch))
+		dir = filepath.Join(workDir, hex.EncodeToString(sum[:8]))
+		if err := clone(ctx, src, dir); err != nil {
+			return "", "", err
+		}
+	}
+
+	commit, err := git(ctx, dir, "rev-parse", "HEAD")
+	if err != nil {
+		if src.URL != "" {
+			return "", "", err
+		}
+		commit = ""
+	}
+
+	if src.Subdir != "" {
+		dir = filepath.Join(dir, filepath.Clean("/"+src.Subdir))
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			return "", "", fmt.Errorf("%w: %s has no directory %s", ErrInvalidSource, src.Name(), src.Subdir)
+		}
+	}
+	return dir, commit, nil
+}
+
+// clone creates a shallow clone in dir, or updates the one already there
+func clone(ctx context.Context, src Source, dir string) error {
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		ref := "HEAD"
+		if src.Branch != "" {
+			ref = src.Branch
+		}
+		if _, err := git(ctx, dir, "fetch", "--depth", "1", "origin", ref); err != nil {
+			return err
+		}
+		_, err := git(ctx, dir, "reset", "--hard", "FETCH_HEAD")
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dir), 0o755); err != nil {
+		return fmt.Errorf("failed to create work directory: %w", err)
+	}
+	os.RemoveAll(dir) // a clone interrupted before .git was written
+	args := []string{"clone", "--depth", "1", "--single-branch"}
+	if src.Branch != "" {
+		args = append(args, "--branch", src.Branch)
+	}
+	_, err := git(ctx, "", append(args, "--", src.URL, dir)...)
+	return err
+}
+
+// git runs a git command and returns its trimmed output. Prompts are
+// disabled so a private repository fails instead of hanging the job.
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s failed: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
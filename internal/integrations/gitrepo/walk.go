@@ -0,0 +1,122 @@
+package gitrepo
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/memmieai/memmie-studio/internal/integrations/github"
+)
+
+// skippedDirs are dependency and build output directories never ingested
+var skippedDirs = map[string]bool{
+	"node_modules": true,
+	"vendor":       true,
+	"third_party":  true,
+	"dist":         true,
+	"build":        true,
+	"target":       true,
+	"__pycache__":  true,
+	"venv":         true,
+}
+
+// file is a source file found in the repository
+type file struct {
+	Path     string // slash-separated, relative to the repository root
+	Language string
+	Content  string
+}
+
+// walkOptions filters the files a walk returns
+type walkOptions struct {
+	Extensions  []string // empty accepts every extension with a known language
+	Exclude     []string // glob patterns or directory prefixes
+	MaxFileSize int64
+}
+
+// walk lists the repository's source files. Hidden and dependency
+// directories, files without a known language, oversized files and files
+// that are not UTF-8 text are reported as skipped.
+func walk(root string, opts walkOptions) ([]file, []string, error) {
+	var files []file
+	var skipped []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			if rel != "." && (strings.HasPrefix(d.Name(), ".") || skippedDirs[d.Name()] || excluded(rel, opts.Exclude)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || excluded(rel, opts.Exclude) {
+			return nil
+		}
+
+		language := github.LanguageForPath(rel)
+		if language == "" || !wanted(rel, opts.Extensions) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if opts.MaxFileSize > 0 && info.Size() > opts.MaxFileSize {
+			skipped = append(skipped, rel)
+			return nil
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		if !utf8.Valid(data) || bytes.IndexByte(data, 0) >= 0 {
+			skipped = append(skipped, rel)
+			return nil
+		}
+		files = append(files, file{Path: rel, Language: language, Content: string(data)})
+		return nil
+	})
+	return files, skipped, err
+}
+
+// excluded reports whether a path matches an exclude pattern, either as a
+// glob or as a directory prefix
+func excluded(rel string, patterns []string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.Trim(pattern, "/")
+		if ok, _ := path.Match(pattern, rel); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, path.Base(rel)); ok {
+			return true
+		}
+		if rel == pattern || strings.HasPrefix(rel, pattern+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// wanted reports whether a path passes the extension filter
+func wanted(rel string, extensions []string) bool {
+	if len(extensions) == 0 {
+		return true
+	}
+	ext := strings.ToLower(path.Ext(rel))
+	for _, allowed := range extensions {
+		if strings.ToLower(allowed) == ext {
+			return true
+		}
+	}
+	return false
+}
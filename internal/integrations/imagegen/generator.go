@@ -0,0 +1,207 @@
+// Package imagegen generates cover art and illustrations from prompts
+// assembled out of blob metadata and chapter summaries
+package imagegen
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"time"
+)
+
+// Request is an image generation request
+type Request struct {
+	Prompt  string
+	Size    string // e.g. 1024x1024, 1024x1792
+	Quality string // standard, hd
+	Style   string // engine-specific style preset
+}
+
+// Image is a generated image
+type Image struct {
+	Data          []byte
+	ContentType   string
+	RevisedPrompt string
+}
+
+// Generator produces an image for a prompt
+type Generator interface {
+	Generate(ctx context.Context, req Request) (*Image, error)
+}
+
+// OpenAIGenerator uses the OpenAI images API
+type OpenAIGenerator struct {
+	apiKey     string
+	baseURL    string
+	model      string
+	httpClient *http.Client
+}
+
+// NewOpenAIGenerator creates an OpenAI image generator
+func NewOpenAIGenerator(apiKey, model string) *OpenAIGenerator {
+	if model == "" {
+		model = "dall-e-3"
+	}
+	return &OpenAIGenerator{
+		apiKey:  apiKey,
+		baseURL: "https://api.openai.com/v1",
+		model:   model,
+		httpClient: &http.Client{
+			Timeout: 2 * time.Minute,
+		},
+	}
+}
+
+// Generate creates one image
+func (g *OpenAIGenerator) Generate(ctx context.Context, req Request) (*Image, error) {
+	payload := map[string]interface{}{
+		"model":           g.model,
+		"prompt":          req.Prompt,
+		"n":               1,
+		"response_format": "b64_json",
+	}
+	if req.Size != "" {
+		payload["size"] = req.Size
+	}
+	if req.Quality != "" {
+		payload["quality"] = req.Quality
+	}
+	if req.Style == "vivid" || req.Style == "natural" {
+		payload["style"] = req.Style
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", g.baseURL+"/images/generations", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+g.apiKey)
+
+	resp, err := g.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("image generation failed (status %d): %s", resp.StatusCode, detail)
+	}
+
+	var result struct {
+		Data []struct {
+			B64JSON       string `json:"b64_json"`
+			RevisedPrompt string `json:"revised_prompt"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(result.Data) == 0 {
+		return nil, fmt.Errorf("image generation returned no images")
+	}
+
+	data, err := base64.StdEncoding.DecodeString(result.Data[0].B64JSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	return &Image{Data: data, ContentType: "image/png", RevisedPrompt: result.Data[0].RevisedPrompt}, nil
+}
+
+// StabilityGenerator uses the Stability AI stable-image API
+type StabilityGenerator struct {
+	apiKey     string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewStabilityGenerator creates a Stability AI image generator
+func NewStabilityGenerator(apiKey string) *StabilityGenerator {
+	return &StabilityGenerator{
+		apiKey:  apiKey,
+		baseURL: "https://api.stability.ai/v2beta",
+		httpClient: &http.Client{
+			Timeout: 2 * time.Minute,
+		},
+	}
+}
+
+// aspectRatios maps pixel sizes to Stability aspect ratios
+var aspectRatios = map[string]string{
+	"1024x1024": "1:1",
+	"1024x1792": "9:16",
+	"1792x1024": "16:9",
+	"1024x1536": "2:3",
+	"1536x1024": "3:2",
+}
+
+// stylePresets are the style_preset values Stability accepts; other styles
+// are already part of the prompt
+var stylePresets = map[string]bool{
+	"3d-model": true, "analog-film": true, "anime": true, "cinematic": true,
+	"comic-book": true, "digital-art": true, "enhance": true, "fantasy-art": true,
+	"isometric": true, "line-art": true, "low-poly": true, "modeling-compound": true,
+	"neon-punk": true, "origami": true, "photographic": true, "pixel-art": true,
+	"tile-texture": true,
+}
+
+// Generate creates one image
+func (g *StabilityGenerator) Generate(ctx context.Context, req Request) (*Image, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	fields := map[string]string{
+		"prompt":        req.Prompt,
+		"output_format": "png",
+		"aspect_ratio":  aspectRatios[req.Size],
+	}
+	if stylePresets[req.Style] {
+		fields["style_preset"] = req.Style
+	}
+	for name, value := range fields {
+		if value == "" {
+			continue
+		}
+		if err := writer.WriteField(name, value); err != nil {
+			return nil, fmt.Errorf("failed to write field %s: %w", name, err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish form: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", g.baseURL+"/stable-image/generate/core", &body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", writer.FormDataContentType())
+	httpReq.Header.Set("Authorization", "Bearer "+g.apiKey)
+	httpReq.Header.Set("Accept", "image/*")
+
+	resp, err := g.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("image generation failed (status %d): %s", resp.StatusCode, detail)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	return &Image{Data: data, ContentType: "image/png"}, nil
+}
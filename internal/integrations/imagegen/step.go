@@ -0,0 +1,164 @@
+package imagegen
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/memmieai/memmie-studio/internal/artifact"
+	"github.com/memmieai/memmie-studio/internal/blob"
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// StepType is the workflow step type handled by the image executor
+const StepType = "image_generation"
+
+// Prompt kinds with built-in templates
+const (
+	KindCover        = "cover"
+	KindIllustration = "illustration"
+)
+
+// DefaultPrompts are the built-in prompt templates per kind. Templates
+// receive the step inputs, so any mapped metadata field can be referenced.
+var DefaultPrompts = map[string]string{
+	KindCover: `Book cover art for "{{.title}}"{{with .genre}}, a {{.}} book{{end}}.` +
+		`{{with .summary}} The story: {{.}}{{end}}` +
+		`{{with .style}} Art style: {{.}}.{{end}} Leave space for the title; do not render any text.`,
+	KindIllustration: `Illustration for chapter {{.chapter_number}}{{with .chapter_title}}, "{{.}}"{{end}}` +
+		`{{with .title}} of "{{.}}"{{end}}. Scene: {{.summary}}` +
+		`{{with .style}} Art style: {{.}}.{{end}} No text or captions.`,
+}
+
+// stepExecutor generates an image, stores it as an artifact, and records a
+// derived image blob linked to its source blob
+type stepExecutor struct {
+	generators       map[string]Generator
+	defaultGenerator string
+	artifacts        artifact.Store
+	blobs            blob.Store
+}
+
+// NewStepExecutor creates an image generation executor over named
+// generators. blobs may be nil to skip creating derived blobs. Step inputs:
+// kind (cover or illustration), prompt (used verbatim when set),
+// prompt_template (overrides the kind's template), engine, size, quality,
+// style, and any fields the template references such as title, genre,
+// summary, chapter_number and chapter_title.
+func NewStepExecutor(generators map[string]Generator, defaultGenerator string, artifacts artifact.Store, blobs blob.Store) workflows.StepExecutor {
+	return &stepExecutor{
+		generators:       generators,
+		defaultGenerator: defaultGenerator,
+		artifacts:        artifacts,
+		blobs:            blobs,
+	}
+}
+
+// Execute generates the step's image
+func (e *stepExecutor) Execute(ctx context.Context, req workflows.StepRequest) (map[string]interface{}, error) {
+	engine, _ := req.Input["engine"].(string)
+	if engine == "" {
+		engine = e.defaultGenerator
+	}
+	generator, ok := e.generators[engine]
+	if !ok {
+		return nil, fmt.Errorf("unknown image engine %q", engine)
+	}
+
+	kind, _ := req.Input["kind"].(string)
+	if kind == "" {
+		kind = KindIllustration
+	}
+	prompt, err := BuildPrompt(kind, req.Input)
+	if err != nil {
+		return nil, err
+	}
+
+	size, _ := req.Input["size"].(string)
+	quality, _ := req.Input["quality"].(string)
+	style, _ := req.Input["style"].(string)
+	if size == "" && kind == KindCover {
+		size = "1024x1792"
+	}
+
+	image, err := generator.Generate(ctx, Request{Prompt: prompt, Size: size, Quality: quality, Style: style})
+	if err != nil {
+		return nil, err
+	}
+
+	key := artifact.Key(artifact.ExtensionFor(image.ContentType), "images", req.Context.BlobID, req.ExecutionID+"-"+req.Step.ID)
+	imageURL, err := e.artifacts.Put(ctx, key, image.ContentType, image.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store image: %w", err)
+	}
+
+	output := map[string]interface{}{
+		"image_url":      imageURL,
+		"content_type":   image.ContentType,
+		"kind":           kind,
+		"engine":         engine,
+		"prompt":         prompt,
+		"revised_prompt": image.RevisedPrompt,
+	}
+
+	if e.blobs == nil {
+		return output, nil
+	}
+
+	parentID := req.Context.BlobID
+	derived, err := e.blobs.CreateBlob(ctx, &blob.Blob{
+		UserID:     req.Context.UserID,
+		ProviderID: req.Context.ProviderID,
+		Content:    imageURL,
+		ParentID:   &parentID,
+		Metadata: map[string]interface{}{
+			"kind":         "image",
+			"image_kind":   kind,
+			"derived_from": parentID,
+			"execution_id": req.ExecutionID,
+			"step_id":      req.Step.ID,
+			"image_url":    imageURL,
+			"content_type": image.ContentType,
+			"prompt":       prompt,
+			"engine":       engine,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store image blob: %w", err)
+	}
+	output["blob_id"] = derived.ID
+
+	return output, nil
+}
+
+// BuildPrompt assembles a prompt for a kind from step inputs. An explicit
+// prompt input wins, then prompt_template, then the kind's default template.
+func BuildPrompt(kind string, input map[string]interface{}) (string, error) {
+	if prompt, ok := input["prompt"].(string); ok && prompt != "" {
+		return prompt, nil
+	}
+
+	text, _ := input["prompt_template"].(string)
+	if text == "" {
+		var ok bool
+		text, ok = DefaultPrompts[kind]
+		if !ok {
+			return "", fmt.Errorf("no prompt template for image kind %q", kind)
+		}
+	}
+
+	tmpl, err := template.New(kind).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid prompt template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, input); err != nil {
+		return "", fmt.Errorf("failed to render prompt: %w", err)
+	}
+	// Missing map keys render as "<no value>"; drop them from the prompt
+	prompt := strings.ReplaceAll(buf.String(), "<no value>", "")
+	return strings.Join(strings.Fields(prompt), " "), nil
+}
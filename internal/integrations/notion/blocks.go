@@ -0,0 +1,199 @@
+package notion
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxTextLength is Notion's limit on a single rich text object
+const maxTextLength = 2000
+
+// Blocks converts markdown-ish text into Notion blocks. Headings (#, ##,
+// ###), bullets (- or *), numbered items (1.) and paragraphs are recognized.
+func Blocks(text string) []Block {
+	var blocks []Block
+	var paragraph []string
+
+	flush := func() {
+		if len(paragraph) > 0 {
+			blocks = append(blocks, textBlock("paragraph", strings.Join(paragraph, " ")))
+			paragraph = nil
+		}
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			flush()
+		case strings.HasPrefix(trimmed, "### "):
+			flush()
+			blocks = append(blocks, textBlock("heading_3", trimmed[4:]))
+		case strings.HasPrefix(trimmed, "## "):
+			flush()
+			blocks = append(blocks, textBlock("heading_2", trimmed[3:]))
+		case strings.HasPrefix(trimmed, "# "):
+			flush()
+			blocks = append(blocks, textBlock("heading_1", trimmed[2:]))
+		case strings.HasPrefix(trimmed, "- "), strings.HasPrefix(trimmed, "* "):
+			flush()
+			blocks = append(blocks, textBlock("bulleted_list_item", trimmed[2:]))
+		case numberedItem(trimmed) != "":
+			flush()
+			blocks = append(blocks, textBlock("numbered_list_item", numberedItem(trimmed)))
+		default:
+			paragraph = append(paragraph, trimmed)
+		}
+	}
+	flush()
+
+	return blocks
+}
+
+// ContentBlocks renders a step output value as blocks. Strings are parsed
+// as text, lists become bullets, and maps become a heading per key.
+func ContentBlocks(value interface{}) []Block {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		return Blocks(v)
+	case []interface{}:
+		var blocks []Block
+		for _, item := range v {
+			blocks = append(blocks, textBlock("bulleted_list_item", plainText(item)))
+		}
+		return blocks
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		var blocks []Block
+		for _, key := range keys {
+			if v[key] == nil {
+				continue
+			}
+			blocks = append(blocks, textBlock("heading_3", key))
+			blocks = append(blocks, ContentBlocks(v[key])...)
+		}
+		return blocks
+	default:
+		return Blocks(plainText(v))
+	}
+}
+
+// textBlock builds a block of the given type holding text
+func textBlock(blockType, text string) Block {
+	return Block{
+		"object": "block",
+		"type":   blockType,
+		blockType: map[string]interface{}{
+			"rich_text": richText(text),
+		},
+	}
+}
+
+// richText splits text into rich text objects within Notion's length limit
+func richText(text string) []map[string]interface{} {
+	runes := []rune(text)
+	parts := []map[string]interface{}{}
+	for start := 0; start == 0 || start < len(runes); start += maxTextLength {
+		end := start + maxTextLength
+		if end > len(runes) {
+			end = len(runes)
+		}
+		parts = append(parts, map[string]interface{}{
+			"type": "text",
+			"text": map[string]interface{}{"content": string(runes[start:end])},
+		})
+	}
+	return parts
+}
+
+// numberedItem returns the text of a "1. item" line, or "" otherwise
+func numberedItem(line string) string {
+	idx := strings.Index(line, ". ")
+	if idx <= 0 {
+		return ""
+	}
+	for _, r := range line[:idx] {
+		if r < '0' || r > '9' {
+			return ""
+		}
+	}
+	return line[idx+2:]
+}
+
+// plainText renders any value as text
+func plainText(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case map[string]interface{}, []interface{}:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(data)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// propertyValue converts a value into a Notion property value of the given
+// type, returning false for types that cannot be set from plain values
+func propertyValue(propType string, value interface{}) (interface{}, bool) {
+	switch propType {
+	case "title":
+		return map[string]interface{}{"title": richText(plainText(value))}, true
+	case "rich_text":
+		return map[string]interface{}{"rich_text": richText(plainText(value))}, true
+	case "number":
+		switch n := value.(type) {
+		case float64, int, int64:
+			return map[string]interface{}{"number": n}, true
+		}
+		return nil, false
+	case "checkbox":
+		b, ok := value.(bool)
+		return map[string]interface{}{"checkbox": b}, ok
+	case "url":
+		return map[string]interface{}{"url": plainText(value)}, true
+	case "select":
+		return map[string]interface{}{"select": map[string]string{"name": plainText(value)}}, true
+	case "multi_select":
+		var options []map[string]string
+		switch list := value.(type) {
+		case []interface{}:
+			for _, item := range list {
+				options = append(options, map[string]string{"name": plainText(item)})
+			}
+		case []string:
+			for _, item := range list {
+				options = append(options, map[string]string{"name": item})
+			}
+		default:
+			for _, item := range strings.Split(plainText(value), ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					options = append(options, map[string]string{"name": item})
+				}
+			}
+		}
+		return map[string]interface{}{"multi_select": options}, true
+	case "date":
+		switch d := value.(type) {
+		case time.Time:
+			return map[string]interface{}{"date": map[string]string{"start": d.Format(time.RFC3339)}}, true
+		case string:
+			return map[string]interface{}{"date": map[string]string{"start": d}}, true
+		}
+		return nil, false
+	}
+	return nil, false
+}
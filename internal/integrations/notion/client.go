@@ -0,0 +1,249 @@
+// Package notion exports workflow results such as summaries, outlines, and
+// research findings into Notion pages and databases
+package notion
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	defaultBaseURL = "https://api.notion.com/v1"
+	apiVersion     = "2022-06-28"
+
+	// maxBlocksPerRequest is Notion's limit on children per append call
+	maxBlocksPerRequest = 100
+)
+
+// Client is a minimal Notion API client
+type Client struct {
+	token      string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewClient creates a Notion client authenticated with an integration token
+func NewClient(token string) *Client {
+	return &Client{
+		token:   token,
+		baseURL: defaultBaseURL,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// WithBaseURL overrides the Notion API base URL
+func (c *Client) WithBaseURL(baseURL string) *Client {
+	c.baseURL = strings.TrimSuffix(baseURL, "/")
+	return c
+}
+
+// Block is a Notion block object
+type Block map[string]interface{}
+
+// Page identifies a Notion page
+type Page struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+}
+
+// Database is the subset of a database object we read
+type Database struct {
+	ID         string `json:"id"`
+	Properties map[string]struct {
+		Type string `json:"type"`
+	} `json:"properties"`
+}
+
+// childBlock is a block returned from a children listing
+type childBlock struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	ChildPage *struct {
+		Title string `json:"title"`
+	} `json:"child_page"`
+}
+
+// GetDatabase fetches a database's schema
+func (c *Client) GetDatabase(ctx context.Context, databaseID string) (*Database, error) {
+	var db Database
+	if err := c.do(ctx, "GET", "/databases/"+url.PathEscape(databaseID), nil, &db); err != nil {
+		return nil, fmt.Errorf("failed to get database %s: %w", databaseID, err)
+	}
+	return &db, nil
+}
+
+// QueryDatabase returns pages in a database matching a filter
+func (c *Client) QueryDatabase(ctx context.Context, databaseID string, filter map[string]interface{}) ([]Page, error) {
+	var result struct {
+		Results []Page `json:"results"`
+	}
+	payload := map[string]interface{}{"filter": filter, "page_size": 10}
+	if err := c.do(ctx, "POST", "/databases/"+url.PathEscape(databaseID)+"/query", payload, &result); err != nil {
+		return nil, fmt.Errorf("failed to query database %s: %w", databaseID, err)
+	}
+	return result.Results, nil
+}
+
+// CreatePage creates a page under a parent with initial content
+func (c *Client) CreatePage(ctx context.Context, parent, properties map[string]interface{}, children []Block) (*Page, error) {
+	first := children
+	var rest []Block
+	if len(children) > maxBlocksPerRequest {
+		first, rest = children[:maxBlocksPerRequest], children[maxBlocksPerRequest:]
+	}
+
+	payload := map[string]interface{}{
+		"parent":     parent,
+		"properties": properties,
+		"children":   first,
+	}
+
+	var page Page
+	if err := c.do(ctx, "POST", "/pages", payload, &page); err != nil {
+		return nil, fmt.Errorf("failed to create page: %w", err)
+	}
+
+	if len(rest) > 0 {
+		if err := c.AppendChildren(ctx, page.ID, rest); err != nil {
+			return nil, err
+		}
+	}
+	return &page, nil
+}
+
+// UpdatePageProperties updates a page's properties
+func (c *Client) UpdatePageProperties(ctx context.Context, pageID string, properties map[string]interface{}) (*Page, error) {
+	var page Page
+	payload := map[string]interface{}{"properties": properties}
+	if err := c.do(ctx, "PATCH", "/pages/"+url.PathEscape(pageID), payload, &page); err != nil {
+		return nil, fmt.Errorf("failed to update page %s: %w", pageID, err)
+	}
+	return &page, nil
+}
+
+// AppendChildren appends blocks to a page or block in batches
+func (c *Client) AppendChildren(ctx context.Context, blockID string, children []Block) error {
+	for start := 0; start < len(children); start += maxBlocksPerRequest {
+		end := start + maxBlocksPerRequest
+		if end > len(children) {
+			end = len(children)
+		}
+		payload := map[string]interface{}{"children": children[start:end]}
+		if err := c.do(ctx, "PATCH", "/blocks/"+url.PathEscape(blockID)+"/children", payload, nil); err != nil {
+			return fmt.Errorf("failed to append blocks to %s: %w", blockID, err)
+		}
+	}
+	return nil
+}
+
+// ReplaceChildren deletes a page's existing blocks and appends new ones
+func (c *Client) ReplaceChildren(ctx context.Context, pageID string, children []Block) error {
+	existing, err := c.listChildren(ctx, pageID)
+	if err != nil {
+		return err
+	}
+	for _, child := range existing {
+		if child.Type == "child_page" || child.Type == "child_database" {
+			continue
+		}
+		if err := c.do(ctx, "DELETE", "/blocks/"+url.PathEscape(child.ID), nil, nil); err != nil {
+			return fmt.Errorf("failed to delete block %s: %w", child.ID, err)
+		}
+	}
+	return c.AppendChildren(ctx, pageID, children)
+}
+
+// FindChildPage returns the child page of parentID with the given title
+func (c *Client) FindChildPage(ctx context.Context, parentID, title string) (*Page, error) {
+	children, err := c.listChildren(ctx, parentID)
+	if err != nil {
+		return nil, err
+	}
+	for _, child := range children {
+		if child.ChildPage != nil && child.ChildPage.Title == title {
+			return &Page{ID: child.ID}, nil
+		}
+	}
+	return nil, nil
+}
+
+// listChildren lists every child block, following pagination
+func (c *Client) listChildren(ctx context.Context, blockID string) ([]childBlock, error) {
+	var all []childBlock
+	cursor := ""
+	for {
+		path := "/blocks/" + url.PathEscape(blockID) + "/children?page_size=100"
+		if cursor != "" {
+			path += "&start_cursor=" + url.QueryEscape(cursor)
+		}
+
+		var page struct {
+			Results    []childBlock `json:"results"`
+			HasMore    bool         `json:"has_more"`
+			NextCursor string       `json:"next_cursor"`
+		}
+		if err := c.do(ctx, "GET", path, nil, &page); err != nil {
+			return nil, fmt.Errorf("failed to list children of %s: %w", blockID, err)
+		}
+
+		all = append(all, page.Results...)
+		if !page.HasMore {
+			return all, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// do sends a request and decodes the JSON response into out when non-nil
+func (c *Client) do(ctx context.Context, method, path string, payload, out interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+c.token)
+	httpReq.Header.Set("Notion-Version", apiVersion)
+	if payload != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		}
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		return fmt.Errorf("notion error (status %d, %s): %s", resp.StatusCode, apiErr.Code, apiErr.Message)
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
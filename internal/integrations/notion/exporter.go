@@ -0,0 +1,199 @@
+package notion
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+const (
+	// StepType is the workflow step type handled by the Notion exporter
+	StepType = "notion_export"
+
+	// DefaultKeyProperty is the rich text database property used to find
+	// pages exported by earlier runs
+	DefaultKeyProperty = "Memmie Key"
+)
+
+// Export describes content to write to Notion. Exactly one of DatabaseID
+// or ParentPageID is required. Pages are deduplicated by Key in databases and
+// by Title under parent pages, so re-running a workflow updates the page it
+// created instead of adding another.
+type Export struct {
+	DatabaseID   string
+	ParentPageID string
+	Key          string
+	Title        string
+	Properties   map[string]interface{}
+	Content      interface{}
+}
+
+// Result identifies the exported page
+type Result struct {
+	PageID  string
+	URL     string
+	Created bool
+}
+
+// Exporter writes exports to Notion
+type Exporter struct {
+	client      *Client
+	keyProperty string
+}
+
+// NewExporter creates an exporter that dedupes on keyProperty
+func NewExporter(client *Client, keyProperty string) *Exporter {
+	if keyProperty == "" {
+		keyProperty = DefaultKeyProperty
+	}
+	return &Exporter{client: client, keyProperty: keyProperty}
+}
+
+// Export creates or updates the page for an export
+func (e *Exporter) Export(ctx context.Context, export Export) (*Result, error) {
+	blocks := ContentBlocks(export.Content)
+
+	switch {
+	case export.DatabaseID != "":
+		return e.exportToDatabase(ctx, export, blocks)
+	case export.ParentPageID != "":
+		return e.exportToPage(ctx, export, blocks)
+	default:
+		return nil, fmt.Errorf("database_id or parent_page_id is required")
+	}
+}
+
+// exportToDatabase upserts a database row keyed by the export key
+func (e *Exporter) exportToDatabase(ctx context.Context, export Export, blocks []Block) (*Result, error) {
+	db, err := e.client.GetDatabase(ctx, export.DatabaseID)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]interface{}, len(export.Properties)+2)
+	for name, value := range export.Properties {
+		values[name] = value
+	}
+	for name, prop := range db.Properties {
+		if prop.Type == "title" && export.Title != "" {
+			values[name] = export.Title
+		}
+	}
+
+	_, hasKey := db.Properties[e.keyProperty]
+	if hasKey && export.Key != "" {
+		values[e.keyProperty] = export.Key
+	}
+
+	properties := make(map[string]interface{}, len(values))
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		prop, ok := db.Properties[name]
+		if !ok || values[name] == nil {
+			continue
+		}
+		if value, ok := propertyValue(prop.Type, values[name]); ok {
+			properties[name] = value
+		}
+	}
+
+	if hasKey && export.Key != "" {
+		existing, err := e.client.QueryDatabase(ctx, export.DatabaseID, map[string]interface{}{
+			"property":  e.keyProperty,
+			"rich_text": map[string]string{"equals": export.Key},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(existing) > 0 {
+			page, err := e.client.UpdatePageProperties(ctx, existing[0].ID, properties)
+			if err != nil {
+				return nil, err
+			}
+			if err := e.client.ReplaceChildren(ctx, page.ID, blocks); err != nil {
+				return nil, err
+			}
+			return &Result{PageID: page.ID, URL: page.URL}, nil
+		}
+	}
+
+	page, err := e.client.CreatePage(ctx, map[string]interface{}{"database_id": export.DatabaseID}, properties, blocks)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{PageID: page.ID, URL: page.URL, Created: true}, nil
+}
+
+// exportToPage upserts a child page matched by title
+func (e *Exporter) exportToPage(ctx context.Context, export Export, blocks []Block) (*Result, error) {
+	if export.Title == "" {
+		return nil, fmt.Errorf("title is required when exporting under a page")
+	}
+
+	existing, err := e.client.FindChildPage(ctx, export.ParentPageID, export.Title)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		if err := e.client.ReplaceChildren(ctx, existing.ID, blocks); err != nil {
+			return nil, err
+		}
+		return &Result{PageID: existing.ID}, nil
+	}
+
+	properties := map[string]interface{}{
+		"title": map[string]interface{}{"title": richText(export.Title)},
+	}
+	page, err := e.client.CreatePage(ctx, map[string]interface{}{"page_id": export.ParentPageID}, properties, blocks)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{PageID: page.ID, URL: page.URL, Created: true}, nil
+}
+
+// NewStepExecutor creates a step executor that exports content to Notion.
+// Step inputs: database_id or parent_page_id, title, content (text, list,
+// or map), properties (database property name to value, typically mapped
+// from blob metadata), kind (e.g. summary, outline, findings) and key
+// (optional dedupe key, defaulting to blob ID and kind).
+func NewStepExecutor(exporter *Exporter) workflows.StepExecutor {
+	return workflows.StepExecutorFunc(func(ctx context.Context, req workflows.StepRequest) (map[string]interface{}, error) {
+		export := Export{
+			Content: req.Input["content"],
+		}
+		export.DatabaseID, _ = req.Input["database_id"].(string)
+		export.ParentPageID, _ = req.Input["parent_page_id"].(string)
+		export.Title, _ = req.Input["title"].(string)
+		export.Properties, _ = req.Input["properties"].(map[string]interface{})
+
+		kind, _ := req.Input["kind"].(string)
+		if kind == "" {
+			kind = req.Step.ID
+		}
+		export.Key, _ = req.Input["key"].(string)
+		if export.Key == "" {
+			export.Key = fmt.Sprintf("%s:%s", req.Context.BlobID, kind)
+		}
+		if export.Title == "" {
+			export.Title = fmt.Sprintf("%s (%s)", kind, req.Context.BlobID)
+		}
+
+		result, err := exporter.Export(ctx, export)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export to notion: %w", err)
+		}
+
+		return map[string]interface{}{
+			"page_id": result.PageID,
+			"url":     result.URL,
+			"created": result.Created,
+			"key":     export.Key,
+		}, nil
+	})
+}
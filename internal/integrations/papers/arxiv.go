@@ -0,0 +1,122 @@
+package papers
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ArXiv searches the arXiv Atom API. arXiv asks clients to wait three
+// seconds between requests.
+type ArXiv struct {
+	baseURL    string
+	httpClient *http.Client
+	limiter    *limiter
+}
+
+// NewArXiv creates an arXiv source
+func NewArXiv() *ArXiv {
+	return &ArXiv{
+		baseURL: "https://export.arxiv.org/api/query",
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		limiter: newLimiter(3 * time.Second),
+	}
+}
+
+// Name returns the search engine name used in step parameters
+func (a *ArXiv) Name() string {
+	return "arxiv"
+}
+
+// Search queries arXiv, matching keywords against all fields
+func (a *ArXiv) Search(ctx context.Context, query Query, limit int) ([]Paper, error) {
+	var clauses []string
+	for _, keyword := range query.Keywords {
+		clauses = append(clauses, fmt.Sprintf("all:%q", keyword))
+	}
+	if len(clauses) == 0 && query.Title != "" {
+		clauses = append(clauses, fmt.Sprintf("ti:%q", query.Title))
+	}
+	if len(clauses) == 0 {
+		return nil, nil
+	}
+
+	params := url.Values{}
+	params.Set("search_query", strings.Join(clauses, " OR "))
+	params.Set("max_results", strconv.Itoa(limit))
+	params.Set("sortBy", "relevance")
+
+	if err := a.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	resp, err := get(ctx, a.httpClient, a.baseURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("arxiv: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var feed struct {
+		Entries []struct {
+			ID        string `xml:"id"`
+			Title     string `xml:"title"`
+			Summary   string `xml:"summary"`
+			Published string `xml:"published"`
+			Authors   []struct {
+				Name string `xml:"name"`
+			} `xml:"author"`
+			DOI        string `xml:"http://arxiv.org/schemas/atom doi"`
+			JournalRef string `xml:"http://arxiv.org/schemas/atom journal_ref"`
+		} `xml:"entry"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&feed); err != nil {
+		return nil, fmt.Errorf("arxiv: failed to decode response: %w", err)
+	}
+
+	papers := make([]Paper, 0, len(feed.Entries))
+	for _, entry := range feed.Entries {
+		paper := Paper{
+			Title:    collapseSpace(entry.Title),
+			Abstract: collapseSpace(entry.Summary),
+			Venue:    entry.JournalRef,
+			URL:      entry.ID,
+			DOI:      entry.DOI,
+			ArXivID:  arXivID(entry.ID),
+			Sources:  []string{a.Name()},
+		}
+		for _, author := range entry.Authors {
+			paper.Authors = append(paper.Authors, author.Name)
+		}
+		if published, err := time.Parse(time.RFC3339, entry.Published); err == nil {
+			paper.Year = published.Year()
+		}
+		papers = append(papers, paper)
+	}
+	return papers, nil
+}
+
+// arXivID extracts the versionless identifier from an abs URL, e.g.
+// http://arxiv.org/abs/2101.00001v2 becomes 2101.00001
+func arXivID(absURL string) string {
+	id := absURL
+	if i := strings.Index(id, "/abs/"); i >= 0 {
+		id = id[i+len("/abs/"):]
+	}
+	if i := strings.LastIndex(id, "v"); i > 0 {
+		if _, err := strconv.Atoi(id[i+1:]); err == nil {
+			id = id[:i]
+		}
+	}
+	return id
+}
+
+// collapseSpace joins the line-wrapped text arXiv returns
+func collapseSpace(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
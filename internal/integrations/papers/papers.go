@@ -0,0 +1,352 @@
+// Package papers finds related research papers across arXiv, PubMed, and
+// Semantic Scholar, scores them against the source document, and merges
+// duplicates returned by more than one index
+package papers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Paper is a search result from one or more sources
+type Paper struct {
+	Title         string
+	Abstract      string
+	Authors       []string
+	Year          int
+	Venue         string
+	URL           string
+	DOI           string
+	ArXivID       string
+	PMID          string
+	CitationCount int
+	Sources       []string
+	Relevance     float64
+}
+
+// Map converts the paper to a step output value
+func (p Paper) Map() map[string]interface{} {
+	authors := make([]interface{}, len(p.Authors))
+	for i, author := range p.Authors {
+		authors[i] = author
+	}
+	sources := make([]interface{}, len(p.Sources))
+	for i, source := range p.Sources {
+		sources[i] = source
+	}
+	return map[string]interface{}{
+		"title":          p.Title,
+		"abstract":       p.Abstract,
+		"authors":        authors,
+		"year":           p.Year,
+		"venue":          p.Venue,
+		"url":            p.URL,
+		"doi":            p.DOI,
+		"arxiv_id":       p.ArXivID,
+		"pmid":           p.PMID,
+		"citation_count": p.CitationCount,
+		"sources":        sources,
+		"relevance":      p.Relevance,
+	}
+}
+
+// Query describes the document to find related papers for
+type Query struct {
+	Title    string
+	Abstract string
+	Keywords []string
+}
+
+// Text returns the free-text search string sent to sources: the keywords
+// when present, otherwise the title
+func (q Query) Text() string {
+	if len(q.Keywords) > 0 {
+		return strings.Join(q.Keywords, " ")
+	}
+	return q.Title
+}
+
+// Source searches one paper index
+type Source interface {
+	Name() string
+	Search(ctx context.Context, query Query, limit int) ([]Paper, error)
+}
+
+// Finder searches several sources concurrently and merges their results
+type Finder struct {
+	sources map[string]Source
+}
+
+// NewFinder creates a finder over sources, keyed by their names
+func NewFinder(sources ...Source) *Finder {
+	f := &Finder{sources: make(map[string]Source, len(sources))}
+	for _, source := range sources {
+		f.sources[source.Name()] = source
+	}
+	return f
+}
+
+// Options controls a search
+type Options struct {
+	Sources      []string // source names; empty searches all
+	Limit        int      // maximum papers returned
+	MinRelevance float64  // papers scoring below this are dropped
+}
+
+// Result is the outcome of a search. Errors holds per-source failures that
+// did not prevent other sources from returning results.
+type Result struct {
+	Papers []Paper
+	Counts map[string]int
+	Errors map[string]string
+}
+
+// Find searches the requested sources, scores and dedupes the results, and
+// returns the most relevant papers. It fails only when every source fails.
+func (f *Finder) Find(ctx context.Context, query Query, opts Options) (*Result, error) {
+	names := opts.Sources
+	if len(names) == 0 {
+		for name := range f.sources {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+
+	type response struct {
+		name   string
+		papers []Paper
+		err    error
+	}
+	responses := make([]response, len(names))
+
+	var wg sync.WaitGroup
+	for i, name := range names {
+		source, ok := f.sources[name]
+		if !ok {
+			responses[i] = response{name: name, err: fmt.Errorf("unknown search engine %q", name)}
+			continue
+		}
+		wg.Add(1)
+		go func(i int, name string, source Source) {
+			defer wg.Done()
+			papers, err := source.Search(ctx, query, limit)
+			responses[i] = response{name: name, papers: papers, err: err}
+		}(i, name, source)
+	}
+	wg.Wait()
+
+	result := &Result{
+		Counts: make(map[string]int),
+		Errors: make(map[string]string),
+	}
+	var all []Paper
+	for _, resp := range responses {
+		if resp.err != nil {
+			result.Errors[resp.name] = resp.err.Error()
+			continue
+		}
+		result.Counts[resp.name] = len(resp.papers)
+		all = append(all, resp.papers...)
+	}
+	if len(result.Errors) == len(names) {
+		return nil, fmt.Errorf("all search engines failed: %v", result.Errors)
+	}
+
+	for i := range all {
+		all[i].Relevance = Score(query, all[i])
+	}
+	merged := Dedupe(all)
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Relevance > merged[j].Relevance
+	})
+	for _, paper := range merged {
+		if paper.Relevance < opts.MinRelevance {
+			continue
+		}
+		result.Papers = append(result.Papers, paper)
+		if len(result.Papers) == limit {
+			break
+		}
+	}
+
+	return result, nil
+}
+
+// Dedupe merges papers that share a DOI, arXiv ID, PubMed ID, or normalized
+// title. The merged paper keeps the highest relevance and fills missing
+// fields from its duplicates.
+func Dedupe(papers []Paper) []Paper {
+	var merged []Paper
+	index := make(map[string]int)
+
+	for _, paper := range papers {
+		keys := dedupeKeys(paper)
+		at := -1
+		for _, key := range keys {
+			if i, ok := index[key]; ok {
+				at = i
+				break
+			}
+		}
+		if at < 0 {
+			at = len(merged)
+			merged = append(merged, paper)
+		} else {
+			merged[at] = mergePapers(merged[at], paper)
+		}
+		for _, key := range dedupeKeys(merged[at]) {
+			index[key] = at
+		}
+	}
+
+	return merged
+}
+
+// dedupeKeys returns the identifiers a paper can be matched on
+func dedupeKeys(p Paper) []string {
+	var keys []string
+	if p.DOI != "" {
+		keys = append(keys, "doi:"+strings.ToLower(p.DOI))
+	}
+	if p.ArXivID != "" {
+		keys = append(keys, "arxiv:"+p.ArXivID)
+	}
+	if p.PMID != "" {
+		keys = append(keys, "pmid:"+p.PMID)
+	}
+	if title := normalizeTitle(p.Title); title != "" {
+		keys = append(keys, "title:"+title)
+	}
+	return keys
+}
+
+// mergePapers combines two records of the same paper
+func mergePapers(a, b Paper) Paper {
+	if b.Relevance > a.Relevance {
+		a.Relevance = b.Relevance
+	}
+	if len(b.Abstract) > len(a.Abstract) {
+		a.Abstract = b.Abstract
+	}
+	if len(a.Authors) == 0 {
+		a.Authors = b.Authors
+	}
+	if a.Year == 0 {
+		a.Year = b.Year
+	}
+	if a.Venue == "" {
+		a.Venue = b.Venue
+	}
+	if a.URL == "" {
+		a.URL = b.URL
+	}
+	if a.DOI == "" {
+		a.DOI = b.DOI
+	}
+	if a.ArXivID == "" {
+		a.ArXivID = b.ArXivID
+	}
+	if a.PMID == "" {
+		a.PMID = b.PMID
+	}
+	if b.CitationCount > a.CitationCount {
+		a.CitationCount = b.CitationCount
+	}
+	for _, source := range b.Sources {
+		if !contains(a.Sources, source) {
+			a.Sources = append(a.Sources, source)
+		}
+	}
+	return a
+}
+
+// normalizeTitle lowercases a title and drops punctuation and spacing so
+// the same paper formatted differently by two sources compares equal
+func normalizeTitle(title string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// limiter spaces requests to a source at a fixed minimum interval
+type limiter struct {
+	interval time.Duration
+	next     time.Time
+	mu       sync.Mutex
+}
+
+func newLimiter(interval time.Duration) *limiter {
+	return &limiter{interval: interval}
+}
+
+// Wait blocks until the next request may be sent
+func (l *limiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	delay := time.Until(at)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// get sends a GET request and returns the response when it succeeded
+func get(ctx context.Context, client *http.Client, endpoint string, headers map[string]string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("search failed (status %d): %s", resp.StatusCode, detail)
+	}
+	return resp, nil
+}
@@ -0,0 +1,205 @@
+package papers
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PubMed searches PubMed through the NCBI E-utilities. NCBI allows three
+// requests per second without an API key and ten with one.
+type PubMed struct {
+	apiKey     string
+	baseURL    string
+	httpClient *http.Client
+	limiter    *limiter
+}
+
+// NewPubMed creates a PubMed source. apiKey may be empty.
+func NewPubMed(apiKey string) *PubMed {
+	interval := 350 * time.Millisecond
+	if apiKey != "" {
+		interval = 110 * time.Millisecond
+	}
+	return &PubMed{
+		apiKey:  apiKey,
+		baseURL: "https://eutils.ncbi.nlm.nih.gov/entrez/eutils",
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		limiter: newLimiter(interval),
+	}
+}
+
+// Name returns the search engine name used in step parameters
+func (p *PubMed) Name() string {
+	return "pubmed"
+}
+
+// Search finds matching PubMed IDs, then fetches their records
+func (p *PubMed) Search(ctx context.Context, query Query, limit int) ([]Paper, error) {
+	var clauses []string
+	for _, keyword := range query.Keywords {
+		clauses = append(clauses, fmt.Sprintf("%q[tiab]", keyword))
+	}
+	if len(clauses) == 0 && query.Title != "" {
+		clauses = append(clauses, query.Title)
+	}
+	if len(clauses) == 0 {
+		return nil, nil
+	}
+
+	ids, err := p.search(ctx, strings.Join(clauses, " OR "), limit)
+	if err != nil {
+		return nil, fmt.Errorf("pubmed: %w", err)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	papers, err := p.fetch(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("pubmed: %w", err)
+	}
+	return papers, nil
+}
+
+// search runs esearch and returns PubMed IDs by relevance
+func (p *PubMed) search(ctx context.Context, term string, limit int) ([]string, error) {
+	params := p.params()
+	params.Set("term", term)
+	params.Set("retmax", strconv.Itoa(limit))
+	params.Set("retmode", "json")
+	params.Set("sort", "relevance")
+
+	if err := p.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	resp, err := get(ctx, p.httpClient, p.baseURL+"/esearch.fcgi?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		ESearchResult struct {
+			IDList []string `json:"idlist"`
+		} `json:"esearchresult"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode search response: %w", err)
+	}
+	return result.ESearchResult.IDList, nil
+}
+
+// fetch runs efetch for a batch of IDs and parses the article records
+func (p *PubMed) fetch(ctx context.Context, ids []string) ([]Paper, error) {
+	params := p.params()
+	params.Set("id", strings.Join(ids, ","))
+	params.Set("retmode", "xml")
+
+	if err := p.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	resp, err := get(ctx, p.httpClient, p.baseURL+"/efetch.fcgi?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var set struct {
+		Articles []struct {
+			PMID    string `xml:"MedlineCitation>PMID"`
+			Article struct {
+				Title    markup `xml:"ArticleTitle"`
+				Abstract []struct {
+					Label string `xml:"Label,attr"`
+					Text  string `xml:",innerxml"`
+				} `xml:"Abstract>AbstractText"`
+				Authors []struct {
+					LastName       string `xml:"LastName"`
+					ForeName       string `xml:"ForeName"`
+					CollectiveName string `xml:"CollectiveName"`
+				} `xml:"AuthorList>Author"`
+				Journal string `xml:"Journal>Title"`
+				Year    string `xml:"Journal>JournalIssue>PubDate>Year"`
+			} `xml:"MedlineCitation>Article"`
+			IDs []struct {
+				Type  string `xml:"IdType,attr"`
+				Value string `xml:",chardata"`
+			} `xml:"PubmedData>ArticleIdList>ArticleId"`
+		} `xml:"PubmedArticle"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("failed to decode articles: %w", err)
+	}
+
+	papers := make([]Paper, 0, len(set.Articles))
+	for _, article := range set.Articles {
+		paper := Paper{
+			Title:   article.Article.Title.Text(),
+			Venue:   article.Article.Journal,
+			URL:     "https://pubmed.ncbi.nlm.nih.gov/" + article.PMID + "/",
+			PMID:    article.PMID,
+			Sources: []string{p.Name()},
+		}
+		paper.Year, _ = strconv.Atoi(article.Article.Year)
+
+		var sections []string
+		for _, section := range article.Article.Abstract {
+			text := markup{Inner: section.Text}.Text()
+			if section.Label != "" {
+				text = section.Label + ": " + text
+			}
+			sections = append(sections, text)
+		}
+		paper.Abstract = strings.Join(sections, "\n")
+
+		for _, author := range article.Article.Authors {
+			name := strings.TrimSpace(author.ForeName + " " + author.LastName)
+			if name == "" {
+				name = author.CollectiveName
+			}
+			paper.Authors = append(paper.Authors, name)
+		}
+		for _, id := range article.IDs {
+			if id.Type == "doi" {
+				paper.DOI = id.Value
+			}
+		}
+		papers = append(papers, paper)
+	}
+	return papers, nil
+}
+
+// params returns the query parameters shared by every E-utilities call
+func (p *PubMed) params() url.Values {
+	params := url.Values{}
+	params.Set("db", "pubmed")
+	params.Set("tool", "memmie-studio")
+	if p.apiKey != "" {
+		params.Set("api_key", p.apiKey)
+	}
+	return params
+}
+
+// markup captures element content that may contain inline formatting such
+// as <i> or <sup>
+type markup struct {
+	Inner string `xml:",innerxml"`
+}
+
+var tagPattern = regexp.MustCompile(`<[^>]+>`)
+
+// Text returns the content with tags removed and entities decoded
+func (m markup) Text() string {
+	return collapseSpace(html.UnescapeString(tagPattern.ReplaceAllString(m.Inner, "")))
+}
@@ -0,0 +1,169 @@
+package papers
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Term weights used by Score. Keywords are the strongest signal, title
+// words next, and frequent abstract words only expand the query.
+const (
+	keywordWeight  = 2.0
+	titleWeight    = 1.0
+	abstractWeight = 0.5
+
+	// abstractTerms is how many of the most frequent abstract words are used
+	abstractTerms = 10
+
+	// abstractMatchCredit is the share of a term's weight earned when it
+	// appears only in the candidate's abstract rather than its title
+	abstractMatchCredit = 0.75
+)
+
+// Score rates how relevant a paper is to a query, from 0 to 1. It is the
+// weighted share of query terms found in the paper, with terms found in the
+// paper's title counting fully and terms found only in its abstract counting
+// partially. A multi-word keyword matches when all of its words appear.
+func Score(query Query, paper Paper) float64 {
+	terms := queryTerms(query)
+	if len(terms) == 0 {
+		return 0
+	}
+
+	title := wordSet(paper.Title)
+	text := merge(title, wordSet(paper.Abstract))
+
+	var total, matched float64
+	for _, term := range terms {
+		total += term.weight
+		switch {
+		case containsAll(title, term.words):
+			matched += term.weight
+		case containsAll(text, term.words):
+			matched += term.weight * abstractMatchCredit
+		}
+	}
+
+	return math.Round(matched/total*1000) / 1000
+}
+
+type weightedTerm struct {
+	words  []string
+	weight float64
+}
+
+// queryTerms builds the weighted terms of a query, keeping the highest
+// weight when a term comes from more than one field
+func queryTerms(query Query) []weightedTerm {
+	weights := make(map[string]float64)
+	var order []string
+	add := func(words []string, weight float64) {
+		if len(words) == 0 {
+			return
+		}
+		key := strings.Join(words, " ")
+		if _, ok := weights[key]; !ok {
+			order = append(order, key)
+		}
+		if weight > weights[key] {
+			weights[key] = weight
+		}
+	}
+
+	for _, keyword := range query.Keywords {
+		add(tokenize(keyword), keywordWeight)
+	}
+	for _, word := range tokenize(query.Title) {
+		add([]string{word}, titleWeight)
+	}
+	for _, word := range topWords(query.Abstract, abstractTerms) {
+		add([]string{word}, abstractWeight)
+	}
+
+	terms := make([]weightedTerm, len(order))
+	for i, key := range order {
+		terms[i] = weightedTerm{words: strings.Fields(key), weight: weights[key]}
+	}
+	return terms
+}
+
+// topWords returns the n most frequent words in text, ties broken
+// alphabetically
+func topWords(text string, n int) []string {
+	counts := make(map[string]int)
+	for _, word := range tokenize(text) {
+		counts[word]++
+	}
+	words := make([]string, 0, len(counts))
+	for word := range counts {
+		words = append(words, word)
+	}
+	sort.Slice(words, func(i, j int) bool {
+		if counts[words[i]] != counts[words[j]] {
+			return counts[words[i]] > counts[words[j]]
+		}
+		return words[i] < words[j]
+	})
+	if len(words) > n {
+		words = words[:n]
+	}
+	return words
+}
+
+// tokenize lowercases text and splits it into words, dropping stopwords and
+// words shorter than three characters
+func tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-'
+	})
+	words := make([]string, 0, len(fields))
+	for _, field := range fields {
+		field = strings.Trim(field, "-")
+		if len([]rune(field)) < 3 || stopwords[field] {
+			continue
+		}
+		words = append(words, field)
+	}
+	return words
+}
+
+func wordSet(text string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range tokenize(text) {
+		set[word] = true
+	}
+	return set
+}
+
+func merge(a, b map[string]bool) map[string]bool {
+	set := make(map[string]bool, len(a)+len(b))
+	for word := range a {
+		set[word] = true
+	}
+	for word := range b {
+		set[word] = true
+	}
+	return set
+}
+
+func containsAll(set map[string]bool, words []string) bool {
+	for _, word := range words {
+		if !set[word] {
+			return false
+		}
+	}
+	return true
+}
+
+var stopwords = map[string]bool{
+	"the": true, "and": true, "for": true, "with": true, "from": true,
+	"that": true, "this": true, "these": true, "those": true, "are": true,
+	"was": true, "were": true, "been": true, "being": true, "have": true,
+	"has": true, "had": true, "not": true, "but": true, "its": true,
+	"our": true, "their": true, "which": true, "into": true, "than": true,
+	"can": true, "also": true, "using": true, "based": true, "via": true,
+	"study": true, "paper": true, "results": true, "show": true,
+	"new": true, "approach": true, "method": true, "methods": true,
+}
@@ -0,0 +1,115 @@
+package papers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// SemanticScholar searches the Semantic Scholar Graph API. Keyed clients
+// get one request per second; unauthenticated clients share a pool, so they
+// are spaced further apart.
+type SemanticScholar struct {
+	apiKey     string
+	baseURL    string
+	httpClient *http.Client
+	limiter    *limiter
+}
+
+// NewSemanticScholar creates a Semantic Scholar source. apiKey may be empty.
+func NewSemanticScholar(apiKey string) *SemanticScholar {
+	interval := 3 * time.Second
+	if apiKey != "" {
+		interval = time.Second
+	}
+	return &SemanticScholar{
+		apiKey:  apiKey,
+		baseURL: "https://api.semanticscholar.org/graph/v1",
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		limiter: newLimiter(interval),
+	}
+}
+
+// Name returns the search engine name used in step parameters
+func (s *SemanticScholar) Name() string {
+	return "semantic_scholar"
+}
+
+// Search runs a relevance search
+func (s *SemanticScholar) Search(ctx context.Context, query Query, limit int) ([]Paper, error) {
+	text := query.Text()
+	if text == "" {
+		return nil, nil
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	params := url.Values{}
+	params.Set("query", text)
+	params.Set("limit", strconv.Itoa(limit))
+	params.Set("fields", "title,abstract,authors,year,venue,url,externalIds,citationCount")
+
+	headers := map[string]string{}
+	if s.apiKey != "" {
+		headers["x-api-key"] = s.apiKey
+	}
+
+	if err := s.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	resp, err := get(ctx, s.httpClient, s.baseURL+"/paper/search?"+params.Encode(), headers)
+	if err != nil {
+		return nil, fmt.Errorf("semantic_scholar: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data []struct {
+			Title    string `json:"title"`
+			Abstract string `json:"abstract"`
+			Authors  []struct {
+				Name string `json:"name"`
+			} `json:"authors"`
+			Year        int    `json:"year"`
+			Venue       string `json:"venue"`
+			URL         string `json:"url"`
+			ExternalIDs struct {
+				DOI    string `json:"DOI"`
+				ArXiv  string `json:"ArXiv"`
+				PubMed string `json:"PubMed"`
+			} `json:"externalIds"`
+			CitationCount int `json:"citationCount"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("semantic_scholar: failed to decode response: %w", err)
+	}
+
+	papers := make([]Paper, 0, len(result.Data))
+	for _, item := range result.Data {
+		paper := Paper{
+			Title:         item.Title,
+			Abstract:      item.Abstract,
+			Year:          item.Year,
+			Venue:         item.Venue,
+			URL:           item.URL,
+			DOI:           item.ExternalIDs.DOI,
+			ArXivID:       item.ExternalIDs.ArXiv,
+			PMID:          item.ExternalIDs.PubMed,
+			CitationCount: item.CitationCount,
+			Sources:       []string{s.Name()},
+		}
+		for _, author := range item.Authors {
+			paper.Authors = append(paper.Authors, author.Name)
+		}
+		papers = append(papers, paper)
+	}
+	return papers, nil
+}
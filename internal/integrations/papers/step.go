@@ -0,0 +1,73 @@
+package papers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// ProviderID is the provider the research template's find_related step
+// names; the executor is registered under it rather than a step type
+const ProviderID = "paper-finder"
+
+// NewStepExecutor creates the related-paper search executor. Step inputs:
+// title, abstract, keywords (list or comma-separated), and limit. The step's
+// search_engines and min_relevance parameters pick the sources and the score
+// threshold; inputs of the same name override them.
+func NewStepExecutor(finder *Finder) workflows.StepExecutor {
+	return workflows.StepExecutorFunc(func(ctx context.Context, req workflows.StepRequest) (map[string]interface{}, error) {
+		query := Query{
+			Keywords: req.StringList("keywords"),
+		}
+		query.Title, _ = req.Input["title"].(string)
+		query.Abstract, _ = req.Input["abstract"].(string)
+		if query.Title == "" && len(query.Keywords) == 0 {
+			return nil, fmt.Errorf("title or keywords are required")
+		}
+
+		opts := Options{
+			Sources:      req.StringList("search_engines"),
+			Limit:        int(number(req.Input["limit"])),
+			MinRelevance: number(req.Setting("min_relevance")),
+		}
+
+		result, err := finder.Find(ctx, query, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		papers := make([]interface{}, len(result.Papers))
+		for i, paper := range result.Papers {
+			papers[i] = paper.Map()
+		}
+		counts := make(map[string]interface{}, len(result.Counts))
+		for name, count := range result.Counts {
+			counts[name] = count
+		}
+		errors := make(map[string]interface{}, len(result.Errors))
+		for name, message := range result.Errors {
+			errors[name] = message
+		}
+
+		return map[string]interface{}{
+			"papers":        papers,
+			"count":         len(papers),
+			"source_counts": counts,
+			"errors":        errors,
+		}, nil
+	})
+}
+
+// number reads a numeric input that may have been decoded from JSON
+func number(value interface{}) float64 {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	}
+	return 0
+}
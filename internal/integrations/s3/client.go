@@ -0,0 +1,116 @@
+// Package s3 ingests objects uploaded to S3 or MinIO buckets as blobs,
+// driven by bucket event notifications
+package s3
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/memmieai/memmie-studio/internal/awsauth"
+)
+
+// Object is a downloaded object
+type Object struct {
+	Data        []byte
+	ContentType string
+	ETag        string
+	Size        int64
+}
+
+// Client reads objects from S3 or an S3-compatible store
+type Client struct {
+	endpoint   string
+	region     string
+	creds      awsauth.Credentials
+	httpClient *http.Client
+}
+
+// NewClient creates a client. With an empty endpoint it talks to AWS using
+// virtual-hosted URLs; otherwise it uses path-style URLs against the
+// endpoint, as MinIO expects.
+func NewClient(endpoint, region string, creds awsauth.Credentials) *Client {
+	if region == "" {
+		region = "us-east-1"
+	}
+	return &Client{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		region:   region,
+		creds:    creds,
+		httpClient: &http.Client{
+			Timeout: 5 * time.Minute,
+		},
+	}
+}
+
+// GetObject downloads an object, failing when it is larger than maxSize
+func (c *Client) GetObject(ctx context.Context, bucket, key string, maxSize int64) (*Object, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.objectURL(bucket, key), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	awsauth.Sign(req, nil, c.creds, c.region, "s3", time.Now())
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("failed to get s3://%s/%s (status %d): %s", bucket, key, resp.StatusCode, detail)
+	}
+	if maxSize > 0 && resp.ContentLength > maxSize {
+		return nil, fmt.Errorf("object s3://%s/%s is %d bytes, over the %d byte limit", bucket, key, resp.ContentLength, maxSize)
+	}
+
+	reader := io.Reader(resp.Body)
+	if maxSize > 0 {
+		reader = io.LimitReader(resp.Body, maxSize+1)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object: %w", err)
+	}
+	if maxSize > 0 && int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("object s3://%s/%s is over the %d byte limit", bucket, key, maxSize)
+	}
+
+	return &Object{
+		Data:        data,
+		ContentType: resp.Header.Get("Content-Type"),
+		ETag:        strings.Trim(resp.Header.Get("ETag"), `"`),
+		Size:        int64(len(data)),
+	}, nil
+}
+
+// objectURL builds the object URL with the key escaped the way SigV4
+// canonicalizes S3 paths
+func (c *Client) objectURL(bucket, key string) string {
+	escaped := escapeKey(key)
+	if c.endpoint == "" {
+		return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucket, c.region, escaped)
+	}
+	return c.endpoint + "/" + url.PathEscape(bucket) + "/" + escaped
+}
+
+// escapeKey percent-encodes every byte of a key except unreserved
+// characters and path separators
+func escapeKey(key string) string {
+	var b strings.Builder
+	for i := 0; i < len(key); i++ {
+		ch := key[i]
+		if (ch >= 'A' && ch <= 'Z') || (ch >= 'a' && ch <= 'z') || (ch >= '0' && ch <= '9') ||
+			ch == '-' || ch == '_' || ch == '.' || ch == '~' || ch == '/' {
+			b.WriteByte(ch)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", ch)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,75 @@
+package s3
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Record is one object event from a bucket notification
+type Record struct {
+	EventName string
+	Bucket    string
+	Key       string
+	Size      int64
+	ETag      string
+}
+
+// Created reports whether the record is an object creation event
+func (r Record) Created() bool {
+	// AWS sends "ObjectCreated:Put"; MinIO sends "s3:ObjectCreated:Put"
+	return strings.HasPrefix(strings.TrimPrefix(r.EventName, "s3:"), "ObjectCreated:")
+}
+
+// notification is the S3 event notification format shared by AWS and MinIO
+type notification struct {
+	Records []struct {
+		EventName string `json:"eventName"`
+		S3        struct {
+			Bucket struct {
+				Name string `json:"name"`
+			} `json:"bucket"`
+			Object struct {
+				Key  string `json:"key"`
+				Size int64  `json:"size"`
+				ETag string `json:"eTag"`
+			} `json:"object"`
+		} `json:"s3"`
+	} `json:"Records"`
+
+	// Set when the notification arrives wrapped in an SNS message
+	Type         string `json:"Type"`
+	Message      string `json:"Message"`
+	SubscribeURL string `json:"SubscribeURL"`
+}
+
+// ParseNotification reads the records of an S3 event notification, either
+// posted directly (MinIO webhook targets) or wrapped in an SNS notification
+// (AWS). Object keys are URL-decoded.
+func ParseNotification(body []byte) ([]Record, error) {
+	var n notification
+	if err := json.Unmarshal(body, &n); err != nil {
+		return nil, fmt.Errorf("invalid notification: %w", err)
+	}
+	if n.Type == "Notification" && n.Message != "" {
+		return ParseNotification([]byte(n.Message))
+	}
+
+	records := make([]Record, 0, len(n.Records))
+	for _, r := range n.Records {
+		// Keys are form-encoded in notifications, with spaces as "+"
+		key, err := url.QueryUnescape(r.S3.Object.Key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid object key %q: %w", r.S3.Object.Key, err)
+		}
+		records = append(records, Record{
+			EventName: r.EventName,
+			Bucket:    r.S3.Bucket.Name,
+			Key:       key,
+			Size:      r.S3.Object.Size,
+			ETag:      r.S3.Object.ETag,
+		})
+	}
+	return records, nil
+}
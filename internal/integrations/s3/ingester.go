@@ -0,0 +1,170 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"path"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/memmieai/memmie-studio/internal/blob"
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+const (
+	// SourceS3 marks blobs ingested from buckets in their metadata
+	SourceS3 = "s3"
+
+	// EventCreate is the trigger event fired for newly ingested objects
+	EventCreate = "onCreate"
+)
+
+// IngestConfig controls which objects are ingested and who owns the blobs
+type IngestConfig struct {
+	UserID     string
+	ProviderID string
+	Prefix     string   // only keys under this prefix are ingested
+	Extensions []string // e.g. .csv, .json; empty accepts any extension
+	MaxSize    int64    // bytes, defaults to 10MB
+}
+
+// Ingester turns uploaded objects into blobs, one per object key
+type Ingester struct {
+	client    *Client
+	blobs     blob.Store
+	processor workflows.BlobProcessor
+	config    IngestConfig
+	etags     map[string]string // bucket/key -> last ingested ETag
+	mu        sync.Mutex
+}
+
+// NewIngester creates an ingester
+func NewIngester(client *Client, blobs blob.Store, processor workflows.BlobProcessor, config IngestConfig) *Ingester {
+	if config.ProviderID == "" {
+		config.ProviderID = SourceS3
+	}
+	if config.MaxSize == 0 {
+		config.MaxSize = 10 << 20
+	}
+	return &Ingester{
+		client:    client,
+		blobs:     blobs,
+		processor: processor,
+		config:    config,
+		etags:     make(map[string]string),
+	}
+}
+
+// Ingest creates a blob for a newly created object and fires onCreate for
+// it. It returns the blob ID, or "" when the record was skipped because it
+// is not a creation, is filtered out, or repeats an ETag already ingested.
+func (i *Ingester) Ingest(ctx context.Context, record Record) (string, error) {
+	if !record.Created() || !i.wants(record.Key) {
+		return "", nil
+	}
+
+	location := record.Bucket + "/" + record.Key
+	i.mu.Lock()
+	seen := record.ETag != "" && i.etags[location] == record.ETag
+	i.mu.Unlock()
+	if seen {
+		return "", nil
+	}
+
+	object, err := i.client.GetObject(ctx, record.Bucket, record.Key, i.config.MaxSize)
+	if err != nil {
+		return "", err
+	}
+
+	contentType := object.ContentType
+	if contentType == "" || contentType == "application/octet-stream" {
+		if byExt := mime.TypeByExtension(path.Ext(record.Key)); byExt != "" {
+			contentType = byExt
+		}
+	}
+	if !utf8.Valid(object.Data) {
+		return "", fmt.Errorf("object s3://%s is not UTF-8 text", location)
+	}
+
+	created, err := i.blobs.CreateBlob(ctx, &blob.Blob{
+		UserID:     i.config.UserID,
+		ProviderID: i.config.ProviderID,
+		Content:    string(object.Data),
+		Metadata: map[string]interface{}{
+			"source":       SourceS3,
+			"bucket":       record.Bucket,
+			"key":          record.Key,
+			"etag":         object.ETag,
+			"size":         object.Size,
+			"content_type": contentType,
+			"filename":     path.Base(record.Key),
+			"format":       formatFor(record.Key),
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create blob for s3://%s: %w", location, err)
+	}
+
+	i.mu.Lock()
+	i.etags[location] = record.ETag
+	i.mu.Unlock()
+
+	if i.processor != nil {
+		if err := i.processor.ProcessBlob(ctx, created.ID, i.config.UserID, EventCreate); err != nil {
+			return created.ID, fmt.Errorf("failed to process blob %s: %w", created.ID, err)
+		}
+	}
+	return created.ID, nil
+}
+
+// IngestAll ingests every record, continuing past failures, and returns the
+// created blob IDs with the errors joined
+func (i *Ingester) IngestAll(ctx context.Context, records []Record) ([]string, error) {
+	var ids []string
+	var errs []error
+	for _, record := range records {
+		id, err := i.Ingest(ctx, record)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids, errors.Join(errs...)
+}
+
+// wants reports whether a key passes the prefix and extension filters
+func (i *Ingester) wants(key string) bool {
+	if strings.HasSuffix(key, "/") || !strings.HasPrefix(key, i.config.Prefix) {
+		return false
+	}
+	if len(i.config.Extensions) == 0 {
+		return true
+	}
+	ext := strings.ToLower(path.Ext(key))
+	for _, allowed := range i.config.Extensions {
+		if strings.ToLower(allowed) == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// formatFor derives the data format the data-processing template reads from
+// blob metadata, e.g. "csv" for reports/2024.csv
+func formatFor(key string) string {
+	ext := strings.TrimPrefix(strings.ToLower(path.Ext(key)), ".")
+	switch ext {
+	case "ndjson":
+		return "jsonl"
+	case "yml":
+		return "yaml"
+	case "txt":
+		return "text"
+	}
+	return ext
+}
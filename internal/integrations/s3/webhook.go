@@ -0,0 +1,107 @@
+package s3
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// WebhookHandler returns an http.Handler for bucket event notifications.
+// Requests must carry token in the Authorization header, either bare (MinIO
+// webhook auth_token) or as a bearer token, or in a token query parameter for
+// SNS subscriptions, which cannot set headers. SNS subscription confirmations
+// are accepted automatically. Objects are ingested in the background so the
+// notifier is not held open while files download; ingest errors are passed
+// to onError, if set.
+func WebhookHandler(token string, ingester *Ingester, onError func(error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(token, r) {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			http.Error(w, "failed to read body", http.StatusBadRequest)
+			return
+		}
+
+		var envelope struct {
+			Type         string `json:"Type"`
+			SubscribeURL string `json:"SubscribeURL"`
+		}
+		if err := json.Unmarshal(body, &envelope); err == nil && envelope.Type == "SubscriptionConfirmation" {
+			if err := confirmSubscription(r.Context(), envelope.SubscribeURL); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		records, err := ParseNotification(body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(records) == 0 {
+			// MinIO sends an empty test event when a target is configured
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+			defer cancel()
+
+			if _, err := ingester.IngestAll(ctx, records); err != nil && onError != nil {
+				onError(fmt.Errorf("failed to ingest bucket notification: %w", err))
+			}
+		}()
+
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+// authorized compares the request's token to the configured token
+func authorized(token string, r *http.Request) bool {
+	if token == "" {
+		return false
+	}
+	given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if given == "" {
+		given = r.URL.Query().Get("token")
+	}
+	return subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+// confirmSubscription visits an SNS SubscribeURL, refusing URLs that do not
+// point at SNS so the endpoint cannot be used to make arbitrary requests
+func confirmSubscription(ctx context.Context, subscribeURL string) error {
+	u, err := url.Parse(subscribeURL)
+	if err != nil || u.Scheme != "https" || !strings.HasPrefix(u.Host, "sns.") || !strings.HasSuffix(u.Host, ".amazonaws.com") {
+		return fmt.Errorf("invalid subscribe url")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to confirm subscription: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("subscription confirmation failed (status %d)", resp.StatusCode)
+	}
+	return nil
+}
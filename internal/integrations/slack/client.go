@@ -0,0 +1,98 @@
+// Package slack posts workflow notifications, approval requests, and failure
+// alerts to Slack channels, threading messages per blob
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const defaultBaseURL = "https://slack.com/api"
+
+// Client is a minimal Slack Web API client
+type Client struct {
+	token      string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewClient creates a Slack client authenticated with a bot token
+func NewClient(token string) *Client {
+	return &Client{
+		token:   token,
+		baseURL: defaultBaseURL,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// WithBaseURL overrides the Slack API base URL
+func (c *Client) WithBaseURL(baseURL string) *Client {
+	c.baseURL = baseURL
+	return c
+}
+
+// Block is a Slack Block Kit element
+type Block map[string]interface{}
+
+// Message is a chat.postMessage request
+type Message struct {
+	Channel  string  `json:"channel"`
+	Text     string  `json:"text"`
+	Blocks   []Block `json:"blocks,omitempty"`
+	ThreadTS string  `json:"thread_ts,omitempty"`
+}
+
+// PostResult identifies a posted message
+type PostResult struct {
+	Channel string `json:"channel"`
+	TS      string `json:"ts"`
+}
+
+// apiResponse is the common Slack API response envelope
+type apiResponse struct {
+	OK      bool   `json:"ok"`
+	Error   string `json:"error,omitempty"`
+	Channel string `json:"channel"`
+	TS      string `json:"ts"`
+}
+
+// PostMessage posts a message to a channel or thread
+func (c *Client) PostMessage(ctx context.Context, msg Message) (*PostResult, error) {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/chat.postMessage", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json; charset=utf-8")
+	httpReq.Header.Set("Authorization", "Bearer "+c.token)
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var result apiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if !result.OK {
+		return nil, fmt.Errorf("slack error: %s", result.Error)
+	}
+
+	return &PostResult{Channel: result.Channel, TS: result.TS}, nil
+}
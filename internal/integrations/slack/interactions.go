@@ -0,0 +1,134 @@
+package slack
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+const (
+	actionApprove = "memmie_approve"
+	actionReject  = "memmie_reject"
+
+	// EventApprovalResponded is published when someone approves or rejects
+	// an approval request from Slack
+	EventApprovalResponded = "approval.responded"
+
+	maxRequestAge = 5 * time.Minute
+)
+
+// interactionPayload is the subset of a Slack block_actions payload we read
+type interactionPayload struct {
+	Type string `json:"type"`
+	User struct {
+		ID       string `json:"id"`
+		Username string `json:"username"`
+	} `json:"user"`
+	Actions []struct {
+		ActionID string `json:"action_id"`
+		Value    string `json:"value"`
+	} `json:"actions"`
+}
+
+// InteractionHandler returns an http.Handler for Slack's interactivity
+// request URL. It verifies the request signature and publishes an
+// approval.responded event for every approve or reject button press.
+func InteractionHandler(signingSecret string, eventBus workflows.EventBus) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			http.Error(w, "failed to read body", http.StatusBadRequest)
+			return
+		}
+
+		if err := verifySignature(signingSecret, r.Header, body, time.Now()); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			http.Error(w, "invalid form body", http.StatusBadRequest)
+			return
+		}
+
+		var payload interactionPayload
+		if err := json.Unmarshal([]byte(form.Get("payload")), &payload); err != nil {
+			http.Error(w, "invalid payload", http.StatusBadRequest)
+			return
+		}
+
+		for _, action := range payload.Actions {
+			if action.ActionID != actionApprove && action.ActionID != actionReject {
+				continue
+			}
+
+			var ref map[string]interface{}
+			if err := json.Unmarshal([]byte(action.Value), &ref); err != nil {
+				http.Error(w, "invalid action value", http.StatusBadRequest)
+				return
+			}
+
+			blobID, _ := ref["blob_id"].(string)
+			event := workflows.Event{
+				ID:        fmt.Sprintf("slack-%s-%d", payload.User.ID, time.Now().UnixNano()),
+				Type:      EventApprovalResponded,
+				BlobID:    blobID,
+				Timestamp: time.Now(),
+				Source:    "slack",
+				Data: map[string]interface{}{
+					"execution_id": ref["execution_id"],
+					"step_id":      ref["step_id"],
+					"approved":     action.ActionID == actionApprove,
+					"slack_user":   payload.User.ID,
+				},
+			}
+			if err := eventBus.Publish(r.Context(), event); err != nil {
+				http.Error(w, "failed to record approval", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// verifySignature checks Slack's v0 request signature, refusing every
+// request without a secret to check it with
+func verifySignature(secret string, header http.Header, body []byte, now time.Time) error {
+	if secret == "" {
+		return fmt.Errorf("no slack signing secret configured")
+	}
+	timestamp := header.Get("X-Slack-Request-Timestamp")
+	signature := header.Get("X-Slack-Signature")
+	if timestamp == "" || signature == "" {
+		return fmt.Errorf("missing slack signature headers")
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid slack timestamp")
+	}
+	if age := now.Sub(time.Unix(ts, 0)); age > maxRequestAge || age < -maxRequestAge {
+		return fmt.Errorf("stale slack request")
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return fmt.Errorf("invalid slack signature")
+	}
+	return nil
+}
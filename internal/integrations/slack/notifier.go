@@ -0,0 +1,221 @@
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"text/template"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// Kind selects the message template and layout
+type Kind string
+
+const (
+	KindMessage  Kind = "message"
+	KindSummary  Kind = "summary"
+	KindApproval Kind = "approval"
+	KindFailure  Kind = "failure"
+)
+
+// DefaultTemplates are the built-in message templates per kind. Templates
+// receive the notification data map, so any step input or event field can be
+// referenced, e.g. {{.blob_id}}.
+var DefaultTemplates = map[Kind]string{
+	KindMessage:  `{{.text}}`,
+	KindSummary:  `:white_check_mark: *{{or .workflow_id "Workflow"}}* completed for blob {{.blob_id}}{{with .summary}}` + "\n" + `{{.}}{{end}}`,
+	KindApproval: `:raised_hand: Approval needed for *{{or .step_id "a step"}}* in {{or .workflow_id "a workflow"}} (blob {{.blob_id}}){{with .text}}` + "\n" + `{{.}}{{end}}`,
+	KindFailure:  `:rotating_light: *{{or .workflow_id "Workflow"}}* failed for blob {{.blob_id}}{{with .error}}` + "\n" + "```{{.}}```" + `{{end}}`,
+}
+
+// Notifier renders and posts notifications, keeping one Slack thread per
+// blob and channel so all activity for a blob stays together
+type Notifier struct {
+	client         *Client
+	defaultChannel string
+	templates      map[Kind]*template.Template
+	threads        map[string]string
+	mu             sync.Mutex
+}
+
+// NewNotifier creates a notifier using the default templates
+func NewNotifier(client *Client, defaultChannel string) *Notifier {
+	n := &Notifier{
+		client:         client,
+		defaultChannel: defaultChannel,
+		templates:      make(map[Kind]*template.Template),
+		threads:        make(map[string]string),
+	}
+	for kind, text := range DefaultTemplates {
+		n.templates[kind] = template.Must(template.New(string(kind)).Parse(text))
+	}
+	return n
+}
+
+// SetTemplate overrides the template used for a message kind
+func (n *Notifier) SetTemplate(kind Kind, text string) error {
+	tmpl, err := template.New(string(kind)).Parse(text)
+	if err != nil {
+		return fmt.Errorf("invalid %s template: %w", kind, err)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.templates[kind] = tmpl
+	return nil
+}
+
+// Notification describes a message to send
+type Notification struct {
+	Kind     Kind
+	Channel  string
+	BlobID   string
+	Template string // overrides the kind's template when set
+	Data     map[string]interface{}
+}
+
+// Notify renders and posts a notification. Messages about a blob are posted
+// as replies in that blob's thread, which is started by the first message.
+func (n *Notifier) Notify(ctx context.Context, notification Notification) (*PostResult, error) {
+	channel := notification.Channel
+	if channel == "" {
+		channel = n.defaultChannel
+	}
+	if channel == "" {
+		return nil, fmt.Errorf("no slack channel configured")
+	}
+
+	text, err := n.render(notification)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := Message{Channel: channel, Text: text}
+	if notification.Kind == KindApproval {
+		msg.Blocks = approvalBlocks(text, notification.Data)
+	}
+
+	threadKey := channel + "|" + notification.BlobID
+	if notification.BlobID != "" {
+		n.mu.Lock()
+		msg.ThreadTS = n.threads[threadKey]
+		n.mu.Unlock()
+	}
+
+	result, err := n.client.PostMessage(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	if notification.BlobID != "" && msg.ThreadTS == "" {
+		n.mu.Lock()
+		if _, exists := n.threads[threadKey]; !exists {
+			n.threads[threadKey] = result.TS
+		}
+		n.mu.Unlock()
+	}
+
+	return result, nil
+}
+
+// render executes the notification template
+func (n *Notifier) render(notification Notification) (string, error) {
+	var tmpl *template.Template
+	if notification.Template != "" {
+		parsed, err := template.New("custom").Parse(notification.Template)
+		if err != nil {
+			return "", fmt.Errorf("invalid template: %w", err)
+		}
+		tmpl = parsed
+	} else {
+		kind := notification.Kind
+		if kind == "" {
+			kind = KindMessage
+		}
+		n.mu.Lock()
+		tmpl = n.templates[kind]
+		n.mu.Unlock()
+		if tmpl == nil {
+			return "", fmt.Errorf("unknown notification kind %q", kind)
+		}
+	}
+
+	data := map[string]interface{}{"blob_id": notification.BlobID}
+	for k, v := range notification.Data {
+		data[k] = v
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// approvalBlocks lays out an approval request with approve and reject buttons
+// whose values identify the execution step being approved
+func approvalBlocks(text string, data map[string]interface{}) []Block {
+	value, _ := json.Marshal(map[string]interface{}{
+		"execution_id": data["execution_id"],
+		"step_id":      data["step_id"],
+		"blob_id":      data["blob_id"],
+	})
+
+	return []Block{
+		{
+			"type": "section",
+			"text": map[string]interface{}{"type": "mrkdwn", "text": text},
+		},
+		{
+			"type": "actions",
+			"elements": []interface{}{
+				map[string]interface{}{
+					"type":      "button",
+					"action_id": actionApprove,
+					"style":     "primary",
+					"text":      map[string]interface{}{"type": "plain_text", "text": "Approve"},
+					"value":     string(value),
+				},
+				map[string]interface{}{
+					"type":      "button",
+					"action_id": actionReject,
+					"style":     "danger",
+					"text":      map[string]interface{}{"type": "plain_text", "text": "Reject"},
+					"value":     string(value),
+				},
+			},
+		},
+	}
+}
+
+// HandleEvent is an EventHandler that posts summaries for completed
+// executions and alerts for failed ones
+func (n *Notifier) HandleEvent(ctx context.Context, event workflows.Event) error {
+	var kind Kind
+	switch event.Type {
+	case workflows.EventExecutionCompleted:
+		kind = KindSummary
+	case workflows.EventExecutionFailed:
+		kind = KindFailure
+	default:
+		return nil
+	}
+
+	data := map[string]interface{}{
+		"provider_id": event.ProviderID,
+		"user_id":     event.UserID,
+	}
+	for k, v := range event.Data {
+		data[k] = v
+	}
+
+	_, err := n.Notify(ctx, Notification{
+		Kind:   kind,
+		BlobID: event.BlobID,
+		Data:   data,
+	})
+	return err
+}
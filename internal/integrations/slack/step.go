@@ -0,0 +1,53 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// StepType is the workflow step type handled by the Slack executor
+const StepType = "slack"
+
+// NewStepExecutor creates a step executor that posts a notification. Step
+// inputs: channel (optional, falls back to the default channel), kind
+// (message, summary, approval, failure), template (optional override) and
+// any other fields, which are available to the template.
+func NewStepExecutor(notifier *Notifier) workflows.StepExecutor {
+	return workflows.StepExecutorFunc(func(ctx context.Context, req workflows.StepRequest) (map[string]interface{}, error) {
+		data := map[string]interface{}{
+			"execution_id": req.ExecutionID,
+			"workflow_id":  req.WorkflowID,
+			"step_id":      req.Step.ID,
+			"user_id":      req.Context.UserID,
+			"provider_id":  req.Context.ProviderID,
+		}
+		for k, v := range req.Input {
+			data[k] = v
+		}
+
+		channel, _ := req.Input["channel"].(string)
+		tmpl, _ := req.Input["template"].(string)
+		kind := KindMessage
+		if k, ok := req.Input["kind"].(string); ok && k != "" {
+			kind = Kind(k)
+		}
+
+		result, err := notifier.Notify(ctx, Notification{
+			Kind:     kind,
+			Channel:  channel,
+			BlobID:   req.Context.BlobID,
+			Template: tmpl,
+			Data:     data,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to post slack notification: %w", err)
+		}
+
+		return map[string]interface{}{
+			"channel": result.Channel,
+			"ts":      result.TS,
+		}, nil
+	})
+}
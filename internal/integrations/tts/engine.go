@@ -0,0 +1,228 @@
+// Package tts renders text into audio with pluggable text-to-speech engines
+package tts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Request is a synthesis request
+type Request struct {
+	Text   string
+	Voice  string
+	Speed  float64 // 1.0 is normal speed
+	Format string  // mp3, opus, aac, flac, wav; engines may ignore it
+}
+
+// Audio is synthesized audio
+type Audio struct {
+	Data        []byte
+	ContentType string
+}
+
+// Engine synthesizes speech. MaxChars is the longest text accepted in one
+// request; longer text is split into chunks.
+type Engine interface {
+	Synthesize(ctx context.Context, req Request) (*Audio, error)
+	MaxChars() int
+}
+
+// OpenAIEngine uses the OpenAI speech API
+type OpenAIEngine struct {
+	apiKey     string
+	baseURL    string
+	model      string
+	httpClient *http.Client
+}
+
+// NewOpenAIEngine creates an OpenAI speech engine
+func NewOpenAIEngine(apiKey, model string) *OpenAIEngine {
+	if model == "" {
+		model = "tts-1"
+	}
+	return &OpenAIEngine{
+		apiKey:  apiKey,
+		baseURL: "https://api.openai.com/v1",
+		model:   model,
+		httpClient: &http.Client{
+			Timeout: 2 * time.Minute,
+		},
+	}
+}
+
+// MaxChars returns the API's input limit
+func (e *OpenAIEngine) MaxChars() int {
+	return 4096
+}
+
+// Synthesize renders text to audio
+func (e *OpenAIEngine) Synthesize(ctx context.Context, req Request) (*Audio, error) {
+	voice := req.Voice
+	if voice == "" {
+		voice = "alloy"
+	}
+	format := req.Format
+	if format == "" {
+		format = "mp3"
+	}
+
+	payload := map[string]interface{}{
+		"model":           e.model,
+		"input":           req.Text,
+		"voice":           voice,
+		"response_format": format,
+	}
+	if req.Speed > 0 {
+		payload["speed"] = req.Speed
+	}
+
+	data, err := postAudio(ctx, e.httpClient, e.baseURL+"/audio/speech", payload, map[string]string{
+		"Authorization": "Bearer " + e.apiKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Audio{Data: data, ContentType: contentTypeFor(format)}, nil
+}
+
+// ElevenLabsEngine uses the ElevenLabs text-to-speech API. Voice is an
+// ElevenLabs voice ID and output is always MP3.
+type ElevenLabsEngine struct {
+	apiKey       string
+	baseURL      string
+	model        string
+	defaultVoice string
+	httpClient   *http.Client
+}
+
+// NewElevenLabsEngine creates an ElevenLabs engine
+func NewElevenLabsEngine(apiKey, defaultVoice string) *ElevenLabsEngine {
+	return &ElevenLabsEngine{
+		apiKey:       apiKey,
+		baseURL:      "https://api.elevenlabs.io/v1",
+		model:        "eleven_multilingual_v2",
+		defaultVoice: defaultVoice,
+		httpClient: &http.Client{
+			Timeout: 2 * time.Minute,
+		},
+	}
+}
+
+// MaxChars returns the API's input limit
+func (e *ElevenLabsEngine) MaxChars() int {
+	return 5000
+}
+
+// Synthesize renders text to audio
+func (e *ElevenLabsEngine) Synthesize(ctx context.Context, req Request) (*Audio, error) {
+	voice := req.Voice
+	if voice == "" {
+		voice = e.defaultVoice
+	}
+	if voice == "" {
+		return nil, fmt.Errorf("elevenlabs requires a voice ID")
+	}
+
+	payload := map[string]interface{}{
+		"text":     req.Text,
+		"model_id": e.model,
+	}
+	if req.Speed > 0 {
+		payload["voice_settings"] = map[string]interface{}{"speed": req.Speed}
+	}
+
+	data, err := postAudio(ctx, e.httpClient, e.baseURL+"/text-to-speech/"+url.PathEscape(voice), payload, map[string]string{
+		"xi-api-key": e.apiKey,
+		"Accept":     "audio/mpeg",
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Audio{Data: data, ContentType: "audio/mpeg"}, nil
+}
+
+// postAudio posts a JSON payload and returns the binary response body
+func postAudio(ctx context.Context, client *http.Client, endpoint string, payload interface{}, headers map[string]string) ([]byte, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		httpReq.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("speech synthesis failed (status %d): %s", resp.StatusCode, detail)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audio: %w", err)
+	}
+	return data, nil
+}
+
+// contentTypeFor maps an output format to its MIME type
+func contentTypeFor(format string) string {
+	switch format {
+	case "opus":
+		return "audio/ogg"
+	case "aac":
+		return "audio/aac"
+	case "flac":
+		return "audio/flac"
+	case "wav":
+		return "audio/wav"
+	default:
+		return "audio/mpeg"
+	}
+}
+
+// Chunk splits text into pieces of at most max characters, breaking at
+// paragraph, then sentence, then word boundaries
+func Chunk(text string, max int) []string {
+	text = strings.TrimSpace(text)
+	var chunks []string
+	for len([]rune(text)) > max {
+		runes := []rune(text)
+		window := string(runes[:max])
+
+		cut := -1
+		for _, sep := range []string{"\n\n", ". ", "! ", "? ", "\n", " "} {
+			if idx := strings.LastIndex(window, sep); idx > 0 {
+				cut = idx + len(sep)
+				break
+			}
+		}
+		if cut <= 0 {
+			cut = len(window)
+		}
+
+		chunks = append(chunks, strings.TrimSpace(text[:cut]))
+		text = strings.TrimSpace(text[cut:])
+	}
+	if text != "" {
+		chunks = append(chunks, text)
+	}
+	return chunks
+}
@@ -0,0 +1,139 @@
+package tts
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/memmieai/memmie-studio/internal/artifact"
+	"github.com/memmieai/memmie-studio/internal/blob"
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// StepType is the workflow step type handled by the TTS executor
+const StepType = "tts"
+
+// stepExecutor renders text to audio, stores the audio as an artifact, and
+// records it as a derived audio blob
+type stepExecutor struct {
+	engines       map[string]Engine
+	defaultEngine string
+	artifacts     artifact.Store
+	blobs         blob.Store
+}
+
+// NewStepExecutor creates a TTS executor over named engines. blobs may be
+// nil to skip creating derived audio blobs. Step inputs: text, engine,
+// voice, speed, and format (used only when the text fits in one request;
+// chunked renders are always MP3 so the pieces can be concatenated).
+func NewStepExecutor(engines map[string]Engine, defaultEngine string, artifacts artifact.Store, blobs blob.Store) workflows.StepExecutor {
+	return &stepExecutor{
+		engines:       engines,
+		defaultEngine: defaultEngine,
+		artifacts:     artifacts,
+		blobs:         blobs,
+	}
+}
+
+// Execute renders the step's text
+func (e *stepExecutor) Execute(ctx context.Context, req workflows.StepRequest) (map[string]interface{}, error) {
+	text, _ := req.Input["text"].(string)
+	if text == "" {
+		return nil, fmt.Errorf("text is required")
+	}
+
+	engineName, _ := req.Input["engine"].(string)
+	if engineName == "" {
+		engineName = e.defaultEngine
+	}
+	engine, ok := e.engines[engineName]
+	if !ok {
+		return nil, fmt.Errorf("unknown tts engine %q", engineName)
+	}
+
+	speed, err := toFloat(req.Input["speed"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid speed: %w", err)
+	}
+	voice, _ := req.Input["voice"].(string)
+	format, _ := req.Input["format"].(string)
+
+	chunks := Chunk(text, engine.MaxChars())
+	if len(chunks) > 1 {
+		format = "mp3"
+	}
+
+	var data []byte
+	contentType := ""
+	for _, chunk := range chunks {
+		audio, err := engine.Synthesize(ctx, Request{Text: chunk, Voice: voice, Speed: speed, Format: format})
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, audio.Data...)
+		contentType = audio.ContentType
+	}
+
+	key := artifact.Key(artifact.ExtensionFor(contentType), "tts", req.Context.BlobID, req.ExecutionID+"-"+req.Step.ID)
+	audioURL, err := e.artifacts.Put(ctx, key, contentType, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store audio: %w", err)
+	}
+
+	output := map[string]interface{}{
+		"audio_url":    audioURL,
+		"content_type": contentType,
+		"engine":       engineName,
+		"voice":        voice,
+		"speed":        speed,
+		"chunks":       len(chunks),
+		"characters":   len([]rune(text)),
+	}
+
+	if e.blobs == nil {
+		return output, nil
+	}
+
+	parentID := req.Context.BlobID
+	derived, err := e.blobs.CreateBlob(ctx, &blob.Blob{
+		UserID:     req.Context.UserID,
+		ProviderID: req.Context.ProviderID,
+		Content:    audioURL,
+		ParentID:   &parentID,
+		Metadata: map[string]interface{}{
+			"kind":         "audio",
+			"derived_from": parentID,
+			"execution_id": req.ExecutionID,
+			"step_id":      req.Step.ID,
+			"audio_url":    audioURL,
+			"content_type": contentType,
+			"engine":       engineName,
+			"voice":        voice,
+			"speed":        speed,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store audio blob: %w", err)
+	}
+	output["blob_id"] = derived.ID
+
+	return output, nil
+}
+
+// toFloat reads an optional numeric input that may arrive as a string
+func toFloat(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case string:
+		if v == "" {
+			return 0, nil
+		}
+		return strconv.ParseFloat(v, 64)
+	}
+	return 0, fmt.Errorf("unsupported value %v", value)
+}
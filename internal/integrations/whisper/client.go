@@ -0,0 +1,157 @@
+// Package whisper transcribes audio blobs with Whisper, either through the
+// OpenAI API or a local OpenAI-compatible server such as whisper.cpp
+package whisper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultAPIURL = "https://api.openai.com/v1"
+	defaultModel  = "whisper-1"
+)
+
+// Audio is an audio file to transcribe
+type Audio struct {
+	Data     []byte
+	Filename string
+	Language string // optional ISO-639-1 hint
+	Prompt   string // optional vocabulary or style hint
+}
+
+// Segment is a timestamped span of the transcript, in seconds
+type Segment struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
+}
+
+// Transcript is the result of a transcription
+type Transcript struct {
+	Text     string    `json:"text"`
+	Language string    `json:"language"`
+	Duration float64   `json:"duration"`
+	Segments []Segment `json:"segments"`
+}
+
+// Transcriber turns audio into timestamped text
+type Transcriber interface {
+	Transcribe(ctx context.Context, audio Audio) (*Transcript, error)
+}
+
+// Client transcribes through an OpenAI-compatible transcription endpoint
+type Client struct {
+	apiKey     string
+	baseURL    string
+	model      string
+	httpClient *http.Client
+}
+
+// NewAPIClient creates a client for the OpenAI Whisper API
+func NewAPIClient(apiKey string) *Client {
+	return &Client{
+		apiKey:  apiKey,
+		baseURL: defaultAPIURL,
+		model:   defaultModel,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Minute,
+		},
+	}
+}
+
+// NewLocalClient creates a client for a local OpenAI-compatible Whisper
+// server, e.g. http://localhost:8080/v1
+func NewLocalClient(baseURL, model string) *Client {
+	c := NewAPIClient("")
+	c.baseURL = strings.TrimSuffix(baseURL, "/")
+	if model != "" {
+		c.model = model
+	}
+	return c
+}
+
+// Transcribe uploads audio and returns the verbose transcript
+func (c *Client) Transcribe(ctx context.Context, audio Audio) (*Transcript, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	filename := audio.Filename
+	if filename == "" {
+		filename = "audio.webm"
+	}
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create form file: %w", err)
+	}
+	if _, err := part.Write(audio.Data); err != nil {
+		return nil, fmt.Errorf("failed to write audio: %w", err)
+	}
+
+	fields := map[string]string{
+		"model":                     c.model,
+		"response_format":           "verbose_json",
+		"timestamp_granularities[]": "segment",
+		"language":                  audio.Language,
+		"prompt":                    audio.Prompt,
+	}
+	for name, value := range fields {
+		if value == "" {
+			continue
+		}
+		if err := writer.WriteField(name, value); err != nil {
+			return nil, fmt.Errorf("failed to write field %s: %w", name, err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish form: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/audio/transcriptions", &body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", writer.FormDataContentType())
+	if c.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("transcription failed (status %d): %s", resp.StatusCode, detail)
+	}
+
+	var transcript Transcript
+	if err := json.NewDecoder(resp.Body).Decode(&transcript); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	transcript.Text = strings.TrimSpace(transcript.Text)
+	return &transcript, nil
+}
+
+// Timestamped renders the transcript with a [mm:ss] marker per segment
+func (t *Transcript) Timestamped() string {
+	if len(t.Segments) == 0 {
+		return t.Text
+	}
+
+	var b strings.Builder
+	for _, segment := range t.Segments {
+		total := int(segment.Start)
+		fmt.Fprintf(&b, "[%02d:%02d] %s\n", total/60, total%60, strings.TrimSpace(segment.Text))
+	}
+	return b.String()
+}
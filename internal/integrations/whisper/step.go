@@ -0,0 +1,164 @@
+package whisper
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/memmieai/memmie-studio/internal/blob"
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+const (
+	// StepType is the workflow step type handled by the transcription executor
+	StepType = "transcribe"
+
+	// maxAudioSize caps downloaded audio at the Whisper API's upload limit
+	maxAudioSize = 25 << 20
+)
+
+// stepExecutor transcribes audio and stores the transcript as a derived
+// text blob, so summarization providers triggered by onCreate pick it up
+type stepExecutor struct {
+	transcriber Transcriber
+	blobs       blob.Store
+	processor   workflows.BlobProcessor
+	httpClient  *http.Client
+}
+
+// NewStepExecutor creates a transcription executor. blobs may be nil to
+// return transcripts without storing them; processor may be nil when the
+// blob store already publishes create events. Step inputs: audio_url or
+// audio (base64), filename, language, prompt, and target_provider_id for the
+// derived blob.
+func NewStepExecutor(transcriber Transcriber, blobs blob.Store, processor workflows.BlobProcessor) workflows.StepExecutor {
+	return &stepExecutor{
+		transcriber: transcriber,
+		blobs:       blobs,
+		processor:   processor,
+		httpClient: &http.Client{
+			Timeout: 5 * time.Minute,
+		},
+	}
+}
+
+// Execute transcribes the step's audio
+func (e *stepExecutor) Execute(ctx context.Context, req workflows.StepRequest) (map[string]interface{}, error) {
+	audio, err := e.loadAudio(ctx, req.Input)
+	if err != nil {
+		return nil, err
+	}
+	audio.Language, _ = req.Input["language"].(string)
+	audio.Prompt, _ = req.Input["prompt"].(string)
+
+	transcript, err := e.transcriber.Transcribe(ctx, audio)
+	if err != nil {
+		return nil, err
+	}
+
+	segments := make([]interface{}, len(transcript.Segments))
+	for i, segment := range transcript.Segments {
+		segments[i] = map[string]interface{}{
+			"start": segment.Start,
+			"end":   segment.End,
+			"text":  segment.Text,
+		}
+	}
+
+	output := map[string]interface{}{
+		"text":        transcript.Text,
+		"timestamped": transcript.Timestamped(),
+		"language":    transcript.Language,
+		"duration":    transcript.Duration,
+		"segments":    segments,
+	}
+
+	if e.blobs == nil {
+		return output, nil
+	}
+
+	providerID, _ := req.Input["target_provider_id"].(string)
+	if providerID == "" {
+		providerID = req.Context.ProviderID
+	}
+	parentID := req.Context.BlobID
+
+	derived, err := e.blobs.CreateBlob(ctx, &blob.Blob{
+		UserID:     req.Context.UserID,
+		ProviderID: providerID,
+		Content:    transcript.Text,
+		ParentID:   &parentID,
+		Metadata: map[string]interface{}{
+			"kind":         "transcript",
+			"derived_from": parentID,
+			"execution_id": req.ExecutionID,
+			"language":     transcript.Language,
+			"duration":     transcript.Duration,
+			"segments":     segments,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store transcript: %w", err)
+	}
+	output["blob_id"] = derived.ID
+
+	if e.processor != nil {
+		if err := e.processor.ProcessBlob(ctx, derived.ID, req.Context.UserID, "onCreate"); err != nil {
+			return nil, fmt.Errorf("failed to process transcript blob %s: %w", derived.ID, err)
+		}
+	}
+
+	return output, nil
+}
+
+// loadAudio downloads audio_url or decodes inline base64 audio
+func (e *stepExecutor) loadAudio(ctx context.Context, input map[string]interface{}) (Audio, error) {
+	filename, _ := input["filename"].(string)
+
+	if inline, ok := input["audio"].(string); ok && inline != "" {
+		data, err := base64.StdEncoding.DecodeString(inline)
+		if err != nil {
+			return Audio{}, fmt.Errorf("failed to decode audio: %w", err)
+		}
+		return Audio{Data: data, Filename: filename}, nil
+	}
+
+	audioURL, _ := input["audio_url"].(string)
+	if audioURL == "" {
+		return Audio{}, fmt.Errorf("audio_url or audio is required")
+	}
+	if filename == "" {
+		if parsed, err := url.Parse(audioURL); err == nil {
+			filename = path.Base(parsed.Path)
+		}
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", audioURL, nil)
+	if err != nil {
+		return Audio{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := e.httpClient.Do(httpReq)
+	if err != nil {
+		return Audio{}, fmt.Errorf("failed to download audio: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Audio{}, fmt.Errorf("failed to download audio: status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxAudioSize+1))
+	if err != nil {
+		return Audio{}, fmt.Errorf("failed to read audio: %w", err)
+	}
+	if len(data) > maxAudioSize {
+		return Audio{}, fmt.Errorf("audio exceeds %d MB limit", maxAudioSize>>20)
+	}
+
+	return Audio{Data: data, Filename: filename}, nil
+}
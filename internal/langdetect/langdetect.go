@@ -0,0 +1,209 @@
+// Package langdetect identifies the natural language of blob content so
+// trigger conditions and workflow steps can route on it. Detection is
+// offline: non-Latin scripts map directly to a language, and Latin-script
+// text is scored against common function words.
+package langdetect
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+const (
+	// minLetters is the least text detection is attempted on
+	minLetters = 20
+
+	// minHits is the number of function words Latin-script text must
+	// contain before a language is reported
+	minHits = 3
+
+	// maxRunes bounds the text examined; the start of a document is enough
+	maxRunes = 10000
+)
+
+// Result is a detected language
+type Result struct {
+	Language   string  `json:"language"` // ISO 639-1 code, empty when undetermined
+	Script     string  `json:"script"`   // Latin, Cyrillic, Han, ...
+	Confidence float64 `json:"confidence"`
+}
+
+// stopwords are frequent function words per Latin-script language. Words
+// shared between languages count for each of them.
+var stopwords = map[string][]string{
+	"en": {"the", "and", "of", "to", "is", "in", "that", "it", "was", "for", "with", "as", "his", "her", "he", "she", "on", "be", "at", "by", "this", "had", "not", "are", "but", "from", "they", "you", "have", "which", "were", "would", "there", "their", "what", "been", "said", "into"},
+	"es": {"el", "la", "los", "las", "de", "que", "y", "en", "un", "una", "es", "por", "con", "para", "no", "se", "del", "al", "lo", "como", "más", "pero", "sus", "su", "le", "ya", "fue", "este", "esta", "muy", "también", "cuando", "donde", "hay", "porque", "había", "estaba"},
+	"fr": {"le", "la", "les", "de", "des", "et", "est", "un", "une", "du", "que", "qui", "dans", "pour", "pas", "sur", "au", "avec", "il", "elle", "ne", "se", "ce", "cette", "sont", "mais", "ou", "nous", "vous", "ils", "était", "avait", "lui", "été", "comme", "aux", "leur"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ein", "eine", "zu", "den", "dem", "des", "mit", "sich", "auf", "für", "von", "im", "auch", "es", "er", "sie", "wir", "ich", "war", "wie", "aber", "noch", "nach", "bei", "oder", "wenn", "dass", "hat", "wird", "sind", "einen", "sein"},
+	"it": {"il", "lo", "la", "gli", "le", "di", "che", "e", "è", "un", "una", "per", "non", "con", "del", "della", "dei", "nel", "nella", "sono", "si", "da", "ma", "come", "anche", "più", "questo", "questa", "alla", "era", "ha", "loro", "essere", "molto", "al"},
+	"pt": {"o", "a", "os", "as", "de", "que", "e", "do", "da", "dos", "das", "em", "um", "uma", "para", "com", "não", "no", "na", "por", "mais", "se", "como", "mas", "foi", "ao", "seu", "sua", "ele", "ela", "são", "está", "também", "isso", "muito", "já", "nos", "quando"},
+	"nl": {"de", "het", "een", "en", "van", "is", "dat", "die", "in", "te", "niet", "op", "met", "voor", "zijn", "er", "aan", "ook", "als", "maar", "bij", "om", "door", "dan", "nog", "wat", "naar", "was", "uit", "werd", "hij", "zij", "heeft", "wordt", "geen", "worden", "deze"},
+	"sv": {"och", "att", "det", "som", "en", "på", "är", "av", "för", "med", "till", "den", "har", "de", "inte", "om", "ett", "han", "hon", "var", "jag", "men", "sig", "från", "vi", "så", "kan", "när", "efter", "eller", "också", "hade", "vid", "skulle"},
+	"pl": {"i", "w", "na", "z", "się", "nie", "do", "to", "że", "jest", "o", "jak", "ale", "po", "co", "tak", "za", "od", "przez", "jego", "jej", "był", "była", "są", "dla", "tym", "czy", "już", "może", "gdy", "który", "która"},
+	"tr": {"ve", "bir", "bu", "da", "de", "için", "ile", "çok", "ne", "gibi", "daha", "ama", "olarak", "olan", "her", "kadar", "sonra", "ben", "sen", "o", "değil", "var", "yok", "mi", "en", "şey", "çünkü", "ise"},
+}
+
+// latinLanguages orders the stopword languages so ties are broken the same
+// way on every run
+var latinLanguages = []string{"en", "es", "fr", "de", "it", "pt", "nl", "sv", "pl", "tr"}
+
+// stopwordIndex maps each function word to the languages it belongs to
+var stopwordIndex = func() map[string][]string {
+	index := make(map[string][]string)
+	for lang, words := range stopwords {
+		for _, w := range words {
+			index[w] = append(index[w], lang)
+		}
+	}
+	return index
+}()
+
+// scripts are the non-Latin writing systems detection distinguishes, with
+// the language each one implies unless a more specific rule applies
+var scripts = []struct {
+	name     string
+	table    *unicode.RangeTable
+	language string
+}{
+	{"Cyrillic", unicode.Cyrillic, "ru"},
+	{"Greek", unicode.Greek, "el"},
+	{"Arabic", unicode.Arabic, "ar"},
+	{"Hebrew", unicode.Hebrew, "he"},
+	{"Devanagari", unicode.Devanagari, "hi"},
+	{"Thai", unicode.Thai, "th"},
+	{"Hangul", unicode.Hangul, "ko"},
+	{"Hiragana", unicode.Hiragana, "ja"},
+	{"Katakana", unicode.Katakana, "ja"},
+	{"Han", unicode.Han, "zh"},
+}
+
+// Detect identifies the language of text
+func Detect(text string) Result {
+	text = head(text)
+	counts := make(map[string]int)
+	letters := 0
+	var ukrainian, persian int
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		counts[scriptOf(r)]++
+		switch r {
+		case 'і', 'ї', 'є', 'ґ', 'І', 'Ї', 'Є', 'Ґ':
+			ukrainian++
+		case 'پ', 'چ', 'ژ', 'گ', 'ی':
+			persian++
+		}
+	}
+	if letters < minLetters {
+		return Result{}
+	}
+
+	script, top := "Latin", 0
+	for name, count := range counts {
+		if count > top {
+			script, top = name, count
+		}
+	}
+	share := float64(top) / float64(letters)
+
+	switch script {
+	case "Latin":
+		return detectLatin(text)
+	case "Hiragana", "Katakana":
+		return Result{Language: "ja", Script: "Japanese", Confidence: round(share)}
+	case "Han":
+		// Japanese mixes kana into Han text; Chinese has none
+		kana := counts["Hiragana"] + counts["Katakana"]
+		if kana > 0 && float64(kana)/float64(letters) > 0.05 {
+			return Result{Language: "ja", Script: "Japanese", Confidence: round(share + float64(kana)/float64(letters))}
+		}
+	case "Cyrillic":
+		if ukrainian > 0 {
+			return Result{Language: "uk", Script: script, Confidence: round(share * 0.9)}
+		}
+		return Result{Language: "ru", Script: script, Confidence: round(share * 0.8)}
+	case "Arabic":
+		if persian > 0 {
+			return Result{Language: "fa", Script: script, Confidence: round(share * 0.8)}
+		}
+	}
+	for _, s := range scripts {
+		if s.name == script {
+			return Result{Language: s.language, Script: script, Confidence: round(share)}
+		}
+	}
+	return Result{Script: script}
+}
+
+// detectLatin scores Latin-script text against each language's function
+// words. Confidence combines the margin over the runner-up with how much of
+// the text the winner's function words cover.
+func detectLatin(text string) Result {
+	scores := make(map[string]int)
+	words := 0
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	}) {
+		words++
+		for _, lang := range stopwordIndex[strings.Trim(w, "'")] {
+			scores[lang]++
+		}
+	}
+
+	best, second := "", 0
+	for _, lang := range latinLanguages {
+		switch score := scores[lang]; {
+		case best == "" || score > scores[best]:
+			if best != "" {
+				second = scores[best]
+			}
+			best = lang
+		case score > second:
+			second = score
+		}
+	}
+	hits := scores[best]
+	if hits < minHits {
+		return Result{Script: "Latin"}
+	}
+
+	margin := float64(hits-second) / float64(hits)
+	coverage := math.Min(float64(hits)/float64(words)/0.25, 1)
+	return Result{Language: best, Script: "Latin", Confidence: round(0.6*margin + 0.4*coverage)}
+}
+
+// head returns the first maxRunes runes of text
+func head(text string) string {
+	n := 0
+	for i := range text {
+		if n == maxRunes {
+			return text[:i]
+		}
+		n++
+	}
+	return text
+}
+
+// scriptOf names the writing system of a letter
+func scriptOf(r rune) string {
+	if r < 0x250 {
+		return "Latin"
+	}
+	for _, s := range scripts {
+		if unicode.Is(s.table, r) {
+			return s.name
+		}
+	}
+	if unicode.Is(unicode.Latin, r) {
+		return "Latin"
+	}
+	return "Other"
+}
+
+// round keeps confidences to two decimal places, capped at 1
+func round(v float64) float64 {
+	return math.Round(math.Min(v, 1)*100) / 100
+}
@@ -0,0 +1,73 @@
+package langdetect
+
+import (
+	"context"
+
+	"github.com/memmieai/memmie-studio/internal/blob"
+)
+
+// Metadata keys written by Store
+const (
+	LanguageKey   = "content_language"
+	ConfidenceKey = "content_language_confidence"
+)
+
+// Store tags blobs with their content language as they are created and
+// updated, so every ingest path records it before workflows are triggered.
+// A language set by the caller without a confidence is treated as explicit
+// and never overwritten.
+type Store struct {
+	blob.Store
+}
+
+// NewStore wraps a blob store with language detection
+func NewStore(inner blob.Store) *Store {
+	return &Store{Store: inner}
+}
+
+// CreateBlob detects the blob's language and creates it
+func (s *Store) CreateBlob(ctx context.Context, b *blob.Blob) (*blob.Blob, error) {
+	return s.Store.CreateBlob(ctx, Tag(b))
+}
+
+// UpdateBlob re-detects the blob's language and updates it
+func (s *Store) UpdateBlob(ctx context.Context, b *blob.Blob) (*blob.Blob, error) {
+	return s.Store.UpdateBlob(ctx, Tag(b))
+}
+
+// DeleteBlob deletes a blob when the wrapped store can
+func (s *Store) DeleteBlob(ctx context.Context, userID, blobID string) error {
+	deleter, ok := s.Store.(blob.Deleter)
+	if !ok {
+		return blob.ErrDeleteUnsupported
+	}
+	return deleter.DeleteBlob(ctx, userID, blobID)
+}
+
+// Tag returns a copy of b with its detected language in metadata. Blobs
+// whose language is explicit, or cannot be determined, keep their metadata
+// as it is, except that a stale detection is removed.
+func Tag(b *blob.Blob) *blob.Blob {
+	if b == nil {
+		return b
+	}
+	_, detected := b.Metadata[ConfidenceKey]
+	if _, set := b.Metadata[LanguageKey]; set && !detected {
+		return b
+	}
+
+	tagged := *b
+	tagged.Metadata = make(map[string]interface{}, len(b.Metadata)+2)
+	for k, v := range b.Metadata {
+		tagged.Metadata[k] = v
+	}
+	result := Detect(b.Content)
+	if result.Language == "" {
+		delete(tagged.Metadata, LanguageKey)
+		delete(tagged.Metadata, ConfidenceKey)
+		return &tagged
+	}
+	tagged.Metadata[LanguageKey] = result.Language
+	tagged.Metadata[ConfidenceKey] = result.Confidence
+	return &tagged
+}
@@ -0,0 +1,95 @@
+package moderation
+
+import (
+	"hash/fnv"
+	"regexp"
+)
+
+// Copyright check defaults
+const (
+	defaultCopyrightWords = 12
+	defaultMaxQuoteWords  = 60
+)
+
+// quotation matches text in straight or curly double quotes
+var quotation = regexp.MustCompile(`"[^"]+"|“[^”]+”`)
+
+// newCopyrightCheck reports runs of words copied from the policy's
+// protected passages and quotations too long to be fair use
+func newCopyrightCheck(p Policy) check {
+	size := int(p.Threshold)
+	if size <= 0 {
+		size = defaultCopyrightWords
+	}
+	maxQuote := p.MaxQuoteWords
+	if maxQuote == 0 {
+		maxQuote = defaultMaxQuoteWords
+	}
+	shingles := make(map[uint64]bool)
+	for _, passage := range p.Passages {
+		tokens := tokenize(passage)
+		for i := 0; i+size <= len(tokens); i++ {
+			shingles[shingle(tokens[i:i+size])] = true
+		}
+	}
+
+	return func(text string) []Finding {
+		var findings []Finding
+		if len(shingles) > 0 {
+			findings = append(findings, copiedRuns(p, text, size, shingles)...)
+		}
+		if maxQuote < 0 {
+			return findings
+		}
+		for _, loc := range quotation.FindAllStringIndex(text, -1) {
+			words := wordCount(text[loc[0]:loc[1]])
+			if words <= maxQuote || overlaps(findings, loc[0], loc[1]) {
+				continue
+			}
+			f := finding(p, "quotation", text, loc[0], loc[1])
+			f.Score = float64(words)
+			findings = append(findings, f)
+		}
+		return findings
+	}
+}
+
+// copiedRuns finds the runs of text whose every word is covered by a
+// shingle from a protected passage
+func copiedRuns(p Policy, text string, size int, shingles map[uint64]bool) []Finding {
+	tokens := tokenize(text)
+	covered := make([]bool, len(tokens))
+	for i := 0; i+size <= len(tokens); i++ {
+		if shingles[shingle(tokens[i:i+size])] {
+			for k := i; k < i+size; k++ {
+				covered[k] = true
+			}
+		}
+	}
+	var findings []Finding
+	for i := 0; i < len(tokens); {
+		if !covered[i] {
+			i++
+			continue
+		}
+		j := i
+		for j < len(tokens) && covered[j] {
+			j++
+		}
+		f := finding(p, "passage", text, tokens[i].start, tokens[j-1].end)
+		f.Score = float64(j - i)
+		findings = append(findings, f)
+		i = j
+	}
+	return findings
+}
+
+// shingle hashes a run of words
+func shingle(tokens []token) uint64 {
+	h := fnv.New64a()
+	for _, t := range tokens {
+		h.Write([]byte(t.word))
+		h.Write([]byte{' '})
+	}
+	return h.Sum64()
+}
@@ -0,0 +1,238 @@
+package moderation
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Decisions, from least to most severe
+const (
+	DecisionAllow = "allow"
+	DecisionFlag  = "flag"
+	DecisionBlock = "block"
+)
+
+// maxFindings bounds the findings reported per policy. Blocks are still
+// decided, and fingerprinted, on all of them.
+const maxFindings = 25
+
+// maxExcerpt bounds the characters of text quoted in a finding
+const maxExcerpt = 160
+
+// Finding is a piece of content a policy objected to
+type Finding struct {
+	Policy   string  `json:"policy"`
+	Category string  `json:"category"`
+	Action   string  `json:"action"`
+	Kind     string  `json:"kind"`    // e.g. "insult", "passage", "credit_card"
+	Excerpt  string  `json:"excerpt"` // sensitive values are masked
+	Start    int     `json:"start"`   // character offset into the checked text
+	End      int     `json:"end"`
+	Score    float64 `json:"score,omitempty"` // toxicity per 100 words, or words copied
+	match    string
+	span     [2]int // byte offsets of match
+}
+
+// Result is the outcome of checking content
+type Result struct {
+	Decision    string    `json:"decision"`
+	Findings    []Finding `json:"findings"`
+	Omitted     int       `json:"omitted,omitempty"`     // findings past the per-policy limit
+	Fingerprint string    `json:"fingerprint,omitempty"` // identifies the blocking findings
+	Overridden  bool      `json:"overridden,omitempty"`
+	OverrideID  string    `json:"override_id,omitempty"`
+	CheckedAt   time.Time `json:"checked_at"`
+}
+
+// Blocked reports whether the content may not be published
+func (r *Result) Blocked() bool {
+	return r.Decision == DecisionBlock && !r.Overridden
+}
+
+// Summary describes the findings in a line, e.g. "block: secrets
+// (credit_card x2), toxicity (insult)"
+func (r *Result) Summary() string {
+	if len(r.Findings) == 0 {
+		return r.Decision
+	}
+	type key struct{ policy, kind string }
+	counts := make(map[key]int)
+	var order []key
+	for _, f := range r.Findings {
+		k := key{f.Policy, f.Kind}
+		if counts[k] == 0 {
+			order = append(order, k)
+		}
+		counts[k]++
+	}
+	byPolicy := make(map[string][]string)
+	var policies []string
+	for _, k := range order {
+		if _, ok := byPolicy[k.policy]; !ok {
+			policies = append(policies, k.policy)
+		}
+		kind := k.kind
+		if n := counts[k]; n > 1 {
+			kind = fmt.Sprintf("%s x%d", kind, n)
+		}
+		byPolicy[k.policy] = append(byPolicy[k.policy], kind)
+	}
+	parts := make([]string, len(policies))
+	for i, policy := range policies {
+		parts[i] = fmt.Sprintf("%s (%s)", policy, strings.Join(byPolicy[policy], ", "))
+	}
+	return r.Decision + ": " + strings.Join(parts, ", ")
+}
+
+// Engine checks content against a set of policies
+type Engine struct {
+	policies []Policy
+	checks   []check
+}
+
+// check finds a policy's findings in text
+type check func(text string) []Finding
+
+// NewEngine validates policies and prepares their detectors. Disabled
+// policies are kept but not applied.
+func NewEngine(policies []Policy) (*Engine, error) {
+	e := &Engine{policies: make([]Policy, len(policies))}
+	copy(e.policies, policies)
+	for i := range e.policies {
+		p := &e.policies[i]
+		if err := p.validate(); err != nil {
+			return nil, err
+		}
+		if p.Disabled {
+			continue
+		}
+		switch p.Category {
+		case CategoryToxicity:
+			e.checks = append(e.checks, newToxicityCheck(*p))
+		case CategoryCopyright:
+			e.checks = append(e.checks, newCopyrightCheck(*p))
+		case CategorySensitiveData:
+			e.checks = append(e.checks, newSensitiveCheck(*p))
+		}
+	}
+	return e, nil
+}
+
+// DefaultEngine returns an engine applying DefaultPolicies
+func DefaultEngine() *Engine {
+	e, err := NewEngine(DefaultPolicies())
+	if err != nil {
+		panic(fmt.Sprintf("moderation: invalid default policies: %v", err))
+	}
+	return e
+}
+
+// LoadEngine creates an engine for the policies in a JSON file, or for the
+// defaults when path is empty
+func LoadEngine(path string) (*Engine, error) {
+	policies, err := LoadPolicies(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewEngine(policies)
+}
+
+// Policies returns the engine's policies
+func (e *Engine) Policies() []Policy {
+	out := make([]Policy, len(e.policies))
+	copy(out, e.policies)
+	return out
+}
+
+// Select returns an engine that applies only the named policies
+func (e *Engine) Select(names []string) (*Engine, error) {
+	byName := make(map[string]Policy, len(e.policies))
+	for _, p := range e.policies {
+		byName[p.Name] = p
+	}
+	selected := make([]Policy, 0, len(names))
+	for _, name := range names {
+		p, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: no policy named %q", ErrInvalidPolicy, name)
+		}
+		selected = append(selected, p)
+	}
+	return NewEngine(selected)
+}
+
+// Check applies every enabled policy to text
+func (e *Engine) Check(text string) *Result {
+	result := &Result{Decision: DecisionAllow, Findings: []Finding{}, CheckedAt: time.Now().UTC()}
+	var blocking []string
+	for _, check := range e.checks {
+		findings := check(text)
+		for i, f := range findings {
+			if f.Action == ActionBlock {
+				result.Decision = DecisionBlock
+				blocking = append(blocking, f.Policy+"|"+f.Kind+"|"+strings.ToLower(f.match))
+			} else if result.Decision == DecisionAllow {
+				result.Decision = DecisionFlag
+			}
+			if i >= maxFindings {
+				result.Omitted++
+				continue
+			}
+			result.Findings = append(result.Findings, f)
+		}
+	}
+	if len(blocking) > 0 {
+		result.Fingerprint = fingerprint(blocking)
+	}
+	return result
+}
+
+// fingerprint hashes the blocking matches independently of their order
+// and position, so an override survives edits elsewhere in the content
+// but not new or changed blocking matches
+func fingerprint(matches []string) string {
+	sort.Strings(matches)
+	sum := sha256.Sum256([]byte(strings.Join(matches, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// finding builds a finding for text[start:end], given in bytes
+func finding(p Policy, kind, text string, start, end int) Finding {
+	match := text[start:end]
+	runeStart := utf8.RuneCountInString(text[:start])
+	return Finding{
+		Policy:   p.Name,
+		Category: p.Category,
+		Action:   p.Action,
+		Kind:     kind,
+		Excerpt:  excerpt(match),
+		Start:    runeStart,
+		End:      runeStart + utf8.RuneCountInString(match),
+		match:    match,
+		span:     [2]int{start, end},
+	}
+}
+
+// excerpt shortens text to maxExcerpt characters on a single line
+func excerpt(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if runes := []rune(text); len(runes) > maxExcerpt {
+		return string(runes[:maxExcerpt-1]) + "…"
+	}
+	return text
+}
+
+// overlaps reports whether the bytes [start, end) overlap a finding
+func overlaps(findings []Finding, start, end int) bool {
+	for _, f := range findings {
+		if start < f.span[1] && f.span[0] < end {
+			return true
+		}
+	}
+	return false
+}
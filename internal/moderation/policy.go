@@ -0,0 +1,137 @@
+// Package moderation checks content against configurable policies for
+// toxicity, copyrighted text and sensitive data before it leaves the
+// studio. A policy either flags what it finds or blocks the publish, and
+// blocks can be overridden with a recorded reason.
+package moderation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Policy categories
+const (
+	CategoryToxicity      = "toxicity"
+	CategoryCopyright     = "copyright"
+	CategorySensitiveData = "sensitive_data"
+)
+
+// Policy actions
+const (
+	ActionFlag  = "flag"
+	ActionBlock = "block"
+)
+
+// ErrInvalidPolicy is returned for policies that cannot be applied
+var ErrInvalidPolicy = errors.New("invalid moderation policy")
+
+// Policy is one rule content is checked against. Which fields apply
+// depends on the category:
+//
+//   - toxicity: Threshold is the weighted score per 100 words that trips the
+//     policy (default 2); Terms adds to or reweights the built-in lexicon,
+//     and a weight of 0 removes a term.
+//   - copyright: Passages are protected texts, and Threshold is how many
+//     words in a row must match one (default 12). Quotations longer than
+//     MaxQuoteWords (default 60, negative to disable) are also reported.
+//   - sensitive_data: Kinds selects the detectors (email, phone, ssn,
+//     credit_card, api_key, private_key); all of them when empty.
+type Policy struct {
+	Name          string             `json:"name"`
+	Category      string             `json:"category"`
+	Action        string             `json:"action"`
+	Threshold     float64            `json:"threshold,omitempty"`
+	Terms         map[string]float64 `json:"terms,omitempty"`
+	Passages      []string           `json:"passages,omitempty"`
+	MaxQuoteWords int                `json:"max_quote_words,omitempty"`
+	Kinds         []string           `json:"kinds,omitempty"`
+	Disabled      bool               `json:"disabled,omitempty"`
+}
+
+// DefaultPolicies are applied when no policy file is configured: secrets
+// and government IDs block, everything else is flagged for a person to look
+// at
+func DefaultPolicies() []Policy {
+	return []Policy{
+		{Name: "toxicity", Category: CategoryToxicity, Action: ActionFlag},
+		{Name: "long_quotations", Category: CategoryCopyright, Action: ActionFlag},
+		{Name: "secrets", Category: CategorySensitiveData, Action: ActionBlock, Kinds: []string{KindSSN, KindCreditCard, KindAPIKey, KindPrivateKey}},
+		{Name: "contact_details", Category: CategorySensitiveData, Action: ActionFlag, Kinds: []string{KindEmail, KindPhone}},
+	}
+}
+
+// ParsePolicies reads policies from JSON, either a list or an object with
+// a policies list, and validates them
+func ParsePolicies(data []byte) ([]Policy, error) {
+	var policies []Policy
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "{") {
+		var wrapper struct {
+			Policies []Policy `json:"policies"`
+		}
+		if err := json.Unmarshal(data, &wrapper); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPolicy, err)
+		}
+		policies = wrapper.Policies
+	} else if err := json.Unmarshal(data, &policies); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPolicy, err)
+	}
+
+	names := make(map[string]bool, len(policies))
+	for i := range policies {
+		p := &policies[i]
+		if err := p.validate(); err != nil {
+			return nil, err
+		}
+		if names[p.Name] {
+			return nil, fmt.Errorf("%w: duplicate policy %q", ErrInvalidPolicy, p.Name)
+		}
+		names[p.Name] = true
+	}
+	return policies, nil
+}
+
+// LoadPolicies reads policies from a JSON file, or returns the defaults
+// when path is empty
+func LoadPolicies(path string) ([]Policy, error) {
+	if path == "" {
+		return DefaultPolicies(), nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read moderation policies: %w", err)
+	}
+	return ParsePolicies(data)
+}
+
+// validate checks a policy and fills in its name and action defaults
+func (p *Policy) validate() error {
+	p.Category = strings.ToLower(strings.TrimSpace(p.Category))
+	p.Action = strings.ToLower(strings.TrimSpace(p.Action))
+	if p.Name == "" {
+		p.Name = p.Category
+	}
+	if p.Action == "" {
+		p.Action = ActionFlag
+	}
+	if p.Action != ActionFlag && p.Action != ActionBlock {
+		return fmt.Errorf("%w: %s has unknown action %q (use flag or block)", ErrInvalidPolicy, p.Name, p.Action)
+	}
+	if p.Threshold < 0 {
+		return fmt.Errorf("%w: %s has a negative threshold", ErrInvalidPolicy, p.Name)
+	}
+	switch p.Category {
+	case CategoryToxicity, CategoryCopyright:
+	case CategorySensitiveData:
+		for _, kind := range p.Kinds {
+			if _, ok := sensitivePatterns[kind]; !ok {
+				return fmt.Errorf("%w: %s has unknown kind %q", ErrInvalidPolicy, p.Name, kind)
+			}
+		}
+	default:
+		return fmt.Errorf("%w: %s has unknown category %q (use toxicity, copyright or sensitive_data)", ErrInvalidPolicy, p.Name, p.Category)
+	}
+	return nil
+}
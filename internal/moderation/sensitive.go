@@ -0,0 +1,149 @@
+package moderation
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Sensitive data kinds
+const (
+	KindEmail      = "email"
+	KindPhone      = "phone"
+	KindSSN        = "ssn"
+	KindCreditCard = "credit_card"
+	KindAPIKey     = "api_key"
+	KindPrivateKey = "private_key"
+)
+
+// sensitiveKinds is the order sensitive data is looked for in. Earlier
+// kinds win where matches overlap, so card numbers are not also reported as
+// phone numbers.
+var sensitiveKinds = []string{KindPrivateKey, KindAPIKey, KindCreditCard, KindSSN, KindEmail, KindPhone}
+
+// sensitivePatterns find candidate values of each kind
+var sensitivePatterns = map[string]*regexp.Regexp{
+	KindEmail:      regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`),
+	KindPhone:      regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?(?:\(\d{2,4}\)|\d{2,4})[ .-]?\d{3,4}[ .-]?\d{3,4}`),
+	KindSSN:        regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`),
+	KindCreditCard: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`),
+	KindAPIKey:     regexp.MustCompile(`\b(?:AKIA[0-9A-Z]{16}|sk-[A-Za-z0-9_-]{20,}|gh[pousr]_[A-Za-z0-9]{36}|github_pat_[A-Za-z0-9_]{22,}|xox[abprs]-[A-Za-z0-9-]{10,}|AIza[0-9A-Za-z_-]{35})`),
+	KindPrivateKey: regexp.MustCompile(`-----BEGIN (?:[A-Z]+ )*PRIVATE KEY-----`),
+}
+
+// sensitiveValid rejects candidates that only look like a kind
+var sensitiveValid = map[string]func(text string, loc []int) bool{
+	KindPhone: func(text string, loc []int) bool {
+		value := text[loc[0]:loc[1]]
+		digits := len(digitsOf(value))
+		// Bare digit runs are more often IDs, years or amounts than phones
+		formatted := strings.ContainsAny(value, " .-()") || strings.HasPrefix(value, "+")
+		return digits >= 10 && digits <= 15 && formatted && isWordBoundary(text, loc[0]) &&
+			!nextToDigits(text, loc[0], -1) && !nextToDigits(text, loc[1], 1)
+	},
+	KindSSN: func(text string, loc []int) bool {
+		digits := digitsOf(text[loc[0]:loc[1]])
+		area, group, serial := digits[:3], digits[3:5], digits[5:]
+		return area != "000" && area != "666" && area[0] != '9' && group != "00" && serial != "0000"
+	},
+	KindCreditCard: func(text string, loc []int) bool {
+		return luhn(digitsOf(text[loc[0]:loc[1]]))
+	},
+}
+
+// newSensitiveCheck reports the sensitive values of the policy's kinds,
+// masked so the report does not repeat them
+func newSensitiveCheck(p Policy) check {
+	enabled := make(map[string]bool, len(p.Kinds))
+	for _, kind := range p.Kinds {
+		enabled[kind] = true
+	}
+	return func(text string) []Finding {
+		var findings []Finding
+		for _, kind := range sensitiveKinds {
+			if len(enabled) > 0 && !enabled[kind] {
+				continue
+			}
+			valid := sensitiveValid[kind]
+			for _, loc := range sensitivePatterns[kind].FindAllStringIndex(text, -1) {
+				if overlaps(findings, loc[0], loc[1]) || (valid != nil && !valid(text, loc)) {
+					continue
+				}
+				f := finding(p, kind, text, loc[0], loc[1])
+				f.Excerpt = mask(kind, f.match)
+				findings = append(findings, f)
+			}
+		}
+		return findings
+	}
+}
+
+// mask hides all but enough of a value to recognize it: an email's first
+// letter and domain, a key's prefix, and the last four digits otherwise
+func mask(kind, value string) string {
+	switch kind {
+	case KindEmail:
+		at := strings.LastIndex(value, "@")
+		return value[:1] + "***" + value[at:]
+	case KindPrivateKey:
+		return value
+	case KindAPIKey:
+		return value[:4] + strings.Repeat("*", len(value)-4)
+	}
+	digits := 0
+	masked := []rune(value)
+	for i := len(masked) - 1; i >= 0; i-- {
+		if !unicode.IsDigit(masked[i]) {
+			continue
+		}
+		if digits++; digits > 4 {
+			masked[i] = '*'
+		}
+	}
+	return string(masked)
+}
+
+// nextToDigits reports whether a digit sits at byte i of text, looking
+// back from i when dir is negative, possibly past one separator. Phone-like
+// runs inside longer digit groups, such as card numbers, are not phones.
+func nextToDigits(text string, i, dir int) bool {
+	if dir < 0 {
+		i--
+	}
+	for step := 0; step < 2 && i >= 0 && i < len(text); step++ {
+		switch c := text[i]; {
+		case c >= '0' && c <= '9':
+			return true
+		case c != ' ' && c != '.' && c != '-':
+			return false
+		}
+		i += dir
+	}
+	return false
+}
+
+// digitsOf returns the digits of a value
+func digitsOf(value string) string {
+	var b strings.Builder
+	for _, r := range value {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// luhn reports whether digits pass the Luhn checksum card numbers carry
+func luhn(digits string) bool {
+	sum := 0
+	for i := 0; i < len(digits); i++ {
+		d := int(digits[len(digits)-1-i] - '0')
+		if i%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return len(digits) > 0 && sum%10 == 0
+}
@@ -0,0 +1,180 @@
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/memmieai/memmie-studio/internal/blob"
+)
+
+// ProviderID owns override records
+const ProviderID = "moderation"
+
+// KindOverride marks override records
+const KindOverride = "moderation_override"
+
+var (
+	// ErrBlocked is returned when a policy blocks content from being
+	// published
+	ErrBlocked = errors.New("blocked by content policy")
+
+	// ErrInvalidOverride is returned when an override has no reason or
+	// nothing to override
+	ErrInvalidOverride = errors.New("invalid moderation override")
+)
+
+// Override records that a user let blocked content through. It covers the
+// blocking findings it was made for; content that is blocked for anything
+// else needs a new override.
+type Override struct {
+	ID          string    `json:"id"`
+	SubjectID   string    `json:"subject_id"`
+	Target      string    `json:"target"` // what was being published, e.g. "export" or "github_publish"
+	Fingerprint string    `json:"fingerprint"`
+	Reason      string    `json:"reason"`
+	UserID      string    `json:"user_id"`
+	Findings    []Finding `json:"findings"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Service checks content before it is published and keeps the audit trail
+// of overrides
+type Service struct {
+	blobs  blob.Store
+	engine *Engine
+}
+
+// NewService creates a moderation service
+func NewService(blobs blob.Store, engine *Engine) *Service {
+	return &Service{blobs: blobs, engine: engine}
+}
+
+// Engine returns the service's policy engine
+func (s *Service) Engine() *Engine {
+	return s.engine
+}
+
+// Check checks a subject's content against engine's policies, or the
+// service's when engine is nil, marking a block as overridden when an
+// override covers it
+func (s *Service) Check(ctx context.Context, engine *Engine, userID, subjectID, content string) (*Result, error) {
+	if engine == nil {
+		engine = s.engine
+	}
+	result := engine.Check(content)
+	if result.Decision != DecisionBlock {
+		return result, nil
+	}
+	overrides, err := s.Overrides(ctx, userID, subjectID)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range overrides {
+		if o.Fingerprint == result.Fingerprint {
+			result.Overridden, result.OverrideID = true, o.ID
+			break
+		}
+	}
+	return result, nil
+}
+
+// Gate checks content about to be published, returning ErrBlocked along
+// with the result when a policy blocks it. Flagged content passes.
+func (s *Service) Gate(ctx context.Context, engine *Engine, userID, subjectID, content string) (*Result, error) {
+	result, err := s.Check(ctx, engine, userID, subjectID, content)
+	if err != nil {
+		return nil, err
+	}
+	if result.Blocked() {
+		return result, fmt.Errorf("%w: %s", ErrBlocked, result.Summary())
+	}
+	return result, nil
+}
+
+// CheckBlob checks a blob's current content
+func (s *Service) CheckBlob(ctx context.Context, userID, blobID string) (*Result, error) {
+	b, err := s.blobs.GetBlob(ctx, userID, blobID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Check(ctx, nil, userID, blobID, b.Content)
+}
+
+// Override records a user's reason for publishing content a result blocked.
+// The record is kept as a child blob of the subject.
+func (s *Service) Override(ctx context.Context, userID, subjectID, target string, result *Result, reason string) (*Override, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, fmt.Errorf("%w: reason is required", ErrInvalidOverride)
+	}
+	if result == nil || result.Decision != DecisionBlock || result.Fingerprint == "" {
+		return nil, fmt.Errorf("%w: the content is not blocked", ErrInvalidOverride)
+	}
+	var blocking []Finding
+	for _, f := range result.Findings {
+		if f.Action == ActionBlock {
+			blocking = append(blocking, f)
+		}
+	}
+	o := &Override{
+		SubjectID:   subjectID,
+		Target:      target,
+		Fingerprint: result.Fingerprint,
+		Reason:      reason,
+		UserID:      userID,
+		Findings:    blocking,
+		CreatedAt:   time.Now().UTC(),
+	}
+	content, err := json.Marshal(o)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode override: %w", err)
+	}
+	parentID := subjectID
+	created, err := s.blobs.CreateBlob(ctx, &blob.Blob{
+		UserID:     userID,
+		ProviderID: ProviderID,
+		Content:    string(content),
+		ParentID:   &parentID,
+		Metadata: map[string]interface{}{
+			"kind":        KindOverride,
+			"subject_id":  subjectID,
+			"target":      target,
+			"fingerprint": o.Fingerprint,
+			"reason":      reason,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store override: %w", err)
+	}
+	o.ID = created.ID
+	return o, nil
+}
+
+// Overrides returns the audit trail of a subject's overrides, newest first
+func (s *Service) Overrides(ctx context.Context, userID, subjectID string) ([]*Override, error) {
+	children, err := s.blobs.ListBlobs(ctx, userID, blob.Filter{ProviderID: ProviderID, ParentID: subjectID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list overrides: %w", err)
+	}
+	overrides := []*Override{}
+	for _, child := range children {
+		if child.Metadata["kind"] != KindOverride {
+			continue
+		}
+		var o Override
+		if err := json.Unmarshal([]byte(child.Content), &o); err != nil {
+			return nil, fmt.Errorf("failed to decode override %s: %w", child.ID, err)
+		}
+		o.ID = child.ID
+		overrides = append(overrides, &o)
+	}
+	sort.SliceStable(overrides, func(i, j int) bool {
+		return overrides[i].CreatedAt.After(overrides[j].CreatedAt)
+	})
+	return overrides, nil
+}
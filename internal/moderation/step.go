@@ -0,0 +1,155 @@
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// StepType is the workflow step type that checks a blob against the
+// content policies
+const StepType = "moderation_check"
+
+// ModerationPath is the blob metadata path a check's result is written to
+const ModerationPath = "metadata.moderation"
+
+// NewStepExecutor creates the executor that checks the blob being
+// processed. Step inputs: content, the blob's current content when absent;
+// policies, the names of the policies to apply, all of them when absent;
+// and fail_on_block, which fails the step on a block so later steps do not
+// run. The last two fall back to the step's parameters. Without
+// fail_on_block, later steps can be conditioned on the output's decision.
+func NewStepExecutor(service *Service) workflows.StepExecutor {
+	return workflows.StepExecutorFunc(func(ctx context.Context, req workflows.StepRequest) (map[string]interface{}, error) {
+		engine, err := selectPolicies(service, req.Setting("policies"))
+		if err != nil {
+			return nil, err
+		}
+		content, ok := req.Input["content"].(string)
+		if !ok {
+			b, err := service.blobs.GetBlob(ctx, req.Context.UserID, req.Context.BlobID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load blob: %w", err)
+			}
+			content = b.Content
+		}
+
+		result, err := service.Check(ctx, engine, req.Context.UserID, req.Context.BlobID, content)
+		if err != nil {
+			return nil, err
+		}
+		if failOnBlock, _ := req.Setting("fail_on_block").(bool); failOnBlock && result.Blocked() {
+			return nil, fmt.Errorf("%w: %s", ErrBlocked, result.Summary())
+		}
+
+		summary := map[string]interface{}{
+			"decision":   result.Decision,
+			"summary":    result.Summary(),
+			"findings":   len(result.Findings) + result.Omitted,
+			"overridden": result.Overridden,
+			"checked_at": result.CheckedAt,
+		}
+		return map[string]interface{}{
+			"decision": result.Decision,
+			"blocked":  result.Blocked(),
+			"summary":  result.Summary(),
+			"result":   result,
+			"deltas": []interface{}{
+				map[string]interface{}{
+					"type":      "update",
+					"path":      ModerationPath,
+					"new_value": summary,
+					"metadata": map[string]interface{}{
+						"step_id":      req.Step.ID,
+						"execution_id": req.ExecutionID,
+					},
+				},
+			},
+		}, nil
+	})
+}
+
+// NewGate wraps a step executor that publishes content outside the studio
+// so it only runs when the text in its input passes the content policies.
+// A block fails the step, naming what was found; flagged content is
+// published and the result added to the step's output as moderation. The
+// step's moderation_policies parameter selects the policies to apply.
+func NewGate(service *Service, next workflows.StepExecutor) workflows.StepExecutor {
+	return workflows.StepExecutorFunc(func(ctx context.Context, req workflows.StepRequest) (map[string]interface{}, error) {
+		engine, err := selectPolicies(service, req.Step.Config.Parameters["moderation_policies"])
+		if err != nil {
+			return nil, err
+		}
+		var texts []string
+		collectText(req.Input, &texts)
+		result, err := service.Gate(ctx, engine, req.Context.UserID, req.Context.BlobID, strings.Join(texts, "\n\n"))
+		if err != nil {
+			return nil, err
+		}
+
+		output, err := next.Execute(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		if result.Decision != DecisionAllow {
+			if output == nil {
+				output = make(map[string]interface{})
+			}
+			output["moderation"] = result
+		}
+		return output, nil
+	})
+}
+
+// selectPolicies returns the engine for a list of policy names, or nil for
+// the service's own when the list is empty
+func selectPolicies(service *Service, value interface{}) (*Engine, error) {
+	var names []string
+	switch v := value.(type) {
+	case string:
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if name, ok := item.(string); ok && name != "" {
+				names = append(names, name)
+			}
+		}
+	case []string:
+		names = v
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+	return service.engine.Select(names)
+}
+
+// collectText gathers the strings in a step input, visiting map keys in
+// order so the checked text is stable
+func collectText(value interface{}, texts *[]string) {
+	switch v := value.(type) {
+	case string:
+		if strings.TrimSpace(v) != "" {
+			*texts = append(*texts, v)
+		}
+	case []interface{}:
+		for _, item := range v {
+			collectText(item, texts)
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			collectText(v[k], texts)
+		}
+	}
+}
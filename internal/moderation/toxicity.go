@@ -0,0 +1,187 @@
+package moderation
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Toxicity scoring defaults
+const (
+	defaultToxicityThreshold = 2.0
+	toxicityWindow           = 100 // words a score is taken over
+	minToxicityWindow        = 25  // shorter texts are scored as if this long
+)
+
+// lexiconEntry is a term's weight and what kind of language it is
+type lexiconEntry struct {
+	weight float64
+	kind   string
+}
+
+// lexicon is the built-in toxicity vocabulary. It is deliberately small and
+// unambiguous; deployments extend it with a policy's terms.
+var lexicon = map[string]lexiconEntry{
+	"fuck":            {1, "profanity"},
+	"fucking":         {1, "profanity"},
+	"motherfucker":    {1.5, "profanity"},
+	"shit":            {0.75, "profanity"},
+	"bullshit":        {0.75, "profanity"},
+	"asshole":         {1, "profanity"},
+	"bastard":         {0.75, "profanity"},
+	"bitch":           {1, "profanity"},
+	"idiot":           {1, "insult"},
+	"moron":           {1, "insult"},
+	"imbecile":        {1, "insult"},
+	"worthless":       {0.75, "insult"},
+	"pathetic":        {0.5, "insult"},
+	"scumbag":         {1, "insult"},
+	"shut up":         {0.5, "insult"},
+	"you are stupid":  {1.5, "insult"},
+	"you're stupid":   {1.5, "insult"},
+	"kill you":        {2.5, "threat"},
+	"kill yourself":   {3, "threat"},
+	"hope you die":    {3, "threat"},
+	"burn your house": {3, "threat"},
+	"hurt your":       {1.5, "threat"},
+}
+
+// token is a lowercased word and its byte offsets in the text
+type token struct {
+	word       string
+	start, end int
+}
+
+// tokenize splits text into words of letters, digits and apostrophes
+func tokenize(text string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range text {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r) || r == '\'' || r == '’'
+		if isWord && start < 0 {
+			start = i
+		} else if !isWord && start >= 0 {
+			tokens = append(tokens, newToken(text, start, i))
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, newToken(text, start, len(text)))
+	}
+	return tokens
+}
+
+// newToken normalizes the word at text[start:end]
+func newToken(text string, start, end int) token {
+	word := strings.ToLower(strings.ReplaceAll(text[start:end], "’", "'"))
+	return token{word: strings.Trim(word, "'"), start: start, end: end}
+}
+
+// phrase is a lexicon term split into words
+type phrase struct {
+	words []string
+	lexiconEntry
+}
+
+// termMatch is a phrase found at a run of tokens
+type termMatch struct {
+	first, last int // token indexes
+	lexiconEntry
+}
+
+// newToxicityCheck scores text by the weight of the lexicon terms in each
+// window of words, and reports the terms in windows over the threshold
+func newToxicityCheck(p Policy) check {
+	threshold := p.Threshold
+	if threshold == 0 {
+		threshold = defaultToxicityThreshold
+	}
+	entries := make(map[string]lexiconEntry, len(lexicon)+len(p.Terms))
+	for term, entry := range lexicon {
+		entries[term] = entry
+	}
+	for term, weight := range p.Terms {
+		term = strings.ToLower(strings.TrimSpace(term))
+		if weight == 0 {
+			delete(entries, term)
+			continue
+		}
+		kind := "term"
+		if existing, ok := entries[term]; ok {
+			kind = existing.kind
+		}
+		entries[term] = lexiconEntry{weight: weight, kind: kind}
+	}
+	phrases := make(map[string][]phrase)
+	for term, entry := range entries {
+		var words []string
+		for _, t := range tokenize(term) {
+			words = append(words, t.word)
+		}
+		if len(words) > 0 {
+			phrases[words[0]] = append(phrases[words[0]], phrase{words: words, lexiconEntry: entry})
+		}
+	}
+
+	return func(text string) []Finding {
+		tokens := tokenize(text)
+		var matches []termMatch
+		for i, t := range tokens {
+			for _, ph := range phrases[t.word] {
+				if phraseAt(tokens, i, ph.words) {
+					matches = append(matches, termMatch{first: i, last: i + len(ph.words) - 1, lexiconEntry: ph.lexiconEntry})
+				}
+			}
+		}
+
+		span := float64(max(min(toxicityWindow, len(tokens)), minToxicityWindow))
+		var findings []Finding
+		for j := 0; j < len(matches); {
+			end := matches[j].first + toxicityWindow
+			total, k := 0.0, j
+			for ; k < len(matches) && matches[k].first < end; k++ {
+				total += matches[k].weight
+			}
+			score := total * 100 / span
+			if score < threshold {
+				j++
+				continue
+			}
+			for _, m := range matches[j:k] {
+				f := finding(p, m.kind, text, tokens[m.first].start, tokens[m.last].end)
+				f.Score = float64(int(score*100)) / 100
+				findings = append(findings, f)
+			}
+			j = k
+		}
+		return findings
+	}
+}
+
+// phraseAt reports whether the words start at tokens[i]
+func phraseAt(tokens []token, i int, words []string) bool {
+	if i+len(words) > len(tokens) {
+		return false
+	}
+	for k, word := range words {
+		if tokens[i+k].word != word {
+			return false
+		}
+	}
+	return true
+}
+
+// wordCount counts the words in text
+func wordCount(text string) int {
+	return len(tokenize(text))
+}
+
+// isWordBoundary reports whether the byte at i in text does not continue a
+// word or number
+func isWordBoundary(text string, i int) bool {
+	if i <= 0 || i >= len(text) {
+		return true
+	}
+	r, _ := utf8.DecodeLastRuneInString(text[:i])
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
@@ -0,0 +1,142 @@
+// Package outputlimit caps the size of workflow step outputs, so a runaway
+// model response or a giant dataset transform is cut down, moved to blob
+// storage or failed before downstream steps and the execution backend have
+// to handle it.
+package outputlimit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/memmieai/memmie-studio/internal/blob"
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// MetadataKey is the output key describing an output that was over its
+// limit
+const MetadataKey = "_output_limit"
+
+// ErrOutputTooLarge is returned for outputs over their limit under the fail
+// policy, and when the other policies cannot bring an output within it
+var ErrOutputTooLarge = errors.New("step output too large")
+
+// Limit is the largest output a step may return, in bytes of JSON, and the
+// output policy applied to larger outputs. MaxBytes 0 means no limit and an
+// empty policy means truncate.
+type Limit struct {
+	MaxBytes int
+	Policy   string
+}
+
+// Limiter holds step outputs to their limits
+type Limiter struct {
+	defaults Limit
+	blobs    blob.Store
+}
+
+// NewLimiter creates a limiter applying defaults to steps that set no limit
+// of their own. Spilled outputs are written to blobs; with blobs nil the
+// spill policy fails instead.
+func NewLimiter(defaults Limit, blobs blob.Store) *Limiter {
+	return &Limiter{defaults: defaults, blobs: blobs}
+}
+
+// Wrap returns an executor whose outputs are held to each step's
+// max_output_bytes and output_policy, or to the limiter's defaults
+func (l *Limiter) Wrap(executor workflows.StepExecutor) workflows.StepExecutor {
+	return workflows.StepExecutorFunc(func(ctx context.Context, req workflows.StepRequest) (map[string]interface{}, error) {
+		output, err := executor.Execute(ctx, req)
+		if err != nil || output == nil {
+			return output, err
+		}
+		return l.enforce(ctx, req, output)
+	})
+}
+
+// limitFor returns a step's limit, each part falling back to the default
+func (l *Limiter) limitFor(step workflows.BlobProcessingStep) Limit {
+	limit := l.defaults
+	if step.Config.MaxOutputBytes > 0 {
+		limit.MaxBytes = step.Config.MaxOutputBytes
+	}
+	if step.Config.OutputPolicy != "" {
+		limit.Policy = step.Config.OutputPolicy
+	}
+	if limit.Policy == "" {
+		limit.Policy = workflows.OutputPolicyTruncate
+	}
+	return limit
+}
+
+// enforce applies a step's limit to its output
+func (l *Limiter) enforce(ctx context.Context, req workflows.StepRequest, output map[string]interface{}) (map[string]interface{}, error) {
+	limit := l.limitFor(req.Step)
+	if limit.MaxBytes <= 0 {
+		return output, nil
+	}
+	data, err := json.Marshal(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to measure output of step %s: %w", req.Step.ID, err)
+	}
+	if len(data) <= limit.MaxBytes {
+		return output, nil
+	}
+	tooLarge := fmt.Errorf("%w: step %s returned %d bytes, limit %d", ErrOutputTooLarge, req.Step.ID, len(data), limit.MaxBytes)
+
+	switch limit.Policy {
+	case workflows.OutputPolicySpill:
+		if l.blobs == nil {
+			return nil, fmt.Errorf("%w; no blob store to spill to", tooLarge)
+		}
+		return l.spill(ctx, req, data, limit)
+	case workflows.OutputPolicyTruncate:
+		// Truncate a decoded copy, so every value is a plain JSON type and
+		// the executor's own output is left alone
+		var decoded map[string]interface{}
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			return nil, fmt.Errorf("failed to decode output of step %s: %w", req.Step.ID, err)
+		}
+		if !truncate(decoded, len(data), limit.MaxBytes) {
+			return nil, fmt.Errorf("%w; truncating could not make it fit", tooLarge)
+		}
+		return decoded, nil
+	default:
+		return nil, tooLarge
+	}
+}
+
+// spill stores an output in a blob derived from the execution's blob and
+// returns an output pointing at it
+func (l *Limiter) spill(ctx context.Context, req workflows.StepRequest, data []byte, limit Limit) (map[string]interface{}, error) {
+	spilled := &blob.Blob{
+		UserID:     req.Context.UserID,
+		ProviderID: req.Context.ProviderID,
+		Content:    string(data),
+		Metadata: map[string]interface{}{
+			"kind":         "step_output",
+			"execution_id": req.ExecutionID,
+			"workflow_id":  req.WorkflowID,
+			"step_id":      req.Step.ID,
+			"size_bytes":   len(data),
+		},
+	}
+	if req.Context.BlobID != "" {
+		parentID := req.Context.BlobID
+		spilled.ParentID = &parentID
+		spilled.Metadata["derived_from"] = parentID
+	}
+	created, err := l.blobs.CreateBlob(ctx, spilled)
+	if err != nil {
+		return nil, fmt.Errorf("failed to spill output of step %s: %w", req.Step.ID, err)
+	}
+	return map[string]interface{}{
+		MetadataKey: map[string]interface{}{
+			"policy":         workflows.OutputPolicySpill,
+			"original_bytes": len(data),
+			"limit_bytes":    limit.MaxBytes,
+			"blob_id":        created.ID,
+		},
+	}, nil
+}
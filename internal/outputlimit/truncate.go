@@ -0,0 +1,148 @@
+package outputlimit
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+const (
+	// marker ends every string that was cut
+	marker = "…"
+
+	// minCutBytes is the smallest string worth cutting
+	minCutBytes = 16
+
+	// maxCuts bounds the passes truncate makes over an output
+	maxCuts = 100
+)
+
+// cut is a string or array that truncation can shorten
+type cut struct {
+	path  string
+	size  int
+	value interface{}
+	set   func(interface{})
+}
+
+// truncate shortens the largest strings and arrays of a decoded output
+// until its JSON fits in maxBytes, recording what it cut under MetadataKey.
+// Strings keep their beginning, ending in the marker; arrays keep their
+// first elements. It reports false when the output cannot be made to fit.
+func truncate(output map[string]interface{}, originalBytes, maxBytes int) bool {
+	paths := []interface{}{}
+	seen := make(map[string]bool)
+	report := map[string]interface{}{
+		"policy":         workflows.OutputPolicyTruncate,
+		"original_bytes": originalBytes,
+		"limit_bytes":    maxBytes,
+	}
+	output[MetadataKey] = report
+
+	for i := 0; i < maxCuts; i++ {
+		report["truncated"] = paths
+		var cuts []cut
+		size := measure(output, "", nil, &cuts)
+		if size <= maxBytes {
+			return true
+		}
+		if len(cuts) == 0 {
+			return false
+		}
+		sort.SliceStable(cuts, func(i, j int) bool { return cuts[i].size > cuts[j].size })
+		largest := cuts[0]
+		// Keep the share of the value that leaves the output at the limit
+		target := largest.size - (size - maxBytes)
+		switch v := largest.value.(type) {
+		case string:
+			keep := 0
+			if target > len(marker)+2 {
+				keep = len(v) * (target - len(marker) - 2) / (largest.size - 2)
+			}
+			if keep >= len(v) {
+				keep = len(v) - 1
+			}
+			for keep > 0 && !utf8.RuneStart(v[keep]) {
+				keep--
+			}
+			largest.set(v[:keep] + marker)
+		case []interface{}:
+			keep := 0
+			if target > 2 {
+				keep = len(v) * target / largest.size
+			}
+			if keep >= len(v) {
+				keep = len(v) - 1
+			}
+			largest.set(v[:keep])
+		}
+		if !seen[largest.path] {
+			seen[largest.path] = true
+			paths = append(paths, largest.path)
+		}
+	}
+	return false
+}
+
+// measure returns the size of a decoded JSON value's encoding, collecting
+// the strings and arrays within it that can be cut. set replaces the value
+// in its parent.
+func measure(value interface{}, path string, set func(interface{}), cuts *[]cut) int {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		size := 2
+		n := 0
+		for key, item := range v {
+			if path == "" && key == MetadataKey {
+				size += encodedSize(key) + 1 + encodedSize(item)
+			} else {
+				key := key
+				size += encodedSize(key) + 1 + measure(item, path+"/"+escapePointer(key), func(x interface{}) { v[key] = x }, cuts)
+			}
+			n++
+		}
+		if n > 1 {
+			size += n - 1
+		}
+		return size
+	case []interface{}:
+		size := 2
+		for i, item := range v {
+			i := i
+			size += measure(item, path+"/"+strconv.Itoa(i), func(x interface{}) { v[i] = x }, cuts)
+		}
+		if len(v) > 1 {
+			size += len(v) - 1
+		}
+		// A single element is cut inside rather than dropped
+		if len(v) > 1 && set != nil {
+			*cuts = append(*cuts, cut{path: path, size: size, value: v, set: set})
+		}
+		return size
+	case string:
+		size := encodedSize(v)
+		if size > minCutBytes && set != nil {
+			*cuts = append(*cuts, cut{path: path, size: size, value: v, set: set})
+		}
+		return size
+	}
+	return encodedSize(value)
+}
+
+// encodedSize returns the length of a value's JSON encoding
+func encodedSize(value interface{}) int {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return 0
+	}
+	return len(data)
+}
+
+// escapePointer escapes a key for use in a JSON pointer
+func escapePointer(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
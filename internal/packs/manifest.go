@@ -0,0 +1,439 @@
+package packs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// PromptPrefix starts a step input or parameter that is replaced by one of
+// the pack's prompt templates when the pack is installed
+const PromptPrefix = "$.prompts."
+
+// maxNameLength bounds pack names and the IDs of what packs define
+const maxNameLength = 64
+
+// Manifest describes a workflow pack: the workflows, schemas, prompt
+// templates and providers it installs into a namespace, and the packs it
+// requires to be installed there first, by name, with the versions it
+// accepts of each.
+//
+// Within a pack, workflows, schemas and providers are named by plain IDs,
+// and a required pack's by its name and ID, such as "outline.chapter".
+// Installed, each is registered as pack.id:namespace, so the same pack can
+// serve several namespaces.
+type Manifest struct {
+	Name        string                              `json:"name"`
+	Version     string                              `json:"version"`
+	Description string                              `json:"description,omitempty"`
+	Requires    map[string]string                   `json:"requires,omitempty"`
+	Workflows   []*workflows.BlobProcessingWorkflow `json:"workflows,omitempty"`
+	Schemas     map[string]map[string]interface{}   `json:"schemas,omitempty"`
+	Prompts     map[string]string                   `json:"prompts,omitempty"`
+	Providers   []ProviderRequirement               `json:"providers,omitempty"`
+}
+
+// ProviderRequirement is a provider a pack runs its workflows with, built
+// from a bundled provider definition that must be registered: it takes the
+// definition's type and configuration, with Parameters over the
+// definition's, and fires on Triggers for the namespace's blobs only
+type ProviderRequirement struct {
+	ID         string                    `json:"id"`
+	Definition string                    `json:"definition"`
+	Workflows  []string                  `json:"workflows"`
+	Triggers   []workflows.TriggerConfig `json:"triggers"`
+	Parameters map[string]interface{}    `json:"parameters,omitempty"`
+}
+
+// ParseManifest reads a JSON manifest, rejecting unknown fields so that
+// misspelt ones are not silently dropped
+func ParseManifest(data []byte) (*Manifest, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var manifest Manifest
+	if err := decoder.Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidManifest, err)
+	}
+	return &manifest, nil
+}
+
+// Validate checks a manifest on its own: its name, version and version
+// constraints, that IDs are unique plain names and that its references to
+// prompts, workflows and required packs resolve. Workflow definitions are
+// checked once they are built for a namespace.
+func (m *Manifest) Validate() error {
+	var problems []string
+	problem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if !validName(m.Name) {
+		problem("name %q must be lowercase letters, digits and hyphens, starting with a letter", m.Name)
+	}
+	if _, err := ParseVersion(m.Version); err != nil {
+		problem("version %q is not a semantic version", m.Version)
+	}
+	for _, name := range sortedNames(m.Requires) {
+		if name == m.Name {
+			problem("the pack requires itself")
+		} else if !validName(name) {
+			problem("requires an invalid pack name %q", name)
+		}
+		if _, err := ParseConstraint(m.Requires[name]); err != nil {
+			problem("requires %s %q, which is not a version constraint", name, m.Requires[name])
+		}
+	}
+
+	workflowIDs := make(map[string]bool, len(m.Workflows))
+	for i, workflow := range m.Workflows {
+		if workflow == nil {
+			problem("workflow %d is empty", i)
+			continue
+		}
+		if !validID(workflow.ID) {
+			problem("workflow id %q must be a plain name", workflow.ID)
+		} else if workflowIDs[workflow.ID] {
+			problem("workflow %s is defined twice", workflow.ID)
+		}
+		workflowIDs[workflow.ID] = true
+		for _, step := range workflow.Steps {
+			for _, name := range promptReferences(step.InputMap, step.Config.Parameters) {
+				if _, ok := m.Prompts[name]; !ok {
+					problem("step %s of workflow %s uses unknown prompt %q", step.ID, workflow.ID, name)
+				}
+			}
+		}
+	}
+	schemaIDs := make([]string, 0, len(m.Schemas))
+	for id := range m.Schemas {
+		schemaIDs = append(schemaIDs, id)
+	}
+	sort.Strings(schemaIDs)
+	for _, id := range schemaIDs {
+		if !validID(id) {
+			problem("schema id %q must be a plain name", id)
+		} else if m.Schemas[id] == nil {
+			problem("schema %s has no definition", id)
+		}
+	}
+	for _, name := range sortedNames(m.Prompts) {
+		if !validID(name) {
+			problem("prompt name %q must be a plain name", name)
+		}
+	}
+
+	providerIDs := make(map[string]bool, len(m.Providers))
+	for _, requirement := range m.Providers {
+		if !validID(requirement.ID) {
+			problem("provider id %q must be a plain name", requirement.ID)
+		} else if providerIDs[requirement.ID] {
+			problem("provider %s is defined twice", requirement.ID)
+		}
+		providerIDs[requirement.ID] = true
+		if requirement.Definition == "" {
+			problem("provider %s names no provider definition", requirement.ID)
+		}
+		if len(requirement.Workflows) == 0 {
+			problem("provider %s runs no workflows", requirement.ID)
+		}
+		for _, ref := range requirement.Workflows {
+			if pack, _, ok := m.foreign(ref); ok {
+				if _, required := m.Requires[pack]; !required {
+					problem("provider %s runs workflow %s of pack %s, which is not required", requirement.ID, ref, pack)
+				}
+			} else if !workflowIDs[ref] {
+				problem("provider %s runs unknown workflow %s", requirement.ID, ref)
+			}
+		}
+		if len(requirement.Triggers) == 0 {
+			problem("provider %s has no triggers", requirement.ID)
+		}
+	}
+
+	for _, ref := range m.schemaReferences() {
+		if pack, _, ok := m.foreign(ref); ok {
+			if _, required := m.Requires[pack]; !required {
+				problem("schema %s belongs to pack %s, which is not required", ref, pack)
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidManifest, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// registrations are what installing a pack in a namespace registers, by
+// the IDs they are registered under
+type registrations struct {
+	workflows []*workflows.BlobProcessingWorkflow
+	providers []*workflows.Provider
+	schemas   map[string]map[string]interface{}
+}
+
+// build makes the registrations of a valid manifest for a user's
+// namespace: copies of its workflows, schemas and providers under their
+// qualified IDs, references between them qualified the same way and prompt
+// references replaced by the prompts. Workflows are validated and linted.
+func (m *Manifest) build(namespaceID, userID string) (*registrations, error) {
+	regs := &registrations{schemas: make(map[string]map[string]interface{}, len(m.Schemas))}
+	for id, definition := range m.Schemas {
+		regs.schemas[QualifiedID(m.Name, id, namespaceID)] = definition
+	}
+
+	runBy := make(map[string]string)
+	providerIDs := make(map[string]bool, len(m.Providers))
+	for _, requirement := range m.Providers {
+		providerIDs[requirement.ID] = true
+		for _, ref := range requirement.Workflows {
+			if _, ok := runBy[ref]; !ok {
+				runBy[ref] = requirement.ID
+			}
+		}
+	}
+
+	for _, source := range m.Workflows {
+		var workflow workflows.BlobProcessingWorkflow
+		if err := copyJSON(source, &workflow); err != nil {
+			return nil, fmt.Errorf("failed to copy workflow %s: %w", source.ID, err)
+		}
+		workflow.ID = QualifiedID(m.Name, source.ID, namespaceID)
+		switch {
+		case providerIDs[workflow.ProviderID]:
+			workflow.ProviderID = QualifiedID(m.Name, workflow.ProviderID, namespaceID)
+		case workflow.ProviderID == "" && runBy[source.ID] != "":
+			workflow.ProviderID = QualifiedID(m.Name, runBy[source.ID], namespaceID)
+		}
+		workflow.OutputSchemaID = m.qualifySchema(workflow.OutputSchemaID, namespaceID)
+		for i := range workflow.Steps {
+			step := &workflow.Steps[i]
+			step.OutputSchemaID = m.qualifySchema(step.OutputSchemaID, namespaceID)
+			step.InputMap = m.substitute(step.InputMap).(map[string]interface{})
+			step.Config.Parameters = m.substitute(step.Config.Parameters).(map[string]interface{})
+		}
+		if workflow.Type == "" {
+			workflow.Type = workflows.WorkflowTypeProcessBlob
+		}
+		if err := workflow.Validate(); err != nil {
+			return nil, err
+		}
+		if err := workflows.CheckLint(&workflow); err != nil {
+			return nil, err
+		}
+		regs.workflows = append(regs.workflows, &workflow)
+	}
+
+	for _, requirement := range m.Providers {
+		provider, err := m.provider(requirement, namespaceID, userID)
+		if err != nil {
+			return nil, err
+		}
+		regs.providers = append(regs.providers, provider)
+	}
+	return regs, nil
+}
+
+// provider builds the provider a requirement describes for a user's
+// namespace. Its triggers only match that user's blobs in the namespace,
+// so a pack never runs on another user's content, whoever else puts blobs
+// there.
+func (m *Manifest) provider(requirement ProviderRequirement, namespaceID, userID string) (*workflows.Provider, error) {
+	definition, err := workflows.LookupProviderDefinition(requirement.Definition)
+	if err != nil {
+		return nil, fmt.Errorf("%w: provider %s requires %v", ErrUnresolvedDependency, requirement.ID, err)
+	}
+	config := definition.Config
+	provider := &workflows.Provider{
+		ID:          QualifiedID(m.Name, requirement.ID, namespaceID),
+		Name:        definition.Provider.Name,
+		Type:        definition.Provider.Type,
+		NamespaceID: namespaceID,
+		WorkflowIDs: make([]string, 0, len(requirement.Workflows)),
+		Triggers:    make([]workflows.TriggerConfig, 0, len(requirement.Triggers)),
+		Config: workflows.ProviderConfig{
+			MaxConcurrentJobs: config.MaxConcurrentJobs,
+			RateLimitPerMin:   config.RateLimitPerMin,
+			TimeoutSeconds:    config.TimeoutSeconds,
+			Parameters:        make(map[string]interface{}, len(config.Parameters)+len(requirement.Parameters)),
+		},
+		Active: true,
+	}
+	if config.RetryPolicy != nil {
+		policy := *config.RetryPolicy
+		provider.Config.RetryPolicy = &policy
+	}
+	for name, value := range config.Parameters {
+		provider.Config.Parameters[name] = value
+	}
+	for name, value := range m.substitute(requirement.Parameters).(map[string]interface{}) {
+		provider.Config.Parameters[name] = value
+	}
+	for _, ref := range requirement.Workflows {
+		provider.WorkflowIDs = append(provider.WorkflowIDs, m.qualify(ref, true, namespaceID))
+	}
+	for _, trigger := range requirement.Triggers {
+		conditions := make([]workflows.TriggerCondition, 0, len(trigger.Conditions)+2)
+		conditions = append(conditions, trigger.Conditions...)
+		conditions = append(conditions,
+			workflows.TriggerCondition{Field: "namespace_id", Operator: "eq", Value: namespaceID},
+			workflows.TriggerCondition{Field: "user_id", Operator: "eq", Value: userID},
+		)
+		trigger.Conditions = conditions
+		provider.Triggers = append(provider.Triggers, trigger)
+	}
+	if err := provider.Validate(); err != nil {
+		return nil, err
+	}
+	return provider, nil
+}
+
+// qualify maps a reference made within the pack to the ID it is registered
+// under: a required pack's, or the pack's own when it is local. Other
+// references, such as schemas registered outside packs, are left as they
+// are.
+func (m *Manifest) qualify(ref string, local bool, namespaceID string) string {
+	if pack, id, ok := m.foreign(ref); ok {
+		return QualifiedID(pack, id, namespaceID)
+	}
+	if local {
+		return QualifiedID(m.Name, ref, namespaceID)
+	}
+	return ref
+}
+
+// qualifySchema maps a schema reference to the ID it is registered under
+func (m *Manifest) qualifySchema(ref, namespaceID string) string {
+	_, local := m.Schemas[ref]
+	return m.qualify(ref, local, namespaceID)
+}
+
+// foreign splits a reference to a required pack's workflow or schema, such
+// as outline.chapter, into the pack's name and the ID
+func (m *Manifest) foreign(ref string) (string, string, bool) {
+	pack, id, ok := strings.Cut(ref, ".")
+	if !ok || !validName(pack) || !validID(id) {
+		return "", "", false
+	}
+	return pack, id, true
+}
+
+// schemaReferences lists the schemas the pack's workflows and steps name
+func (m *Manifest) schemaReferences() []string {
+	var refs []string
+	for _, workflow := range m.Workflows {
+		if workflow == nil {
+			continue
+		}
+		if workflow.OutputSchemaID != "" {
+			refs = append(refs, workflow.OutputSchemaID)
+		}
+		for _, step := range workflow.Steps {
+			if step.OutputSchemaID != "" {
+				refs = append(refs, step.OutputSchemaID)
+			}
+		}
+	}
+	return refs
+}
+
+// substitute replaces prompt references in a value, descending into maps
+// and lists, with the pack's prompts
+func (m *Manifest) substitute(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		if name := strings.TrimPrefix(v, PromptPrefix); name != v {
+			if prompt, ok := m.Prompts[name]; ok {
+				return prompt
+			}
+		}
+		return v
+	case map[string]interface{}:
+		if v == nil {
+			return v
+		}
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			result[key] = m.substitute(item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = m.substitute(item)
+		}
+		return result
+	}
+	return value
+}
+
+// promptReferences lists the prompts the values name
+func promptReferences(values ...map[string]interface{}) []string {
+	var names []string
+	var walk func(value interface{})
+	walk = func(value interface{}) {
+		switch v := value.(type) {
+		case string:
+			if name := strings.TrimPrefix(v, PromptPrefix); name != v {
+				names = append(names, name)
+			}
+		case map[string]interface{}:
+			for _, item := range v {
+				walk(item)
+			}
+		case []interface{}:
+			for _, item := range v {
+				walk(item)
+			}
+		}
+	}
+	for _, value := range values {
+		walk(value)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// QualifiedID is the ID a pack's workflow, schema or provider is
+// registered under in a namespace
+func QualifiedID(pack, id, namespaceID string) string {
+	return pack + "." + id + ":" + namespaceID
+}
+
+// validName reports whether a pack name is lowercase letters, digits and
+// hyphens, starting with a letter
+func validName(name string) bool {
+	if name == "" || len(name) > maxNameLength || name[0] < 'a' || name[0] > 'z' {
+		return false
+	}
+	return strings.Trim(name, "abcdefghijklmnopqrstuvwxyz0123456789-") == ""
+}
+
+// validID reports whether an ID within a pack is a plain name, free of the
+// separators of qualified IDs
+func validID(id string) bool {
+	return id != "" && len(id) <= maxNameLength && !strings.ContainsAny(id, `.:/\ `)
+}
+
+// copyJSON deep-copies a value through JSON
+func copyJSON(from, to interface{}) error {
+	data, err := json.Marshal(from)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, to)
+}
+
+// sortedNames returns a map's keys in order
+func sortedNames(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
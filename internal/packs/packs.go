@@ -0,0 +1,437 @@
+// Package packs lets developers publish workflow packs into a namespace: a
+// manifest bundling workflows, the schemas their outputs are checked
+// against, prompt templates their steps use and the providers that run
+// them, versioned semantically. A pack may require other packs, which must
+// already be installed in the namespace at versions it accepts, and cannot
+// be uninstalled or moved to a version its dependents do not accept.
+// Uninstalling a pack removes everything it registered.
+package packs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// Pack errors
+var (
+	ErrPackNotFound         = errors.New("pack not found")
+	ErrInvalidManifest      = errors.New("invalid pack manifest")
+	ErrUnresolvedDependency = errors.New("unresolved pack dependency")
+	ErrPackInUse            = errors.New("pack is required by other packs")
+	ErrNamespaceNotOwned    = errors.New("namespace belongs to another user")
+)
+
+// Registrar registers and removes the providers packs run their workflows
+// with. The workflows orchestrator is a Registrar.
+type Registrar interface {
+	RegisterProvider(ctx context.Context, provider *workflows.Provider) error
+	UnregisterProvider(providerID string) error
+}
+
+// Installation is a pack installed in a namespace, with the manifest it
+// was installed from, the versions of the packs it requires it was
+// resolved against and the IDs of what it registered
+type Installation struct {
+	ID           string            `json:"id"`
+	NamespaceID  string            `json:"namespace_id"`
+	UserID       string            `json:"user_id"`
+	Name         string            `json:"name"`
+	Version      string            `json:"version"`
+	Dependencies map[string]string `json:"dependencies,omitempty"`
+	Workflows    []string          `json:"workflows"`
+	Providers    []string          `json:"providers"`
+	Schemas      []string          `json:"schemas"`
+	Manifest     *Manifest         `json:"manifest"`
+	InstalledAt  time.Time         `json:"installed_at"`
+	UpdatedAt    time.Time         `json:"updated_at"`
+}
+
+// Service installs and uninstalls packs, recording installations in a
+// store
+type Service struct {
+	store     Store
+	registry  *workflows.WorkflowRegistry
+	providers Registrar
+	mu        sync.Mutex
+	now       func() time.Time
+}
+
+// NewService creates a pack service registering workflows through
+// registry and providers with providers
+func NewService(store Store, registry *workflows.WorkflowRegistry, providers Registrar) *Service {
+	return &Service{store: store, registry: registry, providers: providers, now: time.Now}
+}
+
+// Install installs a pack in a user's namespace, or moves an installed
+// pack to the manifest's version. Its requirements must be installed in
+// the namespace at versions it accepts, the packs requiring it must accept
+// its new version, and the schemas its workflows name outside the pack
+// must be registered. Everything is built, validated and linted before
+// anything is registered; moving versions updates the pack's workflows and
+// removes what the new version no longer defines.
+func (s *Service) Install(ctx context.Context, userID, namespaceID string, manifest *Manifest) (*Installation, error) {
+	if namespaceID == "" || strings.ContainsAny(namespaceID, `/\`) || strings.HasPrefix(namespaceID, ".") {
+		return nil, fmt.Errorf("%w: invalid namespace %q", ErrInvalidManifest, namespaceID)
+	}
+	if err := manifest.Validate(); err != nil {
+		return nil, err
+	}
+	version, _ := ParseVersion(manifest.Version)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	installed, err := s.namespacePacks(ctx, userID, namespaceID)
+	if err != nil {
+		return nil, err
+	}
+	dependencies, err := resolve(manifest, installed)
+	if err != nil {
+		return nil, err
+	}
+	previous := installed[manifest.Name]
+	if previous != nil {
+		for _, dependent := range dependents(manifest.Name, installed) {
+			constraint, _ := ParseConstraint(dependent.Manifest.Requires[manifest.Name])
+			if !constraint.Allows(version) {
+				return nil, fmt.Errorf("%w: %s %s requires %s %s", ErrPackInUse, dependent.Name, dependent.Version, manifest.Name, constraint)
+			}
+		}
+	}
+
+	regs, err := manifest.build(namespaceID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSchemas(regs); err != nil {
+		return nil, err
+	}
+	for _, provider := range regs.providers {
+		for _, workflowID := range provider.WorkflowIDs {
+			if regs.workflow(workflowID) != nil {
+				continue
+			}
+			if _, err := s.registry.Get(ctx, workflowID); err != nil {
+				return nil, fmt.Errorf("%w: provider %s runs %v", ErrUnresolvedDependency, provider.ID, err)
+			}
+		}
+	}
+
+	now := s.now()
+	installation := &Installation{
+		ID:           installationID(namespaceID, manifest.Name),
+		NamespaceID:  namespaceID,
+		UserID:       userID,
+		Name:         manifest.Name,
+		Version:      version.String(),
+		Dependencies: dependencies,
+		Workflows:    []string{},
+		Providers:    []string{},
+		Schemas:      []string{},
+		Manifest:     manifest,
+		InstalledAt:  now,
+		UpdatedAt:    now,
+	}
+	if previous != nil {
+		installation.InstalledAt = previous.InstalledAt
+	}
+	if err := s.register(ctx, regs, installation); err != nil {
+		if previous == nil {
+			// Leave nothing of a pack that did not install
+			s.unregister(ctx, installation, nil)
+		}
+		return nil, err
+	}
+	if previous != nil {
+		if err := s.unregister(ctx, previous, installation); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.store.Save(ctx, installation); err != nil {
+		return nil, err
+	}
+	return installation, nil
+}
+
+// List returns the packs installed in a user's namespace, by name
+func (s *Service) List(ctx context.Context, userID, namespaceID string) ([]*Installation, error) {
+	installed, err := s.namespacePacks(ctx, userID, namespaceID)
+	if errors.Is(err, ErrNamespaceNotOwned) {
+		return []*Installation{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*Installation, 0, len(installed))
+	for _, installation := range installed {
+		list = append(list, installation)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+// Get returns a pack installed in a user's namespace
+func (s *Service) Get(ctx context.Context, userID, namespaceID, name string) (*Installation, error) {
+	installation, err := s.store.Get(ctx, installationID(namespaceID, name))
+	if err != nil {
+		return nil, err
+	}
+	if installation.UserID != userID {
+		return nil, fmt.Errorf("%w: %s", ErrPackNotFound, name)
+	}
+	return installation, nil
+}
+
+// Uninstall removes a pack from a user's namespace with its providers,
+// workflows and schemas, unless other packs installed there require it
+func (s *Service) Uninstall(ctx context.Context, userID, namespaceID, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	installed, err := s.namespacePacks(ctx, userID, namespaceID)
+	if errors.Is(err, ErrNamespaceNotOwned) {
+		return fmt.Errorf("%w: %s", ErrPackNotFound, name)
+	}
+	if err != nil {
+		return err
+	}
+	installation, ok := installed[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrPackNotFound, name)
+	}
+	if list := dependents(name, installed); len(list) > 0 {
+		names := make([]string, len(list))
+		for i, dependent := range list {
+			names[i] = dependent.Name
+		}
+		return fmt.Errorf("%w: %s is required by %s", ErrPackInUse, name, strings.Join(names, ", "))
+	}
+	if err := s.unregister(ctx, installation, nil); err != nil {
+		return err
+	}
+	return s.store.Delete(ctx, installation.ID)
+}
+
+// Restore registers the recorded packs again, for after a restart, when
+// schemas and providers are no longer registered. Workflows the backend
+// still holds are kept as they are. It returns the packs that failed.
+func (s *Service) Restore(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list, err := s.store.List(ctx)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, installation := range list {
+		regs, err := installation.Manifest.build(installation.NamespaceID, installation.UserID)
+		if err == nil {
+			err = s.register(ctx, regs, installation)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to restore pack %s in namespace %s: %w", installation.Name, installation.NamespaceID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// register registers a pack's schemas, then its workflows, then its
+// providers, recording their IDs in the installation
+func (s *Service) register(ctx context.Context, regs *registrations, installation *Installation) error {
+	installation.Schemas, installation.Workflows, installation.Providers = []string{}, []string{}, []string{}
+	for id, definition := range regs.schemas {
+		workflows.RegisterSchema(id, definition)
+		installation.Schemas = append(installation.Schemas, id)
+	}
+	sort.Strings(installation.Schemas)
+
+	for _, workflow := range regs.workflows {
+		existing, err := s.registry.Get(ctx, workflow.ID)
+		switch {
+		case errors.Is(err, workflows.ErrWorkflowNotFound):
+			if err := s.registry.Create(ctx, workflow); err != nil {
+				return fmt.Errorf("failed to create workflow %s: %w", workflow.ID, err)
+			}
+		case err != nil:
+			return err
+		case !sameDefinition(existing, workflow):
+			if err := s.registry.Update(ctx, workflow); err != nil {
+				return fmt.Errorf("failed to update workflow %s: %w", workflow.ID, err)
+			}
+		}
+		installation.Workflows = append(installation.Workflows, workflow.ID)
+	}
+
+	for _, provider := range regs.providers {
+		if err := s.providers.RegisterProvider(ctx, provider); err != nil {
+			return fmt.Errorf("failed to register provider %s: %w", provider.ID, err)
+		}
+		installation.Providers = append(installation.Providers, provider.ID)
+	}
+	return nil
+}
+
+// unregister removes what a pack registered that its new installation,
+// if any, no longer does: providers first, so no more executions start,
+// then workflows, then schemas
+func (s *Service) unregister(ctx context.Context, previous, current *Installation) error {
+	var keep Installation
+	if current != nil {
+		keep = *current
+	}
+	for _, providerID := range previous.Providers {
+		if contains(keep.Providers, providerID) {
+			continue
+		}
+		if err := s.providers.UnregisterProvider(providerID); err != nil && !errors.Is(err, workflows.ErrProviderNotFound) {
+			return fmt.Errorf("failed to unregister provider %s: %w", providerID, err)
+		}
+	}
+	for _, workflowID := range previous.Workflows {
+		if contains(keep.Workflows, workflowID) {
+			continue
+		}
+		if err := s.registry.Delete(ctx, workflowID); err != nil && !errors.Is(err, workflows.ErrWorkflowNotFound) {
+			return fmt.Errorf("failed to delete workflow %s: %w", workflowID, err)
+		}
+	}
+	for _, schemaID := range previous.Schemas {
+		if !contains(keep.Schemas, schemaID) {
+			workflows.UnregisterSchema(schemaID)
+		}
+	}
+	return nil
+}
+
+// namespacePacks returns the packs installed in a namespace, by name. A
+// namespace's packs belong to the user who installed the first of them.
+func (s *Service) namespacePacks(ctx context.Context, userID, namespaceID string) (map[string]*Installation, error) {
+	list, err := s.store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	installed := make(map[string]*Installation)
+	for _, installation := range list {
+		if installation.NamespaceID != namespaceID {
+			continue
+		}
+		if installation.UserID != userID {
+			return nil, fmt.Errorf("%w: %s", ErrNamespaceNotOwned, namespaceID)
+		}
+		installed[installation.Name] = installation
+	}
+	return installed, nil
+}
+
+// resolve checks that the packs a manifest requires are installed at
+// versions it accepts, returning the versions it resolved to
+func resolve(manifest *Manifest, installed map[string]*Installation) (map[string]string, error) {
+	if len(manifest.Requires) == 0 {
+		return nil, nil
+	}
+	var problems []string
+	resolved := make(map[string]string, len(manifest.Requires))
+	for _, name := range sortedNames(manifest.Requires) {
+		constraint, _ := ParseConstraint(manifest.Requires[name])
+		dependency, ok := installed[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s %s is not installed", name, constraint))
+			continue
+		}
+		version, err := ParseVersion(dependency.Version)
+		if err != nil || !constraint.Allows(version) {
+			problems = append(problems, fmt.Sprintf("%s %s is installed, not %s", name, dependency.Version, constraint))
+			continue
+		}
+		resolved[name] = dependency.Version
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrUnresolvedDependency, strings.Join(problems, "; "))
+	}
+	return resolved, nil
+}
+
+// dependents returns the installed packs that require a pack, by name
+func dependents(name string, installed map[string]*Installation) []*Installation {
+	var list []*Installation
+	for _, installation := range installed {
+		if _, ok := installation.Manifest.Requires[name]; ok {
+			list = append(list, installation)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// checkSchemas checks that the schemas a pack's workflows name are the
+// pack's own or registered
+func checkSchemas(regs *registrations) error {
+	var missing []string
+	check := func(id string) {
+		if id == "" || regs.schemas[id] != nil || contains(missing, id) {
+			return
+		}
+		if _, err := workflows.LookupSchema(id); err != nil {
+			missing = append(missing, id)
+		}
+	}
+	for _, workflow := range regs.workflows {
+		check(workflow.OutputSchemaID)
+		for _, step := range workflow.Steps {
+			check(step.OutputSchemaID)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: schemas %s are not registered", ErrUnresolvedDependency, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// workflow returns one of the registrations' workflows, or nil
+func (r *registrations) workflow(id string) *workflows.BlobProcessingWorkflow {
+	for _, workflow := range r.workflows {
+		if workflow.ID == id {
+			return workflow
+		}
+	}
+	return nil
+}
+
+// installationID names a pack's installation in a namespace
+func installationID(namespaceID, name string) string {
+	return namespaceID + "." + name
+}
+
+// sameDefinition reports whether two workflows are defined alike, apart
+// from what the registry sets
+func sameDefinition(a, b *workflows.BlobProcessingWorkflow) bool {
+	x, y := *a, *b
+	x.Version, x.CreatedAt, x.UpdatedAt = 0, time.Time{}, time.Time{}
+	y.Version, y.CreatedAt, y.UpdatedAt = 0, time.Time{}, time.Time{}
+	dx, err := json.Marshal(x)
+	if err != nil {
+		return false
+	}
+	dy, err := json.Marshal(y)
+	return err == nil && bytes.Equal(dx, dy)
+}
+
+// contains reports whether a list holds a value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
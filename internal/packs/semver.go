@@ -0,0 +1,226 @@
+package packs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a semantic version, MAJOR.MINOR.PATCH with an optional
+// pre-release. Build metadata is accepted and ignored.
+type Version struct {
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease string
+}
+
+// ParseVersion reads a semantic version such as 1.4.0 or 2.0.0-beta.1
+func ParseVersion(s string) (Version, error) {
+	core := strings.TrimSpace(s)
+	if i := strings.IndexByte(core, '+'); i >= 0 {
+		core = core[:i]
+	}
+	var v Version
+	if i := strings.IndexByte(core, '-'); i >= 0 {
+		core, v.Prerelease = core[:i], core[i+1:]
+		if v.Prerelease == "" {
+			return Version{}, fmt.Errorf("%w: version %q has an empty pre-release", ErrInvalidManifest, s)
+		}
+		for _, identifier := range strings.Split(v.Prerelease, ".") {
+			if identifier == "" || strings.Trim(identifier, "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ-") != "" {
+				return Version{}, fmt.Errorf("%w: version %q has an invalid pre-release", ErrInvalidManifest, s)
+			}
+		}
+	}
+	parts := strings.Split(core, ".")
+	if len(parts) != 3 {
+		return Version{}, fmt.Errorf("%w: version %q is not MAJOR.MINOR.PATCH", ErrInvalidManifest, s)
+	}
+	numbers := make([]int, 3)
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || (len(part) > 1 && part[0] == '0') {
+			return Version{}, fmt.Errorf("%w: version %q is not MAJOR.MINOR.PATCH", ErrInvalidManifest, s)
+		}
+		numbers[i] = n
+	}
+	v.Major, v.Minor, v.Patch = numbers[0], numbers[1], numbers[2]
+	return v, nil
+}
+
+// String formats the version
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	return s
+}
+
+// Compare returns -1, 0 or 1 as v is older than, the same as or newer
+// than other, a pre-release being older than its release
+func (v Version) Compare(other Version) int {
+	for _, d := range []int{v.Major - other.Major, v.Minor - other.Minor, v.Patch - other.Patch} {
+		if d != 0 {
+			return sign(d)
+		}
+	}
+	switch {
+	case v.Prerelease == other.Prerelease:
+		return 0
+	case v.Prerelease == "":
+		return 1
+	case other.Prerelease == "":
+		return -1
+	}
+	a, b := strings.Split(v.Prerelease, "."), strings.Split(other.Prerelease, ".")
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := compareIdentifier(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return sign(len(a) - len(b))
+}
+
+// compareIdentifier orders pre-release identifiers: numeric ones by value
+// and before alphanumeric ones, which are ordered as text
+func compareIdentifier(a, b string) int {
+	x, errA := strconv.Atoi(a)
+	y, errB := strconv.Atoi(b)
+	switch {
+	case errA == nil && errB == nil:
+		return sign(x - y)
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// sign returns -1, 0 or 1 for the sign of n
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+// comparator is one bound of a constraint, such as >=1.2.0
+type comparator struct {
+	op      string
+	version Version
+}
+
+// allows reports whether a version satisfies the bound
+func (c comparator) allows(v Version) bool {
+	cmp := v.Compare(c.version)
+	switch c.op {
+	case "=":
+		return cmp == 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+	return false
+}
+
+// Constraint is a set of versions a pack accepts of a pack it requires,
+// written as comparators that must all hold, such as ">=1.2.0 <2.0.0",
+// with "||" between alternatives. ^1.2.3 accepts versions up to the next
+// major version, or the next minor one below 1.0.0, ~1.2.3 up to the next
+// minor version, a bare version only itself and * any release.
+// Pre-releases are only accepted by an alternative naming a pre-release of
+// the same MAJOR.MINOR.PATCH.
+type Constraint struct {
+	raw          string
+	alternatives [][]comparator
+}
+
+// ParseConstraint reads a version constraint
+func ParseConstraint(s string) (*Constraint, error) {
+	c := &Constraint{raw: strings.TrimSpace(s)}
+	for _, alternative := range strings.Split(c.raw, "||") {
+		fields := strings.Fields(alternative)
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("%w: constraint %q has an empty alternative", ErrInvalidManifest, s)
+		}
+		var comparators []comparator
+		for _, field := range fields {
+			parsed, err := parseComparator(field)
+			if err != nil {
+				return nil, fmt.Errorf("%w: constraint %q: %v", ErrInvalidManifest, s, err)
+			}
+			comparators = append(comparators, parsed...)
+		}
+		c.alternatives = append(c.alternatives, comparators)
+	}
+	return c, nil
+}
+
+// parseComparator reads one term of a constraint into the bounds it stands
+// for
+func parseComparator(term string) ([]comparator, error) {
+	if term == "*" {
+		return []comparator{{op: ">=", version: Version{}}}, nil
+	}
+	op := term[:len(term)-len(strings.TrimLeft(term, "^~=<>"))]
+	v, err := ParseVersion(term[len(op):])
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a version", term[len(op):])
+	}
+	switch op {
+	case "", "=":
+		return []comparator{{op: "=", version: v}}, nil
+	case ">", ">=", "<", "<=":
+		return []comparator{{op: op, version: v}}, nil
+	case "^":
+		upper := Version{Major: v.Major + 1}
+		switch {
+		case v.Major == 0 && v.Minor == 0:
+			upper = Version{Patch: v.Patch + 1}
+		case v.Major == 0:
+			upper = Version{Minor: v.Minor + 1}
+		}
+		return []comparator{{op: ">=", version: v}, {op: "<", version: upper}}, nil
+	case "~":
+		return []comparator{{op: ">=", version: v}, {op: "<", version: Version{Major: v.Major, Minor: v.Minor + 1}}}, nil
+	}
+	return nil, fmt.Errorf("unknown operator %q", op)
+}
+
+// Allows reports whether a version satisfies the constraint
+func (c *Constraint) Allows(v Version) bool {
+	for _, comparators := range c.alternatives {
+		allowed := true
+		prerelease := v.Prerelease == ""
+		for _, bound := range comparators {
+			if !bound.allows(v) {
+				allowed = false
+				break
+			}
+			bv := bound.version
+			if bv.Prerelease != "" && bv.Major == v.Major && bv.Minor == v.Minor && bv.Patch == v.Patch {
+				prerelease = true
+			}
+		}
+		if allowed && prerelease {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the constraint as written
+func (c *Constraint) String() string {
+	return c.raw
+}
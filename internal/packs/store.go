@@ -0,0 +1,135 @@
+package packs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Store persists installed packs so they can be registered again after a
+// restart
+type Store interface {
+	Save(ctx context.Context, installation *Installation) error
+	Get(ctx context.Context, installationID string) (*Installation, error)
+	List(ctx context.Context) ([]*Installation, error)
+	Delete(ctx context.Context, installationID string) error
+}
+
+// FileStore keeps each installation in a JSON file under a directory,
+// named by its ID
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates a store writing under dir
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir}
+}
+
+// Save writes an installation, replacing the file atomically so a crash never
+// leaves a partial one
+func (s *FileStore) Save(ctx context.Context, installation *Installation) error {
+	path, err := s.path(installation.ID)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(installation, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal pack: %w", err)
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create pack directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(s.dir, ".pack-*")
+	if err != nil {
+		return fmt.Errorf("failed to write pack %s: %w", installation.ID, err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write pack %s: %w", installation.ID, err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write pack %s: %w", installation.ID, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write pack %s: %w", installation.ID, err)
+	}
+	return nil
+}
+
+// Get reads an installation
+func (s *FileStore) Get(ctx context.Context, installationID string) (*Installation, error) {
+	path, err := s.path(installationID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrPackNotFound, installationID)
+	}
+	return s.read(path)
+}
+
+// List reads every installation
+func (s *FileStore) List(ctx context.Context) ([]*Installation, error) {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list packs: %w", err)
+	}
+	installations := make([]*Installation, 0, len(paths))
+	for _, path := range paths {
+		installation, err := s.read(path)
+		if errors.Is(err, ErrPackNotFound) {
+			// Deleted since the listing
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		installations = append(installations, installation)
+	}
+	return installations, nil
+}
+
+// Delete removes an installation
+func (s *FileStore) Delete(ctx context.Context, installationID string) error {
+	path, err := s.path(installationID)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrPackNotFound, installationID)
+	}
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%w: %s", ErrPackNotFound, installationID)
+		}
+		return fmt.Errorf("failed to delete pack %s: %w", installationID, err)
+	}
+	return nil
+}
+
+// read decodes an installation file
+func (s *FileStore) read(path string) (*Installation, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %s", ErrPackNotFound, strings.TrimSuffix(filepath.Base(path), ".json"))
+		}
+		return nil, fmt.Errorf("failed to read pack: %w", err)
+	}
+	var installation Installation
+	if err := json.Unmarshal(data, &installation); err != nil {
+		return nil, fmt.Errorf("failed to parse pack %s: %w", path, err)
+	}
+	return &installation, nil
+}
+
+// path maps an installation ID to its file, rejecting IDs that are not plain
+// names
+func (s *FileStore) path(installationID string) (string, error) {
+	if installationID == "" || strings.ContainsAny(installationID, `/\`) || strings.HasPrefix(installationID, ".") {
+		return "", fmt.Errorf("invalid pack installation id %q", installationID)
+	}
+	return filepath.Join(s.dir, installationID+".json"), nil
+}
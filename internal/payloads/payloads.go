@@ -0,0 +1,223 @@
+// Package payloads captures the raw exchange of workflow steps, the input
+// and parameters each step was given and the output or error it returned,
+// so a bad model response can be traced to what the model was sent.
+// Payloads are redacted of personal data and credentials, capped in size
+// and kept only for a retention window.
+package payloads
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// DefaultMaxBytes caps each captured request and response, in bytes of JSON
+const DefaultMaxBytes = 64 * 1024
+
+// DefaultRetention is how long captured payloads are kept
+const DefaultRetention = 72 * time.Hour
+
+// DefaultCheckInterval is how often expired payloads are deleted
+const DefaultCheckInterval = time.Hour
+
+// Payload is one attempt of a step: what it was sent and what it returned.
+// A request or response over the size cap is kept as a string holding the
+// start of its JSON, with Truncated set; the byte counts are the sizes
+// before truncation.
+type Payload struct {
+	ID            string      `json:"id"`
+	ExecutionID   string      `json:"execution_id"`
+	WorkflowID    string      `json:"workflow_id"`
+	StepID        string      `json:"step_id"`
+	StepType      string      `json:"step_type"`
+	UserID        string      `json:"user_id"`
+	Request       interface{} `json:"request"`
+	Response      interface{} `json:"response,omitempty"`
+	Error         string      `json:"error,omitempty"`
+	RequestBytes  int         `json:"request_bytes"`
+	ResponseBytes int         `json:"response_bytes"`
+	Truncated     bool        `json:"truncated,omitempty"`
+	DurationMs    int64       `json:"duration_ms"`
+	CapturedAt    time.Time   `json:"captured_at"`
+	ExpiresAt     time.Time   `json:"expires_at"`
+}
+
+// Capture records the payloads of the steps it wraps
+type Capture struct {
+	store     Store
+	maxBytes  int
+	retention time.Duration
+	onError   func(error)
+	now       func() time.Time
+}
+
+// NewCapture creates a capture saving payloads to store, each request and
+// response capped at maxBytes and kept for retention; zero means the
+// defaults. Capturing never fails a step; onError, if set, is told when a
+// payload cannot be saved.
+func NewCapture(store Store, maxBytes int, retention time.Duration, onError func(error)) *Capture {
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
+	return &Capture{
+		store:     store,
+		maxBytes:  maxBytes,
+		retention: retention,
+		onError:   onError,
+		now:       time.Now,
+	}
+}
+
+// Wrap returns an executor that runs executor and captures the exchange
+func (c *Capture) Wrap(executor workflows.StepExecutor) workflows.StepExecutor {
+	return workflows.StepExecutorFunc(func(ctx context.Context, req workflows.StepRequest) (map[string]interface{}, error) {
+		started := c.now()
+		output, err := executor.Execute(ctx, req)
+		finished := c.now()
+
+		payload := &Payload{
+			ID:          uuid.New().String(),
+			ExecutionID: req.ExecutionID,
+			WorkflowID:  req.WorkflowID,
+			StepID:      req.Step.ID,
+			StepType:    req.Step.Type,
+			UserID:      req.Context.UserID,
+			DurationMs:  finished.Sub(started).Milliseconds(),
+			CapturedAt:  finished.UTC(),
+			ExpiresAt:   finished.Add(c.retention).UTC(),
+		}
+		var truncated bool
+		payload.Request, payload.RequestBytes, truncated = c.prepare(map[string]interface{}{
+			"input":      req.Input,
+			"parameters": req.Step.Config.Parameters,
+		})
+		payload.Truncated = truncated
+		if err != nil {
+			payload.Error = RedactString(err.Error())
+		} else {
+			payload.Response, payload.ResponseBytes, truncated = c.prepare(output)
+			payload.Truncated = payload.Truncated || truncated
+		}
+		// The step's own context may be cancelled; the payload is still
+		// worth keeping
+		if saveErr := c.store.Save(context.Background(), payload); saveErr != nil && c.onError != nil {
+			c.onError(saveErr)
+		}
+		return output, err
+	})
+}
+
+// prepare redacts a value and caps its size, returning it with its size
+// before the cap and whether it was cut
+func (c *Capture) prepare(value interface{}) (interface{}, int, bool) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, 0, false
+	}
+	// Decode a copy so every value is a plain JSON type the redaction can
+	// walk and the executor's own values are left alone
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, len(data), false
+	}
+	redacted, err := json.Marshal(Redact(decoded))
+	if err != nil {
+		return nil, len(data), false
+	}
+	if len(redacted) <= c.maxBytes {
+		return json.RawMessage(redacted), len(data), false
+	}
+	cut := c.maxBytes
+	for cut > 0 && !utf8.RuneStart(redacted[cut]) {
+		cut--
+	}
+	return string(redacted[:cut]), len(data), true
+}
+
+// Log serves captured payloads and deletes them once they expire
+type Log struct {
+	store   Store
+	onError func(error)
+	now     func() time.Time
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewLog creates a log over store and starts deleting expired payloads
+// every interval. onError, if set, is told when they cannot be deleted.
+// Close stops it.
+func NewLog(store Store, interval time.Duration, onError func(error)) *Log {
+	if interval <= 0 {
+		interval = DefaultCheckInterval
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	l := &Log{
+		store:   store,
+		onError: onError,
+		now:     time.Now,
+		ctx:     ctx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	go l.run(interval)
+	return l
+}
+
+// List returns the unexpired payloads a user's steps produced in an
+// execution, in the order they were captured
+func (l *Log) List(ctx context.Context, userID, executionID string) ([]*Payload, error) {
+	payloads, err := l.store.List(ctx, executionID)
+	if err != nil {
+		return nil, err
+	}
+	now := l.now()
+	kept := make([]*Payload, 0, len(payloads))
+	for _, payload := range payloads {
+		if payload.UserID == userID && !payload.ExpiresAt.Before(now) {
+			kept = append(kept, payload)
+		}
+	}
+	sort.Slice(kept, func(i, j int) bool {
+		if !kept[i].CapturedAt.Equal(kept[j].CapturedAt) {
+			return kept[i].CapturedAt.Before(kept[j].CapturedAt)
+		}
+		return kept[i].ID < kept[j].ID
+	})
+	return kept, nil
+}
+
+// Close stops deleting expired payloads
+func (l *Log) Close() {
+	l.cancel()
+	<-l.done
+}
+
+// run deletes expired payloads until the log is closed
+func (l *Log) run(interval time.Duration) {
+	defer close(l.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := l.store.DeleteExpired(l.ctx, l.now()); err != nil && l.onError != nil && !errors.Is(err, context.Canceled) {
+			l.onError(err)
+		}
+		select {
+		case <-l.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package payloads
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces the values of sensitive keys
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys name fields whose values are dropped whole, matched against
+// keys lowercased with dashes as underscores
+var sensitiveKeys = []string{
+	"password", "passwd", "secret", "token", "api_key", "apikey",
+	"authorization", "cookie", "private_key", "ssn", "card_number", "cvv",
+}
+
+// patterns find personal data and credentials inside strings, most specific
+// first so a card number is not taken for a phone number
+var patterns = []struct {
+	kind string
+	re   *regexp.Regexp
+}{
+	{"secret", regexp.MustCompile(`(?i)\bbearer\s+[a-z0-9._~+/=-]{8,}`)},
+	{"secret", regexp.MustCompile(`\b(?:sk-[A-Za-z0-9_-]{16,}|gh[pousr]_[A-Za-z0-9]{20,}|xox[abpr]-[A-Za-z0-9-]{10,}|AKIA[0-9A-Z]{16})\b`)},
+	{"email", regexp.MustCompile(`(?i)\b[a-z0-9._%+-]+@[a-z0-9.-]+\.[a-z]{2,}\b`)},
+	{"ssn", regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`)},
+	{"card", regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)},
+	{"phone", regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?(?:\(\d{3}\)|\b\d{3})[ .-]?\d{3}[ .-]?\d{4}\b`)},
+}
+
+// Redact returns a copy of a JSON-like value with the string values of
+// sensitive keys replaced, so counts such as max_tokens stay, and emails,
+// phone numbers, card numbers, social security numbers and credentials in
+// strings masked by kind, such as [REDACTED:email]. The value itself is
+// left alone.
+func Redact(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			if _, ok := child.(string); ok && sensitiveKey(key) {
+				redacted[key] = Redacted
+				continue
+			}
+			redacted[key] = Redact(child)
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, child := range v {
+			redacted[i] = Redact(child)
+		}
+		return redacted
+	case []string:
+		redacted := make([]interface{}, len(v))
+		for i, child := range v {
+			redacted[i] = RedactString(child)
+		}
+		return redacted
+	case string:
+		return RedactString(v)
+	}
+	return value
+}
+
+// RedactString masks the personal data and credentials in a string
+func RedactString(s string) string {
+	for _, p := range patterns {
+		mask := "[REDACTED:" + p.kind + "]"
+		s = p.re.ReplaceAllStringFunc(s, func(match string) string {
+			if p.kind == "card" && !luhn(match) {
+				return match
+			}
+			return mask
+		})
+	}
+	return s
+}
+
+// sensitiveKey reports whether a key names a field to drop whole
+func sensitiveKey(key string) bool {
+	key = strings.ReplaceAll(strings.ToLower(key), "-", "_")
+	for _, sensitive := range sensitiveKeys {
+		if strings.Contains(key, sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+// luhn reports whether a run of digits, ignoring separators, passes the
+// card number checksum, so order numbers and timestamps are left alone
+func luhn(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && sum%10 == 0
+}
@@ -0,0 +1,157 @@
+package payloads
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Store persists captured payloads. The worker capturing them and the
+// server reading them may be separate processes sharing the store.
+type Store interface {
+	Save(ctx context.Context, payload *Payload) error
+	// List returns an execution's payloads in no particular order
+	List(ctx context.Context, executionID string) ([]*Payload, error)
+	// DeleteExpired removes payloads that expired before now and returns
+	// how many it removed
+	DeleteExpired(ctx context.Context, now time.Time) (int, error)
+}
+
+// FileStore keeps each payload in a JSON file, under a directory per
+// execution
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates a store writing under dir
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir}
+}
+
+// Save writes a payload, replacing its file atomically so a reader never
+// sees a partial one
+func (s *FileStore) Save(ctx context.Context, payload *Payload) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal step payload: %w", err)
+	}
+	dir := filepath.Join(s.dir, fileName(payload.ExecutionID))
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create payload directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, ".payload-*")
+	if err != nil {
+		return fmt.Errorf("failed to write payload of step %s: %w", payload.StepID, err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write payload of step %s: %w", payload.StepID, err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write payload of step %s: %w", payload.StepID, err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, payload.ID+".json")); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write payload of step %s: %w", payload.StepID, err)
+	}
+	return nil
+}
+
+// List returns an execution's payloads. Executions whose IDs map to the
+// same directory are told apart by the IDs in the payloads.
+func (s *FileStore) List(ctx context.Context, executionID string) ([]*Payload, error) {
+	payloads, err := s.read(filepath.Join(s.dir, fileName(executionID)))
+	if err != nil {
+		return nil, err
+	}
+	kept := payloads[:0]
+	for _, payload := range payloads {
+		if payload.ExecutionID == executionID {
+			kept = append(kept, payload)
+		}
+	}
+	return kept, nil
+}
+
+// DeleteExpired removes expired payloads and the directories they leave
+// empty
+func (s *FileStore) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	dirs, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to list step payloads: %w", err)
+	}
+	deleted := 0
+	for _, entry := range dirs {
+		if ctx.Err() != nil {
+			return deleted, ctx.Err()
+		}
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(s.dir, entry.Name())
+		payloads, err := s.read(dir)
+		if err != nil {
+			return deleted, err
+		}
+		for _, payload := range payloads {
+			if !payload.ExpiresAt.Before(now) {
+				continue
+			}
+			if err := os.Remove(filepath.Join(dir, payload.ID+".json")); err != nil && !os.IsNotExist(err) {
+				return deleted, fmt.Errorf("failed to delete step payload %s: %w", payload.ID, err)
+			}
+			deleted++
+		}
+		// Fails while the directory holds payloads, or one is being saved
+		os.Remove(dir)
+	}
+	return deleted, nil
+}
+
+// read decodes the payload files in a directory
+func (s *FileStore) read(dir string) ([]*Payload, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list step payloads: %w", err)
+	}
+	payloads := make([]*Payload, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			// Deleted since the listing
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read step payload: %w", err)
+		}
+		var payload Payload
+		if err := json.Unmarshal(data, &payload); err != nil {
+			return nil, fmt.Errorf("failed to parse step payload %s: %w", path, err)
+		}
+		payloads = append(payloads, &payload)
+	}
+	return payloads, nil
+}
+
+// fileName maps an execution ID to a directory name
+func fileName(id string) string {
+	if id == "" || strings.HasPrefix(id, ".") {
+		id = "_" + id
+	}
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' || r == 0 {
+			return '_'
+		}
+		return r
+	}, id)
+}
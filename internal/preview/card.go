@@ -0,0 +1,469 @@
+// Package preview builds presentation cards for blobs: a title, estimated
+// reading time, a short summary, key entities and a cover image, so
+// frontends can render a blob without reading its content. Cards are built
+// from the content right away and improved by a workflow that summarizes
+// the blob and extracts its entities with models, and are cached as child
+// blobs until the content changes.
+package preview
+
+import (
+	"encoding/json"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/memmieai/memmie-studio/internal/blob"
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// Card statuses
+const (
+	StatusPending = "pending" // built from the content; the workflow has not finished
+	StatusReady   = "ready"
+)
+
+// Summary sources
+const (
+	SourceExtract = "extract" // the content's opening sentences
+	SourceModel   = "model"
+)
+
+// Card building defaults
+const (
+	wordsPerMinute    = 238 // adult silent reading speed for prose
+	charsPerMinute    = 500 // the same for scripts written without spaces
+	maxSummaryWords   = 60
+	minSummaryWords   = 25
+	minParagraphWords = 8
+	maxEntities       = 8
+	maxTitleRunes     = 80
+)
+
+// Card is a blob's presentation metadata
+type Card struct {
+	BlobID         string    `json:"blob_id"`
+	ProviderID     string    `json:"provider_id"`
+	Title          string    `json:"title"`
+	Words          int       `json:"words"`
+	ReadingMinutes int       `json:"reading_minutes"`
+	Summary        string    `json:"summary"`
+	SummarySource  string    `json:"summary_source"`
+	Entities       []Entity  `json:"entities"`
+	Cover          *Cover    `json:"cover,omitempty"`
+	Language       string    `json:"language,omitempty"`
+	Tags           []string  `json:"tags,omitempty"`
+	Status         string    `json:"status"`
+	ContentHash    string    `json:"content_hash"`
+	UpdatedAt      time.Time `json:"updated_at"` // when the blob last changed
+	GeneratedAt    time.Time `json:"generated_at"`
+}
+
+// Entity is a person, place, organization or other name the blob mentions
+type Entity struct {
+	Name  string `json:"name"`
+	Type  string `json:"type,omitempty"` // given by an extractor model; empty for names found in the text
+	Count int    `json:"count"`
+}
+
+// Cover is the image a card shows
+type Cover struct {
+	URL    string `json:"url"`
+	BlobID string `json:"blob_id,omitempty"` // the image blob, when the cover is one
+	Source string `json:"source"`            // "metadata" or "image"
+}
+
+// Build creates a blob's card from its content, metadata and child blobs
+func Build(b *blob.Blob, children []*blob.Blob) *Card {
+	text := plainText(b.Content)
+	words, cjk := countWords(text)
+	card := &Card{
+		BlobID:         b.ID,
+		ProviderID:     b.ProviderID,
+		Title:          title(b, text),
+		Words:          words + cjk,
+		ReadingMinutes: readingMinutes(words, cjk),
+		Summary:        Summarize(text),
+		SummarySource:  SourceExtract,
+		Entities:       Entities(plainText(headingPattern.ReplaceAllString(b.Content, ""))),
+		Cover:          cover(b, children),
+		ContentHash:    contentHash(b.Content),
+		UpdatedAt:      b.UpdatedAt,
+		GeneratedAt:    time.Now(),
+	}
+	card.refresh(b)
+	return card
+}
+
+// refresh copies the metadata a card shows as is, which can change without
+// the content changing
+func (c *Card) refresh(b *blob.Blob) {
+	if title, ok := b.Metadata["title"].(string); ok && strings.TrimSpace(title) != "" {
+		c.Title = strings.TrimSpace(title)
+	}
+	c.Language, _ = b.Metadata["language"].(string)
+	c.Tags = workflows.StringList(b.Metadata["tags"])
+	c.UpdatedAt = b.UpdatedAt
+}
+
+// readingMinutes estimates how long words and CJK characters take to read,
+// rounded up to whole minutes
+func readingMinutes(words, cjk int) int {
+	if words+cjk == 0 {
+		return 0
+	}
+	minutes := float64(words)/wordsPerMinute + float64(cjk)/charsPerMinute
+	return max(1, int(math.Ceil(minutes)))
+}
+
+// countWords counts the words of text, and separately the characters of
+// scripts that do not separate words with spaces
+func countWords(text string) (words, cjk int) {
+	inWord := false
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			cjk++
+			inWord = false
+		case unicode.IsSpace(r):
+			inWord = false
+		case !inWord && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			words++
+			inWord = true
+		}
+	}
+	return words, cjk
+}
+
+// isCJK reports whether r is a Chinese, Japanese or Korean character
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// Summarize picks the opening sentences of text's first paragraph of prose,
+// up to about maxSummaryWords
+func Summarize(text string) string {
+	paragraphs := strings.Split(text, "\n\n")
+	lead := ""
+	for _, p := range paragraphs {
+		p = strings.Join(strings.Fields(p), " ")
+		if p == "" {
+			continue
+		}
+		if lead == "" {
+			lead = p
+		}
+		if len(strings.Fields(p)) >= minParagraphWords {
+			lead = p
+			break
+		}
+	}
+
+	var summary []string
+	count := 0
+	for _, sentence := range sentences(lead) {
+		n := len(strings.Fields(sentence))
+		if count > 0 && count+n > maxSummaryWords {
+			break
+		}
+		summary = append(summary, sentence)
+		if count += n; count >= minSummaryWords {
+			break
+		}
+	}
+	result := strings.Join(summary, " ")
+	if words := strings.Fields(result); len(words) > maxSummaryWords {
+		result = strings.Join(words[:maxSummaryWords], " ") + "…"
+	}
+	return result
+}
+
+// sentences splits a paragraph after each ., ! or ? followed by a space
+func sentences(paragraph string) []string {
+	var out []string
+	start := 0
+	for i := 0; i < len(paragraph); i++ {
+		if c := paragraph[i]; c != '.' && c != '!' && c != '?' {
+			continue
+		}
+		end := i + 1
+		for end < len(paragraph) && strings.IndexByte(`"')]`, paragraph[end]) >= 0 {
+			end++
+		}
+		if end == len(paragraph) || paragraph[end] == ' ' {
+			out = append(out, strings.TrimSpace(paragraph[start:end]))
+			start = end
+			i = end
+		}
+	}
+	if rest := strings.TrimSpace(paragraph[start:]); rest != "" {
+		out = append(out, rest)
+	}
+	return out
+}
+
+// sentenceStarters are capitalized only because they open a sentence, and
+// are dropped from the front of names
+var sentenceStarters = map[string]bool{
+	"a": true, "an": true, "the": true, "and": true, "but": true, "or": true, "so": true, "if": true,
+	"in": true, "on": true, "at": true, "of": true, "for": true, "to": true, "by": true, "with": true, "from": true,
+	"i": true, "he": true, "she": true, "it": true, "we": true, "they": true, "you": true, "my": true, "our": true,
+	"this": true, "that": true, "these": true, "those": true, "there": true, "then": true, "when": true,
+	"what": true, "why": true, "how": true, "who": true, "as": true, "after": true, "before": true, "while": true,
+	"chapter": true, "part": true,
+}
+
+// Entities finds the names text mentions most: runs of capitalized words,
+// not counting words capitalized only because they start a sentence
+func Entities(text string) []Entity {
+	type candidate struct {
+		name    string
+		count   int
+		first   int
+		midText bool // seen somewhere other than a sentence's start
+	}
+	found := make(map[string]*candidate)
+	order := 0
+	add := func(words []string, atStart bool) {
+		for len(words) > 0 && sentenceStarters[strings.ToLower(words[0])] {
+			words = words[1:]
+		}
+		if len(words) == 0 {
+			return
+		}
+		name := strings.Join(words, " ")
+		c, ok := found[name]
+		if !ok {
+			c = &candidate{name: name, first: order}
+			found[name] = c
+			order++
+		}
+		c.count++
+		c.midText = c.midText || !atStart || len(words) > 1
+	}
+
+	var run []string
+	runAtStart, sentenceStart := false, true
+	flush := func() {
+		if len(run) > 0 {
+			add(run, runAtStart)
+		}
+		run = nil
+	}
+	for _, token := range tokens(text) {
+		if !token.word {
+			flush()
+			if token.end {
+				sentenceStart = true
+			}
+			continue
+		}
+		first := []rune(token.text)[0]
+		if unicode.IsUpper(first) && len([]rune(token.text)) > 1 {
+			if len(run) == 0 {
+				runAtStart = sentenceStart
+			}
+			run = append(run, token.text)
+		} else {
+			flush()
+		}
+		sentenceStart = false
+	}
+	flush()
+
+	// A first or last name alone counts toward the one full name it is part of
+	for name, c := range found {
+		if strings.Contains(name, " ") {
+			continue
+		}
+		var full *candidate
+		for other, o := range found {
+			words := strings.Fields(other)
+			if len(words) > 1 && (words[0] == name || words[len(words)-1] == name) {
+				if full != nil {
+					full = nil
+					break
+				}
+				full = o
+			}
+		}
+		if full != nil {
+			full.count += c.count
+			delete(found, name)
+		}
+	}
+
+	var entities []*candidate
+	for _, c := range found {
+		if c.midText {
+			entities = append(entities, c)
+		}
+	}
+	sort.Slice(entities, func(i, j int) bool {
+		if entities[i].count != entities[j].count {
+			return entities[i].count > entities[j].count
+		}
+		return entities[i].first < entities[j].first
+	})
+	out := []Entity{}
+	for _, c := range entities {
+		if len(out) == maxEntities {
+			break
+		}
+		out = append(out, Entity{Name: c.name, Count: c.count})
+	}
+	return out
+}
+
+// token is a word or a break between names
+type token struct {
+	text string
+	word bool
+	end  bool // the break ends a sentence
+}
+
+// tokens splits text into words and the punctuation between them. Spaces
+// are not tokens, so the words of a name stay next to each other.
+func tokens(text string) []token {
+	var out []token
+	var word strings.Builder
+	flush := func() {
+		if word.Len() > 0 {
+			out = append(out, token{text: strings.Trim(word.String(), "'’-"), word: true})
+			word.Reset()
+		}
+	}
+	for _, r := range text {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || ((r == '\'' || r == '’' || r == '-') && word.Len() > 0):
+			word.WriteRune(r)
+		case r == ' ' || r == '\t':
+			flush()
+		default:
+			flush()
+			out = append(out, token{text: string(r), end: r == '.' || r == '!' || r == '?' || r == '\n'})
+		}
+	}
+	flush()
+	return out
+}
+
+// coverKeys are the metadata fields a blob's cover URL may be set in
+var coverKeys = []string{"cover_url", "cover_image_url", "image_url", "thumbnail_url"}
+
+// cover picks a card's image: one set in the blob's metadata, else the
+// newest generated cover among its children, else its first image
+func cover(b *blob.Blob, children []*blob.Blob) *Cover {
+	for _, key := range coverKeys {
+		if url, ok := b.Metadata[key].(string); ok && url != "" {
+			return &Cover{URL: url, Source: "metadata"}
+		}
+	}
+	var best *blob.Blob
+	for _, child := range children {
+		if kind, _ := child.Metadata["kind"].(string); kind != "image" {
+			continue
+		}
+		isCover := child.Metadata["image_kind"] == "cover"
+		switch {
+		case best == nil:
+			best = child
+		case isCover && best.Metadata["image_kind"] != "cover":
+			best = child
+		case isCover && child.CreatedAt.After(best.CreatedAt):
+			best = child
+		case !isCover && best.Metadata["image_kind"] != "cover" && child.CreatedAt.Before(best.CreatedAt):
+			best = child
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	url, _ := best.Metadata["image_url"].(string)
+	if url == "" {
+		url = best.Content
+	}
+	return &Cover{URL: url, BlobID: best.ID, Source: "image"}
+}
+
+// title picks a card's title from the first heading or line of text
+func title(b *blob.Blob, text string) string {
+	for _, line := range strings.Split(b.Content, "\n") {
+		if strings.HasPrefix(line, "#") {
+			return truncate(strings.TrimSpace(strings.TrimLeft(line, "#")), maxTitleRunes)
+		}
+	}
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return truncate(line, maxTitleRunes)
+		}
+	}
+	return ""
+}
+
+// Markdown syntax plainText removes
+var (
+	fencePattern    = regexp.MustCompile("(?s)```.*?```")
+	imagePattern    = regexp.MustCompile(`!\[[^\]]*\]\([^)]*\)`)
+	linkPattern     = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+	headingPattern  = regexp.MustCompile(`(?m)^\s{0,3}#{1,6}\s.*$`)
+	linePrefixes    = regexp.MustCompile(`(?m)^\s{0,3}(?:#{1,6}\s+|>\s?|[-*+]\s+|\d+[.)]\s+)`)
+	emphasisPattern = regexp.MustCompile("[*_`~]+")
+	blankLines      = regexp.MustCompile(`\n{3,}`)
+	trailingSpaces  = regexp.MustCompile(`[ \t]+\n`)
+)
+
+// plainText reduces content to the text a reader reads: the string values
+// of JSON content, and markdown without its markup
+func plainText(content string) string {
+	trimmed := strings.TrimSpace(content)
+	if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		var value interface{}
+		if json.Unmarshal([]byte(trimmed), &value) == nil {
+			var parts []string
+			collectStrings(value, &parts)
+			return strings.Join(parts, "\n\n")
+		}
+	}
+	text := fencePattern.ReplaceAllString(content, "")
+	text = imagePattern.ReplaceAllString(text, "")
+	text = linkPattern.ReplaceAllString(text, "$1")
+	text = linePrefixes.ReplaceAllString(text, "")
+	text = emphasisPattern.ReplaceAllString(text, "")
+	text = trailingSpaces.ReplaceAllString(text, "\n")
+	return strings.TrimSpace(blankLines.ReplaceAllString(text, "\n\n"))
+}
+
+// collectStrings gathers the string values of decoded JSON in order,
+// visiting object keys sorted
+func collectStrings(value interface{}, parts *[]string) {
+	switch v := value.(type) {
+	case string:
+		if strings.TrimSpace(v) != "" {
+			*parts = append(*parts, v)
+		}
+	case []interface{}:
+		for _, item := range v {
+			collectStrings(item, parts)
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			collectStrings(v[key], parts)
+		}
+	}
+}
+
+// truncate cuts text to at most n characters, marking the cut
+func truncate(text string, n int) string {
+	if runes := []rune(text); len(runes) > n {
+		return strings.TrimSpace(string(runes[:n-1])) + "…"
+	}
+	return text
+}
@@ -0,0 +1,276 @@
+package preview
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/memmieai/memmie-studio/internal/blob"
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// ProviderID owns the blobs cards are cached in
+const ProviderID = "preview"
+
+// KindCard marks a cached card blob, a child of the blob it describes
+const KindCard = "preview_card"
+
+// pendingTimeout is how long a card waits for its workflow before a later
+// request starts it again
+const pendingTimeout = 10 * time.Minute
+
+// Service builds and caches cards
+type Service struct {
+	blobs      blob.Store
+	workflows  workflows.WorkflowService
+	mu         sync.Mutex
+	registered bool
+}
+
+// NewService creates a preview service. Without a workflow service cards
+// are built from the content alone and are ready at once.
+func NewService(blobs blob.Store, workflowService workflows.WorkflowService) *Service {
+	return &Service{blobs: blobs, workflows: workflowService}
+}
+
+// Card returns a blob's card, from the cache unless the blob's content
+// changed or refresh is set. A new card is pending while the workflow
+// improves it.
+func (s *Service) Card(ctx context.Context, userID, blobID string, refresh bool) (*Card, error) {
+	b, err := s.blobs.GetBlob(ctx, userID, blobID)
+	if err != nil {
+		return nil, err
+	}
+	children, err := s.blobs.ListBlobs(ctx, userID, blob.Filter{ParentID: b.ID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list child blobs: %w", err)
+	}
+
+	cached, card := cachedCard(children)
+	if card != nil && !refresh && card.ContentHash == contentHash(b.Content) {
+		card.refresh(b)
+		card.Cover = cover(b, children)
+		if card.Status == StatusPending && time.Since(card.GeneratedAt) > pendingTimeout {
+			card.Status = StatusReady
+			if s.workflows != nil {
+				if err := s.generate(ctx, b, card); err != nil {
+					return nil, err
+				}
+			}
+			if err := s.save(ctx, b, cached, card); err != nil {
+				return nil, err
+			}
+		}
+		return card, nil
+	}
+
+	card = Build(b, children)
+	card.Status = StatusReady
+	if s.workflows != nil {
+		if err := s.generate(ctx, b, card); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.save(ctx, b, cached, card); err != nil {
+		return nil, err
+	}
+	return card, nil
+}
+
+// Cards returns the cards of several blobs in order, leaving out blobs that
+// do not exist
+func (s *Service) Cards(ctx context.Context, userID string, blobIDs []string) ([]*Card, error) {
+	cards := make([]*Card, 0, len(blobIDs))
+	for _, id := range blobIDs {
+		card, err := s.Card(ctx, userID, id, false)
+		if errors.Is(err, blob.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		cards = append(cards, card)
+	}
+	return cards, nil
+}
+
+// ProviderCards returns the cards of a provider's blobs, most recently
+// updated first
+func (s *Service) ProviderCards(ctx context.Context, userID, providerID string, limit int) ([]*Card, error) {
+	blobs, err := s.blobs.ListBlobs(ctx, userID, blob.Filter{ProviderID: providerID, Limit: limit})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list blobs: %w", err)
+	}
+	ids := make([]string, 0, len(blobs))
+	for _, b := range blobs {
+		if b.ProviderID != ProviderID {
+			ids = append(ids, b.ID)
+		}
+	}
+	return s.Cards(ctx, userID, ids)
+}
+
+// Complete merges a workflow's summary and entities into a blob's cached
+// card and marks it ready. Results for content that has since changed are
+// dropped, and reported by returning nil.
+func (s *Service) Complete(ctx context.Context, userID, blobID, hash, summary string, entities []Entity) (*Card, error) {
+	b, err := s.blobs.GetBlob(ctx, userID, blobID)
+	if err != nil {
+		return nil, err
+	}
+	children, err := s.blobs.ListBlobs(ctx, userID, blob.Filter{ParentID: b.ID, ProviderID: ProviderID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list child blobs: %w", err)
+	}
+	cached, card := cachedCard(children)
+	current := contentHash(b.Content)
+	if card == nil || card.ContentHash != current || (hash != "" && hash != current) {
+		return nil, nil
+	}
+
+	if summary != "" {
+		card.Summary = summary
+		card.SummarySource = SourceModel
+	}
+	if len(entities) > 0 {
+		card.Entities = mergeEntities(entities, card.Entities)
+	}
+	card.Status = StatusReady
+	card.GeneratedAt = time.Now()
+	card.refresh(b)
+	if err := s.save(ctx, b, cached, card); err != nil {
+		return nil, err
+	}
+	return card, nil
+}
+
+// generate starts the workflow that improves a card, marking it pending
+func (s *Service) generate(ctx context.Context, b *blob.Blob, card *Card) error {
+	if err := s.register(ctx); err != nil {
+		return err
+	}
+	input := map[string]interface{}{
+		"blob":         b.ToMap(),
+		"blob_id":      b.ID,
+		"user_id":      b.UserID,
+		"provider_id":  b.ProviderID,
+		"content_hash": card.ContentHash,
+		"text":         plainText(b.Content),
+	}
+	_, err := s.workflows.ExecuteWorkflow(ctx, workflows.ExecutionRequest{
+		WorkflowID: workflows.PreviewCardWorkflowID,
+		Input:      input,
+		Context: workflows.ExecutionContext{
+			UserID:     b.UserID,
+			ProviderID: b.ProviderID,
+			BlobID:     b.ID,
+		},
+		Async: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start card workflow: %w", err)
+	}
+	card.Status = StatusPending
+	card.GeneratedAt = time.Now()
+	return nil
+}
+
+// register registers the card workflow with the workflow service the
+// first time it is needed
+func (s *Service) register(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.registered {
+		return nil
+	}
+	if err := s.workflows.RegisterWorkflow(ctx, workflows.CreatePreviewCardWorkflow()); err != nil {
+		return fmt.Errorf("failed to register card workflow: %w", err)
+	}
+	s.registered = true
+	return nil
+}
+
+// save writes a card to its cache blob, creating it the first time
+func (s *Service) save(ctx context.Context, b *blob.Blob, cached *blob.Blob, card *Card) error {
+	data, err := json.Marshal(card)
+	if err != nil {
+		return fmt.Errorf("failed to encode card: %w", err)
+	}
+	if cached != nil {
+		cached.Content = string(data)
+		if cached.Metadata == nil {
+			cached.Metadata = make(map[string]interface{})
+		}
+		cached.Metadata["content_hash"] = card.ContentHash
+		cached.Metadata["status"] = card.Status
+		if _, err := s.blobs.UpdateBlob(ctx, cached); err != nil {
+			return fmt.Errorf("failed to update card: %w", err)
+		}
+		return nil
+	}
+	parentID := b.ID
+	_, err = s.blobs.CreateBlob(ctx, &blob.Blob{
+		UserID:     b.UserID,
+		ProviderID: ProviderID,
+		ParentID:   &parentID,
+		Content:    string(data),
+		Metadata: map[string]interface{}{
+			"kind":         KindCard,
+			"derived_from": parentID,
+			"content_hash": card.ContentHash,
+			"status":       card.Status,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store card: %w", err)
+	}
+	return nil
+}
+
+// cachedCard finds the cached card among a blob's children
+func cachedCard(children []*blob.Blob) (*blob.Blob, *Card) {
+	for _, child := range children {
+		if child.ProviderID != ProviderID || child.Metadata["kind"] != KindCard {
+			continue
+		}
+		var card Card
+		if err := json.Unmarshal([]byte(child.Content), &card); err != nil {
+			return child, nil
+		}
+		return child, &card
+	}
+	return nil, nil
+}
+
+// mergeEntities puts an extractor's entities first, taking counts from the
+// names found in the text where they match
+func mergeEntities(extracted, found []Entity) []Entity {
+	counts := make(map[string]int, len(found))
+	for _, e := range found {
+		counts[e.Name] = e.Count
+	}
+	out := make([]Entity, 0, maxEntities)
+	seen := make(map[string]bool, len(extracted))
+	for _, e := range extracted {
+		if e.Name == "" || seen[e.Name] || len(out) == maxEntities {
+			continue
+		}
+		seen[e.Name] = true
+		if e.Count == 0 {
+			e.Count = max(1, counts[e.Name])
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+// contentHash identifies content
+func contentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
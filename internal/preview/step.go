@@ -0,0 +1,101 @@
+package preview
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// StepType is the step that stores a card workflow's results
+const StepType = "preview_card"
+
+// NewStepExecutor creates the executor that completes a blob's cached card.
+// Step inputs: content_hash, the content the workflow was started for;
+// summary, a summarizer's output or its summary field; and entities, an
+// extractor's output: a list of names, a list of objects with a name (or
+// text or entity) and a type (or label), or an object of names by type,
+// optionally under entities. Results for changed content are skipped.
+func NewStepExecutor(service *Service) workflows.StepExecutor {
+	return workflows.StepExecutorFunc(func(ctx context.Context, req workflows.StepRequest) (map[string]interface{}, error) {
+		hash, _ := req.Input["content_hash"].(string)
+		card, err := service.Complete(ctx, req.Context.UserID, req.Context.BlobID, hash, summaryOf(req.Input["summary"]), entitiesOf(req.Input["entities"]))
+		if err != nil {
+			return nil, err
+		}
+		if card == nil {
+			return map[string]interface{}{"skipped": true, "reason": "content changed"}, nil
+		}
+		return map[string]interface{}{
+			"skipped":         false,
+			"summary":         card.Summary,
+			"entities":        card.Entities,
+			"reading_minutes": card.ReadingMinutes,
+			"status":          card.Status,
+		}, nil
+	})
+}
+
+// summaryOf reads a summarizer's output
+func summaryOf(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case map[string]interface{}:
+		for _, key := range []string{"summary", "text", "result"} {
+			if s, ok := v[key].(string); ok {
+				return strings.TrimSpace(s)
+			}
+		}
+	}
+	return ""
+}
+
+// entitiesOf reads an extractor's output
+func entitiesOf(value interface{}) []Entity {
+	m, ok := value.(map[string]interface{})
+	if ok {
+		if inner, ok := m["entities"]; ok {
+			return entitiesOf(inner)
+		}
+		kinds := make([]string, 0, len(m))
+		for kind := range m {
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+		var entities []Entity
+		for _, kind := range kinds {
+			for _, name := range workflows.StringList(m[kind]) {
+				entities = append(entities, Entity{Name: name, Type: kind})
+			}
+		}
+		return entities
+	}
+
+	items, _ := value.([]interface{})
+	var entities []Entity
+	for _, item := range items {
+		switch v := item.(type) {
+		case string:
+			entities = append(entities, Entity{Name: v})
+		case map[string]interface{}:
+			var e Entity
+			for _, key := range []string{"name", "text", "entity"} {
+				if e.Name, _ = v[key].(string); e.Name != "" {
+					break
+				}
+			}
+			for _, key := range []string{"type", "label"} {
+				if e.Type, _ = v[key].(string); e.Type != "" {
+					break
+				}
+			}
+			if count, ok := v["count"].(float64); ok {
+				e.Count = int(count)
+			}
+			entities = append(entities, e)
+		}
+	}
+	return entities
+}
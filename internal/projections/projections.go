@@ -0,0 +1,222 @@
+// Package projections keeps read models of blobs, such as a book's table of
+// contents, up to date as deltas are applied to them, so lists can be read
+// without materializing every blob they cover. A read model can always be
+// rebuilt from the blobs and their delta history.
+package projections
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/memmieai/memmie-studio/internal/blob"
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// rebuildPageSize is how many blobs a rebuild lists at a time
+const rebuildPageSize = 200
+
+// ErrUnknownProjection is returned for read models no projection keeps
+var ErrUnknownProjection = errors.New("unknown projection")
+
+// History supplies a blob's delta log. Any workflows.DeltaStorage is a
+// History.
+type History interface {
+	GetByBlobID(ctx context.Context, blobID string) ([]workflows.Delta, error)
+}
+
+// Source is a blob as projections see it: the blob and its state replayed
+// from its delta log up to Sequence
+type Source struct {
+	Blob     *blob.Blob
+	State    map[string]interface{}
+	Sequence int64
+}
+
+// Projection maps a blob to the rows it contributes to a read model
+type Projection interface {
+	// Name names the read model
+	Name() string
+	// Project returns a blob's rows, none when the read model does not
+	// cover it
+	Project(src Source) []Row
+}
+
+// Projector projects blobs into read models whenever deltas are applied to
+// them. Each blob is projected from its latest state, so a missed or
+// repeated event is corrected by the blob's next one, and one blob is
+// projected at a time.
+type Projector struct {
+	blobs       blob.Store
+	history     History
+	store       Store
+	projections []Projection
+	onError     func(error)
+	locks       *workflows.KeyedMutex
+	processor   workflows.DeltaProcessor
+}
+
+// NewProjector creates a projector keeping the projections' read models in
+// store. Without history blobs are projected without delta state. onError,
+// if set, is told of blobs that failed to project from events.
+func NewProjector(blobs blob.Store, history History, store Store, projections []Projection, onError func(error)) *Projector {
+	return &Projector{
+		blobs:       blobs,
+		history:     history,
+		store:       store,
+		projections: projections,
+		onError:     onError,
+		locks:       workflows.NewKeyedMutex(),
+	}
+}
+
+// Subscribe projects the blobs of delta.applied and delta.batch_applied
+// events on bus until ctx ends
+func (p *Projector) Subscribe(ctx context.Context, bus workflows.EventBus) error {
+	return bus.Subscribe(ctx, func(ctx context.Context, event workflows.Event) error {
+		if (event.Type != workflows.EventDeltaApplied && event.Type != workflows.EventDeltaBatchApplied) || event.BlobID == "" {
+			return nil
+		}
+		if err := p.Refresh(ctx, event.UserID, event.BlobID); err != nil && p.onError != nil {
+			p.onError(err)
+		}
+		return nil
+	}, workflows.EventFilter{Types: []string{workflows.EventDeltaApplied, workflows.EventDeltaBatchApplied}})
+}
+
+// Refresh projects one of a user's blobs again, removing its rows when it
+// no longer exists or is in the trash
+func (p *Projector) Refresh(ctx context.Context, userID, blobID string) error {
+	unlock, err := p.locks.Lock(ctx, blobID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	b, err := p.blobs.GetBlob(ctx, userID, blobID)
+	if errors.Is(err, blob.ErrNotFound) {
+		b = nil
+	} else if err != nil {
+		return fmt.Errorf("failed to get blob %s: %w", blobID, err)
+	}
+	rows := make(map[string][]Row, len(p.projections))
+	if b != nil && !b.Trashed() {
+		src, err := p.source(ctx, b)
+		if err != nil {
+			return err
+		}
+		for _, projection := range p.projections {
+			rows[projection.Name()] = stamp(projection.Project(src), src)
+		}
+	}
+	for _, projection := range p.projections {
+		if err := p.store.ReplaceBlob(ctx, projection.Name(), userID, blobID, rows[projection.Name()]); err != nil {
+			return fmt.Errorf("failed to project blob %s into %s: %w", blobID, projection.Name(), err)
+		}
+	}
+	return nil
+}
+
+// Rebuild replaces a user's read models with ones projected afresh from
+// every blob of theirs and its delta history, for read models that missed
+// changes or a projection that changed. It returns how many blobs were
+// projected.
+func (p *Projector) Rebuild(ctx context.Context, userID string) (int, error) {
+	rows := make(map[string][]Row, len(p.projections))
+	seen := make(map[string]bool)
+	filter := blob.Filter{Limit: rebuildPageSize}
+	for {
+		page, err := p.blobs.ListBlobs(ctx, userID, filter)
+		if err != nil && !errors.Is(err, blob.ErrNotFound) {
+			return 0, fmt.Errorf("failed to list blobs: %w", err)
+		}
+		before := len(seen)
+		for _, b := range page {
+			if seen[b.ID] {
+				continue
+			}
+			seen[b.ID] = true
+			if b.Trashed() {
+				continue
+			}
+			src, err := p.source(ctx, b)
+			if err != nil {
+				return 0, err
+			}
+			for _, projection := range p.projections {
+				rows[projection.Name()] = append(rows[projection.Name()], stamp(projection.Project(src), src)...)
+			}
+		}
+		// Stop on a short page, or one with nothing new in case the store
+		// ignores the offset
+		if len(page) < rebuildPageSize || len(seen) == before {
+			break
+		}
+		filter.Offset += len(page)
+	}
+
+	for _, projection := range p.projections {
+		if err := p.store.ReplaceUser(ctx, projection.Name(), userID, rows[projection.Name()]); err != nil {
+			return 0, fmt.Errorf("failed to rebuild %s: %w", projection.Name(), err)
+		}
+	}
+	return len(seen), nil
+}
+
+// List returns the rows of a user's read model in a group, in position
+// order
+func (p *Projector) List(ctx context.Context, name, userID, group string) ([]Row, error) {
+	if !p.Has(name) {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProjection, name)
+	}
+	return p.store.List(ctx, name, userID, group)
+}
+
+// Has reports whether a projection keeps a read model
+func (p *Projector) Has(name string) bool {
+	for _, projection := range p.projections {
+		if projection.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Names returns the read models kept, in the order projections were given
+func (p *Projector) Names() []string {
+	names := make([]string, len(p.projections))
+	for i, projection := range p.projections {
+		names[i] = projection.Name()
+	}
+	return names
+}
+
+// source replays a blob's delta state
+func (p *Projector) source(ctx context.Context, b *blob.Blob) (Source, error) {
+	src := Source{Blob: b, State: map[string]interface{}{}}
+	if p.history == nil {
+		return src, nil
+	}
+	deltas, err := p.history.GetByBlobID(ctx, b.ID)
+	if err != nil {
+		return Source{}, fmt.Errorf("failed to get deltas of %s: %w", b.ID, err)
+	}
+	src.State = p.processor.Replay(deltas, math.MaxInt64)
+	for _, delta := range deltas {
+		if delta.Sequence > src.Sequence {
+			src.Sequence = delta.Sequence
+		}
+	}
+	return src, nil
+}
+
+// stamp marks rows with the blob and state they were projected from
+func stamp(rows []Row, src Source) []Row {
+	for i := range rows {
+		rows[i].BlobID = src.Blob.ID
+		rows[i].Sequence = src.Sequence
+		rows[i].UpdatedAt = src.Blob.UpdatedAt
+	}
+	return rows
+}
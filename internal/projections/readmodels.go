@@ -0,0 +1,145 @@
+package projections
+
+import (
+	"strings"
+
+	"github.com/memmieai/memmie-studio/internal/books"
+)
+
+// Read model names
+const (
+	BookContentsName = "book_contents"
+	TopicIndexName   = "topic_index"
+)
+
+// ResearchProviderPrefix starts the provider ID of research documents,
+// followed by their topic, as workflows.CreateResearchWorkflow names it
+const ResearchProviderPrefix = "research:"
+
+// Defaults returns the read models the server keeps
+func Defaults() []Projection {
+	return []Projection{BookContents{}, TopicIndex{}}
+}
+
+// BookContents is the table of contents of each book: a row per chapter,
+// grouped by book and positioned by chapter number, with the summary its
+// workflows produced
+type BookContents struct{}
+
+// Name names the read model
+func (BookContents) Name() string { return BookContentsName }
+
+// Project returns a chapter's row
+func (BookContents) Project(src Source) []Row {
+	b := src.Blob
+	if b.Metadata["type"] != books.TypeChapter || b.NamespaceID == "" {
+		return nil
+	}
+	number := metaInt(b.Metadata, "chapter_number")
+	return []Row{{
+		Key:      b.ID,
+		Group:    b.NamespaceID,
+		Position: number,
+		Title:    metaString(b.Metadata, "chapter_title"),
+		Data: map[string]interface{}{
+			"chapter_id":     b.ID,
+			"chapter_number": number,
+			"chapter_title":  metaString(b.Metadata, "chapter_title"),
+			"status":         metaString(b.Metadata, "status"),
+			"word_count":     metaInt(b.Metadata, "word_count"),
+			"summary":        summary(src.State),
+		},
+	}}
+}
+
+// TopicIndex indexes each research topic's documents: a row per document,
+// grouped by topic and ordered by title, with its tags and summary
+type TopicIndex struct{}
+
+// Name names the read model
+func (TopicIndex) Name() string { return TopicIndexName }
+
+// Project returns a research document's row
+func (TopicIndex) Project(src Source) []Row {
+	b := src.Blob
+	if !strings.HasPrefix(b.ProviderID, ResearchProviderPrefix) {
+		return nil
+	}
+	topic := b.NamespaceID
+	if topic == "" {
+		topic = strings.TrimPrefix(b.ProviderID, ResearchProviderPrefix)
+	}
+	title := metaString(b.Metadata, "title")
+	if title == "" {
+		title = firstLine(b.Content)
+	}
+	return []Row{{
+		Key:   b.ID,
+		Group: topic,
+		Title: title,
+		Data: map[string]interface{}{
+			"blob_id": b.ID,
+			"title":   title,
+			"tags":    metaStrings(b.Metadata, "tags"),
+			"summary": summary(src.State),
+		},
+	}}
+}
+
+// summary returns the summary a blob's workflows produced: a summary
+// field, or the summary of a summary step's output
+func summary(state map[string]interface{}) string {
+	switch value := state["summary"].(type) {
+	case string:
+		return value
+	case map[string]interface{}:
+		s, _ := value["summary"].(string)
+		return s
+	}
+	return ""
+}
+
+// firstLine returns the first non-empty line of text, without heading marks
+func firstLine(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(strings.TrimLeft(line, "# ")); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// metaString reads a string metadata field
+func metaString(metadata map[string]interface{}, key string) string {
+	s, _ := metadata[key].(string)
+	return s
+}
+
+// metaInt reads an integer metadata field, decoded from JSON or not
+func metaInt(metadata map[string]interface{}, key string) int {
+	switch n := metadata[key].(type) {
+	case int:
+		return n
+	case int64:
+		return int(n)
+	case float64:
+		return int(n)
+	}
+	return 0
+}
+
+// metaStrings reads a string list metadata field
+func metaStrings(metadata map[string]interface{}, key string) []string {
+	strs := []string{}
+	switch list := metadata[key].(type) {
+	case []string:
+		strs = append(strs, list...)
+	case []interface{}:
+		for _, item := range list {
+			if s, ok := item.(string); ok {
+				strs = append(strs, s)
+			}
+		}
+	}
+	return strs
+}
@@ -0,0 +1,108 @@
+package projections
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Row is one entry of a read model, such as a chapter in a book's table of
+// contents. Rows are listed by group, such as the book, in position order
+// and then by title.
+type Row struct {
+	Key       string                 `json:"key"`
+	Group     string                 `json:"group"`
+	Position  int                    `json:"position"`
+	Title     string                 `json:"title,omitempty"`
+	BlobID    string                 `json:"blob_id"`
+	Sequence  int64                  `json:"sequence"` // last delta of the blob projected
+	Data      map[string]interface{} `json:"data"`
+	UpdatedAt time.Time              `json:"updated_at"`
+}
+
+// Store keeps read models by name and user
+type Store interface {
+	// ReplaceBlob replaces the rows a blob contributes to a read model
+	ReplaceBlob(ctx context.Context, name, userID, blobID string, rows []Row) error
+	// ReplaceUser replaces all of a user's rows of a read model
+	ReplaceUser(ctx context.Context, name, userID string, rows []Row) error
+	// List returns a user's rows of a read model in a group, ordered by
+	// position, title and key
+	List(ctx context.Context, name, userID, group string) ([]Row, error)
+}
+
+// modelKey identifies a user's read model
+type modelKey struct {
+	name   string
+	userID string
+}
+
+// MemoryStore is an in-process Store. Read models are lost on restart and
+// rebuilt as blobs change or on request.
+type MemoryStore struct {
+	mu     sync.RWMutex
+	models map[modelKey]map[string][]Row // blob ID to its rows
+}
+
+// NewMemoryStore creates an empty store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{models: make(map[modelKey]map[string][]Row)}
+}
+
+// ReplaceBlob replaces the rows a blob contributes to a read model
+func (s *MemoryStore) ReplaceBlob(ctx context.Context, name, userID, blobID string, rows []Row) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := modelKey{name: name, userID: userID}
+	model := s.models[key]
+	if len(rows) == 0 {
+		delete(model, blobID)
+		return nil
+	}
+	if model == nil {
+		model = make(map[string][]Row)
+		s.models[key] = model
+	}
+	model[blobID] = append([]Row(nil), rows...)
+	return nil
+}
+
+// ReplaceUser replaces all of a user's rows of a read model
+func (s *MemoryStore) ReplaceUser(ctx context.Context, name, userID string, rows []Row) error {
+	model := make(map[string][]Row)
+	for _, row := range rows {
+		model[row.BlobID] = append(model[row.BlobID], row)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.models[modelKey{name: name, userID: userID}] = model
+	return nil
+}
+
+// List returns a user's rows of a read model in a group, ordered by
+// position, title and key
+func (s *MemoryStore) List(ctx context.Context, name, userID, group string) ([]Row, error) {
+	s.mu.RLock()
+	rows := []Row{}
+	for _, blobRows := range s.models[modelKey{name: name, userID: userID}] {
+		for _, row := range blobRows {
+			if row.Group == group {
+				rows = append(rows, row)
+			}
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Position != rows[j].Position {
+			return rows[i].Position < rows[j].Position
+		}
+		if ti, tj := strings.ToLower(rows[i].Title), strings.ToLower(rows[j].Title); ti != tj {
+			return ti < tj
+		}
+		return rows[i].Key < rows[j].Key
+	})
+	return rows, nil
+}
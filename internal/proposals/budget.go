@@ -0,0 +1,250 @@
+package proposals
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/memmieai/memmie-studio/internal/dataprofile"
+)
+
+// ErrInvalidBudget is returned for budgets that cannot be read
+var ErrInvalidBudget = errors.New("invalid budget")
+
+// categoryOrder is the order federal budget justifications conventionally
+// follow. Other categories come after, alphabetically.
+var categoryOrder = []string{
+	"personnel", "fringe benefits", "equipment", "travel", "participant support",
+	"supplies", "contractual", "other direct costs", "indirect costs",
+}
+
+// budgetColumns are the column names accepted for each budget field
+var budgetColumns = map[string][]string{
+	"category":    {"category", "type", "line", "line_item"},
+	"description": {"description", "item", "details", "justification"},
+	"amount":      {"amount", "cost", "total", "requested"},
+	"year":        {"year", "budget_year", "period"},
+}
+
+// BudgetItem is a line of a proposal budget
+type BudgetItem struct {
+	Category    string  `json:"category"`
+	Description string  `json:"description,omitempty"`
+	Amount      float64 `json:"amount"`
+	Year        int     `json:"year,omitempty"`
+}
+
+// CategoryTotal sums a budget category
+type CategoryTotal struct {
+	Category string       `json:"category"`
+	Total    float64      `json:"total"`
+	Share    float64      `json:"share"` // of the whole budget, 0 to 1
+	Items    []BudgetItem `json:"items"`
+}
+
+// Budget is a summarized proposal budget
+type Budget struct {
+	Total      float64         `json:"total"`
+	Categories []CategoryTotal `json:"categories"`
+	Years      map[int]float64 `json:"years,omitempty"`
+}
+
+// ParseBudget reads budget lines from CSV, TSV, JSON lines, a JSON array of
+// objects or an object with an "items" array. Each line needs a category
+// and an amount; description and year are optional.
+func ParseBudget(content string) ([]BudgetItem, error) {
+	trimmed := strings.TrimSpace(content)
+	if strings.HasPrefix(trimmed, "{") {
+		var wrapped struct {
+			Items json.RawMessage `json:"items"`
+		}
+		if err := json.Unmarshal([]byte(trimmed), &wrapped); err == nil && len(wrapped.Items) > 0 {
+			content = string(wrapped.Items)
+		}
+	}
+	table, err := dataprofile.Parse(content, "")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBudget, err)
+	}
+
+	columns := make(map[string]int)
+	for field, names := range budgetColumns {
+		columns[field] = -1
+		for i, column := range table.Columns {
+			if containsName(names, column) {
+				columns[field] = i
+				break
+			}
+		}
+	}
+	if columns["category"] == -1 || columns["amount"] == -1 {
+		return nil, fmt.Errorf("%w: a category and an amount column are required", ErrInvalidBudget)
+	}
+
+	items := make([]BudgetItem, 0, len(table.Rows))
+	for n, row := range table.Rows {
+		item := BudgetItem{
+			Category:    strings.TrimSpace(cellText(row, columns["category"])),
+			Description: strings.TrimSpace(cellText(row, columns["description"])),
+		}
+		if item.Category == "" {
+			return nil, fmt.Errorf("%w: line %d has no category", ErrInvalidBudget, n+1)
+		}
+		amount, ok := cellAmount(row, columns["amount"])
+		if !ok {
+			return nil, fmt.Errorf("%w: line %d has no amount", ErrInvalidBudget, n+1)
+		}
+		item.Amount = amount
+		if year, ok := cellAmount(row, columns["year"]); ok {
+			item.Year = int(year)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// Summarize totals budget lines by category and year. Categories are
+// matched ignoring case and keep the first spelling seen.
+func Summarize(items []BudgetItem) Budget {
+	budget := Budget{Categories: []CategoryTotal{}}
+	index := make(map[string]int)
+	for _, item := range items {
+		key := strings.ToLower(item.Category)
+		i, ok := index[key]
+		if !ok {
+			i = len(budget.Categories)
+			index[key] = i
+			budget.Categories = append(budget.Categories, CategoryTotal{Category: item.Category})
+		}
+		budget.Categories[i].Total += item.Amount
+		budget.Categories[i].Items = append(budget.Categories[i].Items, item)
+		budget.Total += item.Amount
+		if item.Year > 0 {
+			if budget.Years == nil {
+				budget.Years = make(map[int]float64)
+			}
+			budget.Years[item.Year] += item.Amount
+		}
+	}
+	for i := range budget.Categories {
+		if budget.Total != 0 {
+			budget.Categories[i].Share = budget.Categories[i].Total / budget.Total
+		}
+	}
+	sort.SliceStable(budget.Categories, func(i, j int) bool {
+		a, b := categoryRank(budget.Categories[i].Category), categoryRank(budget.Categories[j].Category)
+		if a != b {
+			return a < b
+		}
+		return strings.ToLower(budget.Categories[i].Category) < strings.ToLower(budget.Categories[j].Category)
+	})
+	return budget
+}
+
+// Narrative writes the budget justification: an overview of the request and
+// a paragraph per category listing what it pays for
+func (b Budget) Narrative() string {
+	var out strings.Builder
+	out.WriteString("## Budget Justification\n\n")
+	fmt.Fprintf(&out, "The total request is %s", money(b.Total))
+	if len(b.Years) > 1 {
+		years := make([]int, 0, len(b.Years))
+		for year := range b.Years {
+			years = append(years, year)
+		}
+		sort.Ints(years)
+		parts := make([]string, len(years))
+		for i, year := range years {
+			parts[i] = fmt.Sprintf("Year %d: %s", year, money(b.Years[year]))
+		}
+		fmt.Fprintf(&out, " over %d years (%s)", len(years), strings.Join(parts, "; "))
+	}
+	out.WriteString(".\n")
+
+	for _, c := range b.Categories {
+		fmt.Fprintf(&out, "\n**%s (%s, %.0f%% of the total).** ", c.Category, money(c.Total), c.Share*100)
+		var lines []string
+		for _, item := range c.Items {
+			line := item.Description
+			if line == "" {
+				line = c.Category
+			}
+			detail := money(item.Amount)
+			if item.Year > 0 {
+				detail += fmt.Sprintf(" in Year %d", item.Year)
+			}
+			lines = append(lines, fmt.Sprintf("%s (%s)", line, detail))
+		}
+		fmt.Fprintf(&out, "%s.\n", strings.Join(lines, "; "))
+	}
+	return out.String()
+}
+
+// categoryRank places conventional categories first
+func categoryRank(category string) int {
+	for i, name := range categoryOrder {
+		if strings.EqualFold(category, name) {
+			return i
+		}
+	}
+	return len(categoryOrder)
+}
+
+// money formats an amount in dollars with thousands separators, showing
+// cents only when there are any
+func money(amount float64) string {
+	sign := ""
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	cents := int64(math.Round(amount * 100))
+	whole := strconv.FormatInt(cents/100, 10)
+	for i := len(whole) - 3; i > 0; i -= 3 {
+		whole = whole[:i] + "," + whole[i:]
+	}
+	if cents%100 != 0 {
+		return fmt.Sprintf("%s$%s.%02d", sign, whole, cents%100)
+	}
+	return sign + "$" + whole
+}
+
+// containsName reports whether a column is one of the names, ignoring case
+// and spacing
+func containsName(names []string, column string) bool {
+	column = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(column)), " ", "_")
+	for _, name := range names {
+		if column == name {
+			return true
+		}
+	}
+	return false
+}
+
+// cellText reads a table cell as text
+func cellText(row []interface{}, i int) string {
+	if i < 0 || i >= len(row) || row[i] == nil {
+		return ""
+	}
+	if s, ok := row[i].(string); ok {
+		return s
+	}
+	return fmt.Sprint(row[i])
+}
+
+// cellAmount reads a table cell as a number, accepting currency text such
+// as "$12,500.00"
+func cellAmount(row []interface{}, i int) (float64, bool) {
+	if i < 0 || i >= len(row) || row[i] == nil {
+		return 0, false
+	}
+	if f, ok := row[i].(float64); ok {
+		return f, true
+	}
+	text := strings.NewReplacer("$", "", ",", "", " ", "").Replace(cellText(row, i))
+	f, err := strconv.ParseFloat(text, 64)
+	return f, err == nil
+}
@@ -0,0 +1,280 @@
+package proposals
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/memmieai/memmie-studio/internal/style"
+)
+
+// Compliance rules reported in issues
+const (
+	RuleMissingSection   = "missing_section"
+	RuleDuplicateSection = "duplicate_section"
+	RuleSectionOrder     = "section_order"
+	RuleSectionLength    = "section_length"
+	RulePageLimit        = "page_limit"
+	RuleAbstract         = "abstract"
+	RuleBudgetNarrative  = "budget_narrative"
+)
+
+// Issue severities. Errors make a proposal non-compliant; warnings do not.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Section statuses in a report
+const (
+	StatusOK       = "ok"
+	StatusMissing  = "missing"
+	StatusTooLong  = "too_long"
+	StatusTooShort = "too_short"
+)
+
+// Section is a headed part of a proposal. Its body runs to the next heading
+// of the same or a higher level, so it includes its subsections.
+type Section struct {
+	Heading    string
+	Level      int
+	Line       int
+	Words      int
+	Characters int
+}
+
+// Issue is a single compliance problem
+type Issue struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Section  string `json:"section,omitempty"`
+	Message  string `json:"message"`
+}
+
+// SectionReport is how one required or optional section fared
+type SectionReport struct {
+	Heading    string  `json:"heading"`         // as the funder names it
+	Found      string  `json:"found,omitempty"` // as the proposal names it
+	Required   bool    `json:"required"`
+	Words      int     `json:"words"`
+	Characters int     `json:"characters"`
+	Pages      float64 `json:"pages"`
+	Status     string  `json:"status"`
+}
+
+// Report is the result of a compliance check
+type Report struct {
+	Funder          string          `json:"funder"`
+	Program         string          `json:"program,omitempty"`
+	Compliant       bool            `json:"compliant"`
+	Errors          int             `json:"errors"`
+	Warnings        int             `json:"warnings"`
+	Words           int             `json:"words"`
+	Pages           float64         `json:"pages"` // counted toward the page limit
+	PageLimit       float64         `json:"page_limit,omitempty"`
+	Sections        []SectionReport `json:"sections"`
+	Issues          []Issue         `json:"issues"`
+	Abstract        string          `json:"abstract,omitempty"`
+	BudgetNarrative string          `json:"budget_narrative,omitempty"`
+	CheckedAt       time.Time       `json:"checked_at"`
+}
+
+// Generated is text produced for the proposal outside its content and
+// checked along with it
+type Generated struct {
+	Abstract        string
+	BudgetNarrative string
+}
+
+// ParseSections splits markdown into sections at its # headings, skipping
+// fenced code blocks
+func ParseSections(content string) []Section {
+	lines := strings.Split(content, "\n")
+	var sections []Section
+	var bodies [][]string
+	fenced := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fenced = !fenced
+		}
+		if level, heading := headingOf(trimmed); !fenced && level > 0 {
+			// A heading ends every open section at its level or deeper
+			sections = append(sections, Section{Heading: heading, Level: level, Line: i + 1})
+			bodies = append(bodies, nil)
+			continue
+		}
+		for j := range sections {
+			if !closedBefore(sections, j, len(sections)) {
+				bodies[j] = append(bodies[j], line)
+			}
+		}
+	}
+	for i := range sections {
+		body := strings.TrimSpace(strings.Join(bodies[i], "\n"))
+		sections[i].Words = len(style.Words(body))
+		sections[i].Characters = len([]rune(body))
+	}
+	return sections
+}
+
+// closedBefore reports whether section j was ended by a later heading among
+// the first n sections
+func closedBefore(sections []Section, j, n int) bool {
+	for k := j + 1; k < n; k++ {
+		if sections[k].Level <= sections[j].Level {
+			return true
+		}
+	}
+	return false
+}
+
+// headingOf parses an ATX heading line, returning level 0 for other lines
+func headingOf(line string) (int, string) {
+	level := 0
+	for level < len(line) && level < 6 && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level == len(line) || line[level] != ' ' {
+		return 0, ""
+	}
+	heading := strings.TrimSpace(strings.TrimRight(strings.TrimSpace(line[level:]), "#"))
+	if heading == "" {
+		return 0, ""
+	}
+	return level, heading
+}
+
+// Check checks a proposal's markdown content, and any generated abstract
+// and budget narrative, against the funder's requirements
+func Check(content string, req *Requirements, generated Generated) *Report {
+	sections := ParseSections(content)
+	report := &Report{
+		Funder:          req.Funder,
+		Program:         req.Program,
+		PageLimit:       req.PageLimit,
+		Sections:        []SectionReport{},
+		Issues:          []Issue{},
+		Abstract:        generated.Abstract,
+		BudgetNarrative: generated.BudgetNarrative,
+		CheckedAt:       time.Now().UTC(),
+	}
+	report.Words = len(style.Words(stripHeadings(content)))
+	counted := report.Words
+
+	lastFound, lastHeading := -1, ""
+	for _, rule := range req.Sections {
+		found := -1
+		for i, s := range sections {
+			if !rule.matches(s.Heading) {
+				continue
+			}
+			if found == -1 {
+				found = i
+				continue
+			}
+			report.issue(RuleDuplicateSection, SeverityWarning, rule.Heading,
+				fmt.Sprintf("%q appears again at line %d; only the first is checked", s.Heading, s.Line))
+		}
+
+		sr := SectionReport{Heading: rule.Heading, Required: rule.Required, Status: StatusOK}
+		if found == -1 {
+			sr.Status = StatusMissing
+			if rule.Required {
+				report.issue(RuleMissingSection, SeverityError, rule.Heading, fmt.Sprintf("required section %q is missing", rule.Heading))
+			}
+			report.Sections = append(report.Sections, sr)
+			continue
+		}
+
+		s := sections[found]
+		sr.Found, sr.Words, sr.Characters, sr.Pages = s.Heading, s.Words, s.Characters, round(req.pages(s.Words))
+		if rule.ExcludeFromLimit {
+			counted -= s.Words
+		}
+		if req.Ordered && found < lastFound {
+			report.issue(RuleSectionOrder, SeverityWarning, rule.Heading, fmt.Sprintf("%q should come after %q", rule.Heading, lastHeading))
+		}
+		if found > lastFound {
+			lastFound, lastHeading = found, rule.Heading
+		}
+
+		switch {
+		case rule.MaxPages > 0 && sr.Pages > rule.MaxPages:
+			sr.Status = StatusTooLong
+			report.issue(RuleSectionLength, SeverityError, rule.Heading, fmt.Sprintf("%.1f pages, limit is %g", sr.Pages, rule.MaxPages))
+		case rule.MaxWords > 0 && s.Words > rule.MaxWords:
+			sr.Status = StatusTooLong
+			report.issue(RuleSectionLength, SeverityError, rule.Heading, fmt.Sprintf("%d words, limit is %d", s.Words, rule.MaxWords))
+		case rule.MaxCharacters > 0 && s.Characters > rule.MaxCharacters:
+			sr.Status = StatusTooLong
+			report.issue(RuleSectionLength, SeverityError, rule.Heading, fmt.Sprintf("%d characters, limit is %d", s.Characters, rule.MaxCharacters))
+		case rule.MinWords > 0 && s.Words < rule.MinWords:
+			sr.Status = StatusTooShort
+			report.issue(RuleSectionLength, SeverityWarning, rule.Heading, fmt.Sprintf("%d words, expected at least %d", s.Words, rule.MinWords))
+		}
+		report.Sections = append(report.Sections, sr)
+	}
+
+	report.Pages = round(req.pages(counted))
+	if req.PageLimit > 0 && report.Pages > req.PageLimit {
+		report.issue(RulePageLimit, SeverityError, "", fmt.Sprintf("%.1f pages, limit is %g", report.Pages, req.PageLimit))
+	}
+	report.checkText(RuleAbstract, "abstract", generated.Abstract, req.Abstract, req)
+	report.checkText(RuleBudgetNarrative, "budget narrative", generated.BudgetNarrative, req.BudgetNarrative, req)
+
+	report.Compliant = report.Errors == 0
+	return report
+}
+
+// checkText checks a generated block of text against its rule
+func (r *Report) checkText(ruleName, label, text string, rule *TextRule, req *Requirements) {
+	if rule == nil {
+		return
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		if rule.Required {
+			r.issue(ruleName, SeverityError, "", fmt.Sprintf("the %s is required but was not produced", label))
+		}
+		return
+	}
+	words := len(style.Words(text))
+	characters := len([]rune(text))
+	switch {
+	case rule.MaxWords > 0 && words > rule.MaxWords:
+		r.issue(ruleName, SeverityError, "", fmt.Sprintf("the %s has %d words, limit is %d", label, words, rule.MaxWords))
+	case rule.MaxCharacters > 0 && characters > rule.MaxCharacters:
+		r.issue(ruleName, SeverityError, "", fmt.Sprintf("the %s has %d characters, limit is %d", label, characters, rule.MaxCharacters))
+	case rule.MaxPages > 0 && req.pages(words) > rule.MaxPages:
+		r.issue(ruleName, SeverityError, "", fmt.Sprintf("the %s runs %.1f pages, limit is %g", label, req.pages(words), rule.MaxPages))
+	}
+}
+
+// issue records a problem
+func (r *Report) issue(rule, severity, section, message string) {
+	r.Issues = append(r.Issues, Issue{Rule: rule, Severity: severity, Section: section, Message: message})
+	if severity == SeverityError {
+		r.Errors++
+	} else {
+		r.Warnings++
+	}
+}
+
+// stripHeadings drops heading lines so they are not counted as body text
+func stripHeadings(content string) string {
+	lines := strings.Split(content, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if level, _ := headingOf(strings.TrimSpace(line)); level == 0 {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+// round keeps two decimal places
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,75 @@
+package proposals
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/memmieai/memmie-studio/internal/blob"
+)
+
+// KindReport marks compliance report blobs
+const KindReport = "compliance_report"
+
+// Service checks proposals stored as blobs and keeps their reports
+type Service struct {
+	blobs blob.Store
+}
+
+// NewService creates a proposal service
+func NewService(blobs blob.Store) *Service {
+	return &Service{blobs: blobs}
+}
+
+// ProviderID returns the provider of a proposal, which owns its blobs
+func ProviderID(proposalID string) string {
+	return "proposal:" + proposalID
+}
+
+// Requirements loads a requirement schema blob
+func (s *Service) Requirements(ctx context.Context, userID, blobID string) (*Requirements, error) {
+	return LoadRequirements(ctx, s.blobs, userID, blobID)
+}
+
+// Budget loads and summarizes a budget blob
+func (s *Service) Budget(ctx context.Context, userID, blobID string) (Budget, error) {
+	b, err := s.blobs.GetBlob(ctx, userID, blobID)
+	if err != nil {
+		return Budget{}, fmt.Errorf("failed to load budget %s: %w", blobID, err)
+	}
+	items, err := ParseBudget(b.Content)
+	if err != nil {
+		return Budget{}, err
+	}
+	return Summarize(items), nil
+}
+
+// SaveReport stores a report as a child blob of the proposal it checks,
+// under the proposal's provider, and returns the blob's ID
+func (s *Service) SaveReport(ctx context.Context, userID, providerID, proposalBlobID, executionID string, report *Report) (string, error) {
+	content, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode report: %w", err)
+	}
+	parentID := proposalBlobID
+	b, err := s.blobs.CreateBlob(ctx, &blob.Blob{
+		UserID:     userID,
+		ProviderID: providerID,
+		Content:    string(content),
+		ParentID:   &parentID,
+		Metadata: map[string]interface{}{
+			"kind":         KindReport,
+			"derived_from": proposalBlobID,
+			"execution_id": executionID,
+			"funder":       report.Funder,
+			"compliant":    report.Compliant,
+			"errors":       report.Errors,
+			"warnings":     report.Warnings,
+			"pages":        report.Pages,
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to store report: %w", err)
+	}
+	return b.ID, nil
+}
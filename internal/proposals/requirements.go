@@ -0,0 +1,131 @@
+// Package proposals checks grant proposals against a funder's requirements
+// and drafts the budget narrative. Requirements are kept in a schema blob so
+// one funder's rules can be shared by every proposal written for it.
+package proposals
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/memmieai/memmie-studio/internal/blob"
+)
+
+// KindRequirements marks funder requirement schema blobs
+const KindRequirements = "funder_requirements"
+
+// DefaultWordsPerPage converts word counts to pages when the funder does not
+// say, roughly a single-spaced page of 11pt text
+const DefaultWordsPerPage = 500
+
+// ErrInvalidRequirements is returned for requirement schemas that cannot be
+// used
+var ErrInvalidRequirements = errors.New("invalid funder requirements")
+
+// Requirements are a funder's rules for a proposal
+type Requirements struct {
+	Funder          string        `json:"funder"`
+	Program         string        `json:"program,omitempty"`
+	PageLimit       float64       `json:"page_limit,omitempty"` // whole proposal, excluding sections marked so
+	WordsPerPage    int           `json:"words_per_page,omitempty"`
+	Ordered         bool          `json:"ordered,omitempty"` // sections must appear in the listed order
+	Sections        []SectionRule `json:"sections"`
+	Abstract        *TextRule     `json:"abstract,omitempty"`
+	BudgetNarrative *TextRule     `json:"budget_narrative,omitempty"`
+}
+
+// SectionRule describes one section the funder asks for
+type SectionRule struct {
+	Heading          string   `json:"heading"`
+	Aliases          []string `json:"aliases,omitempty"`
+	Required         bool     `json:"required"`
+	MaxPages         float64  `json:"max_pages,omitempty"`
+	MinWords         int      `json:"min_words,omitempty"`
+	MaxWords         int      `json:"max_words,omitempty"`
+	MaxCharacters    int      `json:"max_characters,omitempty"`
+	ExcludeFromLimit bool     `json:"exclude_from_limit,omitempty"` // e.g. references
+}
+
+// TextRule limits a generated or supplied block of text
+type TextRule struct {
+	Required      bool    `json:"required"`
+	MaxWords      int     `json:"max_words,omitempty"`
+	MaxCharacters int     `json:"max_characters,omitempty"`
+	MaxPages      float64 `json:"max_pages,omitempty"`
+}
+
+// ParseRequirements reads a JSON requirement schema
+func ParseRequirements(content string) (*Requirements, error) {
+	var r Requirements
+	if err := json.Unmarshal([]byte(content), &r); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRequirements, err)
+	}
+	if err := r.validate(); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// LoadRequirements reads the requirement schema stored in a blob
+func LoadRequirements(ctx context.Context, blobs blob.Store, userID, blobID string) (*Requirements, error) {
+	b, err := blobs.GetBlob(ctx, userID, blobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load requirements %s: %w", blobID, err)
+	}
+	return ParseRequirements(b.Content)
+}
+
+// validate checks the schema and fills in defaults
+func (r *Requirements) validate() error {
+	if r.WordsPerPage <= 0 {
+		r.WordsPerPage = DefaultWordsPerPage
+	}
+	seen := make(map[string]bool)
+	for i, s := range r.Sections {
+		key := normalizeHeading(s.Heading)
+		if key == "" {
+			return fmt.Errorf("%w: section %d has no heading", ErrInvalidRequirements, i+1)
+		}
+		if seen[key] {
+			return fmt.Errorf("%w: section %q is listed twice", ErrInvalidRequirements, s.Heading)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// pages converts a word count to pages
+func (r *Requirements) pages(words int) float64 {
+	return float64(words) / float64(r.WordsPerPage)
+}
+
+// matches reports whether a proposal heading satisfies the rule. Headings
+// match ignoring case, numbering and punctuation, and may carry a suffix
+// such as "Specific Aims (1 page)".
+func (s SectionRule) matches(heading string) bool {
+	normalized := normalizeHeading(heading)
+	for _, name := range append([]string{s.Heading}, s.Aliases...) {
+		want := normalizeHeading(name)
+		if want != "" && (normalized == want || strings.HasPrefix(normalized, want+" ")) {
+			return true
+		}
+	}
+	return false
+}
+
+// numbering matches section numbers such as "1.", "2.3", "B." or "IV)"
+var numbering = regexp.MustCompile(`^\s*(?:\d+(?:\.\d+)*\.?|[A-Ha-h][.)]|[IVXivx]{1,4}[.)])\s+`)
+
+// normalizeHeading lowercases a heading and strips its numbering and
+// punctuation
+func normalizeHeading(heading string) string {
+	heading = numbering.ReplaceAllString(heading, "")
+	fields := strings.FieldsFunc(strings.ToLower(heading), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(fields, " ")
+}
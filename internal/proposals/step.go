@@ -0,0 +1,120 @@
+package proposals
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// Step types handled by the proposal executors
+const (
+	ComplianceStepType = "proposal_compliance"
+	BudgetStepType     = "budget_narrative"
+)
+
+// CompliancePath is the proposal blob metadata path the report summary is
+// written to
+const CompliancePath = "metadata.compliance"
+
+// NewComplianceExecutor creates the executor that checks a proposal against
+// its funder's requirements. Step inputs: content, requirements_blob_id or
+// inline requirements (either falls back to the step's parameters), and the
+// generated abstract and budget_narrative. The report is stored as a child
+// blob of the proposal and its summary written to the proposal's metadata.
+func NewComplianceExecutor(service *Service) workflows.StepExecutor {
+	return workflows.StepExecutorFunc(func(ctx context.Context, req workflows.StepRequest) (map[string]interface{}, error) {
+		content, _ := req.Input["content"].(string)
+
+		var requirements *Requirements
+		var err error
+		if inline := req.Setting("requirements"); inline != nil {
+			requirements, err = inlineRequirements(inline)
+		} else if blobID, _ := req.Setting("requirements_blob_id").(string); blobID != "" {
+			requirements, err = service.Requirements(ctx, req.Context.UserID, blobID)
+		} else {
+			return nil, fmt.Errorf("requirements_blob_id or requirements is required")
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		generated := Generated{}
+		generated.Abstract, _ = req.Input["abstract"].(string)
+		generated.BudgetNarrative, _ = req.Input["budget_narrative"].(string)
+		report := Check(content, requirements, generated)
+
+		reportID, err := service.SaveReport(ctx, req.Context.UserID, req.Context.ProviderID, req.Context.BlobID, req.ExecutionID, report)
+		if err != nil {
+			return nil, err
+		}
+
+		summary := map[string]interface{}{
+			"report_id": reportID,
+			"funder":    report.Funder,
+			"compliant": report.Compliant,
+			"errors":    report.Errors,
+			"warnings":  report.Warnings,
+			"pages":     report.Pages,
+		}
+		return map[string]interface{}{
+			"report_id": reportID,
+			"report":    report,
+			"compliant": report.Compliant,
+			"summary":   summary,
+			"deltas": []interface{}{
+				map[string]interface{}{
+					"type":      "update",
+					"path":      CompliancePath,
+					"new_value": summary,
+					"metadata": map[string]interface{}{
+						"step_id":      req.Step.ID,
+						"execution_id": req.ExecutionID,
+					},
+				},
+			},
+		}, nil
+	})
+}
+
+// NewBudgetExecutor creates the executor that drafts the budget narrative.
+// Step inputs: budget_blob_id, or budget holding the budget's content.
+func NewBudgetExecutor(service *Service) workflows.StepExecutor {
+	return workflows.StepExecutorFunc(func(ctx context.Context, req workflows.StepRequest) (map[string]interface{}, error) {
+		var budget Budget
+		if content, _ := req.Input["budget"].(string); content != "" {
+			items, err := ParseBudget(content)
+			if err != nil {
+				return nil, err
+			}
+			budget = Summarize(items)
+		} else if blobID, _ := req.Input["budget_blob_id"].(string); blobID != "" {
+			var err error
+			if budget, err = service.Budget(ctx, req.Context.UserID, blobID); err != nil {
+				return nil, err
+			}
+		} else {
+			return nil, fmt.Errorf("budget_blob_id or budget is required")
+		}
+
+		return map[string]interface{}{
+			"narrative":  budget.Narrative(),
+			"total":      budget.Total,
+			"categories": budget.Categories,
+		}, nil
+	})
+}
+
+// inlineRequirements reads requirements given as JSON text or a decoded
+// object
+func inlineRequirements(value interface{}) (*Requirements, error) {
+	if text, ok := value.(string); ok {
+		return ParseRequirements(text)
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode requirements: %w", err)
+	}
+	return ParseRequirements(string(encoded))
+}
@@ -0,0 +1,105 @@
+// Package quotas limits how many workflow executions each user can run at
+// once and per day, and shares execution capacity between users with a
+// weighted fair scheduler, so one user's bulk import cannot starve
+// everyone else's work
+package quotas
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Quota errors
+var (
+	ErrQuotaExceeded = errors.New("execution quota exceeded")
+	ErrInvalidQuotas = errors.New("invalid quotas")
+)
+
+// Limits are one user's quotas. Zero means unlimited, except Weight, which
+// defaults to 1: a user with weight 2 is given twice the share of contended
+// capacity of a user with weight 1.
+type Limits struct {
+	Concurrent int     `json:"concurrent"`
+	Daily      int     `json:"daily"`
+	Queued     int     `json:"queued"`
+	Weight     float64 `json:"weight"`
+}
+
+// Config sets the quotas. MaxConcurrent caps executions across all users;
+// Default applies to every user, and Users overrides it per user ID, field
+// by field.
+type Config struct {
+	MaxConcurrent int               `json:"max_concurrent"`
+	Default       Limits            `json:"default"`
+	Users         map[string]Limits `json:"users"`
+}
+
+// LoadConfig reads quotas from a JSON file
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read quotas: %w", err)
+	}
+	return ParseConfig(data)
+}
+
+// ParseConfig reads quotas from JSON
+func ParseConfig(data []byte) (Config, error) {
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("%w: %v", ErrInvalidQuotas, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// Validate rejects negative limits
+func (c Config) Validate() error {
+	if c.MaxConcurrent < 0 {
+		return fmt.Errorf("%w: max_concurrent is negative", ErrInvalidQuotas)
+	}
+	if err := c.Default.validate(); err != nil {
+		return fmt.Errorf("%w: default: %v", ErrInvalidQuotas, err)
+	}
+	for userID, limits := range c.Users {
+		if err := limits.validate(); err != nil {
+			return fmt.Errorf("%w: user %s: %v", ErrInvalidQuotas, userID, err)
+		}
+	}
+	return nil
+}
+
+// For returns a user's limits
+func (c Config) For(userID string) Limits {
+	limits := c.Default
+	if override, ok := c.Users[userID]; ok {
+		if override.Concurrent != 0 {
+			limits.Concurrent = override.Concurrent
+		}
+		if override.Daily != 0 {
+			limits.Daily = override.Daily
+		}
+		if override.Queued != 0 {
+			limits.Queued = override.Queued
+		}
+		if override.Weight != 0 {
+			limits.Weight = override.Weight
+		}
+	}
+	if limits.Weight == 0 {
+		limits.Weight = 1
+	}
+	return limits
+}
+
+// validate rejects negative limits
+func (l Limits) validate() error {
+	if l.Concurrent < 0 || l.Daily < 0 || l.Queued < 0 || l.Weight < 0 {
+		return errors.New("limits must not be negative")
+	}
+	return nil
+}
@@ -0,0 +1,352 @@
+package quotas
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// Usage is a user's current use of their quotas
+type Usage struct {
+	UserID    string `json:"user_id"`
+	Running   int    `json:"running"`
+	Queued    int    `json:"queued"`
+	UsedToday int    `json:"used_today"`
+	Limits    Limits `json:"limits"`
+}
+
+// LaneStats reports how long one lane's requests have waited for slots.
+// Granted and the wait times cover every slot granted since the scheduler
+// started; OldestWaitMs is how long the longest-queued request has waited
+// so far.
+type LaneStats struct {
+	Lane         string  `json:"lane"`
+	Running      int     `json:"running"`
+	Queued       int     `json:"queued"`
+	Granted      int64   `json:"granted"`
+	MeanWaitMs   float64 `json:"mean_wait_ms"`
+	MaxWaitMs    float64 `json:"max_wait_ms"`
+	OldestWaitMs float64 `json:"oldest_wait_ms"`
+}
+
+// Scheduler hands out execution slots. A user's requests wait in their own
+// queue per lane while the user or the whole system is at its concurrency
+// limit. When a slot frees, it goes to the first lane with a request that
+// can run, so interactive requests overtake queued batch ones. Within the
+// lane, the waiting user with the earliest virtual finish time goes next;
+// each slot advances a user's finish time by 1/weight, so contended
+// capacity is shared in proportion to weight however many requests each
+// user has queued. The virtual time is the start of the latest slot
+// granted.
+type Scheduler struct {
+	cfg Config
+	now func() time.Time
+
+	mu       sync.Mutex
+	accounts map[string]*account
+	lanes    map[string]*lane
+	running  int
+	virtual  float64
+}
+
+// lane is a lane's running count and wait totals
+type lane struct {
+	running   int
+	granted   int64
+	totalWait time.Duration
+	maxWait   time.Duration
+}
+
+// account is a user's scheduling state
+type account struct {
+	userID  string
+	limits  Limits
+	running int
+	queues  map[string][]*ticket
+	finish  float64
+	day     string
+	used    int
+}
+
+// ticket is a request waiting for a slot
+type ticket struct {
+	lane     string
+	queuedAt time.Time
+	ready    chan struct{}
+	granted  bool
+}
+
+// Slot is a granted execution slot. Release frees it when the execution
+// ends; Cancel frees it and gives back the day's use when the execution
+// never started.
+type Slot struct {
+	scheduler *Scheduler
+	account   *account
+	lane      string
+	once      sync.Once
+}
+
+// NewScheduler creates a scheduler enforcing a config
+func NewScheduler(cfg Config) *Scheduler {
+	s := &Scheduler{
+		cfg:      cfg,
+		now:      time.Now,
+		accounts: make(map[string]*account),
+		lanes:    make(map[string]*lane),
+	}
+	for _, name := range workflows.Lanes {
+		s.lanes[name] = &lane{}
+	}
+	return s
+}
+
+// Acquire waits for an execution slot for a user in a lane; requests in no
+// known lane wait in the interactive lane. It fails at once with
+// ErrQuotaExceeded when the user's daily quota or queue is full, and with
+// the context's error if the context ends first.
+func (s *Scheduler) Acquire(ctx context.Context, userID, laneName string) (*Slot, error) {
+	if !workflows.IsLane(laneName) {
+		laneName = workflows.LaneInteractive
+	}
+
+	s.mu.Lock()
+	acc := s.account(userID)
+	queued := acc.queued()
+	if acc.limits.Daily > 0 && acc.used+queued >= acc.limits.Daily {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("%w: %d executions a day", ErrQuotaExceeded, acc.limits.Daily)
+	}
+	if acc.limits.Queued > 0 && queued >= acc.limits.Queued {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("%w: %d executions already queued", ErrQuotaExceeded, queued)
+	}
+	if queued == 0 && acc.finish < s.virtual {
+		// A user who has been waiting on nothing starts from the current
+		// virtual time, so idling earns no credit to burst with later
+		acc.finish = s.virtual
+	}
+	t := &ticket{lane: laneName, queuedAt: s.now(), ready: make(chan struct{})}
+	acc.queues[laneName] = append(acc.queues[laneName], t)
+	s.dispatch()
+	s.mu.Unlock()
+
+	select {
+	case <-t.ready:
+		return &Slot{scheduler: s, account: acc, lane: laneName}, nil
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t.granted {
+		// The slot was granted as the context ended
+		s.free(acc, laneName, true)
+		s.dispatch()
+		return nil, ctx.Err()
+	}
+	queue := acc.queues[laneName]
+	for i, waiting := range queue {
+		if waiting == t {
+			acc.queues[laneName] = append(queue[:i], queue[i+1:]...)
+			break
+		}
+	}
+	return nil, ctx.Err()
+}
+
+// Admit grants a slot in a lane at once, for interactive executions that
+// are worth nothing late. The slot counts against the user's and the
+// system's concurrency like any other, so queued requests wait on it, but
+// it is granted even above those limits. It fails with ErrQuotaExceeded
+// only when the user's daily quota is used up.
+func (s *Scheduler) Admit(userID, laneName string) (*Slot, error) {
+	if !workflows.IsLane(laneName) {
+		laneName = workflows.LaneInteractive
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	acc := s.account(userID)
+	if acc.limits.Daily > 0 && acc.used+acc.queued() >= acc.limits.Daily {
+		return nil, fmt.Errorf("%w: %d executions a day", ErrQuotaExceeded, acc.limits.Daily)
+	}
+	// The slot advances the user's finish time as a queued one would, so
+	// admitted executions still count towards their fair share
+	if acc.queued() == 0 && acc.finish < s.virtual {
+		acc.finish = s.virtual
+	}
+	acc.finish += 1 / acc.limits.Weight
+	acc.running++
+	acc.used++
+	s.running++
+	l := s.lanes[laneName]
+	l.running++
+	l.granted++
+	return &Slot{scheduler: s, account: acc, lane: laneName}, nil
+}
+
+// Usage reports a user's current use of their quotas
+func (s *Scheduler) Usage(userID string) Usage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	acc := s.account(userID)
+	return Usage{
+		UserID:    userID,
+		Running:   acc.running,
+		Queued:    acc.queued(),
+		UsedToday: acc.used,
+		Limits:    acc.limits,
+	}
+}
+
+// Lanes reports each lane's running and queued requests and how long its
+// requests have waited, in the order lanes are served
+func (s *Scheduler) Lanes() []LaneStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	stats := make([]LaneStats, 0, len(workflows.Lanes))
+	for _, name := range workflows.Lanes {
+		l := s.lanes[name]
+		stat := LaneStats{
+			Lane:      name,
+			Running:   l.running,
+			Granted:   l.granted,
+			MaxWaitMs: milliseconds(l.maxWait),
+		}
+		if l.granted > 0 {
+			stat.MeanWaitMs = milliseconds(l.totalWait) / float64(l.granted)
+		}
+		for _, acc := range s.accounts {
+			queue := acc.queues[name]
+			stat.Queued += len(queue)
+			if len(queue) > 0 {
+				// Queues are first in, first out, so the head waited longest
+				if wait := milliseconds(now.Sub(queue[0].queuedAt)); wait > stat.OldestWaitMs {
+					stat.OldestWaitMs = wait
+				}
+			}
+		}
+		stats = append(stats, stat)
+	}
+	return stats
+}
+
+// Release frees the slot
+func (slot *Slot) Release() {
+	slot.release(false)
+}
+
+// Cancel frees the slot without counting it against the day's quota
+func (slot *Slot) Cancel() {
+	slot.release(true)
+}
+
+// release frees the slot once
+func (slot *Slot) release(refund bool) {
+	slot.once.Do(func() {
+		s := slot.scheduler
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.free(slot.account, slot.lane, refund)
+		s.dispatch()
+	})
+}
+
+// account returns a user's scheduling state, starting a new day's count
+// when the day has changed
+func (s *Scheduler) account(userID string) *account {
+	acc, ok := s.accounts[userID]
+	if !ok {
+		acc = &account{userID: userID, limits: s.cfg.For(userID), queues: make(map[string][]*ticket), finish: s.virtual}
+		s.accounts[userID] = acc
+	}
+	if day := s.now().UTC().Format("2006-01-02"); acc.day != day {
+		acc.day = day
+		acc.used = 0
+	}
+	return acc
+}
+
+// dispatch grants slots to waiting users while capacity allows
+func (s *Scheduler) dispatch() {
+	for s.cfg.MaxConcurrent <= 0 || s.running < s.cfg.MaxConcurrent {
+		if !s.grantNext() {
+			return
+		}
+	}
+}
+
+// grantNext grants a slot to the request that goes next, reporting whether
+// any request could run
+func (s *Scheduler) grantNext() bool {
+	for _, name := range workflows.Lanes {
+		var next *account
+		var nextFinish float64
+		for _, acc := range s.accounts {
+			if len(acc.queues[name]) == 0 || (acc.limits.Concurrent > 0 && acc.running >= acc.limits.Concurrent) {
+				continue
+			}
+			finish := acc.finish + 1/acc.limits.Weight
+			if next == nil || finish < nextFinish || (finish == nextFinish && acc.userID < next.userID) {
+				next, nextFinish = acc, finish
+			}
+		}
+		if next == nil {
+			continue
+		}
+
+		t := next.queues[name][0]
+		next.queues[name] = next.queues[name][1:]
+		if next.finish > s.virtual {
+			s.virtual = next.finish
+		}
+		next.finish = nextFinish
+		next.running++
+		next.used++
+		s.running++
+
+		l := s.lanes[name]
+		wait := s.now().Sub(t.queuedAt)
+		l.running++
+		l.granted++
+		l.totalWait += wait
+		if wait > l.maxWait {
+			l.maxWait = wait
+		}
+
+		t.granted = true
+		close(t.ready)
+		return true
+	}
+	return false
+}
+
+// free returns a slot's capacity
+func (s *Scheduler) free(acc *account, laneName string, refund bool) {
+	acc.running--
+	s.lanes[laneName].running--
+	s.running--
+	if refund && acc.used > 0 {
+		acc.used--
+	}
+}
+
+// queued counts a user's requests waiting in every lane
+func (acc *account) queued() int {
+	n := 0
+	for _, queue := range acc.queues {
+		n += len(queue)
+	}
+	return n
+}
+
+// milliseconds converts a duration to fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,154 @@
+package quotas
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// DefaultPollInterval is how often running executions are checked for
+// completion
+const DefaultPollInterval = 5 * time.Second
+
+// Service schedules executions on a workflow service. Starting an execution
+// waits for a slot of the requesting user; the slot is held until the
+// execution is seen to finish, either when its status is looked up or by a
+// background poll of the executions still running.
+type Service struct {
+	workflows.WorkflowService
+	scheduler *Scheduler
+	onError   func(error)
+
+	mu      sync.Mutex
+	running map[string]*Slot
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// deletingService is a Service over a backend that can delete workflows
+type deletingService struct {
+	*Service
+	workflows.WorkflowDeleter
+}
+
+// NewService schedules a workflow service's executions with a scheduler,
+// polling running executions every interval. onError, if set, is told when
+// a poll fails. The result deletes workflows exactly when the wrapped
+// service can; Close stops polling.
+func NewService(next workflows.WorkflowService, scheduler *Scheduler, interval time.Duration, onError func(error)) workflows.WorkflowService {
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	s := &Service{
+		WorkflowService: next,
+		scheduler:       scheduler,
+		onError:         onError,
+		running:         make(map[string]*Slot),
+		stop:            make(chan struct{}),
+		done:            make(chan struct{}),
+	}
+	go s.poll(interval)
+	if deleter, ok := next.(workflows.WorkflowDeleter); ok {
+		return &deletingService{Service: s, WorkflowDeleter: deleter}
+	}
+	return s
+}
+
+// ExecuteWorkflow starts an execution once the requesting user has a slot
+// in the request's lane. Interactive executions are admitted at once
+// rather than queued.
+func (s *Service) ExecuteWorkflow(ctx context.Context, req workflows.ExecutionRequest) (*workflows.ExecutionResponse, error) {
+	var slot *Slot
+	var err error
+	if req.Profile == workflows.ProfileInteractive {
+		slot, err = s.scheduler.Admit(req.Context.UserID, req.Lane)
+	} else {
+		slot, err = s.scheduler.Acquire(ctx, req.Context.UserID, req.Lane)
+	}
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.WorkflowService.ExecuteWorkflow(ctx, req)
+	if err != nil {
+		slot.Cancel()
+		return nil, err
+	}
+	if workflows.IsTerminalStatus(resp.Status) {
+		slot.Release()
+		return resp, nil
+	}
+
+	s.mu.Lock()
+	s.running[resp.ExecutionID] = slot
+	s.mu.Unlock()
+	return resp, nil
+}
+
+// GetExecutionStatus reports an execution's status, freeing its slot once
+// it has finished
+func (s *Service) GetExecutionStatus(ctx context.Context, executionID string) (*workflows.ExecutionResponse, error) {
+	resp, err := s.WorkflowService.GetExecutionStatus(ctx, executionID)
+	s.observe(executionID, resp, err)
+	return resp, err
+}
+
+// Close stops polling. Slots still held stay held.
+func (s *Service) Close() {
+	select {
+	case <-s.stop:
+	default:
+		close(s.stop)
+	}
+	<-s.done
+}
+
+// poll checks running executions until the service is closed
+func (s *Service) poll(interval time.Duration) {
+	defer close(s.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+
+		s.mu.Lock()
+		ids := make([]string, 0, len(s.running))
+		for id := range s.running {
+			ids = append(ids, id)
+		}
+		s.mu.Unlock()
+
+		for _, id := range ids {
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			resp, err := s.WorkflowService.GetExecutionStatus(ctx, id)
+			cancel()
+			s.observe(id, resp, err)
+			if err != nil && !errors.Is(err, workflows.ErrExecutionNotFound) && s.onError != nil {
+				s.onError(err)
+			}
+		}
+	}
+}
+
+// observe frees an execution's slot when a status lookup shows it has
+// finished or no longer exists
+func (s *Service) observe(executionID string, resp *workflows.ExecutionResponse, err error) {
+	finished := errors.Is(err, workflows.ErrExecutionNotFound) || (err == nil && resp != nil && workflows.IsTerminalStatus(resp.Status))
+	if !finished {
+		return
+	}
+	s.mu.Lock()
+	slot, ok := s.running[executionID]
+	delete(s.running, executionID)
+	s.mu.Unlock()
+	if ok {
+		slot.Release()
+	}
+}
@@ -0,0 +1,598 @@
+// Package reprocess re-runs a provider's workflows over many blobs, such as
+// after a workflow is fixed or changed. Jobs work through their blobs with
+// bounded concurrency and checkpoint their progress in a Store, so a job
+// interrupted by a restart or cancelled part way can be resumed without
+// starting over.
+package reprocess
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/memmieai/memmie-studio/internal/blob"
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// Job statuses
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusPaused    = "paused"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+// Job limits
+const (
+	// DefaultConcurrency is how many blobs a job processes at once unless
+	// the request says otherwise
+	DefaultConcurrency = 5
+
+	// MaxConcurrency bounds a request's concurrency
+	MaxConcurrency = 50
+
+	// MaxBlobs bounds the blobs in one job
+	MaxBlobs = 10000
+
+	// MaxRatePerMinute bounds a request's rate
+	MaxRatePerMinute = 6000
+
+	// defaultEvent is the event blobs are reprocessed for
+	defaultEvent = "onUpdate"
+
+	// queryPageSize is how many blobs are listed at a time for a query
+	queryPageSize = 100
+
+	// checkpointInterval is how often a running job saves its progress
+	checkpointInterval = time.Second
+
+	// retention is how long finished jobs stay queryable
+	retention = 7 * 24 * time.Hour
+)
+
+// Job errors
+var (
+	ErrJobNotFound    = errors.New("reprocess job not found")
+	ErrJobRunning     = errors.New("reprocess job is running")
+	ErrJobFinished    = errors.New("reprocess job already finished")
+	ErrInvalidRequest = errors.New("invalid reprocess request")
+)
+
+// Query selects the blobs to reprocess by the blob listing filters
+type Query struct {
+	ProviderID  string `json:"provider_id,omitempty"`
+	NamespaceID string `json:"namespace_id,omitempty"`
+	ParentID    string `json:"parent_id,omitempty"`
+}
+
+// Request describes a reprocess job: either blob IDs or a query, the event
+// to run the workflows for, how many blobs to process at once and,
+// optionally, how many to start a minute
+type Request struct {
+	BlobIDs       []string `json:"blob_ids,omitempty"`
+	Query         *Query   `json:"query,omitempty"`
+	EventType     string   `json:"event_type,omitempty"`
+	Concurrency   int      `json:"concurrency,omitempty"`
+	RatePerMinute int      `json:"rate_per_minute,omitempty"`
+}
+
+// Job is a reprocess run's progress. Failed maps each blob that failed to
+// its error; Executions counts the executions started.
+type Job struct {
+	ID            string            `json:"id"`
+	UserID        string            `json:"user_id"`
+	ProviderID    string            `json:"provider_id"`
+	EventType     string            `json:"event_type"`
+	Concurrency   int               `json:"concurrency"`
+	RatePerMinute int               `json:"rate_per_minute,omitempty"`
+	Status        string            `json:"status"`
+	Total         int               `json:"total"`
+	Processed     int               `json:"processed"`
+	Succeeded     int               `json:"succeeded"`
+	Failed        map[string]string `json:"failed,omitempty"`
+	Executions    int               `json:"executions"`
+	Error         string            `json:"error,omitempty"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+	CompletedAt   *time.Time        `json:"completed_at,omitempty"`
+
+	// pending are the blobs not yet processed, in order
+	pending []string
+}
+
+// snapshot copies a job for callers outside the service
+func (j *Job) snapshot() *Job {
+	copied := *j
+	copied.pending = nil
+	if j.Failed != nil {
+		copied.Failed = make(map[string]string, len(j.Failed))
+		for blobID, message := range j.Failed {
+			copied.Failed[blobID] = message
+		}
+	}
+	return &copied
+}
+
+// finished reports whether a job has stopped for good or until resumed
+func (j *Job) finished() bool {
+	return j.Status != StatusQueued && j.Status != StatusRunning && j.Status != StatusPaused
+}
+
+// active reports whether a job should be running
+func (j *Job) active() bool {
+	return j.Status == StatusQueued || j.Status == StatusRunning
+}
+
+// Processor runs one provider's workflows on a blob, as the orchestrator
+// does
+type Processor interface {
+	GetProvider(providerID string) (*workflows.Provider, error)
+	ProcessBlobWithProvider(ctx context.Context, providerID, blobID, userID, eventType string) ([]string, error)
+}
+
+// Service runs reprocess jobs in the background. It assumes it is the only
+// service running the store's jobs.
+type Service struct {
+	store     Store
+	processor Processor
+	blobs     blob.Store
+	onError   func(error)
+
+	mu      sync.Mutex
+	jobs    map[string]*Job
+	cancels map[string]context.CancelFunc
+	ctx     context.Context
+	stop    context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewService creates a reprocess service and resumes the jobs in the store
+// that were queued or running when it last stopped; paused jobs stay
+// paused. Queries are resolved
+// against blobs. onError, if set, is told when the store cannot be read or
+// written; blobs that fail are recorded on their jobs. Close stops it.
+func NewService(store Store, processor Processor, blobs blob.Store, onError func(error)) *Service {
+	ctx, stop := context.WithCancel(context.Background())
+	s := &Service{
+		store:     store,
+		processor: processor,
+		blobs:     blobs,
+		onError:   onError,
+		jobs:      make(map[string]*Job),
+		cancels:   make(map[string]context.CancelFunc),
+		ctx:       ctx,
+		stop:      stop,
+	}
+
+	jobs, err := store.List(ctx)
+	if err != nil {
+		s.report(err)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.Before(jobs[j].CreatedAt) })
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, job := range jobs {
+		s.jobs[job.ID] = job
+		if job.active() {
+			s.startLocked(job)
+		}
+	}
+	return s
+}
+
+// Start creates a job reprocessing a user's blobs with a provider and
+// starts running it
+func (s *Service) Start(ctx context.Context, userID, providerID string, req Request) (*Job, error) {
+	provider, err := s.processor.GetProvider(providerID)
+	if err != nil {
+		return nil, err
+	}
+	if !provider.Active {
+		return nil, fmt.Errorf("%w: %s", workflows.ErrProviderInactive, providerID)
+	}
+	if req.EventType == "" {
+		req.EventType = defaultEvent
+	}
+	if !workflows.IsTriggerEvent(req.EventType) {
+		return nil, fmt.Errorf("%w: unknown event_type %q", ErrInvalidRequest, req.EventType)
+	}
+	switch {
+	case req.Concurrency == 0:
+		req.Concurrency = DefaultConcurrency
+	case req.Concurrency < 0 || req.Concurrency > MaxConcurrency:
+		return nil, fmt.Errorf("%w: concurrency must be between 1 and %d", ErrInvalidRequest, MaxConcurrency)
+	}
+	if req.RatePerMinute < 0 || req.RatePerMinute > MaxRatePerMinute {
+		return nil, fmt.Errorf("%w: rate_per_minute must be between 0 and %d", ErrInvalidRequest, MaxRatePerMinute)
+	}
+	blobIDs, err := s.resolve(ctx, userID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	job := &Job{
+		ID:            uuid.New().String(),
+		UserID:        userID,
+		ProviderID:    providerID,
+		EventType:     req.EventType,
+		Concurrency:   req.Concurrency,
+		RatePerMinute: req.RatePerMinute,
+		Status:        StatusQueued,
+		Total:         len(blobIDs),
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		pending:       blobIDs,
+	}
+	if err := s.store.Save(ctx, job); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune(now)
+	s.jobs[job.ID] = job
+	s.startLocked(job)
+	return job.snapshot(), nil
+}
+
+// resolve returns the distinct blob IDs a request names or its query
+// matches
+func (s *Service) resolve(ctx context.Context, userID string, req Request) ([]string, error) {
+	if len(req.BlobIDs) > 0 && req.Query != nil {
+		return nil, fmt.Errorf("%w: give blob_ids or query, not both", ErrInvalidRequest)
+	}
+	seen := make(map[string]bool)
+	var blobIDs []string
+	add := func(blobID string) error {
+		if blobID == "" || seen[blobID] {
+			return nil
+		}
+		if len(blobIDs) == MaxBlobs {
+			return fmt.Errorf("%w: a job can reprocess at most %d blobs", ErrInvalidRequest, MaxBlobs)
+		}
+		seen[blobID] = true
+		blobIDs = append(blobIDs, blobID)
+		return nil
+	}
+
+	switch {
+	case len(req.BlobIDs) > 0:
+		for _, blobID := range req.BlobIDs {
+			if err := add(blobID); err != nil {
+				return nil, err
+			}
+		}
+	case req.Query != nil:
+		if s.blobs == nil {
+			return nil, fmt.Errorf("%w: blob queries are not configured", ErrInvalidRequest)
+		}
+		filter := blob.Filter{
+			ProviderID:  req.Query.ProviderID,
+			NamespaceID: req.Query.NamespaceID,
+			ParentID:    req.Query.ParentID,
+			Limit:       queryPageSize,
+		}
+		for {
+			page, err := s.blobs.ListBlobs(ctx, userID, filter)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list blobs: %w", err)
+			}
+			before := len(blobIDs)
+			for _, b := range page {
+				if err := add(b.ID); err != nil {
+					return nil, err
+				}
+			}
+			// Stop on a short page, or one with nothing new in case the
+			// store ignores the offset
+			if len(page) < queryPageSize || len(blobIDs) == before {
+				break
+			}
+			filter.Offset += len(page)
+		}
+	}
+	if len(blobIDs) == 0 {
+		return nil, fmt.Errorf("%w: no blobs to reprocess", ErrInvalidRequest)
+	}
+	return blobIDs, nil
+}
+
+// Get returns one of a user's jobs
+func (s *Service) Get(userID, id string) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok || job.UserID != userID {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	return job.snapshot(), nil
+}
+
+// List returns a user's jobs, optionally for one provider, newest first
+func (s *Service) List(userID, providerID string) []*Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs := []*Job{}
+	for _, job := range s.jobs {
+		if job.UserID == userID && (providerID == "" || job.ProviderID == providerID) {
+			jobs = append(jobs, job.snapshot())
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.After(jobs[j].CreatedAt) })
+	return jobs
+}
+
+// Cancel stops a queued, running or paused job. Blobs already being
+// processed finish; the rest stay pending for Resume.
+func (s *Service) Cancel(ctx context.Context, userID, id string) (*Job, error) {
+	return s.stopJob(ctx, userID, id, StatusCancelled)
+}
+
+// Pause stops a queued or running job like Cancel, but leaves it unfinished:
+// it is kept past the retention of finished jobs and is not resumed when the
+// service restarts, only by Resume. Pausing a paused job returns it as is.
+func (s *Service) Pause(ctx context.Context, userID, id string) (*Job, error) {
+	return s.stopJob(ctx, userID, id, StatusPaused)
+}
+
+// stopJob moves an unfinished job to a stopped status and stops its run
+func (s *Service) stopJob(ctx context.Context, userID, id, status string) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok || job.UserID != userID {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	if job.finished() {
+		return nil, fmt.Errorf("%w: %s is %s", ErrJobFinished, id, job.Status)
+	}
+	if job.Status == status {
+		return job.snapshot(), nil
+	}
+	now := time.Now().UTC()
+	job.Status = status
+	job.UpdatedAt = now
+	cancel, running := s.cancels[id]
+	if running {
+		cancel()
+	} else if status == StatusCancelled {
+		// A paused job has no run left to record its completion
+		job.CompletedAt = &now
+	}
+	if err := s.store.Save(ctx, job); err != nil {
+		return nil, err
+	}
+	return job.snapshot(), nil
+}
+
+// Resume restarts a paused, cancelled, failed or completed job on the blobs
+// it did not get to and the blobs that failed
+func (s *Service) Resume(ctx context.Context, userID, id string) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok || job.UserID != userID {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	if job.active() {
+		return nil, fmt.Errorf("%w: %s is %s", ErrJobRunning, id, job.Status)
+	}
+	// A stopped job may still be finishing its last blobs
+	if _, running := s.cancels[id]; running {
+		return nil, fmt.Errorf("%w: %s is still stopping", ErrJobRunning, id)
+	}
+	retry := make([]string, 0, len(job.Failed))
+	for blobID := range job.Failed {
+		retry = append(retry, blobID)
+	}
+	sort.Strings(retry)
+	if len(job.pending)+len(retry) == 0 {
+		return nil, fmt.Errorf("%w: %s has no blobs left to reprocess", ErrJobFinished, id)
+	}
+
+	job.pending = append(job.pending, retry...)
+	job.Processed -= len(retry)
+	job.Failed = nil
+	job.Error = ""
+	job.Status = StatusQueued
+	job.CompletedAt = nil
+	job.UpdatedAt = time.Now().UTC()
+	if err := s.store.Save(ctx, job); err != nil {
+		return nil, err
+	}
+	s.startLocked(job)
+	return job.snapshot(), nil
+}
+
+// Close stops running jobs, leaving them to resume when a service is next
+// created on the store
+func (s *Service) Close() {
+	s.stop()
+	s.wg.Wait()
+}
+
+// startLocked runs a job in the background. s.mu must be held.
+func (s *Service) startLocked(job *Job) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	s.cancels[job.ID] = cancel
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.run(ctx, job)
+		cancel()
+		s.mu.Lock()
+		delete(s.cancels, job.ID)
+		s.mu.Unlock()
+	}()
+}
+
+// run processes a job's pending blobs until none are left or the job is
+// stopped, checkpointing its progress as it goes. Progress is saved at most
+// every checkpointInterval, so a crash may process a few blobs again.
+func (s *Service) run(ctx context.Context, job *Job) {
+	s.mu.Lock()
+	if !job.active() {
+		s.mu.Unlock()
+		return
+	}
+	job.Status = StatusRunning
+	job.UpdatedAt = time.Now().UTC()
+	blobIDs := append([]string(nil), job.pending...)
+	remaining := make(map[string]bool, len(blobIDs))
+	for _, blobID := range blobIDs {
+		remaining[blobID] = true
+	}
+	s.checkpointLocked(job, blobIDs, remaining)
+	s.mu.Unlock()
+	lastSaved := time.Now()
+
+	// Reprocessing is batch work, so interactive executions go first
+	ctx = workflows.WithLane(ctx, workflows.LaneBatch)
+	queue := make(chan string)
+	var workers sync.WaitGroup
+	var abort error
+	concurrency := job.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+	for i := 0; i < concurrency; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for blobID := range queue {
+				executions, err := s.processor.ProcessBlobWithProvider(ctx, job.ProviderID, blobID, job.UserID, job.EventType)
+
+				s.mu.Lock()
+				switch {
+				case err != nil && ctx.Err() != nil:
+					// Interrupted, so the blob stays pending
+				case errors.Is(err, workflows.ErrProviderNotFound), errors.Is(err, workflows.ErrProviderInactive):
+					// Every other blob would fail the same way
+					if abort == nil {
+						abort = err
+					}
+				default:
+					delete(remaining, blobID)
+					job.Processed++
+					job.Executions += len(executions)
+					if err != nil {
+						if job.Failed == nil {
+							job.Failed = make(map[string]string)
+						}
+						job.Failed[blobID] = err.Error()
+					} else {
+						job.Succeeded++
+					}
+				}
+				job.UpdatedAt = time.Now().UTC()
+				if time.Since(lastSaved) >= checkpointInterval {
+					s.checkpointLocked(job, blobIDs, remaining)
+					lastSaved = time.Now()
+				}
+				s.mu.Unlock()
+			}
+		}()
+	}
+
+	// A rate spaces the blobs out evenly over each minute
+	var tick <-chan time.Time
+	if job.RatePerMinute > 0 {
+		ticker := time.NewTicker(time.Minute / time.Duration(job.RatePerMinute))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+feed:
+	for i, blobID := range blobIDs {
+		s.mu.Lock()
+		stopped := abort != nil
+		s.mu.Unlock()
+		if stopped {
+			break
+		}
+		if tick != nil && i > 0 {
+			select {
+			case <-tick:
+			case <-ctx.Done():
+				break feed
+			}
+		}
+		select {
+		case queue <- blobID:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(queue)
+	workers.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now().UTC()
+	switch {
+	case abort != nil:
+		job.Status = StatusFailed
+		job.Error = abort.Error()
+	case len(remaining) == 0:
+		job.Status = StatusCompleted
+	case job.Status == StatusCancelled:
+	case job.Status == StatusPaused:
+		job.UpdatedAt = now
+		s.checkpointLocked(job, blobIDs, remaining)
+		return
+	default:
+		// The service is closing; the job resumes when it is next created
+		s.checkpointLocked(job, blobIDs, remaining)
+		return
+	}
+	job.UpdatedAt = now
+	job.CompletedAt = &now
+	s.checkpointLocked(job, blobIDs, remaining)
+}
+
+// checkpointLocked records a job's remaining blobs and saves it. s.mu must
+// be held.
+func (s *Service) checkpointLocked(job *Job, blobIDs []string, remaining map[string]bool) {
+	pending := make([]string, 0, len(remaining))
+	for _, blobID := range blobIDs {
+		if remaining[blobID] {
+			pending = append(pending, blobID)
+		}
+	}
+	job.pending = pending
+	if err := s.store.Save(context.Background(), job); err != nil {
+		s.report(err)
+	}
+}
+
+// prune forgets finished jobs past their retention. s.mu must be held.
+func (s *Service) prune(now time.Time) {
+	for id, job := range s.jobs {
+		if job.CompletedAt == nil || !job.CompletedAt.Before(now.Add(-retention)) {
+			continue
+		}
+		if _, running := s.cancels[id]; running {
+			continue
+		}
+		if err := s.store.Delete(context.Background(), id); err != nil {
+			s.report(err)
+			continue
+		}
+		delete(s.jobs, id)
+	}
+}
+
+// report passes an error to onError, if set
+func (s *Service) report(err error) {
+	if s.onError != nil && !errors.Is(err, context.Canceled) {
+		s.onError(err)
+	}
+}
@@ -0,0 +1,97 @@
+package reprocess
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Store persists job records, including the blobs each job has left, so
+// jobs can resume after a restart
+type Store interface {
+	Save(ctx context.Context, job *Job) error
+	List(ctx context.Context) ([]*Job, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// record is a job as stored, with the blobs it has left
+type record struct {
+	*Job
+	Pending []string `json:"pending"`
+}
+
+// FileStore keeps each job in a JSON file under a directory
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates a store writing under dir
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir}
+}
+
+// Save writes a job, replacing its file atomically
+func (s *FileStore) Save(ctx context.Context, job *Job) error {
+	if job.ID == "" || strings.ContainsAny(job.ID, `/\`) || strings.HasPrefix(job.ID, ".") {
+		return fmt.Errorf("invalid job id %q", job.ID)
+	}
+	data, err := json.Marshal(record{Job: job, Pending: job.pending})
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create job directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(s.dir, ".job-*")
+	if err != nil {
+		return fmt.Errorf("failed to write job %s: %w", job.ID, err)
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(s.dir, job.ID+".json"))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write job %s: %w", job.ID, err)
+	}
+	return nil
+}
+
+// List reads every job
+func (s *FileStore) List(ctx context.Context) ([]*Job, error) {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+	jobs := make([]*Job, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read job: %w", err)
+		}
+		var rec record
+		if err := json.Unmarshal(data, &rec); err != nil || rec.Job == nil {
+			return nil, fmt.Errorf("failed to parse job %s: %v", path, err)
+		}
+		rec.Job.pending = rec.Pending
+		jobs = append(jobs, rec.Job)
+	}
+	return jobs, nil
+}
+
+// Delete removes a job
+func (s *FileStore) Delete(ctx context.Context, id string) error {
+	if err := os.Remove(filepath.Join(s.dir, id+".json")); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete job %s: %w", id, err)
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package reviews
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/memmieai/memmie-studio/internal/blob"
+)
+
+// quoteContext is the number of characters kept on each side of a quote to
+// tell apart repeated occurrences when relocating it
+const quoteContext = 32
+
+// Anchor is the part of a blob a thread is about. Path is a field of the
+// blob, "content" by default; for JSON content it may continue into the
+// document, as in "content.chapters.2.title", and metadata fields are
+// addressed as "metadata.<key>". With a range, the thread is about the
+// characters [Start, End) of the text at Path; without one, about the
+// whole value.
+type Anchor struct {
+	Path     string `json:"path"`
+	Start    *int   `json:"start,omitempty"`
+	End      *int   `json:"end,omitempty"`
+	Quote    string `json:"quote,omitempty"`  // the anchored text
+	Prefix   string `json:"prefix,omitempty"` // text just before the quote
+	Suffix   string `json:"suffix,omitempty"` // text just after the quote
+	Sequence int64  `json:"sequence"`         // delta sequence the anchor is current as of
+	Orphaned bool   `json:"orphaned,omitempty"`
+}
+
+// HasRange reports whether the anchor covers a character range
+func (a *Anchor) HasRange() bool {
+	return a.Start != nil && a.End != nil
+}
+
+// NormalizePath converts "$.content[2].title", "/metadata/title" and
+// "content.2.title" forms to dotted form, defaulting to content
+func NormalizePath(path string) string {
+	path = strings.TrimSpace(path)
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	if strings.HasPrefix(path, "/") {
+		path = strings.ReplaceAll(strings.TrimPrefix(path, "/"), "/", ".")
+	}
+	path = strings.NewReplacer("[", ".", "]", "").Replace(path)
+	if path == "" {
+		return "content"
+	}
+	return path
+}
+
+// resolve finds the value at a path in a blob
+func resolve(b *blob.Blob, path string) (interface{}, bool) {
+	segments := strings.Split(path, ".")
+	switch segments[0] {
+	case "content":
+		if len(segments) == 1 {
+			return b.Content, true
+		}
+		var doc interface{}
+		if err := json.Unmarshal([]byte(b.Content), &doc); err != nil {
+			return nil, false
+		}
+		return walk(doc, segments[1:])
+	case "metadata":
+		return walk(map[string]interface{}(b.Metadata), segments[1:])
+	}
+	return nil, false
+}
+
+// resolveState finds the value at a path in a version replayed from the
+// delta log, whose state is keyed by delta path. A path inside a field is
+// looked up in the deepest field the log recorded.
+func resolveState(state map[string]interface{}, path string) (interface{}, bool) {
+	segments := strings.Split(path, ".")
+	for i := len(segments); i > 0; i-- {
+		value, ok := state[strings.Join(segments[:i], ".")]
+		if !ok {
+			continue
+		}
+		if i == len(segments) {
+			return value, true
+		}
+		if text, ok := value.(string); ok && segments[0] == "content" {
+			var doc interface{}
+			if err := json.Unmarshal([]byte(text), &doc); err != nil {
+				return nil, false
+			}
+			value = doc
+		}
+		return walk(value, segments[i:])
+	}
+	return nil, false
+}
+
+// walk follows path segments through decoded JSON
+func walk(value interface{}, segments []string) (interface{}, bool) {
+	for _, segment := range segments {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			next, ok := v[segment]
+			if !ok {
+				return nil, false
+			}
+			value = next
+		case []interface{}:
+			i, err := strconv.Atoi(segment)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			value = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// textOf returns the text a range anchor addresses
+func textOf(value interface{}, ok bool) (string, bool) {
+	if !ok {
+		return "", false
+	}
+	text, isText := value.(string)
+	return text, isText
+}
+
+// place sets an anchor's range, quote and context against text. When no
+// range is given but a quote is, the quote's first occurrence is used.
+func (a *Anchor) place(text string) error {
+	runes := []rune(text)
+	if !a.HasRange() {
+		if a.Quote == "" {
+			return nil
+		}
+		i := strings.Index(text, a.Quote)
+		if i < 0 {
+			return fmt.Errorf("%w: quote not found at %s", ErrInvalidAnchor, a.Path)
+		}
+		start := utf8.RuneCountInString(text[:i])
+		end := start + utf8.RuneCountInString(a.Quote)
+		a.Start, a.End = &start, &end
+	}
+	if *a.Start < 0 || *a.End < *a.Start || *a.End > len(runes) {
+		return fmt.Errorf("%w: range [%d, %d) is outside the %d characters at %s", ErrInvalidAnchor, *a.Start, *a.End, len(runes), a.Path)
+	}
+	a.setQuote(runes)
+	return nil
+}
+
+// setQuote records the anchored text and its surroundings
+func (a *Anchor) setQuote(runes []rune) {
+	start, end := *a.Start, *a.End
+	a.Quote = string(runes[start:end])
+	a.Prefix = string(runes[max(0, start-quoteContext):start])
+	a.Suffix = string(runes[end:min(len(runes), end+quoteContext)])
+}
+
+// matches reports whether the anchor's range still holds its quote
+func (a *Anchor) matches(runes []rune) bool {
+	return *a.End <= len(runes) && string(runes[*a.Start:*a.End]) == a.Quote
+}
+
+// relocate finds the anchor's quote in text after an unrecorded change,
+// preferring the occurrence whose surroundings match and then the one
+// closest to the old position
+func (a *Anchor) relocate(runes []rune) bool {
+	quote, prefix, suffix := []rune(a.Quote), []rune(a.Prefix), []rune(a.Suffix)
+	if len(quote) == 0 {
+		return false
+	}
+	best, bestScore := -1, -1
+	for i := 0; i+len(quote) <= len(runes); i++ {
+		if !equalRunes(runes[i:i+len(quote)], quote) {
+			continue
+		}
+		score := 0
+		if i >= len(prefix) && equalRunes(runes[i-len(prefix):i], prefix) {
+			score++
+		}
+		if end := i + len(quote); end+len(suffix) <= len(runes) && equalRunes(runes[end:end+len(suffix)], suffix) {
+			score++
+		}
+		if best == -1 || score > bestScore || (score == bestScore && absInt(i-*a.Start) < absInt(best-*a.Start)) {
+			best, bestScore = i, score
+		}
+	}
+	if best == -1 {
+		return false
+	}
+	start, end := best, best+len(quote)
+	a.Start, a.End = &start, &end
+	a.setQuote(runes)
+	return true
+}
+
+// equalRunes reports whether two rune slices are the same
+func equalRunes(a, b []rune) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// absInt returns the absolute value of an int
+func absInt(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
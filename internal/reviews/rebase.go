@@ -0,0 +1,136 @@
+package reviews
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/memmieai/memmie-studio/internal/revisions"
+)
+
+// maxCharDiff bounds the characters in a changed run of lines that is
+// diffed character by character. Larger runs are taken as replaced whole,
+// which orphans anchors inside them rather than risk a slow diff.
+const maxCharDiff = 4000
+
+// edit is a run of characters kept, deleted or inserted
+type edit struct {
+	op string
+	n  int
+}
+
+// edits computes the character edits that turn old into updated. The texts
+// are diffed by line first, and each run of changed lines by character.
+func edits(old, updated string) []edit {
+	var out []edit
+	var deleted, inserted []string
+	flush := func() {
+		if len(deleted) == 0 && len(inserted) == 0 {
+			return
+		}
+		a, b := []rune(strings.Join(deleted, "")), []rune(strings.Join(inserted, ""))
+		if len(a)+len(b) <= maxCharDiff {
+			for _, line := range revisions.DiffLines(runeStrings(a), runeStrings(b)) {
+				out = appendEdit(out, line.Op, 1)
+			}
+		} else {
+			out = appendEdit(out, revisions.OpDelete, len(a))
+			out = appendEdit(out, revisions.OpInsert, len(b))
+		}
+		deleted, inserted = nil, nil
+	}
+
+	for _, line := range revisions.DiffLines(splitLines(old), splitLines(updated)) {
+		switch line.Op {
+		case revisions.OpEqual:
+			flush()
+			out = appendEdit(out, revisions.OpEqual, utf8.RuneCountInString(line.Text))
+		case revisions.OpDelete:
+			deleted = append(deleted, line.Text)
+		case revisions.OpInsert:
+			inserted = append(inserted, line.Text)
+		}
+	}
+	flush()
+	return out
+}
+
+// appendEdit adds n characters to the edit list, extending the last run
+// when it has the same operation
+func appendEdit(edits []edit, op string, n int) []edit {
+	if n == 0 {
+		return edits
+	}
+	if last := len(edits) - 1; last >= 0 && edits[last].op == op {
+		edits[last].n += n
+		return edits
+	}
+	return append(edits, edit{op: op, n: n})
+}
+
+// mapPosition maps a position between characters of the old text to the
+// new text. Text inserted exactly at the position goes before it when
+// after is set and after it otherwise; a position inside deleted text maps
+// to where the deletion was.
+func mapPosition(edits []edit, position int, after bool) int {
+	oldPos, newPos := 0, 0
+	for _, e := range edits {
+		if e.op == revisions.OpInsert {
+			if oldPos == position && !after {
+				return newPos
+			}
+			newPos += e.n
+			continue
+		}
+		if oldPos == position {
+			return newPos
+		}
+		if position < oldPos+e.n {
+			if e.op == revisions.OpEqual {
+				return newPos + position - oldPos
+			}
+			return newPos
+		}
+		oldPos += e.n
+		if e.op == revisions.OpEqual {
+			newPos += e.n
+		}
+	}
+	return newPos
+}
+
+// rebase moves a range anchor from old text to updated text through the
+// edits between them. An anchor whose text was deleted entirely is orphaned and
+// keeps its last quote.
+func (a *Anchor) rebase(old, updated string) {
+	list := edits(old, updated)
+	start := mapPosition(list, *a.Start, true)
+	end := mapPosition(list, *a.End, false)
+	if end < start {
+		end = start
+	}
+	if start == end && a.Quote != "" {
+		a.Orphaned = true
+		return
+	}
+	a.Start, a.End = &start, &end
+	a.setQuote([]rune(updated))
+}
+
+// splitLines splits text after each newline, so the lines add up to the
+// text
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// runeStrings splits runes into one-character strings for diffing
+func runeStrings(runes []rune) []string {
+	out := make([]string, len(runes))
+	for i, r := range runes {
+		out[i] = string(r)
+	}
+	return out
+}
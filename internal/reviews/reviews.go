@@ -0,0 +1,390 @@
+// Package reviews keeps comment threads anchored to parts of a blob. An
+// anchor is a character range or JSON path, and range anchors follow their
+// text as later changes move it.
+package reviews
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/memmieai/memmie-studio/internal/blob"
+	"github.com/memmieai/memmie-studio/internal/revisions"
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// ProviderID owns review thread blobs
+const ProviderID = "reviews"
+
+// KindThread marks review thread blobs
+const KindThread = "review_thread"
+
+// Thread statuses
+const (
+	StatusOpen     = "open"
+	StatusResolved = "resolved"
+)
+
+// Author types
+const (
+	AuthorUser     = "user"
+	AuthorProvider = "provider"
+)
+
+var (
+	// ErrThreadNotFound is returned when a thread does not exist on a blob
+	ErrThreadNotFound = errors.New("review thread not found")
+
+	// ErrInvalidAnchor is returned for anchors that do not address part of
+	// the blob
+	ErrInvalidAnchor = errors.New("invalid anchor")
+
+	// ErrInvalidComment is returned for empty comments
+	ErrInvalidComment = errors.New("invalid comment")
+)
+
+// Author identifies who wrote a comment: a user, or a provider commenting
+// from a workflow step
+type Author struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+}
+
+// Comment is a message in a thread
+type Comment struct {
+	ID        string    `json:"id"`
+	Author    Author    `json:"author"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Thread is a discussion about part of a blob
+type Thread struct {
+	ID         string     `json:"id"`
+	BlobID     string     `json:"blob_id"`
+	Anchor     Anchor     `json:"anchor"`
+	Status     string     `json:"status"`
+	Comments   []Comment  `json:"comments"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	ResolvedBy *Author    `json:"resolved_by,omitempty"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+}
+
+// Service stores threads as child blobs of the blob they review
+type Service struct {
+	blobs   blob.Store
+	history revisions.History
+}
+
+// NewService creates a review service. Without a delta history, anchors are
+// moved by finding their quoted text again.
+func NewService(blobs blob.Store, history revisions.History) *Service {
+	return &Service{blobs: blobs, history: history}
+}
+
+// Create starts a thread on a blob with its first comment
+func (s *Service) Create(ctx context.Context, userID, blobID string, anchor Anchor, body string, author Author) (*Thread, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, fmt.Errorf("%w: body is required", ErrInvalidComment)
+	}
+	b, latest, _, err := s.current(ctx, userID, blobID)
+	if err != nil {
+		return nil, err
+	}
+
+	anchor.Path = NormalizePath(anchor.Path)
+	anchor.Sequence, anchor.Orphaned = latest, false
+	value, ok := resolve(b, anchor.Path)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s does not exist", ErrInvalidAnchor, anchor.Path)
+	}
+	if anchor.HasRange() || anchor.Quote != "" {
+		text, ok := textOf(value, true)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s is not text", ErrInvalidAnchor, anchor.Path)
+		}
+		if err := anchor.place(text); err != nil {
+			return nil, err
+		}
+	} else if anchor.Start != nil || anchor.End != nil {
+		return nil, fmt.Errorf("%w: start and end must be given together", ErrInvalidAnchor)
+	}
+
+	now := time.Now().UTC()
+	t := &Thread{
+		BlobID:    blobID,
+		Anchor:    anchor,
+		Status:    StatusOpen,
+		Comments:  []Comment{{ID: "1", Author: author, Body: body, CreatedAt: now}},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	content, err := json.Marshal(t)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode thread: %w", err)
+	}
+	parentID := blobID
+	created, err := s.blobs.CreateBlob(ctx, &blob.Blob{
+		UserID:     userID,
+		ProviderID: ProviderID,
+		Content:    string(content),
+		ParentID:   &parentID,
+		Metadata:   t.metadata(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store thread: %w", err)
+	}
+	t.ID = created.ID
+	return t, nil
+}
+
+// List returns a blob's threads, oldest first, with their anchors moved to
+// the blob's current content. An empty status lists every thread.
+func (s *Service) List(ctx context.Context, userID, blobID, status string) ([]*Thread, error) {
+	b, latest, deltas, err := s.current(ctx, userID, blobID)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := s.threads(ctx, userID, blobID)
+	if err != nil {
+		return nil, err
+	}
+	threads := []*Thread{}
+	for _, item := range stored {
+		if refresh(item.thread, b, deltas, latest) {
+			if err := s.save(ctx, item.blob, item.thread); err != nil {
+				return nil, err
+			}
+		}
+		if status == "" || item.thread.Status == status {
+			threads = append(threads, item.thread)
+		}
+	}
+	return threads, nil
+}
+
+// Get returns one of a blob's threads with its anchor moved to the blob's
+// current content
+func (s *Service) Get(ctx context.Context, userID, blobID, threadID string) (*Thread, error) {
+	return s.update(ctx, userID, blobID, threadID, func(*Thread) bool { return false })
+}
+
+// Reply adds a comment to a thread. Replying does not reopen a resolved
+// thread.
+func (s *Service) Reply(ctx context.Context, userID, blobID, threadID, body string, author Author) (*Thread, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, fmt.Errorf("%w: body is required", ErrInvalidComment)
+	}
+	return s.update(ctx, userID, blobID, threadID, func(t *Thread) bool {
+		now := time.Now().UTC()
+		t.Comments = append(t.Comments, Comment{
+			ID:        strconv.Itoa(len(t.Comments) + 1),
+			Author:    author,
+			Body:      body,
+			CreatedAt: now,
+		})
+		t.UpdatedAt = now
+		return true
+	})
+}
+
+// Resolve marks a thread resolved. Resolving a resolved thread changes
+// nothing.
+func (s *Service) Resolve(ctx context.Context, userID, blobID, threadID string, author Author) (*Thread, error) {
+	return s.update(ctx, userID, blobID, threadID, func(t *Thread) bool {
+		if t.Status == StatusResolved {
+			return false
+		}
+		now := time.Now().UTC()
+		t.Status, t.ResolvedBy, t.ResolvedAt, t.UpdatedAt = StatusResolved, &author, &now, now
+		return true
+	})
+}
+
+// Reopen marks a resolved thread open again
+func (s *Service) Reopen(ctx context.Context, userID, blobID, threadID string) (*Thread, error) {
+	return s.update(ctx, userID, blobID, threadID, func(t *Thread) bool {
+		if t.Status == StatusOpen {
+			return false
+		}
+		t.Status, t.ResolvedBy, t.ResolvedAt, t.UpdatedAt = StatusOpen, nil, nil, time.Now().UTC()
+		return true
+	})
+}
+
+// update loads a thread, refreshes its anchor, applies change and saves the
+// thread when either altered it
+func (s *Service) update(ctx context.Context, userID, blobID, threadID string, change func(*Thread) bool) (*Thread, error) {
+	b, latest, deltas, err := s.current(ctx, userID, blobID)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := s.blobs.GetBlob(ctx, userID, threadID)
+	if errors.Is(err, blob.ErrNotFound) {
+		return nil, ErrThreadNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load thread: %w", err)
+	}
+	t, err := decodeThread(stored)
+	if err != nil || t.BlobID != blobID {
+		return nil, ErrThreadNotFound
+	}
+
+	refreshed := refresh(t, b, deltas, latest)
+	if changed := change(t); changed || refreshed {
+		if err := s.save(ctx, stored, t); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// current loads the reviewed blob and, when there is a delta history, its
+// log and latest sequence number
+func (s *Service) current(ctx context.Context, userID, blobID string) (*blob.Blob, int64, []workflows.Delta, error) {
+	b, err := s.blobs.GetBlob(ctx, userID, blobID)
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("failed to load blob %s: %w", blobID, err)
+	}
+	if s.history == nil {
+		return b, 0, nil, nil
+	}
+	deltas, err := s.history.GetByBlobID(ctx, blobID)
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("failed to load deltas: %w", err)
+	}
+	return b, revisions.Latest(deltas), deltas, nil
+}
+
+// storedThread pairs a thread with the blob it is stored in
+type storedThread struct {
+	blob   *blob.Blob
+	thread *Thread
+}
+
+// threads loads a blob's threads, oldest first
+func (s *Service) threads(ctx context.Context, userID, blobID string) ([]storedThread, error) {
+	blobs, err := s.blobs.ListBlobs(ctx, userID, blob.Filter{ProviderID: ProviderID, ParentID: blobID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list threads: %w", err)
+	}
+	var out []storedThread
+	for _, b := range blobs {
+		if b.Metadata["kind"] != KindThread {
+			continue
+		}
+		t, err := decodeThread(b)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, storedThread{blob: b, thread: t})
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].thread.CreatedAt.Before(out[j].thread.CreatedAt)
+	})
+	return out, nil
+}
+
+// save writes a thread back to its blob
+func (s *Service) save(ctx context.Context, b *blob.Blob, t *Thread) error {
+	content, err := json.Marshal(t)
+	if err != nil {
+		return fmt.Errorf("failed to encode thread: %w", err)
+	}
+	b.Content = string(content)
+	if b.Metadata == nil {
+		b.Metadata = make(map[string]interface{})
+	}
+	for k, v := range t.metadata() {
+		b.Metadata[k] = v
+	}
+	if _, err := s.blobs.UpdateBlob(ctx, b); err != nil {
+		return fmt.Errorf("failed to update thread %s: %w", b.ID, err)
+	}
+	return nil
+}
+
+// metadata summarizes a thread for its blob's metadata
+func (t *Thread) metadata() map[string]interface{} {
+	return map[string]interface{}{
+		"kind":     KindThread,
+		"blob_id":  t.BlobID,
+		"status":   t.Status,
+		"path":     t.Anchor.Path,
+		"comments": len(t.Comments),
+		"orphaned": t.Anchor.Orphaned,
+	}
+}
+
+// decodeThread reads a thread from its blob
+func decodeThread(b *blob.Blob) (*Thread, error) {
+	var t Thread
+	if err := json.Unmarshal([]byte(b.Content), &t); err != nil {
+		return nil, fmt.Errorf("failed to decode thread %s: %w", b.ID, err)
+	}
+	t.ID = b.ID
+	return &t, nil
+}
+
+// refresh moves a thread's anchor to the blob's current content and reports
+// whether it changed. Range anchors are rebased through the delta log when
+// the log accounts for the current text, and otherwise relocated by their
+// quote. Anchors whose text or path is gone are orphaned.
+func refresh(t *Thread, b *blob.Blob, deltas []workflows.Delta, latest int64) bool {
+	a := &t.Anchor
+	if a.Orphaned {
+		return false
+	}
+	before, _ := json.Marshal(a)
+
+	value, ok := resolve(b, a.Path)
+	switch {
+	case !a.HasRange():
+		a.Orphaned = !ok
+	default:
+		text, isText := textOf(value, ok)
+		runes := []rune(text)
+		switch {
+		case !isText:
+			a.Orphaned = true
+		case a.Sequence == latest && a.matches(runes):
+		case a.Sequence < latest && rebaseThroughLog(a, deltas, latest, text):
+		case a.matches(runes):
+		case !a.relocate(runes):
+			a.Orphaned = true
+		}
+	}
+	a.Sequence = latest
+
+	after, _ := json.Marshal(a)
+	if string(before) == string(after) {
+		return false
+	}
+	t.UpdatedAt = time.Now().UTC()
+	return true
+}
+
+// rebaseThroughLog rebases a range anchor from the text at its sequence to
+// the latest text, when the log's latest text is the blob's current text
+// and its earlier text still holds the anchor's quote
+func rebaseThroughLog(a *Anchor, deltas []workflows.Delta, latest int64, current string) bool {
+	updated, ok := textOf(resolveState(revisions.At(deltas, latest).State, a.Path))
+	if !ok || updated != current {
+		return false
+	}
+	old, ok := textOf(resolveState(revisions.At(deltas, a.Sequence).State, a.Path))
+	if !ok || !a.matches([]rune(old)) {
+		return false
+	}
+	a.rebase(old, updated)
+	return true
+}
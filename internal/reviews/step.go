@@ -0,0 +1,69 @@
+package reviews
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// StepType is the workflow step type providers comment with
+const StepType = "review_comment"
+
+// NewStepExecutor creates the executor that lets a workflow step comment on
+// the blob being processed, as the step's provider. Step inputs: body; then
+// thread_id to reply to a thread, or an anchor given by path (content by
+// default) and either start and end or a quote to find. Empty bodies are
+// skipped, so a step can map a check's optional finding straight in.
+func NewStepExecutor(service *Service) workflows.StepExecutor {
+	return workflows.StepExecutorFunc(func(ctx context.Context, req workflows.StepRequest) (map[string]interface{}, error) {
+		body, _ := req.Input["body"].(string)
+		if body == "" {
+			return map[string]interface{}{"skipped": true}, nil
+		}
+		author := Author{ID: req.Step.ProviderID, Type: AuthorProvider}
+		if author.ID == "" {
+			author.ID = req.Step.ID
+		}
+
+		var thread *Thread
+		var err error
+		if threadID, _ := req.Input["thread_id"].(string); threadID != "" {
+			thread, err = service.Reply(ctx, req.Context.UserID, req.Context.BlobID, threadID, body, author)
+		} else {
+			anchor := Anchor{}
+			anchor.Path, _ = req.Input["path"].(string)
+			anchor.Quote, _ = req.Input["quote"].(string)
+			start, hasStart := toInt(req.Input["start"])
+			end, hasEnd := toInt(req.Input["end"])
+			if hasStart != hasEnd {
+				return nil, fmt.Errorf("start and end must be given together")
+			}
+			if hasStart {
+				anchor.Start, anchor.End = &start, &end
+			}
+			thread, err = service.Create(ctx, req.Context.UserID, req.Context.BlobID, anchor, body, author)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"thread_id": thread.ID,
+			"status":    thread.Status,
+			"comments":  len(thread.Comments),
+		}, nil
+	})
+}
+
+// toInt reads a JSON number as an int
+func toInt(value interface{}) (int, bool) {
+	switch v := value.(type) {
+	case float64:
+		return int(v), true
+	case int:
+		return v, true
+	case int64:
+		return int(v), true
+	}
+	return 0, false
+}
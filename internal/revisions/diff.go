@@ -0,0 +1,212 @@
+package revisions
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Line operations
+const (
+	OpEqual  = "equal"
+	OpInsert = "insert"
+	OpDelete = "delete"
+)
+
+// Line is one line of a diff
+type Line struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// Hunk is a run of changed lines with surrounding context. Starts are
+// 1-based line numbers, as in unified diffs.
+type Hunk struct {
+	OldStart int    `json:"old_start"`
+	OldLines int    `json:"old_lines"`
+	NewStart int    `json:"new_start"`
+	NewLines int    `json:"new_lines"`
+	Lines    []Line `json:"lines"`
+}
+
+// DiffLines computes a minimal line diff of a and b with Myers' algorithm
+func DiffLines(a, b []string) []Line {
+	n, m := len(a), len(b)
+	max := n + m
+	if max == 0 {
+		return nil
+	}
+
+	// v[k+offset] is the furthest x reached on diagonal k. Before each edit
+	// distance d, the diagonals d reads are saved for backtracking; keeping
+	// only that window holds memory to O(d²) rather than O(d·(n+m)).
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	var trace []window
+
+	for d := 0; d <= max; d++ {
+		trace = append(trace, window{lo: -d - 1, v: append([]int(nil), v[offset-d-1:offset+d+2]...)})
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[k-1+offset] < v[k+1+offset]) {
+				x = v[k+1+offset]
+			} else {
+				x = v[k-1+offset] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[k+offset] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, trace)
+			}
+		}
+	}
+	return nil
+}
+
+// window is the slice of diagonals saved before one edit distance
+type window struct {
+	lo int // diagonal of v[0]
+	v  []int
+}
+
+// at returns the furthest x saved for diagonal k
+func (w window) at(k int) int {
+	return w.v[k-w.lo]
+}
+
+// backtrack walks the trace from the end to recover the edit script
+func backtrack(a, b []string, trace []window) []Line {
+	x, y := len(a), len(b)
+	var lines []Line
+
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && v.at(k-1) < v.at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v.at(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			lines = append(lines, Line{Op: OpEqual, Text: a[x]})
+		}
+		if d > 0 {
+			if x == prevX {
+				y--
+				lines = append(lines, Line{Op: OpInsert, Text: b[y]})
+			} else {
+				x--
+				lines = append(lines, Line{Op: OpDelete, Text: a[x]})
+			}
+		}
+	}
+
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+	return lines
+}
+
+// Hunks groups a diff into hunks with up to context unchanged lines around
+// each change. Changes separated by at most twice the context share a hunk.
+func Hunks(lines []Line, context int) []Hunk {
+	var hunks []Hunk
+	for i := 0; i < len(lines); i++ {
+		if lines[i].Op == OpEqual {
+			continue
+		}
+
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for j := i + 1; j < len(lines) && j <= end+2*context+1; j++ {
+			if lines[j].Op != OpEqual {
+				end = j
+			}
+		}
+		stop := end + context + 1
+		if stop > len(lines) {
+			stop = len(lines)
+		}
+
+		h := Hunk{OldStart: 1, NewStart: 1, Lines: lines[start:stop]}
+		for _, line := range lines[:start] {
+			if line.Op != OpInsert {
+				h.OldStart++
+			}
+			if line.Op != OpDelete {
+				h.NewStart++
+			}
+		}
+		for _, line := range h.Lines {
+			if line.Op != OpInsert {
+				h.OldLines++
+			}
+			if line.Op != OpDelete {
+				h.NewLines++
+			}
+		}
+		hunks = append(hunks, h)
+		i = stop - 1
+	}
+	return hunks
+}
+
+// Unified renders hunks in unified diff format under the given file names
+func Unified(oldName, newName string, hunks []Hunk) string {
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldName, newName)
+	for _, h := range hunks {
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", hunkRange(h.OldStart, h.OldLines), hunkRange(h.NewStart, h.NewLines))
+		for _, line := range h.Lines {
+			switch line.Op {
+			case OpInsert:
+				b.WriteString("+")
+			case OpDelete:
+				b.WriteString("-")
+			default:
+				b.WriteString(" ")
+			}
+			b.WriteString(line.Text)
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// hunkRange formats a hunk's line range. An empty range is anchored on the
+// line before it, which is 0 at the start of a file.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start-1)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// splitLines splits text into lines without their terminators
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
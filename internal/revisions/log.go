@@ -0,0 +1,60 @@
+package revisions
+
+import "github.com/memmieai/memmie-studio/internal/workflows"
+
+// LogQuery selects a page of a blob's delta log. SinceSequence is an
+// exclusive cursor; empty ProviderID, Types and Paths match every delta,
+// Paths otherwise matching the deltas that changed one of them; a Limit of
+// 0 returns every match.
+type LogQuery struct {
+	ProviderID    string
+	Types         []string
+	Paths         []string
+	SinceSequence int64
+	Limit         int
+}
+
+// LogPage is a page of a delta log in sequence order. NextSequence is
+// the cursor for the following page, or 0 on the last page.
+type LogPage struct {
+	Deltas         []workflows.Delta `json:"deltas"`
+	LatestSequence int64             `json:"latest_sequence"`
+	NextSequence   int64             `json:"next_since_sequence,omitempty"`
+}
+
+// Log returns the page of a delta log a query selects
+func Log(deltas []workflows.Delta, query LogQuery) LogPage {
+	types := make(map[string]bool, len(query.Types))
+	for _, t := range query.Types {
+		types[t] = true
+	}
+
+	page := LogPage{Deltas: []workflows.Delta{}, LatestSequence: Latest(deltas)}
+	for _, d := range sorted(deltas) {
+		if d.Sequence <= query.SinceSequence {
+			continue
+		}
+		if (query.ProviderID != "" && d.ProviderID != query.ProviderID) || (len(types) > 0 && !types[d.Type]) {
+			continue
+		}
+		if len(query.Paths) > 0 && !touches(d.Path, query.Paths) {
+			continue
+		}
+		if query.Limit > 0 && len(page.Deltas) == query.Limit {
+			page.NextSequence = page.Deltas[len(page.Deltas)-1].Sequence
+			break
+		}
+		page.Deltas = append(page.Deltas, d)
+	}
+	return page
+}
+
+// touches reports whether a change at a path changes one of paths
+func touches(path string, paths []string) bool {
+	for _, p := range paths {
+		if workflows.PathsOverlap(path, p) {
+			return true
+		}
+	}
+	return false
+}
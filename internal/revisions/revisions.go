@@ -0,0 +1,237 @@
+// Package revisions reconstructs blob versions from the delta log and
+// compares them
+package revisions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// Change types
+const (
+	ChangeAdded    = "added"
+	ChangeRemoved  = "removed"
+	ChangeModified = "modified"
+)
+
+// ErrInvalidRange is returned when a requested version range does not exist
+var ErrInvalidRange = errors.New("invalid version range")
+
+// DefaultContext is the number of unchanged lines shown around changes
+const DefaultContext = 3
+
+// History supplies a blob's delta log. Any workflows.DeltaStorage is a
+// History.
+type History interface {
+	GetByBlobID(ctx context.Context, blobID string) ([]workflows.Delta, error)
+}
+
+// Version is a blob's state after the deltas up to a sequence number, keyed
+// by delta path
+type Version struct {
+	Sequence int64                  `json:"sequence"`
+	State    map[string]interface{} `json:"state"`
+}
+
+// FieldChange is the difference in one path between two versions
+type FieldChange struct {
+	Path       string      `json:"path"`
+	Type       string      `json:"type"`
+	OldValue   interface{} `json:"old_value,omitempty"`
+	NewValue   interface{} `json:"new_value,omitempty"`
+	Hunks      []Hunk      `json:"hunks"`
+	Insertions int         `json:"insertions"`
+	Deletions  int         `json:"deletions"`
+}
+
+// DeltaSummary identifies a delta applied between two versions
+type DeltaSummary struct {
+	ID         string `json:"id"`
+	Sequence   int64  `json:"sequence"`
+	ProviderID string `json:"provider_id"`
+	Type       string `json:"type"`
+	Path       string `json:"path"`
+}
+
+// Diff compares two versions of a blob
+type Diff struct {
+	BlobID     string         `json:"blob_id"`
+	From       int64          `json:"from"`
+	To         int64          `json:"to"`
+	Latest     int64          `json:"latest"`
+	Changes    []FieldChange  `json:"changes"`
+	Deltas     []DeltaSummary `json:"deltas"`
+	Insertions int            `json:"insertions"`
+	Deletions  int            `json:"deletions"`
+	Unified    string         `json:"unified"`
+}
+
+// Latest returns the highest sequence number in a delta log
+func Latest(deltas []workflows.Delta) int64 {
+	var latest int64
+	for _, d := range deltas {
+		if d.Sequence > latest {
+			latest = d.Sequence
+		}
+	}
+	return latest
+}
+
+// At replays the deltas up to and including a sequence number. Sequence 0
+// is the empty state before the first delta.
+func At(deltas []workflows.Delta, sequence int64) Version {
+	ordered := sorted(deltas)
+	state := make(map[string]interface{})
+	for _, d := range ordered {
+		if d.Sequence > sequence {
+			break
+		}
+		path := normalizePath(d.Path)
+		if d.Type == "delete" {
+			delete(state, path)
+			continue
+		}
+		state[path] = d.NewValue
+	}
+	return Version{Sequence: sequence, State: state}
+}
+
+// Compare diffs the versions at two sequence numbers. Text values are
+// diffed line by line; other values are compared as indented JSON.
+func Compare(blobID string, deltas []workflows.Delta, from, to int64, context int) (*Diff, error) {
+	latest := Latest(deltas)
+	if from < 0 || to < 0 {
+		return nil, fmt.Errorf("%w: sequence numbers must not be negative", ErrInvalidRange)
+	}
+	if from > to {
+		return nil, fmt.Errorf("%w: from (%d) is after to (%d)", ErrInvalidRange, from, to)
+	}
+	if to > latest {
+		return nil, fmt.Errorf("%w: sequence %d is past the latest version %d", ErrInvalidRange, to, latest)
+	}
+
+	old, updated := At(deltas, from), At(deltas, to)
+	diff := &Diff{
+		BlobID:  blobID,
+		From:    from,
+		To:      to,
+		Latest:  latest,
+		Changes: []FieldChange{},
+		Deltas:  []DeltaSummary{},
+	}
+
+	var unified strings.Builder
+	for _, path := range unionPaths(old.State, updated.State) {
+		oldValue, hadOld := old.State[path]
+		newValue, hasNew := updated.State[path]
+
+		change := FieldChange{Path: path, OldValue: oldValue, NewValue: newValue}
+		switch {
+		case !hadOld:
+			change.Type = ChangeAdded
+		case !hasNew:
+			change.Type = ChangeRemoved
+		default:
+			change.Type = ChangeModified
+		}
+
+		lines := DiffLines(splitLines(render(oldValue, hadOld)), splitLines(render(newValue, hasNew)))
+		changed := false
+		for _, line := range lines {
+			switch line.Op {
+			case OpInsert:
+				change.Insertions++
+				changed = true
+			case OpDelete:
+				change.Deletions++
+				changed = true
+			}
+		}
+		if !changed && hadOld == hasNew {
+			continue
+		}
+
+		change.Hunks = Hunks(lines, context)
+		if change.Hunks == nil {
+			change.Hunks = []Hunk{}
+		}
+		diff.Changes = append(diff.Changes, change)
+		diff.Insertions += change.Insertions
+		diff.Deletions += change.Deletions
+		unified.WriteString(Unified(
+			fmt.Sprintf("a/%s@%d", path, from),
+			fmt.Sprintf("b/%s@%d", path, to),
+			change.Hunks,
+		))
+	}
+	diff.Unified = unified.String()
+
+	for _, d := range sorted(deltas) {
+		if d.Sequence > from && d.Sequence <= to {
+			diff.Deltas = append(diff.Deltas, DeltaSummary{
+				ID:         d.ID,
+				Sequence:   d.Sequence,
+				ProviderID: d.ProviderID,
+				Type:       d.Type,
+				Path:       normalizePath(d.Path),
+			})
+		}
+	}
+	return diff, nil
+}
+
+// render converts a value to the text that is diffed
+func render(value interface{}, present bool) string {
+	if !present || value == nil {
+		return ""
+	}
+	if s, ok := value.(string); ok {
+		return s
+	}
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+// normalizePath maps "/content" and "content" to the same key
+func normalizePath(path string) string {
+	return strings.TrimPrefix(path, "/")
+}
+
+// unionPaths returns the paths in either state, with content first
+func unionPaths(a, b map[string]interface{}) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	var paths []string
+	for _, state := range []map[string]interface{}{a, b} {
+		for path := range state {
+			if !seen[path] {
+				seen[path] = true
+				paths = append(paths, path)
+			}
+		}
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		if (paths[i] == "content") != (paths[j] == "content") {
+			return paths[i] == "content"
+		}
+		return paths[i] < paths[j]
+	})
+	return paths
+}
+
+// sorted returns deltas in sequence order
+func sorted(deltas []workflows.Delta) []workflows.Delta {
+	ordered := append([]workflows.Delta(nil), deltas...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Sequence < ordered[j].Sequence
+	})
+	return ordered
+}
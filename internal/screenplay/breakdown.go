@@ -0,0 +1,266 @@
+package screenplay
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/memmieai/memmie-studio/internal/style"
+)
+
+const (
+	// linesPerPage is the number of printed lines on a screenplay page
+	linesPerPage = 55
+
+	// Column widths, in characters, that action, dialogue and
+	// parentheticals wrap at on the page
+	actionWidth        = 61
+	dialogueWidth      = 35
+	parentheticalWidth = 25
+)
+
+// inlineNote matches notes inside other elements, which are not part of
+// the script's text
+var inlineNote = regexp.MustCompile(`\[\[.*?\]\]`)
+
+// Scene is a scene in a breakdown. Length is in eighths of a page, the unit
+// schedules are drawn up in.
+type Scene struct {
+	Number      int      `json:"number"`
+	SceneNumber string   `json:"scene_number,omitempty"`
+	Heading     string   `json:"heading"`
+	Setting     string   `json:"setting,omitempty"` // INT., EXT., INT./EXT.
+	Location    string   `json:"location,omitempty"`
+	Time        string   `json:"time,omitempty"`
+	Characters  []string `json:"characters"`
+	Speeches    int      `json:"speeches"`
+	Words       int      `json:"words"`
+	Eighths     int      `json:"eighths"`
+}
+
+// CharacterStats sums a character's part in the script
+type CharacterStats struct {
+	Name       string `json:"name"`
+	Speeches   int    `json:"speeches"`
+	Words      int    `json:"words"`
+	Scenes     int    `json:"scenes"`
+	FirstScene int    `json:"first_scene"`
+}
+
+// LocationStats sums the scenes set in a location
+type LocationStats struct {
+	Name    string `json:"name"`
+	Setting string `json:"setting,omitempty"`
+	Scenes  int    `json:"scenes"`
+	Eighths int    `json:"eighths"`
+}
+
+// Breakdown lists a script's scenes, speaking characters and locations
+type Breakdown struct {
+	Title         string           `json:"title,omitempty"`
+	Pages         float64          `json:"pages"`
+	Words         int              `json:"words"`
+	DialogueShare float64          `json:"dialogue_share"` // of all words, 0 to 1
+	Scenes        []Scene          `json:"scenes"`
+	Characters    []CharacterStats `json:"characters"`
+	Locations     []LocationStats  `json:"locations"`
+}
+
+// Summary returns the breakdown's totals for blob metadata
+func (b *Breakdown) Summary() map[string]interface{} {
+	return map[string]interface{}{
+		"title":          b.Title,
+		"pages":          b.Pages,
+		"words":          b.Words,
+		"scenes":         len(b.Scenes),
+		"characters":     len(b.Characters),
+		"locations":      len(b.Locations),
+		"dialogue_share": b.DialogueShare,
+	}
+}
+
+// Break breaks a script down by scene. Anything before the first scene
+// heading is counted toward the page total but belongs to no scene.
+// Characters are counted from their cues, without extensions such as
+// "(V.O.)", and ordered by number of speeches.
+func Break(script *Script) *Breakdown {
+	b := &Breakdown{
+		Title:      script.Title(),
+		Scenes:     []Scene{},
+		Characters: []CharacterStats{},
+		Locations:  []LocationStats{},
+	}
+
+	var scene *Scene
+	var sceneLines, totalLines, dialogueWords int
+	characters := make(map[string]*CharacterStats)
+	speaker := ""
+	closeScene := func() {
+		if scene != nil {
+			scene.Eighths = eighths(sceneLines)
+			b.Scenes = append(b.Scenes, *scene)
+		}
+	}
+
+	for _, e := range script.Elements {
+		lines := printedLines(e)
+		totalLines += lines
+		sceneLines += lines
+		words := len(style.Words(inlineNote.ReplaceAllString(e.Text, "")))
+
+		switch e.Type {
+		case SceneHeading:
+			closeScene()
+			setting, location, time := splitHeading(e.Text)
+			scene = &Scene{
+				Number:      len(b.Scenes) + 1,
+				SceneNumber: e.SceneNumber,
+				Heading:     e.Text,
+				Setting:     setting,
+				Location:    location,
+				Time:        time,
+				Characters:  []string{},
+			}
+			sceneLines = lines
+			speaker = ""
+			continue
+		case Character:
+			speaker = cueName(e.Text)
+			if speaker == "" {
+				continue
+			}
+			c, ok := characters[speaker]
+			if !ok {
+				c = &CharacterStats{Name: speaker, FirstScene: len(b.Scenes) + 1}
+				characters[speaker] = c
+			}
+			c.Speeches++
+			if scene != nil {
+				scene.Speeches++
+				if !containsString(scene.Characters, speaker) {
+					scene.Characters = append(scene.Characters, speaker)
+					c.Scenes++
+				}
+			}
+			continue
+		case Dialogue:
+			dialogueWords += words
+			if c := characters[speaker]; c != nil {
+				c.Words += words
+			}
+		case Action, Lyric, Centered:
+		default:
+			// Notes, sections and synopses are not part of the script
+			continue
+		}
+		b.Words += words
+		if scene != nil {
+			scene.Words += words
+		}
+	}
+	closeScene()
+
+	b.Pages = math.Round(float64(totalLines)/linesPerPage*10) / 10
+	if b.Words > 0 {
+		b.DialogueShare = math.Round(float64(dialogueWords)/float64(b.Words)*100) / 100
+	}
+	for _, c := range characters {
+		b.Characters = append(b.Characters, *c)
+	}
+	sort.Slice(b.Characters, func(i, j int) bool {
+		if b.Characters[i].Speeches != b.Characters[j].Speeches {
+			return b.Characters[i].Speeches > b.Characters[j].Speeches
+		}
+		return b.Characters[i].Name < b.Characters[j].Name
+	})
+	b.Locations = locations(b.Scenes)
+	return b
+}
+
+// locations sums scenes by location, longest first
+func locations(scenes []Scene) []LocationStats {
+	index := make(map[string]int)
+	out := []LocationStats{}
+	for _, s := range scenes {
+		name := s.Location
+		if name == "" {
+			name = s.Heading
+		}
+		i, ok := index[name]
+		if !ok {
+			i = len(out)
+			index[name] = i
+			out = append(out, LocationStats{Name: name, Setting: s.Setting})
+		} else if out[i].Setting != s.Setting {
+			out[i].Setting = "INT./EXT."
+		}
+		out[i].Scenes++
+		out[i].Eighths += s.Eighths
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Eighths > out[j].Eighths })
+	return out
+}
+
+// splitHeading splits a normalized scene heading into its setting, location
+// and time of day
+func splitHeading(heading string) (setting, location, time string) {
+	m := scenePrefix.FindStringSubmatch(heading)
+	if m == nil {
+		return "", heading, ""
+	}
+	key := strings.ToLower(strings.Join(strings.Fields(strings.ReplaceAll(m[1], ".", "")), ""))
+	setting = settings[key]
+	rest := m[2]
+	if i := strings.LastIndex(rest, " - "); i >= 0 {
+		return setting, strings.TrimSpace(rest[:i]), strings.TrimSpace(rest[i+3:])
+	}
+	return setting, strings.TrimSpace(rest), ""
+}
+
+// cueName strips a character cue's extensions and markers
+func cueName(cue string) string {
+	cue = cueExtension.ReplaceAllString(cue, "")
+	return strings.ToUpper(strings.Join(strings.Fields(strings.Trim(cue, "@^ ")), " "))
+}
+
+// printedLines estimates the lines an element takes on the page, including
+// the blank line before it
+func printedLines(e Element) int {
+	switch e.Type {
+	case SceneHeading, Transition, Character, Centered:
+		return 2
+	case Parenthetical:
+		return wrapped(e.Text, parentheticalWidth)
+	case Dialogue:
+		return wrapped(e.Text, dialogueWidth)
+	case Action, Lyric:
+		return 1 + wrapped(e.Text, actionWidth)
+	}
+	return 0
+}
+
+// wrapped counts the lines text wraps to at a column width
+func wrapped(text string, width int) int {
+	lines := 0
+	for _, line := range strings.Split(inlineNote.ReplaceAllString(text, ""), "\n") {
+		n := len([]rune(strings.TrimSpace(line)))
+		lines += int(math.Max(1, math.Ceil(float64(n)/float64(width))))
+	}
+	return lines
+}
+
+// eighths converts printed lines to eighths of a page, at least one
+func eighths(lines int) int {
+	return int(math.Max(1, math.Round(float64(lines)*8/linesPerPage)))
+}
+
+// containsString reports whether a list holds a string
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,196 @@
+package screenplay
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/memmieai/memmie-studio/internal/blob"
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// Kinds of blobs derived from a screenplay
+const (
+	KindFormatted = "formatted_screenplay"
+	KindCoverage  = "screenplay_coverage"
+)
+
+// Coverage is a reader's report on a screenplay. The written parts come
+// from the coverage model; the numbers come from the breakdown.
+type Coverage struct {
+	Title      string            `json:"title,omitempty"`
+	Logline    string            `json:"logline"`
+	Synopsis   string            `json:"synopsis"`
+	Comments   string            `json:"comments,omitempty"`
+	Strengths  []string          `json:"strengths,omitempty"`
+	Weaknesses []string          `json:"weaknesses,omitempty"`
+	Ratings    map[string]string `json:"ratings,omitempty"` // e.g. "dialogue": "good"
+	Verdict    string            `json:"verdict,omitempty"` // pass, consider or recommend
+	Breakdown  *Breakdown        `json:"-"`
+}
+
+// CoverageFromMap reads coverage from a model's output. Recommendation is
+// accepted in place of verdict.
+func CoverageFromMap(m map[string]interface{}) Coverage {
+	c := Coverage{
+		Strengths:  workflows.StringList(m["strengths"]),
+		Weaknesses: workflows.StringList(m["weaknesses"]),
+	}
+	c.Logline, _ = m["logline"].(string)
+	c.Synopsis, _ = m["synopsis"].(string)
+	c.Comments, _ = m["comments"].(string)
+	c.Verdict, _ = m["verdict"].(string)
+	if c.Verdict == "" {
+		c.Verdict, _ = m["recommendation"].(string)
+	}
+	c.Verdict = strings.ToLower(strings.TrimSpace(c.Verdict))
+	if ratings, ok := m["ratings"].(map[string]interface{}); ok {
+		c.Ratings = make(map[string]string, len(ratings))
+		for k, v := range ratings {
+			c.Ratings[k] = fmt.Sprint(v)
+		}
+	}
+	return c
+}
+
+// Markdown renders the coverage report, with the breakdown's numbers when
+// it has one
+func (c Coverage) Markdown() string {
+	var b strings.Builder
+	title := c.Title
+	if title == "" {
+		title = "Untitled"
+	}
+	fmt.Fprintf(&b, "# Coverage: %s\n", title)
+	if c.Verdict != "" {
+		fmt.Fprintf(&b, "\n**Verdict:** %s\n", strings.ToUpper(c.Verdict))
+	}
+	if c.Logline != "" {
+		fmt.Fprintf(&b, "\n## Logline\n\n%s\n", c.Logline)
+	}
+	if bd := c.Breakdown; bd != nil {
+		fmt.Fprintf(&b, "\n## At a Glance\n\n- Pages: %.1f\n- Scenes: %d\n- Speaking characters: %d\n- Locations: %d\n- Dialogue: %.0f%% of words\n",
+			bd.Pages, len(bd.Scenes), len(bd.Characters), len(bd.Locations), bd.DialogueShare*100)
+		if n := len(bd.Characters); n > 0 {
+			leads := bd.Characters
+			if n > 5 {
+				leads = leads[:5]
+			}
+			names := make([]string, len(leads))
+			for i, lead := range leads {
+				unit := "speeches"
+				if lead.Speeches == 1 {
+					unit = "speech"
+				}
+				names[i] = fmt.Sprintf("%s (%d %s)", lead.Name, lead.Speeches, unit)
+			}
+			fmt.Fprintf(&b, "- Leads: %s\n", strings.Join(names, ", "))
+		}
+	}
+	if c.Synopsis != "" {
+		fmt.Fprintf(&b, "\n## Synopsis\n\n%s\n", c.Synopsis)
+	}
+	writeList(&b, "Strengths", c.Strengths)
+	writeList(&b, "Weaknesses", c.Weaknesses)
+	if len(c.Ratings) > 0 {
+		b.WriteString("\n## Ratings\n\n| Category | Rating |\n| --- | --- |\n")
+		keys := make([]string, 0, len(c.Ratings))
+		for k := range c.Ratings {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(&b, "| %s | %s |\n", k, c.Ratings[k])
+		}
+	}
+	if c.Comments != "" {
+		fmt.Fprintf(&b, "\n## Comments\n\n%s\n", c.Comments)
+	}
+	return b.String()
+}
+
+// writeList writes a titled bullet list, if it has items
+func writeList(b *strings.Builder, title string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "\n## %s\n\n", title)
+	for _, item := range items {
+		fmt.Fprintf(b, "- %s\n", item)
+	}
+}
+
+// Service stores the blobs derived from screenplays
+type Service struct {
+	blobs blob.Store
+}
+
+// NewService creates a screenplay service
+func NewService(blobs blob.Store) *Service {
+	return &Service{blobs: blobs}
+}
+
+// ProviderID returns the provider of a screenplay project
+func ProviderID(projectID string) string {
+	return "screenplay:" + projectID
+}
+
+// Derived describes a blob derived from a screenplay
+type Derived struct {
+	UserID      string
+	ProviderID  string
+	SourceID    string
+	ExecutionID string
+	Kind        string
+	Content     string
+	Metadata    map[string]interface{}
+}
+
+// SaveDerived stores a child blob of the screenplay, replacing the content
+// of the one of the same kind made from an earlier draft, and returns its
+// ID
+func (s *Service) SaveDerived(ctx context.Context, d Derived) (string, error) {
+	metadata := map[string]interface{}{
+		"kind":         d.Kind,
+		"derived_from": d.SourceID,
+		"execution_id": d.ExecutionID,
+	}
+	for k, v := range d.Metadata {
+		metadata[k] = v
+	}
+
+	children, err := s.blobs.ListBlobs(ctx, d.UserID, blob.Filter{ProviderID: d.ProviderID, ParentID: d.SourceID})
+	if err != nil {
+		return "", fmt.Errorf("failed to list derived blobs: %w", err)
+	}
+	for _, child := range children {
+		if child.Metadata["kind"] != d.Kind {
+			continue
+		}
+		child.Content = d.Content
+		if child.Metadata == nil {
+			child.Metadata = make(map[string]interface{})
+		}
+		for k, v := range metadata {
+			child.Metadata[k] = v
+		}
+		if _, err := s.blobs.UpdateBlob(ctx, child); err != nil {
+			return "", fmt.Errorf("failed to update %s %s: %w", d.Kind, child.ID, err)
+		}
+		return child.ID, nil
+	}
+
+	parentID := d.SourceID
+	created, err := s.blobs.CreateBlob(ctx, &blob.Blob{
+		UserID:     d.UserID,
+		ProviderID: d.ProviderID,
+		Content:    d.Content,
+		ParentID:   &parentID,
+		Metadata:   metadata,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to store %s: %w", d.Kind, err)
+	}
+	return created.ID, nil
+}
@@ -0,0 +1,123 @@
+package screenplay
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// fdxDocument is the part of a Final Draft file that holds the script
+type fdxDocument struct {
+	XMLName   xml.Name       `xml:"FinalDraft"`
+	Content   []fdxParagraph `xml:"Content>Paragraph"`
+	TitlePage []fdxParagraph `xml:"TitlePage>Content>Paragraph"`
+}
+
+// fdxParagraph is a Final Draft paragraph. Its text may be split across
+// several styled runs.
+type fdxParagraph struct {
+	Type   string    `xml:"Type,attr"`
+	Number string    `xml:"Number,attr"`
+	Texts  []fdxText `xml:"Text"`
+	Dual   *struct {
+		Paragraphs []fdxParagraph `xml:"Paragraph"`
+	} `xml:"DualDialogue"`
+}
+
+type fdxText struct {
+	Value string `xml:",chardata"`
+}
+
+// fdxTypes maps Final Draft paragraph types to element types. Unlisted
+// types, such as General and Shot, are read as action.
+var fdxTypes = map[string]string{
+	"Scene Heading": SceneHeading,
+	"Action":        Action,
+	"Character":     Character,
+	"Dialogue":      Dialogue,
+	"Parenthetical": Parenthetical,
+	"Transition":    Transition,
+	"Lyrics":        Lyric,
+}
+
+// ParseFinalDraft reads a Final Draft (.fdx) screenplay
+func ParseFinalDraft(content string) (*Script, error) {
+	var doc fdxDocument
+	if err := xml.Unmarshal([]byte(content), &doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidScript, err)
+	}
+
+	script := &Script{Format: FormatFinalDraft, Elements: []Element{}}
+	for _, p := range expandDual(doc.Content) {
+		text := p.text()
+		if text == "" {
+			continue
+		}
+		e := Element{Type: Action, Text: text}
+		if t, ok := fdxTypes[p.Type]; ok {
+			e.Type = t
+		}
+		if e.Type == SceneHeading {
+			e.SceneNumber = p.Number
+		}
+		if e.Type == Character && p.Dual != nil {
+			e.Dual = true
+		}
+		script.Elements = append(script.Elements, e)
+	}
+
+	// Title pages have no fields, only centered lines; the first is taken
+	// as the title and the line after "by" or "written by" as the author
+	var lines []string
+	for _, p := range doc.TitlePage {
+		if text := p.text(); text != "" {
+			lines = append(lines, text)
+		}
+	}
+	for i, line := range lines {
+		if i == 0 {
+			script.TitlePage = append(script.TitlePage, TitleField{Key: "Title", Value: line})
+			continue
+		}
+		lower := strings.ToLower(line)
+		if (lower == "by" || lower == "written by") && i+1 < len(lines) {
+			script.TitlePage = append(script.TitlePage,
+				TitleField{Key: "Credit", Value: line},
+				TitleField{Key: "Author", Value: lines[i+1]})
+			break
+		}
+	}
+	return script, nil
+}
+
+// expandDual flattens dual dialogue blocks into their paragraphs, marking
+// the second speaker's cue as dual the way Fountain does
+func expandDual(paragraphs []fdxParagraph) []fdxParagraph {
+	var out []fdxParagraph
+	for _, p := range paragraphs {
+		if p.Dual == nil {
+			out = append(out, p)
+			continue
+		}
+		cues := 0
+		for _, inner := range p.Dual.Paragraphs {
+			if inner.Type == "Character" {
+				cues++
+				if cues == 2 {
+					inner.Dual = p.Dual
+				}
+			}
+			out = append(out, inner)
+		}
+	}
+	return out
+}
+
+// text joins a paragraph's runs
+func (p fdxParagraph) text() string {
+	var b strings.Builder
+	for _, t := range p.Texts {
+		b.WriteString(t.Value)
+	}
+	return strings.TrimSpace(b.String())
+}
@@ -0,0 +1,184 @@
+package screenplay
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// scenePrefix splits a scene heading into its setting prefix and the
+	// rest
+	scenePrefix = regexp.MustCompile(`(?i)^(int\.?\s*/\s*ext|ext\.?\s*/\s*int|i\s*/\s*e|int|ext|est)\.?\s*(.*)$`)
+
+	// timeSeparator matches the dash between a location and time of day,
+	// however it was typed
+	timeSeparator = regexp.MustCompile(`\s+(?:-+|–|—)\s+|\s*--\s*`)
+
+	// cueExtension matches a character cue's extension, e.g. "(v.o.)"
+	cueExtension = regexp.MustCompile(`\(([^)]*)\)`)
+)
+
+// settings maps the ways a scene's setting is typed to its standard form
+var settings = map[string]string{
+	"int": "INT.", "ext": "EXT.", "est": "EST.",
+	"int/ext": "INT./EXT.", "ext/int": "EXT./INT.", "i/e": "I/E.",
+}
+
+// Normalize rewrites a script's elements in standard form: scene headings
+// as "INT. LOCATION - TIME", character cues and transitions in capitals,
+// and parentheticals in parentheses
+func Normalize(script *Script) {
+	for i := range script.Elements {
+		e := &script.Elements[i]
+		switch e.Type {
+		case SceneHeading:
+			e.Text = normalizeHeading(e.Text)
+		case Character:
+			e.Text = normalizeCue(e.Text)
+		case Transition:
+			e.Text = strings.ToUpper(strings.Join(strings.Fields(e.Text), " "))
+		case Parenthetical:
+			text := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(e.Text), "("), ")"))
+			e.Text = "(" + text + ")"
+		case Dialogue, Action, Lyric:
+			e.Text = trimLines(e.Text)
+		}
+	}
+}
+
+// normalizeHeading standardizes a scene heading's setting prefix, the dash
+// before the time of day, spacing and capitals
+func normalizeHeading(heading string) string {
+	heading = strings.Join(strings.Fields(heading), " ")
+	m := scenePrefix.FindStringSubmatch(heading)
+	if m == nil {
+		return strings.ToUpper(heading)
+	}
+	key := strings.ToLower(strings.Join(strings.Fields(strings.ReplaceAll(m[1], ".", "")), ""))
+	rest := timeSeparator.ReplaceAllString(m[2], " - ")
+	return strings.ToUpper(strings.TrimSpace(settings[key] + " " + rest))
+}
+
+// normalizeCue capitalizes a character cue and its extension
+func normalizeCue(cue string) string {
+	cue = strings.Join(strings.Fields(cue), " ")
+	return cueExtension.ReplaceAllStringFunc(strings.ToUpper(cue), func(ext string) string {
+		return "(" + strings.TrimSpace(ext[1:len(ext)-1]) + ")"
+	})
+}
+
+// trimLines removes trailing spaces from each line of text
+func trimLines(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Fountain writes a script as Fountain. Elements that would be read back
+// as something else are forced with Fountain's prefix characters, so the
+// output parses to the same elements.
+func Fountain(script *Script) string {
+	var b strings.Builder
+	for _, f := range script.TitlePage {
+		values := strings.Split(f.Value, "\n")
+		if len(values) == 1 {
+			b.WriteString(f.Key + ": " + values[0] + "\n")
+			continue
+		}
+		b.WriteString(f.Key + ":\n")
+		for _, v := range values {
+			b.WriteString("    " + v + "\n")
+		}
+	}
+	if len(script.TitlePage) > 0 {
+		b.WriteString("\n")
+	}
+
+	for i, e := range script.Elements {
+		// Dialogue and parentheticals sit directly under their cue
+		if i > 0 && !(e.Type == Dialogue || e.Type == Parenthetical) {
+			b.WriteString("\n")
+		}
+		b.WriteString(fountainElement(e))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// fountainElement writes one element
+func fountainElement(e Element) string {
+	switch e.Type {
+	case SceneHeading:
+		text := e.Text
+		if !sceneStart.MatchString(text + " ") {
+			text = "." + text
+		}
+		if e.SceneNumber != "" {
+			text += " #" + e.SceneNumber + "#"
+		}
+		return text
+	case Character:
+		text := e.Text
+		if !isCharacterCue(text) {
+			text = "@" + text
+		}
+		if e.Dual {
+			text += " ^"
+		}
+		return text
+	case Transition:
+		if isTransition(e.Text) {
+			return e.Text
+		}
+		return "> " + e.Text
+	case Centered:
+		return "> " + e.Text + " <"
+	case Lyric:
+		return "~" + strings.ReplaceAll(e.Text, "\n", "\n~")
+	case Section:
+		return strings.Repeat("#", max(e.Depth, 1)) + " " + e.Text
+	case Synopsis:
+		return "= " + e.Text
+	case Note:
+		return "[[" + e.Text + "]]"
+	case PageBreak:
+		return "==="
+	case Action:
+		lines := strings.Split(e.Text, "\n")
+		for i, line := range lines {
+			trimmed := strings.TrimSpace(line)
+			if hasMarker(trimmed) || (i == 0 && needsForcedAction(trimmed, len(lines) > 1)) {
+				lines[i] = "!" + line
+			}
+		}
+		return strings.Join(lines, "\n")
+	}
+	return e.Text
+}
+
+// hasMarker reports whether a line starts with one of Fountain's element
+// markers, which apply anywhere in a paragraph
+func hasMarker(line string) bool {
+	if line == "" || strings.HasPrefix(line, "...") {
+		return false
+	}
+	return strings.ContainsAny(line[:1], "#=~>.@!") || strings.HasPrefix(line, "[[")
+}
+
+// needsForcedAction reports whether an action paragraph's first line would
+// be read as another element after a blank line
+func needsForcedAction(line string, continued bool) bool {
+	switch {
+	case line == "":
+		return false
+	case sceneStart.MatchString(line + " "):
+		return true
+	case isTransition(line) && !continued:
+		return true
+	case isCharacterCue(line) && continued:
+		return true
+	}
+	return false
+}
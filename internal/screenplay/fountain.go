@@ -0,0 +1,192 @@
+package screenplay
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	// boneyard is Fountain's commented-out text, which is not part of the
+	// script
+	boneyard = regexp.MustCompile(`(?s)/\*.*?\*/`)
+
+	// sceneStart matches the prefixes that make a line a scene heading
+	sceneStart = regexp.MustCompile(`(?i)^(?:int\.?\s*/\s*ext|ext\.?\s*/\s*int|i\s*/\s*e|int|ext|est)[.\s]`)
+
+	// sceneNumber matches a scene number such as "#12A#" ending a heading
+	sceneNumber = regexp.MustCompile(`\s*#([\w.-]+)#\s*$`)
+
+	// titleKey matches the start of a title page entry
+	titleKey = regexp.MustCompile(`^([A-Za-z][A-Za-z ]*):\s*(.*)$`)
+
+	// pageBreak matches a forced page break
+	pageBreak = regexp.MustCompile(`^={3,}\s*$`)
+)
+
+// ParseFountain reads a Fountain screenplay
+func ParseFountain(content string) *Script {
+	content = strings.ReplaceAll(strings.ReplaceAll(content, "\r\n", "\n"), "\r", "\n")
+	content = boneyard.ReplaceAllString(content, "")
+	lines := strings.Split(content, "\n")
+
+	script := &Script{Format: FormatFountain, Elements: []Element{}}
+	i := parseTitlePage(lines, script)
+
+	prevBlank := true
+	inDialogue := false
+	add := func(e Element) {
+		script.Elements = append(script.Elements, e)
+	}
+	last := func() *Element {
+		if len(script.Elements) == 0 {
+			return nil
+		}
+		return &script.Elements[len(script.Elements)-1]
+	}
+	nextBlank := func(i int) bool {
+		return i+1 >= len(lines) || strings.TrimSpace(lines[i+1]) == ""
+	}
+
+	for ; i < len(lines); i++ {
+		raw := strings.TrimRight(lines[i], " \t")
+		line := strings.TrimSpace(raw)
+		if line == "" {
+			prevBlank, inDialogue = true, false
+			continue
+		}
+
+		if inDialogue {
+			if strings.HasPrefix(line, "(") && strings.HasSuffix(line, ")") {
+				add(Element{Type: Parenthetical, Text: line})
+			} else if e := last(); e != nil && e.Type == Dialogue {
+				e.Text += "\n" + line
+			} else {
+				add(Element{Type: Dialogue, Text: line})
+			}
+			prevBlank = false
+			continue
+		}
+
+		switch {
+		case pageBreak.MatchString(line):
+			add(Element{Type: PageBreak})
+		case strings.HasPrefix(line, "#"):
+			depth := len(line) - len(strings.TrimLeft(line, "#"))
+			add(Element{Type: Section, Text: strings.TrimSpace(line[depth:]), Depth: depth})
+		case strings.HasPrefix(line, "="):
+			add(Element{Type: Synopsis, Text: strings.TrimSpace(line[1:])})
+		case strings.HasPrefix(line, "~"):
+			add(Element{Type: Lyric, Text: strings.TrimSpace(line[1:])})
+		case strings.HasPrefix(line, "[[") && strings.HasSuffix(line, "]]"):
+			add(Element{Type: Note, Text: strings.TrimSpace(line[2 : len(line)-2])})
+		case strings.HasPrefix(line, ">") && strings.HasSuffix(line, "<"):
+			add(Element{Type: Centered, Text: strings.TrimSpace(line[1 : len(line)-1])})
+		case strings.HasPrefix(line, ">"):
+			add(Element{Type: Transition, Text: strings.TrimSpace(line[1:])})
+		case strings.HasPrefix(line, "!"):
+			addAction(script, strings.Replace(raw, "!", "", 1), prevBlank)
+		case strings.HasPrefix(line, ".") && !strings.HasPrefix(line, ".."):
+			add(sceneHeading(line[1:]))
+		case prevBlank && sceneStart.MatchString(line):
+			add(sceneHeading(line))
+		case prevBlank && nextBlank(i) && isTransition(line):
+			add(Element{Type: Transition, Text: line})
+		case prevBlank && !nextBlank(i) && (strings.HasPrefix(line, "@") || isCharacterCue(line)):
+			name := strings.TrimPrefix(line, "@")
+			dual := strings.HasSuffix(name, "^")
+			add(Element{Type: Character, Text: strings.TrimSpace(strings.TrimSuffix(name, "^")), Dual: dual})
+			inDialogue = true
+		default:
+			addAction(script, raw, prevBlank)
+		}
+		prevBlank = false
+	}
+	return script
+}
+
+// parseTitlePage reads the title page at the start of the lines, if there
+// is one, and returns the index of the first line after it
+func parseTitlePage(lines []string, script *Script) int {
+	start := 0
+	for start < len(lines) && strings.TrimSpace(lines[start]) == "" {
+		start++
+	}
+	if start == len(lines) || !titleKey.MatchString(strings.TrimSpace(lines[start])) {
+		return 0
+	}
+	i := start
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if strings.TrimSpace(line) == "" {
+			break
+		}
+		indented := strings.HasPrefix(line, "\t") || strings.HasPrefix(line, "   ")
+		if m := titleKey.FindStringSubmatch(strings.TrimSpace(line)); m != nil && !indented {
+			script.TitlePage = append(script.TitlePage, TitleField{Key: strings.TrimSpace(m[1]), Value: m[2]})
+			continue
+		}
+		if len(script.TitlePage) == 0 {
+			// Not a title page after all
+			script.TitlePage = nil
+			return 0
+		}
+		f := &script.TitlePage[len(script.TitlePage)-1]
+		f.Value = strings.TrimLeft(f.Value+"\n"+strings.TrimSpace(line), "\n")
+	}
+	return i
+}
+
+// addAction adds action text, continuing the previous action paragraph
+// when there was no blank line between them
+func addAction(script *Script, text string, prevBlank bool) {
+	if n := len(script.Elements); n > 0 && !prevBlank && script.Elements[n-1].Type == Action {
+		script.Elements[n-1].Text += "\n" + text
+		return
+	}
+	script.Elements = append(script.Elements, Element{Type: Action, Text: text})
+}
+
+// sceneHeading builds a scene heading, splitting off its scene number
+func sceneHeading(text string) Element {
+	e := Element{Type: SceneHeading, Text: strings.TrimSpace(text)}
+	if m := sceneNumber.FindStringSubmatchIndex(e.Text); m != nil {
+		e.SceneNumber = e.Text[m[2]:m[3]]
+		e.Text = strings.TrimSpace(e.Text[:m[0]])
+	}
+	return e
+}
+
+// isTransition reports whether a line is an uppercase transition such as
+// "CUT TO:"
+func isTransition(line string) bool {
+	return strings.HasSuffix(line, "TO:") && isUpper(line)
+}
+
+// isCharacterCue reports whether a line is a character name in capitals,
+// optionally followed by an extension such as "(V.O.)" and a dual dialogue
+// caret
+func isCharacterCue(line string) bool {
+	name := strings.TrimSpace(strings.TrimSuffix(line, "^"))
+	if i := strings.Index(name, "("); i >= 0 {
+		name = strings.TrimSpace(name[:i])
+	}
+	if name == "" || len([]rune(name)) > 40 || sceneStart.MatchString(line) || strings.HasSuffix(name, ":") {
+		return false
+	}
+	return isUpper(name)
+}
+
+// isUpper reports whether text has letters and all of them are capitals
+func isUpper(text string) bool {
+	letters := false
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			if !unicode.IsUpper(r) {
+				return false
+			}
+			letters = true
+		}
+	}
+	return letters
+}
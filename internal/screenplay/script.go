@@ -0,0 +1,96 @@
+// Package screenplay reads screenplays written in Fountain or Final Draft,
+// rewrites them as consistently formatted Fountain, and breaks them down
+// into scenes, characters and locations for coverage.
+package screenplay
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Supported screenplay formats
+const (
+	FormatFountain   = "fountain"
+	FormatFinalDraft = "fdx"
+)
+
+// Element types
+const (
+	SceneHeading  = "scene_heading"
+	Action        = "action"
+	Character     = "character"
+	Dialogue      = "dialogue"
+	Parenthetical = "parenthetical"
+	Transition    = "transition"
+	Centered      = "centered"
+	Lyric         = "lyric"
+	Section       = "section"
+	Synopsis      = "synopsis"
+	Note          = "note"
+	PageBreak     = "page_break"
+)
+
+// ErrInvalidScript is returned for content that cannot be read as a
+// screenplay
+var ErrInvalidScript = errors.New("invalid screenplay")
+
+// Element is a paragraph of a screenplay
+type Element struct {
+	Type        string `json:"type"`
+	Text        string `json:"text"`
+	SceneNumber string `json:"scene_number,omitempty"` // as written, e.g. "12A"
+	Dual        bool   `json:"dual,omitempty"`         // character cue of dual dialogue
+	Depth       int    `json:"depth,omitempty"`        // section level
+}
+
+// TitleField is a title page entry
+type TitleField struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// Script is a parsed screenplay
+type Script struct {
+	Format    string       `json:"format"`
+	TitlePage []TitleField `json:"title_page,omitempty"`
+	Elements  []Element    `json:"elements"`
+}
+
+// Title returns the title page's title, if any
+func (s *Script) Title() string {
+	for _, f := range s.TitlePage {
+		if strings.EqualFold(f.Key, "title") {
+			return strings.Join(strings.Fields(strings.ReplaceAll(f.Value, "*", "")), " ")
+		}
+	}
+	return ""
+}
+
+// Parse reads a screenplay. An empty format is detected from the content:
+// XML is read as Final Draft and anything else as Fountain.
+func Parse(content, format string) (*Script, error) {
+	content = strings.TrimPrefix(content, "\ufeff")
+	if strings.TrimSpace(content) == "" {
+		return nil, fmt.Errorf("%w: no content", ErrInvalidScript)
+	}
+	if format == "" {
+		format = DetectFormat(content)
+	}
+	switch strings.ToLower(strings.TrimPrefix(format, ".")) {
+	case FormatFountain, "spmd", "txt":
+		return ParseFountain(content), nil
+	case FormatFinalDraft, "finaldraft", "final_draft":
+		return ParseFinalDraft(content)
+	}
+	return nil, fmt.Errorf("%w: unsupported format %q", ErrInvalidScript, format)
+}
+
+// DetectFormat guesses a screenplay's format from its content
+func DetectFormat(content string) string {
+	trimmed := strings.TrimSpace(content)
+	if strings.HasPrefix(trimmed, "<?xml") || strings.HasPrefix(trimmed, "<FinalDraft") {
+		return FormatFinalDraft
+	}
+	return FormatFountain
+}
@@ -0,0 +1,156 @@
+package screenplay
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// Step types handled by the screenplay executors
+const (
+	FormatStepType    = "screenplay_format"
+	BreakdownStepType = "screenplay_breakdown"
+	CoverageStepType  = "screenplay_coverage"
+)
+
+// Blob metadata paths the executors write summaries to
+const (
+	BreakdownPath = "metadata.breakdown"
+	CoveragePath  = "metadata.coverage"
+)
+
+// NewFormatExecutor creates the executor that parses a screenplay and
+// stores it as normalized Fountain in a child blob. Step inputs: content
+// and format (fountain or fdx; detected when empty).
+func NewFormatExecutor(service *Service) workflows.StepExecutor {
+	return workflows.StepExecutorFunc(func(ctx context.Context, req workflows.StepRequest) (map[string]interface{}, error) {
+		script, err := parseInput(req)
+		if err != nil {
+			return nil, err
+		}
+		Normalize(script)
+		fountain := Fountain(script)
+
+		blobID, err := service.SaveDerived(ctx, Derived{
+			UserID:      req.Context.UserID,
+			ProviderID:  req.Context.ProviderID,
+			SourceID:    req.Context.BlobID,
+			ExecutionID: req.ExecutionID,
+			Kind:        KindFormatted,
+			Content:     fountain,
+			Metadata: map[string]interface{}{
+				"format":        FormatFountain,
+				"source_format": script.Format,
+				"title":         script.Title(),
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"blob_id":       blobID,
+			"content":       fountain,
+			"title":         script.Title(),
+			"source_format": script.Format,
+			"elements":      len(script.Elements),
+		}, nil
+	})
+}
+
+// NewBreakdownExecutor creates the executor that breaks a screenplay down
+// into scenes, characters and locations. Step inputs: content and format,
+// as for the format step. The totals are written to the screenplay's
+// metadata.
+func NewBreakdownExecutor() workflows.StepExecutor {
+	return workflows.StepExecutorFunc(func(ctx context.Context, req workflows.StepRequest) (map[string]interface{}, error) {
+		script, err := parseInput(req)
+		if err != nil {
+			return nil, err
+		}
+		Normalize(script)
+		breakdown := Break(script)
+		summary := breakdown.Summary()
+
+		return map[string]interface{}{
+			"breakdown": breakdown,
+			"summary":   summary,
+			"deltas": []interface{}{
+				delta(req, BreakdownPath, summary),
+			},
+		}, nil
+	})
+}
+
+// NewCoverageExecutor creates the executor that stores a coverage report
+// as a child blob of the screenplay. Step inputs: coverage (the coverage
+// model's logline, synopsis, comments, strengths, weaknesses, ratings and
+// verdict), and content and format, from which the report's numbers are
+// computed.
+func NewCoverageExecutor(service *Service) workflows.StepExecutor {
+	return workflows.StepExecutorFunc(func(ctx context.Context, req workflows.StepRequest) (map[string]interface{}, error) {
+		written, ok := req.Input["coverage"].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("coverage is required")
+		}
+		script, err := parseInput(req)
+		if err != nil {
+			return nil, err
+		}
+		Normalize(script)
+
+		coverage := CoverageFromMap(written)
+		coverage.Title = script.Title()
+		coverage.Breakdown = Break(script)
+
+		blobID, err := service.SaveDerived(ctx, Derived{
+			UserID:      req.Context.UserID,
+			ProviderID:  req.Context.ProviderID,
+			SourceID:    req.Context.BlobID,
+			ExecutionID: req.ExecutionID,
+			Kind:        KindCoverage,
+			Content:     coverage.Markdown(),
+			Metadata: map[string]interface{}{
+				"title":   coverage.Title,
+				"logline": coverage.Logline,
+				"verdict": coverage.Verdict,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		summary := map[string]interface{}{
+			"blob_id": blobID,
+			"logline": coverage.Logline,
+			"verdict": coverage.Verdict,
+		}
+		return map[string]interface{}{
+			"blob_id":  blobID,
+			"coverage": coverage,
+			"deltas": []interface{}{
+				delta(req, CoveragePath, summary),
+			},
+		}, nil
+	})
+}
+
+// parseInput parses the screenplay in a step's content input
+func parseInput(req workflows.StepRequest) (*Script, error) {
+	content, _ := req.Input["content"].(string)
+	format, _ := req.Input["format"].(string)
+	return Parse(content, format)
+}
+
+// delta builds a metadata update for the screenplay blob
+func delta(req workflows.StepRequest, path string, value interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"type":      "update",
+		"path":      path,
+		"new_value": value,
+		"metadata": map[string]interface{}{
+			"step_id":      req.Step.ID,
+			"execution_id": req.ExecutionID,
+		},
+	}
+}
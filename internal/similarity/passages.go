@@ -0,0 +1,51 @@
+package similarity
+
+import (
+	"strings"
+
+	"github.com/memmieai/memmie-studio/internal/style"
+)
+
+// Passage is a run of whole sentences within one paragraph, with rune
+// offsets into the text it came from
+type Passage struct {
+	Text  string
+	Start int
+	End   int
+	Words int
+}
+
+// Passages splits text into passages of up to maxWords words. Sentences are
+// never split, so a single long sentence may exceed maxWords. Passages
+// shorter than minWords are dropped: headings and one-line exchanges repeat
+// legitimately and would only add noise.
+func Passages(text string, minWords, maxWords int) []Passage {
+	runes := []rune(text)
+	var passages []Passage
+	var current *Passage
+
+	flush := func() {
+		if current != nil && current.Words >= minWords {
+			current.Text = string(runes[current.Start:current.End])
+			passages = append(passages, *current)
+		}
+		current = nil
+	}
+
+	for _, s := range style.Sentences(text) {
+		words := len(s.Words)
+		if current != nil {
+			gap := string(runes[current.End:s.Start])
+			if strings.Contains(gap, "\n\n") || current.Words+words > maxWords {
+				flush()
+			}
+		}
+		if current == nil {
+			current = &Passage{Start: s.Start}
+		}
+		current.End = s.End
+		current.Words += words
+	}
+	flush()
+	return passages
+}
@@ -0,0 +1,341 @@
+// Package similarity finds passages of a chapter that duplicate the rest of
+// its book or a reference corpus, using the embedding index
+package similarity
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/memmieai/memmie-studio/internal/blob"
+	"github.com/memmieai/memmie-studio/internal/books"
+	"github.com/memmieai/memmie-studio/internal/embeddings"
+)
+
+// Defaults for a check
+const (
+	DefaultThreshold = 0.85
+	DefaultMinWords  = 12
+	DefaultMaxWords  = 80
+)
+
+// Source kinds
+const (
+	SourceChapter = "chapter"
+	SourceCorpus  = "corpus"
+)
+
+// Request describes a chapter to check
+type Request struct {
+	UserID    string
+	BookID    string   // chapters of this book are compared; optional
+	ChapterID string   // excluded from the comparison; optional
+	Content   string   // the text to check
+	Corpora   []string // blob namespaces to compare against as well
+	Threshold float64  // minimum cosine similarity to flag
+}
+
+// Source identifies where a matching passage lives
+type Source struct {
+	Kind          string `json:"kind"`
+	BlobID        string `json:"blob_id"`
+	Corpus        string `json:"corpus,omitempty"`
+	Title         string `json:"title,omitempty"`
+	ChapterNumber int    `json:"chapter_number,omitempty"`
+}
+
+// Match is a passage of the checked text that resembles an existing one
+type Match struct {
+	Start       int     `json:"start"`
+	End         int     `json:"end"`
+	Text        string  `json:"text"`
+	Score       float64 `json:"score"`
+	Source      Source  `json:"source"`
+	SourceStart int     `json:"source_start"`
+	SourceEnd   int     `json:"source_end"`
+	SourceText  string  `json:"source_text"`
+}
+
+// Report is the result of a check
+type Report struct {
+	Passages        int     `json:"passages"`
+	Matches         []Match `json:"matches"`
+	DuplicatedWords int     `json:"duplicated_words"`
+	DuplicatedRatio float64 `json:"duplicated_ratio"` // duplicated words / passage words
+	MaxScore        float64 `json:"max_score"`
+}
+
+// Detector compares chapters against their book and reference corpora. The
+// index doubles as a cache: a blob is embedded again only when its content
+// changes.
+type Detector struct {
+	books    *books.Service
+	blobs    blob.Store
+	embedder embeddings.Embedder
+	index    embeddings.Index
+	minWords int
+	maxWords int
+}
+
+// NewDetector creates a detector
+func NewDetector(bookService *books.Service, blobs blob.Store, embedder embeddings.Embedder, index embeddings.Index) *Detector {
+	return &Detector{
+		books:    bookService,
+		blobs:    blobs,
+		embedder: embedder,
+		index:    index,
+		minWords: DefaultMinWords,
+		maxWords: DefaultMaxWords,
+	}
+}
+
+// Check flags passages of the request's content whose closest indexed
+// passage scores at or above the threshold
+func (d *Detector) Check(ctx context.Context, req Request) (*Report, error) {
+	threshold := req.Threshold
+	if threshold <= 0 {
+		threshold = DefaultThreshold
+	}
+
+	var collections []string
+	if req.BookID != "" {
+		collection, err := d.indexBook(ctx, req.UserID, req.BookID)
+		if err != nil {
+			return nil, err
+		}
+		collections = append(collections, collection)
+	}
+	for _, corpus := range req.Corpora {
+		collection, err := d.indexCorpus(ctx, req.UserID, corpus)
+		if err != nil {
+			return nil, err
+		}
+		collections = append(collections, collection)
+	}
+
+	passages := Passages(req.Content, d.minWords, d.maxWords)
+	report := &Report{Passages: len(passages), Matches: []Match{}}
+	if len(passages) == 0 || len(collections) == 0 {
+		return report, nil
+	}
+
+	vectors, err := d.embedder.Embed(ctx, passageTexts(passages))
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed passages: %w", err)
+	}
+
+	totalWords := 0
+	for i, passage := range passages {
+		totalWords += passage.Words
+		best, found, err := d.bestMatch(ctx, collections, vectors[i], req.ChapterID)
+		if err != nil {
+			return nil, err
+		}
+		if !found || best.Score < threshold {
+			continue
+		}
+
+		match := Match{
+			Start:       passage.Start,
+			End:         passage.End,
+			Text:        passage.Text,
+			Score:       round(best.Score),
+			Source:      sourceOf(best.Document),
+			SourceStart: toInt(best.Metadata["start"]),
+			SourceEnd:   toInt(best.Metadata["end"]),
+			SourceText:  best.Text,
+		}
+		report.Matches = append(report.Matches, match)
+		report.DuplicatedWords += passage.Words
+		if match.Score > report.MaxScore {
+			report.MaxScore = match.Score
+		}
+	}
+	if totalWords > 0 {
+		report.DuplicatedRatio = round(float64(report.DuplicatedWords) / float64(totalWords))
+	}
+	return report, nil
+}
+
+// bestMatch searches each collection for the passage most similar to vector,
+// ignoring passages of the excluded blob
+func (d *Detector) bestMatch(ctx context.Context, collections []string, vector []float32, exclude string) (embeddings.Match, bool, error) {
+	var best embeddings.Match
+	found := false
+	for _, collection := range collections {
+		// A few extra results leave room for the excluded chapter's own
+		// passages, which are indexed with the rest of the book
+		matches, err := d.index.Search(ctx, collection, vector, 5)
+		if err != nil {
+			return best, false, fmt.Errorf("failed to search %s: %w", collection, err)
+		}
+		for _, m := range matches {
+			if exclude != "" && m.Source == exclude {
+				continue
+			}
+			if !found || m.Score > best.Score {
+				best, found = m, true
+			}
+			break
+		}
+	}
+	return best, found, nil
+}
+
+// indexBook brings the index up to date with a book's chapters and returns
+// the book's collection
+func (d *Detector) indexBook(ctx context.Context, userID, bookID string) (string, error) {
+	chapters, err := d.books.ListChapters(ctx, userID, bookID)
+	if err != nil {
+		return "", fmt.Errorf("failed to list chapters: %w", err)
+	}
+
+	collection := fmt.Sprintf("user:%s/book:%s", userID, bookID)
+	for _, chapter := range chapters {
+		metadata := map[string]interface{}{
+			"kind":           SourceChapter,
+			"title":          chapter.Title,
+			"chapter_number": chapter.Number,
+		}
+		if err := d.indexText(ctx, collection, chapter.ID, chapter.Content, metadata); err != nil {
+			return "", err
+		}
+	}
+	return collection, nil
+}
+
+// indexCorpus brings the index up to date with the blobs in a namespace and
+// returns the corpus's collection
+func (d *Detector) indexCorpus(ctx context.Context, userID, namespace string) (string, error) {
+	blobs, err := d.blobs.ListBlobs(ctx, userID, blob.Filter{NamespaceID: namespace})
+	if err != nil && !errors.Is(err, blob.ErrNotFound) {
+		return "", fmt.Errorf("failed to list corpus %s: %w", namespace, err)
+	}
+
+	collection := fmt.Sprintf("user:%s/corpus:%s", userID, namespace)
+	for _, b := range blobs {
+		title, _ := b.Metadata["title"].(string)
+		metadata := map[string]interface{}{
+			"kind":   SourceCorpus,
+			"corpus": namespace,
+			"title":  title,
+		}
+		if err := d.indexText(ctx, collection, b.ID, b.Content, metadata); err != nil {
+			return "", err
+		}
+	}
+	return collection, nil
+}
+
+// indexText embeds a blob's passages unless the index already holds them
+// for the same content and metadata
+func (d *Detector) indexText(ctx context.Context, collection, blobID, text string, metadata map[string]interface{}) error {
+	sum := sha256.Sum256([]byte(fmt.Sprint(metadata) + "\n" + text))
+	version := hex.EncodeToString(sum[:])
+	indexed, err := d.index.Version(ctx, collection, blobID)
+	if err != nil {
+		return fmt.Errorf("failed to read index version: %w", err)
+	}
+	if indexed == version {
+		return nil
+	}
+
+	passages := Passages(text, d.minWords, d.maxWords)
+	docs := make([]embeddings.Document, len(passages))
+	if len(passages) > 0 {
+		vectors, err := d.embedder.Embed(ctx, passageTexts(passages))
+		if err != nil {
+			return fmt.Errorf("failed to embed %s: %w", blobID, err)
+		}
+		for i, passage := range passages {
+			docMetadata := make(map[string]interface{}, len(metadata)+2)
+			for k, v := range metadata {
+				docMetadata[k] = v
+			}
+			docMetadata["start"] = passage.Start
+			docMetadata["end"] = passage.End
+			docs[i] = embeddings.Document{
+				ID:       fmt.Sprintf("%s:%d", blobID, passage.Start),
+				Text:     passage.Text,
+				Metadata: docMetadata,
+				Vector:   vectors[i],
+			}
+		}
+	}
+
+	if err := d.index.Replace(ctx, collection, blobID, version, docs); err != nil {
+		return fmt.Errorf("failed to index %s: %w", blobID, err)
+	}
+	return nil
+}
+
+// Map converts a match to the map form used in step outputs
+func (m Match) Map() map[string]interface{} {
+	source := map[string]interface{}{
+		"kind":    m.Source.Kind,
+		"blob_id": m.Source.BlobID,
+	}
+	if m.Source.Corpus != "" {
+		source["corpus"] = m.Source.Corpus
+	}
+	if m.Source.Title != "" {
+		source["title"] = m.Source.Title
+	}
+	if m.Source.ChapterNumber != 0 {
+		source["chapter_number"] = m.Source.ChapterNumber
+	}
+	return map[string]interface{}{
+		"start":        m.Start,
+		"end":          m.End,
+		"text":         m.Text,
+		"score":        m.Score,
+		"source":       source,
+		"source_start": m.SourceStart,
+		"source_end":   m.SourceEnd,
+		"source_text":  m.SourceText,
+	}
+}
+
+// sourceOf describes the blob an indexed passage came from
+func sourceOf(doc embeddings.Document) Source {
+	kind, _ := doc.Metadata["kind"].(string)
+	corpus, _ := doc.Metadata["corpus"].(string)
+	title, _ := doc.Metadata["title"].(string)
+	return Source{
+		Kind:          kind,
+		BlobID:        doc.Source,
+		Corpus:        corpus,
+		Title:         title,
+		ChapterNumber: toInt(doc.Metadata["chapter_number"]),
+	}
+}
+
+// passageTexts returns the text of each passage
+func passageTexts(passages []Passage) []string {
+	texts := make([]string, len(passages))
+	for i, p := range passages {
+		texts[i] = p.Text
+	}
+	return texts
+}
+
+// toInt converts a JSON number to an int
+func toInt(value interface{}) int {
+	switch v := value.(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	}
+	return 0
+}
+
+// round rounds to three decimal places
+func round(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
@@ -0,0 +1,89 @@
+package similarity
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// StepType is the step type the similarity executor is registered under
+const StepType = "similarity_check"
+
+// Delta paths written by the similarity step
+const (
+	SummaryPath     = "metadata.similarity"
+	AnnotationsPath = "metadata.annotations.similarity"
+)
+
+// NewStepExecutor creates the duplicate passage executor. Step inputs:
+// content, book_id, chapter_id, corpora (blob namespaces, as a list or
+// comma-separated) and threshold; corpora and threshold fall back to the
+// step's parameters. Matches are reported as metadata deltas for the author
+// to review; the chapter text is not changed.
+func NewStepExecutor(detector *Detector) workflows.StepExecutor {
+	return workflows.StepExecutorFunc(func(ctx context.Context, req workflows.StepRequest) (map[string]interface{}, error) {
+		content, _ := req.Input["content"].(string)
+		if content == "" {
+			return nil, fmt.Errorf("content is required")
+		}
+		bookID, _ := req.Input["book_id"].(string)
+		corpora := req.StringList("corpora")
+		if bookID == "" && len(corpora) == 0 {
+			return nil, fmt.Errorf("book_id or corpora are required")
+		}
+		chapterID, _ := req.Input["chapter_id"].(string)
+		if chapterID == "" {
+			chapterID = req.Context.BlobID
+		}
+		threshold, _ := req.Setting("threshold").(float64)
+
+		report, err := detector.Check(ctx, Request{
+			UserID:    req.Context.UserID,
+			BookID:    bookID,
+			ChapterID: chapterID,
+			Content:   content,
+			Corpora:   corpora,
+			Threshold: threshold,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		matches := make([]interface{}, len(report.Matches))
+		for i, m := range report.Matches {
+			matches[i] = m.Map()
+		}
+		summary := map[string]interface{}{
+			"passages":         report.Passages,
+			"flagged":          len(report.Matches),
+			"duplicated_words": report.DuplicatedWords,
+			"duplicated_ratio": report.DuplicatedRatio,
+			"max_score":        report.MaxScore,
+		}
+		deltaMetadata := map[string]interface{}{
+			"step_id":      req.Step.ID,
+			"execution_id": req.ExecutionID,
+		}
+
+		return map[string]interface{}{
+			"flagged": len(report.Matches) > 0,
+			"matches": matches,
+			"summary": summary,
+			"deltas": []interface{}{
+				map[string]interface{}{
+					"type":      "update",
+					"path":      SummaryPath,
+					"new_value": summary,
+					"metadata":  deltaMetadata,
+				},
+				map[string]interface{}{
+					"type":      "update",
+					"path":      AnnotationsPath,
+					"new_value": matches,
+					"metadata":  deltaMetadata,
+				},
+			},
+		}, nil
+	})
+}
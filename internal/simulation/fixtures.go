@@ -0,0 +1,189 @@
+// Package simulation runs workflow DAGs against stubbed step outputs, so
+// conditions, input mappings and failure handling can be checked locally
+// without calling any provider.
+package simulation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// Fixtures is a set of simulation cases for one workflow
+type Fixtures struct {
+	// Workflow is the path of the workflow definition, relative to the
+	// fixtures file. It may be left out when the definition is given
+	// separately.
+	Workflow string `json:"workflow,omitempty" yaml:"workflow,omitempty"`
+	Cases    []Case `json:"cases" yaml:"cases"`
+}
+
+// Case is one simulated run: the workflow input, the stubbed output of each
+// step and what the run is expected to do. Recorded cases come from a
+// Recorder; their stubs cover exactly the steps that ran, so any other step
+// is expected not to run.
+type Case struct {
+	Name     string                 `json:"name" yaml:"name"`
+	Recorded bool                   `json:"recorded,omitempty" yaml:"recorded,omitempty"`
+	Input    map[string]interface{} `json:"input,omitempty" yaml:"input,omitempty"`
+	Context  Context                `json:"context,omitempty" yaml:"context,omitempty"`
+	// Stubs are keyed by step ID. Providers stub every step of a provider
+	// that has no stub of its own.
+	Stubs     map[string]Stub `json:"stubs,omitempty" yaml:"stubs,omitempty"`
+	Providers map[string]Stub `json:"providers,omitempty" yaml:"providers,omitempty"`
+	Expect    Expect          `json:"expect,omitempty" yaml:"expect,omitempty"`
+}
+
+// Context is the execution context of a simulated run
+type Context struct {
+	ExecutionID string `json:"execution_id,omitempty" yaml:"execution_id,omitempty"`
+	RequestID   string `json:"request_id,omitempty" yaml:"request_id,omitempty"`
+	UserID      string `json:"user_id,omitempty" yaml:"user_id,omitempty"`
+	ProviderID  string `json:"provider_id,omitempty" yaml:"provider_id,omitempty"`
+	BlobID      string `json:"blob_id,omitempty" yaml:"blob_id,omitempty"`
+}
+
+// Stub is the canned result of a step. A step fails with Error when it is
+// set; otherwise it fails its first FailTimes attempts, which exercises
+// retries, and then returns Output.
+type Stub struct {
+	Output    map[string]interface{} `json:"output,omitempty" yaml:"output,omitempty"`
+	Error     string                 `json:"error,omitempty" yaml:"error,omitempty"`
+	FailTimes int                    `json:"fail_times,omitempty" yaml:"fail_times,omitempty"`
+}
+
+// Expect lists the checks made after a case runs. Status defaults to
+// completed. Steps maps step IDs to completed, skipped, failed or not_run;
+// Inputs maps step IDs to the input values they must have received; Values
+// maps scope paths such as $.steps.classify.output.label to their values.
+// Order lists steps in the order they ran; a run passes when no step of the
+// list falls in an earlier DAG level than a step before it.
+type Expect struct {
+	Status string                            `json:"status,omitempty" yaml:"status,omitempty"`
+	Order  []string                          `json:"order,omitempty" yaml:"order,omitempty"`
+	Steps  map[string]string                 `json:"steps,omitempty" yaml:"steps,omitempty"`
+	Inputs map[string]map[string]interface{} `json:"inputs,omitempty" yaml:"inputs,omitempty"`
+	Values map[string]interface{}            `json:"values,omitempty" yaml:"values,omitempty"`
+}
+
+// LoadFixtures reads a YAML or JSON fixtures file, or every fixtures file
+// in a directory, such as the recordings of one workflow
+func LoadFixtures(path string) (*Fixtures, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixtures: %w", err)
+	}
+	if !info.IsDir() {
+		return loadFixturesFile(path)
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixtures: %w", err)
+	}
+	merged := &Fixtures{}
+	for _, entry := range entries {
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".yaml", ".yml", ".json":
+		default:
+			continue
+		}
+		if entry.IsDir() {
+			continue
+		}
+		fixtures, err := loadFixturesFile(filepath.Join(path, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if fixtures.Workflow != "" {
+			if merged.Workflow != "" && merged.Workflow != fixtures.Workflow {
+				return nil, fmt.Errorf("fixtures in %s name different workflows: %s and %s", path, merged.Workflow, fixtures.Workflow)
+			}
+			merged.Workflow = fixtures.Workflow
+		}
+		merged.Cases = append(merged.Cases, fixtures.Cases...)
+	}
+	return merged, nil
+}
+
+// SaveFixtures writes fixtures as YAML
+func SaveFixtures(path string, fixtures *Fixtures) error {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(fixtures); err != nil {
+		return fmt.Errorf("failed to encode fixtures: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create fixtures directory: %w", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write fixtures: %w", err)
+	}
+	return nil
+}
+
+// loadFixturesFile reads one fixtures file
+func loadFixturesFile(path string) (*Fixtures, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixtures: %w", err)
+	}
+	var fixtures Fixtures
+	if err := decode(path, data, &fixtures); err != nil {
+		return nil, fmt.Errorf("failed to parse fixtures %s: %w", path, err)
+	}
+	if fixtures.Workflow != "" && !filepath.IsAbs(fixtures.Workflow) {
+		fixtures.Workflow = filepath.Join(filepath.Dir(path), fixtures.Workflow)
+	}
+	for i := range fixtures.Cases {
+		if fixtures.Cases[i].Name == "" {
+			fixtures.Cases[i].Name = fmt.Sprintf("case %d", i+1)
+		}
+	}
+	return &fixtures, nil
+}
+
+// LoadWorkflow reads a workflow definition, either in the YAML format of the
+// workflows directory or as the JSON the workflows API accepts
+func LoadWorkflow(path string) (*workflows.BlobProcessingWorkflow, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workflow: %w", err)
+	}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		var workflow workflows.BlobProcessingWorkflow
+		if err := json.Unmarshal(data, &workflow); err != nil {
+			return nil, fmt.Errorf("failed to parse workflow %s: %w", path, err)
+		}
+		return &workflow, nil
+	}
+	workflow, err := workflows.ParseYAMLWorkflow(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse workflow %s: %w", path, err)
+	}
+	return workflow, nil
+}
+
+// decode reads YAML or JSON by file extension and passes the result through
+// JSON, so numbers compare the way they do in a real run
+func decode(path string, data []byte, v interface{}) error {
+	if !strings.EqualFold(filepath.Ext(path), ".json") {
+		var raw interface{}
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			return err
+		}
+		var err error
+		if data, err = json.Marshal(raw); err != nil {
+			return err
+		}
+	}
+	return json.Unmarshal(data, v)
+}
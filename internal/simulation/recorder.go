@@ -0,0 +1,205 @@
+package simulation
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// Recorder captures the steps of real executions as recorded cases, one
+// fixtures file per execution under <dir>/<workflow_id>/, so they can be
+// replayed with Run as golden tests. Each step's output becomes its stub
+// and its input an expectation; the workflow input is rebuilt from the
+// $.input, $.blob and $.provider.config paths the steps read. Files are
+// rewritten as steps finish, and an execution recorded by an earlier
+// process is continued. Executions are kept in memory, so the recorder is
+// meant for development workers rather than production ones.
+type Recorder struct {
+	dir     string
+	onError func(error)
+
+	mu         sync.Mutex
+	executions map[string]*Case
+}
+
+// NewRecorder creates a recorder writing under dir. Recording never fails a
+// step; onError, if set, is told when a recording cannot be written.
+func NewRecorder(dir string, onError func(error)) *Recorder {
+	return &Recorder{
+		dir:        dir,
+		onError:    onError,
+		executions: make(map[string]*Case),
+	}
+}
+
+// Wrap returns an executor that runs executor and records the step
+func (r *Recorder) Wrap(executor workflows.StepExecutor) workflows.StepExecutor {
+	return workflows.StepExecutorFunc(func(ctx context.Context, req workflows.StepRequest) (map[string]interface{}, error) {
+		output, err := executor.Execute(ctx, req)
+		if recordErr := r.record(req, output, err); recordErr != nil && r.onError != nil {
+			r.onError(recordErr)
+		}
+		return output, err
+	})
+}
+
+// Path returns the file an execution is recorded in
+func (r *Recorder) Path(workflowID, executionID string) string {
+	return filepath.Join(r.dir, fileName(workflowID), fileName(executionID)+".yaml")
+}
+
+// record adds one attempt of a step to its execution's case and saves it
+func (r *Recorder) record(req workflows.StepRequest, output map[string]interface{}, stepErr error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	path := r.Path(req.WorkflowID, req.ExecutionID)
+	c, ok := r.executions[req.ExecutionID]
+	if !ok {
+		c = r.resume(path)
+		if c == nil {
+			c = &Case{
+				Name:     req.ExecutionID,
+				Recorded: true,
+				Input:    map[string]interface{}{},
+				Context: Context{
+					ExecutionID: req.ExecutionID,
+					RequestID:   req.Context.RequestID,
+					UserID:      req.Context.UserID,
+					ProviderID:  req.Context.ProviderID,
+					BlobID:      req.Context.BlobID,
+				},
+			}
+		}
+		r.executions[req.ExecutionID] = c
+	}
+	if c.Stubs == nil {
+		c.Stubs = map[string]Stub{}
+	}
+	if c.Expect.Steps == nil {
+		c.Expect.Steps = map[string]string{}
+	}
+	if c.Expect.Inputs == nil {
+		c.Expect.Inputs = map[string]map[string]interface{}{}
+	}
+
+	step := req.Step
+	stub, seen := c.Stubs[step.ID]
+	if !seen {
+		c.Expect.Order = append(c.Expect.Order, step.ID)
+	}
+	input := normalize(req.Input)
+	c.Expect.Inputs[step.ID] = input
+	if input != nil {
+		rebuildInput(c.Input, step.InputMap, input, req.Context.BlobID)
+	}
+
+	// A step that fails the run fails it until a retry succeeds
+	fatal := step.OnFailure != "skip" && step.OnFailure != "continue"
+	if stepErr != nil {
+		stub.Error = stepErr.Error()
+		stub.FailTimes++
+		c.Expect.Steps[step.ID] = StatusFailed
+		if fatal {
+			c.Expect.Status = StatusFailed
+		}
+	} else {
+		if fatal && stub.Error != "" {
+			c.Expect.Status = ""
+		}
+		stub.Error = ""
+		stub.Output = normalize(output)
+		c.Expect.Steps[step.ID] = StatusCompleted
+	}
+	c.Stubs[step.ID] = stub
+
+	return SaveFixtures(path, &Fixtures{Cases: []Case{*c}})
+}
+
+// resume loads an execution recorded before this process started
+func (r *Recorder) resume(path string) *Case {
+	if _, err := os.Stat(path); err != nil {
+		return nil
+	}
+	fixtures, err := loadFixturesFile(path)
+	if err != nil || len(fixtures.Cases) != 1 {
+		return nil
+	}
+	return &fixtures.Cases[0]
+}
+
+// rebuildInput writes the input values a step received back to the paths of
+// the workflow input they were mapped from
+func rebuildInput(workflowInput, inputMap, input map[string]interface{}, blobID string) {
+	for key, mapping := range inputMap {
+		value, ok := input[key]
+		if !ok || value == nil {
+			continue
+		}
+		switch m := mapping.(type) {
+		case string:
+			setInputPath(workflowInput, m, value, blobID)
+		case map[string]interface{}:
+			if _, isSwitch := m["switch"]; isSwitch {
+				continue
+			}
+			if nested, ok := value.(map[string]interface{}); ok {
+				rebuildInput(workflowInput, m, nested, blobID)
+			}
+		}
+	}
+}
+
+// setInputPath sets a value in the workflow input for a scope path that
+// resolves against it
+func setInputPath(workflowInput map[string]interface{}, path string, value interface{}, blobID string) {
+	var segments []string
+	switch {
+	case path == "$.input":
+		if m, ok := value.(map[string]interface{}); ok {
+			for k, v := range m {
+				workflowInput[k] = v
+			}
+		}
+		return
+	case strings.HasPrefix(path, "$.input."):
+		segments = strings.Split(strings.TrimPrefix(path, "$.input."), ".")
+	case strings.HasPrefix(path, "$.blob."):
+		if _, ok := workflowInput["blob"].(map[string]interface{}); !ok {
+			workflowInput["blob"] = map[string]interface{}{"id": blobID}
+		}
+		segments = append([]string{"blob"}, strings.Split(strings.TrimPrefix(path, "$.blob."), ".")...)
+	case strings.HasPrefix(path, "$.provider.config."):
+		segments = append([]string{"parameters"}, strings.Split(strings.TrimPrefix(path, "$.provider.config."), ".")...)
+	default:
+		return
+	}
+
+	node := workflowInput
+	for _, segment := range segments[:len(segments)-1] {
+		next, ok := node[segment].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			node[segment] = next
+		}
+		node = next
+	}
+	node[segments[len(segments)-1]] = value
+}
+
+// fileName makes an ID safe to use as a file name
+func fileName(id string) string {
+	if id == "" {
+		return "unknown"
+	}
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' || r == 0 {
+			return '_'
+		}
+		return r
+	}, id)
+}
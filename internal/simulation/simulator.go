@@ -0,0 +1,417 @@
+package simulation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// Step and run statuses
+const (
+	StatusCompleted = "completed"
+	StatusSkipped   = "skipped"
+	StatusFailed    = "failed"
+	StatusNotRun    = "not_run"
+)
+
+// stepTypeDelay steps are timers in the backends and complete with no output
+const stepTypeDelay = "delay"
+
+// Report is the result of simulating one case
+type Report struct {
+	Case     string                 `json:"case"`
+	Workflow string                 `json:"workflow"`
+	Status   string                 `json:"status"`
+	Error    string                 `json:"error,omitempty"`
+	Steps    []StepTrace            `json:"steps"`
+	Output   map[string]interface{} `json:"output"`
+	Warnings []string               `json:"warnings,omitempty"`
+	Checks   []Check                `json:"checks,omitempty"`
+}
+
+// StepTrace records what happened to one step
+type StepTrace struct {
+	ID         string                 `json:"id"`
+	Type       string                 `json:"type,omitempty"`
+	Level      int                    `json:"level"`
+	Status     string                 `json:"status"`
+	Reason     string                 `json:"reason,omitempty"`
+	Condition  string                 `json:"condition,omitempty"`
+	Input      map[string]interface{} `json:"input,omitempty"`
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+	Output     map[string]interface{} `json:"output,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+	Attempts   int                    `json:"attempts,omitempty"`
+	// Unresolved lists the input paths that did not resolve, which run as nil
+	Unresolved []string `json:"unresolved,omitempty"`
+}
+
+// Check is one expectation and whether the run met it
+type Check struct {
+	Name     string      `json:"name"`
+	Passed   bool        `json:"passed"`
+	Expected interface{} `json:"expected"`
+	Actual   interface{} `json:"actual"`
+}
+
+// Passed reports whether every check passed
+func (r *Report) Passed() bool {
+	for _, check := range r.Checks {
+		if !check.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// Run simulates a case against a workflow. It follows the Temporal backend:
+// steps run level by level, unmet conditions skip a step, input mappings and
+// parameters resolve against the scope, retries follow the step's policy and
+// a failure fails the run unless the step is marked skip or continue. Stubs
+// stand in for every step, so nothing outside the process is called.
+//
+// An error means the workflow could not run at all; failed steps and failed
+// runs are reported.
+func Run(workflow *workflows.BlobProcessingWorkflow, c Case) (*Report, error) {
+	levels, err := workflow.GetDAGOrder()
+	if err != nil {
+		return nil, fmt.Errorf("invalid workflow %s: %w", workflow.ID, err)
+	}
+
+	req := workflows.ExecutionRequest{
+		WorkflowID: workflow.ID,
+		Input:      normalize(c.Input),
+		Context: workflows.ExecutionContext{
+			UserID:     c.Context.UserID,
+			ProviderID: c.Context.ProviderID,
+			BlobID:     c.Context.BlobID,
+			RequestID:  c.Context.RequestID,
+		},
+	}
+	if req.Context.ProviderID == "" {
+		req.Context.ProviderID = workflow.ProviderID
+	}
+	executionID := c.Context.ExecutionID
+	if executionID == "" {
+		executionID = "simulation"
+	}
+	scope := workflows.NewExecutionScope(executionID, req)
+
+	upstream := ancestors(workflow)
+	report := &Report{Case: c.Name, Workflow: workflow.ID, Status: StatusCompleted}
+	var order []string
+	for levelIdx, level := range levels {
+		if report.Status == StatusFailed {
+			for _, step := range level {
+				report.Steps = append(report.Steps, StepTrace{ID: step.ID, Type: step.Type, Level: levelIdx, Status: StatusNotRun})
+			}
+			continue
+		}
+
+		for _, step := range level {
+			order = append(order, step.ID)
+			trace := StepTrace{ID: step.ID, Type: step.Type, Level: levelIdx, Condition: step.Condition}
+
+			ok, err := scope.Evaluate(step.Condition)
+			if err != nil {
+				return nil, fmt.Errorf("step %s: invalid condition: %w", step.ID, err)
+			}
+			if !ok {
+				scope.SetStepSkipped(step.ID, "condition not met")
+				trace.Status, trace.Reason = StatusSkipped, "condition not met"
+				report.Steps = append(report.Steps, trace)
+				continue
+			}
+
+			if step.Type == stepTypeDelay {
+				scope.SetStepOutput(step.ID, map[string]interface{}{})
+				trace.Status = StatusCompleted
+				report.Steps = append(report.Steps, trace)
+				continue
+			}
+
+			trace.Parameters, trace.Input = scope.SelectStep(step)
+			trace.Unresolved = unresolved(scope, step.InputMap)
+			for _, path := range trace.Unresolved {
+				if dep := stepOf(path); dep != "" && !upstream[step.ID][dep] {
+					report.warn("step %s: input path %s reads step %s, which it does not depend on", step.ID, path, dep)
+					continue
+				}
+				report.warn("step %s: input path %s did not resolve", step.ID, path)
+			}
+
+			output, tries, err := run(step, c, attempts(step, workflow.Config))
+			trace.Attempts = tries
+			if err != nil {
+				trace.Status, trace.Error = StatusFailed, err.Error()
+				if errors.Is(err, errNoStub) {
+					report.warn("step %s has no stub and was failed", step.ID)
+				}
+				if step.OnFailure == "skip" || step.OnFailure == "continue" {
+					scope.SetStepFailed(step.ID, err)
+				} else if report.Status != StatusFailed {
+					report.Status = StatusFailed
+					report.Error = fmt.Sprintf("step %s failed: %v", step.ID, err)
+				}
+				report.Steps = append(report.Steps, trace)
+				continue
+			}
+
+			for _, key := range sortedKeys(step.OutputMap) {
+				if path, ok := step.OutputMap[key].(string); ok && !hasPath(output, path) {
+					report.warn("step %s: output_map %s reads %s, which the stub output does not have", step.ID, key, path)
+				}
+			}
+			scope.SetStepOutput(step.ID, output)
+			trace.Status, trace.Output = StatusCompleted, output
+			report.Steps = append(report.Steps, trace)
+		}
+	}
+
+	if report.Status == StatusCompleted {
+		report.Output = scope.Output(order)
+	}
+	report.check(scope, c)
+	return report, nil
+}
+
+// errNoStub fails a step that the case has no stub for
+var errNoStub = errors.New("no stub for this step")
+
+// run plays a step's stub over its attempts
+func run(step workflows.BlobProcessingStep, c Case, maxAttempts int) (map[string]interface{}, int, error) {
+	stub, ok := c.Stubs[step.ID]
+	if !ok {
+		if stub, ok = c.Providers[step.ProviderID]; !ok {
+			return nil, 1, errNoStub
+		}
+	}
+	if stub.Error != "" {
+		err := errors.New(stub.Error)
+		if !workflows.IsRetryable(err) {
+			// Backends do not retry permanent errors
+			return nil, 1, err
+		}
+		return nil, maxAttempts, err
+	}
+	if stub.FailTimes >= maxAttempts {
+		return nil, maxAttempts, fmt.Errorf("failed %d of %d attempts", maxAttempts, maxAttempts)
+	}
+	output := normalize(stub.Output)
+	if output == nil {
+		output = map[string]interface{}{}
+	}
+	return output, stub.FailTimes + 1, nil
+}
+
+// attempts is how many times a backend tries a step: its retry policy, else
+// its retries plus one, else three when the workflow retries automatically
+func attempts(step workflows.BlobProcessingStep, config workflows.ProcessingConfig) int {
+	switch {
+	case step.RetryPolicy != nil && step.RetryPolicy.MaxAttempts > 0:
+		return step.RetryPolicy.MaxAttempts
+	case step.Config.MaxRetries > 0:
+		return step.Config.MaxRetries + 1
+	case config.AutoRetry:
+		return 3
+	}
+	return 1
+}
+
+// unresolved returns the $. paths of an input mapping that the scope does
+// not have, sorted
+func unresolved(scope workflows.ExecutionScope, inputMap map[string]interface{}) []string {
+	var paths []string
+	var walk func(value interface{})
+	walk = func(value interface{}) {
+		switch v := value.(type) {
+		case string:
+			if _, ok := scope.Lookup(v); strings.HasPrefix(v, "$.") && !ok {
+				paths = append(paths, v)
+			}
+		case map[string]interface{}:
+			for _, item := range v {
+				walk(item)
+			}
+		case []interface{}:
+			for _, item := range v {
+				walk(item)
+			}
+		}
+	}
+	walk(inputMap)
+	sort.Strings(paths)
+	return paths
+}
+
+// stepOf returns the step a $.steps path reads, if any
+func stepOf(path string) string {
+	rest := strings.TrimPrefix(path, "$.steps.")
+	if rest == path {
+		return ""
+	}
+	return strings.SplitN(rest, ".", 2)[0]
+}
+
+// ancestors maps each step to every step it depends on, directly or not
+func ancestors(workflow *workflows.BlobProcessingWorkflow) map[string]map[string]bool {
+	deps := make(map[string][]string, len(workflow.Steps))
+	for _, step := range workflow.Steps {
+		deps[step.ID] = step.Dependencies
+	}
+	result := make(map[string]map[string]bool, len(deps))
+	var visit func(id string) map[string]bool
+	visit = func(id string) map[string]bool {
+		if set, ok := result[id]; ok {
+			return set
+		}
+		set := map[string]bool{}
+		result[id] = set
+		for _, dep := range deps[id] {
+			set[dep] = true
+			for upstream := range visit(dep) {
+				set[upstream] = true
+			}
+		}
+		return set
+	}
+	for id := range deps {
+		visit(id)
+	}
+	return result
+}
+
+// hasPath reports whether an output_map path, which is relative to the step
+// output, is present
+func hasPath(output map[string]interface{}, path string) bool {
+	if !strings.HasPrefix(path, "$.") {
+		return true
+	}
+	_, ok := workflows.ExecutionScope(output).Lookup(path)
+	return ok
+}
+
+// check records the case's expectations against the run
+func (r *Report) check(scope workflows.ExecutionScope, c Case) {
+	expect := c.Expect
+	status := expect.Status
+	if status == "" {
+		status = StatusCompleted
+	}
+	r.addCheck("status", status, r.Status)
+
+	traces := make(map[string]StepTrace, len(r.Steps))
+	for _, trace := range r.Steps {
+		traces[trace.ID] = trace
+	}
+	for _, id := range sortedKeys(expect.Steps) {
+		actual, ok := traces[id]
+		if !ok {
+			r.addCheck("step "+id, expect.Steps[id], nil)
+			continue
+		}
+		r.addCheck("step "+id, expect.Steps[id], actual.Status)
+	}
+	if c.Recorded {
+		// Steps that did not run when the case was recorded have no stub
+		for _, trace := range r.Steps {
+			if _, ok := c.Stubs[trace.ID]; ok || trace.Type == stepTypeDelay {
+				continue
+			}
+			if trace.Status == StatusCompleted || trace.Status == StatusFailed {
+				r.addCheck("step "+trace.ID, StatusSkipped, trace.Status)
+			}
+		}
+	}
+	if len(expect.Order) > 0 {
+		r.checkOrder(traces, expect.Order)
+	}
+	for _, id := range sortedKeys(expect.Inputs) {
+		input := normalize(traces[id].Input)
+		for _, key := range sortedKeys(expect.Inputs[id]) {
+			r.addCheck(fmt.Sprintf("input %s.%s", id, key), expect.Inputs[id][key], input[key])
+		}
+	}
+	for _, path := range sortedKeys(expect.Values) {
+		actual, _ := scope.Lookup(path)
+		r.addCheck("value "+path, expect.Values[path], actual)
+	}
+}
+
+// checkOrder checks that the steps can still run in the expected order: a
+// step may share a DAG level with the step before it but not come earlier
+func (r *Report) checkOrder(traces map[string]StepTrace, order []string) {
+	var actual []string
+	for _, trace := range r.Steps {
+		if trace.Status == StatusCompleted || trace.Status == StatusFailed {
+			actual = append(actual, trace.ID)
+		}
+	}
+	passed := true
+	level := -1
+	for _, id := range order {
+		trace, ok := traces[id]
+		if !ok {
+			continue
+		}
+		if trace.Level < level {
+			passed = false
+			break
+		}
+		level = trace.Level
+	}
+	r.Checks = append(r.Checks, Check{Name: "order", Passed: passed, Expected: order, Actual: actual})
+}
+
+// addCheck records one expectation
+func (r *Report) addCheck(name string, expected, actual interface{}) {
+	expected, actual = normalizeValue(expected), normalizeValue(actual)
+	r.Checks = append(r.Checks, Check{
+		Name:     name,
+		Passed:   reflect.DeepEqual(expected, actual),
+		Expected: expected,
+		Actual:   actual,
+	})
+}
+
+// warn records something that would likely misbehave in a real run
+func (r *Report) warn(format string, args ...interface{}) {
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, args...))
+}
+
+// normalize passes a map through JSON, as values are between real steps
+func normalize(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	normalized, _ := normalizeValue(m).(map[string]interface{})
+	return normalized
+}
+
+// normalizeValue passes a value through JSON
+func normalizeValue(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return v
+	}
+	return normalized
+}
+
+// sortedKeys returns the keys of a string-keyed map in order
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	for _, key := range reflect.ValueOf(m).MapKeys() {
+		keys = append(keys, key.String())
+	}
+	sort.Strings(keys)
+	return keys
+}
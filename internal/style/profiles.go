@@ -0,0 +1,85 @@
+package style
+
+import "fmt"
+
+// Profile is the set of targets for a writing style. Zero values leave a
+// target unchecked.
+type Profile struct {
+	MinAverageSentence float64 // words
+	MaxAverageSentence float64 // words
+	MinSentenceStdDev  float64 // sentence variety, in words
+	MaxPassiveRatio    float64
+	MaxAdverbRatio     float64
+	MinGrade           float64 // Flesch-Kincaid
+	MaxGrade           float64 // Flesch-Kincaid
+	LongSentence       int     // sentences above this many words are flagged
+}
+
+// Profiles are the writing styles offered by the book writer provider's
+// writing_style setting
+var Profiles = map[string]Profile{
+	"descriptive": {
+		MinAverageSentence: 14,
+		MaxAverageSentence: 26,
+		MinSentenceStdDev:  5,
+		MaxPassiveRatio:    0.2,
+		MaxAdverbRatio:     0.04,
+		LongSentence:       40,
+	},
+	"concise": {
+		MaxAverageSentence: 16,
+		MaxPassiveRatio:    0.1,
+		MaxAdverbRatio:     0.02,
+		MaxGrade:           9,
+		LongSentence:       25,
+	},
+	"poetic": {
+		MinSentenceStdDev: 6,
+		MaxPassiveRatio:   0.25,
+		MaxAdverbRatio:    0.05,
+		LongSentence:      45,
+	},
+	"technical": {
+		MaxAverageSentence: 24,
+		MaxPassiveRatio:    0.3,
+		MaxAdverbRatio:     0.03,
+		MinGrade:           10,
+		MaxGrade:           16,
+		LongSentence:       35,
+	},
+}
+
+// check measures a report against the profile's targets
+func (p Profile) check(name string, r *Report) *Adherence {
+	var checks []Check
+	atLeast := func(metric string, value, min float64) {
+		if min > 0 {
+			checks = append(checks, Check{Name: metric, Value: value, Target: fmt.Sprintf(">= %g", min), Passed: value >= min})
+		}
+	}
+	atMost := func(metric string, value, max float64) {
+		if max > 0 {
+			checks = append(checks, Check{Name: metric, Value: value, Target: fmt.Sprintf("<= %g", max), Passed: value <= max})
+		}
+	}
+
+	atLeast("average_sentence", r.Sentences.Average, p.MinAverageSentence)
+	atMost("average_sentence", r.Sentences.Average, p.MaxAverageSentence)
+	atLeast("sentence_variety", r.Sentences.StdDev, p.MinSentenceStdDev)
+	atMost("passive_ratio", r.PassiveRatio, p.MaxPassiveRatio)
+	atMost("adverb_ratio", r.AdverbRatio, p.MaxAdverbRatio)
+	atLeast("grade_level", r.Readability.FleschKincaidGrade, p.MinGrade)
+	atMost("grade_level", r.Readability.FleschKincaidGrade, p.MaxGrade)
+
+	adherence := &Adherence{Style: name, Checks: checks}
+	passed := 0
+	for _, c := range checks {
+		if c.Passed {
+			passed++
+		}
+	}
+	if len(checks) > 0 {
+		adherence.Score = round(float64(passed) / float64(len(checks)))
+	}
+	return adherence
+}
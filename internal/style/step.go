@@ -0,0 +1,81 @@
+package style
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// StepType is the step type the analysis executor is registered under
+const StepType = "style_analysis"
+
+// Delta paths written by the analysis step
+const (
+	SummaryPath     = "metadata.style"
+	AnnotationsPath = "metadata.annotations.style"
+)
+
+// NewStepExecutor creates the style analysis executor. Step inputs: content,
+// style (the provider's writing_style) and max_annotations; style and
+// max_annotations fall back to the step's parameters. The output carries the
+// full report and metadata deltas for the summary and annotations, so the
+// analyzed text itself is never changed.
+func NewStepExecutor() workflows.StepExecutor {
+	return workflows.StepExecutorFunc(func(ctx context.Context, req workflows.StepRequest) (map[string]interface{}, error) {
+		content, _ := req.Input["content"].(string)
+		if content == "" {
+			return nil, fmt.Errorf("content is required")
+		}
+		styleName, _ := req.Setting("style").(string)
+		maxAnnotations := toInt(req.Setting("max_annotations"))
+
+		report := Analyze(content, styleName, maxAnnotations)
+
+		annotations := make([]interface{}, len(report.Annotations))
+		for i, a := range report.Annotations {
+			annotations[i] = map[string]interface{}{
+				"kind":    a.Kind,
+				"start":   a.Start,
+				"end":     a.End,
+				"text":    a.Text,
+				"message": a.Message,
+			}
+		}
+		deltaMetadata := map[string]interface{}{
+			"step_id":      req.Step.ID,
+			"execution_id": req.ExecutionID,
+		}
+
+		return map[string]interface{}{
+			"report": report,
+			"deltas": []interface{}{
+				map[string]interface{}{
+					"type":      "update",
+					"path":      SummaryPath,
+					"new_value": report.Summary(),
+					"metadata":  deltaMetadata,
+				},
+				map[string]interface{}{
+					"type":      "update",
+					"path":      AnnotationsPath,
+					"new_value": annotations,
+					"metadata":  deltaMetadata,
+				},
+			},
+		}, nil
+	})
+}
+
+// toInt converts a JSON number to an int
+func toInt(value interface{}) int {
+	switch v := value.(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	}
+	return 0
+}
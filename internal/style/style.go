@@ -0,0 +1,288 @@
+// Package style scores prose for readability and adherence to a writing
+// style, and flags sentences worth revising. It runs in-process with no
+// model calls.
+package style
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Annotation kinds
+const (
+	KindLongSentence = "long_sentence"
+	KindPassiveVoice = "passive_voice"
+	KindAdverbs      = "adverb_heavy"
+)
+
+// DefaultLongSentence is the word count above which a sentence is flagged
+// when the style sets no limit
+const DefaultLongSentence = 30
+
+// Readability holds the standard readability formulas
+type Readability struct {
+	FleschReadingEase  float64 `json:"flesch_reading_ease"`
+	FleschKincaidGrade float64 `json:"flesch_kincaid_grade"`
+	GunningFog         float64 `json:"gunning_fog"`
+}
+
+// SentenceStats describes sentence lengths in words
+type SentenceStats struct {
+	Count        int            `json:"count"`
+	Words        int            `json:"words"`
+	Average      float64        `json:"average_length"`
+	StdDev       float64        `json:"stddev"`
+	Longest      int            `json:"longest"`
+	Distribution map[string]int `json:"distribution"` // length bucket -> sentences
+}
+
+// Annotation flags a span of the analyzed text. Offsets count characters
+// (runes), not bytes.
+type Annotation struct {
+	Kind    string `json:"kind"`
+	Start   int    `json:"start"`
+	End     int    `json:"end"`
+	Text    string `json:"text"`
+	Message string `json:"message"`
+}
+
+// Check is one style rule and how the text measured against it
+type Check struct {
+	Name   string  `json:"name"`
+	Value  float64 `json:"value"`
+	Target string  `json:"target"`
+	Passed bool    `json:"passed"`
+}
+
+// Adherence scores the text against a writing style profile
+type Adherence struct {
+	Style  string  `json:"style"`
+	Score  float64 `json:"score"` // fraction of checks passed
+	Checks []Check `json:"checks"`
+}
+
+// Report is the result of analyzing a text
+type Report struct {
+	Readability  Readability   `json:"readability"`
+	Sentences    SentenceStats `json:"sentences"`
+	PassiveRatio float64       `json:"passive_ratio"` // passive sentences / sentences
+	AdverbRatio  float64       `json:"adverb_ratio"`  // -ly adverbs / words
+	Adherence    *Adherence    `json:"adherence,omitempty"`
+	Annotations  []Annotation  `json:"annotations"`
+}
+
+// Analyze scores text and checks it against the named style, which may be
+// empty or unknown to skip the adherence check. At most maxAnnotations
+// spans are flagged; 0 means no limit.
+func Analyze(text, styleName string, maxAnnotations int) *Report {
+	styleName = strings.ToLower(strings.TrimSpace(styleName))
+	profile, hasProfile := Profiles[styleName]
+	longSentence := DefaultLongSentence
+	if hasProfile && profile.LongSentence > 0 {
+		longSentence = profile.LongSentence
+	}
+
+	sentences := Sentences(text)
+	report := &Report{
+		Sentences:   SentenceStats{Distribution: make(map[string]int)},
+		Annotations: []Annotation{},
+	}
+	annotate := func(a Annotation) {
+		if maxAnnotations <= 0 || len(report.Annotations) < maxAnnotations {
+			report.Annotations = append(report.Annotations, a)
+		}
+	}
+
+	syllables, complexWords, adverbs, passive := 0, 0, 0, 0
+	lengths := make([]int, 0, len(sentences))
+	for _, s := range sentences {
+		n := len(s.Words)
+		lengths = append(lengths, n)
+		report.Sentences.Words += n
+		report.Sentences.Distribution[bucket(n)]++
+		if n > report.Sentences.Longest {
+			report.Sentences.Longest = n
+		}
+
+		sentenceAdverbs := 0
+		for _, w := range s.Words {
+			count := Syllables(w)
+			syllables += count
+			if count >= 3 {
+				complexWords++
+			}
+			if isAdverb(w) {
+				sentenceAdverbs++
+			}
+		}
+		adverbs += sentenceAdverbs
+
+		if n > longSentence {
+			annotate(Annotation{
+				Kind: KindLongSentence, Start: s.Start, End: s.End, Text: s.Text,
+				Message: fmt.Sprintf("%d words; consider splitting sentences over %d words", n, longSentence),
+			})
+		}
+		if phrase, ok := passivePhrase(s.Words); ok {
+			passive++
+			annotate(Annotation{
+				Kind: KindPassiveVoice, Start: s.Start, End: s.End, Text: s.Text,
+				Message: fmt.Sprintf("passive voice (%q)", phrase),
+			})
+		}
+		if sentenceAdverbs >= 3 {
+			annotate(Annotation{
+				Kind: KindAdverbs, Start: s.Start, End: s.End, Text: s.Text,
+				Message: fmt.Sprintf("%d adverbs in one sentence", sentenceAdverbs),
+			})
+		}
+	}
+
+	words := report.Sentences.Words
+	report.Sentences.Count = len(sentences)
+	if len(sentences) == 0 || words == 0 {
+		return report
+	}
+
+	wordsPerSentence := float64(words) / float64(len(sentences))
+	syllablesPerWord := float64(syllables) / float64(words)
+	report.Readability = Readability{
+		FleschReadingEase:  round(206.835 - 1.015*wordsPerSentence - 84.6*syllablesPerWord),
+		FleschKincaidGrade: round(0.39*wordsPerSentence + 11.8*syllablesPerWord - 15.59),
+		GunningFog:         round(0.4 * (wordsPerSentence + 100*float64(complexWords)/float64(words))),
+	}
+	report.Sentences.Average = round(wordsPerSentence)
+	report.Sentences.StdDev = round(stddev(lengths, wordsPerSentence))
+	report.PassiveRatio = round(float64(passive) / float64(len(sentences)))
+	report.AdverbRatio = round(float64(adverbs) / float64(words))
+
+	if hasProfile {
+		report.Adherence = profile.check(styleName, report)
+	}
+	return report
+}
+
+// Summary returns the report without annotations, for blob metadata
+func (r *Report) Summary() map[string]interface{} {
+	summary := map[string]interface{}{
+		"flesch_reading_ease":  r.Readability.FleschReadingEase,
+		"flesch_kincaid_grade": r.Readability.FleschKincaidGrade,
+		"gunning_fog":          r.Readability.GunningFog,
+		"sentence_count":       r.Sentences.Count,
+		"average_sentence":     r.Sentences.Average,
+		"sentence_stddev":      r.Sentences.StdDev,
+		"passive_ratio":        r.PassiveRatio,
+		"adverb_ratio":         r.AdverbRatio,
+		"annotation_count":     len(r.Annotations),
+	}
+	if r.Adherence != nil {
+		summary["style"] = r.Adherence.Style
+		summary["style_adherence"] = r.Adherence.Score
+	}
+	return summary
+}
+
+// bucket names the sentence length range a word count falls in
+func bucket(n int) string {
+	switch {
+	case n <= 10:
+		return "1-10"
+	case n <= 20:
+		return "11-20"
+	case n <= 30:
+		return "21-30"
+	case n <= 40:
+		return "31-40"
+	}
+	return "41+"
+}
+
+// beVerbs precede a past participle in the passive voice
+var beVerbs = map[string]bool{
+	"am": true, "is": true, "are": true, "was": true, "were": true,
+	"be": true, "been": true, "being": true, "isn't": true, "wasn't": true,
+	"aren't": true, "weren't": true,
+}
+
+// irregularParticiples are common past participles not ending in -ed
+var irregularParticiples = map[string]bool{
+	"written": true, "taken": true, "given": true, "seen": true, "done": true,
+	"made": true, "known": true, "shown": true, "found": true, "told": true,
+	"held": true, "brought": true, "thought": true, "built": true, "sent": true,
+	"kept": true, "lost": true, "won": true, "caught": true, "taught": true,
+	"bought": true, "spent": true, "paid": true, "said": true, "heard": true,
+	"begun": true, "broken": true, "chosen": true, "driven": true, "eaten": true,
+	"forgotten": true, "forgiven": true, "frozen": true, "hidden": true,
+	"spoken": true, "stolen": true, "sworn": true, "torn": true, "thrown": true,
+	"worn": true, "drawn": true, "grown": true, "blown": true, "struck": true,
+	"hung": true, "led": true, "fed": true, "met": true, "bound": true,
+	"understood": true, "forbidden": true, "beaten": true, "bitten": true,
+}
+
+// notParticiples end in -ed but usually act as adjectives after "to be"
+var notParticiples = map[string]bool{
+	"tired": true, "bored": true, "interested": true, "excited": true,
+	"married": true, "red": true, "bed": true, "shed": true, "need": true,
+	"indeed": true, "speed": true, "seed": true, "feed": true, "hundred": true,
+}
+
+// passivePhrase finds a form of "to be" followed by a past participle,
+// allowing one adverb between them, e.g. "was quickly written"
+func passivePhrase(words []string) (string, bool) {
+	for i, w := range words {
+		if !beVerbs[strings.ToLower(w)] {
+			continue
+		}
+		for j := i + 1; j < len(words) && j <= i+2; j++ {
+			next := strings.ToLower(words[j])
+			if isParticiple(next) {
+				return strings.Join(words[i:j+1], " "), true
+			}
+			if !isAdverb(next) && next != "not" {
+				break
+			}
+		}
+	}
+	return "", false
+}
+
+// isParticiple reports whether a lowercase word looks like a past participle
+func isParticiple(w string) bool {
+	if irregularParticiples[w] {
+		return true
+	}
+	return len(w) > 3 && strings.HasSuffix(w, "ed") && !notParticiples[w]
+}
+
+// adverbExceptions end in -ly but are rarely adverbs
+var adverbExceptions = map[string]bool{
+	"only": true, "family": true, "reply": true, "supply": true, "early": true,
+	"holy": true, "ugly": true, "belly": true, "lily": true, "italy": true,
+	"july": true, "fly": true, "apply": true, "rely": true, "ally": true,
+	"friendly": true, "lovely": true, "lonely": true, "silly": true, "jelly": true,
+}
+
+// isAdverb reports whether a word looks like an -ly adverb
+func isAdverb(w string) bool {
+	lower := strings.ToLower(w)
+	return len(lower) > 4 && strings.HasSuffix(lower, "ly") && !adverbExceptions[lower]
+}
+
+// stddev is the population standard deviation of lengths around mean
+func stddev(lengths []int, mean float64) float64 {
+	if len(lengths) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, n := range lengths {
+		d := float64(n) - mean
+		sum += d * d
+	}
+	return math.Sqrt(sum / float64(len(lengths)))
+}
+
+// round rounds to two decimal places
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
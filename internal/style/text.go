@@ -0,0 +1,142 @@
+package style
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Sentence is a sentence with its rune offsets in the analyzed text
+type Sentence struct {
+	Text  string
+	Start int
+	End   int
+	Words []string
+}
+
+// abbreviations end with a period that does not end a sentence
+var abbreviations = map[string]bool{
+	"mr": true, "mrs": true, "ms": true, "dr": true, "prof": true, "st": true,
+	"jr": true, "sr": true, "vs": true, "etc": true, "e.g": true, "i.e": true,
+	"fig": true, "no": true, "vol": true, "approx": true,
+}
+
+// Sentences splits text into sentences at ., ! and ? followed by
+// whitespace or the end of the text, skipping common abbreviations
+func Sentences(text string) []Sentence {
+	runes := []rune(text)
+	var sentences []Sentence
+	start := 0
+
+	emit := func(end int) {
+		raw := string(runes[start:end])
+		trimmed := strings.TrimSpace(raw)
+		if trimmed != "" {
+			lead := len([]rune(raw)) - len([]rune(strings.TrimLeftFunc(raw, unicode.IsSpace)))
+			s := Sentence{
+				Text:  trimmed,
+				Start: start + lead,
+				End:   start + lead + len([]rune(trimmed)),
+				Words: Words(trimmed),
+			}
+			if len(s.Words) > 0 {
+				sentences = append(sentences, s)
+			}
+		}
+		start = end
+	}
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if r == '\n' && i+1 < len(runes) && runes[i+1] == '\n' {
+			// Paragraph breaks end sentences, e.g. headings without periods
+			emit(i)
+			continue
+		}
+		if r != '.' && r != '!' && r != '?' {
+			continue
+		}
+
+		end := i + 1
+		for end < len(runes) && strings.ContainsRune(".!?\"'”’)", runes[end]) {
+			end++
+		}
+		if end < len(runes) && !unicode.IsSpace(runes[end]) {
+			continue
+		}
+		if r == '.' && isAbbreviation(runes[start:i]) {
+			continue
+		}
+		emit(end)
+		i = end - 1
+	}
+	emit(len(runes))
+	return sentences
+}
+
+// isAbbreviation reports whether the text before a period ends with an
+// abbreviation or an initial
+func isAbbreviation(before []rune) bool {
+	i := len(before)
+	for i > 0 && !unicode.IsSpace(before[i-1]) {
+		i--
+	}
+	word := strings.ToLower(strings.TrimLeft(string(before[i:]), "(\"'“‘"))
+	if len([]rune(word)) == 1 && unicode.IsLetter([]rune(word)[0]) {
+		return true
+	}
+	return abbreviations[word]
+}
+
+// Words returns the words of a text, keeping internal apostrophes and
+// hyphens
+func Words(text string) []string {
+	var words []string
+	var current []rune
+	flush := func() {
+		word := strings.Trim(string(current), "'’-")
+		if word != "" {
+			words = append(words, word)
+		}
+		current = current[:0]
+	}
+
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '\'' || r == '’' || r == '-' {
+			current = append(current, r)
+			continue
+		}
+		flush()
+	}
+	flush()
+	return words
+}
+
+// Syllables estimates a word's syllables by counting vowel groups, dropping
+// a silent final e. Every word has at least one syllable.
+func Syllables(word string) int {
+	w := strings.ToLower(word)
+	if strings.IndexFunc(w, unicode.IsLetter) < 0 {
+		return 1
+	}
+
+	count := 0
+	previousVowel := false
+	for _, r := range w {
+		vowel := strings.ContainsRune("aeiouy", r)
+		if vowel && !previousVowel {
+			count++
+		}
+		previousVowel = vowel
+	}
+
+	if strings.HasSuffix(w, "e") && !strings.HasSuffix(w, "le") && !strings.HasSuffix(w, "ee") && count > 1 {
+		count--
+	}
+	if strings.HasSuffix(w, "le") && len(w) > 2 && strings.ContainsRune("aeiouy", rune(w[len(w)-3])) && count > 1 {
+		count--
+	}
+	if count == 0 {
+		count = 1
+	}
+	return count
+}
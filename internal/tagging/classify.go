@@ -0,0 +1,304 @@
+package tagging
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/memmieai/memmie-studio/internal/blob"
+	"github.com/memmieai/memmie-studio/internal/embeddings"
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// Classification defaults
+const (
+	maxEmbedRunes         = 8000 // characters of content embedded
+	defaultMinSimilarity  = 0.1  // topic similarity below which a topic is not a candidate
+	neighbourCount        = 8    // similar blobs whose tags are counted
+	minNeighbourScore     = 0.5  // similarity below which a blob is not a neighbour
+	defaultThreshold      = 0.5  // score a topic needs to be applied
+	keepFactor            = 0.8  // share of the threshold an applied topic needs to stay
+	defaultMaxTags        = 3
+	maxCachedVectors      = 1024
+	topicCollectionPrefix = "tagging/topics/"
+)
+
+// Weights are how much each signal counts toward a topic's score. Signals
+// a classification lacks are left out of the average.
+type Weights struct {
+	Similarity float64 `json:"similarity"`
+	Neighbours float64 `json:"neighbours"`
+	Classifier float64 `json:"classifier"`
+}
+
+// DefaultWeights trusts the classifier model twice as much as either
+// embedding signal
+var DefaultWeights = Weights{Similarity: 1, Neighbours: 1, Classifier: 2}
+
+// Candidate is a topic the embedding index suggests for a blob
+type Candidate struct {
+	Topic      string  `json:"topic"`
+	Similarity float64 `json:"similarity"` // cosine similarity to the topic
+	Relevance  float64 `json:"relevance"`  // similarity relative to the best topic's, 0 to 1
+	Neighbours float64 `json:"neighbours"` // share of similar blobs tagged with the topic
+}
+
+// Candidates is the embedding stage's view of a blob
+type Candidates struct {
+	Topics        []Candidate `json:"topics"`
+	HasNeighbours bool        `json:"has_neighbours"`
+}
+
+// Scored is a topic's final score
+type Scored struct {
+	Topic string  `json:"topic"`
+	Score float64 `json:"score"`
+}
+
+// Service classifies blobs and queries their tags
+type Service struct {
+	blobs    blob.Store
+	embedder embeddings.Embedder
+	index    embeddings.Index
+	mu       sync.Mutex
+	vectors  map[string][]float32 // content hash -> vector, between the steps of a run
+}
+
+// NewService creates a tagging service. The embedder and index are only
+// needed to classify; tag queries and edits work without them.
+func NewService(blobs blob.Store, embedder embeddings.Embedder, index embeddings.Index) *Service {
+	return &Service{
+		blobs:    blobs,
+		embedder: embedder,
+		index:    index,
+		vectors:  make(map[string][]float32),
+	}
+}
+
+// Suggest scores the topics of a taxonomy for a blob's content by their
+// similarity to it and by the tags of the user's most similar blobs
+func (s *Service) Suggest(ctx context.Context, userID, blobID, content string, topics []Topic, minSimilarity float64) (*Candidates, error) {
+	if minSimilarity <= 0 {
+		minSimilarity = defaultMinSimilarity
+	}
+	vector, err := s.vector(ctx, content)
+	if err != nil {
+		return nil, err
+	}
+	collection, err := s.indexTopics(ctx, topics)
+	if err != nil {
+		return nil, err
+	}
+
+	matches, err := s.index.Search(ctx, collection, vector, len(topics))
+	if err != nil {
+		return nil, fmt.Errorf("failed to search topics: %w", err)
+	}
+	byTopic := make(map[string]*Candidate, len(topics))
+	result := &Candidates{Topics: make([]Candidate, 0, len(topics))}
+	best := 0.0
+	for _, m := range matches {
+		best = max(best, m.Score)
+	}
+	for _, m := range matches {
+		c := Candidate{Topic: m.Source, Similarity: round(m.Score)}
+		if m.Score >= minSimilarity && best > 0 {
+			c.Relevance = round(m.Score / best)
+		}
+		result.Topics = append(result.Topics, c)
+	}
+
+	neighbours, err := s.index.Search(ctx, userCollection(userID), vector, neighbourCount+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search tagged blobs: %w", err)
+	}
+	votes := make(map[string]float64)
+	total := 0.0
+	for _, n := range neighbours {
+		if n.Source == blobID || n.Score < minNeighbourScore {
+			continue
+		}
+		total += n.Score
+		for _, tag := range workflows.StringList(n.Metadata["tags"]) {
+			votes[tag] += n.Score
+		}
+	}
+	for i := range result.Topics {
+		byTopic[result.Topics[i].Topic] = &result.Topics[i]
+	}
+	if total > 0 {
+		result.HasNeighbours = true
+		for tag, vote := range votes {
+			if c, ok := byTopic[tag]; ok {
+				c.Neighbours = round(vote / total)
+			}
+		}
+	}
+
+	sort.SliceStable(result.Topics, func(i, j int) bool {
+		return result.Topics[i].Relevance+result.Topics[i].Neighbours > result.Topics[j].Relevance+result.Topics[j].Neighbours
+	})
+	return result, nil
+}
+
+// Decide combines the embedding stage's scores with a classifier's, when
+// there is one, into each topic's final score, and picks the topics to
+// apply: those scoring at least threshold, best first, up to maxTags.
+// Topics already applied stay while they score at least keepFactor of the
+// threshold, so tags do not flicker as content changes a little.
+func Decide(candidates *Candidates, classifier map[string]float64, weights Weights, current []string, threshold float64, maxTags int) (applied, scores []Scored) {
+	if threshold <= 0 {
+		threshold = defaultThreshold
+	}
+	if maxTags <= 0 {
+		maxTags = defaultMaxTags
+	}
+	applied = []Scored{}
+	scores = make([]Scored, 0, len(candidates.Topics))
+	for _, c := range candidates.Topics {
+		sum, weight := weights.Similarity*c.Relevance, weights.Similarity
+		if candidates.HasNeighbours {
+			sum += weights.Neighbours * c.Neighbours
+			weight += weights.Neighbours
+		}
+		if classifier != nil {
+			sum += weights.Classifier * classifier[c.Topic]
+			weight += weights.Classifier
+		}
+		score := 0.0
+		if weight > 0 {
+			score = round(sum / weight)
+		}
+		scores = append(scores, Scored{Topic: c.Topic, Score: score})
+	}
+	sort.SliceStable(scores, func(i, j int) bool { return scores[i].Score > scores[j].Score })
+
+	had := make(map[string]bool, len(current))
+	for _, tag := range current {
+		had[tag] = true
+	}
+	for _, s := range scores {
+		if len(applied) == maxTags {
+			break
+		}
+		if s.Score >= threshold || (had[s.Topic] && s.Score >= threshold*keepFactor) {
+			applied = append(applied, s)
+		}
+	}
+	return applied, scores
+}
+
+// Index records a blob's tags against its content in the user's tagged
+// blobs, where later classifications find it as a neighbour
+func (s *Service) Index(ctx context.Context, userID, blobID, content string, tags []string) error {
+	vector, err := s.vector(ctx, content)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	delete(s.vectors, contentHash(content))
+	s.mu.Unlock()
+
+	tagList := make([]interface{}, len(tags))
+	for i, tag := range tags {
+		tagList[i] = tag
+	}
+	doc := embeddings.Document{
+		ID:       blobID,
+		Text:     truncate(content, 200),
+		Metadata: map[string]interface{}{"tags": tagList},
+		Vector:   vector,
+	}
+	version := contentHash(content + fmt.Sprint(tags))
+	if err := s.index.Replace(ctx, userCollection(userID), blobID, version, []embeddings.Document{doc}); err != nil {
+		return fmt.Errorf("failed to index tags of %s: %w", blobID, err)
+	}
+	return nil
+}
+
+// vector embeds the start of content, reusing the vector the previous step
+// of the same run computed
+func (s *Service) vector(ctx context.Context, content string) ([]float32, error) {
+	if s.embedder == nil || s.index == nil {
+		return nil, fmt.Errorf("tagging service has no embedding index")
+	}
+	key := contentHash(content)
+	s.mu.Lock()
+	vector, ok := s.vectors[key]
+	s.mu.Unlock()
+	if ok {
+		return vector, nil
+	}
+
+	vectors, err := s.embedder.Embed(ctx, []string{truncate(content, maxEmbedRunes)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed content: %w", err)
+	}
+	s.mu.Lock()
+	if len(s.vectors) >= maxCachedVectors {
+		s.vectors = make(map[string][]float32)
+	}
+	s.vectors[key] = vectors[0]
+	s.mu.Unlock()
+	return vectors[0], nil
+}
+
+// indexTopics embeds a taxonomy's topics unless the index already holds
+// them, and returns their collection
+func (s *Service) indexTopics(ctx context.Context, topics []Topic) (string, error) {
+	version := taxonomyVersion(topics)
+	collection := topicCollectionPrefix + version
+	for _, topic := range topics {
+		indexed, err := s.index.Version(ctx, collection, topic.Name)
+		if err != nil {
+			return "", fmt.Errorf("failed to read index version: %w", err)
+		}
+		if indexed == version {
+			continue
+		}
+
+		texts := make([]string, len(topics))
+		for i, t := range topics {
+			texts[i] = t.text()
+		}
+		vectors, err := s.embedder.Embed(ctx, texts)
+		if err != nil {
+			return "", fmt.Errorf("failed to embed topics: %w", err)
+		}
+		for i, t := range topics {
+			doc := embeddings.Document{ID: t.Name, Text: texts[i], Vector: vectors[i]}
+			if err := s.index.Replace(ctx, collection, t.Name, version, []embeddings.Document{doc}); err != nil {
+				return "", fmt.Errorf("failed to index topic %s: %w", t.Name, err)
+			}
+		}
+		break
+	}
+	return collection, nil
+}
+
+// userCollection is the collection of a user's tagged blobs
+func userCollection(userID string) string {
+	return fmt.Sprintf("user:%s/tags", userID)
+}
+
+// contentHash identifies content
+func contentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// truncate cuts text to at most n characters
+func truncate(text string, n int) string {
+	if runes := []rune(text); len(runes) > n {
+		return string(runes[:n])
+	}
+	return text
+}
+
+// round rounds a score to three decimals
+func round(v float64) float64 {
+	return float64(int(v*1000+0.5)) / 1000
+}
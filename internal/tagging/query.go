@@ -0,0 +1,201 @@
+package tagging
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/memmieai/memmie-studio/internal/blob"
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// Blob metadata keys the tags are kept under
+const (
+	TagsKey       = "tags"            // the blob's tags: manual ones plus automatic ones not suppressed
+	AutoTagsKey   = "auto_tags"       // tags the classifier applied
+	ManualTagsKey = "manual_tags"     // tags users added
+	SuppressedKey = "suppressed_tags" // automatic tags users removed, which are not applied again
+	TopicsKey     = "topics"          // the topics' scores from the last classification
+	TaggedHashKey = "tagged_hash"     // the content and taxonomy last classified
+)
+
+// TagCount is a tag and how many blobs carry it
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// Query selects blobs by their tags. Tags are normalized before matching.
+type Query struct {
+	All        []string // blobs must have every one of these
+	Any        []string // and at least one of these, when given
+	None       []string // and none of these
+	ProviderID string
+	Limit      int
+}
+
+// Match is a blob a query found
+type Match struct {
+	ID         string    `json:"id"`
+	ProviderID string    `json:"provider_id"`
+	Tags       []string  `json:"tags"`
+	AutoTags   []string  `json:"auto_tags,omitempty"`
+	Snippet    string    `json:"snippet"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Tags counts the tags on a user's blobs, most used first
+func (s *Service) Tags(ctx context.Context, userID, providerID string) ([]TagCount, error) {
+	blobs, err := s.blobs.ListBlobs(ctx, userID, blob.Filter{ProviderID: providerID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list blobs: %w", err)
+	}
+	counts := make(map[string]int)
+	for _, b := range blobs {
+		for _, tag := range Of(b) {
+			counts[tag]++
+		}
+	}
+	tags := make([]TagCount, 0, len(counts))
+	for tag, count := range counts {
+		tags = append(tags, TagCount{Tag: tag, Count: count})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Count != tags[j].Count {
+			return tags[i].Count > tags[j].Count
+		}
+		return tags[i].Tag < tags[j].Tag
+	})
+	return tags, nil
+}
+
+// Find returns the user's blobs matching a query, most recently updated
+// first
+func (s *Service) Find(ctx context.Context, userID string, q Query) ([]Match, error) {
+	blobs, err := s.blobs.ListBlobs(ctx, userID, blob.Filter{ProviderID: q.ProviderID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list blobs: %w", err)
+	}
+	all, anyOf, none := NormalizeTags(q.All), NormalizeTags(q.Any), NormalizeTags(q.None)
+
+	matches := []Match{}
+	for _, b := range blobs {
+		tags := Of(b)
+		has := make(map[string]bool, len(tags))
+		for _, tag := range tags {
+			has[tag] = true
+		}
+		if !hasAll(has, all) || (len(anyOf) > 0 && !hasAny(has, anyOf)) || hasAny(has, none) {
+			continue
+		}
+		matches = append(matches, Match{
+			ID:         b.ID,
+			ProviderID: b.ProviderID,
+			Tags:       tags,
+			AutoTags:   workflows.StringList(b.Metadata[AutoTagsKey]),
+			Snippet:    truncate(b.Content, 160),
+			UpdatedAt:  b.UpdatedAt,
+		})
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].UpdatedAt.After(matches[j].UpdatedAt)
+	})
+	if q.Limit > 0 && len(matches) > q.Limit {
+		matches = matches[:q.Limit]
+	}
+	return matches, nil
+}
+
+// Edit adds and removes a blob's tags by hand. Removing an automatic tag
+// suppresses it so later classifications do not apply it again; adding it
+// back lifts the suppression.
+func (s *Service) Edit(ctx context.Context, userID, blobID string, add, remove []string) (*blob.Blob, error) {
+	b, err := s.blobs.GetBlob(ctx, userID, blobID)
+	if err != nil {
+		return nil, err
+	}
+	manual := toSet(workflows.StringList(b.Metadata[ManualTagsKey]))
+	suppressed := toSet(workflows.StringList(b.Metadata[SuppressedKey]))
+	auto := workflows.StringList(b.Metadata[AutoTagsKey])
+	autoSet := toSet(auto)
+	for _, tag := range NormalizeTags(add) {
+		manual[tag] = true
+		delete(suppressed, tag)
+	}
+	for _, tag := range NormalizeTags(remove) {
+		delete(manual, tag)
+		if autoSet[tag] {
+			suppressed[tag] = true
+		}
+	}
+
+	if b.Metadata == nil {
+		b.Metadata = make(map[string]interface{})
+	}
+	b.Metadata[ManualTagsKey] = sortedList(manual)
+	b.Metadata[SuppressedKey] = sortedList(suppressed)
+	b.Metadata[TagsKey] = Effective(sortedList(manual), auto, sortedList(suppressed))
+	updated, err := s.blobs.UpdateBlob(ctx, b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update tags: %w", err)
+	}
+	return updated, nil
+}
+
+// Of returns a blob's tags
+func Of(b *blob.Blob) []string {
+	return NormalizeTags(workflows.StringList(b.Metadata[TagsKey]))
+}
+
+// Effective merges manual tags with the automatic ones not suppressed
+func Effective(manual, auto, suppressed []string) []string {
+	skip := toSet(suppressed)
+	var tags []string
+	tags = append(tags, manual...)
+	for _, tag := range auto {
+		if !skip[tag] {
+			tags = append(tags, tag)
+		}
+	}
+	return NormalizeTags(tags)
+}
+
+// hasAll reports whether every tag is in the set
+func hasAll(set map[string]bool, tags []string) bool {
+	for _, tag := range tags {
+		if !set[tag] {
+			return false
+		}
+	}
+	return true
+}
+
+// hasAny reports whether any tag is in the set
+func hasAny(set map[string]bool, tags []string) bool {
+	for _, tag := range tags {
+		if set[tag] {
+			return true
+		}
+	}
+	return false
+}
+
+// toSet normalizes tags into a set
+func toSet(tags []string) map[string]bool {
+	set := make(map[string]bool, len(tags))
+	for _, tag := range NormalizeTags(tags) {
+		set[tag] = true
+	}
+	return set
+}
+
+// sortedList returns a set's tags in order
+func sortedList(set map[string]bool) []string {
+	tags := make([]string, 0, len(set))
+	for tag := range set {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
@@ -0,0 +1,208 @@
+package tagging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// Step types handled by the tagging executors
+const (
+	SuggestStepType = "topic_candidates"
+	ApplyStepType   = "apply_tags"
+)
+
+// NewSuggestExecutor creates the executor that scores a blob's topics
+// through the embedding index. Step inputs: content; tagged_hash, the
+// blob's metadata of that name, so unchanged content is skipped unless
+// force is set; and topics, a taxonomy, or taxonomy_blob_id, a blob holding
+// one, falling back to the default topics. All but content fall back to
+// the step's parameters, as does min_similarity. The output's labels and
+// topics are meant for a classifier step.
+func NewSuggestExecutor(service *Service) workflows.StepExecutor {
+	return workflows.StepExecutorFunc(func(ctx context.Context, req workflows.StepRequest) (map[string]interface{}, error) {
+		content, _ := req.Input["content"].(string)
+		topics, err := taxonomy(ctx, service, req)
+		if err != nil {
+			return nil, err
+		}
+		hash := contentHash(content + "\n" + taxonomyVersion(topics))
+		force, _ := req.Setting("force").(bool)
+		if tagged, _ := req.Setting("tagged_hash").(string); tagged == hash && !force {
+			return map[string]interface{}{"skipped": true, "content_hash": hash}, nil
+		}
+
+		minSimilarity := number(req.Setting("min_similarity"))
+		candidates, err := service.Suggest(ctx, req.Context.UserID, req.Context.BlobID, content, topics, minSimilarity)
+		if err != nil {
+			return nil, err
+		}
+		labels := make([]interface{}, len(topics))
+		descriptions := make([]interface{}, len(topics))
+		for i, t := range topics {
+			labels[i] = t.Name
+			descriptions[i] = map[string]interface{}{"name": t.Name, "description": t.Description}
+		}
+		return map[string]interface{}{
+			"skipped":      false,
+			"content_hash": hash,
+			"candidates":   candidates,
+			"labels":       labels,
+			"topics":       descriptions,
+		}, nil
+	})
+}
+
+// NewApplyExecutor creates the executor that decides a blob's tags and
+// writes them to its metadata. Step inputs: content, candidates and
+// content_hash from the suggest step, and classification, a classifier's
+// output when there is one: a list of topics, a list of objects with a
+// topic (or label or name) and a score (or confidence), or an object of
+// scores by topic, optionally under topics, labels or scores. threshold,
+// max_tags and weights fall back to the step's parameters. Manual and
+// suppressed tags are read from the blob and kept.
+func NewApplyExecutor(service *Service) workflows.StepExecutor {
+	return workflows.StepExecutorFunc(func(ctx context.Context, req workflows.StepRequest) (map[string]interface{}, error) {
+		content, _ := req.Input["content"].(string)
+		hash, _ := req.Input["content_hash"].(string)
+		var candidates Candidates
+		if err := decode(req.Input["candidates"], &candidates); err != nil || len(candidates.Topics) == 0 {
+			return nil, fmt.Errorf("candidates from the suggest step are required")
+		}
+		weights := DefaultWeights
+		if value := req.Setting("weights"); value != nil {
+			if err := decode(value, &weights); err != nil {
+				return nil, fmt.Errorf("invalid weights: %w", err)
+			}
+		}
+		threshold := number(req.Setting("threshold"))
+		maxTags := number(req.Setting("max_tags"))
+
+		b, err := service.blobs.GetBlob(ctx, req.Context.UserID, req.Context.BlobID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load blob: %w", err)
+		}
+		current := workflows.StringList(b.Metadata[AutoTagsKey])
+		applied, scores := Decide(&candidates, classifierScores(req.Input["classification"]), weights, current, threshold, int(maxTags))
+
+		auto := make([]string, len(applied))
+		for i, s := range applied {
+			auto[i] = s.Topic
+		}
+		tags := Effective(workflows.StringList(b.Metadata[ManualTagsKey]), auto, workflows.StringList(b.Metadata[SuppressedKey]))
+		if err := service.Index(ctx, req.Context.UserID, req.Context.BlobID, content, tags); err != nil {
+			return nil, err
+		}
+
+		update := func(path string, value interface{}) interface{} {
+			return map[string]interface{}{
+				"type":      "update",
+				"path":      "metadata." + path,
+				"new_value": value,
+				"metadata": map[string]interface{}{
+					"step_id":      req.Step.ID,
+					"execution_id": req.ExecutionID,
+				},
+			}
+		}
+		return map[string]interface{}{
+			"tags":      tags,
+			"auto_tags": auto,
+			"topics":    scores,
+			"deltas": []interface{}{
+				update(TagsKey, tags),
+				update(AutoTagsKey, auto),
+				update(TopicsKey, applied),
+				update(TaggedHashKey, hash),
+			},
+		}, nil
+	})
+}
+
+// taxonomy reads a step's topics
+func taxonomy(ctx context.Context, service *Service, req workflows.StepRequest) ([]Topic, error) {
+	if value := req.Setting("topics"); value != nil {
+		return TopicsFrom(value)
+	}
+	if blobID, _ := req.Setting("taxonomy_blob_id").(string); blobID != "" {
+		b, err := service.blobs.GetBlob(ctx, req.Context.UserID, blobID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load taxonomy: %w", err)
+		}
+		return ParseTopics([]byte(b.Content))
+	}
+	return DefaultTopics(), nil
+}
+
+// classifierScores reads a classifier's output into scores by topic, or
+// nil when there is none
+func classifierScores(value interface{}) map[string]float64 {
+	if m, ok := value.(map[string]interface{}); ok {
+		for _, key := range []string{"topics", "labels", "scores", "classifications"} {
+			if inner, ok := m[key]; ok {
+				return classifierScores(inner)
+			}
+		}
+		scores := make(map[string]float64, len(m))
+		for topic, score := range m {
+			if v, ok := score.(float64); ok {
+				scores[NormalizeTag(topic)] = v
+			}
+		}
+		return scores
+	}
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil
+	}
+	scores := make(map[string]float64, len(items))
+	for _, item := range items {
+		switch v := item.(type) {
+		case string:
+			scores[NormalizeTag(v)] = 1
+		case map[string]interface{}:
+			var topic string
+			for _, key := range []string{"topic", "label", "name"} {
+				if topic, _ = v[key].(string); topic != "" {
+					break
+				}
+			}
+			score, ok := v["score"].(float64)
+			if !ok {
+				score, ok = v["confidence"].(float64)
+			}
+			if !ok {
+				score = 1
+			}
+			if topic != "" {
+				scores[NormalizeTag(topic)] = score
+			}
+		}
+	}
+	return scores
+}
+
+// number reads a JSON or Go number
+func number(value interface{}) float64 {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	}
+	return 0
+}
+
+// decode converts step input, which may be a value from an in-process step
+// or its JSON form, into v
+func decode(value, v interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
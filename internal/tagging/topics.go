@@ -0,0 +1,138 @@
+// Package tagging classifies blobs into topics and keeps their tags up to
+// date. Topics are matched through the embedding index, both directly and
+// by the tags of similar blobs, and optionally confirmed by a classifier
+// model; users can add and remove tags on top of the automatic ones.
+package tagging
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// ErrInvalidTopics is returned for taxonomies that cannot be read
+var ErrInvalidTopics = errors.New("invalid topics")
+
+// Topic is a class blobs can be tagged with. Its name is the tag.
+type Topic struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Keywords    []string `json:"keywords,omitempty"`
+}
+
+// text is what a topic is embedded as
+func (t Topic) text() string {
+	parts := []string{t.Name}
+	if t.Description != "" {
+		parts = append(parts, t.Description)
+	}
+	if len(t.Keywords) > 0 {
+		parts = append(parts, strings.Join(t.Keywords, ", "))
+	}
+	return strings.Join(parts, ". ")
+}
+
+// DefaultTopics is the taxonomy used when a workflow does not give one
+func DefaultTopics() []Topic {
+	return []Topic{
+		{Name: "fiction", Description: "Stories, novels and narrative prose with characters and plot", Keywords: []string{"chapter", "character", "scene", "dialogue", "protagonist", "plot", "said", "she", "he"}},
+		{Name: "poetry", Description: "Poems, verse and lyrics", Keywords: []string{"poem", "verse", "stanza", "rhyme", "meter", "haiku", "sonnet"}},
+		{Name: "technology", Description: "Software, computing, engineering and the internet", Keywords: []string{"software", "code", "api", "computer", "data", "algorithm", "cloud", "server", "programming", "ai"}},
+		{Name: "science", Description: "Research, experiments and the natural sciences", Keywords: []string{"research", "study", "experiment", "hypothesis", "physics", "biology", "chemistry", "results", "method"}},
+		{Name: "health", Description: "Medicine, wellbeing, fitness and nutrition", Keywords: []string{"health", "patient", "medical", "disease", "treatment", "clinical", "exercise", "diet", "symptoms"}},
+		{Name: "business", Description: "Companies, markets, finance and management", Keywords: []string{"company", "market", "revenue", "customers", "strategy", "investment", "sales", "startup", "finance", "budget"}},
+		{Name: "education", Description: "Teaching, learning, schools and courses", Keywords: []string{"students", "teacher", "school", "course", "learning", "curriculum", "university", "lesson", "classroom"}},
+		{Name: "history", Description: "Past events, eras and historical figures", Keywords: []string{"century", "war", "empire", "ancient", "historical", "king", "revolution", "dynasty", "medieval"}},
+		{Name: "politics", Description: "Government, policy, elections and law", Keywords: []string{"government", "policy", "election", "law", "vote", "parliament", "congress", "minister", "legislation"}},
+		{Name: "travel", Description: "Places, journeys and trips", Keywords: []string{"travel", "trip", "journey", "city", "hotel", "flight", "destination", "country", "tour"}},
+		{Name: "food", Description: "Cooking, recipes and restaurants", Keywords: []string{"recipe", "cook", "ingredients", "kitchen", "bake", "flavor", "restaurant", "dish", "minutes"}},
+		{Name: "arts", Description: "Visual art, music, film and theatre", Keywords: []string{"art", "music", "film", "painting", "artist", "album", "theatre", "gallery", "director"}},
+		{Name: "sports", Description: "Games, athletes and competitions", Keywords: []string{"game", "team", "season", "player", "match", "score", "coach", "league", "championship"}},
+		{Name: "personal", Description: "Journals, memoir and reflections on personal life", Keywords: []string{"i", "my", "me", "today", "feel", "family", "remember", "journal", "life"}},
+	}
+}
+
+// ParseTopics reads a taxonomy from JSON: a list of topics or of topic
+// names, or an object with a topics list
+func ParseTopics(data []byte) ([]Topic, error) {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTopics, err)
+	}
+	return TopicsFrom(value)
+}
+
+// TopicsFrom reads a taxonomy from decoded JSON or step input
+func TopicsFrom(value interface{}) ([]Topic, error) {
+	if wrapper, ok := value.(map[string]interface{}); ok {
+		value = wrapper["topics"]
+	}
+	if text, ok := value.(string); ok {
+		return ParseTopics([]byte(text))
+	}
+	items, ok := value.([]interface{})
+	if !ok || len(items) == 0 {
+		return nil, fmt.Errorf("%w: expected a list of topics", ErrInvalidTopics)
+	}
+
+	topics := make([]Topic, 0, len(items))
+	seen := make(map[string]bool, len(items))
+	for _, item := range items {
+		var topic Topic
+		switch v := item.(type) {
+		case string:
+			topic.Name = v
+		case map[string]interface{}:
+			topic.Name, _ = v["name"].(string)
+			topic.Description, _ = v["description"].(string)
+			topic.Keywords = workflows.StringList(v["keywords"])
+		}
+		topic.Name = NormalizeTag(topic.Name)
+		if topic.Name == "" {
+			return nil, fmt.Errorf("%w: every topic needs a name", ErrInvalidTopics)
+		}
+		if seen[topic.Name] {
+			return nil, fmt.Errorf("%w: duplicate topic %q", ErrInvalidTopics, topic.Name)
+		}
+		seen[topic.Name] = true
+		topics = append(topics, topic)
+	}
+	return topics, nil
+}
+
+// taxonomyVersion identifies a taxonomy's contents
+func taxonomyVersion(topics []Topic) string {
+	h := sha256.New()
+	for _, t := range topics {
+		h.Write([]byte(t.text() + "\n"))
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// NormalizeTag lowercases a tag and joins its words with hyphens, so
+// "Machine Learning" and "machine-learning" are the same tag
+func NormalizeTag(tag string) string {
+	words := strings.FieldsFunc(strings.ToLower(tag), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '+' && r != '#'
+	})
+	return strings.Join(words, "-")
+}
+
+// NormalizeTags normalizes tags, dropping empty ones and duplicates
+func NormalizeTags(tags []string) []string {
+	out := []string{}
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		if tag = NormalizeTag(tag); tag != "" && !seen[tag] {
+			seen[tag] = true
+			out = append(out, tag)
+		}
+	}
+	return out
+}
@@ -0,0 +1,132 @@
+package timers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Store persists timers so scheduled runs survive restarts
+type Store interface {
+	Save(ctx context.Context, timer *Timer) error
+	Get(ctx context.Context, id string) (*Timer, error)
+	List(ctx context.Context) ([]*Timer, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// FileStore keeps each timer in a JSON file under a directory
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates a store writing under dir
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir}
+}
+
+// Save writes a timer, replacing the file atomically so a crash never
+// leaves a partial one
+func (s *FileStore) Save(ctx context.Context, timer *Timer) error {
+	path, err := s.path(timer.ID)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(timer, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal timer: %w", err)
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create timer directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(s.dir, ".timer-*")
+	if err != nil {
+		return fmt.Errorf("failed to write timer %s: %w", timer.ID, err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write timer %s: %w", timer.ID, err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write timer %s: %w", timer.ID, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write timer %s: %w", timer.ID, err)
+	}
+	return nil
+}
+
+// Get reads a timer
+func (s *FileStore) Get(ctx context.Context, id string) (*Timer, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrTimerNotFound, id)
+	}
+	return s.read(path)
+}
+
+// List reads every timer
+func (s *FileStore) List(ctx context.Context) ([]*Timer, error) {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list timers: %w", err)
+	}
+	timers := make([]*Timer, 0, len(paths))
+	for _, path := range paths {
+		timer, err := s.read(path)
+		if errors.Is(err, ErrTimerNotFound) {
+			// Deleted since the listing
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		timers = append(timers, timer)
+	}
+	return timers, nil
+}
+
+// Delete removes a timer
+func (s *FileStore) Delete(ctx context.Context, id string) error {
+	path, err := s.path(id)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrTimerNotFound, id)
+	}
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%w: %s", ErrTimerNotFound, id)
+		}
+		return fmt.Errorf("failed to delete timer %s: %w", id, err)
+	}
+	return nil
+}
+
+// read decodes a timer file
+func (s *FileStore) read(path string) (*Timer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %s", ErrTimerNotFound, strings.TrimSuffix(filepath.Base(path), ".json"))
+		}
+		return nil, fmt.Errorf("failed to read timer: %w", err)
+	}
+	var timer Timer
+	if err := json.Unmarshal(data, &timer); err != nil {
+		return nil, fmt.Errorf("failed to parse timer %s: %w", path, err)
+	}
+	return &timer, nil
+}
+
+// path maps a timer ID to its file, rejecting IDs that are not plain names
+func (s *FileStore) path(id string) (string, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) || strings.HasPrefix(id, ".") {
+		return "", fmt.Errorf("invalid timer id %q", id)
+	}
+	return filepath.Join(s.dir, id+".json"), nil
+}
@@ -0,0 +1,266 @@
+// Package timers runs blob processing at a later time. Scheduled runs are
+// persisted in a Store and fired once due, including runs that fell due
+// while the server was down, so expensive batches can be queued for
+// off-peak hours.
+package timers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// Timer statuses
+const (
+	StatusPending   = "pending"
+	StatusFired     = "fired"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+// Scheduling limits
+const (
+	// MaxDelay is how far ahead a run can be scheduled
+	MaxDelay = 30 * 24 * time.Hour
+
+	// retention is how long finished timers stay queryable
+	retention = 7 * 24 * time.Hour
+)
+
+// DefaultCheckInterval is how often the store is checked for due runs
+const DefaultCheckInterval = 30 * time.Second
+
+// Timer errors
+var (
+	ErrTimerNotFound = errors.New("scheduled run not found")
+	ErrTimerFinished = errors.New("scheduled run already finished")
+	ErrInvalidTimer  = errors.New("invalid scheduled run")
+)
+
+// Timer is a blob processing run scheduled for a time. Executions lists the
+// executions started per provider once it has fired.
+type Timer struct {
+	ID         string              `json:"id"`
+	BlobID     string              `json:"blob_id"`
+	UserID     string              `json:"user_id"`
+	EventType  string              `json:"event_type"`
+	NotBefore  time.Time           `json:"not_before"`
+	Status     string              `json:"status"`
+	Executions map[string][]string `json:"executions,omitempty"`
+	Error      string              `json:"error,omitempty"`
+	CreatedAt  time.Time           `json:"created_at"`
+	FiredAt    *time.Time          `json:"fired_at,omitempty"`
+}
+
+// Processor runs a blob through its providers, as the orchestrator does
+type Processor interface {
+	ProcessBlobExecutions(ctx context.Context, blobID, userID, eventType string) (map[string][]string, error)
+}
+
+// Service schedules blob processing runs and fires them when due. It
+// assumes it is the only service firing the store's timers.
+type Service struct {
+	store     Store
+	processor Processor
+	onError   func(error)
+	now       func() time.Time
+
+	mu     sync.Mutex
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewService creates a scheduling service and starts checking for due runs
+// every interval. onError, if set, is told when the store cannot be read or
+// written; runs that fail are recorded on their timers. Close stops it.
+func NewService(store Store, processor Processor, interval time.Duration, onError func(error)) *Service {
+	if interval <= 0 {
+		interval = DefaultCheckInterval
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Service{
+		store:     store,
+		processor: processor,
+		onError:   onError,
+		now:       time.Now,
+		ctx:       ctx,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+	go s.run(interval)
+	return s
+}
+
+// ProcessBlobAt schedules a blob to be processed for an event at notBefore.
+// Times in the past fire on the next check.
+func (s *Service) ProcessBlobAt(ctx context.Context, blobID, userID, eventType string, notBefore time.Time) (*Timer, error) {
+	now := s.now()
+	if notBefore.After(now.Add(MaxDelay)) {
+		return nil, fmt.Errorf("%w: runs can be scheduled at most %s ahead", ErrInvalidTimer, MaxDelay)
+	}
+	timer := &Timer{
+		ID:        uuid.New().String(),
+		BlobID:    blobID,
+		UserID:    userID,
+		EventType: eventType,
+		NotBefore: notBefore.UTC(),
+		Status:    StatusPending,
+		CreatedAt: now.UTC(),
+	}
+	if err := s.store.Save(ctx, timer); err != nil {
+		return nil, err
+	}
+	return timer, nil
+}
+
+// Get returns one of a user's scheduled runs
+func (s *Service) Get(ctx context.Context, userID, id string) (*Timer, error) {
+	timer, err := s.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if timer.UserID != userID {
+		return nil, fmt.Errorf("%w: %s", ErrTimerNotFound, id)
+	}
+	return timer, nil
+}
+
+// List returns a user's scheduled runs, optionally for one blob, soonest
+// first
+func (s *Service) List(ctx context.Context, userID, blobID string) ([]*Timer, error) {
+	all, err := s.store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	timers := []*Timer{}
+	for _, timer := range all {
+		if timer.UserID == userID && (blobID == "" || timer.BlobID == blobID) {
+			timers = append(timers, timer)
+		}
+	}
+	sort.Slice(timers, func(i, j int) bool {
+		if !timers[i].NotBefore.Equal(timers[j].NotBefore) {
+			return timers[i].NotBefore.Before(timers[j].NotBefore)
+		}
+		return timers[i].ID < timers[j].ID
+	})
+	return timers, nil
+}
+
+// Cancel stops a pending run from firing
+func (s *Service) Cancel(ctx context.Context, userID, id string) (*Timer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	timer, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if timer.Status != StatusPending {
+		return nil, fmt.Errorf("%w: %s is %s", ErrTimerFinished, id, timer.Status)
+	}
+	timer.Status = StatusCancelled
+	if err := s.store.Save(ctx, timer); err != nil {
+		return nil, err
+	}
+	return timer, nil
+}
+
+// Close stops firing runs, interrupting any run in progress
+func (s *Service) Close() {
+	s.cancel()
+	<-s.done
+}
+
+// run fires due timers until the service is closed
+func (s *Service) run(interval time.Duration) {
+	defer close(s.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.fireDue()
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// fireDue fires every pending timer that is due and forgets finished
+// timers past their retention
+func (s *Service) fireDue() {
+	timers, err := s.store.List(s.ctx)
+	if err != nil {
+		s.report(err)
+		return
+	}
+	now := s.now()
+	sort.Slice(timers, func(i, j int) bool { return timers[i].NotBefore.Before(timers[j].NotBefore) })
+	for _, timer := range timers {
+		if s.ctx.Err() != nil {
+			return
+		}
+		switch {
+		case timer.Status == StatusPending && !timer.NotBefore.After(now):
+			s.fire(timer.ID)
+		case timer.Status != StatusPending && timer.NotBefore.Before(now.Add(-retention)):
+			if err := s.store.Delete(s.ctx, timer.ID); err != nil && !errors.Is(err, ErrTimerNotFound) {
+				s.report(err)
+			}
+		}
+	}
+}
+
+// fire processes a timer's blob, recording the outcome. The timer is read
+// again first so a run cancelled since the listing does not fire.
+func (s *Service) fire(id string) {
+	s.mu.Lock()
+	timer, err := s.store.Get(s.ctx, id)
+	if err != nil || timer.Status != StatusPending {
+		s.mu.Unlock()
+		if err != nil && !errors.Is(err, ErrTimerNotFound) {
+			s.report(err)
+		}
+		return
+	}
+	// Mark the run before starting it, so a crash part way through does not
+	// start it twice
+	firedAt := s.now().UTC()
+	timer.Status = StatusFired
+	timer.FiredAt = &firedAt
+	err = s.store.Save(s.ctx, timer)
+	s.mu.Unlock()
+	if err != nil {
+		s.report(err)
+		return
+	}
+
+	// Scheduled runs are batch work, so interactive executions go first
+	ctx := workflows.WithLane(s.ctx, workflows.LaneBatch)
+	executions, err := s.processor.ProcessBlobExecutions(ctx, timer.BlobID, timer.UserID, timer.EventType)
+	timer.Executions = executions
+	if err != nil {
+		timer.Status = StatusFailed
+		timer.Error = err.Error()
+	}
+	if err := s.store.Save(context.Background(), timer); err != nil {
+		s.report(err)
+	}
+}
+
+// report passes an error to onError, if set
+func (s *Service) report(err error) {
+	if s.onError != nil && !errors.Is(err, context.Canceled) {
+		s.onError(err)
+	}
+}
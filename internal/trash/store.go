@@ -0,0 +1,135 @@
+package trash
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Store persists trash entries so deleted blobs can be restored and purged
+// after a restart
+type Store interface {
+	Save(ctx context.Context, entry *Entry) error
+	Get(ctx context.Context, blobID string) (*Entry, error)
+	List(ctx context.Context) ([]*Entry, error)
+	Delete(ctx context.Context, blobID string) error
+}
+
+// FileStore keeps each entry in a JSON file under a directory, named by
+// its blob ID
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates a store writing under dir
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir}
+}
+
+// Save writes an entry, replacing the file atomically so a crash never
+// leaves a partial one
+func (s *FileStore) Save(ctx context.Context, entry *Entry) error {
+	path, err := s.path(entry.BlobID)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal trash entry: %w", err)
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create trash directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(s.dir, ".entry-*")
+	if err != nil {
+		return fmt.Errorf("failed to write trash entry %s: %w", entry.BlobID, err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write trash entry %s: %w", entry.BlobID, err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write trash entry %s: %w", entry.BlobID, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write trash entry %s: %w", entry.BlobID, err)
+	}
+	return nil
+}
+
+// Get reads a blob's entry
+func (s *FileStore) Get(ctx context.Context, blobID string) (*Entry, error) {
+	path, err := s.path(blobID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotInTrash, blobID)
+	}
+	return s.read(path)
+}
+
+// List reads every entry
+func (s *FileStore) List(ctx context.Context) ([]*Entry, error) {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trash: %w", err)
+	}
+	entries := make([]*Entry, 0, len(paths))
+	for _, path := range paths {
+		entry, err := s.read(path)
+		if errors.Is(err, ErrNotInTrash) {
+			// Restored or purged since the listing
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Delete removes a blob's entry
+func (s *FileStore) Delete(ctx context.Context, blobID string) error {
+	path, err := s.path(blobID)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrNotInTrash, blobID)
+	}
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%w: %s", ErrNotInTrash, blobID)
+		}
+		return fmt.Errorf("failed to delete trash entry %s: %w", blobID, err)
+	}
+	return nil
+}
+
+// read decodes an entry file
+func (s *FileStore) read(path string) (*Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %s", ErrNotInTrash, strings.TrimSuffix(filepath.Base(path), ".json"))
+		}
+		return nil, fmt.Errorf("failed to read trash entry: %w", err)
+	}
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("failed to parse trash entry %s: %w", path, err)
+	}
+	return &entry, nil
+}
+
+// path maps a blob ID to its entry's file, rejecting IDs that are not
+// plain names
+func (s *FileStore) path(blobID string) (string, error) {
+	if blobID == "" || strings.ContainsAny(blobID, `/\`) || strings.HasPrefix(blobID, ".") {
+		return "", fmt.Errorf("invalid blob id %q", blobID)
+	}
+	return filepath.Join(s.dir, blobID+".json"), nil
+}
@@ -0,0 +1,315 @@
+// Package trash soft-deletes blobs. A deleted blob is marked in its
+// metadata and kept for a retention window, during which it can be
+// restored, before it is purged. Restoring replays the blob's delta log up
+// to the moment it was deleted, so deltas written to it while in the trash
+// are undone.
+package trash
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/memmieai/memmie-studio/internal/blob"
+	"github.com/memmieai/memmie-studio/internal/revisions"
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// DefaultRetention is how long deleted blobs stay restorable
+const DefaultRetention = 30 * 24 * time.Hour
+
+// DefaultCheckInterval is how often the trash is checked for blobs to purge
+const DefaultCheckInterval = time.Hour
+
+// Trash errors
+var (
+	ErrNotInTrash      = errors.New("blob is not in the trash")
+	ErrAlreadyTrashed  = errors.New("blob is already in the trash")
+	ErrInvalidSequence = errors.New("invalid restore sequence")
+)
+
+// Entry is a blob in the trash. Sequence is the latest delta applied to it
+// when it was deleted, which a restore returns it to.
+type Entry struct {
+	BlobID      string    `json:"blob_id"`
+	UserID      string    `json:"user_id"`
+	ProviderID  string    `json:"provider_id,omitempty"`
+	NamespaceID string    `json:"namespace_id,omitempty"`
+	Sequence    int64     `json:"sequence"`
+	DeletedAt   time.Time `json:"deleted_at"`
+	PurgeAt     time.Time `json:"purge_at"`
+}
+
+// Service moves blobs to the trash, restores them and purges them once
+// their retention has passed
+type Service struct {
+	store     Store
+	blobs     blob.Store
+	history   revisions.History
+	retention time.Duration
+	onError   func(error)
+	now       func() time.Time
+
+	mu     sync.Mutex
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewService creates a trash over a blob store and starts purging expired
+// blobs every interval. history, if set, supplies the delta logs restores
+// replay; without it a restore only takes the blob out of the trash. Blobs
+// are purged from stores implementing blob.Deleter, and otherwise stay
+// hidden in the trash for good. onError, if set, is told when purging
+// fails. Close stops it.
+func NewService(store Store, blobs blob.Store, history revisions.History, retention, interval time.Duration, onError func(error)) *Service {
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
+	if interval <= 0 {
+		interval = DefaultCheckInterval
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Service{
+		store:     store,
+		blobs:     blobs,
+		history:   history,
+		retention: retention,
+		onError:   onError,
+		now:       time.Now,
+		ctx:       ctx,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+	go s.run(interval)
+	return s
+}
+
+// Delete moves one of a user's blobs to the trash
+func (s *Service) Delete(ctx context.Context, userID, blobID string) (*Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, err := s.blobs.GetBlob(ctx, userID, blobID)
+	if err != nil {
+		return nil, err
+	}
+	if b.Trashed() {
+		return nil, fmt.Errorf("%w: %s", ErrAlreadyTrashed, blobID)
+	}
+	var sequence int64
+	if s.history != nil {
+		deltas, err := s.history.GetByBlobID(ctx, blobID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load deltas: %w", err)
+		}
+		sequence = revisions.Latest(deltas)
+	}
+
+	now := s.now().UTC()
+	entry := &Entry{
+		BlobID:      blobID,
+		UserID:      userID,
+		ProviderID:  b.ProviderID,
+		NamespaceID: b.NamespaceID,
+		Sequence:    sequence,
+		DeletedAt:   now,
+		PurgeAt:     now.Add(s.retention),
+	}
+	if err := s.store.Save(ctx, entry); err != nil {
+		return nil, err
+	}
+	if b.Metadata == nil {
+		b.Metadata = make(map[string]interface{})
+	}
+	b.Metadata[blob.TrashedKey] = now.Format(time.RFC3339)
+	if _, err := s.blobs.UpdateBlob(ctx, b); err != nil {
+		if delErr := s.store.Delete(ctx, blobID); delErr != nil {
+			return nil, fmt.Errorf("failed to move blob %s to the trash: %w (and to remove its entry: %v)", blobID, err, delErr)
+		}
+		return nil, fmt.Errorf("failed to move blob %s to the trash: %w", blobID, err)
+	}
+	return entry, nil
+}
+
+// Get returns the trash entry of one of a user's blobs
+func (s *Service) Get(ctx context.Context, userID, blobID string) (*Entry, error) {
+	entry, err := s.store.Get(ctx, blobID)
+	if err != nil {
+		return nil, err
+	}
+	if entry.UserID != userID {
+		return nil, fmt.Errorf("%w: %s", ErrNotInTrash, blobID)
+	}
+	return entry, nil
+}
+
+// List returns a user's blobs in the trash, most recently deleted first
+func (s *Service) List(ctx context.Context, userID string) ([]*Entry, error) {
+	all, err := s.store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	entries := []*Entry{}
+	for _, entry := range all {
+		if entry.UserID == userID {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].DeletedAt.Equal(entries[j].DeletedAt) {
+			return entries[i].DeletedAt.After(entries[j].DeletedAt)
+		}
+		return entries[i].BlobID < entries[j].BlobID
+	})
+	return entries, nil
+}
+
+// Restore takes a blob out of the trash. Its content and metadata are
+// rebuilt by replaying its delta log up to a sequence number, by default
+// the one it was deleted at; fields the log never set are kept as they
+// are.
+func (s *Service) Restore(ctx context.Context, userID, blobID string, sequence int64) (*blob.Blob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, err := s.Get(ctx, userID, blobID)
+	if err != nil {
+		return nil, err
+	}
+	if sequence < 0 {
+		return nil, fmt.Errorf("%w: sequence must not be negative", ErrInvalidSequence)
+	}
+	if sequence == 0 {
+		sequence = entry.Sequence
+	}
+	b, err := s.blobs.GetBlob(ctx, userID, blobID)
+	if err != nil {
+		return nil, err
+	}
+
+	if sequence > 0 {
+		if s.history == nil {
+			return nil, fmt.Errorf("%w: no delta history to replay", ErrInvalidSequence)
+		}
+		deltas, err := s.history.GetByBlobID(ctx, blobID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load deltas: %w", err)
+		}
+		if latest := revisions.Latest(deltas); sequence > latest {
+			return nil, fmt.Errorf("%w: sequence %d is past the latest delta %d", ErrInvalidSequence, sequence, latest)
+		}
+		var processor workflows.DeltaProcessor
+		state := processor.Replay(deltas, sequence)
+		if content, ok := state["content"].(string); ok {
+			b.Content = content
+		}
+		if metadata, ok := state["metadata"].(map[string]interface{}); ok {
+			if b.Metadata == nil {
+				b.Metadata = make(map[string]interface{}, len(metadata))
+			}
+			for key, value := range metadata {
+				b.Metadata[key] = value
+			}
+		}
+	}
+	delete(b.Metadata, blob.TrashedKey)
+
+	restored, err := s.blobs.UpdateBlob(ctx, b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore blob %s: %w", blobID, err)
+	}
+	if err := s.store.Delete(ctx, blobID); err != nil && !errors.Is(err, ErrNotInTrash) {
+		return nil, err
+	}
+	return restored, nil
+}
+
+// Purge permanently deletes one of a user's blobs from the trash without
+// waiting for its retention to pass
+func (s *Service) Purge(ctx context.Context, userID, blobID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, err := s.Get(ctx, userID, blobID)
+	if err != nil {
+		return err
+	}
+	return s.purge(ctx, entry)
+}
+
+// Close stops purging
+func (s *Service) Close() {
+	s.cancel()
+	<-s.done
+}
+
+// run purges expired blobs until the service is closed
+func (s *Service) run(interval time.Duration) {
+	defer close(s.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.purgeExpired()
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purgeExpired purges every blob whose retention has passed
+func (s *Service) purgeExpired() {
+	entries, err := s.store.List(s.ctx)
+	if err != nil {
+		s.report(err)
+		return
+	}
+	now := s.now()
+	for _, entry := range entries {
+		if s.ctx.Err() != nil {
+			return
+		}
+		if entry.PurgeAt.After(now) {
+			continue
+		}
+		s.mu.Lock()
+		// Read the entry again in case the blob was restored meanwhile
+		if current, err := s.store.Get(s.ctx, entry.BlobID); err == nil {
+			err = s.purge(s.ctx, current)
+			if err != nil {
+				s.report(err)
+			}
+		} else if !errors.Is(err, ErrNotInTrash) {
+			s.report(err)
+		}
+		s.mu.Unlock()
+	}
+}
+
+// purge deletes a blob, when the store can, and forgets its entry. Blobs
+// already gone from the store are forgotten too.
+func (s *Service) purge(ctx context.Context, entry *Entry) error {
+	if deleter, ok := s.blobs.(blob.Deleter); ok {
+		err := deleter.DeleteBlob(ctx, entry.UserID, entry.BlobID)
+		if err != nil && !errors.Is(err, blob.ErrNotFound) && !errors.Is(err, blob.ErrDeleteUnsupported) {
+			return fmt.Errorf("failed to purge blob %s: %w", entry.BlobID, err)
+		}
+	}
+	if err := s.store.Delete(ctx, entry.BlobID); err != nil && !errors.Is(err, ErrNotInTrash) {
+		return err
+	}
+	return nil
+}
+
+// report passes an error to onError, if set
+func (s *Service) report(err error) {
+	if s.onError != nil && !errors.Is(err, context.Canceled) {
+		s.onError(err)
+	}
+}
@@ -0,0 +1,244 @@
+package voicenotes
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// DefaultThreshold is the cosine similarity a note needs to a cluster's
+// centroid to join it
+const DefaultThreshold = 0.2
+
+// maxKeywords is the number of keywords kept per cluster
+const maxKeywords = 5
+
+// Cluster is a group of notes about one topic. Its key is the transcript ID
+// of its first note, so it stays the same as later notes join.
+type Cluster struct {
+	Key           string   `json:"key"`
+	Title         string   `json:"title"`
+	Keywords      []string `json:"keywords"`
+	TranscriptIDs []string `json:"transcript_ids"`
+}
+
+// Cluster groups notes by topic. Notes are taken oldest first and each
+// joins the most similar cluster when its similarity to the cluster's
+// centroid reaches threshold, or starts a new one, so a new memo never moves
+// earlier ones between clusters.
+func (s *Service) Cluster(ctx context.Context, notes []Note, threshold float64) ([]Cluster, error) {
+	if len(notes) == 0 {
+		return []Cluster{}, nil
+	}
+	if threshold <= 0 {
+		threshold = DefaultThreshold
+	}
+
+	// Memos are embedded by their content words, since the function words
+	// and fillers every memo shares would otherwise pull unrelated memos
+	// together
+	texts := make([]string, len(notes))
+	for i, n := range notes {
+		texts[i] = strings.Join(keywordTokens(n.Text), " ")
+	}
+	vectors, err := s.embedder.Embed(ctx, texts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed notes: %w", err)
+	}
+	if len(vectors) != len(notes) {
+		return nil, fmt.Errorf("embedder returned %d vectors for %d notes", len(vectors), len(notes))
+	}
+
+	type group struct {
+		members []int
+		sum     []float64
+	}
+	var groups []*group
+	for i, vector := range vectors {
+		best, bestScore := -1, threshold
+		for g, candidate := range groups {
+			if score := centroidSimilarity(candidate.sum, vector); score >= bestScore {
+				best, bestScore = g, score
+			}
+		}
+		if best == -1 {
+			groups = append(groups, &group{sum: make([]float64, len(vector))})
+			best = len(groups) - 1
+		}
+		groups[best].members = append(groups[best].members, i)
+		for d, v := range vector {
+			groups[best].sum[d] += float64(v)
+		}
+	}
+
+	memberTexts := make([][]string, len(groups))
+	for g, grp := range groups {
+		for _, i := range grp.members {
+			memberTexts[g] = append(memberTexts[g], notes[i].Text)
+		}
+	}
+	keywords := clusterKeywords(memberTexts)
+
+	clusters := make([]Cluster, len(groups))
+	for g, grp := range groups {
+		ids := make([]string, len(grp.members))
+		for j, i := range grp.members {
+			ids[j] = notes[i].TranscriptID
+		}
+		clusters[g] = Cluster{
+			Key:           ids[0],
+			Title:         title(keywords[g]),
+			Keywords:      keywords[g],
+			TranscriptIDs: ids,
+		}
+	}
+	return clusters, nil
+}
+
+// centroidSimilarity is the cosine similarity between a vector and the
+// centroid whose unnormalized sum is given
+func centroidSimilarity(sum []float64, vector []float32) float64 {
+	var dot, norm float64
+	for d, v := range vector {
+		dot += sum[d] * float64(v)
+		norm += sum[d] * sum[d]
+	}
+	if norm == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(norm)
+}
+
+// clusterKeywords picks each cluster's most distinctive words, weighting
+// frequency within the cluster by rarity across clusters
+func clusterKeywords(texts [][]string) [][]string {
+	counts := make([]map[string]int, len(texts))
+	spread := make(map[string]int)
+	for g, members := range texts {
+		counts[g] = make(map[string]int)
+		for _, text := range members {
+			for _, word := range keywordTokens(text) {
+				counts[g][word]++
+			}
+		}
+		for word := range counts[g] {
+			spread[word]++
+		}
+	}
+
+	keywords := make([][]string, len(texts))
+	for g := range texts {
+		type scored struct {
+			word  string
+			score float64
+		}
+		var words []scored
+		for word, count := range counts[g] {
+			idf := math.Log(1 + float64(len(texts))/float64(spread[word]))
+			words = append(words, scored{word, float64(count) * idf})
+		}
+		sort.Slice(words, func(i, j int) bool {
+			if words[i].score != words[j].score {
+				return words[i].score > words[j].score
+			}
+			// Longer words tend to be more specific
+			if len(words[i].word) != len(words[j].word) {
+				return len(words[i].word) > len(words[j].word)
+			}
+			return words[i].word < words[j].word
+		})
+		keywords[g] = []string{}
+		for i := 0; i < len(words) && i < maxKeywords; i++ {
+			keywords[g] = append(keywords[g], words[i].word)
+		}
+	}
+	return keywords
+}
+
+// keywordTokens lowercases text into words of four or more letters that are
+// not stopwords or fillers
+func keywordTokens(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	words := make([]string, 0, len(fields))
+	for _, field := range fields {
+		field = strings.TrimSuffix(strings.Trim(field, "'"), "'s")
+		if len([]rune(field)) < 4 || stopwords[field] {
+			continue
+		}
+		words = append(words, field)
+	}
+	return words
+}
+
+// title names a cluster after its top keywords
+func title(keywords []string) string {
+	if len(keywords) == 0 {
+		return "Untitled"
+	}
+	if len(keywords) > 3 {
+		keywords = keywords[:3]
+	}
+	words := make([]string, len(keywords))
+	for i, word := range keywords {
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		words[i] = string(runes)
+	}
+	return strings.Join(words, ", ")
+}
+
+// Map converts a cluster into step output form
+func (c Cluster) Map() map[string]interface{} {
+	return map[string]interface{}{
+		"key":            c.Key,
+		"title":          c.Title,
+		"keywords":       toInterfaces(c.Keywords),
+		"transcript_ids": toInterfaces(c.TranscriptIDs),
+	}
+}
+
+// ClusterFromMap reads a cluster from step output form
+func ClusterFromMap(m map[string]interface{}) Cluster {
+	c := Cluster{
+		Keywords:      workflows.StringList(m["keywords"]),
+		TranscriptIDs: workflows.StringList(m["transcript_ids"]),
+	}
+	c.Key, _ = m["key"].(string)
+	c.Title, _ = m["title"].(string)
+	return c
+}
+
+// stopwords are common words that say nothing about a memo's topic,
+// including the fillers of spoken language
+var stopwords = map[string]bool{
+	"about": true, "actually": true, "after": true, "again": true, "also": true,
+	"another": true, "back": true, "called": true, "every": true, "other": true,
+	"because": true, "been": true, "before": true, "being": true, "could": true,
+	"does": true, "doing": true, "from": true, "going": true, "gonna": true,
+	"have": true, "here": true, "just": true, "kind": true, "know": true,
+	"like": true, "maybe": true, "more": true, "much": true, "need": true,
+	"okay": true, "only": true, "really": true, "right": true, "some": true,
+	"something": true, "sort": true, "that": true, "that's": true, "them": true,
+	"then": true, "there": true, "there's": true, "these": true, "they": true,
+	"thing": true, "things": true, "think": true, "this": true, "those": true,
+	"want": true, "wanna": true, "well": true, "were": true, "what": true,
+	"when": true, "where": true, "which": true, "while": true, "will": true,
+	"with": true, "would": true, "yeah": true, "your": true, "don't": true,
+	"into": true, "their": true, "should": true, "what's": true, "very": true, "over": true, "even": true,
+}
+
+// toInterfaces converts strings for step output
+func toInterfaces(strs []string) []interface{} {
+	out := make([]interface{}, len(strs))
+	for i, s := range strs {
+		out[i] = s
+	}
+	return out
+}
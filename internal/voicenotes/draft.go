@@ -0,0 +1,291 @@
+package voicenotes
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/memmieai/memmie-studio/internal/blob"
+	"github.com/memmieai/memmie-studio/internal/style"
+)
+
+const (
+	// DefaultParagraphWords is the length at which a drafted paragraph is
+	// closed
+	DefaultParagraphWords = 120
+
+	// pauseBreak is the silence, in seconds, that starts a new paragraph
+	pauseBreak = 2.5
+)
+
+// Section statuses reported by SaveSections
+const (
+	SectionCreated   = "created"
+	SectionUpdated   = "updated"
+	SectionUnchanged = "unchanged"
+)
+
+// fillers are spoken hesitations dropped from drafts
+var fillers = regexp.MustCompile(`(?i)\b(?:u+m+|u+h+|e+r+m+|h+m+|m+h*m+)\b[,.]?\s*`)
+
+// Section is a drafted chapter section
+type Section struct {
+	ClusterKey string      `json:"cluster_key"`
+	Title      string      `json:"title"`
+	Keywords   []string    `json:"keywords"`
+	Paragraphs []Paragraph `json:"paragraphs"`
+}
+
+// Paragraph is a drafted paragraph and the stretch of the memo it came from.
+// A paragraph never spans memos.
+type Paragraph struct {
+	Text         string  `json:"text"`
+	MemoID       string  `json:"memo_id"`
+	TranscriptID string  `json:"transcript_id"`
+	Start        float64 `json:"start"` // seconds into the memo, 0 when untimed
+	End          float64 `json:"end"`
+}
+
+// SectionResult reports a saved section
+type SectionResult struct {
+	ClusterKey string `json:"cluster_key"`
+	BlobID     string `json:"blob_id"`
+	Title      string `json:"title"`
+	Paragraphs int    `json:"paragraphs"`
+	Status     string `json:"status"`
+}
+
+// Draft turns a cluster's notes into a section, oldest note first. Spoken
+// fillers and stuttered repeats are removed, and paragraphs break at the
+// end of a sentence after a long pause or once they reach maxWords words.
+func Draft(cluster Cluster, notes []Note, maxWords int) Section {
+	if maxWords <= 0 {
+		maxWords = DefaultParagraphWords
+	}
+	byID := make(map[string]Note, len(notes))
+	for _, n := range notes {
+		byID[n.TranscriptID] = n
+	}
+
+	section := Section{
+		ClusterKey: cluster.Key,
+		Title:      cluster.Title,
+		Keywords:   cluster.Keywords,
+		Paragraphs: []Paragraph{},
+	}
+	for _, id := range cluster.TranscriptIDs {
+		note, ok := byID[id]
+		if !ok {
+			continue
+		}
+
+		var current Paragraph
+		var parts []string
+		words := 0
+		flush := func() {
+			if len(parts) > 0 {
+				current.Text = finishParagraph(strings.Join(parts, " "))
+				section.Paragraphs = append(section.Paragraphs, current)
+			}
+			parts, words = nil, 0
+		}
+		for _, piece := range pieces(note) {
+			text := cleanSpoken(piece.Text)
+			count := len(style.Words(text))
+			if count == 0 {
+				continue
+			}
+			if len(parts) > 0 && endsSentence(parts[len(parts)-1]) && (words+count > maxWords || piece.Start-current.End >= pauseBreak) {
+				flush()
+			}
+			if len(parts) == 0 {
+				current = Paragraph{MemoID: note.MemoID, TranscriptID: note.TranscriptID, Start: piece.Start}
+			}
+			parts = append(parts, text)
+			words += count
+			current.End = piece.End
+		}
+		flush()
+	}
+	return section
+}
+
+// pieces splits a note into timed segments, or into sentences when the
+// transcript has no timestamps
+func pieces(note Note) []Segment {
+	if len(note.Segments) > 0 {
+		return note.Segments
+	}
+	var out []Segment
+	for _, sentence := range style.Sentences(note.Text) {
+		out = append(out, Segment{Text: sentence.Text})
+	}
+	return out
+}
+
+// cleanSpoken removes fillers and immediately repeated words
+func cleanSpoken(text string) string {
+	text = fillers.ReplaceAllString(text, "")
+	fields := strings.Fields(text)
+	kept := fields[:0]
+	for i, field := range fields {
+		if i > 0 && strings.EqualFold(strings.Trim(field, ",."), strings.Trim(fields[i-1], ",.")) && !strings.ContainsAny(fields[i-1], ".!?") {
+			continue
+		}
+		kept = append(kept, field)
+	}
+	return strings.Join(kept, " ")
+}
+
+// endsSentence reports whether text ends with sentence punctuation
+func endsSentence(text string) bool {
+	text = strings.TrimRight(text, "\"'”’) ")
+	return strings.HasSuffix(text, ".") || strings.HasSuffix(text, "!") || strings.HasSuffix(text, "?")
+}
+
+// finishParagraph capitalizes a paragraph and closes it with a period
+func finishParagraph(text string) string {
+	text = strings.TrimSpace(strings.ReplaceAll(text, " ,", ","))
+	runes := []rune(text)
+	if len(runes) == 0 {
+		return text
+	}
+	runes[0] = unicode.ToUpper(runes[0])
+	text = string(runes)
+	if !endsSentence(text) {
+		text = strings.TrimRight(text, ",;:") + "."
+	}
+	return text
+}
+
+// Markdown renders the section with its title as a heading
+func (s Section) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s\n", s.Title)
+	for _, p := range s.Paragraphs {
+		b.WriteString("\n")
+		b.WriteString(p.Text)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// lineage lists each paragraph's source for section blob metadata.
+// Paragraphs are numbered from 0 in the order they appear in the content.
+func (s Section) lineage() []interface{} {
+	entries := make([]interface{}, len(s.Paragraphs))
+	for i, p := range s.Paragraphs {
+		entries[i] = map[string]interface{}{
+			"paragraph":     i,
+			"memo_id":       p.MemoID,
+			"transcript_id": p.TranscriptID,
+			"start":         p.Start,
+			"end":           p.End,
+		}
+	}
+	return entries
+}
+
+// memoIDs lists the memos the section draws on, in order of first use
+func (s Section) memoIDs() []string {
+	seen := make(map[string]bool)
+	var ids []string
+	for _, p := range s.Paragraphs {
+		if p.MemoID != "" && !seen[p.MemoID] {
+			seen[p.MemoID] = true
+			ids = append(ids, p.MemoID)
+		}
+	}
+	return ids
+}
+
+// DraftProject drafts every cluster from the project's notes and saves the
+// sections
+func (s *Service) DraftProject(ctx context.Context, userID, projectID string, clusters []Cluster, maxWords int) ([]SectionResult, error) {
+	notes, err := s.Notes(ctx, userID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	sections := make([]Section, 0, len(clusters))
+	for _, c := range clusters {
+		if section := Draft(c, notes, maxWords); len(section.Paragraphs) > 0 {
+			sections = append(sections, section)
+		}
+	}
+	return s.SaveSections(ctx, userID, projectID, sections)
+}
+
+// SaveSections stores sections as blobs of the project's provider, one per
+// cluster, updating the blob drafted earlier for the same cluster. Blob
+// stores cannot delete, so sections whose cluster no longer exists are
+// marked stale.
+func (s *Service) SaveSections(ctx context.Context, userID, projectID string, sections []Section) ([]SectionResult, error) {
+	blobs, err := s.projectBlobs(ctx, userID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]*blob.Blob)
+	for _, b := range blobs {
+		if key, ok := b.Metadata["cluster_key"].(string); ok && b.Metadata["kind"] == KindSection {
+			existing[key] = b
+		}
+	}
+
+	results := make([]SectionResult, 0, len(sections))
+	for _, section := range sections {
+		content := section.Markdown()
+		metadata := map[string]interface{}{
+			"kind":         KindSection,
+			"cluster_key":  section.ClusterKey,
+			"title":        section.Title,
+			"keywords":     toInterfaces(section.Keywords),
+			"derived_from": toInterfaces(section.memoIDs()),
+			"lineage":      section.lineage(),
+		}
+		result := SectionResult{ClusterKey: section.ClusterKey, Title: section.Title, Paragraphs: len(section.Paragraphs)}
+
+		b, known := existing[section.ClusterKey]
+		delete(existing, section.ClusterKey)
+		switch {
+		case !known:
+			created, err := s.blobs.CreateBlob(ctx, &blob.Blob{
+				UserID:     userID,
+				ProviderID: ProviderID(projectID),
+				Content:    content,
+				Metadata:   metadata,
+			})
+			if err != nil {
+				return results, fmt.Errorf("failed to create section %s: %w", section.ClusterKey, err)
+			}
+			result.BlobID, result.Status = created.ID, SectionCreated
+
+		case b.Content == content && b.Metadata["stale"] != true:
+			result.BlobID, result.Status = b.ID, SectionUnchanged
+
+		default:
+			for k, v := range metadata {
+				b.Metadata[k] = v
+			}
+			delete(b.Metadata, "stale")
+			b.Content = content
+			if _, err := s.blobs.UpdateBlob(ctx, b); err != nil {
+				return results, fmt.Errorf("failed to update section %s: %w", b.ID, err)
+			}
+			result.BlobID, result.Status = b.ID, SectionUpdated
+		}
+		results = append(results, result)
+	}
+
+	for _, b := range existing {
+		if b.Metadata["stale"] == true {
+			continue
+		}
+		b.Metadata["stale"] = true
+		if _, err := s.blobs.UpdateBlob(ctx, b); err != nil {
+			return results, fmt.Errorf("failed to mark section %s stale: %w", b.ID, err)
+		}
+	}
+	return results, nil
+}
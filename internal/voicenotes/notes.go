@@ -0,0 +1,126 @@
+// Package voicenotes turns a project's voice memos into draft chapter
+// sections. Memo transcripts are clustered by topic and each cluster is
+// drafted into a section whose paragraphs record the memo they came from.
+package voicenotes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/memmieai/memmie-studio/internal/blob"
+	"github.com/memmieai/memmie-studio/internal/embeddings"
+)
+
+// Blob kinds read and written by the pipeline
+const (
+	KindTranscript = "transcript" // written by the transcription step
+	KindSection    = "draft_section"
+)
+
+// Note is a transcribed voice memo
+type Note struct {
+	MemoID       string    `json:"memo_id"`
+	TranscriptID string    `json:"transcript_id"`
+	Text         string    `json:"text"`
+	Segments     []Segment `json:"segments,omitempty"`
+	RecordedAt   time.Time `json:"recorded_at"`
+}
+
+// Segment is a timestamped stretch of a transcript, in seconds
+type Segment struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
+}
+
+// Service clusters and drafts a project's voice notes
+type Service struct {
+	blobs    blob.Store
+	embedder embeddings.Embedder
+}
+
+// NewService creates a voice note service
+func NewService(blobs blob.Store, embedder embeddings.Embedder) *Service {
+	return &Service{blobs: blobs, embedder: embedder}
+}
+
+// ProviderID returns the provider of a voice note project, which owns its
+// memo, transcript and draft section blobs
+func ProviderID(projectID string) string {
+	return "voicenotes:" + projectID
+}
+
+// Notes returns the project's transcribed memos, oldest first
+func (s *Service) Notes(ctx context.Context, userID, projectID string) ([]Note, error) {
+	blobs, err := s.projectBlobs(ctx, userID, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	var notes []Note
+	for _, b := range blobs {
+		if b.Metadata["kind"] != KindTranscript || b.Content == "" {
+			continue
+		}
+		memoID, _ := b.Metadata["derived_from"].(string)
+		if b.ParentID != nil {
+			memoID = *b.ParentID
+		}
+		notes = append(notes, Note{
+			MemoID:       memoID,
+			TranscriptID: b.ID,
+			Text:         b.Content,
+			Segments:     segmentsFrom(b.Metadata["segments"]),
+			RecordedAt:   b.CreatedAt,
+		})
+	}
+	sort.SliceStable(notes, func(i, j int) bool {
+		if notes[i].RecordedAt.Equal(notes[j].RecordedAt) {
+			return notes[i].TranscriptID < notes[j].TranscriptID
+		}
+		return notes[i].RecordedAt.Before(notes[j].RecordedAt)
+	})
+	return notes, nil
+}
+
+// projectBlobs lists every blob of the project's provider
+func (s *Service) projectBlobs(ctx context.Context, userID, projectID string) ([]*blob.Blob, error) {
+	blobs, err := s.blobs.ListBlobs(ctx, userID, blob.Filter{ProviderID: ProviderID(projectID)})
+	if err != nil && !errors.Is(err, blob.ErrNotFound) {
+		return nil, fmt.Errorf("failed to list voice note blobs: %w", err)
+	}
+	return blobs, nil
+}
+
+// segmentsFrom reads transcript segments from blob metadata
+func segmentsFrom(value interface{}) []Segment {
+	items, _ := value.([]interface{})
+	segments := make([]Segment, 0, len(items))
+	for _, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		text, _ := m["text"].(string)
+		segments = append(segments, Segment{Start: toFloat(m["start"]), End: toFloat(m["end"]), Text: text})
+	}
+	return segments
+}
+
+// toFloat reads a JSON number
+func toFloat(value interface{}) float64 {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case float32:
+		return float64(v)
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	}
+	return 0
+}
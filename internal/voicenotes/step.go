@@ -0,0 +1,100 @@
+package voicenotes
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// Step types handled by the voice note executors
+const (
+	ClusterStepType = "voice_note_cluster"
+	DraftStepType   = "voice_note_draft"
+)
+
+// NewClusterExecutor creates the executor that clusters a project's
+// transcribed memos by topic. Step inputs: project_id, and threshold, which
+// falls back to the step's parameters. The clusters are returned for the
+// draft step.
+func NewClusterExecutor(service *Service) workflows.StepExecutor {
+	return workflows.StepExecutorFunc(func(ctx context.Context, req workflows.StepRequest) (map[string]interface{}, error) {
+		projectID, _ := req.Input["project_id"].(string)
+		if projectID == "" {
+			return nil, fmt.Errorf("project_id is required")
+		}
+		threshold, _ := req.Setting("threshold").(float64)
+
+		notes, err := service.Notes(ctx, req.Context.UserID, projectID)
+		if err != nil {
+			return nil, err
+		}
+		clusters, err := service.Cluster(ctx, notes, threshold)
+		if err != nil {
+			return nil, err
+		}
+
+		out := make([]interface{}, len(clusters))
+		for i, c := range clusters {
+			out[i] = c.Map()
+		}
+		return map[string]interface{}{
+			"notes":    len(notes),
+			"clusters": out,
+		}, nil
+	})
+}
+
+// NewDraftExecutor creates the executor that drafts a section per cluster
+// and stores it as a blob whose lineage metadata links every paragraph to
+// its memo. Step inputs: project_id, clusters (from the cluster step; the
+// project is clustered afresh when absent), and max_paragraph_words and
+// threshold, which fall back to the step's parameters.
+func NewDraftExecutor(service *Service) workflows.StepExecutor {
+	return workflows.StepExecutorFunc(func(ctx context.Context, req workflows.StepRequest) (map[string]interface{}, error) {
+		projectID, _ := req.Input["project_id"].(string)
+		if projectID == "" {
+			return nil, fmt.Errorf("project_id is required")
+		}
+		maxWords := toInt(req.Setting("max_paragraph_words"))
+
+		var clusters []Cluster
+		if items, ok := req.Input["clusters"].([]interface{}); ok {
+			for _, item := range items {
+				if m, ok := item.(map[string]interface{}); ok {
+					clusters = append(clusters, ClusterFromMap(m))
+				}
+			}
+		} else {
+			notes, err := service.Notes(ctx, req.Context.UserID, projectID)
+			if err != nil {
+				return nil, err
+			}
+			threshold, _ := req.Setting("threshold").(float64)
+			if clusters, err = service.Cluster(ctx, notes, threshold); err != nil {
+				return nil, err
+			}
+		}
+
+		results, err := service.DraftProject(ctx, req.Context.UserID, projectID, clusters, maxWords)
+		if err != nil {
+			return nil, err
+		}
+		sections := make([]interface{}, len(results))
+		for i, r := range results {
+			sections[i] = map[string]interface{}{
+				"cluster_key": r.ClusterKey,
+				"blob_id":     r.BlobID,
+				"title":       r.Title,
+				"paragraphs":  r.Paragraphs,
+				"status":      r.Status,
+			}
+		}
+		return map[string]interface{}{"sections": sections}, nil
+	})
+}
+
+// toInt reads a JSON number as an int
+func toInt(value interface{}) int {
+	return int(toFloat(value))
+}
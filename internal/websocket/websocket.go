@@ -0,0 +1,282 @@
+// Package websocket implements the server side of the WebSocket protocol
+// (RFC 6455), enough for the studio's session channel: the opening
+// handshake, text and binary messages, pings and the closing handshake
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Errors returned for connections
+var (
+	ErrClosed        = errors.New("websocket closed")
+	ErrProtocol      = errors.New("websocket protocol error")
+	ErrMessageTooBig = errors.New("websocket message too big")
+)
+
+// MaxMessageSize bounds the messages a connection reads
+const MaxMessageSize = 1 << 20
+
+// acceptGUID is appended to a client's key to derive the accept header
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Frame opcodes
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+// closeNormal is the status code of a normal closure
+const closeNormal = 1000
+
+// writeTimeout bounds each write, so a peer that stops reading cannot hold
+// up writers forever
+const writeTimeout = 10 * time.Second
+
+// Conn is a server-side WebSocket connection. One goroutine may read while
+// others write.
+type Conn struct {
+	conn      net.Conn
+	reader    *bufio.Reader
+	writer    *bufio.Writer
+	writeMu   sync.Mutex
+	closeOnce sync.Once
+}
+
+// Upgrade completes the opening handshake for a WebSocket request and takes
+// over its connection. Requests from browsers must come from a page on the
+// same host. On failure it has written an error response.
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	if r.Method != http.MethodGet || !headerHas(r.Header, "Connection", "upgrade") || !headerHas(r.Header, "Upgrade", "websocket") {
+		w.Header().Set("Upgrade", "websocket")
+		http.Error(w, "websocket upgrade required", http.StatusUpgradeRequired)
+		return nil, fmt.Errorf("%w: not a websocket request", ErrProtocol)
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported websocket version", http.StatusUpgradeRequired)
+		return nil, fmt.Errorf("%w: unsupported version", ErrProtocol)
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		http.Error(w, "missing Sec-WebSocket-Key", http.StatusBadRequest)
+		return nil, fmt.Errorf("%w: missing key", ErrProtocol)
+	}
+	if origin := r.Header.Get("Origin"); origin != "" {
+		if u, err := url.Parse(origin); err != nil || !strings.EqualFold(u.Host, r.Host) {
+			http.Error(w, "origin not allowed", http.StatusForbidden)
+			return nil, fmt.Errorf("%w: origin %s not allowed", ErrProtocol, origin)
+		}
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket not supported", http.StatusInternalServerError)
+		return nil, fmt.Errorf("%w: connection cannot be hijacked", ErrProtocol)
+	}
+
+	netConn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("failed to hijack connection: %w", err)
+	}
+	// The server's read and write timeouts no longer apply
+	netConn.SetDeadline(time.Time{})
+
+	c := &Conn{conn: netConn, reader: rw.Reader, writer: rw.Writer}
+	fmt.Fprintf(c.writer, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", acceptKey(key))
+	if err := c.writer.Flush(); err != nil {
+		netConn.Close()
+		return nil, fmt.Errorf("failed to complete handshake: %w", err)
+	}
+	return c, nil
+}
+
+// acceptKey derives the Sec-WebSocket-Accept value for a client's key
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// headerHas reports whether a comma-separated header lists a token
+func headerHas(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ReadMessage reads the next text or binary message, answering pings on
+// the way. It returns ErrClosed once the peer closes the connection.
+func (c *Conn) ReadMessage() ([]byte, error) {
+	var message []byte
+	started := false
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+		switch opcode {
+		case opClose:
+			c.closeWith(payload)
+			return nil, ErrClosed
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opText, opBinary:
+			if started {
+				c.Close()
+				return nil, fmt.Errorf("%w: new message inside a fragmented one", ErrProtocol)
+			}
+			started = true
+		case opContinuation:
+			if !started {
+				c.Close()
+				return nil, fmt.Errorf("%w: continuation without a message", ErrProtocol)
+			}
+		default:
+			c.Close()
+			return nil, fmt.Errorf("%w: unknown opcode %d", ErrProtocol, opcode)
+		}
+
+		if len(message)+len(payload) > MaxMessageSize {
+			c.Close()
+			return nil, ErrMessageTooBig
+		}
+		message = append(message, payload...)
+		if fin {
+			return message, nil
+		}
+	}
+}
+
+// readFrame reads one frame from the client, unmasking its payload
+func (c *Conn) readFrame() (bool, byte, []byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin := header[0]&0x80 != 0
+	opcode := header[0] & 0x0f
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, fmt.Errorf("%w: reserved bits set", ErrProtocol)
+	}
+	if header[1]&0x80 == 0 {
+		return false, 0, nil, fmt.Errorf("%w: client frame not masked", ErrProtocol)
+	}
+
+	length := uint64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var extended [2]byte
+		if _, err := io.ReadFull(c.reader, extended[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, err := io.ReadFull(c.reader, extended[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+	if opcode >= opClose && (length > 125 || !fin) {
+		return false, 0, nil, fmt.Errorf("%w: invalid control frame", ErrProtocol)
+	}
+	if length > MaxMessageSize {
+		return false, 0, nil, ErrMessageTooBig
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.reader, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, opcode, payload, nil
+}
+
+// WriteMessage sends a text message
+func (c *Conn) WriteMessage(data []byte) error {
+	return c.writeFrame(opText, data)
+}
+
+// Ping sends a ping, which keeps idle connections open through proxies
+func (c *Conn) Ping() error {
+	return c.writeFrame(opPing, nil)
+}
+
+// writeFrame sends one unfragmented frame. Server frames are not masked.
+func (c *Conn) writeFrame(opcode byte, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	header := []byte{0x80 | opcode, 0}
+	switch length := len(payload); {
+	case length <= 125:
+		header[1] = byte(length)
+	case length <= 0xffff:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(length))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(length))
+	}
+	if _, err := c.writer.Write(header); err != nil {
+		return err
+	}
+	if _, err := c.writer.Write(payload); err != nil {
+		return err
+	}
+	return c.writer.Flush()
+}
+
+// Close sends a normal closure and closes the connection
+func (c *Conn) Close() error {
+	var status [2]byte
+	binary.BigEndian.PutUint16(status[:], closeNormal)
+	return c.closeWith(status[:])
+}
+
+// closeWith sends a close frame with a payload, at most once, and closes
+// the connection
+func (c *Conn) closeWith(payload []byte) error {
+	var err error
+	c.closeOnce.Do(func() {
+		if len(payload) > 2 {
+			payload = payload[:2]
+		}
+		c.writeFrame(opClose, payload)
+		err = c.conn.Close()
+	})
+	return err
+}
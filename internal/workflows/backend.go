@@ -0,0 +1,84 @@
+package workflows
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// BackendHTTP is the built-in backend that talks to the memmie workflow service
+const BackendHTTP = "http"
+
+// BackendConfig selects and configures an execution backend
+type BackendConfig struct {
+	Type      string            `json:"type"` // http, temporal, conductor
+	URL       string            `json:"url"`
+	Namespace string            `json:"namespace,omitempty"`
+	TaskQueue string            `json:"task_queue,omitempty"`
+	Options   map[string]string `json:"options,omitempty"`
+}
+
+// BackendFactory creates a WorkflowService from configuration
+type BackendFactory func(cfg BackendConfig) (WorkflowService, error)
+
+var (
+	backendsMu sync.RWMutex
+	backends   = map[string]BackendFactory{
+		BackendHTTP: func(cfg BackendConfig) (WorkflowService, error) {
+			if cfg.URL == "" {
+				return nil, fmt.Errorf("http backend requires a workflow service URL")
+			}
+			return NewWorkflowClient(cfg.URL), nil
+		},
+	}
+)
+
+// RegisterBackend makes an execution backend available by name. Backend
+// packages call it from init so importing them is enough to enable them.
+func RegisterBackend(name string, factory BackendFactory) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+
+	if factory == nil {
+		panic("workflows: RegisterBackend factory is nil")
+	}
+	if _, exists := backends[name]; exists {
+		panic("workflows: RegisterBackend called twice for backend " + name)
+	}
+	backends[name] = factory
+}
+
+// NewBackend creates the execution backend named by cfg.Type, defaulting to
+// the HTTP workflow service
+func NewBackend(cfg BackendConfig) (WorkflowService, error) {
+	if cfg.Type == "" {
+		cfg.Type = BackendHTTP
+	}
+
+	backendsMu.RLock()
+	factory, exists := backends[cfg.Type]
+	backendsMu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("unknown execution backend %q (available: %v)", cfg.Type, Backends())
+	}
+
+	service, err := factory(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s backend: %w", cfg.Type, err)
+	}
+	return service, nil
+}
+
+// Backends returns the names of the registered execution backends
+func Backends() []string {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,314 @@
+package workflows
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EventBatch is the type of the events a BatchingBus publishes, each
+// holding a batch of events of one type for one blob
+const EventBatch = "event.batch"
+
+// EventSourceBatcher is the source of batch events
+const EventSourceBatcher = "batcher"
+
+// How a batch's events are encoded in its data
+const (
+	BatchEncodingJSON = "json" // events is the list of events
+	BatchEncodingGzip = "gzip" // events is the gzipped JSON list, base64-encoded
+)
+
+const (
+	// DefaultBatchWindow is how long an event waits for others to batch with
+	DefaultBatchWindow = 50 * time.Millisecond
+	// DefaultBatchMaxEvents is how many events fill a batch
+	DefaultBatchMaxEvents = 500
+	// DefaultBatchMaxBytes is how much event JSON fills a batch
+	DefaultBatchMaxBytes = 256 << 10
+	// batchQueueSize bounds the batches waiting to be published; publishers
+	// wait while it is full
+	batchQueueSize = 64
+)
+
+// BatchConfig configures a BatchingBus; zero fields take the defaults
+type BatchConfig struct {
+	Window    time.Duration
+	MaxEvents int
+	MaxBytes  int
+	Compress  bool // gzip each batch's events
+	// OnError, if set, is told of batches the bus would not take; they are
+	// published from the background, so Publish cannot return their errors
+	OnError func(error)
+}
+
+// batchKey groups the events batched together
+type batchKey struct {
+	blobID    string
+	eventType string
+}
+
+// pendingBatch is a batch still collecting events
+type pendingBatch struct {
+	key    batchKey
+	userID string
+	events []json.RawMessage
+	size   int
+	timer  *time.Timer
+}
+
+// BatchingBus is an EventBus that coalesces the events published to another
+// bus into batch events, one per blob and event type, flushed when a batch
+// has waited the window or is full. Batches are published one at a time in
+// the order they were flushed, so each blob's events of a type keep their
+// order, though events of different types may pass one another. It suits
+// buses where each publish is costly, such as a Kafka mirror. Subscribers
+// through it receive the events unpacked.
+type BatchingBus struct {
+	bus EventBus
+	cfg BatchConfig
+
+	mu      sync.Mutex
+	pending map[batchKey]*pendingBatch
+	ready   chan Event
+	closed  bool
+	done    chan struct{}
+}
+
+// NewBatchingBus wraps a bus with batching. Close flushes what is pending.
+func NewBatchingBus(bus EventBus, cfg BatchConfig) *BatchingBus {
+	if cfg.Window <= 0 {
+		cfg.Window = DefaultBatchWindow
+	}
+	if cfg.MaxEvents <= 0 {
+		cfg.MaxEvents = DefaultBatchMaxEvents
+	}
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = DefaultBatchMaxBytes
+	}
+	b := &BatchingBus{
+		bus:     bus,
+		cfg:     cfg,
+		pending: make(map[batchKey]*pendingBatch),
+		ready:   make(chan Event, batchQueueSize),
+		done:    make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+// Publish adds an event to its batch, flushing the batch when it is full.
+// Once the bus is closed events are published on their own.
+func (b *BatchingBus) Publish(ctx context.Context, event Event) error {
+	InjectTrace(ctx, &event)
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s event: %w", event.Type, err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return b.bus.Publish(ctx, event)
+	}
+	key := batchKey{event.BlobID, event.Type}
+	batch, ok := b.pending[key]
+	if !ok {
+		batch = &pendingBatch{key: key, userID: event.UserID}
+		b.pending[key] = batch
+		batch.timer = time.AfterFunc(b.cfg.Window, func() { b.expire(batch) })
+	}
+	batch.events = append(batch.events, data)
+	batch.size += len(data)
+	if len(batch.events) >= b.cfg.MaxEvents || batch.size >= b.cfg.MaxBytes {
+		return b.flush(ctx, batch)
+	}
+	return nil
+}
+
+// Subscribe subscribes a handler to the bus, unpacking batch events into
+// the events they hold. Filters see batches by the type and blob of their
+// events and each unpacked event again before it is handled.
+func (b *BatchingBus) Subscribe(ctx context.Context, handler EventHandler, filters ...EventFilter) error {
+	return b.bus.Subscribe(ctx, func(ctx context.Context, event Event) error {
+		if event.Type != EventBatch {
+			return handler(ctx, event)
+		}
+		events, err := UnpackBatch(event)
+		if err != nil {
+			return Permanent(err)
+		}
+		var first error
+		for _, unpacked := range events {
+			if !MatchesAny(filters, unpacked) {
+				continue
+			}
+			if err := handler(ContextWithEventTrace(ctx, unpacked), unpacked); err != nil && first == nil {
+				first = err
+			}
+		}
+		return first
+	}, filters...)
+}
+
+// Close flushes every pending batch and waits for them to be published
+func (b *BatchingBus) Close() {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		<-b.done
+		return
+	}
+	for _, batch := range b.pending {
+		b.flush(context.Background(), batch)
+	}
+	b.closed = true
+	close(b.ready)
+	b.mu.Unlock()
+	<-b.done
+}
+
+// expire flushes a batch whose window has passed, unless it was flushed
+// already
+func (b *BatchingBus) expire(batch *pendingBatch) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed || b.pending[batch.key] != batch {
+		return
+	}
+	b.flush(context.Background(), batch)
+}
+
+// flush queues a batch for publishing; the caller holds the lock, which
+// keeps batches queued in the order they were flushed
+func (b *BatchingBus) flush(ctx context.Context, batch *pendingBatch) error {
+	delete(b.pending, batch.key)
+	batch.timer.Stop()
+	event, err := b.pack(batch)
+	if err != nil {
+		b.report(err)
+		return err
+	}
+	select {
+	case b.ready <- event:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to queue %s batch: %w", batch.key.eventType, ctx.Err())
+	}
+}
+
+// pack builds the batch event for a batch
+func (b *BatchingBus) pack(batch *pendingBatch) (Event, error) {
+	list := make([]byte, 0, batch.size+len(batch.events)+1)
+	list = append(list, '[')
+	for i, data := range batch.events {
+		if i > 0 {
+			list = append(list, ',')
+		}
+		list = append(list, data...)
+	}
+	list = append(list, ']')
+
+	data := map[string]interface{}{
+		"event_type": batch.key.eventType,
+		"count":      len(batch.events),
+		"encoding":   BatchEncodingJSON,
+		"events":     json.RawMessage(list),
+	}
+	if b.cfg.Compress {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(list); err != nil {
+			return Event{}, fmt.Errorf("failed to compress %s batch: %w", batch.key.eventType, err)
+		}
+		if err := zw.Close(); err != nil {
+			return Event{}, fmt.Errorf("failed to compress %s batch: %w", batch.key.eventType, err)
+		}
+		data["encoding"] = BatchEncodingGzip
+		data["events"] = base64.StdEncoding.EncodeToString(buf.Bytes())
+	}
+	return Event{
+		ID:            uuid.New().String(),
+		Type:          EventBatch,
+		BlobID:        batch.key.blobID,
+		UserID:        batch.userID,
+		Timestamp:     time.Now(),
+		Data:          data,
+		SchemaVersion: EventSchemaVersion,
+		Source:        EventSourceBatcher,
+	}, nil
+}
+
+// run publishes queued batches in order until the bus is closed
+func (b *BatchingBus) run() {
+	defer close(b.done)
+	for event := range b.ready {
+		if err := b.bus.Publish(context.Background(), event); err != nil {
+			b.report(fmt.Errorf("failed to publish batch of %v %v events for blob %s: %w", event.Data["count"], event.Data["event_type"], event.BlobID, err))
+		}
+	}
+}
+
+// report passes an error to OnError, if set
+func (b *BatchingBus) report(err error) {
+	if b.cfg.OnError != nil {
+		b.cfg.OnError(err)
+	}
+}
+
+// UnpackBatch returns the events a batch event holds, for subscribers that
+// read a batching bus's underlying bus directly
+func UnpackBatch(event Event) ([]Event, error) {
+	if event.Type != EventBatch {
+		return nil, fmt.Errorf("%s is not a batch event", event.Type)
+	}
+	var list []byte
+	switch encoding, _ := event.Data["encoding"].(string); encoding {
+	case BatchEncodingGzip:
+		encoded, _ := event.Data["events"].(string)
+		compressed, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode batch %s: %w", event.ID, err)
+		}
+		zr, err := gzip.NewReader(bytes.NewReader(compressed))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress batch %s: %w", event.ID, err)
+		}
+		if list, err = io.ReadAll(zr); err != nil {
+			return nil, fmt.Errorf("failed to decompress batch %s: %w", event.ID, err)
+		}
+	case BatchEncodingJSON, "":
+		// The events are a raw list as published, or decoded values once
+		// the batch has been through JSON
+		var err error
+		if list, err = json.Marshal(event.Data["events"]); err != nil {
+			return nil, fmt.Errorf("failed to read batch %s: %w", event.ID, err)
+		}
+	default:
+		return nil, fmt.Errorf("batch %s has unknown encoding %q", event.ID, encoding)
+	}
+	var events []Event
+	if err := json.Unmarshal(list, &events); err != nil {
+		return nil, fmt.Errorf("failed to decode batch %s: %w", event.ID, err)
+	}
+	return events, nil
+}
+
+// BatchedType returns the type of the events a batch event holds, or the
+// event's own type for other events, for routing batches as their events
+func BatchedType(event Event) string {
+	if event.Type == EventBatch {
+		if eventType, ok := event.Data["event_type"].(string); ok {
+			return eventType
+		}
+	}
+	return event.Type
+}
@@ -0,0 +1,93 @@
+package workflows
+
+import (
+	"context"
+	"sync"
+)
+
+// EventBroker is an in-memory EventBus that delivers each event to the
+// handlers subscribed when it is published. Handlers run synchronously on
+// the publisher's goroutine, so they must not block.
+type EventBroker struct {
+	mu            sync.RWMutex
+	subscriptions map[int]brokerSubscription
+	next          int
+}
+
+// brokerSubscription is a handler and the events it wants
+type brokerSubscription struct {
+	handler EventHandler
+	filters []EventFilter
+}
+
+// NewEventBroker creates an event broker with no subscribers
+func NewEventBroker() *EventBroker {
+	return &EventBroker{subscriptions: make(map[int]brokerSubscription)}
+}
+
+// Publish delivers an event to every subscribed handler whose filters it
+// matches and returns the first error a handler returned
+func (b *EventBroker) Publish(ctx context.Context, event Event) error {
+	InjectTrace(ctx, &event)
+	b.mu.RLock()
+	handlers := make([]EventHandler, 0, len(b.subscriptions))
+	for _, sub := range b.subscriptions {
+		if MatchesAny(sub.filters, event) {
+			handlers = append(handlers, sub.handler)
+		}
+	}
+	b.mu.RUnlock()
+
+	var first error
+	for _, handler := range handlers {
+		if err := handler(ctx, event); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Subscribe registers a handler for the events matching filters until ctx
+// is done
+func (b *EventBroker) Subscribe(ctx context.Context, handler EventHandler, filters ...EventFilter) error {
+	b.mu.Lock()
+	id := b.next
+	b.next++
+	b.subscriptions[id] = brokerSubscription{handler: TraceHandler(handler), filters: filters}
+	b.mu.Unlock()
+
+	if ctx.Done() != nil {
+		go func() {
+			<-ctx.Done()
+			b.mu.Lock()
+			delete(b.subscriptions, id)
+			b.mu.Unlock()
+		}()
+	}
+	return nil
+}
+
+// mirrorBus publishes to a bus and copies each event to others
+type mirrorBus struct {
+	EventBus
+	mirrors []EventBus
+}
+
+// Mirror returns an EventBus that subscribes on bus and publishes to it and
+// to each mirror, such as an in-process broker for the server's own
+// streams mirrored to a shared bus other systems consume. Every bus is
+// published to even if one fails; the first error is returned.
+func Mirror(bus EventBus, mirrors ...EventBus) EventBus {
+	return &mirrorBus{EventBus: bus, mirrors: mirrors}
+}
+
+// Publish publishes an event to the bus and its mirrors
+func (m *mirrorBus) Publish(ctx context.Context, event Event) error {
+	first := m.EventBus.Publish(ctx, event)
+	for _, mirror := range m.mirrors {
+		if err := mirror.Publish(ctx, event); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
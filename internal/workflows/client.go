@@ -9,6 +9,23 @@ import (
 	"time"
 )
 
+// WorkflowService is the execution backend used by the orchestrator and loader
+type WorkflowService interface {
+	ExecuteWorkflow(ctx context.Context, req ExecutionRequest) (*ExecutionResponse, error)
+	GetExecutionStatus(ctx context.Context, executionID string) (*ExecutionResponse, error)
+	CancelExecution(ctx context.Context, executionID string) error
+	RegisterWorkflow(ctx context.Context, workflow *BlobProcessingWorkflow) error
+	UpdateWorkflow(ctx context.Context, workflow *BlobProcessingWorkflow) error
+	GetWorkflow(ctx context.Context, workflowID string) (*BlobProcessingWorkflow, error)
+	ListWorkflows(ctx context.Context, providerID string) ([]*BlobProcessingWorkflow, error)
+}
+
+// HealthChecker is implemented by execution backends that can report
+// whether they are reachable and serving
+type HealthChecker interface {
+	CheckHealth(ctx context.Context) error
+}
+
 // WorkflowClient handles communication with the workflow service
 type WorkflowClient struct {
 	baseURL    string
@@ -32,6 +49,9 @@ type ExecutionRequest struct {
 	Context    ExecutionContext       `json:"context"`
 	Priority   int                    `json:"priority"`
 	Async      bool                   `json:"async"`
+	Lane       string                 `json:"lane,omitempty"`
+	Profile    string                 `json:"profile,omitempty"`     // interactive, or standard when empty
+	DeadlineMs int64                  `json:"deadline_ms,omitempty"` // interactive executions give up on unfinished steps after it
 }
 
 // ExecutionContext provides context for workflow execution
@@ -42,6 +62,7 @@ type ExecutionContext struct {
 	RequestID   string                 `json:"request_id"`
 	Metadata    map[string]interface{} `json:"metadata"`
 	TraceParent string                 `json:"trace_parent,omitempty"`
+	TraceState  string                 `json:"trace_state,omitempty"`
 }
 
 // ExecutionResponse represents the workflow execution result
@@ -60,6 +81,7 @@ type ExecutionError struct {
 	Message string `json:"message"`
 	Details string `json:"details,omitempty"`
 	StepID  string `json:"step_id,omitempty"`
+	Violations []SchemaViolation `json:"violations,omitempty"` // set for outputs that did not match their schema
 }
 
 // ExecuteWorkflow executes a workflow
@@ -77,6 +99,7 @@ func (c *WorkflowClient) ExecuteWorkflow(ctx context.Context, req ExecutionReque
 	}
 	
 	httpReq.Header.Set("Content-Type", "application/json")
+	SetTraceHeaders(httpReq.Header, req.Context.Trace())
 	
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
@@ -111,6 +134,9 @@ func (c *WorkflowClient) GetExecutionStatus(ctx context.Context, executionID str
 	}
 	defer resp.Body.Close()
 	
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", ErrExecutionNotFound, executionID)
+	}
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
@@ -138,6 +164,9 @@ func (c *WorkflowClient) CancelExecution(ctx context.Context, executionID string
 	}
 	defer resp.Body.Close()
 	
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s", ErrExecutionNotFound, executionID)
+	}
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
 		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
@@ -230,6 +259,28 @@ func (c *WorkflowClient) GetWorkflow(ctx context.Context, workflowID string) (*B
 	return &workflow, nil
 }
 
+// DeleteWorkflow removes a workflow definition
+func (c *WorkflowClient) DeleteWorkflow(ctx context.Context, workflowID string) error {
+	url := fmt.Sprintf("%s/workflows/%s", c.baseURL, workflowID)
+	
+	httpReq, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+	
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	
+	return nil
+}
+
 // ListWorkflows lists all workflows for a provider
 func (c *WorkflowClient) ListWorkflows(ctx context.Context, providerID string) ([]*BlobProcessingWorkflow, error) {
 	url := fmt.Sprintf("%s/workflows?provider_id=%s", c.baseURL, providerID)
@@ -255,4 +306,24 @@ func (c *WorkflowClient) ListWorkflows(ctx context.Context, providerID string) (
 	}
 	
 	return workflows, nil
+}
+
+// CheckHealth calls the workflow service's health endpoint
+func (c *WorkflowClient) CheckHealth(ctx context.Context) error {
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/health", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+	
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	
+	return nil
 }
\ No newline at end of file
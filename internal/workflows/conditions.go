@@ -0,0 +1,112 @@
+package workflows
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// regexCache holds compiled trigger condition patterns
+var regexCache sync.Map
+
+// Validate checks that a condition names a field and uses a known operator
+// with a usable value
+func (c TriggerCondition) Validate() error {
+	if c.Field == "" {
+		return fmt.Errorf("condition has no field")
+	}
+	switch c.Operator {
+	case "eq", "ne", "gt", "gte", "lt", "lte", "contains", "exists":
+		return nil
+	case "in", "not_in":
+		if _, ok := c.Value.([]interface{}); !ok {
+			return fmt.Errorf("condition on %s: %s needs a list value", c.Field, c.Operator)
+		}
+		return nil
+	case "regex":
+		if _, err := compilePattern(c.Value); err != nil {
+			return fmt.Errorf("condition on %s: %w", c.Field, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("condition on %s: unsupported operator %q", c.Field, c.Operator)
+}
+
+// Matches evaluates the condition against a blob in workflow input form.
+// Field is a dotted path into the blob such as metadata.content_language.
+// A missing field only satisfies ne, not_in and exists with a false value.
+func (c TriggerCondition) Matches(blob map[string]interface{}) bool {
+	path := "$.blob." + strings.TrimPrefix(strings.TrimPrefix(c.Field, "$.blob."), "blob.")
+	value, found := ExecutionScope{"blob": blob}.Lookup(path)
+	if found && value == nil {
+		found = false
+	}
+
+	switch c.Operator {
+	case "exists":
+		want, ok := c.Value.(bool)
+		return found == (want || !ok)
+	case "ne":
+		if !found {
+			return true
+		}
+	case "not_in":
+		return !found || !inList(value, c.Value)
+	}
+	if !found {
+		return false
+	}
+
+	switch c.Operator {
+	case "contains":
+		return containsValue(value, c.Value)
+	case "in":
+		return inList(value, c.Value)
+	case "regex":
+		re, err := compilePattern(c.Value)
+		return err == nil && re.MatchString(fmt.Sprint(value))
+	}
+	ok, err := Compare(value, c.Operator, c.Value)
+	return err == nil && ok
+}
+
+// containsValue reports whether a string holds a substring or a list holds an
+// element
+func containsValue(value, want interface{}) bool {
+	if list, ok := value.([]interface{}); ok {
+		return inList(want, list)
+	}
+	return strings.Contains(fmt.Sprint(value), fmt.Sprint(want))
+}
+
+// inList reports whether value equals any element of list
+func inList(value, list interface{}) bool {
+	items, ok := list.([]interface{})
+	if !ok {
+		return false
+	}
+	for _, item := range items {
+		if equal, _ := Compare(value, "eq", item); equal {
+			return true
+		}
+	}
+	return false
+}
+
+// compilePattern compiles a regex condition value, caching the result
+func compilePattern(value interface{}) (*regexp.Regexp, error) {
+	pattern, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("regex needs a string pattern")
+	}
+	if re, ok := regexCache.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	regexCache.Store(pattern, re)
+	return re, nil
+}
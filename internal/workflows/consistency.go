@@ -0,0 +1,280 @@
+package workflows
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	// DefaultConsistencyInterval is how often a consistency checker checks
+	// a sample of blobs
+	DefaultConsistencyInterval = 10 * time.Minute
+	// DefaultConsistencySample is how many blobs a consistency checker
+	// checks at a time
+	DefaultConsistencySample = 10
+	// DefaultConsistencyRecheck is how long a consistency checker waits
+	// before checking a divergent blob again
+	DefaultConsistencyRecheck = 2 * time.Second
+)
+
+// consistencyAttempts bounds how often a blob is read again when its log
+// moves while it is being checked
+const consistencyAttempts = 3
+
+// Divergence problems
+const (
+	DivergenceMissing    = "missing"    // materialized, but not in the stored state
+	DivergenceUnexpected = "unexpected" // stored, but not materialized
+	DivergenceDiffers    = "differs"    // in both, with different values
+)
+
+// StateStorage is DeltaStorage that keeps the state its deltas were
+// applied to, by path, and can pick blobs to check it for
+type StateStorage interface {
+	DeltaStorage
+	// State returns a blob's applied state, by path
+	State(ctx context.Context, blobID string) (map[string]interface{}, error)
+	// SampleBlobs picks up to n blobs with deltas at random
+	SampleBlobs(ctx context.Context, n int) ([]string, error)
+}
+
+// ConsistencyConfig configures a ConsistencyChecker; zero fields take the
+// defaults
+type ConsistencyConfig struct {
+	Interval time.Duration
+	Sample   int
+	Recheck  time.Duration
+}
+
+// StateDivergence is a path whose stored value is not the one its blob's
+// delta log leads to
+type StateDivergence struct {
+	Path         string      `json:"path"`
+	Problem      string      `json:"problem"`
+	Stored       interface{} `json:"stored,omitempty"`
+	Materialized interface{} `json:"materialized,omitempty"`
+}
+
+// ConsistencyReport is the outcome of checking one blob's stored state
+// against its delta log, as of the log's latest sequence
+type ConsistencyReport struct {
+	BlobID      string            `json:"blob_id"`
+	Sequence    int64             `json:"sequence"`
+	CheckedAt   time.Time         `json:"checked_at"`
+	Consistent  bool              `json:"consistent"`
+	Divergences []StateDivergence `json:"divergences,omitempty"`
+}
+
+// MaterializeState rebuilds the state storage keeps for a blob, by path,
+// from its whole delta log, applying each delta the way storage does: the
+// last delta on a path sets it, a delete removes it, a text_crdt delta
+// leaves the path's merged text and a patch delta the patched value. A
+// patch that does not apply is an error, as storage would have refused it.
+func MaterializeState(deltas []Delta) (map[string]interface{}, error) {
+	ordered := append([]Delta(nil), deltas...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Sequence < ordered[j].Sequence
+	})
+
+	state := make(map[string]interface{})
+	for i, delta := range ordered {
+		switch delta.Type {
+		case "delete":
+			delete(state, delta.Path)
+		case DeltaTextCRDT:
+			state[delta.Path] = ReplayText(ordered[:i+1], delta.Path)
+		case DeltaJSONPatch, DeltaMergePatch:
+			value, err := ApplyPatchDelta(ordered, delta)
+			if err != nil {
+				return nil, fmt.Errorf("failed to apply delta %s: %w", delta.ID, err)
+			}
+			state[delta.Path] = value
+		default:
+			state[delta.Path] = delta.NewValue
+		}
+	}
+	return state, nil
+}
+
+// CompareState lists the paths where a stored state differs from a
+// materialized one, in path order
+func CompareState(stored, materialized map[string]interface{}) []StateDivergence {
+	var divergences []StateDivergence
+	for _, path := range sortedKeys(materialized) {
+		value, ok := stored[path]
+		switch {
+		case !ok:
+			divergences = append(divergences, StateDivergence{Path: path, Problem: DivergenceMissing, Materialized: materialized[path]})
+		case !jsonEqual(value, materialized[path]):
+			divergences = append(divergences, StateDivergence{Path: path, Problem: DivergenceDiffers, Stored: value, Materialized: materialized[path]})
+		}
+	}
+	for _, path := range sortedKeys(stored) {
+		if _, ok := materialized[path]; !ok {
+			divergences = append(divergences, StateDivergence{Path: path, Problem: DivergenceUnexpected, Stored: stored[path]})
+		}
+	}
+	return divergences
+}
+
+// ConsistencyChecker re-materializes a random sample of blobs from their
+// delta logs every interval and compares the result with their stored
+// state, a safety net for bugs in how deltas are applied
+type ConsistencyChecker struct {
+	storage      StateStorage
+	sample       int
+	recheck      time.Duration
+	onDivergence func(*ConsistencyReport)
+	onError      func(error)
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewConsistencyChecker starts checking blobs of storage every interval.
+// onDivergence is told of each blob found inconsistent, and onError, if
+// set, of blobs that could not be checked. Close stops it.
+func NewConsistencyChecker(storage StateStorage, cfg ConsistencyConfig, onDivergence func(*ConsistencyReport), onError func(error)) *ConsistencyChecker {
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultConsistencyInterval
+	}
+	if cfg.Sample <= 0 {
+		cfg.Sample = DefaultConsistencySample
+	}
+	if cfg.Recheck <= 0 {
+		cfg.Recheck = DefaultConsistencyRecheck
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &ConsistencyChecker{
+		storage:      storage,
+		sample:       cfg.Sample,
+		recheck:      cfg.Recheck,
+		onDivergence: onDivergence,
+		onError:      onError,
+		cancel:       cancel,
+		done:         make(chan struct{}),
+	}
+	go c.run(ctx, cfg.Interval)
+	return c
+}
+
+// Close stops checking, waiting for a check in progress
+func (c *ConsistencyChecker) Close() {
+	c.cancel()
+	<-c.done
+}
+
+// run checks a sample every interval until the checker is closed
+func (c *ConsistencyChecker) run(ctx context.Context, interval time.Duration) {
+	defer close(c.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		c.checkSample(ctx)
+	}
+}
+
+// checkSample checks a random sample of blobs
+func (c *ConsistencyChecker) checkSample(ctx context.Context) {
+	blobIDs, err := c.storage.SampleBlobs(ctx, c.sample)
+	if err != nil {
+		c.report(fmt.Errorf("failed to sample blobs: %w", err))
+		return
+	}
+	for _, blobID := range blobIDs {
+		if ctx.Err() != nil {
+			return
+		}
+		report, err := c.Check(ctx, blobID)
+		if err != nil {
+			c.report(err)
+			continue
+		}
+		if !report.Consistent && c.onDivergence != nil {
+			c.onDivergence(report)
+		}
+	}
+}
+
+// Check checks one blob's stored state against its delta log. A blob
+// written to while it is read is read again, and one found divergent is
+// checked again after the recheck delay, so a write whose deltas were
+// stored but not yet applied is not taken for divergence; it is only
+// reported inconsistent when it diverges both times at the same sequence.
+func (c *ConsistencyChecker) Check(ctx context.Context, blobID string) (*ConsistencyReport, error) {
+	var previous *ConsistencyReport
+	for attempt := 0; attempt < consistencyAttempts; attempt++ {
+		report, err := c.check(ctx, blobID)
+		if err != nil {
+			return nil, err
+		}
+		if report == nil {
+			continue // the log moved while it was read
+		}
+		if report.Consistent || (previous != nil && previous.Sequence == report.Sequence) {
+			return report, nil
+		}
+		previous = report
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(c.recheck):
+		}
+	}
+	return nil, fmt.Errorf("failed to check blob %s: it kept changing", blobID)
+}
+
+// check reads a blob's log, then its state, then its log again, and
+// compares them; it returns nil when the log moved in between
+func (c *ConsistencyChecker) check(ctx context.Context, blobID string) (*ConsistencyReport, error) {
+	deltas, err := c.storage.GetByBlobID(ctx, blobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read deltas of blob %s: %w", blobID, err)
+	}
+	stored, err := c.storage.State(ctx, blobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state of blob %s: %w", blobID, err)
+	}
+	after, err := c.storage.GetByBlobID(ctx, blobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read deltas of blob %s: %w", blobID, err)
+	}
+	if latestSequence(after) != latestSequence(deltas) {
+		return nil, nil
+	}
+
+	report := &ConsistencyReport{BlobID: blobID, Sequence: latestSequence(deltas), CheckedAt: time.Now()}
+	materialized, err := MaterializeState(deltas)
+	if err != nil {
+		return nil, fmt.Errorf("failed to materialize blob %s: %w", blobID, err)
+	}
+	report.Divergences = CompareState(stored, materialized)
+	report.Consistent = len(report.Divergences) == 0
+	return report, nil
+}
+
+// report passes an error to onError, if set
+func (c *ConsistencyChecker) report(err error) {
+	if c.onError != nil {
+		c.onError(err)
+	}
+}
+
+// latestSequence returns the highest sequence in a delta log
+func latestSequence(deltas []Delta) int64 {
+	var latest int64
+	for _, delta := range deltas {
+		if delta.Sequence > latest {
+			latest = delta.Sequence
+		}
+	}
+	return latest
+}
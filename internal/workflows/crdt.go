@@ -0,0 +1,333 @@
+package workflows
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// DeltaTextCRDT is the type of deltas that edit the text at their path
+// collaboratively. Their new value is a TextEdit. Edits made concurrently,
+// by two providers or two users' editors, merge into the same text
+// whichever is applied first.
+const DeltaTextCRDT = "text_crdt"
+
+// Text edit operations
+const (
+	TextInsert = "insert"
+	TextDelete = "delete"
+)
+
+// ErrInvalidTextEdit is returned for text edits that are malformed or do
+// not fit the text they were made against
+var ErrInvalidTextEdit = errors.New("invalid text edit")
+
+// TextOp inserts Text at Pos, or deletes Len characters from Pos. Positions
+// count characters, not bytes, of the text as the edit's author saw it
+// after the edit's earlier operations.
+type TextOp struct {
+	Op   string `json:"op"`
+	Pos  int    `json:"pos"`
+	Text string `json:"text,omitempty"`
+	Len  int    `json:"len,omitempty"`
+}
+
+// TextEdit is the new value of a text_crdt delta: operations made at a
+// site, such as a provider or an editor session. Clock is the author's
+// vector clock: for each site, how many of its edits the author had seen,
+// counting this one for its own site. Positions are read against the text
+// as it was with exactly those edits.
+type TextEdit struct {
+	Site  string           `json:"site"`
+	Clock map[string]int64 `json:"clock"`
+	Ops   []TextOp         `json:"ops"`
+}
+
+// ParseTextEdit reads a text_crdt delta's new value
+func ParseTextEdit(value interface{}) (TextEdit, error) {
+	var edit TextEdit
+	data, err := json.Marshal(value)
+	if err != nil {
+		return edit, fmt.Errorf("%w: %v", ErrInvalidTextEdit, err)
+	}
+	if err := json.Unmarshal(data, &edit); err != nil {
+		return edit, fmt.Errorf("%w: %v", ErrInvalidTextEdit, err)
+	}
+	return edit, edit.validate()
+}
+
+// validate checks an edit's fields, not its positions
+func (e TextEdit) validate() error {
+	if e.Site == "" {
+		return fmt.Errorf("%w: site is required", ErrInvalidTextEdit)
+	}
+	if e.Clock[e.Site] < 1 {
+		return fmt.Errorf("%w: clock must count the edit for site %s", ErrInvalidTextEdit, e.Site)
+	}
+	for site, count := range e.Clock {
+		if site == "" || count < 0 {
+			return fmt.Errorf("%w: clock has %d for site %q", ErrInvalidTextEdit, count, site)
+		}
+	}
+	if len(e.Ops) == 0 {
+		return fmt.Errorf("%w: ops are required", ErrInvalidTextEdit)
+	}
+	for i, op := range e.Ops {
+		switch {
+		case op.Pos < 0:
+			return fmt.Errorf("%w: op %d is at %d", ErrInvalidTextEdit, i, op.Pos)
+		case op.Op == TextInsert && op.Text == "":
+			return fmt.Errorf("%w: op %d inserts no text", ErrInvalidTextEdit, i)
+		case op.Op == TextDelete && op.Len < 1:
+			return fmt.Errorf("%w: op %d deletes %d characters", ErrInvalidTextEdit, i, op.Len)
+		case op.Op != TextInsert && op.Op != TextDelete:
+			return fmt.Errorf("%w: op %d is %q, not insert or delete", ErrInvalidTextEdit, i, op.Op)
+		}
+	}
+	return nil
+}
+
+// seq returns the edit's number among its site's edits
+func (e TextEdit) seq() int64 {
+	return e.Clock[e.Site]
+}
+
+// sees reports whether the edit's author had seen an edit of a site. The
+// text before any edit, of no site, is seen by every edit.
+func (e TextEdit) sees(site string, seq int64) bool {
+	return site == "" || seq <= e.Clock[site]
+}
+
+// charID identifies a character by the edit that inserted it and its
+// place among that edit's characters. Stamp sums the edit's clock, so an
+// edit made after seeing another always has the larger stamp.
+type charID struct {
+	stamp int64
+	site  string
+	seq   int64
+	index int
+}
+
+// newer orders the characters inserted at the same place: newer ones come
+// first, with ties between concurrent edits broken by site
+func (a charID) newer(b charID) bool {
+	if a.stamp != b.stamp {
+		return a.stamp > b.stamp
+	}
+	if a.site != b.site {
+		return a.site > b.site
+	}
+	if a.seq != b.seq {
+		return a.seq > b.seq
+	}
+	return a.index > b.index
+}
+
+// editRef names an edit by its site and number
+type editRef struct {
+	site string
+	seq  int64
+}
+
+// textChar is a character of a document, kept after it is deleted so
+// edits made before the deletion still find their positions
+type textChar struct {
+	id        charID
+	r         rune
+	deletedBy []editRef
+}
+
+// TextDocument merges text edits as a replicated growable array: each
+// inserted character is placed after the one it was typed after, ahead of
+// older characters placed there, so concurrent edits converge. Edits of a
+// site must be applied in the order it made them.
+type TextDocument struct {
+	chars []textChar
+	seen  map[string]int64
+}
+
+// NewTextDocument starts a document from text every edit has seen
+func NewTextDocument(text string) *TextDocument {
+	d := &TextDocument{seen: make(map[string]int64)}
+	for i, r := range []rune(text) {
+		d.chars = append(d.chars, textChar{id: charID{index: i}, r: r})
+	}
+	return d
+}
+
+// String returns the document's text
+func (d *TextDocument) String() string {
+	var b strings.Builder
+	for _, c := range d.chars {
+		if len(c.deletedBy) == 0 {
+			b.WriteRune(c.r)
+		}
+	}
+	return b.String()
+}
+
+// Apply merges an edit into the document. An edit that is malformed, does
+// not follow its site's last edit or has positions outside the text its
+// author saw is rejected and leaves the document unchanged.
+func (d *TextDocument) Apply(edit TextEdit) error {
+	if err := edit.validate(); err != nil {
+		return err
+	}
+	seq := edit.seq()
+	if last := d.seen[edit.Site]; seq <= last {
+		return fmt.Errorf("%w: edit %d of site %s is not after its edit %d", ErrInvalidTextEdit, seq, edit.Site, last)
+	}
+	length := 0
+	for _, c := range d.chars {
+		if d.visible(c, edit) {
+			length++
+		}
+	}
+	for i, op := range edit.Ops {
+		if op.Op == TextInsert {
+			if op.Pos > length {
+				return fmt.Errorf("%w: op %d inserts at %d of %d characters", ErrInvalidTextEdit, i, op.Pos, length)
+			}
+			length += utf8.RuneCountInString(op.Text)
+			continue
+		}
+		if op.Pos+op.Len > length {
+			return fmt.Errorf("%w: op %d deletes %d-%d of %d characters", ErrInvalidTextEdit, i, op.Pos, op.Pos+op.Len, length)
+		}
+		length -= op.Len
+	}
+
+	var stamp int64
+	for _, count := range edit.Clock {
+		if stamp > math.MaxInt64-count {
+			return fmt.Errorf("%w: clock overflows", ErrInvalidTextEdit)
+		}
+		stamp += count
+	}
+	index := 0
+	for _, op := range edit.Ops {
+		if op.Op == TextDelete {
+			for n := 0; n < op.Len; n++ {
+				i := d.find(op.Pos, edit)
+				d.chars[i].deletedBy = append(d.chars[i].deletedBy, editRef{site: edit.Site, seq: seq})
+			}
+			continue
+		}
+		// Place the text after the character before Pos, past any newer
+		// characters already placed there
+		at := 0
+		if op.Pos > 0 {
+			at = d.find(op.Pos-1, edit) + 1
+		}
+		for _, r := range op.Text {
+			id := charID{stamp: stamp, site: edit.Site, seq: seq, index: index}
+			index++
+			for at < len(d.chars) && d.chars[at].id.newer(id) {
+				at++
+			}
+			d.chars = append(d.chars, textChar{})
+			copy(d.chars[at+1:], d.chars[at:])
+			d.chars[at] = textChar{id: id, r: r}
+			at++
+		}
+	}
+	d.seen[edit.Site] = seq
+	return nil
+}
+
+// visible reports whether an edit's author saw a character: they had seen
+// the edit inserting it and none deleting it
+func (d *TextDocument) visible(c textChar, edit TextEdit) bool {
+	if !edit.sees(c.id.site, c.id.seq) {
+		return false
+	}
+	for _, ref := range c.deletedBy {
+		if edit.sees(ref.site, ref.seq) {
+			return false
+		}
+	}
+	return true
+}
+
+// find returns the index of the character at a position of the text an
+// edit's author sees; the position is known to be in range
+func (d *TextDocument) find(pos int, edit TextEdit) int {
+	for i, c := range d.chars {
+		if !d.visible(c, edit) {
+			continue
+		}
+		if pos == 0 {
+			return i
+		}
+		pos--
+	}
+	return len(d.chars)
+}
+
+// ReplayText materializes the text at a path from deltas as Replay does,
+// merging the text_crdt edits among them
+func ReplayText(deltas []Delta, path string) string {
+	var p DeltaProcessor
+	text, _ := lookup(p.Replay(deltas, math.MaxInt64), pathSegments(path)).(string)
+	return text
+}
+
+// mergeText merges a text_crdt delta's edit into the text at its path and
+// returns the result, or false when the edit cannot be applied. docs, if
+// not nil, keeps each path's document between deltas so concurrent edits
+// merge; a document whose text was since set by other deltas starts over
+// from that text.
+func mergeText(state map[string]interface{}, delta Delta, docs map[string]*TextDocument) (string, bool) {
+	segments := pathSegments(delta.Path)
+	if len(segments) == 0 {
+		return "", false
+	}
+	value := lookup(state, segments)
+	current, ok := value.(string)
+	if !ok && value != nil {
+		return "", false
+	}
+	edit, err := ParseTextEdit(delta.NewValue)
+	if err != nil {
+		return "", false
+	}
+	key := strings.Join(segments, "/")
+	doc := docs[key]
+	if doc == nil || doc.String() != current {
+		doc = NewTextDocument(current)
+	}
+	if err := doc.Apply(edit); err != nil {
+		return "", false
+	}
+	if docs != nil {
+		docs[key] = doc
+	}
+	return doc.String(), true
+}
+
+// lookup returns the value under a path's segments, or nil
+func lookup(state map[string]interface{}, segments []string) interface{} {
+	if len(segments) == 0 {
+		return nil
+	}
+	var node interface{} = state
+	for _, segment := range segments[:len(segments)-1] {
+		if node = child(node, segment); node == nil {
+			return nil
+		}
+	}
+	last := segments[len(segments)-1]
+	switch n := node.(type) {
+	case map[string]interface{}:
+		return n[last]
+	case []interface{}:
+		if i, err := strconv.Atoi(last); err == nil && i >= 0 && i < len(n) {
+			return n[i]
+		}
+	}
+	return nil
+}
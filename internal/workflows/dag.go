@@ -0,0 +1,157 @@
+package workflows
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// ProviderGraph is the provider dependency graph: an edge runs from a
+// provider to each other provider that a step of one of its workflows calls
+type ProviderGraph struct {
+	Nodes []ProviderNode `json:"nodes"`
+	Edges []ProviderEdge `json:"edges"`
+}
+
+// ProviderNode is a provider in the graph. Providers that are called by
+// steps but were never registered appear with Registered false.
+type ProviderNode struct {
+	ID            string   `json:"id"`
+	Name          string   `json:"name,omitempty"`
+	Type          string   `json:"type,omitempty"`
+	Registered    bool     `json:"registered"`
+	Active        bool     `json:"active"`
+	TriggerEvents []string `json:"trigger_events"`
+	WorkflowCount int      `json:"workflow_count"`
+}
+
+// ProviderEdge is a dependency of one provider on another, with the
+// workflows of the source provider that call the target
+type ProviderEdge struct {
+	Source      string   `json:"source"`
+	Target      string   `json:"target"`
+	WorkflowIDs []string `json:"workflow_ids"`
+}
+
+// ProviderGraph builds the provider dependency graph from the registered
+// providers and workflows, with nodes and edges ordered by ID
+func (o *Orchestrator) ProviderGraph() *ProviderGraph {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	nodes := make(map[string]*ProviderNode)
+	edges := make(map[[2]string]*ProviderEdge)
+	for providerID, provider := range o.providers {
+		events := []string{}
+		seenEvents := make(map[string]bool)
+		for _, trigger := range provider.Triggers {
+			if !seenEvents[trigger.Event] {
+				seenEvents[trigger.Event] = true
+				events = append(events, trigger.Event)
+			}
+		}
+		sort.Strings(events)
+		nodes[providerID] = &ProviderNode{
+			ID:            providerID,
+			Name:          provider.Name,
+			Type:          provider.Type,
+			Registered:    true,
+			Active:        provider.Active,
+			TriggerEvents: events,
+			WorkflowCount: len(provider.WorkflowIDs),
+		}
+
+		for _, workflowID := range o.expandWorkflowIDs(provider.WorkflowIDs) {
+			workflow, exists := o.workflows[workflowID]
+			if !exists {
+				continue
+			}
+			for _, step := range workflow.Steps {
+				if step.ProviderID == "" || step.ProviderID == providerID {
+					continue
+				}
+				key := [2]string{providerID, step.ProviderID}
+				edge, ok := edges[key]
+				if !ok {
+					edge = &ProviderEdge{Source: providerID, Target: step.ProviderID}
+					edges[key] = edge
+				}
+				if n := len(edge.WorkflowIDs); n == 0 || edge.WorkflowIDs[n-1] != workflowID {
+					edge.WorkflowIDs = append(edge.WorkflowIDs, workflowID)
+				}
+			}
+		}
+	}
+
+	graph := &ProviderGraph{Nodes: []ProviderNode{}, Edges: []ProviderEdge{}}
+	for _, edge := range edges {
+		if _, ok := nodes[edge.Target]; !ok {
+			nodes[edge.Target] = &ProviderNode{ID: edge.Target, TriggerEvents: []string{}}
+		}
+		sort.Strings(edge.WorkflowIDs)
+		graph.Edges = append(graph.Edges, *edge)
+	}
+	for _, node := range nodes {
+		graph.Nodes = append(graph.Nodes, *node)
+	}
+	sort.Slice(graph.Nodes, func(i, j int) bool { return graph.Nodes[i].ID < graph.Nodes[j].ID })
+	sort.Slice(graph.Edges, func(i, j int) bool {
+		if graph.Edges[i].Source != graph.Edges[j].Source {
+			return graph.Edges[i].Source < graph.Edges[j].Source
+		}
+		return graph.Edges[i].Target < graph.Edges[j].Target
+	})
+	return graph
+}
+
+// WriteDOT writes the graph in Graphviz DOT. Inactive providers are drawn
+// dashed and unregistered ones dotted; edges are labelled with the
+// workflows that create them.
+func (g *ProviderGraph) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph providers {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box];\n")
+	for _, node := range g.Nodes {
+		label := node.ID
+		if node.Name != "" && node.Name != node.ID {
+			label += "\n" + node.Name
+		}
+		if len(node.TriggerEvents) > 0 {
+			label += "\n" + strings.Join(node.TriggerEvents, ", ")
+		}
+		switch {
+		case node.Registered && node.WorkflowCount == 1:
+			label += "\n1 workflow"
+		case node.Registered:
+			label += fmt.Sprintf("\n%d workflows", node.WorkflowCount)
+		}
+		style := ""
+		switch {
+		case !node.Registered:
+			style = ", style=dotted"
+		case !node.Active:
+			style = ", style=dashed"
+		}
+		fmt.Fprintf(&b, "  %s [label=%s%s];\n", dotQuote(node.ID), dotQuote(label), style)
+	}
+	for _, edge := range g.Edges {
+		fmt.Fprintf(&b, "  %s -> %s [label=%s];\n", dotQuote(edge.Source), dotQuote(edge.Target), dotQuote(strings.Join(edge.WorkflowIDs, "\n")))
+	}
+	b.WriteString("}\n")
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("failed to write DOT: %w", err)
+	}
+	return nil
+}
+
+// dotQuote quotes a DOT ID, escaping quotes and turning newlines into line
+// breaks
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
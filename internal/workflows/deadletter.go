@@ -0,0 +1,280 @@
+package workflows
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Dead letter errors
+var (
+	ErrDeadLetterNotFound = errors.New("dead letter not found")
+	ErrSubscriptionEnded  = errors.New("subscription has ended")
+	ErrReplayFailed       = errors.New("replay failed")
+)
+
+// Where an event failed
+const (
+	DeadLetterHandler = "handler" // a subscribed handler kept failing on it
+	DeadLetterPublish = "publish" // the bus would not take it
+)
+
+const (
+	// DefaultDeliveryAttempts is how many times a handler is given an event
+	// before it is dead-lettered
+	DefaultDeliveryAttempts = 3
+	// DefaultDeliveryBackoff is the wait before the first redelivery,
+	// doubled for each one after
+	DefaultDeliveryBackoff = 100 * time.Millisecond
+	// DefaultDeadLetterLimit bounds a queue; the oldest letters go first
+	DefaultDeadLetterLimit = 1000
+)
+
+// DeadLetter is an event that could not be published or handled: the
+// event as it was, the last error and how many attempts were made
+type DeadLetter struct {
+	ID        string    `json:"id"`
+	EventType string    `json:"event_type"`
+	Event     Event     `json:"event"`
+	Stage     string    `json:"stage"` // handler or publish
+	Error     string    `json:"error"`
+	Attempts  int       `json:"attempts"`
+	FailedAt  time.Time `json:"failed_at"`
+	Replays   int       `json:"replays,omitempty"`
+
+	subscription int // the failing handler's, for handler letters
+}
+
+// DeadLetterFilter selects dead letters. Empty fields match every letter.
+type DeadLetterFilter struct {
+	UserID    string
+	EventType string
+	Stage     string
+}
+
+// DeadLetterConfig configures a DeadLetterQueue; zero fields take the
+// defaults. Handlers that return a permanent error are not retried.
+type DeadLetterConfig struct {
+	Attempts int
+	Backoff  time.Duration
+	Limit    int
+}
+
+// DeadLetterQueue is an EventBus that keeps the events another bus failed
+// on. Events the bus will not publish are kept as they are, and each
+// subscribed handler is given an event again, with backoff, until it
+// succeeds or runs out of attempts, when the event is kept for that
+// handler. Kept events can be listed and replayed: a publish again, or a
+// handler's event to that handler while it is still subscribed.
+type DeadLetterQueue struct {
+	bus      EventBus
+	attempts int
+	backoff  time.Duration
+	limit    int
+
+	mu       sync.RWMutex
+	letters  map[string]*DeadLetter
+	order    []string
+	handlers map[int]EventHandler
+	next     int
+}
+
+// NewDeadLetterQueue wraps a bus with a dead letter queue
+func NewDeadLetterQueue(bus EventBus, cfg DeadLetterConfig) *DeadLetterQueue {
+	if cfg.Attempts <= 0 {
+		cfg.Attempts = DefaultDeliveryAttempts
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = DefaultDeliveryBackoff
+	}
+	if cfg.Limit <= 0 {
+		cfg.Limit = DefaultDeadLetterLimit
+	}
+	return &DeadLetterQueue{
+		bus:      bus,
+		attempts: cfg.Attempts,
+		backoff:  cfg.Backoff,
+		limit:    cfg.Limit,
+		letters:  make(map[string]*DeadLetter),
+		handlers: make(map[int]EventHandler),
+	}
+}
+
+// Publish publishes an event, keeping it if the bus fails
+func (q *DeadLetterQueue) Publish(ctx context.Context, event Event) error {
+	err := q.bus.Publish(ctx, event)
+	if err != nil {
+		q.add(&DeadLetter{Event: event, Stage: DeadLetterPublish, Error: err.Error(), Attempts: 1})
+	}
+	return err
+}
+
+// Subscribe subscribes a handler to the bus with its filters, retrying the
+// events it fails on and keeping those it keeps failing on
+func (q *DeadLetterQueue) Subscribe(ctx context.Context, handler EventHandler, filters ...EventFilter) error {
+	q.mu.Lock()
+	id := q.next
+	q.next++
+	q.handlers[id] = handler
+	q.mu.Unlock()
+
+	err := q.bus.Subscribe(ctx, func(ctx context.Context, event Event) error {
+		attempts, err := q.deliver(ctx, handler, event)
+		if err != nil {
+			q.add(&DeadLetter{Event: event, Stage: DeadLetterHandler, Error: err.Error(), Attempts: attempts, subscription: id})
+		}
+		return err
+	}, filters...)
+	if err != nil {
+		q.unsubscribe(id)
+		return err
+	}
+	if ctx.Done() != nil {
+		go func() {
+			<-ctx.Done()
+			q.unsubscribe(id)
+		}()
+	}
+	return nil
+}
+
+// unsubscribe forgets a handler, so its letters can no longer be replayed
+func (q *DeadLetterQueue) unsubscribe(id int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.handlers, id)
+}
+
+// deliver gives a handler an event until it succeeds, fails permanently or
+// runs out of attempts, and returns the attempts made and the last error
+func (q *DeadLetterQueue) deliver(ctx context.Context, handler EventHandler, event Event) (int, error) {
+	backoff := q.backoff
+	for attempt := 1; ; attempt++ {
+		err := handler(ctx, event)
+		if err == nil || attempt == q.attempts || !IsRetryable(err) {
+			return attempt, err
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return attempt, err
+		}
+		backoff *= 2
+	}
+}
+
+// add keeps a letter, forgetting the oldest when the queue is full
+func (q *DeadLetterQueue) add(letter *DeadLetter) {
+	letter.ID = uuid.New().String()
+	letter.EventType = letter.Event.Type
+	letter.FailedAt = time.Now()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.letters[letter.ID] = letter
+	q.order = append(q.order, letter.ID)
+	for len(q.order) > q.limit {
+		delete(q.letters, q.order[0])
+		q.order = q.order[1:]
+	}
+}
+
+// List returns the letters a filter matches, newest first
+func (q *DeadLetterQueue) List(filter DeadLetterFilter) []DeadLetter {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	letters := []DeadLetter{}
+	for i := len(q.order) - 1; i >= 0; i-- {
+		letter, ok := q.letters[q.order[i]]
+		if !ok {
+			continue
+		}
+		switch {
+		case filter.UserID != "" && letter.Event.UserID != filter.UserID,
+			filter.EventType != "" && letter.EventType != filter.EventType,
+			filter.Stage != "" && letter.Stage != filter.Stage:
+			continue
+		}
+		letters = append(letters, *letter)
+	}
+	return letters
+}
+
+// Get returns a letter
+func (q *DeadLetterQueue) Get(id string) (DeadLetter, error) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	letter, ok := q.letters[id]
+	if !ok {
+		return DeadLetter{}, fmt.Errorf("%w: %s", ErrDeadLetterNotFound, id)
+	}
+	return *letter, nil
+}
+
+// Delete discards a letter
+func (q *DeadLetterQueue) Delete(id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.letters[id]; !ok {
+		return fmt.Errorf("%w: %s", ErrDeadLetterNotFound, id)
+	}
+	q.remove(id)
+	return nil
+}
+
+// remove forgets a letter; the caller holds the lock
+func (q *DeadLetterQueue) remove(id string) {
+	delete(q.letters, id)
+	for i, letterID := range q.order {
+		if letterID == id {
+			q.order = append(q.order[:i], q.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// Replay tries a letter's event again, once: a publish letter is published
+// to the bus and a handler letter given to its handler. A letter that
+// replays is discarded; one that fails again is kept with the new error.
+func (q *DeadLetterQueue) Replay(ctx context.Context, id string) error {
+	letter, err := q.Get(id)
+	if err != nil {
+		return err
+	}
+
+	if letter.Stage == DeadLetterPublish {
+		err = q.bus.Publish(ctx, letter.Event)
+	} else {
+		q.mu.RLock()
+		handler, ok := q.handlers[letter.subscription]
+		q.mu.RUnlock()
+		if !ok {
+			return fmt.Errorf("%w: the handler that failed on %s is no longer subscribed", ErrSubscriptionEnded, id)
+		}
+		err = handler(ctx, letter.Event)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	stored, ok := q.letters[id]
+	if err == nil {
+		if ok {
+			q.remove(id)
+		}
+		return nil
+	}
+	if ok {
+		stored.Attempts++
+		stored.Replays++
+		stored.Error = err.Error()
+		stored.FailedAt = time.Now()
+	}
+	return fmt.Errorf("%w: %v", ErrReplayFailed, err)
+}
@@ -3,6 +3,7 @@ package workflows
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"time"
 )
 
@@ -25,6 +26,11 @@ type BlobProcessingWorkflow struct {
 	Type        WorkflowType             `json:"type"`
 	Steps       []BlobProcessingStep     `json:"steps"`
 	Config      ProcessingConfig         `json:"config"`
+	LintSuppress []string                `json:"lint_suppress,omitempty"` // lint rules not applied to the workflow
+	MutexKey    string                   `json:"mutex_key,omitempty"` // default mutex key template for its steps
+	OutputSchemaID string                `json:"output_schema_id,omitempty"` // registered schema the workflow output must match
+	Rollback    *RollbackPolicy          `json:"rollback,omitempty"` // how a failed execution is undone when Config.EnableRollback is set
+	Version     int                      `json:"version,omitempty"` // set by the registry, 1 when created and bumped by each update
 	CreatedAt   time.Time                `json:"created_at"`
 	UpdatedAt   time.Time                `json:"updated_at"`
 }
@@ -42,6 +48,9 @@ type BlobProcessingStep struct {
 	Condition    string                 `json:"condition,omitempty"` // Expression to evaluate
 	OnFailure    string                 `json:"on_failure"` // fail, skip, retry
 	RetryPolicy  *RetryPolicy           `json:"retry_policy,omitempty"`
+	LintSuppress []string               `json:"lint_suppress,omitempty"` // lint rules not applied to the step
+	MutexKey     string                 `json:"mutex_key,omitempty"` // e.g. "book:{namespace_id}:outline"; steps sharing a key run one at a time
+	OutputSchemaID string               `json:"output_schema_id,omitempty"` // registered schema the step output must match
 }
 
 // StepConfig holds step-specific configuration
@@ -52,6 +61,8 @@ type StepConfig struct {
 	CacheResults      bool                   `json:"cache_results"`
 	CacheTTL          int                    `json:"cache_ttl_seconds"`
 	Parameters        map[string]interface{} `json:"parameters"`
+	MaxOutputBytes    int                    `json:"max_output_bytes,omitempty"`
+	OutputPolicy      string                 `json:"output_policy,omitempty"` // truncate, spill, fail
 }
 
 // ProcessingConfig holds workflow-level configuration
@@ -68,10 +79,10 @@ type ProcessingConfig struct {
 
 // RetryPolicy defines retry behavior
 type RetryPolicy struct {
-	MaxAttempts       int    `json:"max_attempts"`
-	BackoffMultiplier float64 `json:"backoff_multiplier"`
-	InitialDelay      int    `json:"initial_delay_ms"`
-	MaxDelay          int    `json:"max_delay_ms"`
+	MaxAttempts       int    `json:"max_attempts" yaml:"max_attempts"`
+	BackoffMultiplier float64 `json:"backoff_multiplier" yaml:"backoff_multiplier"`
+	InitialDelay      int    `json:"initial_delay_ms" yaml:"initial_delay_ms"`
+	MaxDelay          int    `json:"max_delay_ms" yaml:"max_delay_ms"`
 }
 
 // DeltaWorkflow defines a workflow for applying deltas to blobs
@@ -110,10 +121,12 @@ type ValidationRule struct {
 	Severity   string `json:"severity"` // error, warning, info
 }
 
-// RollbackPolicy defines how to handle rollbacks
+// RollbackPolicy defines how to handle rollbacks. For a processing
+// workflow the compensation map names, for a step, the workflow that undoes
+// what the step did outside the blob.
 type RollbackPolicy struct {
 	Enabled          bool              `json:"enabled"`
-	MaxRollbackDepth int               `json:"max_rollback_depth"`
+	MaxRollbackDepth int               `json:"max_rollback_depth"` // most deltas undone, 0 for no limit
 	Strategy         string            `json:"strategy"` // immediate, deferred, manual
 	CompensationMap  map[string]string `json:"compensation_map"` // Maps operations to compensations
 }
@@ -182,12 +195,13 @@ func (w *BlobProcessingWorkflow) GetDAGOrder() ([][]BlobProcessingStep, error) {
 	var levels [][]BlobProcessingStep
 	queue := []string{}
 	
-	// Find nodes with no dependencies
+	// Find nodes with no dependencies, sorted so the order is deterministic
 	for id, degree := range inDegree {
 		if degree == 0 {
 			queue = append(queue, id)
 		}
 	}
+	sort.Strings(queue)
 	
 	for len(queue) > 0 {
 		levelSize := len(queue)
@@ -217,7 +231,7 @@ func (w *BlobProcessingWorkflow) GetDAGOrder() ([][]BlobProcessingStep, error) {
 	}
 	
 	if processedCount != len(w.Steps) {
-		return nil, fmt.Errorf("workflow contains cycles")
+		return nil, ErrWorkflowCycle
 	}
 	
 	return levels, nil
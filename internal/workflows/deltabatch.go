@@ -0,0 +1,139 @@
+package workflows
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidDeltaBatch is returned for delta batches that cannot be
+// committed
+var ErrInvalidDeltaBatch = errors.New("invalid delta batch")
+
+// BatchStorage is DeltaStorage that can commit a batch of deltas
+// atomically
+type BatchStorage interface {
+	DeltaStorage
+	// CommitBatch stores a batch's deltas under consecutive sequences and
+	// applies them to its blob, all or nothing
+	CommitBatch(ctx context.Context, batch *DeltaBatch) error
+}
+
+// DeltaBatch is a set of deltas to one blob that are stored and applied
+// together, such as the deltas of one workflow output: either all of them
+// change the blob or none does
+type DeltaBatch struct {
+	ID     string  `json:"id"`
+	BlobID string  `json:"blob_id"`
+	Deltas []Delta `json:"deltas"`
+}
+
+// NewDeltaBatch groups deltas to a blob into a batch, recording the
+// batch's ID in each delta's metadata. The deltas are copied, so the
+// caller's are left as they were.
+func NewDeltaBatch(blobID string, deltas []Delta) *DeltaBatch {
+	batch := &DeltaBatch{ID: uuid.New().String(), BlobID: blobID, Deltas: make([]Delta, len(deltas))}
+	for i, delta := range deltas {
+		metadata := make(map[string]interface{}, len(delta.Metadata)+1)
+		for key, value := range delta.Metadata {
+			metadata[key] = value
+		}
+		metadata["batch_id"] = batch.ID
+		delta.Metadata = metadata
+		batch.Deltas[i] = delta
+	}
+	return batch
+}
+
+// Validate checks a batch before any of it is stored: it must hold
+// deltas, each with an ID of its own and a type, all to the batch's blob,
+// and the edits of text_crdt, json_patch and merge_patch deltas must parse.
+// Its errors wrap ErrInvalidDeltaBatch.
+func (b *DeltaBatch) Validate() error {
+	if len(b.Deltas) == 0 {
+		return fmt.Errorf("%w: batch %s has no deltas", ErrInvalidDeltaBatch, b.ID)
+	}
+	seen := make(map[string]bool, len(b.Deltas))
+	for _, delta := range b.Deltas {
+		switch {
+		case delta.ID == "":
+			return fmt.Errorf("%w: delta to %s has no ID", ErrInvalidDeltaBatch, delta.Path)
+		case seen[delta.ID]:
+			return fmt.Errorf("%w: delta %s is in the batch twice", ErrInvalidDeltaBatch, delta.ID)
+		case delta.BlobID != b.BlobID:
+			return fmt.Errorf("%w: delta %s is to blob %s, not %s", ErrInvalidDeltaBatch, delta.ID, delta.BlobID, b.BlobID)
+		case delta.Type == "":
+			return fmt.Errorf("%w: delta %s has no type", ErrInvalidDeltaBatch, delta.ID)
+		}
+		seen[delta.ID] = true
+
+		var err error
+		switch delta.Type {
+		case DeltaTextCRDT:
+			_, err = ParseTextEdit(delta.NewValue)
+		case DeltaJSONPatch:
+			_, err = ParsePatch(delta.NewValue)
+		case DeltaMergePatch:
+			err = checkMergePatch(delta)
+		}
+		if err != nil {
+			return fmt.Errorf("%w: delta %s to %s: %v", ErrInvalidDeltaBatch, delta.ID, delta.Path, err)
+		}
+	}
+	return nil
+}
+
+// checkMergePatch checks a merge_patch delta's new value is JSON, and an
+// object when it patches the whole blob, which must stay an object
+func checkMergePatch(delta Delta) error {
+	if _, err := json.Marshal(delta.NewValue); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	if _, ok := delta.NewValue.(map[string]interface{}); !ok && len(pathSegments(delta.Path)) == 0 {
+		return fmt.Errorf("%w: a merge patch of the whole blob is an object", ErrInvalidPatch)
+	}
+	return nil
+}
+
+// eventData returns the data of the batch's delta.batch_applied event:
+// the batch, and the ID, type and path of each of its deltas
+func (b *DeltaBatch) eventData() map[string]interface{} {
+	deltas := make([]interface{}, len(b.Deltas))
+	for i, delta := range b.Deltas {
+		deltas[i] = map[string]interface{}{
+			"delta_id":   delta.ID,
+			"delta_type": delta.Type,
+			"path":       delta.Path,
+		}
+	}
+	return map[string]interface{}{
+		"batch_id": b.ID,
+		"count":    len(b.Deltas),
+		"deltas":   deltas,
+	}
+}
+
+// eventPaths returns the paths a delta.applied or delta.batch_applied
+// event's deltas changed, as its data names them
+func eventPaths(event Event) []string {
+	switch event.Type {
+	case EventDeltaApplied:
+		if path, ok := event.Data["path"].(string); ok {
+			return []string{path}
+		}
+	case EventDeltaBatchApplied:
+		deltas, _ := event.Data["deltas"].([]interface{})
+		var paths []string
+		for _, delta := range deltas {
+			fields, _ := delta.(map[string]interface{})
+			if path, ok := fields["path"].(string); ok {
+				paths = append(paths, path)
+			}
+		}
+		return paths
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package workflows
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DiffDeltas compares a blob's state before a transform with the output
+// the transform produced and returns a delta per changed path, instead of
+// one delta replacing everything at the root. Output merges into the state
+// at the root, as a root delta would, so top-level fields the output leaves
+// out are kept; below the top level a field the output drops is deleted.
+// Objects are compared field by field and arrays of unchanged length
+// element by element; any other changed value is replaced whole.
+func DiffDeltas(before, output map[string]interface{}, providerID, blobID string) []Delta {
+	d := &differ{providerID: providerID, blobID: blobID}
+
+	// Fields a path cannot name are merged at the root, as before
+	merged := make(map[string]interface{})
+	for _, key := range sortedKeys(output) {
+		if !addressable(key) {
+			if old, ok := before[key]; !ok || !sameValue(old, output[key]) {
+				merged[key] = output[key]
+			}
+			continue
+		}
+		old, ok := before[key]
+		d.value("/"+key, old, ok, output[key])
+	}
+	if len(merged) > 0 {
+		d.add("transform", "/", nil, merged)
+	}
+	return d.deltas
+}
+
+// differ collects the deltas found by DiffDeltas
+type differ struct {
+	providerID string
+	blobID     string
+	deltas     []Delta
+}
+
+// value compares the value at a path before and after
+func (d *differ) value(path string, old interface{}, existed bool, updated interface{}) {
+	if !existed {
+		d.add("create", path, nil, updated)
+		return
+	}
+	switch after := updated.(type) {
+	case map[string]interface{}:
+		if previous, ok := old.(map[string]interface{}); ok {
+			d.object(path, previous, after)
+			return
+		}
+	case []interface{}:
+		if previous, ok := old.([]interface{}); ok && len(previous) == len(after) {
+			for i := range after {
+				d.value(path+"/"+strconv.Itoa(i), previous[i], true, after[i])
+			}
+			return
+		}
+	}
+	if !sameValue(old, updated) {
+		d.add("update", path, old, updated)
+	}
+}
+
+// object compares two objects field by field. An object with a field a
+// path cannot name is replaced whole when it changes.
+func (d *differ) object(path string, old, updated map[string]interface{}) {
+	if !allAddressable(old) || !allAddressable(updated) {
+		if !sameValue(old, updated) {
+			d.add("update", path, old, updated)
+		}
+		return
+	}
+
+	for _, key := range sortedKeys(updated) {
+		previous, ok := old[key]
+		d.value(path+"/"+key, previous, ok, updated[key])
+	}
+	for _, key := range sortedKeys(old) {
+		if _, ok := updated[key]; !ok {
+			d.add("delete", path+"/"+key, old[key], nil)
+		}
+	}
+}
+
+// add appends a delta for a changed path
+func (d *differ) add(deltaType, path string, old, updated interface{}) {
+	d.deltas = append(d.deltas, Delta{
+		ID:         uuid.New().String(),
+		BlobID:     d.blobID,
+		ProviderID: d.providerID,
+		Type:       deltaType,
+		Path:       path,
+		OldValue:   old,
+		NewValue:   updated,
+		Timestamp:  time.Now(),
+		Metadata: map[string]interface{}{
+			"source": "workflow_output",
+		},
+	})
+}
+
+// addressable reports whether a field can be named in a JSON pointer path
+// as Apply reads it, which neither unescapes nor keeps empty segments
+func addressable(key string) bool {
+	return key != "" && !strings.ContainsAny(key, "/~")
+}
+
+// allAddressable reports whether every field of an object is addressable
+func allAddressable(m map[string]interface{}) bool {
+	for key := range m {
+		if !addressable(key) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameValue reports whether two JSON values are equal, whatever numeric
+// types they were decoded as
+func sameValue(a, b interface{}) bool {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		return ok && x == y
+	}
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for key, value := range av {
+			other, ok := bv[key]
+			if !ok || !sameValue(value, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !sameValue(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// sortedKeys returns an object's keys in order, so diffs are stable
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// isOutputDelta reports whether a delta is the one ExtractDeltas makes from
+// a whole output that carries no deltas of its own
+func isOutputDelta(delta Delta) bool {
+	source, _ := delta.Metadata["source"].(string)
+	return delta.Type == "transform" && delta.Path == "/" && source == "workflow_output"
+}
+
+// diffOutput replaces the whole-output delta of a transform with the
+// path-level deltas between the blob's current state, replayed from its
+// delta history, and the output
+func (o *Orchestrator) diffOutput(ctx context.Context, delta Delta) ([]Delta, error) {
+	output, ok := delta.NewValue.(map[string]interface{})
+	if !ok {
+		return []Delta{delta}, nil
+	}
+	history, err := o.deltaProcessor.storage.GetByBlobID(ctx, delta.BlobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deltas: %w", err)
+	}
+	before := o.deltaProcessor.Replay(history, math.MaxInt64)
+	return DiffDeltas(before, output, delta.ProviderID, delta.BlobID), nil
+}
@@ -0,0 +1,141 @@
+package workflows
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidEvent is returned for events that do not match their schema
+var ErrInvalidEvent = errors.New("invalid event")
+
+// EventSchemaVersion is the schema version events are published with when
+// they do not set one. Bump it, registering schemas for the new version,
+// when a change to an event's Data would break consumers.
+const EventSchemaVersion = 1
+
+// EventSourceOrchestrator is the source of the events the orchestrator
+// publishes
+const EventSourceOrchestrator = "orchestrator"
+
+// EventViolationError is returned for events whose envelope and data do
+// not match the schema registered for their type and version
+type EventViolationError struct {
+	EventType     string            `json:"event_type"`
+	SchemaVersion int               `json:"schema_version"`
+	SchemaID      string            `json:"schema_id"`
+	Violations    []SchemaViolation `json:"violations"`
+}
+
+func (e *EventViolationError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		messages[i] = violation.String()
+	}
+	return fmt.Sprintf("%s event v%d does not match schema %s: %s", e.EventType, e.SchemaVersion, e.SchemaID, strings.Join(messages, "; "))
+}
+
+// Unwrap makes a violation match ErrInvalidEvent
+func (e *EventViolationError) Unwrap() error { return ErrInvalidEvent }
+
+// eventSchemaKey identifies an event schema by type and version
+type eventSchemaKey struct {
+	eventType string
+	version   int
+}
+
+// eventSchemas maps event types and versions to schema IDs, guarded by
+// schemasMu
+var eventSchemas = make(map[eventSchemaKey]string)
+
+// RegisterEventSchema validates events of a type and schema version
+// against a schema registered with RegisterSchema
+func RegisterEventSchema(eventType string, version int, schemaID string) {
+	schemasMu.Lock()
+	defer schemasMu.Unlock()
+
+	eventSchemas[eventSchemaKey{eventType, version}] = schemaID
+}
+
+// EventSchemaID returns the ID of the schema for an event type and version
+func EventSchemaID(eventType string, version int) (string, bool) {
+	schemasMu.RLock()
+	defer schemasMu.RUnlock()
+
+	id, ok := eventSchemas[eventSchemaKey{eventType, version}]
+	return id, ok
+}
+
+// Register makes a schema available for output validation and, for event
+// schemas, validates the event types it lists at its major version
+func (s *YAMLSchema) Register() error {
+	RegisterSchema(s.ID, s.Definition)
+	if len(s.EventTypes) == 0 {
+		return nil
+	}
+	major, err := strconv.Atoi(strings.SplitN(s.Version, ".", 2)[0])
+	if err != nil || major < 1 {
+		return fmt.Errorf("event schema %s has invalid version %q", s.ID, s.Version)
+	}
+	for _, eventType := range s.EventTypes {
+		RegisterEventSchema(eventType, major, s.ID)
+	}
+	return nil
+}
+
+// CheckEvent validates an event, envelope and data, against the schema
+// registered for its type and version and returns an *EventViolationError
+// listing the violations. Events without a schema pass unless strict.
+func CheckEvent(event Event, strict bool) error {
+	schemaID, ok := EventSchemaID(event.Type, event.SchemaVersion)
+	if !ok {
+		if strict {
+			return fmt.Errorf("%w: no schema for %s events v%d", ErrInvalidEvent, event.Type, event.SchemaVersion)
+		}
+		return nil
+	}
+	definition, err := LookupSchema(schemaID)
+	if err != nil {
+		return err
+	}
+	// Check the JSON consumers receive
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("failed to decode event: %w", err)
+	}
+	if violations := ValidateSchema(definition, value, "$"); len(violations) > 0 {
+		return &EventViolationError{EventType: event.Type, SchemaVersion: event.SchemaVersion, SchemaID: schemaID, Violations: violations}
+	}
+	return nil
+}
+
+// validatingBus checks events before publishing them
+type validatingBus struct {
+	EventBus
+	strict bool
+}
+
+// ValidateEvents returns an EventBus that publishes to bus only events
+// that pass CheckEvent, returning the violation for the others. Events
+// without a schema version get EventSchemaVersion first.
+func ValidateEvents(bus EventBus, strict bool) EventBus {
+	return &validatingBus{EventBus: bus, strict: strict}
+}
+
+// Publish validates an event and publishes it
+func (b *validatingBus) Publish(ctx context.Context, event Event) error {
+	if event.SchemaVersion == 0 {
+		event.SchemaVersion = EventSchemaVersion
+	}
+	if err := CheckEvent(event, b.strict); err != nil {
+		return err
+	}
+	return b.EventBus.Publish(ctx, event)
+}
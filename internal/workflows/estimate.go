@@ -0,0 +1,303 @@
+package workflows
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// BytesPerToken is how many bytes of content make a token, roughly, for
+// blobs whose token count is not given
+const BytesPerToken = 4
+
+// ModelPrice is what a model charges, in US dollars per thousand tokens
+type ModelPrice struct {
+	InputPer1K  float64 `json:"input_per_1k" yaml:"input_per_1k"`
+	OutputPer1K float64 `json:"output_per_1k" yaml:"output_per_1k"`
+}
+
+// PricingTable holds model prices by model name. A model without an entry
+// of its own takes the entry of the longest name it starts with, so
+// "gpt-4o" prices "gpt-4o-2024-08-06".
+type PricingTable map[string]ModelPrice
+
+// DefaultPricing returns list prices of common models, for deployments
+// that do not load their own
+func DefaultPricing() PricingTable {
+	return PricingTable{
+		"gpt-4":             {InputPer1K: 0.03, OutputPer1K: 0.06},
+		"gpt-4-turbo":       {InputPer1K: 0.01, OutputPer1K: 0.03},
+		"gpt-4o":            {InputPer1K: 0.0025, OutputPer1K: 0.01},
+		"gpt-4o-mini":       {InputPer1K: 0.00015, OutputPer1K: 0.0006},
+		"gpt-3.5-turbo":     {InputPer1K: 0.0005, OutputPer1K: 0.0015},
+		"claude-3-opus":     {InputPer1K: 0.015, OutputPer1K: 0.075},
+		"claude-3-5-sonnet": {InputPer1K: 0.003, OutputPer1K: 0.015},
+		"claude-3-haiku":    {InputPer1K: 0.00025, OutputPer1K: 0.00125},
+	}
+}
+
+// LoadPricing reads a YAML or JSON file of model prices over the defaults
+func LoadPricing(path string) (PricingTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pricing %s: %w", path, err)
+	}
+	var loaded PricingTable
+	if err := yaml.Unmarshal(data, &loaded); err != nil {
+		return nil, fmt.Errorf("failed to parse pricing %s: %w", path, err)
+	}
+	pricing := DefaultPricing()
+	for model, price := range loaded {
+		if price.InputPer1K < 0 || price.OutputPer1K < 0 {
+			return nil, fmt.Errorf("pricing %s has a negative price for %s", path, model)
+		}
+		pricing[model] = price
+	}
+	return pricing, nil
+}
+
+// Price returns a model's price
+func (t PricingTable) Price(model string) (ModelPrice, bool) {
+	if price, ok := t[model]; ok {
+		return price, true
+	}
+	best := ""
+	for name := range t {
+		if strings.HasPrefix(model, name) && len(name) > len(best) {
+			best = name
+		}
+	}
+	if best == "" {
+		return ModelPrice{}, false
+	}
+	return t[best], true
+}
+
+// EstimateInput describes the blobs an estimate is for: Count blobs, one
+// when zero, of SizeBytes bytes or Tokens tokens, which is used when set.
+// Policy is the model policy their namespace applies, if any.
+type EstimateInput struct {
+	BlobID    string
+	SizeBytes int
+	Tokens    int
+	Count     int
+	Policy    *ModelPolicy
+}
+
+// StepEstimate is the predicted cost and duration of one run of a step.
+// Durations are zero for steps that have not run.
+type StepEstimate struct {
+	StepID       string  `json:"step_id"`
+	Model        string  `json:"model,omitempty"`
+	InputTokens  int     `json:"input_tokens,omitempty"`
+	OutputTokens int     `json:"output_tokens,omitempty"`
+	CostUSD      float64 `json:"cost_usd"`
+	P50Ms        int64   `json:"p50_ms"`
+	P95Ms        int64   `json:"p95_ms"`
+	Samples      int     `json:"samples"`
+	UsageSamples int     `json:"usage_samples,omitempty"`
+}
+
+// BlobEstimate is the predicted cost and duration of running a workflow on
+// Count blobs alike; the steps and durations are those of one run
+type BlobEstimate struct {
+	BlobID        string         `json:"blob_id,omitempty"`
+	Count         int            `json:"count"`
+	Tokens        int            `json:"tokens"`
+	CostUSD       float64        `json:"cost_usd"`
+	DurationP50Ms int64          `json:"duration_p50_ms"`
+	DurationP95Ms int64          `json:"duration_p95_ms"`
+	Steps         []StepEstimate `json:"steps"`
+}
+
+// Estimate is the predicted cost and duration of running a workflow on a
+// set of blobs. Durations are for the runs one after another; steps of a
+// run that may run at once count as long as the slowest of them.
+type Estimate struct {
+	WorkflowID    string         `json:"workflow_id"`
+	Version       int            `json:"version"`
+	Blobs         int            `json:"blobs"`
+	Tokens        int            `json:"tokens"`
+	CostUSD       float64        `json:"cost_usd"`
+	DurationP50Ms int64          `json:"duration_p50_ms"`
+	DurationP95Ms int64          `json:"duration_p95_ms"`
+	Estimates     []BlobEstimate `json:"estimates"`
+	Warnings      []string       `json:"warnings,omitempty"`
+}
+
+// Estimator predicts what running workflows will cost from their steps'
+// recorded durations and token usage and model prices. AI steps are sent
+// the blob and their prompt; they are predicted to generate tokens in the
+// proportion to their input they have on average, or as many as their
+// input without usage recorded, within their max_tokens. Their durations
+// scale with the tokens they generate.
+type Estimator struct {
+	latencies *StepLatencies
+	pricing   PricingTable
+}
+
+// NewEstimator creates an estimator. Without latencies durations are not
+// predicted; without pricing the default prices are used.
+func NewEstimator(latencies *StepLatencies, pricing PricingTable) *Estimator {
+	if pricing == nil {
+		pricing = DefaultPricing()
+	}
+	return &Estimator{latencies: latencies, pricing: pricing}
+}
+
+// Estimate predicts the cost and duration of running a workflow on each
+// input's blobs
+func (e *Estimator) Estimate(w *BlobProcessingWorkflow, inputs []EstimateInput) (*Estimate, error) {
+	levels, err := w.GetDAGOrder()
+	if err != nil {
+		return nil, fmt.Errorf("failed to order workflow %s: %w", w.ID, err)
+	}
+	estimate := &Estimate{WorkflowID: w.ID, Version: w.Version, Estimates: []BlobEstimate{}}
+	warned := make(map[string]bool)
+	warn := func(format string, args ...interface{}) {
+		message := fmt.Sprintf(format, args...)
+		if !warned[message] {
+			warned[message] = true
+			estimate.Warnings = append(estimate.Warnings, message)
+		}
+	}
+
+	for _, input := range inputs {
+		blob := e.estimateBlob(w, levels, input, warn)
+		estimate.Blobs += blob.Count
+		estimate.Tokens += blob.Tokens * blob.Count
+		estimate.CostUSD += blob.CostUSD * float64(blob.Count)
+		estimate.DurationP50Ms += blob.DurationP50Ms * int64(blob.Count)
+		estimate.DurationP95Ms += blob.DurationP95Ms * int64(blob.Count)
+		estimate.Estimates = append(estimate.Estimates, blob)
+	}
+	estimate.CostUSD = roundCost(estimate.CostUSD)
+	return estimate, nil
+}
+
+// estimateBlob predicts one run of a workflow on a blob
+func (e *Estimator) estimateBlob(w *BlobProcessingWorkflow, levels [][]BlobProcessingStep, input EstimateInput, warn func(string, ...interface{})) BlobEstimate {
+	blob := BlobEstimate{BlobID: input.BlobID, Count: input.Count, Tokens: input.Tokens, Steps: []StepEstimate{}}
+	if blob.Count <= 0 {
+		blob.Count = 1
+	}
+	if blob.Tokens <= 0 {
+		blob.Tokens = int(math.Ceil(float64(input.SizeBytes) / BytesPerToken))
+	}
+
+	for _, level := range levels {
+		var slowest, slowest95 int64
+		for _, step := range level {
+			estimate := e.estimateStep(w, step, blob.Tokens, input.Policy, warn)
+			blob.CostUSD += estimate.CostUSD
+			if estimate.P50Ms > slowest {
+				slowest = estimate.P50Ms
+			}
+			if estimate.P95Ms > slowest95 {
+				slowest95 = estimate.P95Ms
+			}
+			blob.Steps = append(blob.Steps, estimate)
+		}
+		blob.DurationP50Ms += slowest
+		blob.DurationP95Ms += slowest95
+	}
+	sort.Slice(blob.Steps, func(i, j int) bool { return stepIndex(w, blob.Steps[i].StepID) < stepIndex(w, blob.Steps[j].StepID) })
+	blob.CostUSD = roundCost(blob.CostUSD)
+	return blob
+}
+
+// estimateStep predicts one run of a step on a blob of tokens
+func (e *Estimator) estimateStep(w *BlobProcessingWorkflow, step BlobProcessingStep, tokens int, policy *ModelPolicy, warn func(string, ...interface{})) StepEstimate {
+	estimate := StepEstimate{StepID: step.ID}
+	var meanInput, meanOutput float64
+	if e.latencies != nil {
+		if h, ok := e.latencies.stepHealth(w, step); ok {
+			estimate.P50Ms, estimate.P95Ms, estimate.Samples = h.P50Ms, h.P95Ms, h.Samples
+		}
+		meanInput, meanOutput, estimate.UsageSamples = e.latencies.Usage(w.ID, step.ID)
+	}
+	if estimate.Samples == 0 {
+		warn("step %s has no recorded durations", step.ID)
+	}
+	if !isAIStep(step) {
+		return estimate
+	}
+
+	parameters := copyValues(step.Config.Parameters)
+	inputMap := copyValues(step.InputMap)
+	parameters = policy.Apply(step, parameters, inputMap)
+	estimate.Model = staticString(parameters["model"])
+	if estimate.Model == "" {
+		estimate.Model = staticString(inputMap["model"])
+	}
+
+	estimate.InputTokens = tokens
+	for _, key := range []string{"prompt", "system_prompt", "user_prompt"} {
+		if prompt := staticString(inputMap[key]); prompt != "" {
+			estimate.InputTokens += int(math.Ceil(float64(len(prompt)) / BytesPerToken))
+		}
+	}
+	output := float64(estimate.InputTokens)
+	if estimate.UsageSamples > 0 && meanInput > 0 {
+		output = float64(estimate.InputTokens) * meanOutput / meanInput
+	}
+	if limit, ok := toFloat(parameters["max_tokens"]); ok && limit > 0 && output > limit {
+		output = limit
+	}
+	estimate.OutputTokens = int(math.Ceil(output))
+	if meanOutput > 0 {
+		scale := float64(estimate.OutputTokens) / meanOutput
+		estimate.P50Ms = int64(float64(estimate.P50Ms) * scale)
+		estimate.P95Ms = int64(float64(estimate.P95Ms) * scale)
+	}
+
+	if estimate.Model == "" {
+		warn("step %s does not name a model it can be priced by", step.ID)
+		return estimate
+	}
+	price, ok := e.pricing.Price(estimate.Model)
+	if !ok {
+		warn("model %s of step %s has no price", estimate.Model, step.ID)
+		return estimate
+	}
+	estimate.CostUSD = roundCost(float64(estimate.InputTokens)/1000*price.InputPer1K + float64(estimate.OutputTokens)/1000*price.OutputPer1K)
+	return estimate
+}
+
+// copyValues returns a shallow copy of a map, for a policy to change
+func copyValues(values map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		copied[k] = v
+	}
+	return copied
+}
+
+// staticString returns a value that is a literal string, not a $. path
+// resolved at run time, or ""
+func staticString(value interface{}) string {
+	s, _ := value.(string)
+	if strings.HasPrefix(s, "$.") {
+		return ""
+	}
+	return s
+}
+
+// stepIndex returns the position of a step in a workflow
+func stepIndex(w *BlobProcessingWorkflow, stepID string) int {
+	for i, step := range w.Steps {
+		if step.ID == stepID {
+			return i
+		}
+	}
+	return len(w.Steps)
+}
+
+// roundCost rounds a cost to a hundredth of a cent
+func roundCost(cost float64) float64 {
+	return math.Round(cost*10000) / 10000
+}
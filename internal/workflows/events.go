@@ -0,0 +1,123 @@
+package workflows
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event types published by the orchestrator
+const (
+	EventDeltaApplied       = "delta.applied"
+	EventDeltaBatchApplied  = "delta.batch_applied"
+	EventExecutionStarted   = "execution.started"
+	EventExecutionCompleted = "execution.completed"
+	EventExecutionFailed    = "execution.failed"
+	EventStepCompleted      = "step.completed"
+	EventStepFailed         = "step.failed"
+)
+
+// publishEvent publishes an event, logging rather than failing on errors so
+// notification problems never abort processing. Without an event bus it does
+// nothing.
+func (o *Orchestrator) publishEvent(ctx context.Context, event Event) {
+	if o.eventBus == nil {
+		return
+	}
+	stampEvent(&event)
+
+	if err := o.eventBus.Publish(ctx, event); err != nil {
+		// Log error but don't fail
+		fmt.Printf("failed to publish %s event: %v\n", event.Type, err)
+	}
+}
+
+// stampEvent fills in an event's ID, timestamp, source and schema version
+// where they are unset
+func stampEvent(event *Event) {
+	if event.ID == "" {
+		event.ID = uuid.New().String()
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	if event.Source == "" {
+		event.Source = EventSourceOrchestrator
+	}
+	if event.SchemaVersion == 0 {
+		event.SchemaVersion = EventSchemaVersion
+	}
+}
+
+// publishExecutionEvent publishes an execution lifecycle event
+func (o *Orchestrator) publishExecutionEvent(ctx context.Context, eventType string, execCtx ExecutionContext, workflowID, executionID string, data map[string]interface{}) {
+	payload := map[string]interface{}{
+		"workflow_id":  workflowID,
+		"execution_id": executionID,
+		"request_id":   execCtx.RequestID,
+	}
+	for k, v := range data {
+		payload[k] = v
+	}
+
+	event := Event{
+		Type:          eventType,
+		BlobID:        execCtx.BlobID,
+		UserID:        execCtx.UserID,
+		ProviderID:    execCtx.ProviderID,
+		Data:          payload,
+		CorrelationID: execCtx.RequestID,
+		TraceParent:   execCtx.TraceParent,
+		TraceState:    execCtx.TraceState,
+	}
+	if eventType != EventExecutionStarted && executionID != "" {
+		// Later events follow from the execution starting
+		event.CausationID = executionID
+	}
+	o.publishEvent(ctx, event)
+}
+
+// publishStepEvents publishes an event for each step an execution's output
+// reports completed or failed, in step ID order, and records the durations
+// the backend reported. Steps run on the backend, so they are reported once
+// the orchestrator sees the output rather than as each one ends, except
+// for interactive executions, whose output so far is checked as they run.
+// Each step is published once per execution, however often it is seen.
+func (o *Orchestrator) publishStepEvents(ctx context.Context, execCtx ExecutionContext, workflowID, executionID string, output map[string]interface{}) {
+	steps, _ := output["steps"].(map[string]interface{})
+	stepIDs := make([]string, 0, len(steps))
+	for stepID := range steps {
+		stepIDs = append(stepIDs, stepID)
+	}
+	sort.Strings(stepIDs)
+
+	for _, stepID := range stepIDs {
+		result, _ := steps[stepID].(map[string]interface{})
+		if result["status"] != "completed" && result["status"] != "failed" {
+			continue
+		}
+		claimed, interactive := o.executions.claimStep(executionID, stepID)
+		if !claimed {
+			continue
+		}
+		data := map[string]interface{}{"step_id": stepID}
+		if duration, ok := result["duration_ms"]; ok {
+			data["duration_ms"] = duration
+		}
+		switch result["status"] {
+		case "completed":
+			if interactive {
+				// Interactive clients render each step's output as it comes
+				data["output"] = result["output"]
+			}
+			o.publishExecutionEvent(ctx, EventStepCompleted, execCtx, workflowID, executionID, data)
+		case "failed":
+			data["error"] = result["error"]
+			o.publishExecutionEvent(ctx, EventStepFailed, execCtx, workflowID, executionID, data)
+		}
+		o.recordStepLatency(workflowID, stepID, result)
+	}
+}
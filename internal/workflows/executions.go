@@ -0,0 +1,277 @@
+package workflows
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrExecutionNotFound is returned for executions the backend does not know
+var ErrExecutionNotFound = errors.New("execution not found")
+
+// ErrDeltaConflict is returned by DeltaStorage when an execution's deltas
+// no longer apply to the blob, such as when it changed in the meantime
+var ErrDeltaConflict = errors.New("delta conflict")
+
+// maxTrackedExecutions bounds how many executions the orchestrator
+// remembers; the oldest are forgotten first
+const maxTrackedExecutions = 10000
+
+// ExecutionRecord is what the orchestrator knows about an execution it
+// started, beyond what the backend reports. Status is the last status seen,
+// when the execution started or was last looked up.
+type ExecutionRecord struct {
+	ExecutionID   string    `json:"execution_id"`
+	WorkflowID    string    `json:"workflow_id"`
+	ProviderID    string    `json:"provider_id"`
+	BlobID        string    `json:"blob_id"`
+	UserID        string    `json:"user_id"`
+	EventType     string    `json:"event_type,omitempty"`
+	Status        string    `json:"status"`
+	StartedAt     time.Time `json:"started_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	DeltasApplied int       `json:"deltas_applied"`
+
+	// The definition the execution ran, as the orchestrator held it when
+	// the execution started
+	WorkflowVersion int    `json:"workflow_version,omitempty"`
+	WorkflowDigest  string `json:"workflow_digest,omitempty"`
+	definition      *BlobProcessingWorkflow
+
+	// The experiment that routed the execution to its workflow, if any
+	ExperimentID string `json:"experiment_id,omitempty"`
+	Variant      string `json:"variant,omitempty"`
+
+	// What the user thought of the output, oldest first
+	Feedback []ExecutionFeedback `json:"feedback,omitempty"`
+
+	// The rollback that undid the execution, once it is rolled back
+	RollbackID string `json:"rollback_id,omitempty"`
+	rollback   *RollbackResult
+
+	// The W3C trace context of the trace that started the execution
+	TraceParent string `json:"traceparent,omitempty"`
+	TraceState  string `json:"tracestate,omitempty"`
+
+	// The profile the execution ran in when not the standard one, and
+	// whether it completed with only some of its steps at its deadline
+	Profile   string `json:"profile,omitempty"`
+	Partial   bool   `json:"partial,omitempty"`
+	published map[string]bool
+}
+
+// ExecutionFilter selects tracked executions. Empty fields match every
+// execution; Since and Until bound the start time, inclusively and
+// exclusively. Offset and Limit page through the matches, newest first.
+type ExecutionFilter struct {
+	UserID       string
+	BlobID       string
+	ProviderID   string
+	EventType    string
+	ExperimentID string
+	Status       string
+	Since        time.Time
+	Until        time.Time
+	Offset       int
+	Limit        int
+}
+
+// matches reports whether a record passes the filter
+func (f ExecutionFilter) matches(record *ExecutionRecord) bool {
+	switch {
+	case f.UserID != "" && record.UserID != f.UserID,
+		f.BlobID != "" && record.BlobID != f.BlobID,
+		f.ProviderID != "" && record.ProviderID != f.ProviderID,
+		f.EventType != "" && record.EventType != f.EventType,
+		f.ExperimentID != "" && record.ExperimentID != f.ExperimentID,
+		f.Status != "" && record.Status != f.Status,
+		!f.Since.IsZero() && record.StartedAt.Before(f.Since),
+		!f.Until.IsZero() && !record.StartedAt.Before(f.Until):
+		return false
+	}
+	return true
+}
+
+// IsTerminalStatus reports whether an execution status is final
+func IsTerminalStatus(status string) bool {
+	switch status {
+	case "completed", "failed", "cancelled", "timed_out", "terminated":
+		return true
+	}
+	return false
+}
+
+// executionLog keeps the most recent execution records
+type executionLog struct {
+	mu      sync.RWMutex
+	records map[string]*ExecutionRecord
+	order   []string
+	limit   int
+}
+
+// newExecutionLog creates a log holding up to limit records
+func newExecutionLog(limit int) *executionLog {
+	return &executionLog{records: make(map[string]*ExecutionRecord), limit: limit}
+}
+
+// add records an execution, forgetting the oldest when the log is full
+func (l *executionLog) add(record *ExecutionRecord) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.records[record.ExecutionID]; !ok {
+		l.order = append(l.order, record.ExecutionID)
+	}
+	l.records[record.ExecutionID] = record
+	for len(l.order) > l.limit {
+		delete(l.records, l.order[0])
+		l.order = l.order[1:]
+	}
+}
+
+// addDeltas counts deltas applied from an execution's output
+func (l *executionLog) addDeltas(executionID string, n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if record, ok := l.records[executionID]; ok {
+		record.DeltasApplied += n
+	}
+}
+
+// setPartial records that an execution completed with partial output
+func (l *executionLog) setPartial(executionID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if record, ok := l.records[executionID]; ok {
+		record.Partial = true
+	}
+}
+
+// claimStep records that a step's events are being published for an
+// execution, reporting whether they had not been already, so steps
+// streamed while it ran are not published again when it ends, and whether
+// the execution is interactive
+func (l *executionLog) claimStep(executionID, stepID string) (claimed, interactive bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	record, ok := l.records[executionID]
+	if !ok {
+		return true, false
+	}
+	if record.published[stepID] {
+		return false, false
+	}
+	if record.published == nil {
+		record.published = make(map[string]bool)
+	}
+	record.published[stepID] = true
+	return true, record.Profile == ProfileInteractive
+}
+
+// setStatus records the latest status seen for an execution. It returns
+// the updated record, and whether this status is the first final one seen.
+func (l *executionLog) setStatus(executionID, status string) (ExecutionRecord, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	record, ok := l.records[executionID]
+	if !ok || status == "" || record.Status == status {
+		return ExecutionRecord{}, false
+	}
+	finished := IsTerminalStatus(status) && !IsTerminalStatus(record.Status)
+	record.Status = status
+	record.UpdatedAt = time.Now()
+	return *record, finished
+}
+
+// list returns a page of the records a filter matches, newest first, and
+// how many match in all
+func (l *executionLog) list(filter ExecutionFilter) ([]ExecutionRecord, int) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	page := []ExecutionRecord{}
+	total := 0
+	for i := len(l.order) - 1; i >= 0; i-- {
+		record := l.records[l.order[i]]
+		if !filter.matches(record) {
+			continue
+		}
+		if total >= filter.Offset && (filter.Limit <= 0 || len(page) < filter.Limit) {
+			page = append(page, *record)
+		}
+		total++
+	}
+	return page, total
+}
+
+// get returns a copy of an execution's record
+func (l *executionLog) get(executionID string) (ExecutionRecord, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	record, ok := l.records[executionID]
+	if !ok {
+		return ExecutionRecord{}, false
+	}
+	return *record, true
+}
+
+// Execution returns the record of an execution this orchestrator started.
+// Executions started by other processes, or before a restart, are unknown.
+func (o *Orchestrator) Execution(executionID string) (ExecutionRecord, bool) {
+	return o.executions.get(executionID)
+}
+
+// ExecutionDefinition returns the workflow definition an execution this
+// orchestrator started ran, pinned when it started so later updates to the
+// workflow do not change it
+func (o *Orchestrator) ExecutionDefinition(executionID string) (*BlobProcessingWorkflow, bool) {
+	record, ok := o.executions.get(executionID)
+	if !ok || record.definition == nil {
+		return nil, false
+	}
+	return record.definition, true
+}
+
+// Executions returns a page of the tracked executions a filter matches,
+// newest first, and how many match in all. It answers from the
+// orchestrator's own records without asking the backend.
+func (o *Orchestrator) Executions(filter ExecutionFilter) ([]ExecutionRecord, int) {
+	return o.executions.list(filter)
+}
+
+// ObserveExecutionStatus records a status fetched from the backend for a
+// tracked execution, keeping the index current for listings. Executions
+// started asynchronously are first seen finished here, which publishes
+// their step events and outcome.
+func (o *Orchestrator) ObserveExecutionStatus(ctx context.Context, executionID string, resp *ExecutionResponse) {
+	record, finished := o.executions.setStatus(executionID, resp.Status)
+	if !finished {
+		return
+	}
+	o.unjournalExecution(ctx, executionID)
+
+	execCtx := ExecutionContext{UserID: record.UserID, ProviderID: record.ProviderID, BlobID: record.BlobID, TraceParent: record.TraceParent, TraceState: record.TraceState}
+	o.publishStepEvents(ctx, execCtx, record.WorkflowID, executionID, resp.Output)
+	data := map[string]interface{}{"status": resp.Status}
+	if resp.Status == "completed" {
+		if IsPartialOutput(resp.Output) {
+			o.executions.setPartial(executionID)
+			data["partial"] = true
+		}
+		o.publishExecutionEvent(ctx, EventExecutionCompleted, execCtx, record.WorkflowID, executionID, data)
+		return
+	}
+	reason := resp.Status
+	if resp.Error != nil {
+		data["error"] = resp.Error.Message
+		reason = resp.Error.Message
+	}
+	o.publishExecutionEvent(ctx, EventExecutionFailed, execCtx, record.WorkflowID, executionID, data)
+	o.rollbackFailed(ctx, executionID, resp, reason)
+}
@@ -0,0 +1,266 @@
+package workflows
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"time"
+)
+
+// Experiment errors
+var (
+	ErrExperimentNotFound = errors.New("experiment not found")
+	ErrInvalidExperiment  = errors.New("invalid experiment")
+)
+
+// experimentVariants is how many variants an experiment compares
+const experimentVariants = 2
+
+// ExperimentVariant is a workflow an experiment routes executions to, with
+// its share of the traffic relative to the other variant's weight
+type ExperimentVariant struct {
+	Name       string `json:"name"`
+	WorkflowID string `json:"workflow_id"`
+	Weight     int    `json:"weight"`
+}
+
+// Experiment splits the executions of a logical pipeline between two
+// workflow variants. Providers list the experiment's ID among their
+// workflow IDs, and each blob is routed by a hash of its ID, so a blob
+// keeps running the same variant while the split is unchanged.
+type Experiment struct {
+	ID        string              `json:"id"`
+	Variants  []ExperimentVariant `json:"variants"`
+	CreatedAt time.Time           `json:"created_at"`
+	UpdatedAt time.Time           `json:"updated_at"`
+}
+
+// VariantMetrics compares a variant's executions with the other's. Counts
+// come from the executions the orchestrator still tracks, by the status
+// last seen, so asynchronous executions count as running until their
+// status is looked up. Durations run from the start until completion was
+// seen.
+type VariantMetrics struct {
+	Variant            string  `json:"variant"`
+	WorkflowID         string  `json:"workflow_id"`
+	Executions         int     `json:"executions"`
+	Running            int     `json:"running"`
+	Completed          int     `json:"completed"`
+	Failed             int     `json:"failed"`
+	SuccessRate        float64 `json:"success_rate"`
+	DeltasApplied      int     `json:"deltas_applied"`
+	AvgDeltas          float64 `json:"avg_deltas_per_completed"`
+	AvgDurationSeconds float64 `json:"avg_duration_seconds"`
+}
+
+// Validate checks an experiment before it is set: it needs an ID and two
+// variants with distinct names and workflows, and weights that are not
+// negative and do not both come to zero
+func (e *Experiment) Validate() error {
+	if e.ID == "" {
+		return fmt.Errorf("%w: id is required", ErrInvalidExperiment)
+	}
+	if len(e.Variants) != experimentVariants {
+		return fmt.Errorf("%w: %s needs %d variants, has %d", ErrInvalidExperiment, e.ID, experimentVariants, len(e.Variants))
+	}
+	total := 0
+	names := make(map[string]bool)
+	workflowIDs := make(map[string]bool)
+	for _, variant := range e.Variants {
+		switch {
+		case variant.Name == "":
+			return fmt.Errorf("%w: %s has a variant without a name", ErrInvalidExperiment, e.ID)
+		case variant.WorkflowID == "":
+			return fmt.Errorf("%w: variant %s needs a workflow_id", ErrInvalidExperiment, variant.Name)
+		case variant.WorkflowID == e.ID:
+			return fmt.Errorf("%w: variant %s cannot run the experiment itself", ErrInvalidExperiment, variant.Name)
+		case variant.Weight < 0:
+			return fmt.Errorf("%w: variant %s has a negative weight", ErrInvalidExperiment, variant.Name)
+		case names[variant.Name]:
+			return fmt.Errorf("%w: variant %s is named twice", ErrInvalidExperiment, variant.Name)
+		case workflowIDs[variant.WorkflowID]:
+			return fmt.Errorf("%w: both variants run workflow %s", ErrInvalidExperiment, variant.WorkflowID)
+		}
+		names[variant.Name] = true
+		workflowIDs[variant.WorkflowID] = true
+		total += variant.Weight
+	}
+	if total == 0 {
+		return fmt.Errorf("%w: %s sends no traffic to either variant", ErrInvalidExperiment, e.ID)
+	}
+	return nil
+}
+
+// route picks the variant a blob runs
+func (e *Experiment) route(blobID string) ExperimentVariant {
+	total := 0
+	for _, variant := range e.Variants {
+		total += variant.Weight
+	}
+	hash := fnv.New32a()
+	hash.Write([]byte(e.ID + "/" + blobID))
+	point := int(hash.Sum32() % uint32(total))
+	for _, variant := range e.Variants {
+		if point < variant.Weight {
+			return variant
+		}
+		point -= variant.Weight
+	}
+	return e.Variants[len(e.Variants)-1]
+}
+
+// SetExperiment sets or replaces an experiment, loading its variants'
+// workflows from the backend. The ID must not be a workflow's, since
+// providers name either by the same field.
+func (o *Orchestrator) SetExperiment(ctx context.Context, experiment *Experiment) error {
+	if err := experiment.Validate(); err != nil {
+		return err
+	}
+	if _, err := o.client.GetWorkflow(ctx, experiment.ID); err == nil {
+		return fmt.Errorf("%w: %s is a workflow id", ErrInvalidExperiment, experiment.ID)
+	}
+	variants := make(map[string]*BlobProcessingWorkflow, len(experiment.Variants))
+	for _, variant := range experiment.Variants {
+		workflow, err := o.client.GetWorkflow(ctx, variant.WorkflowID)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrWorkflowNotFound, variant.WorkflowID, err)
+		}
+		variants[variant.WorkflowID] = workflow
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	now := time.Now()
+	experiment.CreatedAt, experiment.UpdatedAt = now, now
+	if existing, ok := o.experiments[experiment.ID]; ok {
+		experiment.CreatedAt = existing.CreatedAt
+	}
+	for workflowID, workflow := range variants {
+		o.workflows[workflowID] = workflow
+	}
+	o.experiments[experiment.ID] = experiment
+	return nil
+}
+
+// Experiment returns an experiment
+func (o *Orchestrator) Experiment(experimentID string) (*Experiment, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	experiment, ok := o.experiments[experimentID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrExperimentNotFound, experimentID)
+	}
+	return experiment, nil
+}
+
+// ListExperiments returns the experiments ordered by ID
+func (o *Orchestrator) ListExperiments() []*Experiment {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	experiments := make([]*Experiment, 0, len(o.experiments))
+	for _, experiment := range o.experiments {
+		experiments = append(experiments, experiment)
+	}
+	sort.Slice(experiments, func(i, j int) bool { return experiments[i].ID < experiments[j].ID })
+	return experiments
+}
+
+// DeleteExperiment ends an experiment. Providers that still list its ID
+// run nothing for it until it is set again.
+func (o *Orchestrator) DeleteExperiment(experimentID string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if _, ok := o.experiments[experimentID]; !ok {
+		return fmt.Errorf("%w: %s", ErrExperimentNotFound, experimentID)
+	}
+	delete(o.experiments, experimentID)
+	return nil
+}
+
+// ExperimentMetrics compares the variants of an experiment, the current
+// variants first and then any the experiment ran before it was changed
+func (o *Orchestrator) ExperimentMetrics(experimentID string) ([]VariantMetrics, error) {
+	experiment, err := o.Experiment(experimentID)
+	if err != nil {
+		return nil, err
+	}
+	records, _ := o.executions.list(ExecutionFilter{ExperimentID: experimentID})
+
+	metrics := make(map[string]*VariantMetrics)
+	var order []string
+	for _, variant := range experiment.Variants {
+		metrics[variant.Name] = &VariantMetrics{Variant: variant.Name, WorkflowID: variant.WorkflowID}
+		order = append(order, variant.Name)
+	}
+	var retired []string
+	durations := make(map[string]time.Duration)
+	for _, record := range records {
+		m, ok := metrics[record.Variant]
+		if !ok {
+			m = &VariantMetrics{Variant: record.Variant, WorkflowID: record.WorkflowID}
+			metrics[record.Variant] = m
+			retired = append(retired, record.Variant)
+		}
+		m.Executions++
+		m.DeltasApplied += record.DeltasApplied
+		switch {
+		case record.Status == "completed":
+			m.Completed++
+			durations[record.Variant] += record.UpdatedAt.Sub(record.StartedAt)
+		case IsTerminalStatus(record.Status):
+			m.Failed++
+		default:
+			m.Running++
+		}
+	}
+	sort.Strings(retired)
+
+	result := make([]VariantMetrics, 0, len(metrics))
+	for _, name := range append(order, retired...) {
+		m := metrics[name]
+		if finished := m.Completed + m.Failed; finished > 0 {
+			m.SuccessRate = float64(m.Completed) / float64(finished)
+		}
+		if m.Completed > 0 {
+			m.AvgDeltas = float64(m.DeltasApplied) / float64(m.Completed)
+			m.AvgDurationSeconds = durations[name].Seconds() / float64(m.Completed)
+		}
+		result = append(result, *m)
+	}
+	return result, nil
+}
+
+// resolveWorkflow maps a workflow ID a provider lists to the workflow to
+// run for a blob: the ID itself, or for an experiment the variant the blob
+// is routed to. The caller holds o.mu.
+func (o *Orchestrator) resolveWorkflow(workflowID, blobID string) (string, *Experiment, ExperimentVariant) {
+	experiment, ok := o.experiments[workflowID]
+	if !ok {
+		return workflowID, nil, ExperimentVariant{}
+	}
+	variant := experiment.route(blobID)
+	return variant.WorkflowID, experiment, variant
+}
+
+// expandWorkflowIDs replaces experiment IDs among a provider's workflow IDs
+// with their variants' workflows. The caller holds o.mu.
+func (o *Orchestrator) expandWorkflowIDs(workflowIDs []string) []string {
+	expanded := make([]string, 0, len(workflowIDs))
+	for _, workflowID := range workflowIDs {
+		experiment, ok := o.experiments[workflowID]
+		if !ok {
+			expanded = append(expanded, workflowID)
+			continue
+		}
+		for _, variant := range experiment.Variants {
+			expanded = append(expanded, variant.WorkflowID)
+		}
+	}
+	return expanded
+}
@@ -0,0 +1,259 @@
+package workflows
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Feedback errors
+var (
+	ErrFeedbackNotFound = errors.New("feedback not found")
+	ErrInvalidFeedback  = errors.New("invalid feedback")
+)
+
+// EventExecutionFeedback is published when a user gives feedback on an
+// execution, so prompt tuning can collect it
+const EventExecutionFeedback = "execution.feedback"
+
+// Feedback ratings
+const (
+	RatingUp   = "up"
+	RatingDown = "down"
+)
+
+const (
+	// maxFeedbackComment bounds a feedback comment, in bytes
+	maxFeedbackComment = 4000
+	// maxExecutionFeedback bounds how much feedback one execution keeps
+	maxExecutionFeedback = 100
+)
+
+// ExecutionFeedback is a user's rating of or comment on an execution's
+// output. DeltaIDs names the deltas it is about; without them it is about
+// the whole output.
+type ExecutionFeedback struct {
+	ID          string    `json:"id"`
+	ExecutionID string    `json:"execution_id"`
+	Rating      string    `json:"rating,omitempty"` // up or down
+	Comment     string    `json:"comment,omitempty"`
+	DeltaIDs    []string  `json:"delta_ids,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// FeedbackSummary totals the feedback on a provider's executions of one
+// workflow version. An execution's rating is its latest rated feedback's.
+type FeedbackSummary struct {
+	ProviderID      string  `json:"provider_id"`
+	WorkflowID      string  `json:"workflow_id"`
+	WorkflowVersion int     `json:"workflow_version,omitempty"`
+	Executions      int     `json:"executions"`
+	Rated           int     `json:"rated"`
+	ThumbsUp        int     `json:"thumbs_up"`
+	ThumbsDown      int     `json:"thumbs_down"`
+	Comments        int     `json:"comments"`
+	ApprovalRate    float64 `json:"approval_rate"`
+}
+
+// Validate checks feedback before it is added: it needs a rating or a
+// comment, and the rating must be up or down
+func (f *ExecutionFeedback) Validate() error {
+	f.Comment = strings.TrimSpace(f.Comment)
+	switch {
+	case f.Rating != "" && f.Rating != RatingUp && f.Rating != RatingDown:
+		return fmt.Errorf("%w: rating must be %s or %s", ErrInvalidFeedback, RatingUp, RatingDown)
+	case f.Rating == "" && f.Comment == "":
+		return fmt.Errorf("%w: a rating or a comment is required", ErrInvalidFeedback)
+	case len(f.Comment) > maxFeedbackComment:
+		return fmt.Errorf("%w: comment is longer than %d bytes", ErrInvalidFeedback, maxFeedbackComment)
+	}
+	return nil
+}
+
+// rating is an execution's rating: that of its latest rated feedback
+func (r *ExecutionRecord) rating() string {
+	for i := len(r.Feedback) - 1; i >= 0; i-- {
+		if r.Feedback[i].Rating != "" {
+			return r.Feedback[i].Rating
+		}
+	}
+	return ""
+}
+
+// addFeedback adds feedback to an execution's record
+func (l *executionLog) addFeedback(feedback ExecutionFeedback) (ExecutionRecord, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	record, ok := l.records[feedback.ExecutionID]
+	if !ok {
+		return ExecutionRecord{}, fmt.Errorf("%w: %s", ErrExecutionNotFound, feedback.ExecutionID)
+	}
+	if len(record.Feedback) >= maxExecutionFeedback {
+		return ExecutionRecord{}, fmt.Errorf("%w: execution %s already has %d feedback entries", ErrInvalidFeedback, feedback.ExecutionID, maxExecutionFeedback)
+	}
+	record.Feedback = append(record.Feedback, feedback)
+	return *record, nil
+}
+
+// deleteFeedback removes feedback from an execution's record. The slice is
+// rebuilt since copies of the record may share it.
+func (l *executionLog) deleteFeedback(executionID, feedbackID string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	record, ok := l.records[executionID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrExecutionNotFound, executionID)
+	}
+	kept := make([]ExecutionFeedback, 0, len(record.Feedback))
+	for _, feedback := range record.Feedback {
+		if feedback.ID != feedbackID {
+			kept = append(kept, feedback)
+		}
+	}
+	if len(kept) == len(record.Feedback) {
+		return fmt.Errorf("%w: %s", ErrFeedbackNotFound, feedbackID)
+	}
+	record.Feedback = kept
+	return nil
+}
+
+// AddFeedback records feedback on a tracked execution and publishes it.
+// Delta IDs must name deltas the execution produced, as tagged in the
+// blob's delta history.
+func (o *Orchestrator) AddFeedback(ctx context.Context, feedback *ExecutionFeedback) error {
+	if err := feedback.Validate(); err != nil {
+		return err
+	}
+	record, ok := o.executions.get(feedback.ExecutionID)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrExecutionNotFound, feedback.ExecutionID)
+	}
+	if len(feedback.DeltaIDs) > 0 {
+		deltaIDs, err := o.executionDeltaIDs(ctx, record)
+		if err != nil {
+			return err
+		}
+		seen := make(map[string]bool)
+		var unique []string
+		for _, deltaID := range feedback.DeltaIDs {
+			if !deltaIDs[deltaID] {
+				return fmt.Errorf("%w: delta %s was not produced by execution %s", ErrInvalidFeedback, deltaID, record.ExecutionID)
+			}
+			if !seen[deltaID] {
+				seen[deltaID] = true
+				unique = append(unique, deltaID)
+			}
+		}
+		feedback.DeltaIDs = unique
+	}
+
+	feedback.ID = uuid.New().String()
+	feedback.CreatedAt = time.Now()
+	record, err := o.executions.addFeedback(*feedback)
+	if err != nil {
+		return err
+	}
+
+	execCtx := ExecutionContext{UserID: record.UserID, ProviderID: record.ProviderID, BlobID: record.BlobID}
+	o.publishExecutionEvent(ctx, EventExecutionFeedback, execCtx, record.WorkflowID, record.ExecutionID, map[string]interface{}{
+		"feedback_id":      feedback.ID,
+		"rating":           feedback.Rating,
+		"comment":          feedback.Comment,
+		"delta_ids":        feedback.DeltaIDs,
+		"workflow_version": record.WorkflowVersion,
+		"workflow_digest":  record.WorkflowDigest,
+	})
+	return nil
+}
+
+// executionDeltaIDs returns the IDs of the deltas in a blob's history that
+// an execution produced
+func (o *Orchestrator) executionDeltaIDs(ctx context.Context, record ExecutionRecord) (map[string]bool, error) {
+	if o.deltaProcessor.storage == nil {
+		return nil, fmt.Errorf("%w: delta history is not configured", ErrInvalidFeedback)
+	}
+	deltas, err := o.deltaProcessor.storage.GetByBlobID(ctx, record.BlobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deltas: %w", err)
+	}
+	deltaIDs := make(map[string]bool)
+	for _, delta := range deltas {
+		if executionID, _ := delta.Metadata["execution_id"].(string); executionID == record.ExecutionID {
+			deltaIDs[delta.ID] = true
+		}
+	}
+	return deltaIDs, nil
+}
+
+// ExecutionFeedback returns the feedback on a tracked execution, oldest
+// first
+func (o *Orchestrator) ExecutionFeedback(executionID string) ([]ExecutionFeedback, error) {
+	record, ok := o.executions.get(executionID)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrExecutionNotFound, executionID)
+	}
+	feedback := make([]ExecutionFeedback, len(record.Feedback))
+	copy(feedback, record.Feedback)
+	return feedback, nil
+}
+
+// DeleteFeedback removes feedback from a tracked execution
+func (o *Orchestrator) DeleteFeedback(executionID, feedbackID string) error {
+	return o.executions.deleteFeedback(executionID, feedbackID)
+}
+
+// ProviderFeedback totals the feedback on a provider's tracked executions
+// per workflow version, ordered by workflow and version
+func (o *Orchestrator) ProviderFeedback(providerID string) []FeedbackSummary {
+	records, _ := o.executions.list(ExecutionFilter{ProviderID: providerID})
+
+	type key struct {
+		workflowID string
+		version    int
+	}
+	summaries := make(map[key]*FeedbackSummary)
+	for _, record := range records {
+		k := key{record.WorkflowID, record.WorkflowVersion}
+		summary, ok := summaries[k]
+		if !ok {
+			summary = &FeedbackSummary{ProviderID: providerID, WorkflowID: record.WorkflowID, WorkflowVersion: record.WorkflowVersion}
+			summaries[k] = summary
+		}
+		summary.Executions++
+		switch record.rating() {
+		case RatingUp:
+			summary.Rated++
+			summary.ThumbsUp++
+		case RatingDown:
+			summary.Rated++
+			summary.ThumbsDown++
+		}
+		for _, feedback := range record.Feedback {
+			if feedback.Comment != "" {
+				summary.Comments++
+			}
+		}
+	}
+
+	result := make([]FeedbackSummary, 0, len(summaries))
+	for _, summary := range summaries {
+		if summary.Rated > 0 {
+			summary.ApprovalRate = float64(summary.ThumbsUp) / float64(summary.Rated)
+		}
+		result = append(result, *summary)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].WorkflowID != result[j].WorkflowID {
+			return result[i].WorkflowID < result[j].WorkflowID
+		}
+		return result[i].WorkflowVersion < result[j].WorkflowVersion
+	})
+	return result
+}
@@ -0,0 +1,146 @@
+package workflows
+
+import (
+	"context"
+	"strings"
+)
+
+// EventFilter selects the events a subscription receives. An event matches
+// when each field that is set matches: its type is one of Types, its
+// provider one of ProviderIDs, its blob ID starts with one of
+// BlobIDPrefixes and, for Paths, it is a delta.applied or
+// delta.batch_applied event with a path touching one of them (see
+// PathsOverlap). The zero filter matches every event. Batch events match
+// by the type of the events they hold, and any provider or path, since
+// their events may differ in those.
+type EventFilter struct {
+	Types          []string `json:"types,omitempty"`
+	ProviderIDs    []string `json:"provider_ids,omitempty"`
+	BlobIDPrefixes []string `json:"blob_id_prefixes,omitempty"`
+	Paths          []string `json:"paths,omitempty"`
+}
+
+// Matches reports whether an event passes the filter
+func (f EventFilter) Matches(event Event) bool {
+	if len(f.Types) > 0 && !contains(f.Types, BatchedType(event)) {
+		return false
+	}
+	if len(f.ProviderIDs) > 0 && event.Type != EventBatch && !contains(f.ProviderIDs, event.ProviderID) {
+		return false
+	}
+	if len(f.Paths) > 0 && event.Type != EventBatch && len(MatchPaths(f.Paths, event)) == 0 {
+		return false
+	}
+	if len(f.BlobIDPrefixes) > 0 {
+		for _, prefix := range f.BlobIDPrefixes {
+			if strings.HasPrefix(event.BlobID, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// PathsOverlap reports whether a change at one delta path changes the
+// value at another: when they are the same path or one is within the
+// other. Paths compare by segment, so /chapters/3 and chapters.3 are the
+// same path and the root, "" or "/", overlaps every path.
+func PathsOverlap(a, b string) bool {
+	as, bs := pathSegments(a), pathSegments(b)
+	if len(bs) < len(as) {
+		as, bs = bs, as
+	}
+	for i, segment := range as {
+		if bs[i] != segment {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchPaths returns the paths among paths whose value a delta.applied
+// event's delta, or any of a delta.batch_applied event's, changed, in
+// their order, or nil for other events
+func MatchPaths(paths []string, event Event) []string {
+	changed := eventPaths(event)
+	if len(changed) == 0 {
+		return nil
+	}
+	var matched []string
+	for _, path := range paths {
+		for _, c := range changed {
+			if PathsOverlap(path, c) {
+				matched = append(matched, path)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// PathNotification returns a copy of a delta.applied or
+// delta.batch_applied event for a subscriber to paths, its data naming the
+// subscribed paths its deltas changed as matched_paths, or false when they
+// changed none of them
+func PathNotification(paths []string, event Event) (Event, bool) {
+	matched := MatchPaths(paths, event)
+	if len(matched) == 0 {
+		return event, false
+	}
+	data := make(map[string]interface{}, len(event.Data)+1)
+	for key, value := range event.Data {
+		data[key] = value
+	}
+	data["matched_paths"] = matched
+	event.Data = data
+	return event, true
+}
+
+// MatchesAny reports whether an event passes any of filters; without
+// filters every event does
+func MatchesAny(filters []EventFilter, event Event) bool {
+	if len(filters) == 0 {
+		return true
+	}
+	for _, filter := range filters {
+		if filter.Matches(event) {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterHandler returns a handler passing handler only the events that
+// match filters, for buses that cannot filter before delivery
+func FilterHandler(handler EventHandler, filters ...EventFilter) EventHandler {
+	if len(filters) == 0 {
+		return handler
+	}
+	return func(ctx context.Context, event Event) error {
+		if !MatchesAny(filters, event) {
+			return nil
+		}
+		return handler(ctx, event)
+	}
+}
+
+// FilterTypes returns every event type filters can match, for buses that
+// route events by type, or nil when they can match any type
+func FilterTypes(filters []EventFilter) []string {
+	if len(filters) == 0 {
+		return nil
+	}
+	var types []string
+	for _, filter := range filters {
+		if len(filter.Types) == 0 {
+			return nil
+		}
+		for _, eventType := range filter.Types {
+			if !contains(types, eventType) {
+				types = append(types, eventType)
+			}
+		}
+	}
+	return types
+}
@@ -0,0 +1,145 @@
+package workflows
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// JournalEntry is an execution in flight as journaled: what the
+// orchestrator knew of it and the request that started it, so it can be
+// started again if the backend lost it
+type JournalEntry struct {
+	Record  ExecutionRecord  `json:"record"`
+	Request ExecutionRequest `json:"request"`
+}
+
+// ExecutionJournal persists the executions an orchestrator has in flight,
+// so a restart can reconcile them rather than orphan them
+type ExecutionJournal interface {
+	Save(ctx context.Context, entry *JournalEntry) error
+	List(ctx context.Context) ([]*JournalEntry, error)
+	Delete(ctx context.Context, executionID string) error
+}
+
+// SetExecutionJournal journals executions from now on while they run.
+// Journal errors never fail processing; onError, if set, is told of them.
+func (o *Orchestrator) SetExecutionJournal(journal ExecutionJournal, onError func(error)) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.journal = journal
+	o.onJournalError = onError
+}
+
+// journalExecution saves an execution that is still running
+func (o *Orchestrator) journalExecution(ctx context.Context, req ExecutionRequest, record *ExecutionRecord) {
+	o.mu.RLock()
+	journal, onError := o.journal, o.onJournalError
+	o.mu.RUnlock()
+	if journal == nil || IsTerminalStatus(record.Status) {
+		return
+	}
+	entry := &JournalEntry{Record: *record, Request: req}
+	entry.Record.Feedback = nil
+	if err := journal.Save(ctx, entry); err != nil && onError != nil {
+		onError(fmt.Errorf("failed to journal execution %s: %w", record.ExecutionID, err))
+	}
+}
+
+// unjournalExecution removes a finished execution from the journal
+func (o *Orchestrator) unjournalExecution(ctx context.Context, executionID string) {
+	o.mu.RLock()
+	journal, onError := o.journal, o.onJournalError
+	o.mu.RUnlock()
+	if journal == nil {
+		return
+	}
+	if err := journal.Delete(ctx, executionID); err != nil && onError != nil {
+		onError(fmt.Errorf("failed to remove execution %s from the journal: %w", executionID, err))
+	}
+}
+
+// FileJournal keeps each journaled execution in a JSON file under a
+// directory. Execution IDs may contain slashes, so files are named by
+// their URL-safe base64 encoding.
+type FileJournal struct {
+	dir string
+}
+
+// NewFileJournal creates a journal writing under dir
+func NewFileJournal(dir string) *FileJournal {
+	return &FileJournal{dir: dir}
+}
+
+// Save writes an entry, replacing its file atomically
+func (j *FileJournal) Save(ctx context.Context, entry *JournalEntry) error {
+	id := entry.Record.ExecutionID
+	if id == "" {
+		return fmt.Errorf("invalid execution id %q", id)
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal execution %s: %w", id, err)
+	}
+	if err := os.MkdirAll(j.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create journal directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(j.dir, ".execution-*")
+	if err != nil {
+		return fmt.Errorf("failed to write execution %s: %w", id, err)
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), j.path(id))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write execution %s: %w", id, err)
+	}
+	return nil
+}
+
+// List reads every entry
+func (j *FileJournal) List(ctx context.Context) ([]*JournalEntry, error) {
+	paths, err := filepath.Glob(filepath.Join(j.dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list journaled executions: %w", err)
+	}
+	entries := make([]*JournalEntry, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			// Finished since the listing
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read journaled execution: %w", err)
+		}
+		var entry JournalEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse journaled execution %s: %w", path, err)
+		}
+		entries = append(entries, &entry)
+	}
+	return entries, nil
+}
+
+// Delete removes an entry; removing one that is not journaled does nothing
+func (j *FileJournal) Delete(ctx context.Context, executionID string) error {
+	if err := os.Remove(j.path(executionID)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete execution %s: %w", executionID, err)
+	}
+	return nil
+}
+
+// path maps an execution ID to its file
+func (j *FileJournal) path(executionID string) string {
+	name := base64.RawURLEncoding.EncodeToString([]byte(executionID))
+	return filepath.Join(j.dir, name+".json")
+}
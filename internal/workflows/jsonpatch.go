@@ -0,0 +1,415 @@
+package workflows
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// DeltaJSONPatch is the type of deltas whose new value is a JSON Patch
+// (RFC 6902) document, applied to the value at the delta's path
+const DeltaJSONPatch = "json_patch"
+
+// DeltaMergePatch is the type of deltas whose new value is a JSON Merge
+// Patch (RFC 7396), merged into the value at the delta's path
+const DeltaMergePatch = "merge_patch"
+
+// JSON Patch operations
+const (
+	PatchAdd     = "add"
+	PatchRemove  = "remove"
+	PatchReplace = "replace"
+	PatchMove    = "move"
+	PatchCopy    = "copy"
+	PatchTest    = "test"
+)
+
+// Patch errors
+var (
+	// ErrInvalidPatch is returned for patch documents that are malformed
+	ErrInvalidPatch = errors.New("invalid JSON patch")
+	// ErrPatchFailed is returned when a patch does not apply to a
+	// document, because a path does not exist or a test fails
+	ErrPatchFailed = errors.New("JSON patch failed")
+)
+
+// PatchOperation is one operation of a JSON Patch document
+type PatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	From  string      `json:"from,omitempty"`
+	Value interface{} `json:"value"`
+}
+
+// JSONPatch is a JSON Patch document: operations applied in order, all or
+// none of them
+type JSONPatch []PatchOperation
+
+// ParsePatch reads a JSON Patch document, such as a json_patch delta's new
+// value, checking each operation has the members its op requires
+func ParsePatch(value interface{}) (JSONPatch, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	var members []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return nil, fmt.Errorf("%w: a patch is an array of operations", ErrInvalidPatch)
+	}
+	patch := make(JSONPatch, 0, len(members))
+	for i, m := range members {
+		var op PatchOperation
+		if err := patchMember(m, "op", &op.Op); err != nil {
+			return nil, fmt.Errorf("%w: operation %d: %v", ErrInvalidPatch, i, err)
+		}
+		if err := patchMember(m, "path", &op.Path); err != nil {
+			return nil, fmt.Errorf("%w: operation %d: %v", ErrInvalidPatch, i, err)
+		}
+		if _, err := parsePointer(op.Path); err != nil {
+			return nil, fmt.Errorf("%w: operation %d: %v", ErrInvalidPatch, i, err)
+		}
+		switch op.Op {
+		case PatchAdd, PatchReplace, PatchTest:
+			if err := patchMember(m, "value", &op.Value); err != nil {
+				return nil, fmt.Errorf("%w: operation %d: %v", ErrInvalidPatch, i, err)
+			}
+		case PatchMove, PatchCopy:
+			if err := patchMember(m, "from", &op.From); err != nil {
+				return nil, fmt.Errorf("%w: operation %d: %v", ErrInvalidPatch, i, err)
+			}
+			if _, err := parsePointer(op.From); err != nil {
+				return nil, fmt.Errorf("%w: operation %d: %v", ErrInvalidPatch, i, err)
+			}
+		case PatchRemove:
+		default:
+			return nil, fmt.Errorf("%w: operation %d has unknown op %q", ErrInvalidPatch, i, op.Op)
+		}
+		patch = append(patch, op)
+	}
+	return patch, nil
+}
+
+// patchMember decodes a required member of an operation. A value member
+// that is null is present, unlike one left out.
+func patchMember(m map[string]json.RawMessage, name string, v interface{}) error {
+	raw, ok := m[name]
+	if !ok {
+		return fmt.Errorf("%q is required", name)
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("%q: %v", name, err)
+	}
+	return nil
+}
+
+// Apply applies the patch to a copy of a document and returns the copy. If
+// any operation fails the document is returned unpatched with the error.
+func (p JSONPatch) Apply(doc interface{}) (interface{}, error) {
+	patched := cloneValue(doc)
+	for i, op := range p {
+		var err error
+		if patched, err = op.apply(patched); err != nil {
+			return doc, fmt.Errorf("%w: operation %d (%s %s): %v", ErrPatchFailed, i, op.Op, op.Path, err)
+		}
+	}
+	return patched, nil
+}
+
+// apply applies one operation to a document it may change in place
+func (op PatchOperation) apply(doc interface{}) (interface{}, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return doc, err
+	}
+	switch op.Op {
+	case PatchAdd:
+		return pointerAdd(doc, path, cloneValue(op.Value))
+	case PatchRemove:
+		doc, _, err := pointerRemove(doc, path)
+		return doc, err
+	case PatchReplace:
+		if _, err := pointerGet(doc, path); err != nil {
+			return doc, err
+		}
+		if len(path) == 0 {
+			return cloneValue(op.Value), nil
+		}
+		doc, _, err := pointerRemove(doc, path)
+		if err != nil {
+			return doc, err
+		}
+		return pointerAdd(doc, path, cloneValue(op.Value))
+	case PatchMove:
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return doc, err
+		}
+		if op.From == op.Path {
+			_, err := pointerGet(doc, from)
+			return doc, err
+		}
+		if strings.HasPrefix(op.Path, op.From+"/") {
+			return doc, fmt.Errorf("cannot move %s into itself", op.From)
+		}
+		doc, value, err := pointerRemove(doc, from)
+		if err != nil {
+			return doc, err
+		}
+		return pointerAdd(doc, path, value)
+	case PatchCopy:
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return doc, err
+		}
+		value, err := pointerGet(doc, from)
+		if err != nil {
+			return doc, err
+		}
+		return pointerAdd(doc, path, cloneValue(value))
+	case PatchTest:
+		value, err := pointerGet(doc, path)
+		if err != nil {
+			return doc, err
+		}
+		if !jsonEqual(value, op.Value) {
+			return doc, errors.New("test failed")
+		}
+		return doc, nil
+	}
+	return doc, fmt.Errorf("unknown op %q", op.Op)
+}
+
+// MergePatch returns a copy of target with a JSON Merge Patch merged in:
+// members of an object patch replace the target's, null members remove
+// them, and any other patch replaces the target whole
+func MergePatch(target, patch interface{}) interface{} {
+	members, ok := patch.(map[string]interface{})
+	if !ok {
+		return cloneValue(patch)
+	}
+	merged, ok := cloneValue(target).(map[string]interface{})
+	if !ok {
+		merged = make(map[string]interface{})
+	}
+	for key, value := range members {
+		if value == nil {
+			delete(merged, key)
+			continue
+		}
+		merged[key] = MergePatch(merged[key], value)
+	}
+	return merged
+}
+
+// ApplyPatchDelta applies a json_patch or merge_patch delta strictly: it
+// replays the blob's log up to the delta, which history may include, and
+// returns the value the delta leaves at its path. Unlike Replay, which
+// skips a patch that does not apply, it returns an error wrapping
+// ErrInvalidPatch or ErrPatchFailed.
+func ApplyPatchDelta(history []Delta, delta Delta) (interface{}, error) {
+	before := make([]Delta, 0, len(history))
+	for _, d := range history {
+		if d.ID == delta.ID && delta.ID != "" {
+			break
+		}
+		if delta.Sequence == 0 || d.Sequence < delta.Sequence {
+			before = append(before, d)
+		}
+	}
+	var p DeltaProcessor
+	state := p.Replay(before, math.MaxInt64)
+	return patchValue(state, delta)
+}
+
+// patchValue returns the value a patch delta leaves at its path in a
+// state, or the whole state for the root, without changing the state
+func patchValue(state map[string]interface{}, delta Delta) (interface{}, error) {
+	segments := pathSegments(delta.Path)
+	var target interface{} = map[string]interface{}(state)
+	if len(segments) > 0 {
+		target = lookup(state, segments)
+	}
+	if delta.Type == DeltaMergePatch {
+		return MergePatch(target, delta.NewValue), nil
+	}
+	patch, err := ParsePatch(delta.NewValue)
+	if err != nil {
+		return nil, err
+	}
+	return patch.Apply(target)
+}
+
+// parsePointer splits a JSON pointer (RFC 6901) into its unescaped
+// reference tokens; the empty pointer is the whole document
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("pointer %q does not start with /", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		if strings.Contains(strings.NewReplacer("~0", "", "~1", "").Replace(token), "~") {
+			return nil, fmt.Errorf("pointer %q has an invalid escape", pointer)
+		}
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	return tokens, nil
+}
+
+// pointerGet returns the value a pointer refers to
+func pointerGet(doc interface{}, path []string) (interface{}, error) {
+	node := doc
+	for i, token := range path {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			value, ok := n[token]
+			if !ok {
+				return nil, fmt.Errorf("%s does not exist", formatPointer(path[:i+1]))
+			}
+			node = value
+		case []interface{}:
+			index, err := arrayIndex(token, len(n)-1)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", formatPointer(path[:i+1]), err)
+			}
+			node = n[index]
+		default:
+			return nil, fmt.Errorf("%s is not an object or array", formatPointer(path[:i]))
+		}
+	}
+	return node, nil
+}
+
+// pointerAdd adds a value at a pointer, inserting into arrays, and returns
+// the document
+func pointerAdd(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent, err := pointerGet(doc, path[:len(path)-1])
+	if err != nil {
+		return doc, err
+	}
+	last := path[len(path)-1]
+	switch n := parent.(type) {
+	case map[string]interface{}:
+		n[last] = value
+		return doc, nil
+	case []interface{}:
+		index := len(n)
+		if last != "-" {
+			if index, err = arrayIndex(last, len(n)); err != nil {
+				return doc, fmt.Errorf("%s: %v", formatPointer(path), err)
+			}
+		}
+		grown := make([]interface{}, 0, len(n)+1)
+		grown = append(grown, n[:index]...)
+		grown = append(grown, value)
+		grown = append(grown, n[index:]...)
+		return replaceParent(doc, path[:len(path)-1], grown)
+	}
+	return doc, fmt.Errorf("%s is not an object or array", formatPointer(path[:len(path)-1]))
+}
+
+// pointerRemove removes the value at a pointer and returns the document
+// and the value
+func pointerRemove(doc interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return doc, nil, errors.New("cannot remove the whole document")
+	}
+	parent, err := pointerGet(doc, path[:len(path)-1])
+	if err != nil {
+		return doc, nil, err
+	}
+	last := path[len(path)-1]
+	switch n := parent.(type) {
+	case map[string]interface{}:
+		value, ok := n[last]
+		if !ok {
+			return doc, nil, fmt.Errorf("%s does not exist", formatPointer(path))
+		}
+		delete(n, last)
+		return doc, value, nil
+	case []interface{}:
+		index, err := arrayIndex(last, len(n)-1)
+		if err != nil {
+			return doc, nil, fmt.Errorf("%s: %v", formatPointer(path), err)
+		}
+		value := n[index]
+		shrunk := append(append(make([]interface{}, 0, len(n)-1), n[:index]...), n[index+1:]...)
+		doc, err = replaceParent(doc, path[:len(path)-1], shrunk)
+		return doc, value, err
+	}
+	return doc, nil, fmt.Errorf("%s is not an object or array", formatPointer(path[:len(path)-1]))
+}
+
+// replaceParent puts an array that changed length back where it was found
+func replaceParent(doc interface{}, path []string, array []interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return array, nil
+	}
+	parent, err := pointerGet(doc, path[:len(path)-1])
+	if err != nil {
+		return doc, err
+	}
+	last := path[len(path)-1]
+	switch n := parent.(type) {
+	case map[string]interface{}:
+		n[last] = array
+	case []interface{}:
+		index, err := arrayIndex(last, len(n)-1)
+		if err != nil {
+			return doc, err
+		}
+		n[index] = array
+	}
+	return doc, nil
+}
+
+// arrayIndex reads an array index token, which must be a decimal number
+// without leading zeros no greater than max
+func arrayIndex(token string, max int) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') || strings.TrimLeft(token, "0123456789") != "" {
+		return 0, fmt.Errorf("%q is not an array index", token)
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index > max {
+		return 0, fmt.Errorf("index %s is out of range", token)
+	}
+	return index, nil
+}
+
+// formatPointer joins reference tokens into a JSON pointer
+func formatPointer(path []string) string {
+	var b strings.Builder
+	for _, token := range path {
+		b.WriteString("/")
+		b.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(token))
+	}
+	return b.String()
+}
+
+// jsonEqual compares two values as JSON, so numbers of different Go types
+// with the same value are equal
+func jsonEqual(a, b interface{}) bool {
+	return reflect.DeepEqual(normalizeJSON(a), normalizeJSON(b))
+}
+
+// normalizeJSON round-trips a value through JSON
+func normalizeJSON(value interface{}) interface{} {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return value
+	}
+	return normalized
+}
@@ -0,0 +1,54 @@
+package workflows
+
+import "context"
+
+// Execution lanes. A scheduler serves queued interactive executions, such
+// as those started as a user saves a blob, before queued batch ones such
+// as overnight reprocessing.
+const (
+	LaneInteractive = "interactive"
+	LaneBatch       = "batch"
+)
+
+// Lanes lists the lanes from first served to last
+var Lanes = []string{LaneInteractive, LaneBatch}
+
+// IsLane reports whether executions can run in a lane
+func IsLane(lane string) bool {
+	for _, known := range Lanes {
+		if lane == known {
+			return true
+		}
+	}
+	return false
+}
+
+// laneKey is the context key for a lane set with WithLane
+type laneKey struct{}
+
+// WithLane returns a context whose executions run in a lane, whatever their
+// providers' triggers ask for
+func WithLane(ctx context.Context, lane string) context.Context {
+	return context.WithValue(ctx, laneKey{}, lane)
+}
+
+// LaneFromContext returns the lane set with WithLane, or ""
+func LaneFromContext(ctx context.Context) string {
+	lane, _ := ctx.Value(laneKey{}).(string)
+	return lane
+}
+
+// executionLane picks the lane of a provider's executions for an event: the
+// context's lane, else the lane of the provider's trigger for the event,
+// else the interactive lane
+func executionLane(ctx context.Context, provider *Provider, eventType string) string {
+	if lane := LaneFromContext(ctx); lane != "" {
+		return lane
+	}
+	for _, trigger := range provider.Triggers {
+		if trigger.Event == eventType && trigger.Lane != "" {
+			return trigger.Lane
+		}
+	}
+	return LaneInteractive
+}
@@ -0,0 +1,360 @@
+package workflows
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultLatencyWindow is how many of a step's latest durations are kept
+	DefaultLatencyWindow = 500
+	// DefaultTimeoutMargin is the fraction added to a step's p99 duration to
+	// recommend its timeout
+	DefaultTimeoutMargin = 0.25
+	// DefaultTimeoutMinSamples is how many runs a step needs before its
+	// timeout is tuned
+	DefaultTimeoutMinSamples = 20
+	// DefaultTimeoutTolerance is how far, as a fraction of the timeout, a
+	// recommendation must be from the current timeout to be made
+	DefaultTimeoutTolerance = 0.2
+	// DefaultMinTimeout and DefaultMaxTimeout bound recommended timeouts,
+	// in seconds
+	DefaultMinTimeout = 1
+	DefaultMaxTimeout = 3600
+)
+
+// timeoutLintRule is the lint rule reporting timeouts that latencies
+// recommend changing; steps that suppress it are not tuned either
+const timeoutLintRule = "timeout-latency"
+
+// LatencyConfig configures StepLatencies; zero fields take the defaults.
+// A step's recommended timeout is its p99 duration plus Margin, in whole
+// seconds within MinTimeout and MaxTimeout and no longer than its
+// workflow's max execution time.
+type LatencyConfig struct {
+	Window     int
+	Margin     float64
+	MinSamples int
+	Tolerance  float64
+	MinTimeout int
+	MaxTimeout int
+}
+
+// StepHealth summarizes how a step of a workflow has been running. Score
+// is 0 to 100: the share of runs that completed, less up to half when the
+// p99 duration comes within a fifth of the timeout.
+type StepHealth struct {
+	WorkflowID         string `json:"workflow_id"`
+	StepID             string `json:"step_id"`
+	Completed          int    `json:"completed"`
+	Failed             int    `json:"failed"`
+	Samples            int    `json:"samples"`
+	P50Ms              int64  `json:"p50_ms"`
+	P95Ms              int64  `json:"p95_ms"`
+	P99Ms              int64  `json:"p99_ms"`
+	MaxMs              int64  `json:"max_ms"`
+	TimeoutSeconds     int    `json:"timeout_seconds"`
+	Score              int    `json:"score"`
+	RecommendedTimeout int    `json:"recommended_timeout_seconds,omitempty"`
+}
+
+// stepKey identifies a step of a workflow
+type stepKey struct {
+	workflowID string
+	stepID     string
+}
+
+// stepSamples holds a step's latest durations, in a ring, and its counts.
+// Token totals cover the runs that reported usage.
+type stepSamples struct {
+	durations    []time.Duration
+	next         int
+	completed    int
+	failed       int
+	usageRuns    int
+	inputTokens  int64
+	outputTokens int64
+}
+
+// StepLatencies collects the durations of the steps workflows run, as the
+// backends report them, keeping a window of the latest for each step.
+// Durations run from when a step was scheduled until it finished, retries
+// included; only steps that completed add samples, while failures are
+// counted.
+type StepLatencies struct {
+	cfg LatencyConfig
+
+	mu    sync.RWMutex
+	steps map[stepKey]*stepSamples
+}
+
+// NewStepLatencies creates an empty collector
+func NewStepLatencies(cfg LatencyConfig) *StepLatencies {
+	if cfg.Window <= 0 {
+		cfg.Window = DefaultLatencyWindow
+	}
+	if cfg.Margin <= 0 {
+		cfg.Margin = DefaultTimeoutMargin
+	}
+	if cfg.MinSamples <= 0 {
+		cfg.MinSamples = DefaultTimeoutMinSamples
+	}
+	if cfg.Tolerance <= 0 {
+		cfg.Tolerance = DefaultTimeoutTolerance
+	}
+	if cfg.MinTimeout <= 0 {
+		cfg.MinTimeout = DefaultMinTimeout
+	}
+	if cfg.MaxTimeout <= 0 {
+		cfg.MaxTimeout = DefaultMaxTimeout
+	}
+	return &StepLatencies{cfg: cfg, steps: make(map[stepKey]*stepSamples)}
+}
+
+// Record adds a run of a step
+func (l *StepLatencies) Record(workflowID, stepID string, duration time.Duration, failed bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := stepKey{workflowID, stepID}
+	samples, ok := l.steps[key]
+	if !ok {
+		samples = &stepSamples{}
+		l.steps[key] = samples
+	}
+	if failed {
+		samples.failed++
+		return
+	}
+	samples.completed++
+	if len(samples.durations) < l.cfg.Window {
+		samples.durations = append(samples.durations, duration)
+		return
+	}
+	samples.durations[samples.next] = duration
+	samples.next = (samples.next + 1) % l.cfg.Window
+}
+
+// RecordUsage adds the tokens a completed run of a step used
+func (l *StepLatencies) RecordUsage(workflowID, stepID string, inputTokens, outputTokens int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := stepKey{workflowID, stepID}
+	samples, ok := l.steps[key]
+	if !ok {
+		samples = &stepSamples{}
+		l.steps[key] = samples
+	}
+	samples.usageRuns++
+	samples.inputTokens += int64(inputTokens)
+	samples.outputTokens += int64(outputTokens)
+}
+
+// Usage returns a step's mean input and output tokens per run and how many
+// runs reported usage
+func (l *StepLatencies) Usage(workflowID, stepID string) (float64, float64, int) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	samples, ok := l.steps[stepKey{workflowID, stepID}]
+	if !ok || samples.usageRuns == 0 {
+		return 0, 0, 0
+	}
+	runs := float64(samples.usageRuns)
+	return float64(samples.inputTokens) / runs, float64(samples.outputTokens) / runs, samples.usageRuns
+}
+
+// Health returns the health of each step of a workflow that has run, in
+// step order
+func (l *StepLatencies) Health(w *BlobProcessingWorkflow) []StepHealth {
+	health := []StepHealth{}
+	for _, step := range w.Steps {
+		if h, ok := l.stepHealth(w, step); ok {
+			health = append(health, h)
+		}
+	}
+	return health
+}
+
+// Recommend returns the timeout a step should have, if there are enough
+// samples and it is far enough from the step's timeout
+func (l *StepLatencies) Recommend(w *BlobProcessingWorkflow, step BlobProcessingStep) (int, bool) {
+	h, ok := l.stepHealth(w, step)
+	if !ok || h.RecommendedTimeout == 0 {
+		return 0, false
+	}
+	return h.RecommendedTimeout, true
+}
+
+// stepHealth summarizes a step's samples
+func (l *StepLatencies) stepHealth(w *BlobProcessingWorkflow, step BlobProcessingStep) (StepHealth, bool) {
+	l.mu.RLock()
+	samples, ok := l.steps[stepKey{w.ID, step.ID}]
+	var durations []time.Duration
+	var completed, failed int
+	if ok {
+		durations = append(durations, samples.durations...)
+		completed, failed = samples.completed, samples.failed
+	}
+	l.mu.RUnlock()
+	if !ok || completed+failed == 0 {
+		// Steps that only reported usage have no durations to summarize
+		return StepHealth{}, false
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+	h := StepHealth{
+		WorkflowID:     w.ID,
+		StepID:         step.ID,
+		Completed:      completed,
+		Failed:         failed,
+		Samples:        len(durations),
+		P50Ms:          percentile(durations, 0.50).Milliseconds(),
+		P95Ms:          percentile(durations, 0.95).Milliseconds(),
+		P99Ms:          percentile(durations, 0.99).Milliseconds(),
+		TimeoutSeconds: step.Config.Timeout,
+	}
+	if len(durations) > 0 {
+		h.MaxMs = durations[len(durations)-1].Milliseconds()
+	}
+
+	score := 100 * float64(completed) / float64(completed+failed)
+	if h.TimeoutSeconds > 0 && len(durations) > 0 {
+		pressure := percentile(durations, 0.99).Seconds() / float64(h.TimeoutSeconds)
+		if pressure > 0.8 {
+			score -= 50 * math.Min(1, (pressure-0.8)/0.2)
+		}
+	}
+	h.Score = int(math.Round(math.Max(0, score)))
+
+	if len(durations) >= l.cfg.MinSamples {
+		recommended := l.timeoutFor(w, percentile(durations, 0.99))
+		current := step.Config.Timeout
+		if current == 0 || math.Abs(float64(recommended-current)) > l.cfg.Tolerance*float64(current) {
+			h.RecommendedTimeout = recommended
+		}
+	}
+	return h, true
+}
+
+// timeoutFor is the timeout, in seconds, recommended for a p99 duration
+func (l *StepLatencies) timeoutFor(w *BlobProcessingWorkflow, p99 time.Duration) int {
+	seconds := int(math.Ceil(p99.Seconds() * (1 + l.cfg.Margin)))
+	if seconds < l.cfg.MinTimeout {
+		seconds = l.cfg.MinTimeout
+	}
+	if seconds > l.cfg.MaxTimeout {
+		seconds = l.cfg.MaxTimeout
+	}
+	if limit := w.Config.MaxExecutionTime; limit > 0 && seconds > limit {
+		seconds = limit
+	}
+	return seconds
+}
+
+// percentile returns the nearest-rank percentile of sorted durations
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
+// recordStepLatency adds a step result's duration, when the backend
+// reported one, to the orchestrator's latencies, with the tokens a
+// completed step's output reports it used
+func (o *Orchestrator) recordStepLatency(workflowID, stepID string, result map[string]interface{}) {
+	o.mu.RLock()
+	latencies := o.latencies
+	o.mu.RUnlock()
+	if latencies == nil {
+		return
+	}
+	if ms, ok := toFloat(result["duration_ms"]); ok {
+		latencies.Record(workflowID, stepID, time.Duration(ms*float64(time.Millisecond)), result["status"] == "failed")
+	}
+	if result["status"] != "completed" {
+		return
+	}
+	output, _ := result["output"].(map[string]interface{})
+	if input, generated, ok := tokenUsage(output); ok {
+		latencies.RecordUsage(workflowID, stepID, input, generated)
+	}
+}
+
+// tokenUsage reads the input and output tokens a step output reports in
+// its usage, named as either major model API names them
+func tokenUsage(output map[string]interface{}) (int, int, bool) {
+	usage, ok := output["usage"].(map[string]interface{})
+	if !ok {
+		return 0, 0, false
+	}
+	read := func(keys ...string) (float64, bool) {
+		for _, key := range keys {
+			if n, ok := toFloat(usage[key]); ok {
+				return n, true
+			}
+		}
+		return 0, false
+	}
+	input, inOK := read("input_tokens", "prompt_tokens")
+	generated, outOK := read("output_tokens", "completion_tokens")
+	if !inOK && !outOK {
+		return 0, 0, false
+	}
+	return int(input), int(generated), true
+}
+
+// SetStepLatencies records the durations of the steps of executions that
+// finish from now on
+func (o *Orchestrator) SetStepLatencies(latencies *StepLatencies) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.latencies = latencies
+}
+
+// lintLatencies are the latencies the timeout-latency rule checks timeouts
+// against, guarded by lintMu
+var (
+	lintMu        sync.RWMutex
+	lintLatencies *StepLatencies
+)
+
+// SetLintLatencies lets lint recommend timeouts from latencies; without
+// them the timeout-latency rule finds nothing
+func SetLintLatencies(latencies *StepLatencies) {
+	lintMu.Lock()
+	defer lintMu.Unlock()
+	lintLatencies = latencies
+}
+
+// lintTimeoutLatency finds steps whose timeouts are far from what their
+// recorded durations call for
+func lintTimeoutLatency(w *BlobProcessingWorkflow, rule LintRule) []LintIssue {
+	lintMu.RLock()
+	latencies := lintLatencies
+	lintMu.RUnlock()
+	if latencies == nil {
+		return nil
+	}
+	var issues []LintIssue
+	for _, step := range w.Steps {
+		h, ok := latencies.stepHealth(w, step)
+		if !ok || h.RecommendedTimeout == 0 {
+			continue
+		}
+		current := "no timeout"
+		if step.Config.Timeout > 0 {
+			current = fmt.Sprintf("a timeout of %ds", step.Config.Timeout)
+		}
+		issues = append(issues, rule.issue(step.ID, "step has %s but a p99 of %dms over %d runs; set timeout_seconds to %d", current, h.P99Ms, h.Samples, h.RecommendedTimeout))
+	}
+	return issues
+}
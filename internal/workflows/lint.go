@@ -0,0 +1,305 @@
+package workflows
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Lint severities. Issues of error severity stop a workflow from being
+// registered; warnings and info are advice.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+// LintIssue is a problem a lint rule found in a workflow. StepID is empty
+// for issues with the workflow as a whole.
+type LintIssue struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	StepID   string `json:"step_id,omitempty"`
+	Message  string `json:"message"`
+}
+
+// String formats an issue as "severity rule [step]: message"
+func (i LintIssue) String() string {
+	if i.StepID == "" {
+		return fmt.Sprintf("%s %s: %s", i.Severity, i.Rule, i.Message)
+	}
+	return fmt.Sprintf("%s %s [%s]: %s", i.Severity, i.Rule, i.StepID, i.Message)
+}
+
+// LintRule checks workflows for one kind of problem
+type LintRule struct {
+	ID          string
+	Severity    string
+	Description string
+	check       func(w *BlobProcessingWorkflow, rule LintRule) []LintIssue
+}
+
+// LintRules are the rules Lint applies. A workflow or step suppresses a
+// rule by listing its ID in lint_suppress.
+var LintRules = []LintRule{
+	{
+		ID:          "timeout-exceeds-workflow",
+		Severity:    SeverityError,
+		Description: "step timeouts must not exceed the workflow's max execution time",
+		check:       lintTimeoutExceedsWorkflow,
+	},
+	{
+		ID:          "retries-exceed-workflow",
+		Severity:    SeverityWarning,
+		Description: "a step's attempts together should fit in the workflow's max execution time",
+		check:       lintRetriesExceedWorkflow,
+	},
+	{
+		ID:          "transform-output-map",
+		Severity:    SeverityWarning,
+		Description: "transform steps should declare an output_map",
+		check:       lintTransformOutputMap,
+	},
+	{
+		ID:          "ai-step-cache",
+		Severity:    SeverityWarning,
+		Description: "expensive AI steps should cache their results with a TTL",
+		check:       lintAIStepCache,
+	},
+	{
+		ID:          "unknown-output-schema",
+		Severity:    SeverityWarning,
+		Description: "output_schema_id should name a registered schema, or outputs fail validation",
+		check:       lintUnknownOutputSchema,
+	},
+	{
+		ID:          timeoutLintRule,
+		Severity:    SeverityInfo,
+		Description: "step timeouts should be near their p99 duration plus a margin, once enough runs are recorded",
+		check:       lintTimeoutLatency,
+	},
+	{
+		ID:          "missing-timeout",
+		Severity:    SeverityInfo,
+		Description: "steps without a timeout can run for the workflow's whole max execution time",
+		check:       lintMissingTimeout,
+	},
+}
+
+// unknownSuppression is the rule reporting suppressions of rules that do
+// not exist, which cannot itself be suppressed
+const unknownSuppression = "unknown-suppression"
+
+// aiInputKeys are input keys that mark a step as calling a model
+var aiInputKeys = []string{"model", "prompt", "system_prompt", "user_prompt"}
+
+// aiStepTypes are step types that call a model
+var aiStepTypes = map[string]bool{"ai": true, "llm": true, "generate": true, "completion": true}
+
+// Lint checks a workflow against LintRules, leaving out suppressed issues,
+// and returns the issues ordered by step then rule
+func Lint(w *BlobProcessingWorkflow) []LintIssue {
+	known := make(map[string]bool, len(LintRules))
+	for _, rule := range LintRules {
+		known[rule.ID] = true
+	}
+	stepSuppressions := make(map[string][]string, len(w.Steps))
+	for _, step := range w.Steps {
+		stepSuppressions[step.ID] = step.LintSuppress
+	}
+
+	issues := []LintIssue{}
+	for _, rule := range LintRules {
+		if hasRule(w.LintSuppress, rule.ID) {
+			continue
+		}
+		for _, issue := range rule.check(w, rule) {
+			if issue.StepID == "" || !hasRule(stepSuppressions[issue.StepID], rule.ID) {
+				issues = append(issues, issue)
+			}
+		}
+	}
+
+	for _, id := range w.LintSuppress {
+		if !known[id] {
+			issues = append(issues, LintIssue{Rule: unknownSuppression, Severity: SeverityWarning, Message: fmt.Sprintf("lint_suppress names unknown rule %q", id)})
+		}
+	}
+	for _, step := range w.Steps {
+		for _, id := range step.LintSuppress {
+			if !known[id] {
+				issues = append(issues, LintIssue{Rule: unknownSuppression, Severity: SeverityWarning, StepID: step.ID, Message: fmt.Sprintf("lint_suppress names unknown rule %q", id)})
+			}
+		}
+	}
+
+	order := make(map[string]int, len(w.Steps))
+	for i, step := range w.Steps {
+		order[step.ID] = i + 1
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		if order[issues[i].StepID] != order[issues[j].StepID] {
+			return order[issues[i].StepID] < order[issues[j].StepID]
+		}
+		return issues[i].Rule < issues[j].Rule
+	})
+	return issues
+}
+
+// LintError is returned when a workflow has lint issues of error severity
+type LintError struct {
+	WorkflowID string
+	Issues     []LintIssue
+}
+
+func (e *LintError) Error() string {
+	messages := make([]string, len(e.Issues))
+	for i, issue := range e.Issues {
+		messages[i] = issue.String()
+	}
+	return fmt.Sprintf("%s: workflow %s fails lint: %s", ErrInvalidWorkflow, e.WorkflowID, strings.Join(messages, "; "))
+}
+
+// Unwrap makes a lint failure an invalid workflow
+func (e *LintError) Unwrap() error { return ErrInvalidWorkflow }
+
+// CheckLint lints a workflow and returns a *LintError listing the issues
+// of error severity, if there are any
+func CheckLint(w *BlobProcessingWorkflow) error {
+	var failed []LintIssue
+	for _, issue := range Lint(w) {
+		if issue.Severity == SeverityError {
+			failed = append(failed, issue)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return &LintError{WorkflowID: w.ID, Issues: failed}
+}
+
+// lintTimeoutExceedsWorkflow finds steps allowed longer than the workflow
+func lintTimeoutExceedsWorkflow(w *BlobProcessingWorkflow, rule LintRule) []LintIssue {
+	limit := w.Config.MaxExecutionTime
+	if limit <= 0 {
+		return nil
+	}
+	var issues []LintIssue
+	for _, step := range w.Steps {
+		if step.Config.Timeout > limit {
+			issues = append(issues, rule.issue(step.ID, "timeout of %ds exceeds the workflow's max execution time of %ds", step.Config.Timeout, limit))
+		}
+	}
+	return issues
+}
+
+// lintRetriesExceedWorkflow finds steps whose retries could outlast the
+// workflow
+func lintRetriesExceedWorkflow(w *BlobProcessingWorkflow, rule LintRule) []LintIssue {
+	limit := w.Config.MaxExecutionTime
+	if limit <= 0 {
+		return nil
+	}
+	var issues []LintIssue
+	for _, step := range w.Steps {
+		attempts := step.Config.MaxRetries + 1
+		if step.RetryPolicy != nil && step.RetryPolicy.MaxAttempts > attempts {
+			attempts = step.RetryPolicy.MaxAttempts
+		}
+		// A timeout over the limit on its own is an error already
+		if attempts > 1 && step.Config.Timeout <= limit && step.Config.Timeout*attempts > limit {
+			issues = append(issues, rule.issue(step.ID, "%d attempts of %ds exceed the workflow's max execution time of %ds", attempts, step.Config.Timeout, limit))
+		}
+	}
+	return issues
+}
+
+// lintTransformOutputMap finds transform steps without an output map
+func lintTransformOutputMap(w *BlobProcessingWorkflow, rule LintRule) []LintIssue {
+	var issues []LintIssue
+	for _, step := range w.Steps {
+		if step.Type == "transform" && len(step.OutputMap) == 0 {
+			issues = append(issues, rule.issue(step.ID, "transform step has no output_map, so later steps see its raw output"))
+		}
+	}
+	return issues
+}
+
+// lintAIStepCache finds AI steps that do not cache results for a TTL
+func lintAIStepCache(w *BlobProcessingWorkflow, rule LintRule) []LintIssue {
+	var issues []LintIssue
+	for _, step := range w.Steps {
+		if !isAIStep(step) {
+			continue
+		}
+		switch {
+		case !step.Config.CacheResults:
+			issues = append(issues, rule.issue(step.ID, "AI step does not cache its results; set cache_ttl_seconds"))
+		case step.Config.CacheTTL <= 0:
+			issues = append(issues, rule.issue(step.ID, "AI step caches its results without a TTL; set cache_ttl_seconds"))
+		}
+	}
+	return issues
+}
+
+// lintMissingTimeout finds steps without a timeout
+func lintMissingTimeout(w *BlobProcessingWorkflow, rule LintRule) []LintIssue {
+	var issues []LintIssue
+	for _, step := range w.Steps {
+		if step.Config.Timeout == 0 {
+			issues = append(issues, rule.issue(step.ID, "step has no timeout_seconds"))
+		}
+	}
+	return issues
+}
+
+// lintUnknownOutputSchema finds output schemas that are not registered
+func lintUnknownOutputSchema(w *BlobProcessingWorkflow, rule LintRule) []LintIssue {
+	var issues []LintIssue
+	if w.OutputSchemaID != "" {
+		if _, err := LookupSchema(w.OutputSchemaID); err != nil {
+			issues = append(issues, rule.issue("", "output_schema_id names unknown schema %q", w.OutputSchemaID))
+		}
+	}
+	for _, step := range w.Steps {
+		if step.OutputSchemaID == "" {
+			continue
+		}
+		if _, err := LookupSchema(step.OutputSchemaID); err != nil {
+			issues = append(issues, rule.issue(step.ID, "output_schema_id names unknown schema %q", step.OutputSchemaID))
+		}
+	}
+	return issues
+}
+
+// hasRule reports whether a lint_suppress list names a rule
+func hasRule(rules []string, id string) bool {
+	for _, rule := range rules {
+		if rule == id {
+			return true
+		}
+	}
+	return false
+}
+
+// issue creates an issue of the rule's severity
+func (r LintRule) issue(stepID, format string, args ...interface{}) LintIssue {
+	return LintIssue{Rule: r.ID, Severity: r.Severity, StepID: stepID, Message: fmt.Sprintf(format, args...)}
+}
+
+// isAIStep reports whether a step calls a model, judging by its type or
+// its inputs and parameters naming a model or prompt
+func isAIStep(step BlobProcessingStep) bool {
+	if aiStepTypes[step.Type] {
+		return true
+	}
+	for _, key := range aiInputKeys {
+		if _, ok := step.InputMap[key]; ok {
+			return true
+		}
+		if _, ok := step.Config.Parameters[key]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,334 @@
+package workflows
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// What Publish does when a MemoryBus subscriber's queue is full
+const (
+	QueueBlock      = "block"       // wait for the subscriber to catch up
+	QueueDrop       = "drop"        // drop the event for that subscriber
+	QueueDropOldest = "drop-oldest" // drop the oldest queued event to make room
+	QueueSpill      = "spill"       // write the event to a file until the subscriber catches up
+)
+
+// DefaultQueueSize is how many events a MemoryBus queues per subscriber by
+// default
+const DefaultQueueSize = 1024
+
+// MemoryBusConfig configures a MemoryBus
+type MemoryBusConfig struct {
+	QueueSize int    // events queued per subscriber, DefaultQueueSize if unset
+	Policy    string // QueueBlock (the default), QueueDrop, QueueDropOldest or QueueSpill
+	// SpillDir holds the files of spilling subscribers, the system's
+	// temporary directory if unset; each file may grow to SpillLimit
+	// bytes, DefaultSpillLimit if unset
+	SpillDir   string
+	SpillLimit int64
+	// OnError, if set, is told when a handler fails; handlers run apart
+	// from Publish, so their errors cannot be returned from it
+	OnError func(error)
+}
+
+// BusStats counts what a MemoryBus has done since it was created. Dropped
+// counts events a subscriber missed, because its queue was full or, under
+// the block policy, because the publisher gave up waiting. Spilled counts
+// events written to disk, which Queued includes while they wait.
+type BusStats struct {
+	Policy      string `json:"policy"`
+	QueueSize   int    `json:"queue_size"`
+	Subscribers int    `json:"subscribers"`
+	Queued      int    `json:"queued"` // events waiting across subscribers
+	Published   int64  `json:"published"`
+	Delivered   int64  `json:"delivered"`
+	Dropped     int64  `json:"dropped"`
+	Spilled     int64  `json:"spilled"`
+	Failed      int64  `json:"failed"` // deliveries whose handler returned an error
+}
+
+// SubscriberBuffer overrides the bus's queue size and policy for one
+// subscriber, so a client that is slow to read, such as a WebSocket, can
+// drop or spill its own events without holding up the rest. Zero fields
+// take the bus's settings.
+type SubscriberBuffer struct {
+	Size   int
+	Policy string
+}
+
+// bufferKey is the context key of a SubscriberBuffer
+type bufferKey struct{}
+
+// WithSubscriberBuffer returns a context that subscribes with a buffer of
+// its own on buses that queue per subscriber; other buses ignore it
+func WithSubscriberBuffer(ctx context.Context, buffer SubscriberBuffer) context.Context {
+	return context.WithValue(ctx, bufferKey{}, buffer)
+}
+
+// validQueuePolicy checks a queue policy
+func validQueuePolicy(policy string) error {
+	switch policy {
+	case QueueBlock, QueueDrop, QueueDropOldest, QueueSpill:
+		return nil
+	}
+	return fmt.Errorf("invalid queue policy %q: use %s, %s, %s or %s", policy, QueueBlock, QueueDrop, QueueDropOldest, QueueSpill)
+}
+
+// MemoryBus is an in-process EventBus for single-node deployments. Each
+// subscriber has a bounded queue drained by its own goroutine, so handlers
+// run apart from publishers and see events in the order they were
+// published. When a queue is full, Publish waits for it, drops the event
+// or the oldest queued one, or spills events to disk until the subscriber
+// catches up, as the subscriber's policy says.
+type MemoryBus struct {
+	mu          sync.RWMutex
+	subscribers map[int]*memorySubscriber
+	next        int
+	queueSize   int
+	policy      string
+	spillDir    string
+	spillLimit  int64
+	onError     func(error)
+
+	published int64
+	delivered int64
+	dropped   int64
+	spilled   int64
+	failed    int64
+}
+
+// memorySubscriber is a MemoryBus subscription
+type memorySubscriber struct {
+	queue   chan Event
+	done    <-chan struct{}
+	filters []EventFilter
+	policy  string
+	spill   *spillQueue   // set under the spill policy
+	wake    chan struct{} // tells the drain goroutine of spilled events
+}
+
+// NewMemoryBus creates an in-process event bus
+func NewMemoryBus(cfg MemoryBusConfig) (*MemoryBus, error) {
+	if cfg.Policy == "" {
+		cfg.Policy = QueueBlock
+	}
+	if err := validQueuePolicy(cfg.Policy); err != nil {
+		return nil, err
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = DefaultQueueSize
+	}
+	return &MemoryBus{
+		subscribers: make(map[int]*memorySubscriber),
+		queueSize:   cfg.QueueSize,
+		policy:      cfg.Policy,
+		spillDir:    cfg.SpillDir,
+		spillLimit:  cfg.SpillLimit,
+		onError:     cfg.OnError,
+	}, nil
+}
+
+// Publish queues an event for every subscriber whose filters it matches,
+// in the trace ctx carries unless it has one. A subscriber that blocks
+// returns the context's error if ctx is done while its queue stays full;
+// no subscriber after it gets the event.
+func (b *MemoryBus) Publish(ctx context.Context, event Event) error {
+	InjectTrace(ctx, &event)
+	atomic.AddInt64(&b.published, 1)
+	b.mu.RLock()
+	subscribers := make([]*memorySubscriber, 0, len(b.subscribers))
+	for _, sub := range b.subscribers {
+		if MatchesAny(sub.filters, event) {
+			subscribers = append(subscribers, sub)
+		}
+	}
+	b.mu.RUnlock()
+
+	for i, sub := range subscribers {
+		if sub.spill != nil && sub.spill.Len() > 0 {
+			// Events already spilled go first
+			b.spillEvent(sub, event)
+			continue
+		}
+		select {
+		case sub.queue <- event:
+			continue
+		default:
+		}
+		switch sub.policy {
+		case QueueDrop:
+			atomic.AddInt64(&b.dropped, 1)
+		case QueueDropOldest:
+			b.replaceOldest(sub, event)
+		case QueueSpill:
+			b.spillEvent(sub, event)
+		default:
+			select {
+			case sub.queue <- event:
+			case <-sub.done:
+			case <-ctx.Done():
+				// Neither this subscriber nor those after it get the event
+				atomic.AddInt64(&b.dropped, int64(len(subscribers)-i))
+				return fmt.Errorf("failed to queue %s event: %w", event.Type, ctx.Err())
+			}
+		}
+	}
+	return nil
+}
+
+// replaceOldest makes room in a full queue by dropping its oldest events
+func (b *MemoryBus) replaceOldest(sub *memorySubscriber, event Event) {
+	for {
+		select {
+		case sub.queue <- event:
+			return
+		default:
+		}
+		select {
+		case <-sub.queue:
+			atomic.AddInt64(&b.dropped, 1)
+		default:
+		}
+	}
+}
+
+// spillEvent writes an event to a subscriber's spill file, dropping it if
+// the file is full
+func (b *MemoryBus) spillEvent(sub *memorySubscriber, event Event) {
+	if err := sub.spill.Push(event); err != nil {
+		atomic.AddInt64(&b.dropped, 1)
+		b.report(fmt.Errorf("failed to spill %s event %s: %w", event.Type, event.ID, err))
+		return
+	}
+	atomic.AddInt64(&b.spilled, 1)
+	select {
+	case sub.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Subscribe passes the events published from now on that match filters to
+// handler, on a goroutine of its own, until ctx is done, each with its
+// trace in the context it is handled with. Events that do not match are
+// never queued for it. Events still queued then are discarded. A
+// SubscriberBuffer in ctx sets the subscriber's queue size and policy.
+func (b *MemoryBus) Subscribe(ctx context.Context, handler EventHandler, filters ...EventFilter) error {
+	handler = TraceHandler(handler)
+	buffer, _ := ctx.Value(bufferKey{}).(SubscriberBuffer)
+	if buffer.Size <= 0 {
+		buffer.Size = b.queueSize
+	}
+	if buffer.Policy == "" {
+		buffer.Policy = b.policy
+	}
+	if err := validQueuePolicy(buffer.Policy); err != nil {
+		return err
+	}
+	sub := &memorySubscriber{
+		queue:   make(chan Event, buffer.Size),
+		done:    ctx.Done(),
+		filters: filters,
+		policy:  buffer.Policy,
+		wake:    make(chan struct{}, 1),
+	}
+	if buffer.Policy == QueueSpill {
+		sub.spill = newSpillQueue(b.spillDir, b.spillLimit)
+	}
+	b.mu.Lock()
+	id := b.next
+	b.next++
+	b.subscribers[id] = sub
+	b.mu.Unlock()
+
+	go func() {
+		defer func() {
+			b.mu.Lock()
+			delete(b.subscribers, id)
+			b.mu.Unlock()
+			if sub.spill != nil {
+				sub.spill.Close()
+			}
+		}()
+		for {
+			event, ok := b.nextEvent(sub)
+			if !ok {
+				return
+			}
+			if err := handler(ctx, event); err != nil {
+				atomic.AddInt64(&b.failed, 1)
+				b.report(fmt.Errorf("failed to handle %s event %s: %w", event.Type, event.ID, err))
+				continue
+			}
+			atomic.AddInt64(&b.delivered, 1)
+		}
+	}()
+	return nil
+}
+
+// nextEvent waits for a subscriber's next event: from its queue, which
+// holds events older than any spilled, and then its spill file. It returns
+// false once the subscription has ended.
+func (b *MemoryBus) nextEvent(sub *memorySubscriber) (Event, bool) {
+	for {
+		select {
+		case <-sub.done:
+			return Event{}, false
+		case event := <-sub.queue:
+			return event, true
+		default:
+		}
+		if sub.spill != nil {
+			event, ok, err := sub.spill.Pop()
+			if err != nil {
+				atomic.AddInt64(&b.dropped, 1)
+				b.report(fmt.Errorf("failed to read spilled event: %w", err))
+				continue
+			}
+			if ok {
+				return event, true
+			}
+		}
+		select {
+		case <-sub.done:
+			return Event{}, false
+		case event := <-sub.queue:
+			return event, true
+		case <-sub.wake:
+		}
+	}
+}
+
+// report passes an error to onError, if set
+func (b *MemoryBus) report(err error) {
+	if b.onError != nil {
+		b.onError(err)
+	}
+}
+
+// Stats returns the bus's counters and how full its queues are
+func (b *MemoryBus) Stats() BusStats {
+	b.mu.RLock()
+	queued := 0
+	for _, sub := range b.subscribers {
+		queued += len(sub.queue)
+		if sub.spill != nil {
+			queued += sub.spill.Len()
+		}
+	}
+	subscribers := len(b.subscribers)
+	b.mu.RUnlock()
+
+	return BusStats{
+		Policy:      b.policy,
+		QueueSize:   b.queueSize,
+		Subscribers: subscribers,
+		Queued:      queued,
+		Published:   atomic.LoadInt64(&b.published),
+		Delivered:   atomic.LoadInt64(&b.delivered),
+		Dropped:     atomic.LoadInt64(&b.dropped),
+		Spilled:     atomic.LoadInt64(&b.spilled),
+		Failed:      atomic.LoadInt64(&b.failed),
+	}
+}
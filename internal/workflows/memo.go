@@ -0,0 +1,233 @@
+package workflows
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMemoTTL is how long the results of steps that cache them
+	// without a TTL are kept
+	DefaultMemoTTL = time.Hour
+	// DefaultMemoEntries is how many results a MemoryStepCache keeps
+	DefaultMemoEntries = 10000
+)
+
+// memoKeyVersion prefixes memo keys, so changing what a key covers does
+// not return results stored under the old scheme
+const memoKeyVersion = "step:v1:"
+
+// StepCache stores step results by memo key. A store shared by every
+// worker, such as Redis, shares results across them; MemoryStepCache
+// shares them within one worker.
+type StepCache interface {
+	// Get returns the result stored under a key, if it has not expired
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores a result under a key for ttl
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// memoIdentity is what a memo key covers: who the step runs for, what runs
+// it and what it is given. The workflow, step ID and execution are left
+// out, so identical steps of different workflows share results.
+type memoIdentity struct {
+	UserID     string                 `json:"user_id"`
+	ProviderID string                 `json:"provider_id"`
+	Type       string                 `json:"type"`
+	Parameters map[string]interface{} `json:"parameters"`
+	Input      map[string]interface{} `json:"input"`
+}
+
+// MemoKey returns the memo key of a step request: a hash of the user, the
+// step's provider and type, which pick its executor, and its resolved
+// parameters and input. Results are not shared between users.
+func MemoKey(req StepRequest) (string, error) {
+	data, err := json.Marshal(memoIdentity{
+		UserID:     req.Context.UserID,
+		ProviderID: req.Step.ProviderID,
+		Type:       req.Step.Type,
+		Parameters: req.Step.Config.Parameters,
+		Input:      req.Input,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to hash step %s: %w", req.Step.ID, err)
+	}
+	sum := sha256.Sum256(data)
+	return memoKeyVersion + hex.EncodeToString(sum[:]), nil
+}
+
+// memoCall is a run of a step that identical requests wait on
+type memoCall struct {
+	done   chan struct{}
+	output []byte
+	err    error
+}
+
+// StepMemo is step middleware memoizing the results of steps that set
+// cache_results, under keys shared by every workflow, so a blob that flows
+// through several providers runs each identical step once. Identical
+// requests that arrive while the step runs wait for its result rather than
+// running it again. Failed runs are not memoized.
+type StepMemo struct {
+	cache   StepCache
+	onError func(error)
+
+	mu       sync.Mutex
+	inFlight map[string]*memoCall
+}
+
+// NewStepMemo memoizes step results in cache. onError, if set, is told of
+// cache failures; steps run as if nothing was cached when the cache fails.
+func NewStepMemo(cache StepCache, onError func(error)) *StepMemo {
+	return &StepMemo{cache: cache, onError: onError, inFlight: make(map[string]*memoCall)}
+}
+
+// Wrap returns an executor that answers steps that cache their results
+// from the memo, running them only when it has no result
+func (m *StepMemo) Wrap(executor StepExecutor) StepExecutor {
+	return StepExecutorFunc(func(ctx context.Context, req StepRequest) (map[string]interface{}, error) {
+		if !req.Step.Config.CacheResults {
+			return executor.Execute(ctx, req)
+		}
+		key, err := MemoKey(req)
+		if err != nil {
+			m.report(err)
+			return executor.Execute(ctx, req)
+		}
+
+		if data, ok, err := m.cache.Get(ctx, key); err != nil {
+			m.report(fmt.Errorf("failed to read memo of step %s: %w", req.Step.ID, err))
+		} else if ok {
+			if output, err := decodeMemo(data); err == nil {
+				return output, nil
+			}
+			m.report(fmt.Errorf("failed to decode memo of step %s: %w", req.Step.ID, err))
+		}
+
+		m.mu.Lock()
+		if call, ok := m.inFlight[key]; ok {
+			m.mu.Unlock()
+			select {
+			case <-call.done:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			if call.err != nil {
+				// The run it waited on failed; this one gets its own try
+				return executor.Execute(ctx, req)
+			}
+			return decodeMemo(call.output)
+		}
+		call := &memoCall{done: make(chan struct{})}
+		m.inFlight[key] = call
+		m.mu.Unlock()
+
+		output, err := executor.Execute(ctx, req)
+		call.err = err
+		if err == nil {
+			if call.output, call.err = json.Marshal(output); call.err != nil {
+				m.report(fmt.Errorf("failed to encode output of step %s: %w", req.Step.ID, call.err))
+			} else {
+				// Stored before the call ends, so no identical request
+				// finds neither
+				ttl := time.Duration(req.Step.Config.CacheTTL) * time.Second
+				if ttl <= 0 {
+					ttl = DefaultMemoTTL
+				}
+				if err := m.cache.Set(ctx, key, call.output, ttl); err != nil {
+					m.report(fmt.Errorf("failed to memoize step %s: %w", req.Step.ID, err))
+				}
+			}
+		}
+		m.mu.Lock()
+		delete(m.inFlight, key)
+		m.mu.Unlock()
+		close(call.done)
+		return output, err
+	})
+}
+
+// report passes an error to onError, if set
+func (m *StepMemo) report(err error) {
+	if m.onError != nil {
+		m.onError(err)
+	}
+}
+
+// decodeMemo decodes a memoized output, a fresh copy for each caller
+func decodeMemo(data []byte) (map[string]interface{}, error) {
+	var output map[string]interface{}
+	if err := json.Unmarshal(data, &output); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+// memoEntry is a result a MemoryStepCache holds
+type memoEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// MemoryStepCache is an in-process StepCache keeping up to a number of
+// results, dropping the least recently used first
+type MemoryStepCache struct {
+	max int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // most recently used first
+}
+
+// NewMemoryStepCache creates a cache of up to max results,
+// DefaultMemoEntries if max is not positive
+func NewMemoryStepCache(max int) *MemoryStepCache {
+	if max <= 0 {
+		max = DefaultMemoEntries
+	}
+	return &MemoryStepCache{max: max, entries: make(map[string]*list.Element), order: list.New()}
+}
+
+// Get returns the result stored under a key, if it has not expired
+func (c *MemoryStepCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := element.Value.(*memoEntry)
+	if time.Now().After(entry.expires) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return nil, false, nil
+	}
+	c.order.MoveToFront(element)
+	return entry.value, true, nil
+}
+
+// Set stores a result under a key for ttl
+func (c *MemoryStepCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := &memoEntry{key: key, value: value, expires: time.Now().Add(ttl)}
+	if element, ok := c.entries[key]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return nil
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.max {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*memoEntry).key)
+	}
+	return nil
+}
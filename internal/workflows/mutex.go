@@ -0,0 +1,163 @@
+package workflows
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ErrInvalidMutexKey is returned for mutex keys that are malformed or do not
+// resolve against an execution
+var ErrInvalidMutexKey = errors.New("invalid mutex key")
+
+// StepMutexKey returns the mutex key template a step runs under: its own,
+// or else the workflow's
+func (w *BlobProcessingWorkflow) StepMutexKey(step BlobProcessingStep) string {
+	if step.MutexKey != "" {
+		return step.MutexKey
+	}
+	return w.MutexKey
+}
+
+// ValidateMutexKey checks the syntax of a mutex key template. Placeholders
+// are scope paths in braces without the $. prefix, such as
+// {blob.namespace_id} or {input.parameters.book_id}; a name without a dot,
+// such as {id}, is a field of the blob.
+func ValidateMutexKey(template string) error {
+	_, err := expandMutexKey(template, func(string) (string, error) { return "", nil })
+	return err
+}
+
+// MutexKey resolves a mutex key template against the scope. Every
+// placeholder must resolve to a non-empty string or a number, so a missing
+// field cannot widen the key to lock unrelated executions together.
+func (s ExecutionScope) MutexKey(template string) (string, error) {
+	return expandMutexKey(template, func(name string) (string, error) {
+		path := "$." + name
+		if !strings.Contains(name, ".") {
+			path = "$.blob." + name
+		}
+		value, ok := s.Lookup(path)
+		if !ok {
+			return "", fmt.Errorf("%w: {%s} is not set", ErrInvalidMutexKey, name)
+		}
+		switch v := value.(type) {
+		case string:
+			if v == "" {
+				return "", fmt.Errorf("%w: {%s} is empty", ErrInvalidMutexKey, name)
+			}
+			return v, nil
+		case float64, int, int64:
+			return fmt.Sprint(v), nil
+		}
+		return "", fmt.Errorf("%w: {%s} is not a string or number", ErrInvalidMutexKey, name)
+	})
+}
+
+// expandMutexKey replaces each placeholder in a template with what resolve
+// returns for its name
+func expandMutexKey(template string, resolve func(name string) (string, error)) (string, error) {
+	var key strings.Builder
+	rest := template
+	for {
+		open := strings.IndexAny(rest, "{}")
+		if open < 0 {
+			key.WriteString(rest)
+			return key.String(), nil
+		}
+		if rest[open] == '}' {
+			return "", fmt.Errorf("%w: unmatched } in %q", ErrInvalidMutexKey, template)
+		}
+		end := strings.IndexAny(rest[open+1:], "{}")
+		if end < 0 || rest[open+1+end] == '{' {
+			return "", fmt.Errorf("%w: unclosed { in %q", ErrInvalidMutexKey, template)
+		}
+		name := strings.TrimSpace(rest[open+1 : open+1+end])
+		if name == "" {
+			return "", fmt.Errorf("%w: empty placeholder in %q", ErrInvalidMutexKey, template)
+		}
+		value, err := resolve(name)
+		if err != nil {
+			return "", err
+		}
+		key.WriteString(rest[:open])
+		key.WriteString(value)
+		rest = rest[open+1+end+1:]
+	}
+}
+
+// KeyedMutex serializes the steps of concurrent executions that share a
+// mutex key. Locks are held within one process, so steps are serialized
+// across the executions one worker runs.
+type KeyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*keyLock
+}
+
+// keyLock is the lock for one key, dropped once nobody holds or waits for
+// it
+type keyLock struct {
+	held chan struct{}
+	refs int
+}
+
+// NewKeyedMutex creates a keyed mutex with no keys held
+func NewKeyedMutex() *KeyedMutex {
+	return &KeyedMutex{locks: make(map[string]*keyLock)}
+}
+
+// Lock waits until key is free or ctx is done, and returns the function
+// that frees it again
+func (m *KeyedMutex) Lock(ctx context.Context, key string) (func(), error) {
+	m.mu.Lock()
+	lock, ok := m.locks[key]
+	if !ok {
+		lock = &keyLock{held: make(chan struct{}, 1)}
+		m.locks[key] = lock
+	}
+	lock.refs++
+	m.mu.Unlock()
+
+	select {
+	case lock.held <- struct{}{}:
+	case <-ctx.Done():
+		m.release(key, lock)
+		return nil, ctx.Err()
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			<-lock.held
+			m.release(key, lock)
+		})
+	}, nil
+}
+
+// release drops a reference to a key's lock
+func (m *KeyedMutex) release(key string, lock *keyLock) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	lock.refs--
+	if lock.refs == 0 {
+		delete(m.locks, key)
+	}
+}
+
+// Wrap returns an executor that runs steps with a mutex key only while
+// holding it. Time spent waiting counts against the step's timeout.
+func (m *KeyedMutex) Wrap(executor StepExecutor) StepExecutor {
+	return StepExecutorFunc(func(ctx context.Context, req StepRequest) (map[string]interface{}, error) {
+		if req.MutexKey == "" {
+			return executor.Execute(ctx, req)
+		}
+		unlock, err := m.Lock(ctx, req.MutexKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to acquire mutex %s: %w", req.MutexKey, err)
+		}
+		defer unlock()
+		return executor.Execute(ctx, req)
+	})
+}
@@ -0,0 +1,268 @@
+package workflows
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Namespace default errors
+var (
+	ErrNamespaceDefaultsNotFound = errors.New("namespace defaults not found")
+	ErrInvalidNamespaceDefaults  = errors.New("invalid namespace defaults")
+)
+
+// maxNamespaceDepth bounds how many enclosing namespaces are searched for
+// inherited defaults
+const maxNamespaceDepth = 16
+
+// defaultAttachmentEvent is the event attached providers run on when an
+// attachment names none
+const defaultAttachmentEvent = "onCreate"
+
+// ProviderAttachment attaches a provider to the blobs of a namespace. The
+// provider runs on them for its events, onCreate by default, whatever its
+// own triggers, with Parameters merged over its configured parameters.
+type ProviderAttachment struct {
+	ProviderID string                 `json:"provider_id"`
+	Events     []string               `json:"events,omitempty"`
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// NamespaceDefaults are the providers attached by default to the blobs of a
+// namespace. With AllowSubNamespaces, namespaces inside it inherit them: a
+// sub-namespace overrides an inherited attachment by attaching the same
+// provider, drops it by listing the provider in OptOut, and with NoInherit
+// inherits nothing at all. ModelPolicy is inherited the same way, setting
+// by setting. A namespace is a blob ID, so the namespace enclosing it is
+// that blob's namespace.
+type NamespaceDefaults struct {
+	NamespaceID        string               `json:"namespace_id"`
+	UserID             string               `json:"user_id"`
+	AllowSubNamespaces bool                 `json:"allow_sub_namespaces"`
+	NoInherit          bool                 `json:"no_inherit,omitempty"`
+	Providers          []ProviderAttachment `json:"providers"`
+	OptOut             []string             `json:"opt_out,omitempty"`
+	ModelPolicy        *ModelPolicy         `json:"model_policy,omitempty"`
+	UpdatedAt          time.Time            `json:"updated_at"`
+}
+
+// EffectiveAttachment is an attachment in force for a namespace, with the
+// namespace whose defaults define it
+type EffectiveAttachment struct {
+	ProviderAttachment
+	DefinedBy string `json:"defined_by"`
+	Inherited bool   `json:"inherited"`
+}
+
+// Validate checks namespace defaults before they are set
+func (d *NamespaceDefaults) Validate() error {
+	if d.NamespaceID == "" {
+		return fmt.Errorf("%w: namespace_id is required", ErrInvalidNamespaceDefaults)
+	}
+	seen := make(map[string]bool, len(d.Providers))
+	for _, attachment := range d.Providers {
+		if attachment.ProviderID == "" {
+			return fmt.Errorf("%w: attachments need a provider_id", ErrInvalidNamespaceDefaults)
+		}
+		if seen[attachment.ProviderID] {
+			return fmt.Errorf("%w: provider %s is attached twice", ErrInvalidNamespaceDefaults, attachment.ProviderID)
+		}
+		seen[attachment.ProviderID] = true
+		for _, event := range attachment.Events {
+			if !triggerEvents[event] {
+				return fmt.Errorf("%w: provider %s is attached on unknown event %q", ErrInvalidNamespaceDefaults, attachment.ProviderID, event)
+			}
+		}
+	}
+	for _, providerID := range d.OptOut {
+		if providerID == "" {
+			return fmt.Errorf("%w: opt_out lists an empty provider id", ErrInvalidNamespaceDefaults)
+		}
+	}
+	if d.ModelPolicy != nil {
+		return d.ModelPolicy.Validate()
+	}
+	return nil
+}
+
+// runsOn reports whether an attachment runs its provider for an event
+func (a ProviderAttachment) runsOn(eventType string) bool {
+	if len(a.Events) == 0 {
+		return eventType == defaultAttachmentEvent
+	}
+	for _, event := range a.Events {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// SetNamespaceDefaults sets or replaces a namespace's defaults. Attached
+// providers need not be registered yet; those that are not are skipped.
+func (o *Orchestrator) SetNamespaceDefaults(defaults *NamespaceDefaults) error {
+	if err := defaults.Validate(); err != nil {
+		return err
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if existing, ok := o.namespaces[defaults.NamespaceID]; ok && existing.UserID != defaults.UserID {
+		return fmt.Errorf("%w: namespace %s belongs to another user", ErrInvalidNamespaceDefaults, defaults.NamespaceID)
+	}
+	defaults.UpdatedAt = time.Now()
+	o.namespaces[defaults.NamespaceID] = defaults
+	return nil
+}
+
+// NamespaceDefaults returns a user's defaults for a namespace
+func (o *Orchestrator) NamespaceDefaults(userID, namespaceID string) (*NamespaceDefaults, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	defaults, ok := o.namespaces[namespaceID]
+	if !ok || defaults.UserID != userID {
+		return nil, fmt.Errorf("%w: %s", ErrNamespaceDefaultsNotFound, namespaceID)
+	}
+	return defaults, nil
+}
+
+// DeleteNamespaceDefaults removes a user's defaults for a namespace
+func (o *Orchestrator) DeleteNamespaceDefaults(userID, namespaceID string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	defaults, ok := o.namespaces[namespaceID]
+	if !ok || defaults.UserID != userID {
+		return fmt.Errorf("%w: %s", ErrNamespaceDefaultsNotFound, namespaceID)
+	}
+	delete(o.namespaces, namespaceID)
+	return nil
+}
+
+// EffectiveAttachments returns the attachments in force for the blobs of a
+// user's namespace, its own first and then those inherited from the
+// namespaces enclosing it, nearest first
+func (o *Orchestrator) EffectiveAttachments(ctx context.Context, userID, namespaceID string) []EffectiveAttachment {
+	o.mu.RLock()
+	loader := o.blobLoader
+	o.mu.RUnlock()
+	chain := namespaceChain(ctx, loader, userID, namespaceID)
+
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	attachments := []EffectiveAttachment{}
+	taken := make(map[string]bool)
+	optedOut := make(map[string]bool)
+	for depth, id := range chain {
+		defaults, ok := o.namespaces[id]
+		if !ok || defaults.UserID != userID {
+			continue
+		}
+		if depth == 0 || defaults.AllowSubNamespaces {
+			for _, attachment := range defaults.Providers {
+				if taken[attachment.ProviderID] || optedOut[attachment.ProviderID] {
+					continue
+				}
+				taken[attachment.ProviderID] = true
+				attachments = append(attachments, EffectiveAttachment{
+					ProviderAttachment: attachment,
+					DefinedBy:          id,
+					Inherited:          depth > 0,
+				})
+			}
+		}
+		for _, providerID := range defaults.OptOut {
+			optedOut[providerID] = true
+		}
+		if defaults.NoInherit {
+			break
+		}
+	}
+	return attachments
+}
+
+// namespaceChain returns a namespace and the namespaces enclosing it,
+// innermost first. Each namespace is a blob ID, so the next is that blob's
+// namespace; the chain ends at a namespace that is not a loadable blob.
+func namespaceChain(ctx context.Context, loader BlobLoader, userID, namespaceID string) []string {
+	chain := []string{namespaceID}
+	seen := map[string]bool{namespaceID: true}
+	for loader != nil && len(chain) < maxNamespaceDepth {
+		blob, err := loader.LoadBlob(ctx, userID, chain[len(chain)-1])
+		if err != nil {
+			break
+		}
+		parent, _ := blob["namespace_id"].(string)
+		if parent == "" || seen[parent] {
+			break
+		}
+		seen[parent] = true
+		chain = append(chain, parent)
+	}
+	return chain
+}
+
+// withNamespaceProviders adds to the providers triggered for a blob event
+// those attached to the blob's namespace for the event. An attachment with
+// parameters replaces a triggered provider with a copy using them.
+func (o *Orchestrator) withNamespaceProviders(ctx context.Context, userID string, blob map[string]interface{}, eventType string, providers []*Provider) []*Provider {
+	namespaceID, _ := blob["namespace_id"].(string)
+	if namespaceID == "" {
+		return providers
+	}
+	o.mu.RLock()
+	none := len(o.namespaces) == 0
+	o.mu.RUnlock()
+	if none {
+		return providers
+	}
+
+	index := make(map[string]int, len(providers))
+	for i, provider := range providers {
+		index[provider.ID] = i
+	}
+	for _, attachment := range o.EffectiveAttachments(ctx, userID, namespaceID) {
+		if !attachment.runsOn(eventType) {
+			continue
+		}
+		i, triggered := index[attachment.ProviderID]
+		if triggered && len(attachment.Parameters) == 0 {
+			continue
+		}
+		o.mu.RLock()
+		provider, ok := o.providers[attachment.ProviderID]
+		o.mu.RUnlock()
+		if !ok {
+			continue
+		}
+		if len(attachment.Parameters) > 0 {
+			provider = provider.withParameters(attachment.Parameters)
+		}
+		if triggered {
+			providers[i] = provider
+			continue
+		}
+		index[provider.ID] = len(providers)
+		providers = append(providers, provider)
+	}
+	return providers
+}
+
+// withParameters returns a copy of a provider with parameters merged over
+// its configured ones
+func (p *Provider) withParameters(parameters map[string]interface{}) *Provider {
+	merged := make(map[string]interface{}, len(p.Config.Parameters)+len(parameters))
+	for key, value := range p.Config.Parameters {
+		merged[key] = value
+	}
+	for key, value := range parameters {
+		merged[key] = value
+	}
+	copied := *p
+	copied.Config.Parameters = merged
+	return &copied
+}
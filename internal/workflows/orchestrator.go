@@ -2,7 +2,6 @@ package workflows
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"sync"
 	"time"
@@ -12,14 +11,36 @@ import (
 
 // Orchestrator coordinates workflow execution for blob processing
 type Orchestrator struct {
-	client          *WorkflowClient
+	client          WorkflowService
 	providers       map[string]*Provider
 	workflows       map[string]*BlobProcessingWorkflow
+	namespaces      map[string]*NamespaceDefaults
+	experiments     map[string]*Experiment
 	eventBus        EventBus
 	deltaProcessor  *DeltaProcessor
+	blobLoader      BlobLoader
+	executions      *executionLog
+	journal         ExecutionJournal
+	onJournalError  func(error)
+	onRollbackError func(error)
+	latencies       *StepLatencies
+	outbox          *OutboxDispatcher
+	interactive     InteractiveProfile
+	progress        ProgressReporter
 	mu              sync.RWMutex
 }
 
+// BlobLoader loads a blob's content and metadata for workflow input
+type BlobLoader interface {
+	LoadBlob(ctx context.Context, userID, blobID string) (map[string]interface{}, error)
+}
+
+// BlobProcessor triggers workflow processing for a blob event. Integrations
+// that ingest content depend on this rather than the full orchestrator.
+type BlobProcessor interface {
+	ProcessBlob(ctx context.Context, blobID, userID string, eventType string) error
+}
+
 // Provider represents a blob processing provider
 type Provider struct {
 	ID          string            `json:"id"`
@@ -34,17 +55,19 @@ type Provider struct {
 
 // TriggerConfig defines when a provider should be triggered
 type TriggerConfig struct {
-	Event      string                 `json:"event"` // onCreate, onUpdate, onDelete
-	Conditions []TriggerCondition     `json:"conditions"`
-	Priority   int                    `json:"priority"`
-	Async      bool                   `json:"async"`
-	Metadata   map[string]interface{} `json:"metadata"`
+	Event       string                 `json:"event"` // onCreate, onUpdate, onDelete
+	Conditions  []TriggerCondition     `json:"conditions"`
+	Priority    int                    `json:"priority"`
+	Async       bool                   `json:"async"`
+	Lane        string                 `json:"lane,omitempty"`        // interactive (default) or batch
+	Interactive bool                   `json:"interactive,omitempty"` // run in the interactive profile
+	Metadata    map[string]interface{} `json:"metadata"`
 }
 
 // TriggerCondition defines conditions for triggering
 type TriggerCondition struct {
 	Field    string      `json:"field"`
-	Operator string      `json:"operator"` // eq, ne, gt, lt, contains, regex
+	Operator string      `json:"operator"` // eq, ne, gt, gte, lt, lte, contains, regex, in, not_in, exists
 	Value    interface{} `json:"value"`
 }
 
@@ -57,10 +80,11 @@ type ProviderConfig struct {
 	Parameters        map[string]interface{} `json:"parameters"`
 }
 
-// EventBus interface for event publishing
+// EventBus interface for event publishing. Subscribe passes handler the
+// events matching any of filters, or every event without them.
 type EventBus interface {
 	Publish(ctx context.Context, event Event) error
-	Subscribe(ctx context.Context, handler EventHandler) error
+	Subscribe(ctx context.Context, handler EventHandler, filters ...EventFilter) error
 }
 
 // Event represents a blob event
@@ -72,6 +96,16 @@ type Event struct {
 	ProviderID string                 `json:"provider_id"`
 	Timestamp  time.Time              `json:"timestamp"`
 	Data       map[string]interface{} `json:"data"`
+	
+	// Envelope: the version of the type's schema Data follows, what
+	// published the event, the request it belongs to, what caused it and
+	// the W3C trace context of the trace it is part of
+	SchemaVersion int    `json:"schema_version"`
+	Source        string `json:"source,omitempty"`
+	CorrelationID string `json:"correlation_id,omitempty"`
+	CausationID   string `json:"causation_id,omitempty"`
+	TraceParent   string `json:"traceparent,omitempty"`
+	TraceState    string `json:"tracestate,omitempty"`
 }
 
 // EventHandler handles events
@@ -101,29 +135,58 @@ type Delta struct {
 	Metadata   map[string]interface{} `json:"metadata"`
 	Timestamp  time.Time              `json:"timestamp"`
 	Sequence   int64                  `json:"sequence"`
+	Hash       string                 `json:"hash,omitempty"`
+	PrevHash   string                 `json:"prev_hash,omitempty"`
+	Signature  string                 `json:"signature,omitempty"`
+	KeyID      string                 `json:"key_id,omitempty"`
 }
 
 // NewOrchestrator creates a new workflow orchestrator
 func NewOrchestrator(workflowURL string, eventBus EventBus, deltaStorage DeltaStorage) *Orchestrator {
+	return NewOrchestratorWithService(NewWorkflowClient(workflowURL), eventBus, deltaStorage)
+}
+
+// NewOrchestratorWithService creates an orchestrator backed by an arbitrary workflow service.
+// Without an event bus no events are published, and without delta storage
+// workflow output cannot be applied, though providers can still be registered.
+func NewOrchestratorWithService(service WorkflowService, eventBus EventBus, deltaStorage DeltaStorage) *Orchestrator {
 	return &Orchestrator{
-		client:         NewWorkflowClient(workflowURL),
+		client:         service,
 		providers:      make(map[string]*Provider),
 		workflows:      make(map[string]*BlobProcessingWorkflow),
+		namespaces:     make(map[string]*NamespaceDefaults),
+		experiments:    make(map[string]*Experiment),
 		eventBus:       eventBus,
 		deltaProcessor: &DeltaProcessor{storage: deltaStorage},
+		executions:     newExecutionLog(maxTrackedExecutions),
 	}
 }
 
+// SetBlobLoader makes blob content and metadata available to workflows as $.blob
+func (o *Orchestrator) SetBlobLoader(loader BlobLoader) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.blobLoader = loader
+}
+
 // RegisterProvider registers a provider with its workflows
 func (o *Orchestrator) RegisterProvider(ctx context.Context, provider *Provider) error {
+	if err := provider.Validate(); err != nil {
+		return err
+	}
+	
 	o.mu.Lock()
 	defer o.mu.Unlock()
 	
-	// Register workflows for this provider
+	// Register workflows for this provider; experiments loaded their
+	// variants' workflows when they were set
 	for _, workflowID := range provider.WorkflowIDs {
+		if _, ok := o.experiments[workflowID]; ok {
+			continue
+		}
 		workflow, err := o.client.GetWorkflow(ctx, workflowID)
 		if err != nil {
-			return fmt.Errorf("failed to get workflow %s: %w", workflowID, err)
+			return fmt.Errorf("%w: %s: %v", ErrWorkflowNotFound, workflowID, err)
 		}
 		o.workflows[workflowID] = workflow
 	}
@@ -134,10 +197,33 @@ func (o *Orchestrator) RegisterProvider(ctx context.Context, provider *Provider)
 
 // ProcessBlob processes a blob through applicable providers
 func (o *Orchestrator) ProcessBlob(ctx context.Context, blobID, userID string, eventType string) error {
+	_, err := o.ProcessBlobExecutions(ctx, blobID, userID, eventType)
+	return err
+}
+
+// ProcessBlobExecutions processes a blob like ProcessBlob and returns the
+// IDs of the workflow executions started, by provider. On error it still
+// returns the executions that were started.
+func (o *Orchestrator) ProcessBlobExecutions(ctx context.Context, blobID, userID string, eventType string) (map[string][]string, error) {
 	o.mu.RLock()
-	providers := o.getTriggeredProviders(eventType)
+	subscribed := o.getSubscribedProviders(eventType)
+	loader := o.blobLoader
+	attachable := len(o.namespaces) > 0
 	o.mu.RUnlock()
 	
+	// Load the blob once so every provider sees the same snapshot and
+	// trigger conditions are checked against it
+	var blob map[string]interface{}
+	if loader != nil && (len(subscribed) > 0 || attachable) {
+		loaded, err := loader.LoadBlob(ctx, userID, blobID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load blob %s: %w", blobID, err)
+		}
+		blob = loaded
+	}
+	providers := o.getTriggeredProviders(subscribed, eventType, blob)
+	providers = o.withNamespaceProviders(ctx, userID, blob, eventType, providers)
+	
 	// Create execution context
 	execCtx := ExecutionContext{
 		UserID:    userID,
@@ -148,10 +234,21 @@ func (o *Orchestrator) ProcessBlob(ctx context.Context, blobID, userID string, e
 			"timestamp":  time.Now().Unix(),
 		},
 	}
+	execCtx.TraceParent, execCtx.TraceState = traceFields(ctx)
 	
 	// Process through each provider
 	var wg sync.WaitGroup
 	errors := make(chan error, len(providers))
+	executions := make(map[string][]string)
+	var executionsMu sync.Mutex
+	record := func(providerID string, ids []string) {
+		if len(ids) == 0 {
+			return
+		}
+		executionsMu.Lock()
+		executions[providerID] = ids
+		executionsMu.Unlock()
+	}
 	
 	for _, provider := range providers {
 		if !provider.Active {
@@ -165,13 +262,18 @@ func (o *Orchestrator) ProcessBlob(ctx context.Context, blobID, userID string, e
 			wg.Add(1)
 			go func(p *Provider) {
 				defer wg.Done()
-				if err := o.executeProviderWorkflows(ctx, p, execCtx); err != nil {
+				ids, err := o.executeProviderWorkflows(ctx, p, execCtx, blob)
+				record(p.ID, ids)
+				if err != nil {
 					errors <- fmt.Errorf("provider %s: %w", p.ID, err)
 				}
 			}(provider)
 		} else {
-			if err := o.executeProviderWorkflows(ctx, provider, execCtx); err != nil {
-				return fmt.Errorf("provider %s: %w", provider.ID, err)
+			ids, err := o.executeProviderWorkflows(ctx, provider, execCtx, blob)
+			record(provider.ID, ids)
+			if err != nil {
+				wg.Wait()
+				return executions, fmt.Errorf("provider %s: %w", provider.ID, err)
 			}
 		}
 	}
@@ -187,24 +289,45 @@ func (o *Orchestrator) ProcessBlob(ctx context.Context, blobID, userID string, e
 	}
 	
 	if len(errs) > 0 {
-		return fmt.Errorf("multiple errors during processing: %v", errs)
+		return executions, fmt.Errorf("multiple errors during processing: %v", errs)
 	}
 	
-	return nil
+	return executions, nil
 }
 
-// executeProviderWorkflows executes all workflows for a provider
-func (o *Orchestrator) executeProviderWorkflows(ctx context.Context, provider *Provider, execCtx ExecutionContext) error {
+// executeProviderWorkflows executes all workflows for a provider and
+// returns the IDs of the executions it started
+func (o *Orchestrator) executeProviderWorkflows(ctx context.Context, provider *Provider, execCtx ExecutionContext, blob map[string]interface{}) ([]string, error) {
 	execCtx.ProviderID = provider.ID
 	
+	eventType, _ := execCtx.Metadata["event_type"].(string)
+	lane := executionLane(ctx, provider, eventType)
+	
+	policy := o.namespaceModelPolicy(ctx, execCtx.UserID, blob)
+	profile := executionProfile(ctx, provider, eventType)
+	var deadline time.Duration
+	if profile == ProfileInteractive {
+		// Interactive executions overtake queued ones wherever they wait
+		lane = LaneInteractive
+		policy = o.interactivePolicy(policy)
+		deadline = o.interactiveDeadline()
+	}
+	
+	var executionIDs []string
 	for _, workflowID := range provider.WorkflowIDs {
+		o.mu.RLock()
+		workflowID, experiment, variant := o.resolveWorkflow(workflowID, execCtx.BlobID)
 		workflow, exists := o.workflows[workflowID]
+		o.mu.RUnlock()
 		if !exists {
 			continue
 		}
 		
 		// Build input from blob and provider config
-		input := o.buildWorkflowInput(provider, execCtx)
+		input := o.buildWorkflowInput(provider, execCtx, blob)
+		if policy != nil {
+			input["model_policy"] = policy
+		}
 		
 		req := ExecutionRequest{
 			WorkflowID: workflowID,
@@ -212,70 +335,202 @@ func (o *Orchestrator) executeProviderWorkflows(ctx context.Context, provider *P
 			Context:    execCtx,
 			Priority:   o.getProviderPriority(provider),
 			Async:      true,
+			Lane:       lane,
+		}
+		if profile == ProfileInteractive {
+			req.Profile = profile
+			req.DeadlineMs = deadline.Milliseconds()
 		}
 		
 		// Execute workflow
 		resp, err := o.client.ExecuteWorkflow(ctx, req)
 		if err != nil {
-			return fmt.Errorf("failed to execute workflow %s: %w", workflowID, err)
+			o.publishExecutionEvent(ctx, EventExecutionFailed, execCtx, workflowID, "", map[string]interface{}{
+				"error": err.Error(),
+			})
+			return executionIDs, fmt.Errorf("failed to execute workflow %s: %w", workflowID, err)
 		}
-		
-		// Process workflow output to generate deltas
-		if err := o.processWorkflowOutput(ctx, resp, provider.ID, execCtx.BlobID); err != nil {
-			return fmt.Errorf("failed to process output: %w", err)
+		executionIDs = append(executionIDs, resp.ExecutionID)
+		now := time.Now()
+		record := &ExecutionRecord{
+			ExecutionID: resp.ExecutionID,
+			WorkflowID:  workflowID,
+			ProviderID:  provider.ID,
+			BlobID:      execCtx.BlobID,
+			UserID:      execCtx.UserID,
+			EventType:   eventType,
+			Status:      resp.Status,
+			StartedAt:   now,
+			UpdatedAt:   now,
+			Profile:     req.Profile,
+		}
+		pinDefinition(record, workflow)
+		if experiment != nil {
+			record.ExperimentID = experiment.ID
+			record.Variant = variant.Name
 		}
+		if err := o.trackExecution(ctx, req, resp, record, ""); err != nil {
+			return executionIDs, err
+		}
+	}
+	
+	return executionIDs, nil
+}
+
+// trackExecution records an execution the backend started, journaling it
+// while it runs, publishes its start and step events, and applies its
+// output. retryOf names the execution it replaces, if any.
+func (o *Orchestrator) trackExecution(ctx context.Context, req ExecutionRequest, resp *ExecutionResponse, record *ExecutionRecord, retryOf string) error {
+	execCtx, workflowID := req.Context, record.WorkflowID
+	record.TraceParent, record.TraceState = execCtx.TraceParent, execCtx.TraceState
+	o.executions.add(record)
+	o.journalExecution(ctx, req, record)
+	
+	started := map[string]interface{}{"status": resp.Status}
+	if retryOf != "" {
+		started["retry_of"] = retryOf
+	}
+	o.publishExecutionEvent(ctx, EventExecutionStarted, execCtx, workflowID, resp.ExecutionID, started)
+	o.publishStepEvents(ctx, execCtx, workflowID, resp.ExecutionID, resp.Output)
+	
+	// Process workflow output to generate deltas
+	applied, err := o.processWorkflowOutput(ctx, resp, record)
+	o.executions.addDeltas(resp.ExecutionID, applied)
+	if err != nil {
+		o.publishExecutionEvent(ctx, EventExecutionFailed, execCtx, workflowID, resp.ExecutionID, map[string]interface{}{
+			"error": err.Error(),
+		})
+		o.rollbackFailed(ctx, resp.ExecutionID, resp, err.Error())
+		return fmt.Errorf("failed to process output: %w", err)
 	}
 	
+	if resp.Status == "completed" {
+		data := map[string]interface{}{"status": resp.Status}
+		if IsPartialOutput(resp.Output) {
+			o.executions.setPartial(resp.ExecutionID)
+			data["partial"] = true
+		}
+		o.publishExecutionEvent(ctx, EventExecutionCompleted, execCtx, workflowID, resp.ExecutionID, data)
+	}
+	if req.Profile == ProfileInteractive && !IsTerminalStatus(resp.Status) {
+		o.streamProgress(ctx, execCtx, record, time.Duration(req.DeadlineMs)*time.Millisecond)
+	}
 	return nil
 }
 
-// processWorkflowOutput processes workflow output and generates deltas
-func (o *Orchestrator) processWorkflowOutput(ctx context.Context, resp *ExecutionResponse, providerID, blobID string) error {
+// processWorkflowOutput processes an execution's output and generates
+// deltas, returning how many were applied
+func (o *Orchestrator) processWorkflowOutput(ctx context.Context, resp *ExecutionResponse, record *ExecutionRecord) (int, error) {
 	if resp.Error != nil {
-		return fmt.Errorf("workflow execution error: %s", resp.Error.Message)
+		return 0, fmt.Errorf("workflow execution error: %s", resp.Error.Message)
 	}
+	providerID, blobID := record.ProviderID, record.BlobID
 	
 	// Extract deltas from output
-	deltas := o.extractDeltas(resp.Output, providerID, blobID)
+	deltas := ExtractDeltas(resp.Output, providerID, blobID)
+	if len(deltas) == 0 {
+		// Async executions have no output yet
+		return 0, nil
+	}
+	if o.deltaProcessor.storage == nil {
+		return 0, fmt.Errorf("failed to store %d deltas: no delta storage configured", len(deltas))
+	}
 	
-	// Store deltas
-	for _, delta := range deltas {
-		if err := o.deltaProcessor.storage.Store(ctx, delta); err != nil {
-			return fmt.Errorf("failed to store delta: %w", err)
+	// Break a whole-output transform into the paths it changed
+	if len(deltas) == 1 && isOutputDelta(deltas[0]) {
+		diffed, err := o.diffOutput(ctx, deltas[0])
+		if err != nil {
+			return 0, err
+		}
+		if len(diffed) == 0 {
+			return 0, nil
 		}
+		deltas = diffed
+	}
+	for i := range deltas {
+		tagDelta(&deltas[i], record)
 	}
 	
-	// Apply deltas to blob
-	if err := o.deltaProcessor.storage.ApplyDeltas(ctx, blobID, deltas); err != nil {
-		return fmt.Errorf("failed to apply deltas: %w", err)
+	// The output's deltas change the blob together or not at all, and
+	// one event announces them
+	batch := NewDeltaBatch(blobID, deltas)
+	if err := batch.Validate(); err != nil {
+		return 0, err
+	}
+	event := Event{
+		Type:        EventDeltaBatchApplied,
+		BlobID:      blobID,
+		UserID:      record.UserID,
+		ProviderID:  providerID,
+		CausationID: record.ExecutionID,
+		TraceParent: record.TraceParent,
+		TraceState:  record.TraceState,
+		Data:        batch.eventData(),
 	}
 	
-	// Publish delta events
-	for _, delta := range deltas {
-		event := Event{
-			ID:         uuid.New().String(),
-			Type:       "delta.applied",
-			BlobID:     blobID,
-			ProviderID: providerID,
-			Timestamp:  time.Now(),
-			Data: map[string]interface{}{
-				"delta_id":   delta.ID,
-				"delta_type": delta.Type,
-				"path":       delta.Path,
-			},
+	if err := o.commitDeltas(ctx, batch, []Event{event}); err != nil {
+		return 0, err
+	}
+	return len(batch.Deltas), nil
+}
+
+// commitDeltas stores a batch of deltas, applies them to their blob and
+// publishes their events. The storage must be an OutboxStorage or a
+// BatchStorage: storing and applying the deltas in separate steps could
+// leave a batch stored but not applied, so other storage is refused.
+func (o *Orchestrator) commitDeltas(ctx context.Context, batch *DeltaBatch, events []Event) error {
+	// With an outbox the events are committed with the deltas and the
+	// dispatcher publishes them
+	if outbox := o.outboxDispatcher(); outbox != nil {
+		for i := range events {
+			stampEvent(&events[i])
 		}
-		
-		if err := o.eventBus.Publish(ctx, event); err != nil {
-			// Log error but don't fail
-			fmt.Printf("failed to publish delta event: %v\n", err)
+		if err := outbox.storage.CommitDeltas(ctx, batch.BlobID, batch.Deltas, events); err != nil {
+			return fmt.Errorf("failed to commit deltas: %w", err)
 		}
+		outbox.Notify()
+		return nil
 	}
 	
+	storage, ok := o.deltaProcessor.storage.(BatchStorage)
+	if !ok {
+		return fmt.Errorf("failed to commit delta batch: delta storage %T cannot commit batches atomically", o.deltaProcessor.storage)
+	}
+	if err := storage.CommitBatch(ctx, batch); err != nil {
+		return fmt.Errorf("failed to commit delta batch: %w", err)
+	}
+	
+	// Publish delta events
+	for _, event := range events {
+		o.publishEvent(ctx, event)
+	}
 	return nil
 }
 
-// extractDeltas extracts deltas from workflow output
-func (o *Orchestrator) extractDeltas(output map[string]interface{}, providerID, blobID string) []Delta {
+// CommitDeltas stores deltas made outside a workflow execution, such as
+// merges, applies them to their blob as one batch and publishes the
+// batch's delta.batch_applied event for the user
+func (o *Orchestrator) CommitDeltas(ctx context.Context, blobID, userID string, deltas []Delta) error {
+	if o.deltaProcessor.storage == nil {
+		return fmt.Errorf("failed to store %d deltas: no delta storage configured", len(deltas))
+	}
+	batch := NewDeltaBatch(blobID, deltas)
+	if err := batch.Validate(); err != nil {
+		return err
+	}
+	// Validate made sure the batch has deltas
+	event := Event{
+		Type:       EventDeltaBatchApplied,
+		BlobID:     blobID,
+		UserID:     userID,
+		ProviderID: batch.Deltas[0].ProviderID,
+		Data:       batch.eventData(),
+	}
+	return o.commitDeltas(ctx, batch, []Event{event})
+}
+
+// ExtractDeltas extracts deltas from workflow output
+func ExtractDeltas(output map[string]interface{}, providerID, blobID string) []Delta {
 	var deltas []Delta
 	
 	// Check if output contains deltas field
@@ -313,6 +568,28 @@ func (o *Orchestrator) extractDeltas(output map[string]interface{}, providerID,
 		}
 	}
 	
+	// A JSON Patch or Merge Patch of the whole blob, as providers built
+	// around those standards return, is a patch delta at the root
+	if len(deltas) == 0 {
+		patches := []struct{ key, deltaType string }{{"patch", DeltaJSONPatch}, {"merge_patch", DeltaMergePatch}}
+		for _, patch := range patches {
+			if value, ok := output[patch.key]; ok && value != nil {
+				deltas = append(deltas, Delta{
+					ID:         uuid.New().String(),
+					BlobID:     blobID,
+					ProviderID: providerID,
+					Type:       patch.deltaType,
+					Path:       "/",
+					NewValue:   value,
+					Timestamp:  time.Now(),
+					Metadata: map[string]interface{}{
+						"source": "workflow_output",
+					},
+				})
+			}
+		}
+	}
+	
 	// If no explicit deltas, create one from the entire output
 	if len(deltas) == 0 && len(output) > 0 {
 		delta := Delta{
@@ -333,17 +610,15 @@ func (o *Orchestrator) extractDeltas(output map[string]interface{}, providerID,
 	return deltas
 }
 
-// getTriggeredProviders gets providers triggered by an event
-func (o *Orchestrator) getTriggeredProviders(eventType string) []*Provider {
+// getSubscribedProviders gets providers with a trigger for an event
+func (o *Orchestrator) getSubscribedProviders(eventType string) []*Provider {
 	var providers []*Provider
 	
 	for _, provider := range o.providers {
 		for _, trigger := range provider.Triggers {
 			if trigger.Event == eventType {
-				if o.evaluateTriggerConditions(trigger.Conditions) {
-					providers = append(providers, provider)
-					break
-				}
+				providers = append(providers, provider)
+				break
 			}
 		}
 	}
@@ -351,18 +626,31 @@ func (o *Orchestrator) getTriggeredProviders(eventType string) []*Provider {
 	return providers
 }
 
-// evaluateTriggerConditions evaluates trigger conditions
-func (o *Orchestrator) evaluateTriggerConditions(conditions []TriggerCondition) bool {
-	// If no conditions, trigger is always active
-	if len(conditions) == 0 {
-		return true
+// getTriggeredProviders narrows subscribed providers to those with a
+// trigger for the event whose conditions the blob meets
+func (o *Orchestrator) getTriggeredProviders(subscribed []*Provider, eventType string, blob map[string]interface{}) []*Provider {
+	var providers []*Provider
+	
+	for _, provider := range subscribed {
+		for _, trigger := range provider.Triggers {
+			if trigger.Event == eventType && o.evaluateTriggerConditions(trigger.Conditions, blob) {
+				providers = append(providers, provider)
+				break
+			}
+		}
 	}
 	
+	return providers
+}
+
+// evaluateTriggerConditions evaluates trigger conditions against the blob.
+// Without a blob loader there is no blob, so conditions on its fields fail.
+func (o *Orchestrator) evaluateTriggerConditions(conditions []TriggerCondition, blob map[string]interface{}) bool {
 	// Evaluate all conditions (AND logic)
 	for _, condition := range conditions {
-		// TODO: Implement condition evaluation logic
-		// For now, return true
-		_ = condition
+		if !condition.Matches(blob) {
+			return false
+		}
 	}
 	
 	return true
@@ -390,14 +678,18 @@ func (o *Orchestrator) getProviderPriority(provider *Provider) int {
 }
 
 // buildWorkflowInput builds input for workflow execution
-func (o *Orchestrator) buildWorkflowInput(provider *Provider, ctx ExecutionContext) map[string]interface{} {
-	return map[string]interface{}{
+func (o *Orchestrator) buildWorkflowInput(provider *Provider, ctx ExecutionContext, blob map[string]interface{}) map[string]interface{} {
+	input := map[string]interface{}{
 		"blob_id":     ctx.BlobID,
 		"user_id":     ctx.UserID,
 		"provider_id": provider.ID,
 		"parameters":  provider.Config.Parameters,
 		"metadata":    ctx.Metadata,
 	}
+	if blob != nil {
+		input["blob"] = blob
+	}
+	return input
 }
 
 // GetProviderDAG returns the DAG of providers and their dependencies
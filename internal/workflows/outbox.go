@@ -0,0 +1,184 @@
+package workflows
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultOutboxInterval is how often a dispatcher checks the outbox for
+	// events it was not told of, such as those left by a crash
+	DefaultOutboxInterval = time.Second
+	// DefaultOutboxBatch is how many events a dispatcher reads at a time
+	DefaultOutboxBatch = 100
+)
+
+// OutboxStorage is DeltaStorage with a transactional outbox: the events
+// announcing deltas are written with the deltas, so either both persist or
+// neither does, and are published later by an OutboxDispatcher. A database
+// store keeps the outbox as a table written in the deltas' transaction.
+type OutboxStorage interface {
+	DeltaStorage
+	// CommitDeltas stores and applies a blob's deltas and adds their events
+	// to the outbox, atomically
+	CommitDeltas(ctx context.Context, blobID string, deltas []Delta, events []Event) error
+	// PendingEvents returns up to limit events not yet dispatched, oldest
+	// first
+	PendingEvents(ctx context.Context, limit int) ([]Event, error)
+	// MarkDispatched removes events from the outbox by ID
+	MarkDispatched(ctx context.Context, ids []string) error
+}
+
+// OutboxConfig configures an OutboxDispatcher; zero fields take the
+// defaults
+type OutboxConfig struct {
+	Interval time.Duration
+	Batch    int
+}
+
+// OutboxDispatcher publishes the events of an outbox to a bus, in order,
+// removing each once published or refused. Delivery is at least once: an event
+// published just before a crash is published again, with the same ID, so
+// handlers that must not see it twice dedupe on the ID.
+type OutboxDispatcher struct {
+	storage OutboxStorage
+	bus     EventBus
+	batch   int
+	onError func(error)
+
+	notify chan struct{}
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewOutboxDispatcher starts dispatching an outbox's events when notified
+// and every interval. onError, if set, is told of events that could not be
+// published; they are not tried again, so wrap bus in a DeadLetterQueue to
+// keep them. Close stops it.
+func NewOutboxDispatcher(storage OutboxStorage, bus EventBus, cfg OutboxConfig, onError func(error)) *OutboxDispatcher {
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultOutboxInterval
+	}
+	if cfg.Batch <= 0 {
+		cfg.Batch = DefaultOutboxBatch
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &OutboxDispatcher{
+		storage: storage,
+		bus:     bus,
+		batch:   cfg.Batch,
+		onError: onError,
+		notify:  make(chan struct{}, 1),
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	go d.run(ctx, cfg.Interval)
+	return d
+}
+
+// Notify wakes the dispatcher to publish newly committed events
+func (d *OutboxDispatcher) Notify() {
+	select {
+	case d.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Close stops dispatching, waiting for a pass in progress. Events still in
+// the outbox are published by the next dispatcher.
+func (d *OutboxDispatcher) Close() {
+	d.cancel()
+	<-d.done
+}
+
+// run dispatches at startup, when notified and every interval until the
+// dispatcher is closed
+func (d *OutboxDispatcher) run(ctx context.Context, interval time.Duration) {
+	defer close(d.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	d.dispatch(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.notify:
+		case <-ticker.C:
+		}
+		d.dispatch(ctx)
+	}
+}
+
+// dispatch publishes pending events until the outbox is empty. An event
+// the bus will not take is reported and removed like the others rather
+// than tried again, so it cannot hold up those after it; a
+// DeadLetterQueue bus keeps it to replay.
+func (d *OutboxDispatcher) dispatch(ctx context.Context) {
+	for ctx.Err() == nil {
+		events, err := d.storage.PendingEvents(ctx, d.batch)
+		if err != nil {
+			d.report(fmt.Errorf("failed to read outbox: %w", err))
+			return
+		}
+		if len(events) == 0 {
+			return
+		}
+
+		var dispatched []string
+		for _, event := range events {
+			if err := d.bus.Publish(ctx, event); err != nil {
+				// An event cut off by Close stays for the next dispatcher
+				if ctx.Err() != nil {
+					break
+				}
+				d.report(fmt.Errorf("failed to publish %s event %s: %w", event.Type, event.ID, err))
+			}
+			dispatched = append(dispatched, event.ID)
+		}
+		if len(dispatched) > 0 {
+			if err := d.storage.MarkDispatched(ctx, dispatched); err != nil {
+				d.report(fmt.Errorf("failed to mark %d events dispatched: %w", len(dispatched), err))
+				return
+			}
+		}
+	}
+}
+
+// report passes an error to onError, if set
+func (d *OutboxDispatcher) report(err error) {
+	if d.onError != nil {
+		d.onError(err)
+	}
+}
+
+// EnableOutbox commits workflow output's deltas together with their events
+// from now on, instead of publishing the events once the deltas are
+// applied, and starts a dispatcher publishing them to the orchestrator's
+// bus. The delta storage must be OutboxStorage. The caller closes the
+// dispatcher.
+func (o *Orchestrator) EnableOutbox(cfg OutboxConfig, onError func(error)) (*OutboxDispatcher, error) {
+	storage, ok := o.deltaProcessor.storage.(OutboxStorage)
+	if !ok {
+		return nil, fmt.Errorf("delta storage %T has no outbox", o.deltaProcessor.storage)
+	}
+	if o.eventBus == nil {
+		return nil, fmt.Errorf("an event bus is required to dispatch the outbox")
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.outbox != nil {
+		return nil, fmt.Errorf("outbox is already enabled")
+	}
+	o.outbox = NewOutboxDispatcher(storage, o.eventBus, cfg, onError)
+	return o.outbox, nil
+}
+
+// outboxDispatcher returns the dispatcher of an enabled outbox, or nil
+func (o *Orchestrator) outboxDispatcher() *OutboxDispatcher {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.outbox
+}
@@ -0,0 +1,215 @@
+package workflows
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// maxPolicyTemperature is the highest temperature a model policy may set
+const maxPolicyTemperature = 2
+
+// ModelPolicy overrides the model settings of the AI steps run on a
+// namespace's blobs, so a tenant can, say, keep free-tier users on a
+// cheaper model without editing every workflow. Model replaces every AI
+// step's model, or Models replaces the models it names; Temperature
+// replaces every step's temperature, and MaxTokens caps max_tokens.
+// Interactive gives the settings that replace these for interactive
+// executions, typically a smaller model and a lower cap.
+type ModelPolicy struct {
+	Model       string            `json:"model,omitempty"`
+	Models      map[string]string `json:"models,omitempty"` // model to its replacement
+	Temperature *float64          `json:"temperature,omitempty"`
+	MaxTokens   int               `json:"max_tokens,omitempty"`
+	Interactive *ModelPolicy      `json:"interactive,omitempty"`
+}
+
+// Validate checks a model policy before it is set
+func (p *ModelPolicy) Validate() error {
+	if p.Model != "" && len(p.Models) > 0 {
+		return fmt.Errorf("%w: model_policy sets both model and models", ErrInvalidNamespaceDefaults)
+	}
+	for from, to := range p.Models {
+		if from == "" || to == "" {
+			return fmt.Errorf("%w: model_policy models map model names to model names", ErrInvalidNamespaceDefaults)
+		}
+	}
+	if p.Temperature != nil && (*p.Temperature < 0 || *p.Temperature > maxPolicyTemperature) {
+		return fmt.Errorf("%w: model_policy temperature must be between 0 and %d", ErrInvalidNamespaceDefaults, maxPolicyTemperature)
+	}
+	if p.MaxTokens < 0 {
+		return fmt.Errorf("%w: model_policy max_tokens cannot be negative", ErrInvalidNamespaceDefaults)
+	}
+	if p.Interactive != nil {
+		if p.Interactive.Interactive != nil {
+			return fmt.Errorf("%w: model_policy interactive settings cannot have their own", ErrInvalidNamespaceDefaults)
+		}
+		if err := p.Interactive.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// empty reports whether a policy overrides nothing
+func (p *ModelPolicy) empty() bool {
+	return p.Model == "" && len(p.Models) == 0 && p.Temperature == nil && p.MaxTokens == 0 && p.Interactive == nil
+}
+
+// inherit fills the settings a policy leaves unset from an enclosing
+// namespace's policy
+func (p *ModelPolicy) inherit(outer *ModelPolicy) {
+	if p.Model == "" && len(p.Models) == 0 {
+		p.Model = outer.Model
+	}
+	if p.Model == "" {
+		for from, to := range outer.Models {
+			if _, ok := p.Models[from]; !ok {
+				if p.Models == nil {
+					p.Models = make(map[string]string)
+				}
+				p.Models[from] = to
+			}
+		}
+	}
+	if p.Temperature == nil {
+		p.Temperature = outer.Temperature
+	}
+	if p.MaxTokens == 0 {
+		p.MaxTokens = outer.MaxTokens
+	}
+	if p.Interactive == nil {
+		p.Interactive = outer.Interactive
+	}
+}
+
+// EffectiveModelPolicy returns the model policy for the blobs of a user's
+// namespace: its own, with the settings it leaves unset inherited as
+// attachments are, nearest namespace first. It is nil when no policy
+// applies.
+func (o *Orchestrator) EffectiveModelPolicy(ctx context.Context, userID, namespaceID string) *ModelPolicy {
+	o.mu.RLock()
+	loader := o.blobLoader
+	o.mu.RUnlock()
+	chain := namespaceChain(ctx, loader, userID, namespaceID)
+
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	policy := &ModelPolicy{}
+	for depth, id := range chain {
+		defaults, ok := o.namespaces[id]
+		if !ok || defaults.UserID != userID {
+			continue
+		}
+		if defaults.ModelPolicy != nil && (depth == 0 || defaults.AllowSubNamespaces) {
+			policy.inherit(defaults.ModelPolicy)
+		}
+		if defaults.NoInherit {
+			break
+		}
+	}
+	if policy.empty() {
+		return nil
+	}
+	return policy
+}
+
+// namespaceModelPolicy returns the model policy for a blob's namespace
+func (o *Orchestrator) namespaceModelPolicy(ctx context.Context, userID string, blob map[string]interface{}) *ModelPolicy {
+	namespaceID, _ := blob["namespace_id"].(string)
+	if namespaceID == "" {
+		return nil
+	}
+	o.mu.RLock()
+	none := len(o.namespaces) == 0
+	o.mu.RUnlock()
+	if none {
+		return nil
+	}
+	return o.EffectiveModelPolicy(ctx, userID, namespaceID)
+}
+
+// Apply applies the policy to an AI step's resolved parameters and input.
+// Settings the step gives are replaced where it gives them; a forced model
+// or temperature, or a token cap, the step does not give is added to its
+// parameters. Other steps are left alone.
+func (p *ModelPolicy) Apply(step BlobProcessingStep, parameters, input map[string]interface{}) map[string]interface{} {
+	if p == nil || !isAIStep(step) {
+		return parameters
+	}
+	if parameters == nil {
+		parameters = make(map[string]interface{})
+	}
+	values := []map[string]interface{}{parameters, input}
+
+	set := func(key string, value func(current interface{}) interface{}, force bool) {
+		found := false
+		for _, m := range values {
+			if current, ok := m[key]; ok {
+				m[key] = value(current)
+				found = true
+			}
+		}
+		if !found && force {
+			parameters[key] = value(nil)
+		}
+	}
+	set("model", func(current interface{}) interface{} {
+		if p.Model != "" {
+			return p.Model
+		}
+		if name, ok := current.(string); ok {
+			if replacement, ok := p.Models[name]; ok {
+				return replacement
+			}
+		}
+		return current
+	}, p.Model != "")
+	if p.Temperature != nil {
+		set("temperature", func(interface{}) interface{} { return *p.Temperature }, true)
+	}
+	if p.MaxTokens > 0 {
+		set("max_tokens", func(current interface{}) interface{} {
+			if tokens, ok := toFloat(current); ok && tokens > 0 && tokens < float64(p.MaxTokens) {
+				return current
+			}
+			return p.MaxTokens
+		}, true)
+	}
+	return parameters
+}
+
+// ModelPolicyFrom reads the model policy a workflow input carries, set by
+// the orchestrator for blobs in namespaces with one. Inputs that went
+// through JSON carry it as a map.
+func ModelPolicyFrom(input map[string]interface{}) *ModelPolicy {
+	switch value := input["model_policy"].(type) {
+	case *ModelPolicy:
+		return value
+	case map[string]interface{}:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil
+		}
+		var policy ModelPolicy
+		if err := json.Unmarshal(data, &policy); err != nil || policy.empty() {
+			return nil
+		}
+		return &policy
+	}
+	return nil
+}
+
+// SelectStep resolves a step's parameters and input mapping as in Select
+// and applies the model policy the workflow input carries
+func (s ExecutionScope) SelectStep(step BlobProcessingStep) (parameters, input map[string]interface{}) {
+	parameters = s.Select(step.Config.Parameters)
+	input = s.Select(step.InputMap)
+	if values, ok := s["input"].(map[string]interface{}); ok {
+		if policy := ModelPolicyFrom(values); policy != nil {
+			parameters = policy.Apply(step, parameters, input)
+		}
+	}
+	return parameters, input
+}
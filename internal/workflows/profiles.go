@@ -0,0 +1,178 @@
+package workflows
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Execution profiles. Executions run in the standard profile unless a
+// request or a provider's trigger asks for the interactive one, meant for
+// steps a user sits waiting on: interactive executions start at once
+// rather than queuing, run under the interactive model policy, publish
+// each step's output as it finishes and, at their deadline, give up on the
+// steps still running and complete with the outputs they have.
+const (
+	ProfileStandard    = "standard"
+	ProfileInteractive = "interactive"
+)
+
+// DefaultInteractiveDeadline is how long an interactive execution runs
+// when the orchestrator sets no deadline
+const DefaultInteractiveDeadline = 10 * time.Second
+
+// DeadlineExceededReason is the reason given for the steps an interactive
+// execution skipped or gave up on at its deadline
+const DeadlineExceededReason = "deadline exceeded"
+
+const (
+	// progressInterval is how often a running interactive execution is
+	// checked for steps that have finished
+	progressInterval = 250 * time.Millisecond
+	// progressGrace is how long past its deadline an interactive execution
+	// is still checked, for the backend to wind it up
+	progressGrace = 5 * time.Second
+)
+
+// IsProfile reports whether executions can run in a profile
+func IsProfile(profile string) bool {
+	return profile == ProfileStandard || profile == ProfileInteractive
+}
+
+// InteractiveProfile configures interactive executions. ModelPolicy
+// applies over the namespace's model policy, below the interactive
+// settings the namespace's own policy gives; Deadline bounds each
+// execution, DefaultInteractiveDeadline without it.
+type InteractiveProfile struct {
+	ModelPolicy ModelPolicy
+	Deadline    time.Duration
+}
+
+// ProgressReporter is implemented by backends that can report the output
+// of a running execution so far: the results of the steps that have
+// finished, as the final output will hold them
+type ProgressReporter interface {
+	ExecutionProgress(ctx context.Context, executionID string) (map[string]interface{}, error)
+}
+
+// profileKey is the context key for a profile set with WithProfile
+type profileKey struct{}
+
+// WithProfile returns a context whose executions run in a profile,
+// whatever their providers' triggers ask for
+func WithProfile(ctx context.Context, profile string) context.Context {
+	return context.WithValue(ctx, profileKey{}, profile)
+}
+
+// ProfileFromContext returns the profile set with WithProfile, or ""
+func ProfileFromContext(ctx context.Context) string {
+	profile, _ := ctx.Value(profileKey{}).(string)
+	return profile
+}
+
+// executionProfile picks the profile of a provider's executions for an
+// event: the context's profile, else interactive when the provider's
+// trigger for the event asks for it, else standard
+func executionProfile(ctx context.Context, provider *Provider, eventType string) string {
+	if profile := ProfileFromContext(ctx); profile != "" {
+		return profile
+	}
+	for _, trigger := range provider.Triggers {
+		if trigger.Event == eventType && trigger.Interactive {
+			return ProfileInteractive
+		}
+	}
+	return ProfileStandard
+}
+
+// SetInteractiveProfile configures interactive executions
+func (o *Orchestrator) SetInteractiveProfile(profile InteractiveProfile) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.interactive = profile
+}
+
+// SetProgressReporter sets the backend interactive executions' step
+// outputs are published from while they run; without one they are
+// published when the execution is seen to finish, as for any other
+func (o *Orchestrator) SetProgressReporter(reporter ProgressReporter) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.progress = reporter
+}
+
+// interactiveDeadline returns how long interactive executions may run
+func (o *Orchestrator) interactiveDeadline() time.Duration {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	if o.interactive.Deadline > 0 {
+		return o.interactive.Deadline
+	}
+	return DefaultInteractiveDeadline
+}
+
+// interactivePolicy returns the model policy of interactive executions on
+// a blob whose namespace has a policy, or nil: the namespace policy's
+// interactive settings, then the orchestrator's, then the namespace
+// policy's own, capped at the lowest max_tokens among them
+func (o *Orchestrator) interactivePolicy(namespace *ModelPolicy) *ModelPolicy {
+	o.mu.RLock()
+	defaults := o.interactive.ModelPolicy
+	o.mu.RUnlock()
+
+	policy := &ModelPolicy{}
+	if namespace != nil && namespace.Interactive != nil {
+		policy.inherit(namespace.Interactive)
+	}
+	policy.inherit(&defaults)
+	if namespace != nil {
+		policy.inherit(namespace)
+		if namespace.MaxTokens > 0 && namespace.MaxTokens < policy.MaxTokens {
+			policy.MaxTokens = namespace.MaxTokens
+		}
+	}
+	policy.Interactive = nil
+	if policy.empty() {
+		return nil
+	}
+	return policy
+}
+
+// streamProgress publishes the step events of a running interactive
+// execution, with each step's output, as its steps finish, until it is
+// seen to end or its deadline has long passed. Seeing it end records its
+// outcome at once, so waiting clients hear of it without polling.
+func (o *Orchestrator) streamProgress(ctx context.Context, execCtx ExecutionContext, record *ExecutionRecord, deadline time.Duration) {
+	o.mu.RLock()
+	reporter := o.progress
+	o.mu.RUnlock()
+
+	// The execution outlives the request that started it
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), deadline+progressGrace)
+	go func() {
+		defer cancel()
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			resp, err := o.client.GetExecutionStatus(ctx, record.ExecutionID)
+			if errors.Is(err, ErrExecutionNotFound) {
+				return
+			}
+			if err == nil && IsTerminalStatus(resp.Status) {
+				o.ObserveExecutionStatus(ctx, record.ExecutionID, resp)
+				return
+			}
+			if reporter == nil {
+				continue
+			}
+			if output, err := reporter.ExecutionProgress(ctx, record.ExecutionID); err == nil {
+				o.publishStepEvents(ctx, execCtx, record.WorkflowID, record.ExecutionID, output)
+			}
+		}
+	}()
+}
@@ -0,0 +1,133 @@
+package workflows
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Provider errors
+var (
+	ErrProviderNotFound = errors.New("provider not found")
+	ErrInvalidProvider  = errors.New("invalid provider")
+	ErrProviderInactive = errors.New("provider is not active")
+)
+
+// triggerEvents are the blob events a provider can be triggered by
+var triggerEvents = map[string]bool{"onCreate": true, "onUpdate": true, "onDelete": true, "onSchedule": true}
+
+// IsTriggerEvent reports whether providers can be triggered by an event
+func IsTriggerEvent(event string) bool {
+	return triggerEvents[event]
+}
+
+// providerTypes are the kinds of provider
+var providerTypes = map[string]bool{"namespace": true, "processor": true, "hybrid": true}
+
+// Validate checks a provider before registration: it needs an ID and a
+// known type, and its triggers need known events and lanes and valid
+// conditions
+func (p *Provider) Validate() error {
+	if p.ID == "" {
+		return fmt.Errorf("%w: id is required", ErrInvalidProvider)
+	}
+	if !providerTypes[p.Type] {
+		return fmt.Errorf("%w: %s has unknown type %q", ErrInvalidProvider, p.ID, p.Type)
+	}
+	for _, trigger := range p.Triggers {
+		if !triggerEvents[trigger.Event] {
+			return fmt.Errorf("%w: %s has a trigger on unknown event %q", ErrInvalidProvider, p.ID, trigger.Event)
+		}
+		if trigger.Lane != "" && !IsLane(trigger.Lane) {
+			return fmt.Errorf("%w: trigger for %s on %s has unknown lane %q", ErrInvalidProvider, p.ID, trigger.Event, trigger.Lane)
+		}
+		for _, condition := range trigger.Conditions {
+			if err := condition.Validate(); err != nil {
+				return fmt.Errorf("%w: trigger for %s on %s: %v", ErrInvalidProvider, p.ID, trigger.Event, err)
+			}
+		}
+	}
+	if p.Config.MaxConcurrentJobs < 0 || p.Config.RateLimitPerMin < 0 || p.Config.TimeoutSeconds < 0 {
+		return fmt.Errorf("%w: %s has negative limits", ErrInvalidProvider, p.ID)
+	}
+	return nil
+}
+
+// GetProvider returns a registered provider
+func (o *Orchestrator) GetProvider(providerID string) (*Provider, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	provider, ok := o.providers[providerID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrProviderNotFound, providerID)
+	}
+	return provider, nil
+}
+
+// UnregisterProvider removes a registered provider, so events no longer
+// start its workflows. Executions already started run to the end.
+func (o *Orchestrator) UnregisterProvider(providerID string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if _, ok := o.providers[providerID]; !ok {
+		return fmt.Errorf("%w: %s", ErrProviderNotFound, providerID)
+	}
+	delete(o.providers, providerID)
+	return nil
+}
+
+// ListProviders returns the registered providers ordered by ID
+func (o *Orchestrator) ListProviders() []*Provider {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	providers := make([]*Provider, 0, len(o.providers))
+	for _, provider := range o.providers {
+		providers = append(providers, provider)
+	}
+	sort.Slice(providers, func(i, j int) bool { return providers[i].ID < providers[j].ID })
+	return providers
+}
+
+// ProcessBlobWithProvider runs one provider's workflows on a blob for an
+// event and returns the executions it started. Unlike ProcessBlob it skips
+// the provider's triggers and their conditions, since the caller has chosen
+// the blob, but the provider must be active.
+func (o *Orchestrator) ProcessBlobWithProvider(ctx context.Context, providerID, blobID, userID, eventType string) ([]string, error) {
+	o.mu.RLock()
+	provider, ok := o.providers[providerID]
+	loader := o.blobLoader
+	o.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrProviderNotFound, providerID)
+	}
+	if !provider.Active {
+		return nil, fmt.Errorf("%w: %s", ErrProviderInactive, providerID)
+	}
+
+	var blob map[string]interface{}
+	if loader != nil {
+		loaded, err := loader.LoadBlob(ctx, userID, blobID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load blob %s: %w", blobID, err)
+		}
+		blob = loaded
+	}
+	execCtx := ExecutionContext{
+		UserID:    userID,
+		BlobID:    blobID,
+		RequestID: uuid.New().String(),
+		Metadata: map[string]interface{}{
+			"event_type": eventType,
+			"timestamp":  time.Now().Unix(),
+		},
+	}
+	execCtx.TraceParent, execCtx.TraceState = traceFields(ctx)
+	return o.executeProviderWorkflows(ctx, provider, execCtx, blob)
+}
@@ -0,0 +1,355 @@
+package workflows
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Template provisioning errors
+var (
+	ErrTemplateNotFound           = errors.New("template not found")
+	ErrInvalidTemplateRequest     = errors.New("invalid template request")
+	ErrProviderDefinitionNotFound = errors.New("provider definition not found")
+)
+
+var (
+	definitionsMu sync.RWMutex
+	definitions   = make(map[string]*YAMLProvider)
+)
+
+// Register makes a bundled provider definition available to the templates
+// that require it, replacing any with the same ID
+func (p *YAMLProvider) Register() error {
+	if p.Provider.ID == "" {
+		return fmt.Errorf("%w: provider definition has no id", ErrInvalidProvider)
+	}
+	if !providerTypes[p.Provider.Type] {
+		return fmt.Errorf("%w: definition %s has unknown type %q", ErrInvalidProvider, p.Provider.ID, p.Provider.Type)
+	}
+	for _, mapping := range p.Workflows {
+		if mapping.Template == (mapping.WorkflowID != "") {
+			return fmt.Errorf("%w: definition %s maps a workflow that needs either a workflow_id or template", ErrInvalidProvider, p.Provider.ID)
+		}
+	}
+
+	definitionsMu.Lock()
+	defer definitionsMu.Unlock()
+	definitions[p.Provider.ID] = p
+	return nil
+}
+
+// LookupProviderDefinition returns a registered provider definition
+func LookupProviderDefinition(id string) (*YAMLProvider, error) {
+	definitionsMu.RLock()
+	defer definitionsMu.RUnlock()
+
+	definition, ok := definitions[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrProviderDefinitionNotFound, id)
+	}
+	return definition, nil
+}
+
+// templateBuilder builds a template's workflow. Its namespace variable
+// names the namespace the workflow serves, such as a book.
+type templateBuilder struct {
+	namespaceVariable string
+	build             func(variables map[string]interface{}) *BlobProcessingWorkflow
+}
+
+// templateBuilders are the templates that can be instantiated, by ID
+var templateBuilders = map[string]templateBuilder{
+	"book_writing": {"book_id", func(v map[string]interface{}) *BlobProcessingWorkflow {
+		return CreateBookWritingWorkflow(stringVariable(v, "book_id"), stringVariable(v, "author_id"))
+	}},
+	"research_processor": {"topic_id", func(v map[string]interface{}) *BlobProcessingWorkflow {
+		return CreateResearchWorkflow(stringVariable(v, "topic_id"))
+	}},
+	"code_documentation": {"project_id", func(v map[string]interface{}) *BlobProcessingWorkflow {
+		return CreateCodeDocumentationWorkflow(stringVariable(v, "project_id"))
+	}},
+	"data_processing": {"dataset_id", func(v map[string]interface{}) *BlobProcessingWorkflow {
+		return CreateDataProcessingWorkflow(stringVariable(v, "dataset_id"))
+	}},
+	"audio_transcription": {"project_id", func(v map[string]interface{}) *BlobProcessingWorkflow {
+		return CreateAudioTranscriptionWorkflow(stringVariable(v, "project_id"))
+	}},
+	"audiobook_preview": {"book_id", func(v map[string]interface{}) *BlobProcessingWorkflow {
+		return CreateAudiobookPreviewWorkflow(stringVariable(v, "book_id"))
+	}},
+	"voice_notes_to_chapters": {"project_id", func(v map[string]interface{}) *BlobProcessingWorkflow {
+		return CreateVoiceNoteChapterWorkflow(stringVariable(v, "project_id"))
+	}},
+	"grant_proposal": {"proposal_id", func(v map[string]interface{}) *BlobProcessingWorkflow {
+		return CreateGrantProposalWorkflow(stringVariable(v, "proposal_id"))
+	}},
+	"screenplay_coverage": {"project_id", func(v map[string]interface{}) *BlobProcessingWorkflow {
+		return CreateScreenplayWorkflow(stringVariable(v, "project_id"))
+	}},
+}
+
+// LookupTemplate returns a template that can be instantiated
+func LookupTemplate(id string) (*WorkflowTemplate, error) {
+	if _, ok := templateBuilders[id]; ok {
+		for _, template := range GetWorkflowTemplates() {
+			if template.ID == id {
+				return &template, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, id)
+}
+
+// TemplateRequest asks for a template to be instantiated for a namespace.
+// The namespace fills the template's namespace variable, such as book_id.
+type TemplateRequest struct {
+	TemplateID  string                 `json:"template_id"`
+	NamespaceID string                 `json:"namespace_id"`
+	Variables   map[string]interface{} `json:"variables"`
+}
+
+// TemplateInstance is what instantiating a template set up: its workflow
+// and the providers that run it. SkippedWorkflows are workflows the
+// provider definitions map that are not registered, so their triggers
+// were left out.
+type TemplateInstance struct {
+	TemplateID       string                  `json:"template_id"`
+	NamespaceID      string                  `json:"namespace_id"`
+	Variables        map[string]interface{}  `json:"variables"`
+	Workflow         *BlobProcessingWorkflow `json:"workflow"`
+	Providers        []*Provider             `json:"providers"`
+	SkippedWorkflows []string                `json:"skipped_workflows,omitempty"`
+}
+
+// InstantiateTemplate builds a template's workflow for a namespace and
+// registers it through registry, then registers a provider from each
+// bundled definition the template requires, with triggers narrowed to the
+// namespace's blobs and the variables as its parameters. The provider
+// running the template's own workflow takes the workflow's provider ID;
+// others are named for their definition and the namespace. Everything is
+// built and validated before anything is registered, and instantiating
+// the same template for a namespace again updates what it set up.
+func (o *Orchestrator) InstantiateTemplate(ctx context.Context, registry *WorkflowRegistry, req TemplateRequest) (*TemplateInstance, error) {
+	template, err := LookupTemplate(req.TemplateID)
+	if err != nil {
+		return nil, err
+	}
+	builder := templateBuilders[template.ID]
+	if req.NamespaceID == "" {
+		return nil, fmt.Errorf("%w: namespace_id is required", ErrInvalidTemplateRequest)
+	}
+	given := make(map[string]interface{}, len(req.Variables)+1)
+	for name, value := range req.Variables {
+		given[name] = value
+	}
+	if value, ok := given[builder.namespaceVariable]; ok && value != req.NamespaceID {
+		return nil, fmt.Errorf("%w: %s must be the namespace %s", ErrInvalidTemplateRequest, builder.namespaceVariable, req.NamespaceID)
+	}
+	given[builder.namespaceVariable] = req.NamespaceID
+	variables, err := template.resolveVariables(given)
+	if err != nil {
+		return nil, err
+	}
+
+	workflow := builder.build(variables)
+	if workflow.Type == "" {
+		workflow.Type = WorkflowTypeProcessBlob
+	}
+	if err := workflow.Validate(); err != nil {
+		return nil, err
+	}
+	if err := CheckLint(workflow); err != nil {
+		return nil, err
+	}
+	instance := &TemplateInstance{
+		TemplateID:  template.ID,
+		NamespaceID: req.NamespaceID,
+		Variables:   variables,
+		Workflow:    workflow,
+		Providers:   []*Provider{},
+	}
+	for _, definitionID := range template.Providers {
+		definition, err := LookupProviderDefinition(definitionID)
+		if err != nil {
+			return nil, fmt.Errorf("template %s requires %w", template.ID, err)
+		}
+		provider, skipped, err := provisionProvider(ctx, registry, definition, workflow, req.NamespaceID, variables)
+		if err != nil {
+			return nil, err
+		}
+		instance.SkippedWorkflows = append(instance.SkippedWorkflows, skipped...)
+		if len(provider.WorkflowIDs) > 0 {
+			instance.Providers = append(instance.Providers, provider)
+		}
+	}
+
+	existing, err := registry.Get(ctx, workflow.ID)
+	switch {
+	case errors.Is(err, ErrWorkflowNotFound):
+		if err := registry.Create(ctx, workflow); err != nil {
+			return nil, fmt.Errorf("failed to create workflow %s: %w", workflow.ID, err)
+		}
+	case err != nil:
+		return nil, err
+	case sameDefinition(existing, workflow):
+		instance.Workflow = existing
+	default:
+		if err := registry.Update(ctx, workflow); err != nil {
+			return nil, fmt.Errorf("failed to update workflow %s: %w", workflow.ID, err)
+		}
+	}
+	for _, provider := range instance.Providers {
+		if err := o.RegisterProvider(ctx, provider); err != nil {
+			return instance, fmt.Errorf("failed to register provider %s: %w", provider.ID, err)
+		}
+	}
+	return instance, nil
+}
+
+// provisionProvider builds the provider a definition describes for a
+// template's workflow in a namespace, returning with it the mapped
+// workflows left out because they are not registered
+func provisionProvider(ctx context.Context, registry *WorkflowRegistry, definition *YAMLProvider, workflow *BlobProcessingWorkflow, namespaceID string, variables map[string]interface{}) (*Provider, []string, error) {
+	config := definition.Config
+	provider := &Provider{
+		ID:          definition.Provider.ID + ":" + namespaceID,
+		Name:        definition.Provider.Name,
+		Type:        definition.Provider.Type,
+		NamespaceID: namespaceID,
+		WorkflowIDs: []string{},
+		Triggers:    []TriggerConfig{},
+		Config: ProviderConfig{
+			MaxConcurrentJobs: config.MaxConcurrentJobs,
+			RateLimitPerMin:   config.RateLimitPerMin,
+			TimeoutSeconds:    config.TimeoutSeconds,
+			Parameters:        make(map[string]interface{}, len(config.Parameters)+len(variables)),
+		},
+		Active: true,
+	}
+	if config.RetryPolicy != nil {
+		policy := *config.RetryPolicy
+		provider.Config.RetryPolicy = &policy
+	}
+	for name, value := range config.Parameters {
+		provider.Config.Parameters[name] = value
+	}
+	for name, value := range variables {
+		provider.Config.Parameters[name] = value
+	}
+
+	var skipped []string
+	for _, mapping := range definition.Workflows {
+		workflowID := mapping.WorkflowID
+		if mapping.Template {
+			workflowID = workflow.ID
+			provider.ID = workflow.ProviderID
+		} else if _, err := registry.Get(ctx, workflowID); errors.Is(err, ErrWorkflowNotFound) {
+			skipped = append(skipped, workflowID)
+			continue
+		} else if err != nil {
+			return nil, nil, err
+		}
+		if !contains(provider.WorkflowIDs, workflowID) {
+			provider.WorkflowIDs = append(provider.WorkflowIDs, workflowID)
+		}
+		for _, trigger := range mapping.Triggers {
+			conditions := make([]TriggerCondition, 0, len(trigger.Conditions)+1)
+			for _, condition := range trigger.Conditions {
+				conditions = append(conditions, TriggerCondition{Field: condition.Field, Operator: condition.Operator, Value: condition.Value})
+			}
+			conditions = append(conditions, TriggerCondition{Field: "namespace_id", Operator: "eq", Value: namespaceID})
+			provider.Triggers = append(provider.Triggers, TriggerConfig{
+				Event:      trigger.Event,
+				Conditions: conditions,
+				Priority:   trigger.Priority,
+				Async:      trigger.Async,
+			})
+		}
+	}
+	if err := provider.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return provider, skipped, nil
+}
+
+// resolveVariables checks the variables given for a template against its
+// declared ones and fills in defaults
+func (t *WorkflowTemplate) resolveVariables(given map[string]interface{}) (map[string]interface{}, error) {
+	declared := make(map[string]bool, len(t.Variables))
+	resolved := make(map[string]interface{}, len(t.Variables))
+	var problems []string
+	for _, variable := range t.Variables {
+		declared[variable.Name] = true
+		value, ok := given[variable.Name]
+		if !ok || value == nil {
+			if variable.Required {
+				problems = append(problems, variable.Name+" is required")
+			} else if variable.DefaultValue != nil {
+				resolved[variable.Name] = variable.DefaultValue
+			}
+			continue
+		}
+		if !variableHasType(value, variable.Type) {
+			problems = append(problems, fmt.Sprintf("%s must be a %s", variable.Name, variable.Type))
+			continue
+		}
+		if s, ok := value.(string); ok && len(variable.Options) > 0 && !contains(variable.Options, s) {
+			problems = append(problems, fmt.Sprintf("%s must be one of %v", variable.Name, variable.Options))
+			continue
+		}
+		resolved[variable.Name] = value
+	}
+	var unknown []string
+	for name := range given {
+		if !declared[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		problems = append(problems, fmt.Sprintf("%s is not a variable of template %s", name, t.ID))
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidTemplateRequest, strings.Join(problems, "; "))
+	}
+	return resolved, nil
+}
+
+// variableHasType reports whether a JSON value suits a template variable
+// type
+func variableHasType(value interface{}, variableType string) bool {
+	switch variableType {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		switch value.(type) {
+		case float64, float32, int, int64:
+			return true
+		}
+		return false
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "array":
+		switch value.(type) {
+		case []interface{}, []string:
+			return true
+		}
+		return false
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	}
+	return true
+}
+
+// stringVariable returns a resolved string variable, or ""
+func stringVariable(variables map[string]interface{}, name string) string {
+	s, _ := variables[name].(string)
+	return s
+}
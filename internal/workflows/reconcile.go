@@ -0,0 +1,179 @@
+package workflows
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// What ReconcileExecutions does with executions the backend lost
+const (
+	LostExecutionFail    = "fail"    // mark them failed
+	LostExecutionRequeue = "requeue" // start them again from their request
+)
+
+// Reconciliation actions
+const (
+	ReconcileResumed  = "resumed"  // still running, tracked again
+	ReconcileFinished = "finished" // finished while the server was down
+	ReconcileFailed   = "failed"   // lost, and marked failed
+	ReconcileRequeued = "requeued" // lost, and started again
+	ReconcileSkipped  = "skipped"  // left journaled, since its status could not be fetched
+)
+
+// ReconcilePolicy says how ReconcileExecutions settles journaled
+// executions. Lost is LostExecutionFail (the default) or
+// LostExecutionRequeue. Executions still running MaxAge after they started
+// are cancelled and handled as lost; with no MaxAge they are waited on.
+type ReconcilePolicy struct {
+	Lost   string
+	MaxAge time.Duration
+}
+
+// ReconcileResult is what became of one journaled execution
+type ReconcileResult struct {
+	ExecutionID    string `json:"execution_id"`
+	WorkflowID     string `json:"workflow_id"`
+	Status         string `json:"status,omitempty"` // as the backend reported it
+	Action         string `json:"action"`
+	NewExecutionID string `json:"new_execution_id,omitempty"` // for requeued executions
+	Error          string `json:"error,omitempty"`
+}
+
+// ReconcileExecutions settles the executions the journal says were in
+// flight, oldest first, typically on startup before new work arrives.
+// Each is looked up on the backend: those still running are tracked again
+// and those that finished are recorded and their outcome published, while
+// those the backend no longer knows are failed or requeued as the policy
+// says.
+func (o *Orchestrator) ReconcileExecutions(ctx context.Context, policy ReconcilePolicy) ([]ReconcileResult, error) {
+	switch policy.Lost {
+	case "":
+		policy.Lost = LostExecutionFail
+	case LostExecutionFail, LostExecutionRequeue:
+	default:
+		return nil, fmt.Errorf("invalid lost execution policy %q: use %s or %s", policy.Lost, LostExecutionFail, LostExecutionRequeue)
+	}
+	o.mu.RLock()
+	journal := o.journal
+	o.mu.RUnlock()
+	if journal == nil {
+		return nil, nil
+	}
+
+	entries, err := journal.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list journaled executions: %w", err)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Record.StartedAt.Before(entries[j].Record.StartedAt)
+	})
+	results := make([]ReconcileResult, 0, len(entries))
+	for _, entry := range entries {
+		results = append(results, o.reconcile(ctx, entry, policy))
+	}
+	return results, nil
+}
+
+// reconcile settles one journaled execution
+func (o *Orchestrator) reconcile(ctx context.Context, entry *JournalEntry, policy ReconcilePolicy) ReconcileResult {
+	record := entry.Record
+	result := ReconcileResult{ExecutionID: record.ExecutionID, WorkflowID: record.WorkflowID}
+
+	resp, err := o.client.GetExecutionStatus(ctx, record.ExecutionID)
+	switch {
+	case errors.Is(err, ErrExecutionNotFound):
+		return o.settleLost(ctx, entry, policy, result, "the workflow backend no longer knows the execution")
+	case err != nil:
+		result.Action = ReconcileSkipped
+		result.Error = err.Error()
+		return result
+	}
+	result.Status = resp.Status
+
+	if !IsTerminalStatus(resp.Status) && policy.MaxAge > 0 && time.Since(record.StartedAt) > policy.MaxAge {
+		if err := o.client.CancelExecution(ctx, record.ExecutionID); err != nil {
+			result.Action = ReconcileSkipped
+			result.Error = fmt.Sprintf("failed to cancel execution: %v", err)
+			return result
+		}
+		return o.settleLost(ctx, entry, policy, result, fmt.Sprintf("the execution ran for more than %s", policy.MaxAge))
+	}
+
+	// Track it again; a finished one is published and leaves the journal
+	o.executions.add(&record)
+	o.ObserveExecutionStatus(ctx, record.ExecutionID, resp)
+	if IsTerminalStatus(resp.Status) {
+		result.Action = ReconcileFinished
+	} else {
+		result.Action = ReconcileResumed
+	}
+	return result
+}
+
+// settleLost fails or requeues an execution that will not finish. A
+// requeue that cannot start fails the execution instead.
+func (o *Orchestrator) settleLost(ctx context.Context, entry *JournalEntry, policy ReconcilePolicy, result ReconcileResult, reason string) ReconcileResult {
+	if policy.Lost == LostExecutionRequeue {
+		executionID, err := o.requeue(ctx, entry)
+		if executionID != "" {
+			result.Action = ReconcileRequeued
+			result.NewExecutionID = executionID
+			if err != nil {
+				result.Error = err.Error()
+			}
+			return result
+		}
+		reason = fmt.Sprintf("%s, and it could not be requeued: %v", reason, err)
+	}
+
+	record := entry.Record
+	record.Status = "failed"
+	record.UpdatedAt = time.Now()
+	o.executions.add(&record)
+	o.unjournalExecution(ctx, record.ExecutionID)
+	o.publishExecutionEvent(ctx, EventExecutionFailed, entry.Request.Context, record.WorkflowID, record.ExecutionID, map[string]interface{}{
+		"status": record.Status,
+		"error":  reason,
+	})
+	result.Action = ReconcileFailed
+	result.Error = reason
+	return result
+}
+
+// requeue starts a lost execution again from its request, with the
+// workflow's current definition, and returns the new execution's ID. An
+// error with an ID means the execution started but its output could not
+// be applied.
+func (o *Orchestrator) requeue(ctx context.Context, entry *JournalEntry) (string, error) {
+	req := entry.Request
+	o.mu.RLock()
+	workflow, ok := o.workflows[req.WorkflowID]
+	o.mu.RUnlock()
+	if !ok {
+		// Providers registered at runtime are gone after a restart
+		var err error
+		if workflow, err = o.client.GetWorkflow(ctx, req.WorkflowID); err != nil {
+			return "", fmt.Errorf("%w: %s: %v", ErrWorkflowNotFound, req.WorkflowID, err)
+		}
+	}
+
+	resp, err := o.client.ExecuteWorkflow(ctx, req)
+	if err != nil {
+		return "", fmt.Errorf("failed to execute workflow %s: %w", req.WorkflowID, err)
+	}
+	o.unjournalExecution(ctx, entry.Record.ExecutionID)
+
+	now := time.Now()
+	record := entry.Record
+	record.ExecutionID = resp.ExecutionID
+	record.Status = resp.Status
+	record.StartedAt = now
+	record.UpdatedAt = now
+	record.DeltasApplied = 0
+	record.Feedback = nil
+	pinDefinition(&record, workflow)
+	return resp.ExecutionID, o.trackExecution(ctx, req, resp, &record, entry.Record.ExecutionID)
+}
@@ -0,0 +1,238 @@
+package workflows
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Registry errors
+var (
+	ErrWorkflowNotFound = errors.New("workflow not found")
+	ErrWorkflowExists   = errors.New("workflow already exists")
+	ErrInvalidWorkflow  = errors.New("invalid workflow")
+	ErrWorkflowCycle    = errors.New("workflow contains cycles")
+)
+
+// WorkflowDeleter is implemented by execution backends that can remove a
+// workflow definition
+type WorkflowDeleter interface {
+	DeleteWorkflow(ctx context.Context, workflowID string) error
+}
+
+// workflowTypes are the types a workflow may have
+var workflowTypes = map[WorkflowType]bool{
+	WorkflowTypeProcessBlob:      true,
+	WorkflowTypeApplyDelta:       true,
+	WorkflowTypeProviderPipeline: true,
+	WorkflowTypeNamespaceSync:    true,
+}
+
+// onFailureActions are the values a step's on_failure may take
+var onFailureActions = map[string]bool{"": true, "fail": true, "skip": true, "continue": true, "retry": true}
+
+// Output policies say what happens to a step output over its size limit
+const (
+	OutputPolicyTruncate = "truncate"
+	OutputPolicySpill    = "spill"
+	OutputPolicyFail     = "fail"
+)
+
+// IsOutputPolicy reports whether a step's output_policy may take a value
+func IsOutputPolicy(policy string) bool {
+	return policy == OutputPolicyTruncate || policy == OutputPolicySpill || policy == OutputPolicyFail
+}
+
+// WorkflowRegistry manages workflow definitions in front of an execution
+// backend. It keeps the definitions it has seen in process, so lookups do
+// not go to the backend, and it knows which workflows exist even though
+// backends report a missing workflow like any other failure.
+type WorkflowRegistry struct {
+	service   WorkflowService
+	mu        sync.RWMutex
+	workflows map[string]*BlobProcessingWorkflow
+	deleted   map[string]bool // removed here but still held by a backend that cannot delete
+	loaded    bool
+	onChange  func(workflow *BlobProcessingWorkflow)
+}
+
+// NewWorkflowRegistry creates a registry over a workflow service
+func NewWorkflowRegistry(service WorkflowService) *WorkflowRegistry {
+	return &WorkflowRegistry{
+		service:   service,
+		workflows: make(map[string]*BlobProcessingWorkflow),
+		deleted:   make(map[string]bool),
+	}
+}
+
+// List returns the workflows of a provider, or all of them when providerID
+// is empty, ordered by ID
+func (r *WorkflowRegistry) List(ctx context.Context, providerID string) ([]*BlobProcessingWorkflow, error) {
+	if err := r.load(ctx); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := []*BlobProcessingWorkflow{}
+	for _, workflow := range r.workflows {
+		if providerID == "" || workflow.ProviderID == providerID {
+			result = append(result, workflow)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result, nil
+}
+
+// Get returns a workflow, asking the backend for workflows registered
+// elsewhere since the registry loaded
+func (r *WorkflowRegistry) Get(ctx context.Context, workflowID string) (*BlobProcessingWorkflow, error) {
+	if err := r.load(ctx); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	workflow, ok := r.workflows[workflowID]
+	deleted := r.deleted[workflowID]
+	r.mu.RUnlock()
+	if ok {
+		return workflow, nil
+	}
+	if deleted {
+		return nil, fmt.Errorf("%w: %s", ErrWorkflowNotFound, workflowID)
+	}
+
+	// Backends do not tell a missing workflow from an unreachable one
+	workflow, err := r.service.GetWorkflow(ctx, workflowID)
+	if err != nil || workflow == nil {
+		return nil, fmt.Errorf("%w: %s", ErrWorkflowNotFound, workflowID)
+	}
+	r.mu.Lock()
+	r.workflows[workflowID] = workflow
+	r.mu.Unlock()
+	return workflow, nil
+}
+
+// Create validates, lints and registers a new workflow, a blob processing
+// one unless it has another type. Lint errors are returned as *LintError.
+func (r *WorkflowRegistry) Create(ctx context.Context, workflow *BlobProcessingWorkflow) error {
+	if workflow.Type == "" {
+		workflow.Type = WorkflowTypeProcessBlob
+	}
+	if err := workflow.Validate(); err != nil {
+		return err
+	}
+	if err := CheckLint(workflow); err != nil {
+		return err
+	}
+	if _, err := r.Get(ctx, workflow.ID); err == nil {
+		return fmt.Errorf("%w: %s", ErrWorkflowExists, workflow.ID)
+	} else if !errors.Is(err, ErrWorkflowNotFound) {
+		return err
+	}
+
+	now := time.Now()
+	workflow.CreatedAt, workflow.UpdatedAt = now, now
+	workflow.Version = 1
+	if err := r.service.RegisterWorkflow(ctx, workflow); err != nil {
+		return fmt.Errorf("failed to register workflow: %w", err)
+	}
+	r.mu.Lock()
+	r.workflows[workflow.ID] = workflow
+	delete(r.deleted, workflow.ID)
+	onChange := r.onChange
+	r.mu.Unlock()
+	if onChange != nil {
+		onChange(workflow)
+	}
+	return nil
+}
+
+// Update validates, lints and replaces an existing workflow, keeping its
+// creation time and bumping its version
+func (r *WorkflowRegistry) Update(ctx context.Context, workflow *BlobProcessingWorkflow) error {
+	if workflow.Type == "" {
+		workflow.Type = WorkflowTypeProcessBlob
+	}
+	if err := workflow.Validate(); err != nil {
+		return err
+	}
+	if err := CheckLint(workflow); err != nil {
+		return err
+	}
+	existing, err := r.Get(ctx, workflow.ID)
+	if err != nil {
+		return err
+	}
+
+	workflow.CreatedAt, workflow.UpdatedAt = existing.CreatedAt, time.Now()
+	workflow.Version = existing.Version + 1
+	if err := r.service.UpdateWorkflow(ctx, workflow); err != nil {
+		return fmt.Errorf("failed to update workflow: %w", err)
+	}
+	r.mu.Lock()
+	r.workflows[workflow.ID] = workflow
+	onChange := r.onChange
+	r.mu.Unlock()
+	if onChange != nil {
+		onChange(workflow)
+	}
+	return nil
+}
+
+// OnChange sets a function called with each workflow the registry creates
+// or updates, such as Orchestrator.RefreshWorkflow
+func (r *WorkflowRegistry) OnChange(fn func(workflow *BlobProcessingWorkflow)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onChange = fn
+}
+
+// Delete removes a workflow. Backends that cannot delete keep running it
+// for executions already started, but it is no longer listed or started
+// through the registry.
+func (r *WorkflowRegistry) Delete(ctx context.Context, workflowID string) error {
+	if _, err := r.Get(ctx, workflowID); err != nil {
+		return err
+	}
+	deletable := false
+	if deleter, ok := r.service.(WorkflowDeleter); ok {
+		if err := deleter.DeleteWorkflow(ctx, workflowID); err != nil {
+			return fmt.Errorf("failed to delete workflow: %w", err)
+		}
+		deletable = true
+	}
+	r.mu.Lock()
+	delete(r.workflows, workflowID)
+	if !deletable {
+		r.deleted[workflowID] = true
+	}
+	r.mu.Unlock()
+	return nil
+}
+
+// load reads the backend's workflows the first time the registry is used
+func (r *WorkflowRegistry) load(ctx context.Context) error {
+	r.mu.RLock()
+	loaded := r.loaded
+	r.mu.RUnlock()
+	if loaded {
+		return nil
+	}
+
+	workflows, err := r.service.ListWorkflows(ctx, "")
+	if err != nil {
+		return fmt.Errorf("failed to list workflows: %w", err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, workflow := range workflows {
+		if _, ok := r.workflows[workflow.ID]; !ok && !r.deleted[workflow.ID] {
+			r.workflows[workflow.ID] = workflow
+		}
+	}
+	r.loaded = true
+	return nil
+}
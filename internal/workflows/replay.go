@@ -0,0 +1,180 @@
+package workflows
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Replay materializes a blob's state from its delta log by applying, in
+// sequence order, the deltas up to and including a sequence number. The
+// state starts empty, and the zero DeltaProcessor can replay deltas even
+// though it cannot store them.
+func (p *DeltaProcessor) Replay(deltas []Delta, sequence int64) map[string]interface{} {
+	ordered := append([]Delta(nil), deltas...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Sequence < ordered[j].Sequence
+	})
+
+	state := make(map[string]interface{})
+	docs := make(map[string]*TextDocument)
+	for _, delta := range ordered {
+		if delta.Sequence > sequence {
+			break
+		}
+		state = p.apply(state, delta, docs)
+	}
+	return state
+}
+
+// Apply applies one delta to a materialized state and returns the result.
+// Paths are JSON pointers (/metadata/status, /sections/0) or dotted
+// (metadata.status); numeric segments index into existing arrays. A delete
+// removes the value at its path and any other delta sets it, creating
+// objects along the way. A delta at the root merges an object value into
+// the state, or clears the state when it is a delete. A text_crdt delta
+// sets its path to the text there with its edit merged in; applied alone,
+// that text is taken as seen by the edit, so concurrent edits only merge
+// when replayed together. A json_patch or merge_patch delta patches the
+// value at its path, or the state at the root; a patch that does not apply
+// is skipped, leaving the state as it was.
+func (p *DeltaProcessor) Apply(state map[string]interface{}, delta Delta) map[string]interface{} {
+	return p.apply(state, delta, nil)
+}
+
+// apply applies one delta, keeping the text documents of text_crdt paths
+// in docs if it is not nil
+func (p *DeltaProcessor) apply(state map[string]interface{}, delta Delta, docs map[string]*TextDocument) map[string]interface{} {
+	if delta.Type == DeltaTextCRDT {
+		text, ok := mergeText(state, delta, docs)
+		if !ok {
+			return state
+		}
+		delta.Type, delta.NewValue = "update", text
+	}
+	if delta.Type == DeltaJSONPatch || delta.Type == DeltaMergePatch {
+		value, err := patchValue(state, delta)
+		if err != nil {
+			return state
+		}
+		if len(pathSegments(delta.Path)) == 0 {
+			if patched, ok := value.(map[string]interface{}); ok {
+				return patched
+			}
+			return state
+		}
+		delta.Type, delta.NewValue = "update", value
+	}
+	segments := pathSegments(delta.Path)
+	if len(segments) == 0 {
+		if delta.Type == "delete" {
+			return make(map[string]interface{})
+		}
+		if value, ok := delta.NewValue.(map[string]interface{}); ok {
+			for key, v := range value {
+				state[key] = cloneValue(v)
+			}
+		}
+		return state
+	}
+
+	var node interface{} = state
+	for _, segment := range segments[:len(segments)-1] {
+		next := child(node, segment)
+		if next == nil {
+			if delta.Type == "delete" {
+				return state
+			}
+			next = make(map[string]interface{})
+			if !setChild(node, segment, next) {
+				return state
+			}
+		}
+		node = next
+	}
+
+	last := segments[len(segments)-1]
+	if delta.Type == "delete" {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			delete(n, last)
+		case []interface{}:
+			if i, err := strconv.Atoi(last); err == nil && i >= 0 && i < len(n) {
+				n[i] = nil
+			}
+		}
+		return state
+	}
+	setChild(node, last, cloneValue(delta.NewValue))
+	return state
+}
+
+// pathSegments splits a delta path into its segments
+func pathSegments(path string) []string {
+	var segments []string
+	if strings.HasPrefix(path, "/") {
+		segments = strings.Split(strings.TrimPrefix(path, "/"), "/")
+	} else {
+		segments = strings.Split(path, ".")
+	}
+	kept := segments[:0]
+	for _, segment := range segments {
+		if segment != "" {
+			kept = append(kept, segment)
+		}
+	}
+	return kept
+}
+
+// child returns the object or array under a segment, or nil
+func child(node interface{}, segment string) interface{} {
+	var value interface{}
+	switch n := node.(type) {
+	case map[string]interface{}:
+		value = n[segment]
+	case []interface{}:
+		if i, err := strconv.Atoi(segment); err == nil && i >= 0 && i < len(n) {
+			value = n[i]
+		}
+	}
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		return value
+	}
+	return nil
+}
+
+// setChild sets the value under a segment, reporting whether it could
+func setChild(node interface{}, segment string, value interface{}) bool {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		n[segment] = value
+		return true
+	case []interface{}:
+		if i, err := strconv.Atoi(segment); err == nil && i >= 0 && i < len(n) {
+			n[i] = value
+			return true
+		}
+	}
+	return false
+}
+
+// cloneValue deep-copies objects and arrays, so replaying never changes the
+// deltas it replays
+func cloneValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			out[key] = cloneValue(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = cloneValue(item)
+		}
+		return out
+	}
+	return value
+}
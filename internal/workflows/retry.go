@@ -0,0 +1,118 @@
+package workflows
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// Error classes for deciding whether a failed step is worth another attempt
+const (
+	ErrorRetryable = "retryable"
+	ErrorPermanent = "permanent"
+)
+
+// classifiedError fixes the class of an error
+type classifiedError struct {
+	err   error
+	class string
+}
+
+func (e *classifiedError) Error() string { return e.err.Error() }
+func (e *classifiedError) Unwrap() error { return e.err }
+
+// Permanent marks an error that retrying cannot fix, such as invalid input
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &classifiedError{err: err, class: ErrorPermanent}
+}
+
+// Retryable marks an error worth retrying whatever it looks like
+func Retryable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &classifiedError{err: err, class: ErrorRetryable}
+}
+
+// statusPattern finds the HTTP status in the errors integrations return,
+// such as "unexpected status code: 503" or "search failed (status 429)"
+var statusPattern = regexp.MustCompile(`\bstatus(?: code)?:? (\d{3})\b`)
+
+// ClassifyError decides whether a step error is worth retrying. Errors
+// marked with Permanent or Retryable keep their mark. Otherwise timeouts,
+// network failures, HTTP 408, 425, 429 and 5xx responses are retryable,
+// while other 4xx responses, cancellation and validation errors (those
+// reading "invalid ..." or "... is required") are permanent. Malformed JSON
+// and anything else is retryable, as every error was before errors were
+// classified.
+func ClassifyError(err error) string {
+	var classified *classifiedError
+	if errors.As(err, &classified) {
+		return classified.class
+	}
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorRetryable
+	case errors.Is(err, context.Canceled):
+		return ErrorPermanent
+	case errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.EPIPE):
+		return ErrorRetryable
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return ErrorRetryable
+	}
+
+	if status := ErrorStatus(err); status != 0 {
+		switch {
+		case status == 408, status == 425, status == 429, status >= 500:
+			return ErrorRetryable
+		case status >= 400:
+			return ErrorPermanent
+		}
+	}
+
+	// A malformed response may come back well formed
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+		return ErrorRetryable
+	}
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		message := e.Error()
+		if strings.HasPrefix(message, "invalid ") || strings.HasSuffix(message, " is required") {
+			return ErrorPermanent
+		}
+	}
+	return ErrorRetryable
+}
+
+// IsRetryable reports whether a step error is worth retrying
+func IsRetryable(err error) bool {
+	return err != nil && ClassifyError(err) == ErrorRetryable
+}
+
+// ErrorStatus returns the HTTP status an error from a service call
+// carries, from a StatusCode method or its message, or 0
+func ErrorStatus(err error) int {
+	var coded interface{ StatusCode() int }
+	if errors.As(err, &coded) {
+		return coded.StatusCode()
+	}
+	if match := statusPattern.FindStringSubmatch(err.Error()); match != nil {
+		status, _ := strconv.Atoi(match[1])
+		return status
+	}
+	return 0
+}
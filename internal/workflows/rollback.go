@@ -0,0 +1,432 @@
+package workflows
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Rollback errors
+var (
+	ErrAlreadyRolledBack = errors.New("execution already rolled back")
+	ErrRollbackNotFound  = errors.New("rollback not found")
+	ErrRollbackTooDeep   = errors.New("rollback exceeds the maximum depth")
+)
+
+// RollbackImmediate is the rollback strategy that undoes an execution as
+// soon as it is seen to fail. Other strategies leave failed executions to
+// be rolled back on request.
+const RollbackImmediate = "immediate"
+
+// RollbackResult records a rollback: the deltas of the execution it undid
+// and the inverse deltas that undid them, applied as a chain of their own,
+// and the compensation workflows run for the execution's steps
+type RollbackResult struct {
+	RollbackID    string         `json:"rollback_id"`
+	ExecutionID   string         `json:"execution_id"`
+	BlobID        string         `json:"blob_id"`
+	Reason        string         `json:"reason,omitempty"`
+	Reverted      []string       `json:"reverted"`
+	Deltas        []Delta        `json:"deltas"`
+	Compensations []Compensation `json:"compensations,omitempty"`
+	RolledBackAt  time.Time      `json:"rolled_back_at"`
+}
+
+// Compensation is a workflow run to undo a completed step of an execution
+// being rolled back. Error is set when it could not be started.
+type Compensation struct {
+	StepID      string `json:"step_id"`
+	WorkflowID  string `json:"workflow_id"`
+	ExecutionID string `json:"execution_id,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// InverseDeltas returns deltas that undo some of the deltas in a blob's
+// history: each path the undone deltas changed is set back to its value
+// in the state replayed without them, or deleted where that state has
+// none. Changes later deltas made elsewhere are kept. Paths into arrays
+// restore the whole array, and a delta at the root restores each top-level
+// field. Paths already at their value get no delta.
+func InverseDeltas(history []Delta, undo []Delta) []Delta {
+	return inverseDeltas(history, undo, nil)
+}
+
+// inverseDeltas is InverseDeltas, also leaving the excluded deltas out of
+// the state the undone paths are set back to
+func inverseDeltas(history, undo, excluded []Delta) []Delta {
+	undone := make(map[string]bool, len(undo)+len(excluded))
+	for _, delta := range undo {
+		undone[delta.ID] = true
+	}
+	for _, delta := range excluded {
+		undone[delta.ID] = true
+	}
+	kept := make([]Delta, 0, len(history))
+	for _, delta := range history {
+		if !undone[delta.ID] {
+			kept = append(kept, delta)
+		}
+	}
+	var p DeltaProcessor
+	current := p.Replay(history, math.MaxInt64)
+	target := p.Replay(kept, math.MaxInt64)
+
+	// Inverses keep the spelling of the path they restore, for stores that
+	// key state by path
+	paths := make(map[string][]string)
+	spelled := make(map[string]string)
+	root := false
+	for _, delta := range undo {
+		segments := pathSegments(delta.Path)
+		if len(segments) == 0 {
+			root = true
+			break
+		}
+		whole := len(segments)
+		for len(segments) > 1 && (isArray(lookup(current, segments[:len(segments)-1])) || isArray(lookup(target, segments[:len(segments)-1]))) {
+			segments = segments[:len(segments)-1]
+		}
+		key := strings.Join(segments, "/")
+		paths[key] = segments
+		if _, ok := spelled[key]; !ok && len(segments) == whole {
+			spelled[key] = delta.Path
+		}
+	}
+	if root {
+		paths = make(map[string][]string)
+		for _, state := range []map[string]interface{}{current, target} {
+			for key := range state {
+				paths[key] = []string{key}
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(paths))
+	for key := range paths {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(paths[keys[i]]) != len(paths[keys[j]]) {
+			return len(paths[keys[i]]) < len(paths[keys[j]])
+		}
+		return keys[i] < keys[j]
+	})
+	var inverses []Delta
+	for _, key := range keys {
+		segments := paths[key]
+		if coveredBy(segments, paths) {
+			continue
+		}
+		was, now := lookup(target, segments), lookup(current, segments)
+		if jsonEqual(was, now) {
+			continue
+		}
+		path, ok := spelled[key]
+		if !ok || root {
+			path = "/" + key
+		}
+		inverse := Delta{Type: "update", Path: path, OldValue: cloneValue(now), NewValue: cloneValue(was)}
+		if was == nil {
+			inverse.Type, inverse.NewValue = "delete", nil
+		}
+		inverses = append(inverses, inverse)
+	}
+	return inverses
+}
+
+// coveredBy reports whether a path lies under another of the paths, which
+// restores it along with the rest of its value
+func coveredBy(segments []string, paths map[string][]string) bool {
+	for i := 1; i < len(segments); i++ {
+		if _, ok := paths[strings.Join(segments[:i], "/")]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isArray reports whether a value is a JSON array
+func isArray(value interface{}) bool {
+	_, ok := value.([]interface{})
+	return ok
+}
+
+// SetRollbackErrorHandler sets the function told when rolling back a
+// failed execution fails. Those rollbacks run as the failure is seen and
+// never fail the processing that saw it.
+func (o *Orchestrator) SetRollbackErrorHandler(onError func(error)) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.onRollbackError = onError
+}
+
+// RollbackExecution undoes a tracked execution whatever its workflow's
+// rollback policy: the deltas it applied are inverted, and the workflows
+// compensating its completed steps are started. Each execution is rolled
+// back once.
+func (o *Orchestrator) RollbackExecution(ctx context.Context, executionID, reason string) (*RollbackResult, error) {
+	return o.rollback(ctx, executionID, nil, reason)
+}
+
+// ExecutionRollback returns the rollback that undid a tracked execution
+func (o *Orchestrator) ExecutionRollback(executionID string) (*RollbackResult, error) {
+	record, ok := o.executions.get(executionID)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrExecutionNotFound, executionID)
+	}
+	if record.rollback == nil {
+		return nil, fmt.Errorf("%w: execution %s has not been rolled back", ErrRollbackNotFound, executionID)
+	}
+	return record.rollback, nil
+}
+
+// rollbackFailed rolls back an execution that failed, if its workflow
+// rolls back immediately
+func (o *Orchestrator) rollbackFailed(ctx context.Context, executionID string, resp *ExecutionResponse, reason string) {
+	definition, ok := o.ExecutionDefinition(executionID)
+	if !ok || !definition.Config.EnableRollback {
+		return
+	}
+	if policy := definition.Rollback; policy != nil && policy.Strategy != "" && policy.Strategy != RollbackImmediate {
+		return
+	}
+	if _, err := o.rollback(ctx, executionID, resp, reason); err != nil && !errors.Is(err, ErrAlreadyRolledBack) {
+		o.mu.RLock()
+		onError := o.onRollbackError
+		o.mu.RUnlock()
+		if onError != nil {
+			onError(fmt.Errorf("failed to roll back execution %s: %w", executionID, err))
+		}
+	}
+}
+
+// rollback undoes an execution. resp, if known, is its final response,
+// giving the steps that completed; otherwise it is fetched when there are
+// compensations to run.
+func (o *Orchestrator) rollback(ctx context.Context, executionID string, resp *ExecutionResponse, reason string) (*RollbackResult, error) {
+	rollbackID := uuid.New().String()
+	record, err := o.executions.claimRollback(executionID, rollbackID)
+	if err != nil {
+		return nil, err
+	}
+	result, err := o.applyRollback(ctx, record, rollbackID, resp, reason)
+	if err != nil {
+		// Inverses are computed from the log each time, so a rollback that
+		// failed part way can be tried again
+		o.executions.releaseRollback(executionID, rollbackID)
+		return nil, err
+	}
+	o.executions.finishRollback(executionID, result)
+	return result, nil
+}
+
+// applyRollback inverts an execution's deltas and starts its compensations
+func (o *Orchestrator) applyRollback(ctx context.Context, record ExecutionRecord, rollbackID string, resp *ExecutionResponse, reason string) (*RollbackResult, error) {
+	var policy RollbackPolicy
+	if record.definition != nil && record.definition.Rollback != nil {
+		policy = *record.definition.Rollback
+	}
+	result := &RollbackResult{
+		RollbackID:  rollbackID,
+		ExecutionID: record.ExecutionID,
+		BlobID:      record.BlobID,
+		Reason:      reason,
+		Reverted:    []string{},
+		Deltas:      []Delta{},
+	}
+
+	if storage := o.deltaProcessor.storage; storage != nil {
+		history, err := storage.GetByBlobID(ctx, record.BlobID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get deltas: %w", err)
+		}
+		// Paths are set back to a state without the executions already
+		// rolled back either, so undoing a later one cannot restore them
+		var undo, excluded []Delta
+		rolledBack := make(map[string]bool)
+		for _, delta := range history {
+			executionID, _ := delta.Metadata["execution_id"].(string)
+			if executionID == "" {
+				executionID, _ = delta.Metadata["rollback_of"].(string)
+			}
+			switch {
+			case executionID == "":
+			case executionID == record.ExecutionID:
+				undo = append(undo, delta)
+				result.Reverted = append(result.Reverted, delta.ID)
+			case o.rolledBack(executionID, rolledBack):
+				excluded = append(excluded, delta)
+			}
+		}
+		if policy.MaxRollbackDepth > 0 && len(undo) > policy.MaxRollbackDepth {
+			return nil, fmt.Errorf("%w: execution %s applied %d deltas, more than %d", ErrRollbackTooDeep, record.ExecutionID, len(undo), policy.MaxRollbackDepth)
+		}
+		if len(undo) > 0 {
+			// Each inverse links to the one before it, the first to the
+			// newest delta it undoes
+			previous := undo[len(undo)-1].ID
+			now := time.Now()
+			for _, inverse := range inverseDeltas(history, undo, excluded) {
+				inverse.ID = uuid.New().String()
+				inverse.BlobID = record.BlobID
+				inverse.ProviderID = record.ProviderID
+				inverse.Timestamp = now
+				inverse.Metadata = map[string]interface{}{
+					"source":            "rollback",
+					"rollback_id":       rollbackID,
+					"rollback_of":       record.ExecutionID,
+					"previous_delta_id": previous,
+				}
+				if reason != "" {
+					inverse.Metadata["reason"] = reason
+				}
+				previous = inverse.ID
+				result.Deltas = append(result.Deltas, inverse)
+			}
+		}
+		if len(result.Deltas) > 0 {
+			batch := NewDeltaBatch(record.BlobID, result.Deltas)
+			result.Deltas = batch.Deltas
+			event := Event{
+				Type:        EventDeltaBatchApplied,
+				BlobID:      record.BlobID,
+				UserID:      record.UserID,
+				ProviderID:  record.ProviderID,
+				CausationID: record.ExecutionID,
+				TraceParent: record.TraceParent,
+				TraceState:  record.TraceState,
+				Data:        batch.eventData(),
+			}
+			if err := o.commitDeltas(ctx, batch, []Event{event}); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(policy.CompensationMap) > 0 && record.definition != nil {
+		if resp == nil {
+			status, err := o.client.GetExecutionStatus(ctx, record.ExecutionID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get execution status: %w", err)
+			}
+			resp = status
+		}
+		result.Compensations = o.compensate(ctx, record, policy.CompensationMap, resp)
+	}
+	result.RolledBackAt = time.Now().UTC()
+	return result, nil
+}
+
+// rolledBack reports whether another execution has been rolled back,
+// caching the answer in seen
+func (o *Orchestrator) rolledBack(executionID string, seen map[string]bool) bool {
+	if done, ok := seen[executionID]; ok {
+		return done
+	}
+	record, ok := o.executions.get(executionID)
+	done := ok && record.rollback != nil
+	seen[executionID] = done
+	return done
+}
+
+// compensate starts the compensation workflow of each step the execution
+// completed, the last step first. A compensation receives the step's
+// output along with the blob and the provider's parameters.
+func (o *Orchestrator) compensate(ctx context.Context, record ExecutionRecord, compensations map[string]string, resp *ExecutionResponse) []Compensation {
+	steps, _ := resp.Output["steps"].(map[string]interface{})
+	o.mu.RLock()
+	provider := o.providers[record.ProviderID]
+	o.mu.RUnlock()
+
+	var started []Compensation
+	definition := record.definition.Steps
+	for i := len(definition) - 1; i >= 0; i-- {
+		step := definition[i]
+		workflowID, ok := compensations[step.ID]
+		if !ok {
+			continue
+		}
+		result, _ := steps[step.ID].(map[string]interface{})
+		if result["status"] != "completed" {
+			continue
+		}
+		execCtx := ExecutionContext{
+			UserID:      record.UserID,
+			ProviderID:  record.ProviderID,
+			BlobID:      record.BlobID,
+			TraceParent: record.TraceParent,
+			TraceState:  record.TraceState,
+		}
+		input := map[string]interface{}{
+			"blob_id":     record.BlobID,
+			"user_id":     record.UserID,
+			"provider_id": record.ProviderID,
+			"compensation": map[string]interface{}{
+				"execution_id": record.ExecutionID,
+				"workflow_id":  record.WorkflowID,
+				"step_id":      step.ID,
+				"output":       result["output"],
+			},
+		}
+		if provider != nil {
+			input["parameters"] = provider.Config.Parameters
+		}
+		compensation := Compensation{StepID: step.ID, WorkflowID: workflowID}
+		run, err := o.client.ExecuteWorkflow(ctx, ExecutionRequest{
+			WorkflowID: workflowID,
+			Input:      input,
+			Context:    execCtx,
+			Async:      true,
+		})
+		if err != nil {
+			compensation.Error = err.Error()
+		} else {
+			compensation.ExecutionID = run.ExecutionID
+		}
+		started = append(started, compensation)
+	}
+	return started
+}
+
+// claimRollback marks an execution as being rolled back, so it is rolled
+// back once
+func (l *executionLog) claimRollback(executionID, rollbackID string) (ExecutionRecord, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	record, ok := l.records[executionID]
+	if !ok {
+		return ExecutionRecord{}, fmt.Errorf("%w: %s", ErrExecutionNotFound, executionID)
+	}
+	if record.RollbackID != "" {
+		return ExecutionRecord{}, fmt.Errorf("%w: %s by rollback %s", ErrAlreadyRolledBack, executionID, record.RollbackID)
+	}
+	record.RollbackID = rollbackID
+	return *record, nil
+}
+
+// releaseRollback clears a rollback that failed
+func (l *executionLog) releaseRollback(executionID, rollbackID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if record, ok := l.records[executionID]; ok && record.RollbackID == rollbackID {
+		record.RollbackID = ""
+	}
+}
+
+// finishRollback records a finished rollback on its execution
+func (l *executionLog) finishRollback(executionID string, result *RollbackResult) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if record, ok := l.records[executionID]; ok {
+		record.rollback = result
+	}
+}
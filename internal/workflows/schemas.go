@@ -0,0 +1,243 @@
+package workflows
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Schema errors
+var (
+	ErrSchemaNotFound  = errors.New("schema not found")
+	ErrSchemaViolation = errors.New("output does not match schema")
+)
+
+// SchemaViolation is one way a value fails a schema, at a path such as
+// $.deltas[0].type
+type SchemaViolation struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// String formats a violation as "path: message"
+func (v SchemaViolation) String() string {
+	return v.Path + ": " + v.Message
+}
+
+// SchemaViolationError is returned for step or workflow outputs that do
+// not match their output schema. StepID is empty for the workflow output.
+type SchemaViolationError struct {
+	SchemaID   string            `json:"schema_id"`
+	StepID     string            `json:"step_id,omitempty"`
+	Violations []SchemaViolation `json:"violations"`
+}
+
+func (e *SchemaViolationError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		messages[i] = violation.String()
+	}
+	subject := "workflow output"
+	if e.StepID != "" {
+		subject = "step " + e.StepID + " output"
+	}
+	return fmt.Sprintf("%s does not match schema %s: %s", subject, e.SchemaID, strings.Join(messages, "; "))
+}
+
+// Unwrap makes a violation match ErrSchemaViolation
+func (e *SchemaViolationError) Unwrap() error { return ErrSchemaViolation }
+
+var (
+	schemasMu sync.RWMutex
+	schemas   = make(map[string]map[string]interface{})
+)
+
+// RegisterSchema makes a JSON Schema definition available by ID for output
+// validation, replacing any schema registered with the ID before
+func RegisterSchema(id string, definition map[string]interface{}) {
+	schemasMu.Lock()
+	defer schemasMu.Unlock()
+
+	schemas[id] = definition
+}
+
+// UnregisterSchema removes a registered schema; outputs checked against it
+// afterwards fail as for any unknown schema
+func UnregisterSchema(id string) {
+	schemasMu.Lock()
+	defer schemasMu.Unlock()
+
+	delete(schemas, id)
+}
+
+// LookupSchema returns a registered schema definition
+func LookupSchema(id string) (map[string]interface{}, error) {
+	schemasMu.RLock()
+	defer schemasMu.RUnlock()
+
+	definition, ok := schemas[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrSchemaNotFound, id)
+	}
+	return definition, nil
+}
+
+// CheckOutput validates an output against a registered schema and returns
+// a *SchemaViolationError listing the violations, if there are any
+func CheckOutput(schemaID, stepID string, output map[string]interface{}) error {
+	definition, err := LookupSchema(schemaID)
+	if err != nil {
+		return err
+	}
+	// Round-trip through JSON so the output has the shape consumers see
+	data, err := json.Marshal(output)
+	if err != nil {
+		return fmt.Errorf("failed to marshal output: %w", err)
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("failed to decode output: %w", err)
+	}
+	if violations := ValidateSchema(definition, value, "$"); len(violations) > 0 {
+		return &SchemaViolationError{SchemaID: schemaID, StepID: stepID, Violations: violations}
+	}
+	return nil
+}
+
+// CheckOutputSchemas is step middleware failing steps whose output does
+// not match their output_schema_id. Violations and unknown schemas are
+// permanent, since running the step again would return the same kind of
+// output.
+func CheckOutputSchemas(executor StepExecutor) StepExecutor {
+	return StepExecutorFunc(func(ctx context.Context, req StepRequest) (map[string]interface{}, error) {
+		output, err := executor.Execute(ctx, req)
+		if err != nil || req.Step.OutputSchemaID == "" {
+			return output, err
+		}
+		if err := CheckOutput(req.Step.OutputSchemaID, req.Step.ID, output); err != nil {
+			return nil, Permanent(err)
+		}
+		return output, nil
+	})
+}
+
+// uuidPattern matches the uuid string format
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// ValidateSchema checks a decoded JSON value against the subset of JSON
+// Schema the studio's schemas use: type, required, properties,
+// additionalProperties, items, enum and the uuid format. It returns one
+// violation per problem, at the path of the offending value below path.
+func ValidateSchema(schema map[string]interface{}, value interface{}, path string) []SchemaViolation {
+	var violations []SchemaViolation
+	fail := func(format string, args ...interface{}) {
+		violations = append(violations, SchemaViolation{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if expected, ok := schema["type"].(string); ok && !hasSchemaType(value, expected) {
+		fail("expected %s, got %s", expected, schemaTypeName(value))
+		return violations
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok && !inEnum(enum, value) {
+		fail("%v is not one of %v", value, enum)
+	}
+	if format, ok := schema["format"].(string); ok && format == "uuid" {
+		if s, isString := value.(string); isString && !uuidPattern.MatchString(s) {
+			fail("%q is not a uuid", s)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, field := range required {
+				name, _ := field.(string)
+				if _, present := v[name]; !present {
+					fail("missing required field %s", name)
+				}
+			}
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if property, ok := properties[key].(map[string]interface{}); ok {
+				violations = append(violations, ValidateSchema(property, v[key], path+"."+key)...)
+			} else if additional, ok := schema["additionalProperties"].(bool); ok && !additional {
+				fail("unexpected field %s", key)
+			}
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				violations = append(violations, ValidateSchema(items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	}
+	return violations
+}
+
+// hasSchemaType reports whether a decoded JSON value has a JSON Schema type
+func hasSchemaType(value interface{}, expected string) bool {
+	switch expected {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == float64(int64(n))
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	}
+	return true
+}
+
+// schemaTypeName names the JSON type of a decoded value
+func schemaTypeName(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// inEnum reports whether a value is one of an enum's values. Numbers are
+// compared by value, since YAML schemas decode them as ints.
+func inEnum(enum []interface{}, value interface{}) bool {
+	for _, allowed := range enum {
+		if sameValue(allowed, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,391 @@
+package workflows
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExecutionScope is the document that step input mappings and conditions
+// resolve against. Paths take the form $.input.x, $.blob.x, $.provider.x,
+// $.execution.x and $.steps.<step_id>.output.x.
+type ExecutionScope map[string]interface{}
+
+// NewExecutionScope builds the scope for a workflow run from its request
+func NewExecutionScope(executionID string, req ExecutionRequest) ExecutionScope {
+	input := req.Input
+	if input == nil {
+		input = map[string]interface{}{}
+	}
+
+	blob, ok := input["blob"].(map[string]interface{})
+	if !ok {
+		blob = map[string]interface{}{
+			"id":       req.Context.BlobID,
+			"metadata": input["metadata"],
+		}
+	}
+
+	return ExecutionScope{
+		"input": input,
+		"blob":  blob,
+		"provider": map[string]interface{}{
+			"id":     req.Context.ProviderID,
+			"config": input["parameters"],
+		},
+		"execution": map[string]interface{}{
+			"id":          executionID,
+			"workflow_id": req.WorkflowID,
+			"request_id":  req.Context.RequestID,
+			"user_id":     req.Context.UserID,
+			"blob_id":     req.Context.BlobID,
+			"profile":     req.Profile,
+		},
+		"steps": map[string]interface{}{},
+	}
+}
+
+// SetStepOutput records a completed step's output
+func (s ExecutionScope) SetStepOutput(stepID string, output map[string]interface{}) {
+	s.steps()[stepID] = map[string]interface{}{
+		"status": "completed",
+		"output": output,
+	}
+}
+
+// SetStepSkipped records that a step did not run, with the reason
+func (s ExecutionScope) SetStepSkipped(stepID, reason string) {
+	s.steps()[stepID] = map[string]interface{}{
+		"status": "skipped",
+		"reason": reason,
+	}
+}
+
+// SetStepFailed records a step failure that the workflow tolerated
+func (s ExecutionScope) SetStepFailed(stepID string, err error) {
+	s.steps()[stepID] = map[string]interface{}{
+		"status": "failed",
+		"error":  err.Error(),
+	}
+}
+
+// SetPartial records that the execution gave up on some of its steps at
+// its deadline, so its output holds only the rest
+func (s ExecutionScope) SetPartial() {
+	s["partial"] = true
+}
+
+// SetStepDuration records how long a step that ran took, for backends
+// that can tell
+func (s ExecutionScope) SetStepDuration(stepID string, duration time.Duration) {
+	if result, ok := s.steps()[stepID].(map[string]interface{}); ok {
+		result["duration_ms"] = duration.Milliseconds()
+	}
+}
+
+// steps returns the per-step results map
+func (s ExecutionScope) steps() map[string]interface{} {
+	steps, ok := s["steps"].(map[string]interface{})
+	if !ok {
+		steps = map[string]interface{}{}
+		s["steps"] = steps
+	}
+	return steps
+}
+
+// Lookup resolves a $.-prefixed path against the scope
+func (s ExecutionScope) Lookup(path string) (interface{}, bool) {
+	if !strings.HasPrefix(path, "$.") {
+		return nil, false
+	}
+
+	var current interface{} = map[string]interface{}(s)
+	for _, segment := range strings.Split(strings.TrimPrefix(path, "$."), ".") {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			next, ok := node[segment]
+			if !ok {
+				return nil, false
+			}
+			current = next
+		case []interface{}:
+			idx, err := strconv.Atoi(segment)
+			if err != nil || idx < 0 || idx >= len(node) {
+				return nil, false
+			}
+			current = node[idx]
+		default:
+			return nil, false
+		}
+	}
+
+	return current, true
+}
+
+// Resolve maps a step InputMap onto concrete values. String values starting
+// with $. are looked up in the scope, nested maps and lists are resolved
+// recursively, and everything else is passed through as a literal.
+func (s ExecutionScope) Resolve(inputMap map[string]interface{}) map[string]interface{} {
+	resolved := make(map[string]interface{}, len(inputMap))
+	for key, value := range inputMap {
+		resolved[key] = s.resolveValue(value)
+	}
+	return resolved
+}
+
+// Select resolves a step's input mapping or parameters as in Resolve and
+// then picks the case of each switch as in SelectCases, so steps can branch
+// on the blob and on earlier steps
+func (s ExecutionScope) Select(values map[string]interface{}) map[string]interface{} {
+	if values == nil {
+		return nil
+	}
+	return SelectCases(s.Resolve(values))
+}
+
+// SelectCases replaces each switch in resolved values with the case its
+// value selects. A switch is a map with "switch" and "cases" keys; case
+// names are matched exactly, then ignoring case, falling back to "default"
+// (or nil) when none matches:
+//
+//	"model": {"switch": "$.blob.metadata.content_language", "cases": {"ja": "model-ja"}, "default": "model-en"}
+//
+// Backends that resolve paths themselves call this on the result.
+func SelectCases(params map[string]interface{}) map[string]interface{} {
+	selected := make(map[string]interface{}, len(params))
+	for key, value := range params {
+		selected[key] = selectCase(value)
+	}
+	return selected
+}
+
+// selectCase selects a single parameter value
+func selectCase(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		cases, ok := v["cases"].(map[string]interface{})
+		if _, isSwitch := v["switch"]; isSwitch && ok {
+			if v["switch"] == nil {
+				return selectCase(v["default"])
+			}
+			key := fmt.Sprint(v["switch"])
+			if choice, found := cases[key]; found {
+				return selectCase(choice)
+			}
+			for name, choice := range cases {
+				if strings.EqualFold(name, key) {
+					return selectCase(choice)
+				}
+			}
+			return selectCase(v["default"])
+		}
+		return SelectCases(v)
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = selectCase(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// resolveValue resolves a single mapping value
+func (s ExecutionScope) resolveValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		if strings.HasPrefix(v, "$.") {
+			resolved, _ := s.Lookup(v)
+			return resolved
+		}
+		return v
+	case map[string]interface{}:
+		return s.Resolve(v)
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = s.resolveValue(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// Evaluate evaluates a step condition expression. Supported forms are
+// "<operand> <op> <operand>" with ==, !=, >, >=, <, <=, a bare operand tested
+// for truthiness, and clauses joined with && or ||. An empty condition is true.
+func (s ExecutionScope) Evaluate(condition string) (bool, error) {
+	condition = strings.TrimSpace(condition)
+	if condition == "" {
+		return true, nil
+	}
+
+	for _, disjunct := range strings.Split(condition, "||") {
+		matched := true
+		for _, clause := range strings.Split(disjunct, "&&") {
+			ok, err := s.evaluateClause(strings.TrimSpace(clause))
+			if err != nil {
+				return false, err
+			}
+			if !ok {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// evaluateClause evaluates a single comparison or truthiness test
+func (s ExecutionScope) evaluateClause(clause string) (bool, error) {
+	for _, op := range []string{"==", "!=", ">=", "<=", ">", "<"} {
+		idx := strings.Index(clause, op)
+		if idx == -1 {
+			continue
+		}
+
+		left := s.operand(strings.TrimSpace(clause[:idx]))
+		right := s.operand(strings.TrimSpace(clause[idx+len(op):]))
+		return Compare(left, op, right)
+	}
+
+	return truthy(s.operand(clause)), nil
+}
+
+// operand parses a literal or resolves a path
+func (s ExecutionScope) operand(token string) interface{} {
+	if strings.HasPrefix(token, "$.") {
+		value, _ := s.Lookup(token)
+		return value
+	}
+	if unquoted, err := strconv.Unquote(token); err == nil {
+		return unquoted
+	}
+	if strings.HasPrefix(token, "'") && strings.HasSuffix(token, "'") && len(token) >= 2 {
+		return token[1 : len(token)-1]
+	}
+	switch token {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null", "nil":
+		return nil
+	}
+	if f, err := strconv.ParseFloat(token, 64); err == nil {
+		return f
+	}
+	return token
+}
+
+// Output aggregates step results into the workflow output. Deltas emitted by
+// individual steps are concatenated under "deltas" in step order so the
+// orchestrator can extract them.
+func (s ExecutionScope) Output(order []string) map[string]interface{} {
+	steps := s.steps()
+	output := map[string]interface{}{"steps": steps}
+
+	var deltas []interface{}
+	for _, stepID := range order {
+		result, _ := steps[stepID].(map[string]interface{})
+		stepOutput, _ := result["output"].(map[string]interface{})
+		if list, ok := stepOutput["deltas"].([]interface{}); ok {
+			deltas = append(deltas, list...)
+		}
+	}
+	if len(deltas) > 0 {
+		output["deltas"] = deltas
+	}
+	if s["partial"] == true {
+		output["partial"] = true
+	}
+
+	return output
+}
+
+// IsPartialOutput reports whether an execution's output holds only the
+// steps that finished by its deadline
+func IsPartialOutput(output map[string]interface{}) bool {
+	partial, _ := output["partial"].(bool)
+	return partial
+}
+
+// Compare applies a comparison operator to two values. Numbers are compared
+// numerically and everything else by its string form.
+func Compare(left interface{}, op string, right interface{}) (bool, error) {
+	lf, lok := toFloat(left)
+	rf, rok := toFloat(right)
+
+	switch op {
+	case "==", "eq":
+		if lok && rok {
+			return lf == rf, nil
+		}
+		return fmt.Sprint(left) == fmt.Sprint(right), nil
+	case "!=", "ne":
+		if lok && rok {
+			return lf != rf, nil
+		}
+		return fmt.Sprint(left) != fmt.Sprint(right), nil
+	case ">", "gt", ">=", "gte", "<", "lt", "<=", "lte":
+		if !lok || !rok {
+			return false, nil
+		}
+		switch op {
+		case ">", "gt":
+			return lf > rf, nil
+		case ">=", "gte":
+			return lf >= rf, nil
+		case "<", "lt":
+			return lf < rf, nil
+		default:
+			return lf <= rf, nil
+		}
+	}
+
+	return false, fmt.Errorf("unsupported operator %q", op)
+}
+
+// toFloat converts numeric values to float64
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// truthy reports whether a value counts as true in a condition
+func truthy(v interface{}) bool {
+	switch b := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return b
+	case string:
+		return b != "" && b != "false"
+	}
+	if f, ok := toFloat(v); ok {
+		return f != 0
+	}
+	return true
+}
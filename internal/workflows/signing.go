@@ -0,0 +1,292 @@
+package workflows
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrInvalidDeltaKey is returned for delta signing keys that cannot be used
+var ErrInvalidDeltaKey = errors.New("invalid delta signing key")
+
+// Delta signing algorithms. Ed25519 keys that only verify, such as those
+// an auditor holds, are given as ed25519-public.
+const (
+	AlgorithmHMACSHA256    = "hmac-sha256"
+	AlgorithmEd25519       = "ed25519"
+	AlgorithmEd25519Public = "ed25519-public"
+)
+
+// minHMACSecret is the shortest HMAC secret accepted, in bytes
+const minHMACSecret = 16
+
+// DeltaKey is a key delta hashes are signed with or checked against
+type DeltaKey struct {
+	ID        string
+	Algorithm string
+	secret    []byte
+	private   ed25519.PrivateKey
+	public    ed25519.PublicKey
+}
+
+// ParseDeltaKey reads a key given as algorithm:id:key, the key in base64:
+// an HMAC secret of at least 16 bytes, an Ed25519 seed or private key, or
+// an Ed25519 public key
+func ParseDeltaKey(spec string) (*DeltaKey, error) {
+	parts := strings.SplitN(strings.TrimSpace(spec), ":", 3)
+	if len(parts) != 3 || parts[1] == "" {
+		return nil, fmt.Errorf("%w: use algorithm:id:base64-key", ErrInvalidDeltaKey)
+	}
+	material, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: key %s is not base64", ErrInvalidDeltaKey, parts[1])
+	}
+	key := &DeltaKey{ID: parts[1], Algorithm: parts[0]}
+	switch parts[0] {
+	case AlgorithmHMACSHA256:
+		if len(material) < minHMACSecret {
+			return nil, fmt.Errorf("%w: HMAC key %s is shorter than %d bytes", ErrInvalidDeltaKey, key.ID, minHMACSecret)
+		}
+		key.secret = material
+	case AlgorithmEd25519:
+		switch len(material) {
+		case ed25519.SeedSize:
+			key.private = ed25519.NewKeyFromSeed(material)
+		case ed25519.PrivateKeySize:
+			key.private = ed25519.PrivateKey(material)
+		default:
+			return nil, fmt.Errorf("%w: Ed25519 key %s is neither a seed nor a private key", ErrInvalidDeltaKey, key.ID)
+		}
+		key.public = key.private.Public().(ed25519.PublicKey)
+	case AlgorithmEd25519Public:
+		if len(material) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: Ed25519 public key %s is not %d bytes", ErrInvalidDeltaKey, key.ID, ed25519.PublicKeySize)
+		}
+		key.Algorithm = AlgorithmEd25519
+		key.public = ed25519.PublicKey(material)
+	default:
+		return nil, fmt.Errorf("%w: unknown algorithm %q", ErrInvalidDeltaKey, parts[0])
+	}
+	return key, nil
+}
+
+// CanSign reports whether the key can make signatures, not only check them
+func (k *DeltaKey) CanSign() bool {
+	return k.secret != nil || k.private != nil
+}
+
+// sign signs a delta hash
+func (k *DeltaKey) sign(hash string) string {
+	if k.secret != nil {
+		mac := hmac.New(sha256.New, k.secret)
+		mac.Write([]byte(hash))
+		return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	}
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(k.private, []byte(hash)))
+}
+
+// verify checks a signature of a delta hash
+func (k *DeltaKey) verify(hash, signature string) bool {
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	if k.secret != nil {
+		mac := hmac.New(sha256.New, k.secret)
+		mac.Write([]byte(hash))
+		return hmac.Equal(sig, mac.Sum(nil))
+	}
+	return ed25519.Verify(k.public, []byte(hash), sig)
+}
+
+// DeltaKeyring holds the key new deltas are signed with, if any, and every
+// key signatures are checked against, so deltas signed before a key was
+// rotated out still verify
+type DeltaKeyring struct {
+	signing *DeltaKey
+	keys    map[string]*DeltaKey
+}
+
+// NewDeltaKeyring creates a keyring signing with signing, which may be nil
+// to only check signatures, and checking against it and others
+func NewDeltaKeyring(signing *DeltaKey, others ...*DeltaKey) (*DeltaKeyring, error) {
+	if signing != nil && !signing.CanSign() {
+		return nil, fmt.Errorf("%w: key %s can only verify", ErrInvalidDeltaKey, signing.ID)
+	}
+	k := &DeltaKeyring{signing: signing, keys: make(map[string]*DeltaKey)}
+	for _, key := range append([]*DeltaKey{signing}, others...) {
+		if key == nil {
+			continue
+		}
+		if _, ok := k.keys[key.ID]; ok {
+			return nil, fmt.Errorf("%w: key %s is given twice", ErrInvalidDeltaKey, key.ID)
+		}
+		k.keys[key.ID] = key
+	}
+	return k, nil
+}
+
+// ChainDelta links a delta into its blob's log after the delta whose hash
+// is prevHash, "" for the first, setting its hash and, when the keyring
+// has a signing key, its signature. The delta's place and content must be
+// final: storage calls it as it stores the delta under its sequence.
+// Timestamps are hashed to the microsecond, so storage must keep at least
+// that much.
+func ChainDelta(delta *Delta, prevHash string, keys *DeltaKeyring) error {
+	hash, err := DeltaHash(*delta, prevHash)
+	if err != nil {
+		return err
+	}
+	delta.PrevHash, delta.Hash = prevHash, hash
+	delta.Signature, delta.KeyID = "", ""
+	if keys != nil && keys.signing != nil {
+		delta.Signature, delta.KeyID = keys.signing.sign(hash), keys.signing.ID
+	}
+	return nil
+}
+
+// DeltaHash returns the hex SHA-256 of a delta's blob, sequence, content
+// and timestamp and the hash of the delta before it. Values are hashed as
+// they read back from JSON, so a delta hashes the same before it is stored
+// and after.
+func DeltaHash(delta Delta, prevHash string) (string, error) {
+	data, err := json.Marshal(map[string]interface{}{
+		"blob_id":     delta.BlobID,
+		"sequence":    delta.Sequence,
+		"id":          delta.ID,
+		"provider_id": delta.ProviderID,
+		"type":        delta.Type,
+		"path":        delta.Path,
+		"old_value":   delta.OldValue,
+		"new_value":   delta.NewValue,
+		"metadata":    delta.Metadata,
+		"timestamp":   delta.Timestamp.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano),
+		"prev_hash":   prevHash,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to hash delta %s: %w", delta.ID, err)
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return "", fmt.Errorf("failed to hash delta %s: %w", delta.ID, err)
+	}
+	if data, err = json.Marshal(normalized); err != nil {
+		return "", fmt.Errorf("failed to hash delta %s: %w", delta.ID, err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// ChainProblem is a delta that breaks its blob's chain
+type ChainProblem struct {
+	Sequence int64  `json:"sequence"`
+	DeltaID  string `json:"delta_id"`
+	Problem  string `json:"problem"`
+}
+
+// ChainReport is the outcome of checking a blob's delta log. Unchained
+// counts the deltas stored before the log was chained, which prove
+// nothing; Unsigned and Unverified count chained deltas without a
+// signature and those whose signature could not be checked for want of
+// keys. Checked with keys, unchained and unsigned deltas are problems too.
+// Head is the hash of the last delta, to compare against a copy kept
+// elsewhere: a chain proves nothing was altered or removed before its head,
+// not that its head is the latest delta.
+type ChainReport struct {
+	BlobID     string         `json:"blob_id"`
+	Valid      bool           `json:"valid"`
+	Deltas     int            `json:"deltas"`
+	Chained    int            `json:"chained"`
+	Unchained  int            `json:"unchained"`
+	Signed     int            `json:"signed"`
+	Unsigned   int            `json:"unsigned"`
+	Unverified int            `json:"unverified"`
+	Head       string         `json:"head,omitempty"`
+	HeadAt     int64          `json:"head_sequence,omitempty"`
+	Problems   []ChainProblem `json:"problems,omitempty"`
+}
+
+// VerifyDeltaChain checks a blob's delta log, in sequence order: that no
+// sequence is missing, each delta links to the one before, its content
+// still matches its hash and its signature, checked with keys when given,
+// is valid. With keys every delta must be chained and signed, as anyone
+// able to write the log could otherwise rewrite it, rehash it and drop the
+// signatures; without them a delta must still be signed if one before it
+// is.
+func VerifyDeltaChain(blobID string, deltas []Delta, keys *DeltaKeyring) *ChainReport {
+	report := &ChainReport{BlobID: blobID, Deltas: len(deltas)}
+	problem := func(delta Delta, format string, args ...interface{}) {
+		report.Problems = append(report.Problems, ChainProblem{
+			Sequence: delta.Sequence,
+			DeltaID:  delta.ID,
+			Problem:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	strict := keys != nil && len(keys.keys) > 0
+	var last int64
+	prev := ""
+	signed := false
+	for _, delta := range deltas {
+		if delta.Sequence != last+1 {
+			problem(delta, "expected sequence %d", last+1)
+		}
+		last = delta.Sequence
+
+		if delta.Hash == "" {
+			switch {
+			case report.Chained > 0:
+				problem(delta, "not chained, though deltas before it are")
+			case strict:
+				report.Unchained++
+				problem(delta, "not chained, though the log is checked with keys")
+			default:
+				report.Unchained++
+			}
+			continue
+		}
+		report.Chained++
+		if delta.PrevHash != prev {
+			problem(delta, "does not link to the delta before it")
+		}
+		if hash, err := DeltaHash(delta, delta.PrevHash); err != nil || hash != delta.Hash {
+			problem(delta, "content does not match its hash")
+		}
+		prev = delta.Hash
+		report.Head, report.HeadAt = delta.Hash, delta.Sequence
+
+		switch {
+		case delta.Signature == "":
+			report.Unsigned++
+			if signed {
+				problem(delta, "not signed, though deltas before it are")
+			} else if strict {
+				problem(delta, "not signed, though the log is checked with keys")
+			}
+		case keys == nil:
+			report.Unverified++
+		default:
+			key, ok := keys.keys[delta.KeyID]
+			switch {
+			case !ok:
+				problem(delta, "signed with unknown key %q", delta.KeyID)
+			case !key.verify(delta.Hash, delta.Signature):
+				problem(delta, "signature does not match")
+			default:
+				report.Signed++
+			}
+		}
+		if delta.Signature != "" {
+			signed = true
+		}
+	}
+	report.Valid = len(report.Problems) == 0
+	return report
+}
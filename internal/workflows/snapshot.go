@@ -0,0 +1,274 @@
+package workflows
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// SnapshotVersion is the version of the StateSnapshot format
+const SnapshotVersion = 1
+
+// ErrInvalidSnapshot is returned for snapshots that cannot be restored
+var ErrInvalidSnapshot = errors.New("invalid snapshot")
+
+// StateSnapshot is the orchestrator's configuration at a point in time:
+// the workflows, the providers and the triggers they map events to,
+// namespace defaults and experiments. Every list is ordered by ID, so
+// snapshots of the same state are identical but for TakenAt. Triggers and
+// Queues describe the state and are not restored.
+type StateSnapshot struct {
+	Version     int                       `json:"version"`
+	TakenAt     time.Time                 `json:"taken_at"`
+	Workflows   []*BlobProcessingWorkflow `json:"workflows"`
+	Providers   []*Provider               `json:"providers"`
+	Triggers    map[string][]string       `json:"triggers"` // event to the providers it triggers
+	Namespaces  []*NamespaceDefaults      `json:"namespaces"`
+	Experiments []*Experiment             `json:"experiments"`
+	Queues      *BusStats                 `json:"queues,omitempty"`
+}
+
+// RestoreResult counts what restoring a snapshot changed
+type RestoreResult struct {
+	WorkflowsCreated   int `json:"workflows_created"`
+	WorkflowsUpdated   int `json:"workflows_updated"`
+	WorkflowsUnchanged int `json:"workflows_unchanged"`
+	Providers          int `json:"providers"`
+	Namespaces         int `json:"namespaces"`
+	Experiments        int `json:"experiments"`
+}
+
+// Snapshot captures the orchestrator's state. Workflows are listed from
+// registry, which knows those no provider runs; without it they are the
+// workflows the orchestrator has loaded.
+func (o *Orchestrator) Snapshot(ctx context.Context, registry *WorkflowRegistry) (*StateSnapshot, error) {
+	snapshot := &StateSnapshot{
+		Version:     SnapshotVersion,
+		TakenAt:     time.Now().UTC(),
+		Providers:   o.ListProviders(),
+		Triggers:    make(map[string][]string),
+		Experiments: o.ListExperiments(),
+	}
+	if registry != nil {
+		workflows, err := registry.List(ctx, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list workflows: %w", err)
+		}
+		snapshot.Workflows = workflows
+	}
+
+	o.mu.RLock()
+	if registry == nil {
+		for _, workflow := range o.workflows {
+			snapshot.Workflows = append(snapshot.Workflows, workflow)
+		}
+		sort.Slice(snapshot.Workflows, func(i, j int) bool { return snapshot.Workflows[i].ID < snapshot.Workflows[j].ID })
+	}
+	for _, defaults := range o.namespaces {
+		snapshot.Namespaces = append(snapshot.Namespaces, defaults)
+	}
+	o.mu.RUnlock()
+	sort.Slice(snapshot.Namespaces, func(i, j int) bool { return snapshot.Namespaces[i].NamespaceID < snapshot.Namespaces[j].NamespaceID })
+
+	for _, provider := range snapshot.Providers {
+		for _, trigger := range provider.Triggers {
+			providers := snapshot.Triggers[trigger.Event]
+			if !contains(providers, provider.ID) {
+				snapshot.Triggers[trigger.Event] = append(providers, provider.ID)
+			}
+		}
+	}
+	if snapshot.Workflows == nil {
+		snapshot.Workflows = []*BlobProcessingWorkflow{}
+	}
+	if snapshot.Namespaces == nil {
+		snapshot.Namespaces = []*NamespaceDefaults{}
+	}
+	return snapshot, nil
+}
+
+// Restore applies a snapshot over the orchestrator's state, for recovering
+// an environment: workflows are created through registry or updated where
+// they differ, then namespace defaults, experiments and providers are set.
+// State the snapshot does not name is kept. Everything is validated before
+// anything is applied, so an invalid snapshot changes nothing; a backend
+// failure part way through leaves what was applied before it, and the
+// snapshot can be restored again.
+func (o *Orchestrator) Restore(ctx context.Context, registry *WorkflowRegistry, snapshot *StateSnapshot) (*RestoreResult, error) {
+	if err := snapshot.Validate(); err != nil {
+		return nil, err
+	}
+	if err := o.checkReferences(ctx, registry, snapshot); err != nil {
+		return nil, err
+	}
+	result := &RestoreResult{}
+
+	for _, workflow := range snapshot.Workflows {
+		existing, err := registry.Get(ctx, workflow.ID)
+		switch {
+		case errors.Is(err, ErrWorkflowNotFound):
+			if err := registry.Create(ctx, workflow); err != nil {
+				return result, fmt.Errorf("failed to restore workflow %s: %w", workflow.ID, err)
+			}
+			result.WorkflowsCreated++
+		case err != nil:
+			return result, err
+		case sameDefinition(existing, workflow):
+			result.WorkflowsUnchanged++
+		default:
+			if err := registry.Update(ctx, workflow); err != nil {
+				return result, fmt.Errorf("failed to restore workflow %s: %w", workflow.ID, err)
+			}
+			result.WorkflowsUpdated++
+		}
+	}
+	for _, defaults := range snapshot.Namespaces {
+		if err := o.SetNamespaceDefaults(defaults); err != nil {
+			return result, fmt.Errorf("failed to restore namespace %s: %w", defaults.NamespaceID, err)
+		}
+		result.Namespaces++
+	}
+	// Experiments come before the providers that name them in place of
+	// workflows
+	for _, experiment := range snapshot.Experiments {
+		if err := o.SetExperiment(ctx, experiment); err != nil {
+			return result, fmt.Errorf("failed to restore experiment %s: %w", experiment.ID, err)
+		}
+		result.Experiments++
+	}
+	for _, provider := range snapshot.Providers {
+		if err := o.RegisterProvider(ctx, provider); err != nil {
+			return result, fmt.Errorf("failed to restore provider %s: %w", provider.ID, err)
+		}
+		result.Providers++
+	}
+	return result, nil
+}
+
+// Validate checks a snapshot can be restored: its version is known and
+// every item in it is valid, once
+func (s *StateSnapshot) Validate() error {
+	if s.Version != SnapshotVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidSnapshot, s.Version)
+	}
+	seen := make(map[string]bool)
+	once := func(kind, id string) error {
+		if seen[kind+"/"+id] {
+			return fmt.Errorf("%w: %s %s appears more than once", ErrInvalidSnapshot, kind, id)
+		}
+		seen[kind+"/"+id] = true
+		return nil
+	}
+
+	for _, workflow := range s.Workflows {
+		if workflow == nil {
+			return fmt.Errorf("%w: null workflow", ErrInvalidSnapshot)
+		}
+		if workflow.Type == "" {
+			workflow.Type = WorkflowTypeProcessBlob
+		}
+		if err := workflow.Validate(); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+		}
+		if err := CheckLint(workflow); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+		}
+		if err := once("workflow", workflow.ID); err != nil {
+			return err
+		}
+	}
+	for _, defaults := range s.Namespaces {
+		if defaults == nil {
+			return fmt.Errorf("%w: null namespace", ErrInvalidSnapshot)
+		}
+		if err := defaults.Validate(); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+		}
+		if err := once("namespace", defaults.NamespaceID); err != nil {
+			return err
+		}
+	}
+	for _, experiment := range s.Experiments {
+		if experiment == nil {
+			return fmt.Errorf("%w: null experiment", ErrInvalidSnapshot)
+		}
+		if err := experiment.Validate(); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+		}
+		if err := once("experiment", experiment.ID); err != nil {
+			return err
+		}
+	}
+	for _, provider := range s.Providers {
+		if provider == nil {
+			return fmt.Errorf("%w: null provider", ErrInvalidSnapshot)
+		}
+		if err := provider.Validate(); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+		}
+		if err := once("provider", provider.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sameDefinition reports whether two workflows define the same thing,
+// whatever their versions and timestamps, so restoring a snapshot twice
+// updates nothing the second time
+func sameDefinition(a, b *BlobProcessingWorkflow) bool {
+	x, y := *a, *b
+	x.Version, x.CreatedAt, x.UpdatedAt = 0, time.Time{}, time.Time{}
+	y.Version, y.CreatedAt, y.UpdatedAt = 0, time.Time{}, time.Time{}
+	dx, err := json.Marshal(x)
+	if err != nil {
+		return false
+	}
+	dy, err := json.Marshal(y)
+	return err == nil && bytes.Equal(dx, dy)
+}
+
+// checkReferences checks that the workflows a snapshot's experiments and
+// providers name are in it or registered, since restoring them would
+// otherwise fail once the rest had been applied
+func (o *Orchestrator) checkReferences(ctx context.Context, registry *WorkflowRegistry, snapshot *StateSnapshot) error {
+	known := make(map[string]bool)
+	for _, workflow := range snapshot.Workflows {
+		known[workflow.ID] = true
+	}
+	for _, experiment := range snapshot.Experiments {
+		known[experiment.ID] = true
+	}
+	check := func(workflowID, owner string) error {
+		if known[workflowID] {
+			return nil
+		}
+		if _, err := registry.Get(ctx, workflowID); err != nil {
+			return fmt.Errorf("%w: %s names workflow %s, which is not in the snapshot or registered", ErrInvalidSnapshot, owner, workflowID)
+		}
+		known[workflowID] = true
+		return nil
+	}
+	for _, experiment := range snapshot.Experiments {
+		for _, variant := range experiment.Variants {
+			if err := check(variant.WorkflowID, "experiment "+experiment.ID); err != nil {
+				return err
+			}
+		}
+	}
+	for _, provider := range snapshot.Providers {
+		for _, workflowID := range provider.WorkflowIDs {
+			if _, err := o.Experiment(workflowID); err == nil {
+				continue
+			}
+			if err := check(workflowID, "provider "+provider.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
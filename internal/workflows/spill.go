@@ -0,0 +1,120 @@
+package workflows
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// DefaultSpillLimit is how large a subscriber's spill file may grow before
+// further events are dropped; it starts over once the subscriber catches up
+const DefaultSpillLimit = 64 << 20
+
+// spillQueue is a first-in, first-out queue of events in a file, one JSON
+// line each, for a subscriber whose memory queue is full. The file is
+// created on the first event and emptied whenever every event has been
+// read.
+type spillQueue struct {
+	dir   string
+	limit int64
+
+	mu      sync.Mutex
+	file    *os.File
+	reader  *bufio.Reader
+	written int64 // bytes appended
+	read    int64 // bytes consumed
+	pending int
+	closed  bool
+}
+
+// newSpillQueue creates a queue spilling to a file in dir, the system's
+// temporary directory if dir is empty
+func newSpillQueue(dir string, limit int64) *spillQueue {
+	if limit <= 0 {
+		limit = DefaultSpillLimit
+	}
+	return &spillQueue{dir: dir, limit: limit}
+}
+
+// Push appends an event, failing when the file would pass the limit
+func (q *spillQueue) Push(event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s event: %w", event.Type, err)
+	}
+	data = append(data, '\n')
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return fmt.Errorf("spill queue is closed")
+	}
+	if q.written+int64(len(data)) > q.limit {
+		return fmt.Errorf("spill file is full at %d bytes", q.limit)
+	}
+	if q.file == nil {
+		file, err := os.CreateTemp(q.dir, "events-*.jsonl")
+		if err != nil {
+			return fmt.Errorf("failed to create spill file: %w", err)
+		}
+		q.file = file
+		q.reader = bufio.NewReader(io.NewSectionReader(file, 0, 1<<62))
+	}
+	if _, err := q.file.WriteAt(data, q.written); err != nil {
+		return fmt.Errorf("failed to spill %s event: %w", event.Type, err)
+	}
+	q.written += int64(len(data))
+	q.pending++
+	return nil
+}
+
+// Pop removes and returns the oldest event, if there is one
+func (q *spillQueue) Pop() (Event, bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.pending == 0 {
+		return Event{}, false, nil
+	}
+	line, err := q.reader.ReadBytes('\n')
+	if err != nil {
+		return Event{}, false, fmt.Errorf("failed to read spill file: %w", err)
+	}
+	q.read += int64(len(line))
+	q.pending--
+	if q.pending == 0 {
+		// Start the file over rather than let it grow
+		if err := q.file.Truncate(0); err != nil {
+			return Event{}, false, fmt.Errorf("failed to truncate spill file: %w", err)
+		}
+		q.written, q.read = 0, 0
+		q.reader.Reset(io.NewSectionReader(q.file, 0, 1<<62))
+	}
+	var event Event
+	if err := json.Unmarshal(line, &event); err != nil {
+		return Event{}, true, fmt.Errorf("failed to decode spilled event: %w", err)
+	}
+	return event, true, nil
+}
+
+// Len returns how many events are waiting
+func (q *spillQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.pending
+}
+
+// Close removes the file and anything still in it
+func (q *spillQueue) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.file != nil {
+		q.file.Close()
+		os.Remove(q.file.Name())
+		q.file = nil
+	}
+	q.pending = 0
+	q.closed = true
+}
@@ -0,0 +1,137 @@
+package workflows
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// StepRequest carries everything an in-process executor needs to run a step
+type StepRequest struct {
+	ExecutionID string                 `json:"execution_id"`
+	WorkflowID  string                 `json:"workflow_id"`
+	Step        BlobProcessingStep     `json:"step"`
+	Input       map[string]interface{} `json:"input"`
+	Context     ExecutionContext       `json:"context"`
+	MutexKey    string                 `json:"mutex_key,omitempty"` // resolved key the step must hold while it runs
+}
+
+// Setting reads a step input, falling back to the step's config parameter
+// when the input is missing, null or empty
+func (r StepRequest) Setting(name string) interface{} {
+	if value, ok := r.Input[name]; ok && value != nil && value != "" {
+		return value
+	}
+	return r.Step.Config.Parameters[name]
+}
+
+// StringList reads a setting that lists strings, given as a list or a
+// comma-separated string
+func (r StepRequest) StringList(name string) []string {
+	value := r.Setting(name)
+	if s, ok := value.(string); ok {
+		return StringList(strings.Split(s, ","))
+	}
+	return StringList(value)
+}
+
+// StringList reads a list of strings from decoded JSON, such as step
+// input or metadata, trimmed and skipping items that are blank or not
+// strings
+func StringList(value interface{}) []string {
+	var items []string
+	switch v := value.(type) {
+	case []string:
+		for _, item := range v {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				if s = strings.TrimSpace(s); s != "" {
+					items = append(items, s)
+				}
+			}
+		}
+	}
+	return items
+}
+
+// StepExecutor runs a single workflow step in-process and returns its output
+type StepExecutor interface {
+	Execute(ctx context.Context, req StepRequest) (map[string]interface{}, error)
+}
+
+// StepExecutorFunc adapts a function to the StepExecutor interface
+type StepExecutorFunc func(ctx context.Context, req StepRequest) (map[string]interface{}, error)
+
+// Execute calls f(ctx, req)
+func (f StepExecutorFunc) Execute(ctx context.Context, req StepRequest) (map[string]interface{}, error) {
+	return f(ctx, req)
+}
+
+// StepRegistry maps provider IDs and step types to in-process executors
+type StepRegistry struct {
+	executors map[string]StepExecutor
+	mu        sync.RWMutex
+}
+
+// NewStepRegistry creates an empty step registry
+func NewStepRegistry() *StepRegistry {
+	return &StepRegistry{
+		executors: make(map[string]StepExecutor),
+	}
+}
+
+// Register binds an executor to a provider ID or step type
+func (r *StepRegistry) Register(key string, executor StepExecutor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.executors[key] = executor
+}
+
+// Lookup finds the executor for a step, preferring a provider-specific
+// executor over a generic one registered for the step type
+func (r *StepRegistry) Lookup(step BlobProcessingStep) (StepExecutor, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if executor, ok := r.executors[step.ProviderID]; ok && step.ProviderID != "" {
+		return executor, nil
+	}
+	if executor, ok := r.executors[step.Type]; ok && step.Type != "" {
+		return executor, nil
+	}
+
+	return nil, fmt.Errorf("no executor registered for step %s (provider %q, type %q)", step.ID, step.ProviderID, step.Type)
+}
+
+// Keys returns the registered provider IDs and step types in sorted order
+func (r *StepRegistry) Keys() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := make([]string, 0, len(r.executors))
+	for key := range r.executors {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Wrap replaces every registered executor with wrap(executor), for
+// middleware that should see all steps. Executors registered later are not
+// wrapped.
+func (r *StepRegistry) Wrap(wrap func(StepExecutor) StepExecutor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key, executor := range r.executors {
+		r.executors[key] = wrap(executor)
+	}
+}
@@ -13,6 +13,7 @@ type WorkflowTemplate struct {
 	Description string                 `json:"description"`
 	Variables   []TemplateVariable     `json:"variables"`
 	Workflow    *BlobProcessingWorkflow `json:"workflow"`
+	Providers   []string               `json:"providers,omitempty"` // bundled provider definitions provisioned with it
 	Tags        []string               `json:"tags"`
 	CreatedAt   time.Time              `json:"created_at"`
 }
@@ -52,17 +53,42 @@ func CreateBookWritingWorkflow(bookID, authorID string) *BlobProcessingWorkflow
 				},
 				OnFailure: "fail",
 			},
+			{
+				ID:         "translate_chapter",
+				Name:       "Translate Chapter",
+				ProviderID: "translator",
+				Type:       "translate",
+				InputMap: map[string]interface{}{
+					"content":         "$.blob.content",
+					"source_language": "$.blob.metadata.content_language",
+					"target_language": "en",
+				},
+				Dependencies: []string{"validate_chapter"},
+				Condition:    `$.blob.metadata.content_language != null && $.blob.metadata.content_language != "en"`,
+				Config: StepConfig{
+					Timeout:      120,
+					MaxRetries:   2,
+					CacheResults: true,
+					CacheTTL:     3600,
+				},
+				OnFailure: "skip",
+			},
 			{
 				ID:         "expand_content",
 				Name:       "Expand Chapter Content",
 				ProviderID: "ai-expander",
 				Type:       "transform",
 				InputMap: map[string]interface{}{
-					"content": "$.blob.content",
-					"style":   "$.provider.config.writing_style",
-					"prompt":  "Expand this chapter section with more descriptive details while maintaining the author's voice",
+					// Expand the English translation when there is one
+					"content": map[string]interface{}{
+						"switch":  "$.steps.translate_chapter.status",
+						"cases":   map[string]interface{}{"completed": "$.steps.translate_chapter.output.content"},
+						"default": "$.blob.content",
+					},
+					"style":  "$.provider.config.writing_style",
+					"prompt": "Expand this chapter section with more descriptive details while maintaining the author's voice",
 				},
-				Dependencies: []string{"validate_chapter"},
+				Dependencies: []string{"translate_chapter"},
 				Config: StepConfig{
 					Timeout:      60,
 					MaxRetries:   3,
@@ -72,6 +98,11 @@ func CreateBookWritingWorkflow(bookID, authorID string) *BlobProcessingWorkflow
 						"model":       "gpt-4",
 						"temperature": 0.7,
 						"max_tokens":  2000,
+						"original_language": map[string]interface{}{
+							"switch":  "$.steps.translate_chapter.status",
+							"cases":   map[string]interface{}{"completed": "$.blob.metadata.content_language"},
+							"default": "en",
+						},
 					},
 				},
 				OnFailure: "skip",
@@ -97,6 +128,47 @@ func CreateBookWritingWorkflow(bookID, authorID string) *BlobProcessingWorkflow
 					},
 				},
 			},
+			{
+				ID:         "analyze_style",
+				Name:       "Analyze Style and Readability",
+				ProviderID: "style-analyzer",
+				Type:       "style_analysis",
+				InputMap: map[string]interface{}{
+					"content": "$.blob.content",
+					"style":   "$.provider.config.writing_style",
+				},
+				Dependencies: []string{"validate_chapter"},
+				Config: StepConfig{
+					Timeout:           15,
+					ParallelExecution: true,
+					Parameters: map[string]interface{}{
+						"max_annotations": 50,
+					},
+				},
+				OnFailure: "skip",
+			},
+			{
+				ID:         "check_similarity",
+				Name:       "Check for Duplicated Passages",
+				ProviderID: "similarity-checker",
+				Type:       "similarity_check",
+				InputMap: map[string]interface{}{
+					"content":    "$.blob.content",
+					"book_id":    bookID,
+					"chapter_id": "$.blob.id",
+					"corpora":    "$.provider.config.similarity_corpora",
+				},
+				Dependencies: []string{"validate_chapter"},
+				Config: StepConfig{
+					Timeout:           60,
+					MaxRetries:        2,
+					ParallelExecution: true,
+					Parameters: map[string]interface{}{
+						"threshold": 0.85,
+					},
+				},
+				OnFailure: "skip",
+			},
 			{
 				ID:         "generate_summary",
 				Name:       "Generate Chapter Summary",
@@ -121,7 +193,9 @@ func CreateBookWritingWorkflow(bookID, authorID string) *BlobProcessingWorkflow
 				Type:       "transform",
 				InputMap: map[string]interface{}{
 					"book_id":         bookID,
+					"chapter_id":      "$.blob.id",
 					"chapter_number":  "$.blob.metadata.chapter_number",
+					"chapter_title":   "$.blob.metadata.chapter_title",
 					"chapter_summary": "$.steps.generate_summary.output",
 					"word_count":      "$.blob.metadata.word_count",
 				},
@@ -130,6 +204,44 @@ func CreateBookWritingWorkflow(bookID, authorID string) *BlobProcessingWorkflow
 					Timeout: 15,
 				},
 			},
+			{
+				ID:         "illustrate_chapter",
+				Name:       "Illustrate Chapter",
+				ProviderID: "imagegen",
+				Type:       "image_generation",
+				InputMap: map[string]interface{}{
+					"kind":           "illustration",
+					"title":          "$.provider.config.title",
+					"chapter_number": "$.blob.metadata.chapter_number",
+					"chapter_title":  "$.blob.metadata.chapter_title",
+					"summary":        "$.steps.generate_summary.output.summary",
+					"style":          "$.provider.config.illustration_style",
+				},
+				Dependencies: []string{"generate_summary"},
+				Condition:    "$.provider.config.illustrate_chapters == true",
+				Config: StepConfig{
+					Timeout:    120,
+					MaxRetries: 2,
+				},
+				OnFailure: "skip",
+			},
+			{
+				ID:         "suggest_in_doc",
+				Name:       "Suggest Expansions in Google Docs",
+				ProviderID: "gdocs",
+				Type:       "gdocs_suggest",
+				InputMap: map[string]interface{}{
+					"document_id": "$.blob.metadata.document_id",
+					"suggestions": "$.steps.expand_content.output.suggestions",
+				},
+				Dependencies: []string{"expand_content"},
+				Condition:    `$.blob.metadata.source == "gdocs"`,
+				Config: StepConfig{
+					Timeout:    30,
+					MaxRetries: 2,
+				},
+				OnFailure: "skip",
+			},
 		},
 		Config: ProcessingConfig{
 			MaxConcurrency:   3,
@@ -150,6 +262,9 @@ func CreateBookWritingWorkflow(bookID, authorID string) *BlobProcessingWorkflow
 
 // CreateResearchWorkflow creates a workflow for research document processing
 func CreateResearchWorkflow(topicID string) *BlobProcessingWorkflow {
+	// Documents that copy one already in their namespace, such as the same
+	// paper imported twice, are not processed again
+	notDuplicate := "$.steps.check_duplicates.output.duplicate != true"
 	workflow := &BlobProcessingWorkflow{
 		ID:          fmt.Sprintf("research_%s_workflow", topicID),
 		ProviderID:  fmt.Sprintf("research:%s", topicID),
@@ -157,6 +272,24 @@ func CreateResearchWorkflow(topicID string) *BlobProcessingWorkflow {
 		Description: "Extracts citations, key points, and finds related papers",
 		Type:        WorkflowTypeProcessBlob,
 		Steps: []BlobProcessingStep{
+			{
+				ID:         "check_duplicates",
+				Name:       "Check for Duplicate Documents",
+				ProviderID: "dedup",
+				Type:       "dedup_check",
+				InputMap: map[string]interface{}{
+					"content":      "$.blob.content",
+					"namespace_id": "$.blob.namespace_id",
+				},
+				Condition: "$.blob.namespace_id",
+				Config: StepConfig{
+					Timeout: 30,
+					Parameters: map[string]interface{}{
+						"threshold": 0.95,
+					},
+				},
+				OnFailure: "skip",
+			},
 			{
 				ID:         "extract_metadata",
 				Name:       "Extract Document Metadata",
@@ -166,6 +299,8 @@ func CreateResearchWorkflow(topicID string) *BlobProcessingWorkflow {
 					"content": "$.blob.content",
 					"type":    "$.blob.metadata.document_type",
 				},
+				Dependencies: []string{"check_duplicates"},
+				Condition:    notDuplicate,
 				Config: StepConfig{
 					Timeout:    30,
 					MaxRetries: 2,
@@ -181,6 +316,7 @@ func CreateResearchWorkflow(topicID string) *BlobProcessingWorkflow {
 					"format":  "$.blob.metadata.citation_format",
 				},
 				Dependencies: []string{"extract_metadata"},
+				Condition:    notDuplicate,
 				Config: StepConfig{
 					Timeout:           60,
 					ParallelExecution: true,
@@ -188,6 +324,40 @@ func CreateResearchWorkflow(topicID string) *BlobProcessingWorkflow {
 					CacheTTL:          86400,
 				},
 			},
+			{
+				ID:         "record_citations",
+				Name:       "Record Citations",
+				ProviderID: "citations",
+				Type:       "citation_record",
+				InputMap: map[string]interface{}{
+					"citations": "$.steps.extract_citations.output",
+				},
+				Dependencies: []string{"extract_citations"},
+				Condition:    notDuplicate,
+				Config: StepConfig{
+					Timeout: 10,
+				},
+				OnFailure: "skip",
+			},
+			{
+				ID:         "sync_citations",
+				Name:       "Sync Citations",
+				ProviderID: "citations",
+				Type:       "citation_sync",
+				InputMap: map[string]interface{}{
+					"citations":  "$.steps.extract_citations.output",
+					"format":     "$.provider.config.citation_format",
+					"target":     "$.provider.config.citation_target",
+					"collection": "$.provider.config.zotero_collection",
+				},
+				Dependencies: []string{"extract_citations"},
+				Condition:    notDuplicate + " && $.provider.config.citation_target",
+				Config: StepConfig{
+					Timeout:    60,
+					MaxRetries: 2,
+				},
+				OnFailure: "skip",
+			},
 			{
 				ID:         "extract_key_points",
 				Name:       "Extract Key Points",
@@ -199,6 +369,7 @@ func CreateResearchWorkflow(topicID string) *BlobProcessingWorkflow {
 					"detail":     "high",
 				},
 				Dependencies: []string{"extract_metadata"},
+				Condition:    notDuplicate,
 				Config: StepConfig{
 					Timeout:           45,
 					ParallelExecution: true,
@@ -220,6 +391,7 @@ func CreateResearchWorkflow(topicID string) *BlobProcessingWorkflow {
 					"limit":      20,
 				},
 				Dependencies: []string{"extract_metadata", "extract_key_points"},
+				Condition:    notDuplicate,
 				Config: StepConfig{
 					Timeout:    90,
 					MaxRetries: 3,
@@ -241,12 +413,41 @@ func CreateResearchWorkflow(topicID string) *BlobProcessingWorkflow {
 					"summary_type": "academic",
 				},
 				Dependencies: []string{"extract_key_points", "extract_citations"},
+				Condition:    notDuplicate,
 				Config: StepConfig{
 					Timeout:      60,
 					CacheResults: true,
 					CacheTTL:     7200,
 				},
 			},
+			{
+				ID:         "export_to_notion",
+				Name:       "Export Findings to Notion",
+				ProviderID: "notion",
+				Type:       "notion_export",
+				InputMap: map[string]interface{}{
+					"database_id": "$.provider.config.notion_database_id",
+					"kind":        "findings",
+					"title":       "$.steps.extract_metadata.output.title",
+					"content": map[string]interface{}{
+						"Summary":       "$.steps.generate_summary.output",
+						"Key Points":    "$.steps.extract_key_points.output",
+						"Related Papers": "$.steps.find_related.output",
+					},
+					"properties": map[string]interface{}{
+						"Topic":    topicID,
+						"Type":     "$.blob.metadata.document_type",
+						"Keywords": "$.steps.extract_key_points.output.keywords",
+					},
+				},
+				Dependencies: []string{"generate_summary", "find_related"},
+				Condition:    notDuplicate + " && $.provider.config.notion_database_id",
+				Config: StepConfig{
+					Timeout:    60,
+					MaxRetries: 2,
+				},
+				OnFailure: "skip",
+			},
 		},
 		Config: ProcessingConfig{
 			MaxConcurrency:   5,
@@ -366,6 +567,26 @@ func CreateCodeDocumentationWorkflow(projectID string) *BlobProcessingWorkflow {
 					},
 				},
 			},
+			{
+				ID:         "publish_docs",
+				Name:       "Publish Documentation to GitHub",
+				ProviderID: "github",
+				Type:       "github_publish",
+				InputMap: map[string]interface{}{
+					"repo":        "$.blob.metadata.repo",
+					"branch":      "$.blob.metadata.branch",
+					"file_path":   "$.blob.metadata.file_path",
+					"pull_number": "$.blob.metadata.pull_number",
+					"docs":        "$.steps.generate_docs.output",
+					"mode":        "$.provider.config.github_publish_mode",
+				},
+				Dependencies: []string{"generate_docs"},
+				Condition:    `$.blob.metadata.source == "github"`,
+				Config: StepConfig{
+					Timeout:    60,
+					MaxRetries: 2,
+				},
+			},
 		},
 		Config: ProcessingConfig{
 			MaxConcurrency:   4,
@@ -468,19 +689,22 @@ func CreateDataProcessingWorkflow(datasetID string) *BlobProcessingWorkflow {
 			{
 				ID:         "generate_report",
 				Name:       "Generate Data Quality Report",
-				ProviderID: "report-generator",
-				Type:       "transform",
+				ProviderID: "data-profiler",
+				Type:       "data_profile",
 				InputMap: map[string]interface{}{
 					"original_data":   "$.blob.content",
+					"format":          "$.blob.metadata.format",
 					"processed_data":  "$.steps.transform_format.output",
+					"output_format":   "$.provider.config.target_format",
 					"validation_results": "$.steps.validate_schema.output",
 				},
 				Dependencies: []string{"transform_format"},
 				Config: StepConfig{
 					Timeout: 45,
 					Parameters: map[string]interface{}{
-						"include_statistics": true,
-						"include_visualizations": true,
+						"histogram_bins": 10,
+						"top_values":     5,
+						"null_threshold": 0.2,
 					},
 				},
 			},
@@ -502,112 +726,888 @@ func CreateDataProcessingWorkflow(datasetID string) *BlobProcessingWorkflow {
 	return workflow
 }
 
-// GetWorkflowTemplates returns all available workflow templates
-func GetWorkflowTemplates() []WorkflowTemplate {
-	return []WorkflowTemplate{
-		{
-			ID:          "book_writing",
-			Name:        "Book Writing Assistant",
-			Category:    "creative",
-			Description: "Complete workflow for book writing including expansion, consistency checking, and outline management",
-			Variables: []TemplateVariable{
-				{
-					Name:        "book_id",
-					Type:        "string",
-					Description: "Unique identifier for the book",
-					Required:    true,
-				},
-				{
-					Name:        "author_id",
-					Type:        "string",
-					Description: "Author's unique identifier",
-					Required:    true,
+// CreateAudioTranscriptionWorkflow creates a workflow that transcribes audio
+// blobs into derived text blobs
+func CreateAudioTranscriptionWorkflow(projectID string) *BlobProcessingWorkflow {
+	workflow := &BlobProcessingWorkflow{
+		ID:          fmt.Sprintf("audio_%s_workflow", projectID),
+		ProviderID:  fmt.Sprintf("audio:%s", projectID),
+		Name:        "Audio Transcription",
+		Description: "Transcribes audio into a timestamped text blob for downstream summarization",
+		Type:        WorkflowTypeProcessBlob,
+		Steps: []BlobProcessingStep{
+			{
+				ID:         "transcribe",
+				Name:       "Transcribe Audio",
+				ProviderID: "whisper",
+				Type:       "transcribe",
+				InputMap: map[string]interface{}{
+					"audio_url":          "$.blob.metadata.audio_url",
+					"filename":           "$.blob.metadata.filename",
+					"language":           "$.provider.config.language",
+					"prompt":             "$.provider.config.vocabulary",
+					"target_provider_id": "$.provider.config.transcript_provider_id",
 				},
-				{
-					Name:         "writing_style",
-					Type:         "string",
-					Description:  "Writing style preference",
-					DefaultValue: "descriptive",
-					Options:      []string{"descriptive", "concise", "poetic", "technical"},
+				Config: StepConfig{
+					Timeout:    600,
+					MaxRetries: 2,
 				},
+				OnFailure: "fail",
 			},
-			Tags:      []string{"writing", "book", "creative", "ai-assisted"},
-			CreatedAt: time.Now(),
 		},
-		{
-			ID:          "research_processor",
-			Name:        "Research Document Processor",
-			Category:    "academic",
-			Description: "Extracts citations, key points, and finds related research papers",
-			Variables: []TemplateVariable{
-				{
-					Name:        "topic_id",
-					Type:        "string",
-					Description: "Research topic identifier",
-					Required:    true,
+		Config: ProcessingConfig{
+			MaxConcurrency:   1,
+			StopOnError:      true,
+			EnableRollback:   false,
+			TrackLineage:     true,
+			EmitEvents:       true,
+			AutoRetry:        true,
+			RetryDelay:       30,
+			MaxExecutionTime: 900,
+		},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	
+	return workflow
+}
+
+// CreateAudiobookPreviewWorkflow creates a workflow that narrates chapters
+// and their summaries
+func CreateAudiobookPreviewWorkflow(bookID string) *BlobProcessingWorkflow {
+	workflow := &BlobProcessingWorkflow{
+		ID:          fmt.Sprintf("audiobook_%s_workflow", bookID),
+		ProviderID:  fmt.Sprintf("book:%s", bookID),
+		Name:        "Audiobook Preview",
+		Description: "Renders chapter text and a spoken summary into audio",
+		Type:        WorkflowTypeProcessBlob,
+		Steps: []BlobProcessingStep{
+			{
+				ID:         "render_chapter",
+				Name:       "Narrate Chapter",
+				ProviderID: "tts",
+				Type:       "tts",
+				InputMap: map[string]interface{}{
+					"text":   "$.blob.content",
+					"engine": "$.provider.config.tts_engine",
+					"voice":  "$.provider.config.voice",
+					"speed":  "$.provider.config.speed",
 				},
-				{
-					Name:         "citation_format",
-					Type:         "string",
-					Description:  "Citation format to use",
-					DefaultValue: "apa",
-					Options:      []string{"apa", "mla", "chicago", "ieee"},
+				Config: StepConfig{
+					Timeout:    600,
+					MaxRetries: 2,
 				},
 			},
-			Tags:      []string{"research", "academic", "citations", "papers"},
-			CreatedAt: time.Now(),
-		},
-		{
-			ID:          "code_documentation",
-			Name:        "Code Documentation Generator",
-			Category:    "development",
-			Description: "Analyzes code and generates comprehensive documentation with tests",
-			Variables: []TemplateVariable{
-				{
-					Name:        "project_id",
-					Type:        "string",
-					Description: "Project identifier",
-					Required:    true,
-				},
-				{
-					Name:         "doc_style",
-					Type:         "string",
-					Description:  "Documentation style",
-					DefaultValue: "detailed",
-					Options:      []string{"minimal", "standard", "detailed"},
+			{
+				ID:         "summarize_chapter",
+				Name:       "Summarize Chapter for Narration",
+				ProviderID: "summarizer",
+				Type:       "transform",
+				InputMap: map[string]interface{}{
+					"content": "$.blob.content",
+					"type":    "spoken_summary",
+					"length":  "short",
 				},
-				{
-					Name:         "test_framework",
-					Type:         "string",
-					Description:  "Test framework to use",
-					DefaultValue: "jest",
-					Options:      []string{"jest", "mocha", "pytest", "junit"},
+				Condition: "$.provider.config.narrate_summary == true",
+				Config: StepConfig{
+					Timeout:      30,
+					CacheResults: true,
+					CacheTTL:     7200,
 				},
 			},
-			Tags:      []string{"code", "documentation", "testing", "api"},
-			CreatedAt: time.Now(),
-		},
-		{
-			ID:          "data_processing",
-			Name:        "Data Processing Pipeline",
-			Category:    "data",
-			Description: "Validates, cleans, enriches, and transforms data",
-			Variables: []TemplateVariable{
-				{
-					Name:        "dataset_id",
-					Type:        "string",
-					Description: "Dataset identifier",
-					Required:    true,
+			{
+				ID:         "render_summary",
+				Name:       "Narrate Summary",
+				ProviderID: "tts",
+				Type:       "tts",
+				InputMap: map[string]interface{}{
+					"text":   "$.steps.summarize_chapter.output.summary",
+					"engine": "$.provider.config.tts_engine",
+					"voice":  "$.provider.config.voice",
+					"speed":  "$.provider.config.speed",
 				},
-				{
-					Name:         "target_format",
-					Type:         "string",
-					Description:  "Target data format",
-					DefaultValue: "json",
-					Options:      []string{"json", "csv", "parquet", "avro"},
+				Dependencies: []string{"summarize_chapter"},
+				Condition:    "$.steps.summarize_chapter.output.summary",
+				Config: StepConfig{
+					Timeout:    120,
+					MaxRetries: 2,
 				},
+				OnFailure: "skip",
 			},
-			Tags:      []string{"data", "etl", "transformation", "validation"},
+		},
+		Config: ProcessingConfig{
+			MaxConcurrency:   2,
+			StopOnError:      false,
+			EnableRollback:   false,
+			TrackLineage:     true,
+			EmitEvents:       true,
+			AutoRetry:        true,
+			RetryDelay:       10,
+			MaxExecutionTime: 900,
+		},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	
+	return workflow
+}
+
+// CreateVoiceNoteChapterWorkflow creates a workflow that transcribes voice
+// memos, clusters them by topic and drafts a chapter section per cluster
+func CreateVoiceNoteChapterWorkflow(projectID string) *BlobProcessingWorkflow {
+	workflow := &BlobProcessingWorkflow{
+		ID:          fmt.Sprintf("voicenotes_%s_workflow", projectID),
+		ProviderID:  fmt.Sprintf("voicenotes:%s", projectID),
+		Name:        "Voice Notes to Chapters",
+		Description: "Transcribes voice memos, groups them by topic, and drafts chapter sections that link each paragraph to its memo",
+		Type:        WorkflowTypeProcessBlob,
+		Steps: []BlobProcessingStep{
+			{
+				ID:         "transcribe",
+				Name:       "Transcribe Memo",
+				ProviderID: "whisper",
+				Type:       "transcribe",
+				InputMap: map[string]interface{}{
+					"audio_url":          "$.blob.metadata.audio_url",
+					"filename":           "$.blob.metadata.filename",
+					"language":           "$.provider.config.language",
+					"prompt":             "$.provider.config.vocabulary",
+					"target_provider_id": fmt.Sprintf("voicenotes:%s", projectID),
+				},
+				// Transcripts and drafted sections belong to the same
+				// provider; only memos carry audio
+				Condition: "$.blob.metadata.audio_url",
+				Config: StepConfig{
+					Timeout:    600,
+					MaxRetries: 2,
+				},
+				OnFailure: "fail",
+			},
+			{
+				ID:         "cluster_memos",
+				Name:       "Cluster Memos by Topic",
+				ProviderID: "voicenotes",
+				Type:       "voice_note_cluster",
+				InputMap: map[string]interface{}{
+					"project_id": projectID,
+					"threshold":  "$.provider.config.cluster_threshold",
+				},
+				Dependencies: []string{"transcribe"},
+				Condition:    "$.steps.transcribe.output.blob_id",
+				Config: StepConfig{
+					Timeout:    120,
+					MaxRetries: 2,
+				},
+			},
+			{
+				ID:         "draft_sections",
+				Name:       "Draft Chapter Sections",
+				ProviderID: "voicenotes",
+				Type:       "voice_note_draft",
+				InputMap: map[string]interface{}{
+					"project_id": projectID,
+					"clusters":   "$.steps.cluster_memos.output.clusters",
+				},
+				Dependencies: []string{"cluster_memos"},
+				Condition:    `$.steps.cluster_memos.status == "completed"`,
+				Config: StepConfig{
+					Timeout:    120,
+					MaxRetries: 2,
+					Parameters: map[string]interface{}{
+						"max_paragraph_words": 120,
+					},
+				},
+			},
+		},
+		Config: ProcessingConfig{
+			MaxConcurrency:   1,
+			StopOnError:      true,
+			EnableRollback:   false,
+			TrackLineage:     true,
+			EmitEvents:       true,
+			AutoRetry:        true,
+			RetryDelay:       30,
+			MaxExecutionTime: 1200,
+		},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	
+	return workflow
+}
+
+// CreateGrantProposalWorkflow creates a workflow that drafts a proposal's
+// abstract and budget narrative and checks it against the funder's
+// requirements
+func CreateGrantProposalWorkflow(proposalID string) *BlobProcessingWorkflow {
+	// Requirement schemas, budgets and reports share the proposal's
+	// provider; only blobs tagged as proposal drafts are processed
+	isDraft := `$.blob.metadata.kind == "proposal"`
+
+	workflow := &BlobProcessingWorkflow{
+		ID:          fmt.Sprintf("proposal_%s_workflow", proposalID),
+		ProviderID:  fmt.Sprintf("proposal:%s", proposalID),
+		Name:        "Grant Proposal",
+		Description: "Generates the abstract and budget narrative for a proposal draft and reports its compliance with the funder's requirements",
+		Type:        WorkflowTypeProcessBlob,
+		Steps: []BlobProcessingStep{
+			{
+				ID:         "generate_abstract",
+				Name:       "Generate Abstract",
+				ProviderID: "summarizer",
+				Type:       "transform",
+				InputMap: map[string]interface{}{
+					"content":   "$.blob.content",
+					"type":      "abstract",
+					"max_words": "$.provider.config.abstract_max_words",
+				},
+				Condition: isDraft,
+				Config: StepConfig{
+					Timeout:    60,
+					MaxRetries: 2,
+				},
+				OnFailure: "skip",
+			},
+			{
+				ID:         "draft_budget_narrative",
+				Name:       "Draft Budget Narrative",
+				ProviderID: "proposals",
+				Type:       "budget_narrative",
+				InputMap: map[string]interface{}{
+					"budget_blob_id": "$.blob.metadata.budget_blob_id",
+				},
+				Condition: isDraft + " && $.blob.metadata.budget_blob_id",
+				Config: StepConfig{
+					Timeout:    30,
+					MaxRetries: 2,
+				},
+				OnFailure: "skip",
+			},
+			{
+				ID:         "check_compliance",
+				Name:       "Check Funder Compliance",
+				ProviderID: "proposals",
+				Type:       "proposal_compliance",
+				InputMap: map[string]interface{}{
+					"content":              "$.blob.content",
+					"requirements_blob_id": "$.provider.config.requirements_blob_id",
+					"abstract":             "$.steps.generate_abstract.output.summary",
+					"budget_narrative":     "$.steps.draft_budget_narrative.output.narrative",
+				},
+				Dependencies: []string{"generate_abstract", "draft_budget_narrative"},
+				Condition:    isDraft,
+				Config: StepConfig{
+					Timeout:    30,
+					MaxRetries: 2,
+				},
+			},
+		},
+		Config: ProcessingConfig{
+			MaxConcurrency:   1,
+			StopOnError:      false,
+			EnableRollback:   false,
+			TrackLineage:     true,
+			EmitEvents:       true,
+			AutoRetry:        true,
+			RetryDelay:       30,
+			MaxExecutionTime: 600,
+		},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	
+	return workflow
+}
+
+// CreateScreenplayWorkflow creates a workflow that formats a screenplay,
+// breaks it down and writes coverage for it
+func CreateScreenplayWorkflow(projectID string) *BlobProcessingWorkflow {
+	// The formatted script and coverage are stored under the same provider
+	isSource := "$.blob.metadata.derived_from == null"
+
+	workflow := &BlobProcessingWorkflow{
+		ID:          fmt.Sprintf("screenplay_%s_workflow", projectID),
+		ProviderID:  fmt.Sprintf("screenplay:%s", projectID),
+		Name:        "Screenplay Coverage",
+		Description: "Normalizes Fountain or Final Draft screenplays, breaks them down by scene and character, and generates coverage",
+		Type:        WorkflowTypeProcessBlob,
+		Steps: []BlobProcessingStep{
+			{
+				ID:         "format_script",
+				Name:       "Format Screenplay",
+				ProviderID: "screenplay",
+				Type:       "screenplay_format",
+				InputMap: map[string]interface{}{
+					"content": "$.blob.content",
+					"format":  "$.blob.metadata.format",
+				},
+				Condition: isSource,
+				Config: StepConfig{
+					Timeout:    30,
+					MaxRetries: 2,
+				},
+				OnFailure: "fail",
+			},
+			{
+				ID:         "breakdown",
+				Name:       "Scene and Character Breakdown",
+				ProviderID: "screenplay",
+				Type:       "screenplay_breakdown",
+				InputMap: map[string]interface{}{
+					"content": "$.steps.format_script.output.content",
+					"format":  "fountain",
+				},
+				Dependencies: []string{"format_script"},
+				Condition:    isSource,
+				Config: StepConfig{
+					Timeout:    30,
+					MaxRetries: 2,
+				},
+			},
+			{
+				ID:         "generate_coverage",
+				Name:       "Generate Coverage",
+				ProviderID: "script-coverage",
+				Type:       "transform",
+				InputMap: map[string]interface{}{
+					"content":   "$.steps.format_script.output.content",
+					"breakdown": "$.steps.breakdown.output.summary",
+					"type":      "screenplay_coverage",
+					"genre":     "$.provider.config.genre",
+				},
+				Dependencies: []string{"breakdown"},
+				Condition:    isSource,
+				Config: StepConfig{
+					Timeout:      180,
+					MaxRetries:   2,
+					CacheResults: true,
+					CacheTTL:     86400,
+				},
+				OnFailure: "skip",
+			},
+			{
+				ID:         "store_coverage",
+				Name:       "Store Coverage Report",
+				ProviderID: "screenplay",
+				Type:       "screenplay_coverage",
+				InputMap: map[string]interface{}{
+					"coverage": "$.steps.generate_coverage.output",
+					"content":  "$.steps.format_script.output.content",
+					"format":   "fountain",
+				},
+				Dependencies: []string{"generate_coverage"},
+				Condition:    `$.steps.generate_coverage.status == "completed"`,
+				Config: StepConfig{
+					Timeout:    30,
+					MaxRetries: 2,
+				},
+			},
+		},
+		Config: ProcessingConfig{
+			MaxConcurrency:   1,
+			StopOnError:      false,
+			EnableRollback:   false,
+			TrackLineage:     true,
+			EmitEvents:       true,
+			AutoRetry:        true,
+			RetryDelay:       30,
+			MaxExecutionTime: 900,
+		},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	
+	return workflow
+}
+
+// CreateAutoTaggingWorkflow creates a workflow that classifies a provider's
+// blobs into topics and keeps their tags current as their content changes
+func CreateAutoTaggingWorkflow(providerID string) *BlobProcessingWorkflow {
+	// Unchanged content is not classified again
+	changed := "$.steps.suggest_topics.output.skipped == false"
+
+	workflow := &BlobProcessingWorkflow{
+		ID:          fmt.Sprintf("autotag_%s_workflow", providerID),
+		ProviderID:  providerID,
+		Name:        "Auto-Tagging",
+		Description: "Classifies blobs into topics through the embedding index and a classifier, and applies them as tags",
+		Type:        WorkflowTypeProcessBlob,
+		Steps: []BlobProcessingStep{
+			{
+				ID:         "suggest_topics",
+				Name:       "Suggest Topics",
+				ProviderID: "auto-tagger",
+				Type:       "topic_candidates",
+				InputMap: map[string]interface{}{
+					"content":          "$.blob.content",
+					"tagged_hash":      "$.blob.metadata.tagged_hash",
+					"taxonomy_blob_id": "$.provider.config.taxonomy_blob_id",
+				},
+				Config: StepConfig{
+					Timeout:    60,
+					MaxRetries: 2,
+				},
+				OnFailure: "fail",
+			},
+			{
+				ID:         "classify_topics",
+				Name:       "Classify Topics",
+				ProviderID: "topic-classifier",
+				Type:       "classify",
+				InputMap: map[string]interface{}{
+					"content": "$.blob.content",
+					"labels":  "$.steps.suggest_topics.output.labels",
+					"topics":  "$.steps.suggest_topics.output.topics",
+					"type":    "topic_classification",
+				},
+				Dependencies: []string{"suggest_topics"},
+				Condition:    changed,
+				Config: StepConfig{
+					Timeout:      60,
+					MaxRetries:   1,
+					CacheResults: true,
+					CacheTTL:     86400,
+					Parameters: map[string]interface{}{
+						"multi_label": true,
+					},
+				},
+				OnFailure: "skip",
+			},
+			{
+				ID:         "apply_tags",
+				Name:       "Apply Tags",
+				ProviderID: "auto-tagger",
+				Type:       "apply_tags",
+				InputMap: map[string]interface{}{
+					"content":        "$.blob.content",
+					"candidates":     "$.steps.suggest_topics.output.candidates",
+					"content_hash":   "$.steps.suggest_topics.output.content_hash",
+					"classification": "$.steps.classify_topics.output",
+					"threshold":      "$.provider.config.tag_threshold",
+					"max_tags":       "$.provider.config.max_tags",
+				},
+				Dependencies: []string{"suggest_topics", "classify_topics"},
+				Condition:    changed,
+				Config: StepConfig{
+					Timeout:    30,
+					MaxRetries: 2,
+				},
+			},
+		},
+		Config: ProcessingConfig{
+			MaxConcurrency:   1,
+			StopOnError:      false,
+			EnableRollback:   false,
+			TrackLineage:     true,
+			EmitEvents:       true,
+			AutoRetry:        true,
+			RetryDelay:       10,
+			MaxExecutionTime: 300,
+		},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	
+	return workflow
+}
+
+// PreviewCardWorkflowID is the workflow the preview service starts to
+// summarize a blob for its card
+const PreviewCardWorkflowID = "preview_card_workflow"
+
+// CreatePreviewCardWorkflow creates the workflow that completes a blob's
+// presentation card with a model summary and extracted entities
+func CreatePreviewCardWorkflow() *BlobProcessingWorkflow {
+	workflow := &BlobProcessingWorkflow{
+		ID:          PreviewCardWorkflowID,
+		ProviderID:  "preview",
+		Name:        "Preview Card",
+		Description: "Summarizes a blob and extracts its key entities for its presentation card",
+		Type:        WorkflowTypeProcessBlob,
+		Steps: []BlobProcessingStep{
+			{
+				ID:         "summarize",
+				Name:       "Summarize for Card",
+				ProviderID: "summarizer",
+				Type:       "transform",
+				InputMap: map[string]interface{}{
+					"content": "$.input.text",
+					"type":    "card_summary",
+					"length":  "one_paragraph",
+				},
+				Config: StepConfig{
+					Timeout:      60,
+					MaxRetries:   1,
+					CacheResults: true,
+					CacheTTL:     86400,
+				},
+				OnFailure: "skip",
+			},
+			{
+				ID:         "extract_entities",
+				Name:       "Extract Key Entities",
+				ProviderID: "entity-extractor",
+				Type:       "extract",
+				InputMap: map[string]interface{}{
+					"content": "$.input.text",
+				},
+				Config: StepConfig{
+					Timeout:      60,
+					MaxRetries:   1,
+					CacheResults: true,
+					CacheTTL:     86400,
+					Parameters: map[string]interface{}{
+						"types":        []string{"person", "place", "organization", "work", "event"},
+						"max_entities": 8,
+					},
+				},
+				OnFailure: "skip",
+			},
+			{
+				ID:         "store_card",
+				Name:       "Store Card",
+				ProviderID: "preview",
+				Type:       "preview_card",
+				InputMap: map[string]interface{}{
+					"content_hash": "$.input.content_hash",
+					"summary":      "$.steps.summarize.output",
+					"entities":     "$.steps.extract_entities.output",
+				},
+				Dependencies: []string{"summarize", "extract_entities"},
+				Config: StepConfig{
+					Timeout:    30,
+					MaxRetries: 2,
+				},
+			},
+		},
+		Config: ProcessingConfig{
+			MaxConcurrency:   2,
+			StopOnError:      false,
+			EnableRollback:   false,
+			TrackLineage:     false,
+			EmitEvents:       true,
+			AutoRetry:        true,
+			RetryDelay:       10,
+			MaxExecutionTime: 300,
+		},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	
+	return workflow
+}
+
+// GetWorkflowTemplates returns all available workflow templates
+func GetWorkflowTemplates() []WorkflowTemplate {
+	return []WorkflowTemplate{
+		{
+			ID:          "book_writing",
+			Name:        "Book Writing Assistant",
+			Category:    "creative",
+			Description: "Complete workflow for book writing including expansion, consistency checking, and outline management",
+			Variables: []TemplateVariable{
+				{
+					Name:        "book_id",
+					Type:        "string",
+					Description: "Unique identifier for the book",
+					Required:    true,
+				},
+				{
+					Name:        "author_id",
+					Type:        "string",
+					Description: "Author's unique identifier",
+					Required:    true,
+				},
+				{
+					Name:         "writing_style",
+					Type:         "string",
+					Description:  "Writing style preference",
+					DefaultValue: "descriptive",
+					Options:      []string{"descriptive", "concise", "poetic", "technical"},
+				},
+				{
+					Name:         "illustrate_chapters",
+					Type:         "boolean",
+					Description:  "Generate an illustration for each chapter from its summary",
+					DefaultValue: false,
+				},
+				{
+					Name:         "illustration_style",
+					Type:         "string",
+					Description:  "Art style for generated illustrations",
+					DefaultValue: "watercolor",
+				},
+				{
+					Name:        "similarity_corpora",
+					Type:        "array",
+					Description: "Blob namespaces of earlier work to check new chapters against for duplicated passages",
+				},
+			},
+			Providers: []string{"book-writer"},
+			Tags:      []string{"writing", "book", "creative", "ai-assisted"},
+			CreatedAt: time.Now(),
+		},
+		{
+			ID:          "research_processor",
+			Name:        "Research Document Processor",
+			Category:    "academic",
+			Description: "Extracts citations, key points, and finds related research papers",
+			Variables: []TemplateVariable{
+				{
+					Name:        "topic_id",
+					Type:        "string",
+					Description: "Research topic identifier",
+					Required:    true,
+				},
+				{
+					Name:         "citation_format",
+					Type:         "string",
+					Description:  "Citation format to use",
+					DefaultValue: "apa",
+					Options:      []string{"apa", "mla", "chicago", "ieee", "bibtex"},
+				},
+				{
+					Name:        "citation_target",
+					Type:        "string",
+					Description: "Where to sync extracted citations; leave empty to skip syncing",
+					Options:     []string{"zotero", "bibtex"},
+				},
+				{
+					Name:        "zotero_collection",
+					Type:        "string",
+					Description: "Zotero collection key for synced citations",
+				},
+			},
+			Tags:      []string{"research", "academic", "citations", "papers"},
+			CreatedAt: time.Now(),
+		},
+		{
+			ID:          "code_documentation",
+			Name:        "Code Documentation Generator",
+			Category:    "development",
+			Description: "Analyzes code and generates comprehensive documentation with tests",
+			Variables: []TemplateVariable{
+				{
+					Name:        "project_id",
+					Type:        "string",
+					Description: "Project identifier",
+					Required:    true,
+				},
+				{
+					Name:         "doc_style",
+					Type:         "string",
+					Description:  "Documentation style",
+					DefaultValue: "detailed",
+					Options:      []string{"minimal", "standard", "detailed"},
+				},
+				{
+					Name:         "test_framework",
+					Type:         "string",
+					Description:  "Test framework to use",
+					DefaultValue: "jest",
+					Options:      []string{"jest", "mocha", "pytest", "junit"},
+				},
+			},
+			Tags:      []string{"code", "documentation", "testing", "api"},
+			CreatedAt: time.Now(),
+		},
+		{
+			ID:          "data_processing",
+			Name:        "Data Processing Pipeline",
+			Category:    "data",
+			Description: "Validates, cleans, enriches, and transforms data",
+			Variables: []TemplateVariable{
+				{
+					Name:        "dataset_id",
+					Type:        "string",
+					Description: "Dataset identifier",
+					Required:    true,
+				},
+				{
+					Name:         "target_format",
+					Type:         "string",
+					Description:  "Target data format",
+					DefaultValue: "json",
+					Options:      []string{"json", "csv", "parquet", "avro"},
+				},
+			},
+			Tags:      []string{"data", "etl", "transformation", "validation"},
+			CreatedAt: time.Now(),
+		},
+		{
+			ID:          "audio_transcription",
+			Name:        "Audio Transcription",
+			Category:    "audio",
+			Description: "Transcribes voice notes and recordings into timestamped text blobs that feed summarization workflows",
+			Variables: []TemplateVariable{
+				{
+					Name:        "project_id",
+					Type:        "string",
+					Description: "Project identifier",
+					Required:    true,
+				},
+				{
+					Name:        "language",
+					Type:        "string",
+					Description: "Spoken language hint (ISO-639-1); detected when empty",
+				},
+				{
+					Name:        "transcript_provider_id",
+					Type:        "string",
+					Description: "Provider that owns transcript blobs, so its summarization workflows run on them",
+				},
+			},
+			Tags:      []string{"audio", "transcription", "whisper", "voice"},
+			CreatedAt: time.Now(),
+		},
+		{
+			ID:          "audiobook_preview",
+			Name:        "Audiobook Preview",
+			Category:    "creative",
+			Description: "Narrates chapters, and optionally a short summary, with a configurable voice",
+			Variables: []TemplateVariable{
+				{
+					Name:        "book_id",
+					Type:        "string",
+					Description: "Unique identifier for the book",
+					Required:    true,
+				},
+				{
+					Name:         "tts_engine",
+					Type:         "string",
+					Description:  "Text-to-speech engine",
+					DefaultValue: "openai",
+					Options:      []string{"openai", "elevenlabs"},
+				},
+				{
+					Name:         "voice",
+					Type:         "string",
+					Description:  "Voice name (OpenAI) or voice ID (ElevenLabs)",
+					DefaultValue: "fable",
+				},
+				{
+					Name:         "speed",
+					Type:         "number",
+					Description:  "Speaking rate, 1.0 is normal",
+					DefaultValue: 1.0,
+				},
+				{
+					Name:         "narrate_summary",
+					Type:         "boolean",
+					Description:  "Also render a short spoken summary",
+					DefaultValue: false,
+				},
+			},
+			Tags:      []string{"audio", "tts", "audiobook", "book"},
+			CreatedAt: time.Now(),
+		},
+		{
+			ID:          "voice_notes_to_chapters",
+			Name:        "Voice Notes to Chapters",
+			Category:    "creative",
+			Description: "Transcribes voice memos, clusters them by topic, and drafts chapter sections with paragraph-level links back to each memo",
+			Variables: []TemplateVariable{
+				{
+					Name:        "project_id",
+					Type:        "string",
+					Description: "Project identifier",
+					Required:    true,
+				},
+				{
+					Name:        "language",
+					Type:        "string",
+					Description: "Spoken language hint (ISO-639-1); detected when empty",
+				},
+				{
+					Name:         "cluster_threshold",
+					Type:         "number",
+					Description:  "Similarity (0-1) a memo needs to join an existing topic; higher makes more, narrower sections",
+					DefaultValue: 0.2,
+				},
+			},
+			Tags:      []string{"audio", "voice", "transcription", "book", "drafting"},
+			CreatedAt: time.Now(),
+		},
+		{
+			ID:          "grant_proposal",
+			Name:        "Grant Proposal",
+			Category:    "writing",
+			Description: "Checks proposal sections against a funder's page limits and required headings, generates the abstract and budget narrative, and produces a compliance report",
+			Variables: []TemplateVariable{
+				{
+					Name:        "proposal_id",
+					Type:        "string",
+					Description: "Proposal identifier",
+					Required:    true,
+				},
+				{
+					Name:        "requirements_blob_id",
+					Type:        "string",
+					Description: "Blob holding the funder's requirement schema",
+					Required:    true,
+				},
+				{
+					Name:         "abstract_max_words",
+					Type:         "number",
+					Description:  "Word limit for the generated abstract",
+					DefaultValue: 250,
+				},
+			},
+			Tags:      []string{"grant", "proposal", "compliance", "funding"},
+			CreatedAt: time.Now(),
+		},
+		{
+			ID:          "screenplay_coverage",
+			Name:        "Screenplay Coverage",
+			Category:    "creative",
+			Description: "Parses Fountain and Final Draft screenplays, normalizes their formatting, breaks them down by scene, character and location, and generates a coverage report",
+			Variables: []TemplateVariable{
+				{
+					Name:        "project_id",
+					Type:        "string",
+					Description: "Screenplay project identifier",
+					Required:    true,
+				},
+				{
+					Name:        "genre",
+					Type:        "string",
+					Description: "Genre the coverage reader should judge the script against",
+				},
+			},
+			Tags:      []string{"screenplay", "fountain", "final-draft", "coverage", "film"},
+			CreatedAt: time.Now(),
+		},
+		{
+			ID:          "auto_tagging",
+			Name:        "Auto-Tagging",
+			Category:    "organization",
+			Description: "Classifies a provider's blobs into topics using the embedding index and a classifier step, and keeps their tags current as content changes",
+			Variables: []TemplateVariable{
+				{
+					Name:        "provider_id",
+					Type:        "string",
+					Description: "Provider whose blobs are tagged",
+					Required:    true,
+				},
+				{
+					Name:        "taxonomy_blob_id",
+					Type:        "string",
+					Description: "Blob holding the topics to classify into; the default topics are used without one",
+				},
+				{
+					Name:         "tag_threshold",
+					Type:         "number",
+					Description:  "Score from 0 to 1 a topic needs to be applied",
+					DefaultValue: 0.5,
+				},
+				{
+					Name:         "max_tags",
+					Type:         "number",
+					Description:  "Most topics applied to a blob",
+					DefaultValue: 3,
+				},
+			},
+			Tags:      []string{"tagging", "classification", "topics", "embeddings"},
 			CreatedAt: time.Now(),
 		},
 	}
@@ -0,0 +1,124 @@
+package workflows
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// The W3C trace context headers
+const (
+	TraceParentHeader = "traceparent"
+	TraceStateHeader  = "tracestate"
+)
+
+// traceParentPattern matches a traceparent: version, trace ID, parent span
+// ID and flags. Versions after 00 may append fields.
+var traceParentPattern = regexp.MustCompile(`^([0-9a-f]{2})-([0-9a-f]{32})-([0-9a-f]{16})-([0-9a-f]{2})(-.*)?$`)
+
+// TraceContext is a W3C trace context, as carried by the traceparent and
+// tracestate headers
+type TraceContext struct {
+	TraceParent string
+	TraceState  string
+}
+
+// traceKey is the context key of a TraceContext
+type traceKey struct{}
+
+// ValidTraceParent reports whether a traceparent is well formed, with
+// neither its trace nor its span ID all zeros
+func ValidTraceParent(traceParent string) bool {
+	m := traceParentPattern.FindStringSubmatch(traceParent)
+	if m == nil || m[1] == "ff" || (m[1] == "00" && m[5] != "") {
+		return false
+	}
+	return strings.Trim(m[2], "0") != "" && strings.Trim(m[3], "0") != ""
+}
+
+// NewTraceParent starts a sampled trace, for requests that arrive without
+// one
+func NewTraceParent() string {
+	var ids [24]byte
+	if _, err := rand.Read(ids[:]); err != nil {
+		return ""
+	}
+	return "00-" + hex.EncodeToString(ids[:16]) + "-" + hex.EncodeToString(ids[16:]) + "-01"
+}
+
+// ContextWithTrace returns a context carrying a trace context. An invalid
+// traceparent is dropped with its trace state.
+func ContextWithTrace(ctx context.Context, trace TraceContext) context.Context {
+	if !ValidTraceParent(trace.TraceParent) {
+		return ctx
+	}
+	return context.WithValue(ctx, traceKey{}, trace)
+}
+
+// TraceFromContext returns the trace context a context carries
+func TraceFromContext(ctx context.Context) (TraceContext, bool) {
+	trace, ok := ctx.Value(traceKey{}).(TraceContext)
+	return trace, ok
+}
+
+// traceFields returns the traceparent and tracestate ctx carries, if any
+func traceFields(ctx context.Context) (string, string) {
+	trace, _ := TraceFromContext(ctx)
+	return trace.TraceParent, trace.TraceState
+}
+
+// Trace returns the trace context an execution runs in
+func (c ExecutionContext) Trace() TraceContext {
+	return TraceContext{TraceParent: c.TraceParent, TraceState: c.TraceState}
+}
+
+// TraceFromHeaders reads the trace context of a request's headers. The
+// traceparent is empty if the request has none or it is invalid.
+func TraceFromHeaders(h http.Header) TraceContext {
+	traceParent := strings.TrimSpace(h.Get(TraceParentHeader))
+	if !ValidTraceParent(traceParent) {
+		return TraceContext{}
+	}
+	return TraceContext{TraceParent: traceParent, TraceState: strings.TrimSpace(h.Get(TraceStateHeader))}
+}
+
+// SetTraceHeaders passes a trace context on in outgoing request headers
+func SetTraceHeaders(h http.Header, trace TraceContext) {
+	if !ValidTraceParent(trace.TraceParent) {
+		return
+	}
+	h.Set(TraceParentHeader, trace.TraceParent)
+	if trace.TraceState != "" {
+		h.Set(TraceStateHeader, trace.TraceState)
+	}
+}
+
+// InjectTrace gives an event that has no trace context the one ctx
+// carries. Buses call it as events are published, so every event belongs
+// to the trace of what published it.
+func InjectTrace(ctx context.Context, event *Event) {
+	if event.TraceParent != "" {
+		return
+	}
+	if trace, ok := TraceFromContext(ctx); ok {
+		event.TraceParent, event.TraceState = trace.TraceParent, trace.TraceState
+	}
+}
+
+// ContextWithEventTrace returns a context carrying an event's trace
+// context, for handling the event and publishing what follows from it in
+// the same trace
+func ContextWithEventTrace(ctx context.Context, event Event) context.Context {
+	return ContextWithTrace(ctx, TraceContext{TraceParent: event.TraceParent, TraceState: event.TraceState})
+}
+
+// TraceHandler returns a handler passing handler each event with its trace
+// context extracted into ctx. Buses wrap their subscribers with it.
+func TraceHandler(handler EventHandler) EventHandler {
+	return func(ctx context.Context, event Event) error {
+		return handler(ContextWithEventTrace(ctx, event), event)
+	}
+}
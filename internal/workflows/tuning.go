@@ -0,0 +1,140 @@
+package workflows
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DefaultTuneInterval is how often a TimeoutTuner tunes workflows
+const DefaultTuneInterval = 10 * time.Minute
+
+// TimeoutChange is a step timeout a tuner changed
+type TimeoutChange struct {
+	WorkflowID string `json:"workflow_id"`
+	StepID     string `json:"step_id"`
+	From       int    `json:"from_seconds"`
+	To         int    `json:"to_seconds"`
+}
+
+// TuneTimeouts sets the timeout of each step of a workflow to the one its
+// latencies recommend, updating the definition as a new version when any
+// changed. Workflows and steps that suppress the timeout-latency lint rule
+// are left alone.
+func (r *WorkflowRegistry) TuneTimeouts(ctx context.Context, workflowID string, latencies *StepLatencies) ([]TimeoutChange, error) {
+	current, err := r.Get(ctx, workflowID)
+	if err != nil {
+		return nil, err
+	}
+	if hasRule(current.LintSuppress, timeoutLintRule) {
+		return nil, nil
+	}
+
+	tuned := *current
+	tuned.Steps = make([]BlobProcessingStep, len(current.Steps))
+	copy(tuned.Steps, current.Steps)
+	var changes []TimeoutChange
+	for i, step := range tuned.Steps {
+		if hasRule(step.LintSuppress, timeoutLintRule) {
+			continue
+		}
+		timeout, ok := latencies.Recommend(current, step)
+		if !ok {
+			continue
+		}
+		changes = append(changes, TimeoutChange{WorkflowID: workflowID, StepID: step.ID, From: step.Config.Timeout, To: timeout})
+		tuned.Steps[i].Config.Timeout = timeout
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	if err := r.Update(ctx, &tuned); err != nil {
+		return nil, fmt.Errorf("failed to update workflow %s: %w", workflowID, err)
+	}
+	return changes, nil
+}
+
+// TimeoutTuner tunes the step timeouts of every registered workflow from
+// their latencies at an interval
+type TimeoutTuner struct {
+	registry  *WorkflowRegistry
+	latencies *StepLatencies
+	onChange  func(TimeoutChange)
+	onError   func(error)
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewTimeoutTuner starts tuning workflows every interval. onChange, if
+// set, is told of each timeout changed and onError of workflows that could
+// not be tuned. Close stops it.
+func NewTimeoutTuner(registry *WorkflowRegistry, latencies *StepLatencies, interval time.Duration, onChange func(TimeoutChange), onError func(error)) *TimeoutTuner {
+	if interval <= 0 {
+		interval = DefaultTuneInterval
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t := &TimeoutTuner{
+		registry:  registry,
+		latencies: latencies,
+		onChange:  onChange,
+		onError:   onError,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+	go t.run(ctx, interval)
+	return t
+}
+
+// Close stops tuning, waiting for a pass in progress
+func (t *TimeoutTuner) Close() {
+	t.cancel()
+	<-t.done
+}
+
+// run tunes every interval until the tuner is closed
+func (t *TimeoutTuner) run(ctx context.Context, interval time.Duration) {
+	defer close(t.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			t.tune(ctx)
+		}
+	}
+}
+
+// tune tunes each registered workflow once
+func (t *TimeoutTuner) tune(ctx context.Context) {
+	list, err := t.registry.List(ctx, "")
+	if err != nil {
+		t.report(err)
+		return
+	}
+	for _, workflow := range list {
+		if ctx.Err() != nil {
+			return
+		}
+		changes, err := t.registry.TuneTimeouts(ctx, workflow.ID, t.latencies)
+		if err != nil {
+			t.report(err)
+			continue
+		}
+		for _, change := range changes {
+			if t.onChange != nil {
+				t.onChange(change)
+			}
+		}
+	}
+}
+
+// report passes an error to onError, if set
+func (t *TimeoutTuner) report(err error) {
+	if t.onError != nil {
+		t.onError(err)
+	}
+}
@@ -0,0 +1,152 @@
+package workflows
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// maxStepTimeout bounds a step's timeout_seconds. Longer waits belong in a
+// delay step or a scheduled run, not a single activity.
+const maxStepTimeout = 24 * 60 * 60
+
+// ValidationProblem is one reason a workflow definition is invalid. Field
+// is the JSON field at fault, relative to the step when StepID is set.
+type ValidationProblem struct {
+	StepID  string `json:"step_id,omitempty"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// String formats a problem as "step <id>: message", or just the message
+// for the workflow itself
+func (p ValidationProblem) String() string {
+	if p.StepID == "" {
+		return p.Message
+	}
+	return fmt.Sprintf("step %s: %s", p.StepID, p.Message)
+}
+
+// ValidationError is returned for workflow definitions that fail
+// validation, listing every problem found
+type ValidationError struct {
+	WorkflowID string
+	Problems   []ValidationProblem
+
+	// cycle is the DAG error when the steps form a cycle
+	cycle error
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Problems))
+	for i, problem := range e.Problems {
+		messages[i] = problem.String()
+	}
+	return fmt.Sprintf("%s: %s", ErrInvalidWorkflow, strings.Join(messages, "; "))
+}
+
+// Unwrap makes a validation failure an invalid workflow, and a cycle also
+// ErrWorkflowCycle
+func (e *ValidationError) Unwrap() []error {
+	if e.cycle != nil {
+		return []error{ErrInvalidWorkflow, e.cycle}
+	}
+	return []error{ErrInvalidWorkflow}
+}
+
+// Validate checks a workflow definition: it needs an ID, a name, a known
+// type and at least one step; steps need unique IDs and a provider or type,
+// a known on_failure, timeouts and retries that are not negative or absurdly
+// long, may only depend on steps of the same workflow, and must not form a
+// cycle. Mutex keys must be well formed. It returns a *ValidationError
+// listing every problem.
+func (w *BlobProcessingWorkflow) Validate() error {
+	verr := &ValidationError{WorkflowID: w.ID}
+	add := func(stepID, field, format string, args ...interface{}) {
+		verr.Problems = append(verr.Problems, ValidationProblem{StepID: stepID, Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if w.ID == "" {
+		add("", "id", "id is required")
+	}
+	if w.Name == "" {
+		add("", "name", "name is required")
+	}
+	if !workflowTypes[w.Type] {
+		add("", "type", "unknown type %q", w.Type)
+	}
+	if len(w.Steps) == 0 {
+		add("", "steps", "at least one step is required")
+	}
+	if err := ValidateMutexKey(w.MutexKey); err != nil {
+		add("", "mutex_key", "%v", err)
+	}
+	if w.Config.MaxExecutionTime < 0 || w.Config.RetryDelay < 0 || w.Config.MaxConcurrency < 0 {
+		add("", "config", "max_execution_time_seconds, retry_delay_seconds and max_concurrency cannot be negative")
+	}
+
+	ids := make(map[string]bool, len(w.Steps))
+	orderable := true
+	for i, step := range w.Steps {
+		switch {
+		case step.ID == "":
+			add("", fmt.Sprintf("steps[%d].id", i), "step %d: id is required", i)
+			orderable = false
+		case ids[step.ID]:
+			add(step.ID, "id", "duplicate id")
+			orderable = false
+		}
+		ids[step.ID] = true
+		if step.ProviderID == "" && step.Type == "" {
+			add(step.ID, "provider_id", "provider_id or type is required")
+		}
+		if !onFailureActions[step.OnFailure] {
+			add(step.ID, "on_failure", "unknown on_failure %q", step.OnFailure)
+		}
+		if step.Config.Timeout < 0 || step.Config.MaxRetries < 0 {
+			add(step.ID, "config", "timeout and retries cannot be negative")
+		}
+		if step.Config.Timeout > maxStepTimeout {
+			add(step.ID, "config.timeout_seconds", "timeout cannot be over %d seconds", maxStepTimeout)
+		}
+		if step.Config.MaxOutputBytes < 0 {
+			add(step.ID, "config.max_output_bytes", "max_output_bytes cannot be negative")
+		}
+		if step.Config.OutputPolicy != "" && !IsOutputPolicy(step.Config.OutputPolicy) {
+			add(step.ID, "config.output_policy", "unknown output_policy %q", step.Config.OutputPolicy)
+		}
+		if policy := step.RetryPolicy; policy != nil {
+			if policy.MaxAttempts < 0 || policy.InitialDelay < 0 || policy.MaxDelay < 0 || policy.BackoffMultiplier < 0 {
+				add(step.ID, "retry_policy", "retry_policy values cannot be negative")
+			} else if policy.MaxDelay > 0 && policy.MaxDelay < policy.InitialDelay {
+				add(step.ID, "retry_policy", "max_delay_ms cannot be less than initial_delay_ms")
+			}
+		}
+		if err := ValidateMutexKey(step.MutexKey); err != nil {
+			add(step.ID, "mutex_key", "%v", err)
+		}
+	}
+	for _, step := range w.Steps {
+		for _, dep := range step.Dependencies {
+			if !ids[dep] {
+				add(step.ID, "dependencies", "unknown dependency %s", dep)
+				orderable = false
+			}
+		}
+	}
+
+	// The DAG can only be ordered once step IDs are unique and every
+	// dependency names a step
+	if orderable {
+		if _, err := w.GetDAGOrder(); err != nil {
+			if errors.Is(err, ErrWorkflowCycle) {
+				verr.cycle = err
+			}
+			add("", "steps", "%v", err)
+		}
+	}
+	if len(verr.Problems) > 0 {
+		return verr
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package workflows
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// Digest identifies what a workflow runs: a SHA-256 of its type, steps and
+// config. Definitions with the same digest behave alike whatever their
+// names, versions and timestamps.
+func (w *BlobProcessingWorkflow) Digest() string {
+	data, _ := json.Marshal(struct {
+		Type   WorkflowType         `json:"type"`
+		Steps  []BlobProcessingStep `json:"steps"`
+		Config ProcessingConfig     `json:"config"`
+	}{w.Type, w.Steps, w.Config})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// RefreshWorkflow replaces the definition the orchestrator holds for a
+// workflow its providers run, so executions started afterwards record the
+// definition the backend now has. Workflows no provider runs are ignored.
+func (o *Orchestrator) RefreshWorkflow(workflow *BlobProcessingWorkflow) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if _, ok := o.workflows[workflow.ID]; ok {
+		o.workflows[workflow.ID] = workflow
+	}
+}
+
+// pinDefinition records in an execution's record the definition it runs
+func pinDefinition(record *ExecutionRecord, workflow *BlobProcessingWorkflow) {
+	record.WorkflowVersion = workflow.Version
+	record.WorkflowDigest = workflow.Digest()
+	record.definition = workflow
+}
+
+// tagDelta records in a delta's metadata the execution, workflow
+// definition and experiment variant that produced it, which outlive the
+// execution's record
+func tagDelta(delta *Delta, record *ExecutionRecord) {
+	if delta.Metadata == nil {
+		delta.Metadata = make(map[string]interface{})
+	}
+	delta.Metadata["execution_id"] = record.ExecutionID
+	delta.Metadata["workflow_id"] = record.WorkflowID
+	if record.WorkflowVersion > 0 {
+		delta.Metadata["workflow_version"] = record.WorkflowVersion
+	}
+	delta.Metadata["workflow_digest"] = record.WorkflowDigest
+	if record.ExperimentID != "" {
+		delta.Metadata["experiment_id"] = record.ExperimentID
+		delta.Metadata["variant"] = record.Variant
+	}
+}
@@ -20,6 +20,8 @@ type YAMLWorkflow struct {
 	OutputSchemaID string         `yaml:"output_schema_id"`
 	Active         bool           `yaml:"active"`
 	Steps          []YAMLStep     `yaml:"steps"`
+	LintSuppress   []string       `yaml:"lint_suppress"`
+	MutexKey       string         `yaml:"mutex_key"`
 }
 
 // YAMLStep represents a workflow step in YAML format
@@ -37,7 +39,11 @@ type YAMLStep struct {
 	Compensation *YAMLCompensation      `yaml:"compensation"`
 	Retry        *YAMLRetry             `yaml:"retry"`
 	Timeout      int                    `yaml:"timeout_seconds"`
+	CacheTTL     int                    `yaml:"cache_ttl_seconds"`
 	OnFailure    string                 `yaml:"on_failure"`
+	LintSuppress []string               `yaml:"lint_suppress"`
+	MutexKey     string                 `yaml:"mutex_key"`
+	OutputSchemaID string               `yaml:"output_schema_id"`
 }
 
 // YAMLCompensation represents compensation configuration
@@ -64,6 +70,7 @@ type YAMLSchema struct {
 	Type        string                 `yaml:"type"`
 	Description string                 `yaml:"description"`
 	Definition  map[string]interface{} `yaml:"definition"`
+	EventTypes  []string               `yaml:"event_types"` // for event schemas, the types they validate
 }
 
 // YAMLProvider represents a provider definition in YAML format
@@ -97,9 +104,11 @@ type ProcessorConfig struct {
 	Capabilities []string `yaml:"capabilities"`
 }
 
-// WorkflowMapping represents workflow trigger mapping
+// WorkflowMapping represents workflow trigger mapping. A template mapping
+// stands for the workflow of the template the provider is provisioned for.
 type WorkflowMapping struct {
 	WorkflowID string    `yaml:"workflow_id"`
+	Template   bool      `yaml:"template"`
 	Triggers   []Trigger `yaml:"triggers"`
 }
 
@@ -129,14 +138,14 @@ type ProviderConfiguration struct {
 
 // WorkflowLoader handles loading and registering YAML workflows
 type WorkflowLoader struct {
-	client       *WorkflowClient
+	client       WorkflowService
 	workflowsDir string
 	schemasDir   string
 	providersDir string
 }
 
 // NewWorkflowLoader creates a new workflow loader
-func NewWorkflowLoader(client *WorkflowClient, workflowsDir, schemasDir, providersDir string) *WorkflowLoader {
+func NewWorkflowLoader(client WorkflowService, workflowsDir, schemasDir, providersDir string) *WorkflowLoader {
 	return &WorkflowLoader{
 		client:       client,
 		workflowsDir: workflowsDir,
@@ -194,8 +203,10 @@ func (l *WorkflowLoader) loadSchemaFile(ctx context.Context, filename string) er
 		return fmt.Errorf("failed to unmarshal YAML: %w", err)
 	}
 	
-	// Convert to internal format and register
-	// This would call the workflow service API to register the schema
+	// Register for output or event validation
+	if err := schema.Register(); err != nil {
+		return err
+	}
 	fmt.Printf("Loaded schema: %s from %s\n", schema.ID, filename)
 	
 	return nil
@@ -225,24 +236,43 @@ func (l *WorkflowLoader) loadWorkflowFile(ctx context.Context, filename string)
 		return fmt.Errorf("failed to read file: %w", err)
 	}
 	
-	var workflow YAMLWorkflow
-	if err := yaml.Unmarshal(data, &workflow); err != nil {
-		return fmt.Errorf("failed to unmarshal YAML: %w", err)
+	bpWorkflow, err := ParseYAMLWorkflow(data)
+	if err != nil {
+		return err
 	}
 	
-	// Convert YAML workflow to internal BlobProcessingWorkflow format
-	bpWorkflow := l.convertYAMLToWorkflow(workflow)
+	// Report lint advice, and refuse workflows with lint errors
+	for _, issue := range Lint(bpWorkflow) {
+		if issue.Severity != SeverityError {
+			fmt.Printf("Lint %s: %s\n", filename, issue)
+		}
+	}
+	if err := CheckLint(bpWorkflow); err != nil {
+		return err
+	}
 	
 	// Register with workflow service
 	if err := l.client.RegisterWorkflow(ctx, bpWorkflow); err != nil {
 		return fmt.Errorf("failed to register workflow: %w", err)
 	}
 	
-	fmt.Printf("Loaded workflow: %s from %s\n", workflow.ID, filename)
+	fmt.Printf("Loaded workflow: %s from %s\n", bpWorkflow.ID, filename)
 	
 	return nil
 }
 
+// ParseYAMLWorkflow reads a workflow file into the internal format
+func ParseYAMLWorkflow(data []byte) (*BlobProcessingWorkflow, error) {
+	var workflow YAMLWorkflow
+	if err := yaml.Unmarshal(data, &workflow); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal YAML: %w", err)
+	}
+	
+	// Convert YAML workflow to internal BlobProcessingWorkflow format
+	var l *WorkflowLoader
+	return l.convertYAMLToWorkflow(workflow), nil
+}
+
 // convertYAMLToWorkflow converts YAML workflow to internal format
 func (l *WorkflowLoader) convertYAMLToWorkflow(yaml YAMLWorkflow) *BlobProcessingWorkflow {
 	workflow := &BlobProcessingWorkflow{
@@ -252,6 +282,9 @@ func (l *WorkflowLoader) convertYAMLToWorkflow(yaml YAMLWorkflow) *BlobProcessin
 		Description: yaml.Description,
 		Type:        WorkflowTypeProcessBlob,
 		Steps:       make([]BlobProcessingStep, 0, len(yaml.Steps)),
+		LintSuppress: yaml.LintSuppress,
+		MutexKey:     yaml.MutexKey,
+		OutputSchemaID: yaml.OutputSchemaID,
 		Config: ProcessingConfig{
 			MaxConcurrency:   5,
 			StopOnError:      false,
@@ -276,8 +309,13 @@ func (l *WorkflowLoader) convertYAMLToWorkflow(yaml YAMLWorkflow) *BlobProcessin
 			Condition:  yamlStep.Condition,
 			OnFailure:  yamlStep.OnFailure,
 			Config: StepConfig{
-				Timeout: yamlStep.Timeout,
+				Timeout:      yamlStep.Timeout,
+				CacheResults: yamlStep.CacheTTL > 0,
+				CacheTTL:     yamlStep.CacheTTL,
 			},
+			LintSuppress: yamlStep.LintSuppress,
+			MutexKey:     yamlStep.MutexKey,
+			OutputSchemaID: yamlStep.OutputSchemaID,
 		}
 		
 		// Convert retry policy
@@ -0,0 +1,50 @@
+// Package providers embeds the studio's bundled provider definitions.
+// Importing it registers them, so instantiating a template provisions the
+// providers it requires.
+package providers
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+//go:embed *.yaml
+var files embed.FS
+
+func init() {
+	all, err := parseAll()
+	if err != nil {
+		panic(err)
+	}
+	for _, definition := range all {
+		if err := definition.Register(); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// parseAll parses every embedded provider definition
+func parseAll() ([]*workflows.YAMLProvider, error) {
+	names, err := fs.Glob(files, "*.yaml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list provider definitions: %w", err)
+	}
+	all := make([]*workflows.YAMLProvider, 0, len(names))
+	for _, name := range names {
+		data, err := files.ReadFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read provider definition %s: %w", name, err)
+		}
+		var definition workflows.YAMLProvider
+		if err := yaml.Unmarshal(data, &definition); err != nil {
+			return nil, fmt.Errorf("failed to parse provider definition %s: %w", name, err)
+		}
+		all = append(all, &definition)
+	}
+	return all, nil
+}
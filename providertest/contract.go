@@ -0,0 +1,246 @@
+// Package providertest checks that an external provider honours the studio's
+// contract. A provider under test is pointed at a fake Studio for blob
+// storage and sent trigger payloads that conform to the blob input schema;
+// its responses must conform to the delta output schema:
+//
+//	studio := providertest.NewStudio()
+//	defer studio.Close()
+//	server := httptest.NewServer(myprovider.NewHandler(studio.URL))
+//	defer server.Close()
+//
+//	studio.Run(t, server.URL, providertest.Case{
+//		Name: "expands a draft chapter",
+//		Blob: providertest.Blob{Content: "Once upon a time", Metadata: map[string]interface{}{"type": "chapter"}},
+//	})
+package providertest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+	"github.com/memmieai/memmie-studio/schemas"
+)
+
+// maxResponseSize bounds provider responses
+const maxResponseSize = 10 << 20
+
+// Trigger is the payload a provider receives, per the blob input schema
+type Trigger struct {
+	BlobID       string                 `json:"blob_id"`
+	UserID       string                 `json:"user_id"`
+	ProviderIDs  []string               `json:"provider_ids"`
+	NamespaceID  string                 `json:"namespace_id,omitempty"`
+	Metadata     map[string]interface{} `json:"metadata,omitempty"`
+	TriggerEvent string                 `json:"trigger_event"`
+}
+
+// Output is a provider's response, per the delta output schema
+type Output struct {
+	ExecutionID  string        `json:"execution_id"`
+	Status       string        `json:"status"`
+	Deltas       []Delta       `json:"deltas"`
+	CreatedBlobs []CreatedBlob `json:"created_blobs,omitempty"`
+	Errors       []StepError   `json:"errors,omitempty"`
+}
+
+// Delta is a change a provider makes to the triggering blob
+type Delta struct {
+	Type     string                 `json:"type"`
+	Path     string                 `json:"path"`
+	OldValue interface{}            `json:"old_value,omitempty"`
+	NewValue interface{}            `json:"new_value,omitempty"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// CreatedBlob is a blob a provider reports having created
+type CreatedBlob struct {
+	ID         string `json:"id"`
+	ParentID   string `json:"parent_id,omitempty"`
+	Type       string `json:"type,omitempty"`
+	ProviderID string `json:"provider_id,omitempty"`
+}
+
+// StepError is an error a provider reports
+type StepError struct {
+	StepID    string `json:"step_id,omitempty"`
+	ErrorCode string `json:"error_code,omitempty"`
+	Message   string `json:"message"`
+}
+
+// Result is a provider's answer to one trigger. Violations lists every way
+// the answer breaks the contract; a conforming answer has none.
+type Result struct {
+	Trigger    Trigger
+	StatusCode int
+	Output     Output
+	Violations []string
+}
+
+// Case is one trigger sent to a provider
+type Case struct {
+	Name string
+	// Event is the trigger event, onCreate by default
+	Event string
+	// ProviderID is the provider being triggered, "test-provider" by default
+	ProviderID string
+	// Blob is stored in the studio before the trigger is sent; its IDs are
+	// generated when empty
+	Blob     Blob
+	Metadata map[string]interface{}
+	// Status is the output status the provider must report; any status is
+	// accepted when it is empty
+	Status string
+	// Check makes further assertions on a conforming result
+	Check func(t *testing.T, studio *Studio, result *Result)
+}
+
+// Run sends each case to the provider at endpoint as a subtest and fails
+// those whose answers break the contract
+func (s *Studio) Run(t *testing.T, endpoint string, cases ...Case) {
+	t.Helper()
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			result, err := s.Trigger(context.Background(), endpoint, c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, violation := range result.Violations {
+				t.Error(violation)
+			}
+			if t.Failed() {
+				return
+			}
+			if c.Check != nil {
+				c.Check(t, s, result)
+			}
+		})
+	}
+}
+
+// Trigger stores a case's blob, posts its trigger payload to the provider
+// at endpoint and checks the answer. An error means the provider could not
+// be reached; contract breaches are reported as violations.
+func (s *Studio) Trigger(ctx context.Context, endpoint string, c Case) (*Result, error) {
+	blob := s.AddBlob(c.Blob)
+	trigger := Trigger{
+		BlobID:       blob.ID,
+		UserID:       blob.UserID,
+		ProviderIDs:  []string{orDefault(c.ProviderID, "test-provider")},
+		NamespaceID:  blob.NamespaceID,
+		Metadata:     c.Metadata,
+		TriggerEvent: orDefault(c.Event, "onCreate"),
+	}
+	if violations, err := check(schemas.BlobInputID, trigger); err != nil {
+		return nil, err
+	} else if len(violations) > 0 {
+		return nil, fmt.Errorf("trigger payload does not match the input schema: %s", strings.Join(violations, "; "))
+	}
+
+	body, err := json.Marshal(trigger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal trigger: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	result := &Result{Trigger: trigger, StatusCode: resp.StatusCode}
+	if resp.StatusCode != http.StatusOK {
+		result.Violations = append(result.Violations, fmt.Sprintf("expected status 200, got %d", resp.StatusCode))
+		return result, nil
+	}
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		result.Violations = append(result.Violations, fmt.Sprintf("response is not JSON: %v", err))
+		return result, nil
+	}
+	violations, err := check(schemas.DeltaOutputID, raw)
+	if err != nil {
+		return nil, err
+	}
+	result.Violations = append(result.Violations, violations...)
+	if len(violations) > 0 {
+		return result, nil
+	}
+	if err := json.Unmarshal(data, &result.Output); err != nil {
+		result.Violations = append(result.Violations, fmt.Sprintf("response does not decode: %v", err))
+		return result, nil
+	}
+	result.Violations = append(result.Violations, s.checkOutput(c, blob, result.Output)...)
+	return result, nil
+}
+
+// checkOutput makes the checks the schema cannot express
+func (s *Studio) checkOutput(c Case, blob Blob, output Output) []string {
+	var violations []string
+	if c.Status != "" && output.Status != c.Status {
+		violations = append(violations, fmt.Sprintf("expected status %s, got %s", c.Status, output.Status))
+	}
+	if output.Status == "failed" && len(output.Errors) == 0 {
+		violations = append(violations, "a failed output must report its errors")
+	}
+	for i, delta := range output.Deltas {
+		if delta.Path == "" {
+			violations = append(violations, fmt.Sprintf("$.deltas[%d]: path is empty", i))
+		}
+	}
+	for i, created := range output.CreatedBlobs {
+		stored, ok := s.Blob(created.ID)
+		switch {
+		case !ok:
+			violations = append(violations, fmt.Sprintf("$.created_blobs[%d]: blob %s was never created in the studio", i, created.ID))
+		case stored.UserID != blob.UserID:
+			violations = append(violations, fmt.Sprintf("$.created_blobs[%d]: blob %s belongs to another user", i, created.ID))
+		}
+	}
+	return violations
+}
+
+// check validates a payload against an embedded schema
+func check(schemaID string, payload interface{}) ([]string, error) {
+	schema, err := schemas.Load(schemaID)
+	if err != nil {
+		return nil, err
+	}
+	// Round-trip through JSON so the payload has the shape a provider sees
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("failed to decode payload: %w", err)
+	}
+	var violations []string
+	for _, violation := range workflows.ValidateSchema(schema.Definition, value, "$") {
+		violations = append(violations, violation.String())
+	}
+	return violations, nil
+}
+
+// orDefault returns value, or fallback when value is empty
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
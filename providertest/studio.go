@@ -0,0 +1,208 @@
+package providertest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// Blob is a blob held by the fake studio, in the State Service format
+type Blob struct {
+	ID          string                 `json:"id"`
+	UserID      string                 `json:"user_id"`
+	ProviderID  string                 `json:"provider_id"`
+	NamespaceID string                 `json:"namespace_id,omitempty"`
+	Content     string                 `json:"content"`
+	ParentID    *string                `json:"parent_id,omitempty"`
+	Metadata    map[string]interface{} `json:"metadata"`
+	CreatedAt   time.Time              `json:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at"`
+}
+
+// Studio is a fake studio. It serves the State Service blob API at URL, so
+// a provider under test can read and create blobs as it would in
+// production, and sends the provider trigger payloads.
+type Studio struct {
+	URL string
+
+	server *httptest.Server
+	client *http.Client
+	mu     sync.RWMutex
+	blobs  map[string]*Blob
+	order  []string
+}
+
+// NewStudio starts a fake studio; Close stops it
+func NewStudio() *Studio {
+	s := &Studio{
+		client: &http.Client{Timeout: 30 * time.Second},
+		blobs:  make(map[string]*Blob),
+	}
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/users/{userID}/blobs", s.listBlobs).Methods("GET")
+	router.HandleFunc("/api/v1/users/{userID}/blobs", s.createBlob).Methods("POST")
+	router.HandleFunc("/api/v1/users/{userID}/blobs/{blobID}", s.getBlob).Methods("GET")
+	router.HandleFunc("/api/v1/users/{userID}/blobs/{blobID}", s.updateBlob).Methods("PUT")
+	s.server = httptest.NewServer(router)
+	s.URL = s.server.URL
+	return s
+}
+
+// Close stops the studio
+func (s *Studio) Close() {
+	s.server.Close()
+}
+
+// AddBlob stores a blob, generating its ID and user ID when they are empty,
+// and returns the stored copy
+func (s *Studio) AddBlob(blob Blob) Blob {
+	if blob.ID == "" {
+		blob.ID = uuid.New().String()
+	}
+	if blob.UserID == "" {
+		blob.UserID = uuid.New().String()
+	}
+	if blob.Metadata == nil {
+		blob.Metadata = map[string]interface{}{}
+	}
+	now := time.Now().UTC()
+	if blob.CreatedAt.IsZero() {
+		blob.CreatedAt = now
+	}
+	blob.UpdatedAt = now
+	s.put(&blob)
+	return blob
+}
+
+// Blob returns a stored blob
+func (s *Studio) Blob(blobID string) (Blob, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	blob, ok := s.blobs[blobID]
+	if !ok {
+		return Blob{}, false
+	}
+	return *blob, true
+}
+
+// Children returns the blobs created with a parent, such as those a
+// provider derived from the blob it was triggered for
+func (s *Studio) Children(parentID string) []Blob {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var children []Blob
+	for _, id := range s.order {
+		if blob := s.blobs[id]; blob.ParentID != nil && *blob.ParentID == parentID {
+			children = append(children, *blob)
+		}
+	}
+	return children
+}
+
+// put stores a blob, keeping insertion order
+func (s *Studio) put(blob *Blob) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.blobs[blob.ID]; !ok {
+		s.order = append(s.order, blob.ID)
+	}
+	s.blobs[blob.ID] = blob
+}
+
+// listBlobs serves a user's blobs, filtered as the State Service does
+func (s *Studio) listBlobs(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["userID"]
+	query := r.URL.Query()
+
+	s.mu.RLock()
+	blobs := []*Blob{}
+	for _, id := range s.order {
+		blob := s.blobs[id]
+		if blob.UserID != userID {
+			continue
+		}
+		if v := query.Get("provider_id"); v != "" && blob.ProviderID != v {
+			continue
+		}
+		if v := query.Get("namespace_id"); v != "" && blob.NamespaceID != v {
+			continue
+		}
+		if v := query.Get("parent_id"); v != "" && (blob.ParentID == nil || *blob.ParentID != v) {
+			continue
+		}
+		blobs = append(blobs, blob)
+	}
+	s.mu.RUnlock()
+
+	sort.SliceStable(blobs, func(i, j int) bool { return blobs[i].CreatedAt.Before(blobs[j].CreatedAt) })
+	if offset, err := strconv.Atoi(query.Get("offset")); err == nil && offset > 0 {
+		if offset > len(blobs) {
+			offset = len(blobs)
+		}
+		blobs = blobs[offset:]
+	}
+	if limit, err := strconv.Atoi(query.Get("limit")); err == nil && limit > 0 && limit < len(blobs) {
+		blobs = blobs[:limit]
+	}
+	writeJSON(w, http.StatusOK, blobs)
+}
+
+// createBlob stores a blob a provider creates
+func (s *Studio) createBlob(w http.ResponseWriter, r *http.Request) {
+	var blob Blob
+	if err := json.NewDecoder(r.Body).Decode(&blob); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	blob.UserID = mux.Vars(r)["userID"]
+	blob.CreatedAt = time.Time{}
+	writeJSON(w, http.StatusCreated, s.AddBlob(blob))
+}
+
+// getBlob serves one blob
+func (s *Studio) getBlob(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	blob, ok := s.Blob(vars["blobID"])
+	if !ok || blob.UserID != vars["userID"] {
+		http.Error(w, "blob not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, blob)
+}
+
+// updateBlob replaces a blob's content and metadata
+func (s *Studio) updateBlob(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	existing, ok := s.Blob(vars["blobID"])
+	if !ok || existing.UserID != vars["userID"] {
+		http.Error(w, "blob not found", http.StatusNotFound)
+		return
+	}
+	var blob Blob
+	if err := json.NewDecoder(r.Body).Decode(&blob); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	existing.Content = blob.Content
+	existing.Metadata = blob.Metadata
+	existing.UpdatedAt = time.Now().UTC()
+	s.put(&existing)
+	writeJSON(w, http.StatusOK, existing)
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,78 @@
+// Package schemas embeds the studio's schema definitions, so tools outside
+// the server can validate payloads against the same files. Importing it
+// registers them for workflow output and event validation.
+package schemas
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/memmieai/memmie-studio/internal/workflows"
+)
+
+// Schema IDs
+const (
+	BlobInputID      = "blob_input_schema_v1"
+	DeltaOutputID    = "delta_output_schema_v1"
+	WorkflowOutputID = "workflow_output_schema_v1"
+
+	ExecutionEventID         = "event_execution_v1"
+	StepEventID              = "event_step_v1"
+	DeltaAppliedEventID      = "event_delta_applied_v1"
+	DeltaBatchAppliedEventID = "event_delta_batch_applied_v1"
+	ExecutionFeedbackEventID = "event_execution_feedback_v1"
+	EventBatchID             = "event_batch_v1"
+)
+
+//go:embed *.yaml
+var files embed.FS
+
+func init() {
+	all, err := parseAll()
+	if err != nil {
+		panic(err)
+	}
+	for _, schema := range all {
+		if err := schema.Register(); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Load returns the embedded schema with an ID
+func Load(id string) (*workflows.YAMLSchema, error) {
+	all, err := parseAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, schema := range all {
+		if schema.ID == id {
+			return schema, nil
+		}
+	}
+	return nil, fmt.Errorf("schema %s not found", id)
+}
+
+// parseAll parses every embedded schema
+func parseAll() ([]*workflows.YAMLSchema, error) {
+	names, err := fs.Glob(files, "*.yaml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schemas: %w", err)
+	}
+	all := make([]*workflows.YAMLSchema, 0, len(names))
+	for _, name := range names {
+		data, err := files.ReadFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read schema %s: %w", name, err)
+		}
+		var schema workflows.YAMLSchema
+		if err := yaml.Unmarshal(data, &schema); err != nil {
+			return nil, fmt.Errorf("failed to parse schema %s: %w", name, err)
+		}
+		all = append(all, &schema)
+	}
+	return all, nil
+}